@@ -2,10 +2,16 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/makutaku/blockbench/internal/cli"
+	"github.com/makutaku/blockbench/internal/logging"
+	"github.com/makutaku/blockbench/internal/minecraft"
 	"github.com/makutaku/blockbench/internal/version"
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -15,23 +21,116 @@ var rootCmd = &cobra.Command{
 	Long: `Blockbench is a command-line tool for managing Minecraft Bedrock Edition addons on servers.
 It provides functionality to install, uninstall, and list addons with safety features like
 automatic backups, rollback on failures, and dry-run mode for testing.`,
-	Version: version.GetVersionString(),
+	Version:           version.GetVersionString(),
+	PersistentPreRunE: setup,
+}
+
+// closeLog closes the log file opened by setupLogging
+var closeLog = func() error { return nil }
+
+// setup applies the global flags before any command runs
+func setup(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(cmd, args); err != nil {
+		return err
+	}
+	setupStrictConfig(cmd)
+	return nil
+}
+
+// setupStrictConfig makes malformed world configs fail operations when
+// --strict-config is given or BLOCKBENCH_STRICT_CONFIG is set to a true value
+func setupStrictConfig(cmd *cobra.Command) {
+	strict, _ := cmd.Flags().GetBool("strict-config")
+	if value := os.Getenv("BLOCKBENCH_STRICT_CONFIG"); value != "" && !strict {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			slog.Warn("Invalid environment value, world configs stay lenient", "variable", "BLOCKBENCH_STRICT_CONFIG", "value", value)
+		}
+		strict = enabled
+	}
+	minecraft.SetStrictWorldConfigs(strict)
+}
+
+// setupLogging configures the logger from the global log flags before any
+// command runs
+func setupLogging(cmd *cobra.Command, args []string) error {
+	level, _ := cmd.Flags().GetString("log-level")
+	file, _ := cmd.Flags().GetString("log-file")
+	format, _ := cmd.Flags().GetString("log-format")
+
+	closer, err := logging.Setup(logging.Options{Level: level, File: file, Format: format}, os.Stderr)
+	if err != nil {
+		return err
+	}
+	closeLog = closer
+	slog.Debug("Running command", "command", cmd.CommandPath(), "args", strings.Join(args, " "))
+	return nil
 }
 
 func init() {
 	rootCmd.PersistentFlags().Bool("dry-run", false, "Perform a dry run without making actual changes")
 	rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose output")
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Assume yes for every confirmation, including the steps of --interactive")
+	rootCmd.PersistentFlags().String("docker", "", "Treat server-path as a path inside this Docker container and edit it through the container's volume or bind mount")
+	rootCmd.PersistentFlags().String("log-level", "", "Log level: debug, info, warn, or error (default warn, or info with --log-file)")
+	rootCmd.PersistentFlags().String("log-file", "", "Append log records to this file as an audit trail of operations")
+	rootCmd.PersistentFlags().String("log-format", logging.FormatText, "Log record format: text or json")
+	rootCmd.PersistentFlags().Bool("strict-config", false, "Fail instead of warning when a world config has duplicate pack_id entries, invalid UUIDs, or negative versions (or set BLOCKBENCH_STRICT_CONFIG=1)")
+
+	// The completion command documents the dynamic completions, so it replaces cobra's
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	// Add subcommands
 	rootCmd.AddCommand(cli.NewInstallCommand())
+	rootCmd.AddCommand(cli.NewUpgradeCommand())
+	rootCmd.AddCommand(cli.NewDowngradeCommand())
 	rootCmd.AddCommand(cli.NewUninstallCommand())
+	rootCmd.AddCommand(cli.NewCleanCommand())
+	rootCmd.AddCommand(cli.NewValidateCommand())
+	rootCmd.AddCommand(cli.NewNewCommand())
+	rootCmd.AddCommand(cli.NewRegenUUIDsCommand())
+	rootCmd.AddCommand(cli.NewBumpCommand())
+	rootCmd.AddCommand(cli.NewDevCommand())
 	rootCmd.AddCommand(cli.NewListCommand())
+	rootCmd.AddCommand(cli.NewInfoCommand())
+	rootCmd.AddCommand(cli.NewPackCommand())
+	rootCmd.AddCommand(cli.NewExportCommand())
+	rootCmd.AddCommand(cli.NewMigrateCommand())
+	rootCmd.AddCommand(cli.NewReorderCommand())
+	rootCmd.AddCommand(cli.NewDiffCommand())
+	rootCmd.AddCommand(cli.NewVerifyCommand())
+	rootCmd.AddCommand(cli.NewHistoryCommand())
+	rootCmd.AddCommand(cli.NewUndoCommand())
+	rootCmd.AddCommand(cli.NewBackupCommand())
+	rootCmd.AddCommand(cli.NewSafeModeCommand())
+	rootCmd.AddCommand(cli.NewStateCommand())
+	rootCmd.AddCommand(cli.NewStoreCommand())
+	rootCmd.AddCommand(cli.NewDiscoverCommand())
+	rootCmd.AddCommand(cli.NewLockCommand())
+	rootCmd.AddCommand(cli.NewSyncCommand())
+	rootCmd.AddCommand(cli.NewApplyCommand())
+	rootCmd.AddCommand(cli.NewSourcesCommand())
+	rootCmd.AddCommand(cli.NewCheckUpdatesCommand())
+	rootCmd.AddCommand(cli.NewRegistryCommand())
+	rootCmd.AddCommand(cli.NewSearchCommand())
+	rootCmd.AddCommand(cli.NewServerCommand())
+	rootCmd.AddCommand(cli.NewTrustCommand())
+	rootCmd.AddCommand(cli.NewHooksCommand())
+	rootCmd.AddCommand(cli.NewWebhooksCommand())
+	rootCmd.AddCommand(cli.NewDirsCommand())
 	rootCmd.AddCommand(cli.NewVersionCommand())
+	rootCmd.AddCommand(cli.NewCompletionCommand())
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	}
+	if closeErr := closeLog(); closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to close log file: %v\n", closeErr)
+	}
+	if err != nil {
+		os.Exit(bberrors.ExitCode(err))
 	}
 }
@@ -0,0 +1,124 @@
+package addon
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+// ApplyPlan is a lockfile plan checked against the server's dependency graph
+type ApplyPlan struct {
+	*lockfile.Plan
+	Warnings []string `json:"warnings"`
+}
+
+// PlanApply works out what it takes to make a server match a lockfile.
+// Removals are ordered so packs go before the packs they depend on, and a
+// warning is given for every pack that is kept but depends on a removed one.
+func PlanApply(server *minecraft.Server, lock *lockfile.Lockfile) (*ApplyPlan, error) {
+	group, err := NewDependencyAnalyzer(server).AnalyzeDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	relationships := make(map[string]PackRelationship)
+	installed := make([]minecraft.InstalledPack, 0)
+	for _, list := range [][]PackRelationship{group.RootPacks, group.DependentPacks, group.StandalonePacks} {
+		for _, rel := range list {
+			relationships[rel.Pack.PackID] = rel
+			installed = append(installed, rel.Pack)
+		}
+	}
+	for _, cycle := range group.CircularGroups {
+		for _, rel := range cycle {
+			if _, seen := relationships[rel.Pack.PackID]; !seen {
+				relationships[rel.Pack.PackID] = rel
+				installed = append(installed, rel.Pack)
+			}
+		}
+	}
+
+	plan := &ApplyPlan{Plan: lockfile.PlanSync(lock, installed), Warnings: make([]string, 0)}
+	plan.Remove = orderRemovals(plan.Remove, relationships)
+	plan.Changes = orderRemoveChanges(plan.Changes, plan.Remove)
+
+	removed := make(map[string]bool, len(plan.Remove))
+	for _, uuid := range plan.Remove {
+		removed[uuid] = true
+	}
+	for _, uuid := range plan.Remove {
+		for _, dependent := range relationships[uuid].Dependents {
+			if !removed[dependent] {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s depends on %s, which the plan removes",
+					packLabel(relationships, dependent), packLabel(relationships, uuid)))
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// orderRemovals orders packs to remove so dependents come before their
+// dependencies. Packs in a dependency cycle keep their original order.
+func orderRemovals(uuids []string, relationships map[string]PackRelationship) []string {
+	pending := make(map[string]bool, len(uuids))
+	for _, uuid := range uuids {
+		pending[uuid] = true
+	}
+
+	ordered := make([]string, 0, len(uuids))
+	for len(ordered) < len(uuids) {
+		progressed := false
+		for _, uuid := range uuids {
+			if !pending[uuid] {
+				continue
+			}
+			blocked := false
+			for _, dependent := range relationships[uuid].Dependents {
+				blocked = blocked || pending[dependent]
+			}
+			if blocked {
+				continue
+			}
+			ordered = append(ordered, uuid)
+			pending[uuid] = false
+			progressed = true
+		}
+		if !progressed {
+			// A cycle: remove what's left in the original order
+			for _, uuid := range uuids {
+				if pending[uuid] {
+					ordered = append(ordered, uuid)
+					pending[uuid] = false
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// orderRemoveChanges puts the removals among changes in the order of removals
+func orderRemoveChanges(changes []lockfile.Change, removals []string) []lockfile.Change {
+	byUUID := make(map[string]lockfile.Change)
+	ordered := make([]lockfile.Change, 0, len(changes))
+	for _, change := range changes {
+		if change.Kind == lockfile.ChangeRemove {
+			byUUID[change.UUID] = change
+		} else {
+			ordered = append(ordered, change)
+		}
+	}
+	for _, uuid := range removals {
+		ordered = append(ordered, byUUID[uuid])
+	}
+	return ordered
+}
+
+// packLabel names a pack for messages, falling back to its UUID
+func packLabel(relationships map[string]PackRelationship, uuid string) string {
+	if rel, ok := relationships[uuid]; ok && rel.Pack.Name != "" {
+		return rel.Pack.Name
+	}
+	return uuid
+}
@@ -18,20 +18,163 @@ type BackupManager struct {
 
 // NewBackupManager creates a new addon backup manager
 func NewBackupManager(server *minecraft.Server, backupRoot string) *BackupManager {
+	backups := filesystem.NewBackupManager(backupRoot)
+	backups.FS = server.FS
+	backups.ServerRoot = server.Paths.ServerRoot
+	backups.Incremental = server.IncrementalBackups
 	return &BackupManager{
-		BackupManager: filesystem.NewBackupManager(backupRoot),
+		BackupManager: backups,
 		server:        server,
 	}
 }
 
-// CreateInstallBackup creates a backup before installing an addon
-func (bm *BackupManager) CreateInstallBackup(addonName, addonUUID string) (*filesystem.BackupMetadata, error) {
+// RestoreHealth is the result of checking a server right after a backup was
+// restored. Problems mean the restore did not bring the server back to the
+// backup; warnings are issues the backup itself already had.
+type RestoreHealth struct {
+	Healthy        bool     `json:"healthy"`
+	FilesVerified  int      `json:"files_verified"`
+	ConfigsChecked int      `json:"configs_checked"`
+	PacksChecked   int      `json:"packs_checked"`
+	Problems       []string `json:"problems"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// RestoreBackup restores a backup and verifies the result, failing when the
+// server does not match the backup; see RestoreAndVerify
+func (bm *BackupManager) RestoreBackup(backupID string) error {
+	_, err := bm.RestoreAndVerify(backupID)
+	return err
+}
+
+// RestoreAndVerify restores a backup, then checks the server: the restored
+// files are compared with the backup's checksums, the restored world configs
+// are parsed again, and every pack they activate must have a readable
+// directory. It returns the health even when the check fails, with an error
+// listing the problems, so a restore is never reported as done when it isn't.
+func (bm *BackupManager) RestoreAndVerify(backupID string) (*RestoreHealth, error) {
+	return bm.RestoreFilesAndVerify(backupID, nil)
+}
+
+// RestoreFilesAndVerify restores some of the files of a backup, each an entry
+// of its Files, and checks them as RestoreAndVerify does; none restores them all
+func (bm *BackupManager) RestoreFilesAndVerify(backupID string, files []string) (*RestoreHealth, error) {
+	// A restore rewrites world configs and pack directories
+	defer bm.server.InvalidatePacks()
+	if err := bm.RestoreFiles(backupID, files); err != nil {
+		return nil, err
+	}
+	bm.server.InvalidatePacks()
+
+	health := &RestoreHealth{Problems: make([]string, 0)}
+	verification, err := bm.VerifyRestoreFiles(backupID, files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the restored files: %w", err)
+	}
+	health.FilesVerified = verification.FilesVerified
+	health.Problems = append(health.Problems, verification.Problems...)
+
+	metadata, err := bm.LoadMetadata(backupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		files = metadata.Files
+	}
+	bm.checkRestoredConfigs(files, health)
+	if err := bm.checkRestoredPacks(metadata, health); err != nil {
+		return nil, err
+	}
+
+	health.Healthy = len(health.Problems) == 0
+	if !health.Healthy {
+		return health, fmt.Errorf("restored backup %s, but verification found %d problem(s): %s",
+			backupID, len(health.Problems), strings.Join(health.Problems, "; "))
+	}
+	return health, nil
+}
+
+// checkRestoredConfigs parses the restored world configs among files again.
+// A config that can't be parsed is a problem; malformed entries are warnings,
+// since the backed-up config had them too.
+func (bm *BackupManager) checkRestoredConfigs(files []string, health *RestoreHealth) {
+	worldConfigs := map[string]bool{
+		bm.server.Paths.WorldBehaviorPacks: true,
+		bm.server.Paths.WorldResourcePacks: true,
+	}
+	for _, file := range files {
+		if !worldConfigs[file] {
+			continue
+		}
+		// #nosec G304 - file is a world config path of the server
+		data, err := filesystem.ReadFile(bm.FS, file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		health.ConfigsChecked++
+		if err != nil {
+			health.Problems = append(health.Problems, fmt.Sprintf("%s can't be read: %v", file, err))
+			continue
+		}
+		codec, err := minecraft.DetectWorldConfigCodec(data)
+		if err != nil {
+			health.Problems = append(health.Problems, fmt.Sprintf("%s can't be parsed: %v", file, err))
+			continue
+		}
+		config, err := codec.Decode(data)
+		if err != nil {
+			health.Problems = append(health.Problems, fmt.Sprintf("%s can't be parsed: %v", file, err))
+			continue
+		}
+		for _, problem := range minecraft.CheckWorldConfig(file, data, config) {
+			health.Warnings = append(health.Warnings, problem.String())
+		}
+	}
+}
+
+// checkRestoredPacks checks that every active pack has a readable directory.
+// A broken pack of the backed-up operation is a problem, since the restore
+// should have put it back; any other was broken before the operation.
+func (bm *BackupManager) checkRestoredPacks(metadata *filesystem.BackupMetadata, health *RestoreHealth) error {
+	packs, err := bm.server.ListInstalledPacks()
+	if err != nil {
+		return fmt.Errorf("failed to list the restored packs: %w", err)
+	}
+
+	operationPacks := make(map[string]bool, len(metadata.PackUUIDs))
+	for _, uuid := range metadata.PackUUIDs {
+		operationPacks[strings.ToLower(uuid)] = true
+	}
+	for _, pack := range packs {
+		health.PacksChecked++
+		if pack.Status == minecraft.PackStatusOK {
+			continue
+		}
+		message := fmt.Sprintf("Pack %s is active but %s", pack.PackID, pack.Status)
+		if pack.StatusDetail != "" {
+			message += ": " + pack.StatusDetail
+		}
+		if operationPacks[strings.ToLower(pack.PackID)] {
+			health.Problems = append(health.Problems, message)
+		} else {
+			health.Warnings = append(health.Warnings, message)
+		}
+	}
+	return nil
+}
+
+// CreateInstallBackup creates a backup before installing an addon.
+// packUUIDs lists every pack in the addon; the first is recorded as the addon
+// UUID. extraFiles are backed up too, such as server.properties when the
+// install edits it and the directories of the packs it replaces.
+func (bm *BackupManager) CreateInstallBackup(addonName string, packUUIDs []string, extraFiles ...string) (*filesystem.BackupMetadata, error) {
 	files := []string{
 		bm.server.Paths.WorldBehaviorPacks,
 		bm.server.Paths.WorldResourcePacks,
 		bm.server.Paths.WorldBehaviorHistory,
 		bm.server.Paths.WorldResourceHistory,
 	}
+	files = append(files, extraFiles...)
 
 	description := fmt.Sprintf("Before installing addon: %s", addonName)
 
@@ -41,8 +184,35 @@ func (bm *BackupManager) CreateInstallBackup(addonName, addonUUID string) (*file
 	}
 
 	metadata.AddonName = addonName
-	metadata.AddonUUID = addonUUID
-	metadata.ServerPath = bm.server.Paths.ServerRoot
+	if len(packUUIDs) > 0 {
+		metadata.AddonUUID = packUUIDs[0]
+	}
+	metadata.PackUUIDs = packUUIDs
+
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// CreateRestoreBackup backs up the files of a backup that restoring it will
+// overwrite or delete, so the restore itself can be undone
+func (bm *BackupManager) CreateRestoreBackup(restoring *filesystem.BackupMetadata, files []string) (*filesystem.BackupMetadata, error) {
+	description := fmt.Sprintf("Before restoring backup: %s", restoring.ID)
+
+	metadata, err := bm.CreateBackup("restore", description, files)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.AddonName = restoring.AddonName
+	metadata.AddonUUID = restoring.AddonUUID
+	metadata.PackUUIDs = restoring.PackUUIDs
+
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
 
 	return metadata, nil
 }
@@ -73,7 +243,143 @@ func (bm *BackupManager) CreateUninstallBackup(addonName, addonUUID string) (*fi
 
 	metadata.AddonName = addonName
 	metadata.AddonUUID = addonUUID
-	metadata.ServerPath = bm.server.Paths.ServerRoot
+	metadata.PackUUIDs = []string{addonUUID}
+
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// CleanOperation is the operation of backups taken before unreferenced pack
+// directories are removed
+const CleanOperation = "clean"
+
+// CreateCleanBackup backs up the directories of unreferenced packs before
+// they are removed. Linked packs are left out: removing one only removes the
+// link.
+func (bm *BackupManager) CreateCleanBackup(packs []minecraft.UnreferencedPack) (*filesystem.BackupMetadata, error) {
+	var files, packUUIDs []string
+	for _, pack := range packs {
+		packUUIDs = append(packUUIDs, pack.PackID)
+		if pack.Link == "" {
+			files = append(files, pack.Dir)
+		}
+	}
+
+	metadata, err := bm.CreateBackup(CleanOperation, "Before removing unreferenced pack directories", files)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.PackUUIDs = packUUIDs
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// SelectFiles returns the entries of a backup's Files that only names: a
+// file or directory the backup includes, as an absolute path or relative to
+// the server root, or the UUID of a pack whose directories it includes. An
+// empty only selects every file.
+func (bm *BackupManager) SelectFiles(metadata *filesystem.BackupMetadata, only string) ([]string, error) {
+	if only == "" {
+		return metadata.Files, nil
+	}
+
+	listed := make(map[string]bool, len(metadata.Files))
+	for _, file := range metadata.Files {
+		listed[file] = true
+	}
+	candidates := []string{filepath.Join(bm.server.Paths.ServerRoot, only)}
+	if abs, err := filepath.Abs(only); err == nil {
+		candidates = append([]string{abs}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if listed[candidate] {
+			return []string{candidate}, nil
+		}
+	}
+
+	var packDirs []string
+	for _, file := range metadata.Files {
+		uuid, err := bm.backedUpPackUUID(metadata, file)
+		if err != nil {
+			return nil, err
+		}
+		if uuid != "" && strings.EqualFold(uuid, only) {
+			packDirs = append(packDirs, file)
+		}
+	}
+	if len(packDirs) == 0 {
+		return nil, fmt.Errorf("backup %s includes no file or pack directory matching %s", metadata.ID, only)
+	}
+	return packDirs, nil
+}
+
+// backedUpPackUUID returns the UUID in the manifest of a pack directory a
+// backup includes, read from the backed-up copy or, when the directory did
+// not exist at the time, from the directory now. It is empty for anything
+// that is not a pack directory.
+func (bm *BackupManager) backedUpPackUUID(metadata *filesystem.BackupMetadata, file string) (string, error) {
+	manifestPath := filepath.Join(file, "manifest.json")
+	copyPath, existed, err := bm.BackedUpCopy(metadata, manifestPath)
+	if err != nil {
+		return "", err
+	}
+	if !existed {
+		copyPath = manifestPath
+	}
+	// A directory without a readable manifest is not a pack directory
+	manifest, err := minecraft.ParseManifestFS(bm.FS, copyPath)
+	if err != nil {
+		return "", nil
+	}
+	return manifest.Header.UUID, nil
+}
+
+// SnapshotOperation is the operation of backups taken on demand or on a
+// schedule rather than before an operation
+const SnapshotOperation = "snapshot"
+
+// CreateSnapshotBackup backs up the world configs and every installed pack
+// directory, independent of any operation. Linked packs are skipped: their
+// directory is someone's source, not the server's. With full, the backup is
+// full even when backups are incremental.
+func (bm *BackupManager) CreateSnapshotBackup(full bool) (*filesystem.BackupMetadata, error) {
+	files := []string{
+		bm.server.Paths.WorldBehaviorPacks,
+		bm.server.Paths.WorldResourcePacks,
+		bm.server.Paths.WorldBehaviorHistory,
+		bm.server.Paths.WorldResourceHistory,
+	}
+	for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+		packs, err := bm.server.IndexedPacks(packType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s packs: %w", packType, err)
+		}
+		for _, pack := range packs {
+			if _, linked := minecraft.LinkedPackTarget(pack.Dir); !linked {
+				files = append(files, pack.Dir)
+			}
+		}
+	}
+
+	incremental := bm.Incremental
+	bm.Incremental = incremental && !full
+	defer func() { bm.Incremental = incremental }()
+
+	metadata, err := bm.CreateBackup(SnapshotOperation, "Snapshot of world configs and packs", files)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		return nil, fmt.Errorf("failed to record backup metadata: %w", err)
+	}
 
 	return metadata, nil
 }
@@ -84,7 +390,7 @@ func (bm *BackupManager) findAddonDirectories(addonUUID string) ([]string, error
 	var errors []string
 
 	// Check behavior packs directory
-	behaviorDir, err := bm.findAddonInDirectory(bm.server.Paths.BehaviorPacksDir, addonUUID)
+	behaviorDir, err := bm.server.FindPackDirectory(addonUUID, minecraft.PackTypeBehavior)
 	if err == nil {
 		dirs = append(dirs, behaviorDir)
 	} else {
@@ -95,7 +401,7 @@ func (bm *BackupManager) findAddonDirectories(addonUUID string) ([]string, error
 	}
 
 	// Check resource packs directory
-	resourceDir, err := bm.findAddonInDirectory(bm.server.Paths.ResourcePacksDir, addonUUID)
+	resourceDir, err := bm.server.FindPackDirectory(addonUUID, minecraft.PackTypeResource)
 	if err == nil {
 		dirs = append(dirs, resourceDir)
 	} else {
@@ -133,31 +439,3 @@ func (bm *BackupManager) LoadMetadata(backupID string) (*filesystem.BackupMetada
 
 	return nil, fmt.Errorf("backup not found: %s", backupID)
 }
-
-// findAddonInDirectory searches for an addon directory by UUID
-func (bm *BackupManager) findAddonInDirectory(baseDir, addonUUID string) (string, error) {
-	entries, err := os.ReadDir(baseDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to read directory %s: %w", baseDir, err)
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		packPath := filepath.Join(baseDir, entry.Name())
-		manifestPath := filepath.Join(packPath, "manifest.json")
-
-		manifest, err := minecraft.ParseManifest(manifestPath)
-		if err != nil {
-			continue // Skip if can't read manifest
-		}
-
-		if manifest.Header.UUID == addonUUID {
-			return packPath, nil
-		}
-	}
-
-	return "", fmt.Errorf("addon directory not found for UUID: %s", addonUUID)
-}
@@ -0,0 +1,161 @@
+package addon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+func TestCleanBackupSameNamedPacks(t *testing.T) {
+	server := newTestServer(t)
+	behaviorDir := filepath.Join(server.Paths.BehaviorPacksDir, "Pack")
+	resourceDir := filepath.Join(server.Paths.ResourcePacksDir, "Pack")
+	writeTestPack(t, behaviorDir, behaviorUUID, [3]int{1, 0, 0})
+	writeTestPack(t, resourceDir, resourceUUID, [3]int{1, 0, 0})
+
+	packs, err := server.UnreferencedPacks()
+	if err != nil {
+		t.Fatalf("UnreferencedPacks failed: %v", err)
+	}
+	if len(packs) != 2 {
+		t.Fatalf("Expected both packs unreferenced, got %v", packs)
+	}
+
+	cleaner := NewCleaner(server, t.TempDir())
+	result, err := cleaner.RemoveUnreferenced(packs, CleanOptions{})
+	if err != nil {
+		t.Fatalf("RemoveUnreferenced failed: %v", err)
+	}
+	for _, dir := range []string{behaviorDir, resourceDir} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s removed", dir)
+		}
+	}
+
+	if err := cleaner.backupManager.RestoreBackup(result.BackupMetadata.ID); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	for dir, uuid := range map[string]string{behaviorDir: behaviorUUID, resourceDir: resourceUUID} {
+		manifest, err := minecraft.ParseManifest(filepath.Join(dir, "manifest.json"))
+		if err != nil {
+			t.Fatalf("Expected %s restored: %v", dir, err)
+		}
+		if manifest.Header.UUID != uuid {
+			t.Errorf("Expected %s restored with pack %s, got %s", dir, uuid, manifest.Header.UUID)
+		}
+	}
+}
+
+func TestSnapshotBackupRoundTrip(t *testing.T) {
+	server := newTestServer(t)
+	behaviorDir := filepath.Join(server.Paths.BehaviorPacksDir, "Pack")
+	resourceDir := filepath.Join(server.Paths.ResourcePacksDir, "Pack")
+	writeTestPack(t, behaviorDir, behaviorUUID, [3]int{1, 0, 0})
+	writeTestPack(t, resourceDir, resourceUUID, [3]int{1, 0, 0})
+	config := `[{"pack_id": "` + behaviorUUID + `", "version": [1, 0, 0]}]`
+	if err := os.WriteFile(server.Paths.WorldBehaviorPacks, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write world config: %v", err)
+	}
+
+	backups := NewBackupManager(server, t.TempDir())
+	for _, incremental := range []bool{false, true} {
+		backups.Incremental = incremental
+		metadata, err := backups.CreateSnapshotBackup(false)
+		if err != nil {
+			t.Fatalf("CreateSnapshotBackup failed (incremental %v): %v", incremental, err)
+		}
+		if incremental && metadata.Base == "" {
+			t.Error("Expected the second snapshot to be incremental")
+		}
+
+		// Both same-named packs and the world config change after the snapshot
+		for _, dir := range []string{behaviorDir, resourceDir} {
+			if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0600); err != nil {
+				t.Fatalf("Failed to overwrite manifest: %v", err)
+			}
+		}
+		if err := os.Remove(server.Paths.WorldBehaviorPacks); err != nil {
+			t.Fatalf("Failed to remove world config: %v", err)
+		}
+
+		if _, err := backups.RestoreAndVerify(metadata.ID); err != nil {
+			t.Fatalf("RestoreAndVerify failed (incremental %v): %v", incremental, err)
+		}
+		for dir, uuid := range map[string]string{behaviorDir: behaviorUUID, resourceDir: resourceUUID} {
+			manifest, err := minecraft.ParseManifest(filepath.Join(dir, "manifest.json"))
+			if err != nil || manifest.Header.UUID != uuid {
+				t.Errorf("Expected %s restored with pack %s, got %v (%v)", dir, uuid, manifest, err)
+			}
+		}
+		if data, err := os.ReadFile(server.Paths.WorldBehaviorPacks); err != nil || string(data) != config {
+			t.Errorf("Expected the world config restored, got %q (%v)", data, err)
+		}
+	}
+}
+
+func TestRestoreAndVerifyMemFS(t *testing.T) {
+	server := newTestServer(t)
+	memFS := filesystem.NewMemFS()
+	server.FS = memFS
+	config := server.Paths.WorldBehaviorPacks
+	for _, dir := range []string{filepath.Dir(config), server.Paths.BehaviorPacksDir, server.Paths.ResourcePacksDir} {
+		if err := memFS.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := filesystem.WriteFile(memFS, config, []byte("[]"), 0600); err != nil {
+		t.Fatalf("Failed to write world config: %v", err)
+	}
+
+	backups := NewBackupManager(server, t.TempDir())
+	metadata, err := backups.CreateSnapshotBackup(false)
+	if err != nil {
+		t.Fatalf("CreateSnapshotBackup failed: %v", err)
+	}
+	if err := filesystem.WriteFile(memFS, config, []byte("not json"), 0600); err != nil {
+		t.Fatalf("Failed to break world config: %v", err)
+	}
+
+	health, err := backups.RestoreAndVerify(metadata.ID)
+	if err != nil {
+		t.Fatalf("RestoreAndVerify failed: %v", err)
+	}
+	if health.ConfigsChecked != 1 {
+		t.Errorf("Expected the restored world config checked on the server's filesystem, got %d checked", health.ConfigsChecked)
+	}
+	if _, err := os.Stat(config); !os.IsNotExist(err) {
+		t.Error("Expected nothing restored to the real filesystem")
+	}
+}
+
+func TestSelectFilesMemFS(t *testing.T) {
+	server := newTestServer(t)
+	memFS := filesystem.NewMemFS()
+	server.FS = memFS
+	packDir := filepath.Join(server.Paths.BehaviorPacksDir, "Pack")
+	manifest := `{"format_version": 2, "header": {"name": "Pack", "uuid": "` + behaviorUUID + `", "version": [1, 0, 0]},
+		"modules": [{"type": "data", "uuid": "99999999-9999-9999-9999-999999999991", "version": [1, 0, 0]}]}`
+	if err := memFS.MkdirAll(packDir, 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := filesystem.WriteFile(memFS, filepath.Join(packDir, "manifest.json"), []byte(manifest), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	backups := NewBackupManager(server, t.TempDir())
+	metadata, err := backups.CreateBackup(CleanOperation, "MemFS pack", []string{packDir})
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	files, err := backups.SelectFiles(metadata, strings.ToUpper(behaviorUUID))
+	if err != nil {
+		t.Fatalf("SelectFiles failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != packDir {
+		t.Errorf("Expected the pack directory selected by its UUID, got %v", files)
+	}
+}
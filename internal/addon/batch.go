@@ -0,0 +1,269 @@
+package addon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// Batch operation kinds
+const (
+	BatchInstall   = "install"
+	BatchUninstall = "uninstall"
+	BatchReorder   = "reorder"
+)
+
+// Batch queues installs, uninstalls, and reorders and executes them as one
+// transaction: a single backup is taken first, and if any operation fails the
+// server is restored from it, undoing the operations that already succeeded.
+//
+//	result, err := addon.NewBatch(server, backupDir).
+//		Uninstall("Old Pack", addon.UninstallOptions{}).
+//		Install("new.mcaddon", addon.InstallOptions{}).
+//		Reorder(packUUID, 0).
+//		Execute(addon.BatchOptions{})
+type Batch struct {
+	server        *minecraft.Server
+	backupManager *BackupManager
+	installer     *Installer
+	uninstaller   *Uninstaller
+	operations    []batchOperation
+}
+
+type batchOperation struct {
+	kind             string
+	target           string // Addon path, uninstall identifier, or pack UUID
+	index            int    // Reorder destination
+	installOptions   InstallOptions
+	uninstallOptions UninstallOptions
+}
+
+// BatchOptions contains options for executing a batch
+type BatchOptions struct {
+	DryRun      bool
+	Verbose     bool
+	Description string // Recorded in the backup metadata; defaults to a summary of the operations
+}
+
+// BatchOperationResult is the outcome of one queued operation
+type BatchOperationResult struct {
+	Kind      string           `json:"kind"`
+	Target    string           `json:"target"`
+	Success   bool             `json:"success"`
+	Error     string           `json:"error,omitempty"`
+	Install   *InstallResult   `json:"install,omitempty"`
+	Uninstall *UninstallResult `json:"uninstall,omitempty"`
+}
+
+// BatchResult is the combined report of a batch execution
+type BatchResult struct {
+	Success        bool                       `json:"success"`
+	RolledBack     bool                       `json:"rolled_back"` // A failure restored the server to the batch backup
+	BackupMetadata *filesystem.BackupMetadata `json:"backup,omitempty"`
+	Operations     []BatchOperationResult     `json:"operations"`
+	Errors         []string                   `json:"errors"`
+	Warnings       []string                   `json:"warnings"`
+}
+
+// NewBatch creates an empty batch for a server
+func NewBatch(server *minecraft.Server, backupDir string) *Batch {
+	return &Batch{
+		server:        server,
+		backupManager: NewBackupManager(server, backupDir),
+		installer:     NewInstaller(server, backupDir),
+		uninstaller:   NewUninstaller(server, backupDir),
+	}
+}
+
+// Install queues an addon installation
+func (b *Batch) Install(addonPath string, options InstallOptions) *Batch {
+	b.operations = append(b.operations, batchOperation{kind: BatchInstall, target: addonPath, installOptions: options})
+	return b
+}
+
+// Uninstall queues an addon removal by name or, with options.ByUUID, by UUID
+func (b *Batch) Uninstall(identifier string, options UninstallOptions) *Batch {
+	b.operations = append(b.operations, batchOperation{kind: BatchUninstall, target: identifier, uninstallOptions: options})
+	return b
+}
+
+// Reorder queues moving an active pack to a new index in its world config
+func (b *Batch) Reorder(packID string, index int) *Batch {
+	b.operations = append(b.operations, batchOperation{kind: BatchReorder, target: packID, index: index})
+	return b
+}
+
+// Len returns the number of queued operations
+func (b *Batch) Len() int {
+	return len(b.operations)
+}
+
+// Execute runs the queued operations in order. Operations stop at the first
+// failure, which rolls back the whole batch. In a dry run every operation is
+// simulated against the current server state, so later operations do not see
+// the effects of earlier ones.
+func (b *Batch) Execute(options BatchOptions) (*BatchResult, error) {
+	result := &BatchResult{
+		Operations: make([]BatchOperationResult, 0, len(b.operations)),
+		Errors:     make([]string, 0),
+		Warnings:   make([]string, 0),
+	}
+
+	if len(b.operations) == 0 {
+		return result, fmt.Errorf("batch has no operations")
+	}
+
+	var backup *filesystem.BackupMetadata
+	if !options.DryRun {
+		var err error
+		backup, err = b.createBackup(options)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+			return result, err
+		}
+		result.BackupMetadata = backup
+	}
+
+	for i, op := range b.operations {
+		if options.Verbose {
+			fmt.Printf("[%d/%d] %s %s\n", i+1, len(b.operations), op.kind, op.target)
+		}
+
+		opResult, err := b.executeOperation(op, backup, options)
+		if err != nil {
+			opResult.Error = err.Error()
+		}
+		result.Operations = append(result.Operations, opResult)
+		if opResult.Install != nil {
+			result.Warnings = append(result.Warnings, opResult.Install.Warnings...)
+		}
+		if opResult.Uninstall != nil {
+			result.Warnings = append(result.Warnings, opResult.Uninstall.Warnings...)
+		}
+
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Operation %d (%s %s) failed: %v", i+1, op.kind, op.target, err))
+			err = fmt.Errorf("batch operation %d (%s %s) failed: %w", i+1, op.kind, op.target, err)
+			if backup != nil {
+				if options.Verbose {
+					fmt.Println("Batch failed, rolling back all operations...")
+				}
+				rollbackErr := b.backupManager.RestoreBackup(backup.ID)
+				if rollbackErr != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", rollbackErr))
+					err = rollbackFailed(err, rollbackErr)
+				} else {
+					result.RolledBack = true
+				}
+				recordHistory(b.server, rollbackHistoryEntry("batch", backup.ID, rollbackErr))
+			}
+			return result, err
+		}
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// executeOperation runs one operation against the shared batch backup
+func (b *Batch) executeOperation(op batchOperation, backup *filesystem.BackupMetadata, options BatchOptions) (BatchOperationResult, error) {
+	opResult := BatchOperationResult{Kind: op.kind, Target: op.target}
+
+	switch op.kind {
+	case BatchInstall:
+		installOptions := op.installOptions
+		installOptions.DryRun = options.DryRun
+		installOptions.Verbose = installOptions.Verbose || options.Verbose
+		installOptions.Interactive = false
+		installOptions.batchBackup = backup
+
+		installResult, err := b.installer.InstallAddon(op.target, installOptions)
+		opResult.Install = installResult
+		if err != nil {
+			return opResult, err
+		}
+		opResult.Success = installResult.Success
+
+	case BatchUninstall:
+		uninstallOptions := op.uninstallOptions
+		uninstallOptions.DryRun = options.DryRun
+		uninstallOptions.Verbose = uninstallOptions.Verbose || options.Verbose
+		uninstallOptions.Interactive = false
+		uninstallOptions.batchBackup = backup
+
+		uninstallResult, err := b.uninstaller.UninstallAddon(op.target, uninstallOptions)
+		opResult.Uninstall = uninstallResult
+		if err != nil {
+			return opResult, err
+		}
+		opResult.Success = uninstallResult.Success
+
+	case BatchReorder:
+		if options.DryRun {
+			if _, err := FindInstalledPack(b.server, op.target, true); err != nil {
+				return opResult, err
+			}
+			opResult.Success = true
+			break
+		}
+		if err := b.server.MovePack(op.target, op.index); err != nil {
+			return opResult, err
+		}
+		opResult.Success = true
+
+	default:
+		return opResult, fmt.Errorf("unknown batch operation: %s", op.kind)
+	}
+
+	return opResult, nil
+}
+
+// createBackup takes the single backup covering every operation: the world
+// configs and histories plus the pack directories, so restoring it removes
+// packs installed by the batch and brings back packs it uninstalled.
+// server.properties and level.dat are included when an install changes them.
+func (b *Batch) createBackup(options BatchOptions) (*filesystem.BackupMetadata, error) {
+	paths := b.server.Paths
+	files := []string{
+		paths.WorldBehaviorPacks,
+		paths.WorldResourcePacks,
+		paths.WorldBehaviorHistory,
+		paths.WorldResourceHistory,
+		paths.BehaviorPacksDir,
+		paths.ResourcePacksDir,
+	}
+	var properties, levelDat bool
+	for _, op := range b.operations {
+		if op.kind == BatchInstall {
+			properties = properties || op.installOptions.EnableExperiments || op.installOptions.RequireOnClients
+			levelDat = levelDat || op.installOptions.EnableExperiments
+		}
+	}
+	if properties {
+		files = append(files, paths.ServerProperties)
+	}
+	if levelDat {
+		files = append(files, paths.LevelDat)
+	}
+
+	description := options.Description
+	if description == "" {
+		summaries := make([]string, 0, len(b.operations))
+		for _, op := range b.operations {
+			summaries = append(summaries, fmt.Sprintf("%s %s", op.kind, op.target))
+		}
+		description = fmt.Sprintf("Before batch: %s", strings.Join(summaries, ", "))
+	}
+
+	if options.Verbose {
+		fmt.Println("Creating backup before batch...")
+	}
+
+	metadata, err := b.backupManager.CreateBackup("batch", description, files)
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,222 @@
+package addon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// BumpPart is the part of the version BuildAddon increments
+type BumpPart string
+
+const (
+	BumpNone  BumpPart = ""
+	BumpPatch BumpPart = "patch"
+	BumpMinor BumpPart = "minor"
+	BumpMajor BumpPart = "major"
+)
+
+// ParseBumpPart parses a --bump value
+func ParseBumpPart(value string) (BumpPart, error) {
+	switch part := BumpPart(value); part {
+	case BumpNone, BumpPatch, BumpMinor, BumpMajor:
+		return part, nil
+	}
+	return BumpNone, fmt.Errorf("invalid bump %q: use patch, minor, or major", value)
+}
+
+// Apply returns version with the part incremented and the parts after it reset
+func (b BumpPart) Apply(version [3]int) [3]int {
+	switch b {
+	case BumpMajor:
+		return [3]int{version[0] + 1, 0, 0}
+	case BumpMinor:
+		return [3]int{version[0], version[1] + 1, 0}
+	case BumpPatch:
+		return [3]int{version[0], version[1], version[2] + 1}
+	}
+	return version
+}
+
+// BuildOptions controls BuildAddon
+type BuildOptions struct {
+	Bump   BumpPart // Increment every pack's version first, rewriting its manifest
+	DryRun bool     // Validate and report without writing manifests or the output
+}
+
+// BuiltPack is one pack written by BuildAddon
+type BuiltPack struct {
+	PackID          string             `json:"pack_id"`
+	Name            string             `json:"name"`
+	Type            minecraft.PackType `json:"type"`
+	Version         [3]int             `json:"version"`
+	PreviousVersion *[3]int            `json:"previous_version,omitempty"` // Set when the version was bumped
+	Directory       string             `json:"directory"`
+	File            string             `json:"file,omitempty"` // The .mcpack entry in a .mcaddon
+}
+
+// BuildResult describes the archive written by BuildAddon
+type BuildResult struct {
+	Output string      `json:"output"`
+	Packs  []BuiltPack `json:"packs"`
+}
+
+// builtPack is a pack directory BuildAddon has validated
+type builtPack struct {
+	dir      string
+	manifest *minecraft.Manifest
+}
+
+// BuildAddon packages pack source directories, each with a manifest.json at
+// its root, for distribution. An output ending in .mcpack holds a single pack
+// at its root; a .mcaddon holds one .mcpack per pack. Every manifest is
+// validated first. With options.Bump, each pack's version is incremented in
+// its manifest, along with the modules at that version and the dependencies
+// of the other packs on it. Hidden files, node_modules, and npm's package
+// files are left out.
+func BuildAddon(dirs []string, output string, options BuildOptions) (*BuildResult, error) {
+	ext := strings.ToLower(filepath.Ext(output))
+	if ext != ".mcaddon" && ext != ".mcpack" {
+		return nil, fmt.Errorf("output %s must end in .mcaddon or .mcpack", output)
+	}
+	if ext == ".mcpack" && len(dirs) != 1 {
+		return nil, fmt.Errorf("a .mcpack holds a single pack; write a .mcaddon to package %d packs", len(dirs))
+	}
+	absOutput, err := filepath.Abs(output)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make([]builtPack, 0, len(dirs))
+	seen := make(map[string]string)
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(absOutput, abs+string(filepath.Separator)) {
+			return nil, fmt.Errorf("output %s must not be inside the pack directory %s", output, dir)
+		}
+		manifest, err := minecraft.ParseManifest(filepath.Join(abs, "manifest.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		if err := minecraft.ValidateManifest(manifest); err != nil {
+			return nil, fmt.Errorf("%s: manifest validation failed: %w", dir, err)
+		}
+		if manifest.GetPackType() == minecraft.PackTypeUnknown {
+			return nil, fmt.Errorf("%s: manifest has no data or resources module", dir)
+		}
+		uuid := strings.ToLower(manifest.Header.UUID)
+		if other, ok := seen[uuid]; ok {
+			return nil, fmt.Errorf("%s and %s have the same UUID %s", other, dir, manifest.Header.UUID)
+		}
+		seen[uuid] = dir
+		packs = append(packs, builtPack{dir: abs, manifest: manifest})
+	}
+
+	result := &BuildResult{Output: output, Packs: make([]BuiltPack, 0, len(packs))}
+	for _, pack := range packs {
+		result.Packs = append(result.Packs, BuiltPack{
+			PackID:    pack.manifest.Header.UUID,
+			Name:      pack.manifest.GetDisplayName(),
+			Type:      pack.manifest.GetPackType(),
+			Version:   pack.manifest.Header.Version,
+			Directory: pack.dir,
+		})
+	}
+
+	if options.Bump != BumpNone {
+		if err := bumpPacks(packs, result, options); err != nil {
+			return nil, err
+		}
+	}
+
+	folders := make(map[string]bool)
+	for i := range result.Packs {
+		if ext == ".mcaddon" {
+			result.Packs[i].File = uniqueFolder(folders, filepath.Base(result.Packs[i].Directory)) + ".mcpack"
+		}
+	}
+	if options.DryRun {
+		return result, nil
+	}
+
+	if ext == ".mcpack" {
+		return result, filesystem.CreateArchive(output, []filesystem.ArchiveDir{{Source: packs[0].dir, Skip: skipDevelopmentFile}})
+	}
+
+	// Each pack becomes a .mcpack of its own, staged next to the output so the
+	// final .mcaddon holds them at its root
+	staging, err := os.MkdirTemp(filepath.Dir(absOutput), ".blockbench-build-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	for i, pack := range packs {
+		packPath := filepath.Join(staging, result.Packs[i].File)
+		if err := filesystem.CreateArchive(packPath, []filesystem.ArchiveDir{{Source: pack.dir, Skip: skipDevelopmentFile}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := filesystem.CreateArchive(output, []filesystem.ArchiveDir{{Source: staging}}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// bumpPacks increments every pack's version in its manifest, updating the
+// dependencies between the packs to match
+func bumpPacks(packs []builtPack, result *BuildResult, options BuildOptions) error {
+	files := make([]*minecraft.ManifestFile, len(packs))
+	for i, pack := range packs {
+		file, err := minecraft.OpenManifestFile(filepath.Join(pack.dir, "manifest.json"))
+		if err != nil {
+			return err
+		}
+		files[i] = file
+
+		previous := pack.manifest.Header.Version
+		version := options.Bump.Apply(previous)
+		if _, err := file.SetVersion(version); err != nil {
+			return err
+		}
+		result.Packs[i].PreviousVersion = &previous
+		result.Packs[i].Version = version
+	}
+
+	for _, file := range files {
+		for _, pack := range result.Packs {
+			file.SetDependencyVersion(pack.PackID, *pack.PreviousVersion, pack.Version)
+		}
+	}
+	if options.DryRun {
+		return nil
+	}
+	for _, file := range files {
+		if err := file.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipDevelopmentFile leaves files only used while developing a pack out of
+// its archive: hidden files, node_modules, and npm's package files
+func skipDevelopmentFile(rel string, entry fs.DirEntry) bool {
+	name := path.Base(rel)
+	switch {
+	case strings.HasPrefix(name, "."):
+		return true
+	case entry.IsDir():
+		return name == "node_modules"
+	default:
+		return name == "package.json" || name == "package-lock.json"
+	}
+}
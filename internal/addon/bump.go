@@ -0,0 +1,177 @@
+package addon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// BumpOptions controls BumpVersion
+type BumpOptions struct {
+	Part          BumpPart // The part to increment, when Set is nil
+	Set           *[3]int  // An explicit new version, which must be newer than the current one
+	Changelog     []string // Entries to record under the new version in ChangelogFile
+	ChangelogFile string   // Default: CHANGELOG.md in the pack directory
+	DryRun        bool     // Report without writing anything
+}
+
+// BumpResult describes the version change BumpVersion made
+type BumpResult struct {
+	Directory       string             `json:"directory"`
+	PackID          string             `json:"pack_id"`
+	Name            string             `json:"name"`
+	Type            minecraft.PackType `json:"type"`
+	PreviousVersion [3]int             `json:"previous_version"`
+	Version         [3]int             `json:"version"`
+	Modules         int                `json:"modules"`              // Modules whose version changed
+	Dependents      []string           `json:"dependents,omitempty"` // Sibling packs whose dependency on the pack was updated
+	Changelog       string             `json:"changelog,omitempty"`  // The changelog the entries were added to
+}
+
+// BumpVersion sets a pack's new version in its manifest.json: the header
+// version and the version of every module, which are kept equal. The
+// dependencies of sibling packs in the same parent directory on the old
+// version are updated to the new one. A version that is not newer than the
+// current one is an error. With options.Changelog, the entries are added to
+// the changelog under a heading for the new version, above the previous
+// releases.
+func BumpVersion(dir string, options BumpOptions) (*BumpResult, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := loadDevManifest(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := manifest.Header.Version
+	version := options.Part.Apply(previous)
+	if options.Set != nil {
+		version = *options.Set
+	}
+	if validation.CompareVersions(version, previous) <= 0 {
+		return nil, fmt.Errorf("version %s is not newer than the current version %s of %s",
+			formatVersion(version), formatVersion(previous), manifest.GetDisplayName())
+	}
+
+	file, err := minecraft.OpenManifestFile(filepath.Join(abs, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.SetVersion(version); err != nil {
+		return nil, err
+	}
+	result := &BumpResult{
+		Directory:       abs,
+		PackID:          manifest.Header.UUID,
+		Name:            manifest.GetDisplayName(),
+		Type:            manifest.GetPackType(),
+		PreviousVersion: previous,
+		Version:         version,
+		Modules:         file.SetModuleVersions(version),
+	}
+	files := []*minecraft.ManifestFile{file}
+
+	for _, sibling := range packSubdirs(filepath.Dir(abs)) {
+		if sibling == abs {
+			continue
+		}
+		siblingFile, err := minecraft.OpenManifestFile(filepath.Join(sibling, "manifest.json"))
+		if err != nil {
+			return nil, err
+		}
+		if siblingFile.SetDependencyVersion(manifest.Header.UUID, previous, version) > 0 {
+			files = append(files, siblingFile)
+			result.Dependents = append(result.Dependents, sibling)
+		}
+	}
+
+	if len(options.Changelog) > 0 {
+		result.Changelog = options.ChangelogFile
+		if result.Changelog == "" {
+			result.Changelog = filepath.Join(abs, "CHANGELOG.md")
+		}
+	}
+	if options.DryRun {
+		return result, nil
+	}
+
+	for _, file := range files {
+		if err := file.Save(); err != nil {
+			return nil, err
+		}
+	}
+	if result.Changelog != "" {
+		if err := addChangelogEntries(result.Changelog, version, options.Changelog, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// addChangelogEntries adds a "## [version] - date" section with the entries
+// to a changelog, above its first release section and below an Unreleased
+// one, creating the changelog if it does not exist
+func addChangelogEntries(path string, version [3]int, entries []string, date time.Time) error {
+	// #nosec G304 - path is a changelog the user pointed at
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read changelog: %w", err)
+	}
+	text := string(data)
+	if strings.TrimSpace(text) == "" {
+		text = "# Changelog\n"
+	}
+
+	var section strings.Builder
+	fmt.Fprintf(&section, "## [%s] - %s\n\n", formatVersion(version), date.Format("2006-01-02"))
+	for _, entry := range entries {
+		fmt.Fprintf(&section, "- %s\n", entry)
+	}
+	section.WriteString("\n")
+
+	lines := strings.SplitAfter(text, "\n")
+	insertAt := -1
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(line), "## [unreleased]") {
+			continue
+		}
+		insertAt = i
+		break
+	}
+
+	var out strings.Builder
+	if insertAt < 0 {
+		out.WriteString(strings.TrimRight(text, "\n"))
+		out.WriteString("\n\n")
+		out.WriteString(section.String())
+	} else {
+		for _, line := range lines[:insertAt] {
+			out.WriteString(line)
+		}
+		out.WriteString(section.String())
+		for _, line := range lines[insertAt:] {
+			out.WriteString(line)
+		}
+	}
+	result := strings.TrimRight(out.String(), "\n") + "\n"
+	if err := os.WriteFile(path, []byte(result), filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
+
+// formatVersion formats a version array as major.minor.patch
+func formatVersion(version [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
@@ -0,0 +1,122 @@
+package addon
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// CleanOptions contains options for removing unreferenced pack directories
+type CleanOptions struct {
+	DryRun     bool
+	Verbose    bool
+	PathPolicy *filesystem.PathPolicy // When set, the removal fails before any change if it would write outside the allowed paths
+}
+
+// CleanResult contains the result of removing unreferenced pack directories
+type CleanResult struct {
+	Success        bool                         `json:"success"`
+	Packs          []minecraft.UnreferencedPack `json:"packs"`     // The packs removed, or that a dry run would remove
+	Reclaimed      int64                        `json:"reclaimed"` // Bytes in the removed directories
+	BackupMetadata *filesystem.BackupMetadata   `json:"backup,omitempty"`
+	RolledBack     bool                         `json:"rolled_back,omitempty"` // The backup was restored after a removal failed
+}
+
+// Cleaner removes pack directories no world config refers to
+type Cleaner struct {
+	server        *minecraft.Server
+	backupManager *BackupManager
+}
+
+// NewCleaner creates a new cleaner
+func NewCleaner(server *minecraft.Server, backupDir string) *Cleaner {
+	return &Cleaner{
+		server:        server,
+		backupManager: NewBackupManager(server, backupDir),
+	}
+}
+
+// ReclaimableSize returns the bytes removing packs reclaims
+func ReclaimableSize(packs []minecraft.UnreferencedPack) int64 {
+	var size int64
+	for _, pack := range packs {
+		size += pack.Size
+	}
+	return size
+}
+
+// RemoveUnreferenced backs up the directories of packs, as listed by
+// Server.UnreferencedPacks, and removes them. When a removal fails, the
+// backup is restored. The removal is recorded in the history as an
+// uninstall, so 'undo' restores the directories.
+func (c *Cleaner) RemoveUnreferenced(packs []minecraft.UnreferencedPack, options CleanOptions) (*CleanResult, error) {
+	result, err := c.removeUnreferenced(packs, options)
+	if !options.DryRun && len(packs) > 0 {
+		recordHistory(c.server, cleanHistoryEntry(result, err))
+	}
+	return result, err
+}
+
+func (c *Cleaner) removeUnreferenced(packs []minecraft.UnreferencedPack, options CleanOptions) (*CleanResult, error) {
+	result := &CleanResult{Packs: packs, Reclaimed: ReclaimableSize(packs)}
+	if len(packs) == 0 {
+		result.Success = true
+		return result, nil
+	}
+
+	// Verify every planned write before anything changes, dry run included
+	if options.PathPolicy != nil {
+		writes := []filesystem.PlannedWrite{{Operation: "create backup", Path: c.backupManager.BackupRoot}}
+		for _, pack := range packs {
+			writes = append(writes, filesystem.PlannedWrite{Operation: "remove pack files", Path: pack.Dir})
+		}
+		if err := options.PathPolicy.Check(writes); err != nil {
+			return result, err
+		}
+	}
+
+	if options.DryRun {
+		result.Success = true
+		return result, nil
+	}
+
+	if options.Verbose {
+		fmt.Println("Creating backup before removing unreferenced packs...")
+	}
+	backup, err := c.backupManager.CreateCleanBackup(packs)
+	if err != nil {
+		return result, fmt.Errorf("backup creation failed: %w", err)
+	}
+	result.BackupMetadata = backup
+
+	for _, pack := range packs {
+		if err := c.server.RemoveUnreferencedPack(pack); err != nil {
+			slog.Warn("Rolling back failed removal of unreferenced packs", "backup", backup.ID, "error", err)
+			if restoreErr := c.backupManager.RestoreBackup(backup.ID); restoreErr != nil {
+				slog.Error("Rollback failed", "backup", backup.ID, "error", restoreErr)
+				return result, rollbackFailed(err, restoreErr)
+			}
+			result.RolledBack = true
+			return result, err
+		}
+		if options.Verbose {
+			fmt.Printf("Removed %s\n", pack.Dir)
+		}
+		slog.Info("Removed unreferenced pack", "uuid", pack.PackID, "name", pack.Name, "path", pack.Dir)
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// HookPacks describes the removed packs as hook, history, and webhook packs
+func (r *CleanResult) HookPacks() []hooks.Pack {
+	packs := make([]hooks.Pack, len(r.Packs))
+	for i, pack := range r.Packs {
+		packs[i] = hooks.Pack{UUID: pack.PackID, Name: pack.Name, Type: string(pack.Type), Version: pack.Version}
+	}
+	return packs
+}
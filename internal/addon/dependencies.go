@@ -2,8 +2,7 @@ package addon
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"log/slog"
 
 	"github.com/makutaku/blockbench/internal/minecraft"
 	"github.com/makutaku/blockbench/pkg/validation"
@@ -72,8 +71,8 @@ func (da *DependencyAnalyzer) AnalyzeDependencies() (*DependencyGroup, error) {
 		if err != nil {
 			// If we can't analyze a pack, treat it as standalone
 			// This can happen if the manifest is corrupted or missing
-			fmt.Fprintf(os.Stderr, "Warning: Could not analyze pack %s (%s): %v\n", pack.Name, pack.PackID, err)
-			fmt.Fprintf(os.Stderr, "  Treating pack as standalone (no dependencies)\n")
+			slog.Warn("Could not analyze pack; treating it as standalone (no dependencies)",
+				"pack", pack.Name, "uuid", pack.PackID, "error", err)
 			rel = &PackRelationship{
 				Pack:         pack,
 				Dependencies: []string{},
@@ -114,9 +113,8 @@ func (da *DependencyAnalyzer) buildPackRelationship(pack minecraft.InstalledPack
 			// Pack dependency - validate and normalize UUID
 			if !validation.ValidateUUID(dep.UUID) {
 				// Log warning but don't fail - manifest is already installed
-				fmt.Fprintf(os.Stderr, "Warning: Invalid dependency UUID format '%s' in pack %s\n",
-					dep.UUID, pack.PackID)
-				fmt.Fprintf(os.Stderr, "  Skipping this dependency in analysis\n")
+				slog.Warn("Invalid dependency UUID format; skipping this dependency in analysis",
+					"dependency", dep.UUID, "uuid", pack.PackID)
 				continue
 			}
 			normalizedUUID := validation.NormalizeUUID(dep.UUID)
@@ -131,44 +129,13 @@ func (da *DependencyAnalyzer) buildPackRelationship(pack minecraft.InstalledPack
 	return rel, nil
 }
 
-// loadPackManifest loads a manifest for an installed pack
+// loadPackManifest loads a manifest for an installed pack from the server's manifest index
 func (da *DependencyAnalyzer) loadPackManifest(packID string, packType minecraft.PackType) (*minecraft.Manifest, error) {
-	var baseDir string
-	switch packType {
-	case minecraft.PackTypeBehavior:
-		baseDir = da.server.Paths.BehaviorPacksDir
-	case minecraft.PackTypeResource:
-		baseDir = da.server.Paths.ResourcePacksDir
-	default:
-		return nil, fmt.Errorf("unknown pack type: %s", packType)
-	}
-
-	// Find the pack directory by looking for the UUID in manifest files
-	packPath, err := da.findPackDirectory(baseDir, packID)
+	manifest, err := da.server.FindAndLoadManifestByUUID(packID, packType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find pack directory: %w", err)
 	}
-
-	manifestPath := filepath.Join(packPath, "manifest.json")
-	return minecraft.ParseManifest(manifestPath)
-}
-
-// findPackDirectory finds the directory path for a pack by UUID
-func (da *DependencyAnalyzer) findPackDirectory(baseDir, packID string) (string, error) {
-	// Use the existing findPackDirectory logic from simulator
-	simulator := NewDryRunSimulator(da.server)
-
-	// Try behavior pack directory first
-	if path, err := simulator.findPackDirectory(packID, minecraft.PackTypeBehavior); err == nil {
-		return path, nil
-	}
-
-	// Then try resource pack directory
-	if path, err := simulator.findPackDirectory(packID, minecraft.PackTypeResource); err == nil {
-		return path, nil
-	}
-
-	return "", fmt.Errorf("pack directory not found for UUID: %s", packID)
+	return manifest, nil
 }
 
 // calculateDependents builds reverse dependency relationships
@@ -0,0 +1,163 @@
+package addon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// DevSession keeps an installed pack in sync with its source directory, for
+// the developer loop of 'blockbench dev'
+type DevSession struct {
+	server      *minecraft.Server
+	source      string
+	manifest    *minecraft.Manifest
+	target      string
+	fingerprint string
+}
+
+// DevSync is the outcome of one DevSession.Sync
+type DevSync struct {
+	filesystem.SyncResult
+	PreviousVersion *[3]int `json:"previous_version,omitempty"` // Set when the manifest version changed
+	Version         [3]int  `json:"version"`
+}
+
+// NewDevSession starts a session for the pack in source, which must already
+// be installed on the server as a copy; a linked pack needs no syncing
+func NewDevSession(server *minecraft.Server, source string) (*DevSession, error) {
+	manifest, err := loadDevManifest(source)
+	if err != nil {
+		return nil, err
+	}
+	target, err := server.FindPackDirectory(manifest.Header.UUID, manifest.GetPackType())
+	if err != nil {
+		return nil, fmt.Errorf("pack %s is not installed on this server: %w", manifest.GetDisplayName(), err)
+	}
+	if link, ok := minecraft.LinkedPackTarget(target); ok {
+		return nil, fmt.Errorf("pack %s is installed as a link to %s, so its changes need no syncing", manifest.GetDisplayName(), link)
+	}
+	return &DevSession{server: server, source: source, manifest: manifest, target: target}, nil
+}
+
+// Manifest returns the manifest the installed pack was last synced with
+func (d *DevSession) Manifest() *minecraft.Manifest {
+	return d.manifest
+}
+
+// Target returns the installed pack directory the session syncs into
+func (d *DevSession) Target() string {
+	return d.target
+}
+
+// Changed reports whether the source has changed since the last call, by the
+// paths, sizes, and modification times of its files
+func (d *DevSession) Changed() (bool, error) {
+	hasher := sha256.New()
+	err := filepath.WalkDir(d.source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.source, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skipDevelopmentFile(filepath.ToSlash(rel), entry) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hasher, "%s\x00%d\x00%d\x00", filepath.ToSlash(rel), info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to scan %s: %w", d.source, err)
+	}
+	fingerprint := hex.EncodeToString(hasher.Sum(nil))
+	changed := fingerprint != d.fingerprint
+	d.fingerprint = fingerprint
+	return changed, nil
+}
+
+// Sync re-validates the source manifest and copies the files that changed
+// into the installed pack directory, removing the ones that are gone. A new
+// manifest version is recorded in the world config; a different UUID or pack
+// type is an error, since that is a different pack.
+func (d *DevSession) Sync() (*DevSync, error) {
+	manifest, err := loadDevManifest(d.source)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(manifest.Header.UUID, d.manifest.Header.UUID) || manifest.GetPackType() != d.manifest.GetPackType() {
+		return nil, fmt.Errorf("the manifest now describes another pack (%s %s); restart blockbench dev to install it",
+			manifest.GetPackType(), manifest.Header.UUID)
+	}
+
+	result, err := filesystem.SyncDir(d.source, d.target, skipDevelopmentFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync %s: %w", d.target, err)
+	}
+	synced := &DevSync{SyncResult: *result, Version: manifest.Header.Version}
+
+	if previous := d.manifest.Header.Version; previous != manifest.Header.Version {
+		if err := d.setConfigVersion(manifest); err != nil {
+			return nil, err
+		}
+		synced.PreviousVersion = &previous
+	}
+	if !result.Empty() {
+		d.server.InvalidatePacks()
+		if err := d.server.RecordChecksums(manifest, d.target); err != nil {
+			return nil, err
+		}
+	}
+	d.manifest = manifest
+	return synced, nil
+}
+
+// setConfigVersion records the pack's new version in its world config
+func (d *DevSession) setConfigVersion(manifest *minecraft.Manifest) error {
+	configFile, err := d.server.Paths.WorldConfigFor(manifest.GetPackType())
+	if err != nil {
+		return err
+	}
+	config, err := minecraft.LoadWorldConfigFS(d.server.FS, configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !config.HasPack(manifest.Header.UUID) {
+		return nil
+	}
+	config = minecraft.AddPackToConfig(config, manifest.Header.UUID, manifest.Header.Version)
+	if err := minecraft.SaveWorldConfigFS(d.server.FS, configFile, config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	d.server.InvalidatePacks()
+	return nil
+}
+
+// loadDevManifest reads and validates the manifest of a pack source directory
+func loadDevManifest(source string) (*minecraft.Manifest, error) {
+	manifest, err := minecraft.ParseManifest(filepath.Join(source, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err := minecraft.ValidateManifest(manifest); err != nil {
+		return nil, fmt.Errorf("manifest validation failed: %w", err)
+	}
+	if manifest.GetPackType() == minecraft.PackTypeUnknown {
+		return nil, fmt.Errorf("manifest has no data or resources module")
+	}
+	return manifest, nil
+}
@@ -0,0 +1,134 @@
+package addon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// EntryChange is a manifest dependency or module that an update adds,
+// removes, or changes. From is empty for additions and To for removals.
+type EntryChange struct {
+	Key  string `json:"key"` // Pack UUID or module name for dependencies, module UUID for modules
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// PackDiff is how a new version of a pack differs from the installed one
+type PackDiff struct {
+	UUID             string              `json:"uuid"`
+	Name             string              `json:"name"`
+	Type             minecraft.PackType  `json:"type"`
+	InstalledVersion [3]int              `json:"installed_version"`
+	NewVersion       [3]int              `json:"new_version"`
+	Dependencies     []EntryChange       `json:"dependencies"`
+	Modules          []EntryChange       `json:"modules"`
+	Files            filesystem.TreeDiff `json:"files"`
+}
+
+// Empty reports whether the new version is identical to the installed one
+func (d *PackDiff) Empty() bool {
+	return d.InstalledVersion == d.NewVersion && len(d.Dependencies) == 0 && len(d.Modules) == 0 && d.Files.Empty()
+}
+
+// DiffPack compares an installed pack with the pack of the same UUID in an
+// addon file: its version, manifest dependencies and modules, and every file
+// by SHA-256
+func DiffPack(server *minecraft.Server, identifier string, byUUID bool, addonPath string, limits filesystem.ExtractLimits) (*PackDiff, error) {
+	installed, err := FindInstalledPack(server, identifier, byUUID)
+	if err != nil {
+		return nil, err
+	}
+	installedDir, err := server.FindPackDirectory(installed.PackID, installed.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate installed pack %s: %w", installed.Name, err)
+	}
+	installedManifest, err := server.FindAndLoadManifestByUUID(installed.PackID, installed.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest of installed pack %s: %w", installed.Name, err)
+	}
+
+	extracted, err := ExtractAddon(addonPath, false, limits, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract addon: %w", err)
+	}
+	defer extracted.Cleanup()
+
+	var newPack *ExtractedPack
+	var contained []string
+	for _, pack := range extracted.GetAllPacks() {
+		contained = append(contained, fmt.Sprintf("%s (%s)", pack.Manifest.GetDisplayName(), pack.Manifest.Header.UUID))
+		if pack.Manifest.Header.UUID == installed.PackID {
+			newPack = pack
+		}
+	}
+	if newPack == nil {
+		return nil, fmt.Errorf("%s has no pack with UUID %s; it contains %s", addonPath, installed.PackID, strings.Join(contained, ", "))
+	}
+
+	installedFiles, err := filesystem.HashTree(installedDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := filesystem.HashTree(newPack.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PackDiff{
+		UUID:             installed.PackID,
+		Name:             installedManifest.GetDisplayName(),
+		Type:             installed.Type,
+		InstalledVersion: installedManifest.Header.Version,
+		NewVersion:       newPack.Manifest.Header.Version,
+		Dependencies:     diffEntries(dependencyEntries(installedManifest), dependencyEntries(newPack.Manifest)),
+		Modules:          diffEntries(moduleEntries(installedManifest), moduleEntries(newPack.Manifest)),
+		Files:            filesystem.DiffHashes(installedFiles, newFiles),
+	}, nil
+}
+
+// dependencyEntries describes the dependencies of a manifest by pack UUID or
+// module name
+func dependencyEntries(manifest *minecraft.Manifest) map[string]string {
+	entries := make(map[string]string)
+	for _, dep := range manifest.Dependencies {
+		if dep.ModuleName != "" {
+			entries[dep.ModuleName] = dep.ModuleVersion
+		} else if dep.UUID != "" {
+			entries[dep.UUID] = fmt.Sprintf("%d.%d.%d", dep.Version[0], dep.Version[1], dep.Version[2])
+		}
+	}
+	return entries
+}
+
+// moduleEntries describes the modules of a manifest by module UUID
+func moduleEntries(manifest *minecraft.Manifest) map[string]string {
+	entries := make(map[string]string)
+	for _, module := range manifest.Modules {
+		entries[module.UUID] = fmt.Sprintf("%s %d.%d.%d", module.Type, module.Version[0], module.Version[1], module.Version[2])
+	}
+	return entries
+}
+
+// diffEntries lists the entries added, removed, or changed between two
+// manifests, sorted by key
+func diffEntries(from, to map[string]string) []EntryChange {
+	changes := make([]EntryChange, 0)
+	for key, value := range from {
+		if toValue, ok := to[key]; !ok {
+			changes = append(changes, EntryChange{Key: key, From: value})
+		} else if toValue != value {
+			changes = append(changes, EntryChange{Key: key, From: value, To: toValue})
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes = append(changes, EntryChange{Key: key, To: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
@@ -0,0 +1,242 @@
+package addon
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// maxManifestSize bounds how much of a manifest.json entry is read during the pre-scan
+const maxManifestSize = 1024 * 1024
+
+// archivePack locates a pack inside an open archive for direct installation
+type archivePack struct {
+	files  []*zip.File // Every entry below prefix
+	prefix string      // Directory of the pack's manifest.json inside the archive, with trailing slash
+}
+
+// InArchive reports whether the pack is read straight from its archive rather
+// than from extracted files; Path is then only a display name.
+func (ep *ExtractedPack) InArchive() bool {
+	return ep.archive != nil
+}
+
+// ScanAddonArchive pre-scans the manifests of a .mcaddon or .mcpack without
+// extracting it. The returned packs are installed by streaming their files
+// straight from the archive into the server pack directories, so each file is
+// written to disk once. Nested .mcpack files, and .zip files holding packs,
+// are copied to a temporary directory (compressed) and scanned the same way. Cleanup closes the archives.
+// progress, if not nil, receives the bytes written for each pack.
+func ScanAddonArchive(addonPath string, dryRun bool, limits filesystem.ExtractLimits, progress filesystem.Progress) (*ExtractedAddon, error) {
+	ext := strings.ToLower(filepath.Ext(addonPath))
+	if ext != ".mcaddon" && ext != ".mcpack" {
+		return nil, fmt.Errorf("unsupported file type: %s (expected .mcaddon or .mcpack)", ext)
+	}
+
+	if err := filesystem.ValidateArchive(addonPath); err != nil {
+		return nil, fmt.Errorf("archive validation failed: %w", err)
+	}
+
+	addon := &ExtractedAddon{
+		BehaviorPacks: make([]*ExtractedPack, 0),
+		ResourcePacks: make([]*ExtractedPack, 0),
+		IsDryRun:      dryRun,
+		extractor:     filesystem.NewExtractor(limits),
+	}
+	addon.extractor.Progress = progress
+
+	if err := addon.scanArchive(addonPath, filepath.Base(addonPath), 0); err != nil {
+		if cleanupErr := addon.Cleanup(); cleanupErr != nil {
+			slog.Warn("Failed to cleanup temporary files", "error", cleanupErr)
+		}
+		return nil, err
+	}
+
+	if len(addon.GetAllPacks()) == 0 {
+		if cleanupErr := addon.Cleanup(); cleanupErr != nil {
+			slog.Warn("Failed to cleanup temporary files", "error", cleanupErr)
+		}
+		return nil, fmt.Errorf("no manifest.json files found in archive")
+	}
+
+	return addon, nil
+}
+
+// scanArchive registers the packs of one archive and recurses into nested
+// .mcpack entries and .zip entries that hold packs
+func (ea *ExtractedAddon) scanArchive(archivePath, displayName string, depth int) error {
+	// Maximum nesting depth to prevent infinite loops from malicious archives
+	const maxDepth = 10
+	if depth > maxDepth {
+		return fmt.Errorf("exceeded maximum nesting depth (%d) for mcpack extraction - possible malformed or malicious archive", maxDepth)
+	}
+
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive %s: %w", displayName, err)
+	}
+	ea.archives = append(ea.archives, reader)
+
+	var prefixes []string
+	var packs []*ExtractedPack
+	nested := make(map[string]bool) // Entries scanned as nested archives of packs
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		name := strings.ToLower(path.Base(file.Name))
+		switch {
+		case name == "manifest.json":
+			pack, err := scanArchiveManifest(file, displayName)
+			if err != nil {
+				return err
+			}
+			pack.archive = &archivePack{prefix: entryDir(file.Name)}
+			prefixes = append(prefixes, pack.archive.prefix)
+			packs = append(packs, pack)
+
+		case isNestedArchiveName(name):
+			nestedPath, err := ea.copyNestedArchive(file)
+			if err != nil {
+				return fmt.Errorf("failed to copy nested mcpack %s: %w", file.Name, err)
+			}
+			if strings.HasSuffix(name, ".zip") {
+				holdsPacks, err := archiveHoldsPacks(nestedPath)
+				if err != nil {
+					return fmt.Errorf("failed to inspect nested archive %s: %w", file.Name, err)
+				}
+				if !holdsPacks {
+					continue // Ordinary pack content
+				}
+			}
+			nested[file.Name] = true
+			if err := ea.scanArchive(nestedPath, displayName+"/"+file.Name, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Each entry belongs to the pack with the longest matching prefix; nested
+	// archives of packs are installed as their own packs, never as pack content
+	sort.Sort(sort.Reverse(sort.StringSlice(prefixes)))
+	byPrefix := make(map[string]*ExtractedPack, len(packs))
+	for _, pack := range packs {
+		byPrefix[pack.archive.prefix] = pack
+		ea.addPack(pack)
+	}
+	for _, file := range reader.File {
+		if nested[file.Name] {
+			continue
+		}
+		if pack, ok := byPrefix[owningPrefix(file.Name, prefixes)]; ok {
+			pack.archive.files = append(pack.archive.files, file)
+		}
+	}
+
+	return nil
+}
+
+// entryDir returns the directory of an archive entry with a trailing slash, or "" at the root
+func entryDir(name string) string {
+	dir := path.Dir(name)
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
+}
+
+// scanArchiveManifest parses and validates a manifest.json entry
+func scanArchiveManifest(file *zip.File, displayName string) (*ExtractedPack, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	manifest, err := minecraft.ParseManifestFromReader(io.LimitReader(rc, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to process manifest %s: %w", file.Name, err)
+	}
+	if err := minecraft.ValidateManifest(manifest); err != nil {
+		return nil, fmt.Errorf("failed to process manifest %s: manifest validation failed: %w", file.Name, err)
+	}
+
+	packType := manifest.GetPackType()
+	if packType == minecraft.PackTypeUnknown {
+		return nil, fmt.Errorf("failed to process manifest %s: unable to determine pack type from manifest", file.Name)
+	}
+
+	return &ExtractedPack{
+		Path:     displayName + ":" + path.Dir(file.Name),
+		Manifest: manifest,
+		PackType: packType,
+	}, nil
+}
+
+// owningPrefix returns the longest pack prefix containing name; prefixes must be sorted longest first
+func owningPrefix(name string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return prefix
+		}
+	}
+	return "" // Not inside any pack unless a pack sits at the archive root
+}
+
+// addPack files a scanned pack under its type
+func (ea *ExtractedAddon) addPack(pack *ExtractedPack) {
+	if pack.PackType == minecraft.PackTypeBehavior {
+		ea.BehaviorPacks = append(ea.BehaviorPacks, pack)
+	} else {
+		ea.ResourcePacks = append(ea.ResourcePacks, pack)
+	}
+}
+
+// copyNestedArchive copies a nested .mcpack entry, still compressed, into the temporary directory
+func (ea *ExtractedAddon) copyNestedArchive(file *zip.File) (string, error) {
+	if ea.TempDir == "" {
+		tempDir, err := os.MkdirTemp("", "blockbench_direct_*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		ea.TempDir = tempDir
+	}
+
+	// The nested archive counts against the extraction limits like any other file
+	dir, err := os.MkdirTemp(ea.TempDir, "nested_*")
+	if err != nil {
+		return "", err
+	}
+	if err := ea.extractor.ExtractFiles([]*zip.File{file}, entryDir(file.Name), dir); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, path.Base(file.Name)), nil
+}
+
+// writeArchivePack returns a PackWriter that streams a pack's entries from its archive
+func (ea *ExtractedAddon) writeArchivePack(pack *ExtractedPack) minecraft.PackWriter {
+	return func(targetDir string) error {
+		return ea.extractor.ExtractFiles(pack.archive.files, pack.archive.prefix, targetDir)
+	}
+}
+
+// archiveScriptFiles lists the .js entries of an archive-backed pack, relative to the pack root
+func archiveScriptFiles(pack *ExtractedPack) []string {
+	var files []string
+	for _, file := range pack.archive.files {
+		if !file.FileInfo().IsDir() && strings.EqualFold(path.Ext(file.Name), ".js") {
+			files = append(files, strings.TrimPrefix(file.Name, pack.archive.prefix))
+		}
+	}
+	return files
+}
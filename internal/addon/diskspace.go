@@ -0,0 +1,61 @@
+package addon
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// packInstallSize returns the bytes and number of files installing a pack
+// writes: the declared sizes of its archive entries for direct installation,
+// and the size of its extracted directory otherwise
+func packInstallSize(pack *ExtractedPack) (int64, int64, error) {
+	var bytes, files int64
+	if pack.InArchive() {
+		for _, file := range pack.archive.files {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			bytes += int64(file.UncompressedSize64) // #nosec G115 - bounded by the extraction limits
+			files++
+		}
+		return bytes, files, nil
+	}
+
+	err := filepath.WalkDir(pack.Path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		bytes += info.Size()
+		files++
+		return nil
+	})
+	return bytes, files, err
+}
+
+// checkDiskSpace fails the install before anything is copied when the
+// server's volume can't hold the addon's packs, rather than running out of
+// space halfway through the copy and rolling back. Linked packs take no room.
+func (i *Installer) checkDiskSpace(addon *ExtractedAddon, link bool) error {
+	if link {
+		return nil
+	}
+	var bytes, files int64
+	for _, pack := range addon.GetAllPacks() {
+		packBytes, packFiles, err := packInstallSize(pack)
+		if err != nil {
+			return err
+		}
+		bytes += packBytes
+		files += packFiles
+	}
+	return filesystem.CheckFreeSpace(i.server.Paths.ServerRoot, bytes, files)
+}
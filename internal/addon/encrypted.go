@@ -0,0 +1,99 @@
+package addon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// decryptPacks finds the addon's encrypted Marketplace packs and decrypts
+// each with its key from keys, returning a line for each pack decrypted.
+// Without a key for an encrypted pack, the install fails with an
+// EncryptedPackError. Packs of an unpacked addon directory are copied to a
+// temporary directory first, so the source stays as it is; for the same
+// reason, encrypted packs can't be linked.
+func decryptPacks(extractedAddon *ExtractedAddon, keys *minecraft.ContentKeys, link bool) ([]string, error) {
+	var details []string
+	for _, pack := range extractedAddon.GetAllPacks() {
+		contentID, encrypted, err := packContentID(pack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read contents.json of %s: %w", pack.Manifest.GetDisplayName(), err)
+		}
+		if !encrypted {
+			continue
+		}
+
+		key, ok := keys.For(contentID, pack.Manifest.Header.UUID)
+		if !ok {
+			err := &minecraft.EncryptedPackError{Pack: pack.Manifest.GetDisplayName(), ContentID: contentID}
+			return nil, fmt.Errorf("%w; if you hold a license for it, pass its content key with --content-key", err)
+		}
+		if link {
+			return nil, fmt.Errorf("%s is encrypted and is installed as a decrypted copy, so it can't be linked", pack.Manifest.GetDisplayName())
+		}
+		if pack.InArchive() {
+			return nil, fmt.Errorf("%s is encrypted and must be extracted to be decrypted, so it can't be installed with direct installation", pack.Manifest.GetDisplayName())
+		}
+		if extractedAddon.TempDir == "" || !isWithin(pack.Path, extractedAddon.TempDir) {
+			if err := copyPackToTemp(extractedAddon, pack); err != nil {
+				return nil, err
+			}
+		}
+		if err := minecraft.DecryptPack(pack.Path, key); err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", pack.Manifest.GetDisplayName(), err)
+		}
+		details = append(details, fmt.Sprintf("Decrypted %s (content ID %s) with its content key", pack.Manifest.GetDisplayName(), contentID))
+	}
+	return details, nil
+}
+
+// packContentID reports whether a pack is encrypted, reading its
+// contents.json from disk or from its archive
+func packContentID(pack *ExtractedPack) (string, bool, error) {
+	if !pack.InArchive() {
+		return minecraft.EncryptedContentID(pack.Path)
+	}
+	for _, file := range pack.archive.files {
+		if file.Name != pack.archive.prefix+"contents.json" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return "", false, err
+		}
+		defer rc.Close()
+		return minecraft.ReadEncryptedContentID(rc)
+	}
+	return "", false, nil
+}
+
+// copyPackToTemp copies a pack into the addon's temporary directory, creating
+// it when needed, and points the pack at the copy
+func copyPackToTemp(extractedAddon *ExtractedAddon, pack *ExtractedPack) error {
+	if extractedAddon.TempDir == "" {
+		tempDir, err := os.MkdirTemp("", "blockbench_decrypt_*")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		extractedAddon.TempDir = tempDir
+	}
+	copyDir, err := os.MkdirTemp(extractedAddon.TempDir, "decrypted_*")
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(copyDir, filepath.Base(pack.Path))
+	if _, err := filesystem.SyncDir(pack.Path, target, nil); err != nil {
+		return fmt.Errorf("failed to copy %s for decryption: %w", pack.Manifest.GetDisplayName(), err)
+	}
+	pack.Path = target
+	return nil
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && filepath.IsLocal(rel)
+}
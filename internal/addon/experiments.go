@@ -0,0 +1,84 @@
+package addon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/prompt"
+)
+
+// requiredExperiments returns the world experiments the addon's packs need,
+// with the names of the packs needing each
+func requiredExperiments(extractedAddon *ExtractedAddon) (keys []string, packs map[string][]string) {
+	packs = make(map[string][]string)
+	for _, pack := range extractedAddon.GetAllPacks() {
+		for _, key := range pack.Manifest.RequiredExperiments() {
+			if _, seen := packs[key]; !seen {
+				keys = append(keys, key)
+			}
+			packs[key] = append(packs[key], pack.Manifest.GetDisplayName())
+		}
+	}
+	sort.Strings(keys)
+	return keys, packs
+}
+
+// missingExperiments returns the experiments the addon needs that are off on
+// the server's world, with a warning for each. When level.dat can't be read,
+// every required experiment counts as missing.
+func (i *Installer) missingExperiments(extractedAddon *ExtractedAddon) ([]string, []string) {
+	required, packs := requiredExperiments(extractedAddon)
+	if len(required) == 0 {
+		return nil, nil
+	}
+
+	enabled, err := i.server.WorldExperiments()
+	if err != nil {
+		slog.Warn("Could not read the world's experiments", "error", err)
+	}
+	on := make(map[string]bool, len(enabled))
+	for _, key := range enabled {
+		on[key] = true
+	}
+
+	var missing, warnings []string
+	for _, key := range required {
+		if on[key] {
+			continue
+		}
+		missing = append(missing, key)
+		state := "off on this world"
+		if err != nil {
+			state = "not confirmed on: level.dat could not be read"
+		}
+		verb := "needs"
+		if len(packs[key]) > 1 {
+			verb = "need"
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %s the %s experiment, which is %s", strings.Join(packs[key], ", "), verb, minecraft.ExperimentTitle(key), state))
+	}
+	return missing, warnings
+}
+
+// confirmExperiments asks whether to turn the experiments on in level.dat.
+// A world keeps the mark of having used experiments even after they are
+// turned off again, so this is never done without a yes.
+func confirmExperiments(keys []string, options InstallOptions) (bool, error) {
+	titles := make([]string, 0, len(keys))
+	for _, key := range keys {
+		titles = append(titles, minecraft.ExperimentTitle(key))
+	}
+	prompter := options.Prompter
+	if prompter == nil {
+		prompter = prompt.Stdin()
+	}
+	confirmed, err := prompter.Confirm(fmt.Sprintf("Turn on the %s experiment(s) in the world's level.dat? The world is marked as having used experiments for good", strings.Join(titles, ", ")))
+	if errors.Is(err, prompt.ErrNoInput) {
+		return false, nil
+	}
+	return confirmed, err
+}
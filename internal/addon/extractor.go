@@ -1,8 +1,11 @@
 package addon
 
 import (
+	"archive/zip"
 	"fmt"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -16,6 +19,9 @@ type ExtractedAddon struct {
 	BehaviorPacks []*ExtractedPack
 	ResourcePacks []*ExtractedPack
 	IsDryRun      bool
+
+	archives  []*zip.ReadCloser     // Archives kept open for direct installation
+	extractor *filesystem.Extractor // Shared limits for direct installation
 }
 
 // ExtractedPack represents a single extracted pack
@@ -23,14 +29,26 @@ type ExtractedPack struct {
 	Path     string
 	Manifest *minecraft.Manifest
 	PackType minecraft.PackType
+
+	archive *archivePack // Set when the pack is installed straight from its archive
 }
 
-// Cleanup removes the temporary directory
+// Cleanup closes any open archives and removes the temporary directory
 func (ea *ExtractedAddon) Cleanup() error {
+	var firstErr error
+	for _, archive := range ea.archives {
+		if err := archive.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	ea.archives = nil
+
 	if ea.TempDir != "" {
-		return os.RemoveAll(ea.TempDir)
+		if err := os.RemoveAll(ea.TempDir); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
-	return nil
+	return firstErr
 }
 
 // GetAllPacks returns all packs (behavior and resource) in a single slice
@@ -41,8 +59,15 @@ func (ea *ExtractedAddon) GetAllPacks() []*ExtractedPack {
 	return allPacks
 }
 
-// ExtractAddon extracts a .mcaddon or .mcpack file and analyzes its contents
-func ExtractAddon(addonPath string, dryRun bool) (*ExtractedAddon, error) {
+// ExtractAddon extracts a .mcaddon or .mcpack file and analyzes its contents.
+// The limits apply to the archive and every nested .mcpack together; zero
+// fields use the defaults. Unpacked pack directories are analyzed in place without extraction.
+// progress, if not nil, receives the bytes extracted.
+func ExtractAddon(addonPath string, dryRun bool, limits filesystem.ExtractLimits, progress filesystem.Progress) (*ExtractedAddon, error) {
+	if IsAddonDirectory(addonPath) {
+		return LoadAddonDirectory(addonPath, dryRun)
+	}
+
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(addonPath))
 	if ext != ".mcaddon" && ext != ".mcpack" {
@@ -60,7 +85,7 @@ func ExtractAddon(addonPath string, dryRun bool) (*ExtractedAddon, error) {
 		return nil, fmt.Errorf("failed to analyze archive: %w", err)
 	}
 
-	if !archiveInfo.HasManifest && !archiveInfo.HasMcpackFiles {
+	if !archiveInfo.HasManifest && !archiveInfo.HasMcpackFiles && len(archiveInfo.ZipFiles) == 0 {
 		return nil, fmt.Errorf("archive does not contain any manifest.json files or .mcpack files")
 	}
 
@@ -71,33 +96,54 @@ func ExtractAddon(addonPath string, dryRun bool) (*ExtractedAddon, error) {
 	}
 
 	// Extract archive
-	if err := filesystem.ExtractArchive(addonPath, tempDir); err != nil {
+	extractor := filesystem.NewExtractor(limits)
+	extractor.Progress = progress
+	if err := extractor.Extract(addonPath, tempDir); err != nil {
 		if rmErr := os.RemoveAll(tempDir); rmErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup temp directory: %v\n", rmErr)
+			slog.Warn("Failed to cleanup temp directory", "path", tempDir, "error", rmErr)
 		}
 		return nil, fmt.Errorf("failed to extract archive: %w", err)
 	}
 
-	// Check if we need to extract nested .mcpack files (only for .mcaddon files)
-	if ext == ".mcaddon" {
-		if err := extractNestedMcpacks(tempDir); err != nil {
-			if rmErr := os.RemoveAll(tempDir); rmErr != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup temp directory: %v\n", rmErr)
-			}
-			return nil, fmt.Errorf("failed to extract nested mcpack files: %w", err)
+	// Unpack nested .mcpack files and double-zipped packs, wherever they sit
+	if err := extractNestedArchives(tempDir, extractor); err != nil {
+		if rmErr := os.RemoveAll(tempDir); rmErr != nil {
+			slog.Warn("Failed to cleanup temp directory", "path", tempDir, "error", rmErr)
 		}
+		return nil, fmt.Errorf("failed to extract nested mcpack files: %w", err)
 	}
 
 	// Analyze extracted contents
 	addon, err := analyzeExtractedAddon(tempDir)
 	if err != nil {
 		if rmErr := os.RemoveAll(tempDir); rmErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup temp directory: %v\n", rmErr)
+			slog.Warn("Failed to cleanup temp directory", "path", tempDir, "error", rmErr)
 		}
 		return nil, fmt.Errorf("failed to analyze extracted addon: %w", err)
 	}
 
 	addon.TempDir = tempDir
+	addon.IsDryRun = dryRun
+	slog.Info("Extracted addon", "addon", addonPath, "path", tempDir,
+		"behavior_packs", len(addon.BehaviorPacks), "resource_packs", len(addon.ResourcePacks))
+	return addon, nil
+}
+
+// IsAddonDirectory reports whether the addon path is an unpacked directory rather than an archive
+func IsAddonDirectory(addonPath string) bool {
+	info, err := os.Stat(addonPath)
+	return err == nil && info.IsDir()
+}
+
+// LoadAddonDirectory analyzes an unpacked addon directory containing a manifest.json
+// or multiple pack subdirectories. Packs are installed straight from the source
+// directory, so no temporary directory is created and Cleanup leaves it untouched.
+func LoadAddonDirectory(dirPath string, dryRun bool) (*ExtractedAddon, error) {
+	addon, err := analyzeExtractedAddon(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze addon directory: %w", err)
+	}
+
 	addon.IsDryRun = dryRun
 	return addon, nil
 }
@@ -184,13 +230,17 @@ func processManifest(manifestPath string) (*ExtractedPack, error) {
 	}, nil
 }
 
-// ValidateAddonFile performs pre-extraction validation on an addon file
+// ValidateAddonFile performs pre-extraction validation on an addon file or unpacked addon directory
 func ValidateAddonFile(addonPath string) error {
 	// Check if file exists
 	if _, err := os.Stat(addonPath); os.IsNotExist(err) {
 		return fmt.Errorf("addon file does not exist: %s", addonPath)
 	}
 
+	if IsAddonDirectory(addonPath) {
+		return validateAddonDirectory(addonPath)
+	}
+
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(addonPath))
 	if ext != ".mcaddon" && ext != ".mcpack" {
@@ -208,7 +258,7 @@ func ValidateAddonFile(addonPath string) error {
 		return fmt.Errorf("failed to analyze archive: %w", err)
 	}
 
-	if !info.HasManifest && !info.HasMcpackFiles {
+	if !info.HasManifest && !info.HasMcpackFiles && len(info.ZipFiles) == 0 {
 		return fmt.Errorf("archive does not contain any manifest.json files or .mcpack files")
 	}
 
@@ -219,39 +269,66 @@ func ValidateAddonFile(addonPath string) error {
 	return nil
 }
 
-// extractNestedMcpacks extracts any .mcpack files found in the directory
-// Recursively extracts nested .mcpack files up to a maximum depth to prevent infinite loops
-func extractNestedMcpacks(rootDir string) error {
+// validateAddonDirectory checks that an unpacked addon directory contains at least one manifest.json
+func validateAddonDirectory(dirPath string) error {
+	manifests, err := findManifestFiles(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to scan addon directory: %w", err)
+	}
+
+	if len(manifests) == 0 {
+		return fmt.Errorf("directory does not contain any manifest.json files: %s", dirPath)
+	}
+
+	return nil
+}
+
+// extractNestedArchives extracts the .mcpack files found at any depth in the
+// directory, and the .zip files that hold packs (some addons double-zip packs
+// as .zip instead of .mcpack). A .zip without manifests is ordinary pack
+// content and stays in place. Extraction repeats for archives nested inside
+// archives, up to a maximum depth to prevent infinite loops.
+func extractNestedArchives(rootDir string, extractor *filesystem.Extractor) error {
 	// Maximum nesting depth to prevent infinite loops from malicious archives
 	const maxIterations = 10
 
-	// Keep extracting until no more .mcpack files are found
+	content := make(map[string]bool) // .zip files that are pack content
 	for iteration := 0; iteration < maxIterations; iteration++ {
-		mcpackFiles, err := findMcpackFiles(rootDir)
+		archives, err := findNestedArchives(rootDir, content)
 		if err != nil {
 			return fmt.Errorf("failed to find mcpack files: %w", err)
 		}
 
-		// If no .mcpack files found, extraction is complete
-		if len(mcpackFiles) == 0 {
+		// If no nested archives are left, extraction is complete
+		if len(archives) == 0 {
 			return nil
 		}
 
-		// Extract all found .mcpack files in this iteration
-		for _, mcpackPath := range mcpackFiles {
+		// Extract all found archives in this iteration
+		for _, archivePath := range archives {
+			if strings.EqualFold(filepath.Ext(archivePath), ".zip") {
+				holdsPacks, err := archiveHoldsPacks(archivePath)
+				if err != nil {
+					return fmt.Errorf("failed to inspect nested archive %s: %w", archivePath, err)
+				}
+				if !holdsPacks {
+					content[archivePath] = true
+					continue
+				}
+			}
+
 			// Get the filename without extension for the subdirectory name
-			filename := filepath.Base(mcpackPath)
+			filename := filepath.Base(archivePath)
 			dirName := strings.TrimSuffix(filename, filepath.Ext(filename))
-			extractDir := filepath.Join(filepath.Dir(mcpackPath), dirName)
+			extractDir := filepath.Join(filepath.Dir(archivePath), dirName)
 
-			// Extract the .mcpack file
-			if err := filesystem.ExtractArchive(mcpackPath, extractDir); err != nil {
-				return fmt.Errorf("failed to extract mcpack %s: %w", mcpackPath, err)
+			if err := extractor.Extract(archivePath, extractDir); err != nil {
+				return fmt.Errorf("failed to extract mcpack %s: %w", archivePath, err)
 			}
 
-			// Remove the original .mcpack file to avoid confusion
-			if err := os.Remove(mcpackPath); err != nil {
-				return fmt.Errorf("failed to remove original mcpack file %s: %w", mcpackPath, err)
+			// Remove the original archive to avoid confusion
+			if err := os.Remove(archivePath); err != nil {
+				return fmt.Errorf("failed to remove original mcpack file %s: %w", archivePath, err)
 			}
 		}
 	}
@@ -259,21 +336,38 @@ func extractNestedMcpacks(rootDir string) error {
 	return fmt.Errorf("exceeded maximum nesting depth (%d) for mcpack extraction - possible malformed or malicious archive", maxIterations)
 }
 
-// findMcpackFiles recursively finds all .mcpack files in a directory
-func findMcpackFiles(rootDir string) ([]string, error) {
-	var mcpackFiles []string
+// findNestedArchives recursively finds all .mcpack and .zip files in a
+// directory, except the .zip files already known to be pack content
+func findNestedArchives(rootDir string, content map[string]bool) ([]string, error) {
+	var archives []string
 
 	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".mcpack") {
-			mcpackFiles = append(mcpackFiles, path)
+		if !info.IsDir() && isNestedArchiveName(info.Name()) && !content[path] {
+			archives = append(archives, path)
 		}
 
 		return nil
 	})
 
-	return mcpackFiles, err
+	return archives, err
+}
+
+// isNestedArchiveName reports whether a file inside an addon may be an archive of packs
+func isNestedArchiveName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".mcpack" || ext == ".zip"
+}
+
+// archiveHoldsPacks reports whether a nested .zip contains packs or further
+// nested archives rather than being ordinary pack content
+func archiveHoldsPacks(archivePath string) (bool, error) {
+	info, err := filesystem.GetArchiveInfo(archivePath)
+	if err != nil {
+		return false, err
+	}
+	return info.HasManifest || info.HasMcpackFiles || len(info.ZipFiles) > 0, nil
 }
@@ -0,0 +1,62 @@
+package addon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAddonDirectory(t *testing.T) {
+	tests := []struct {
+		name          string
+		packDirs      map[string]string // Pack directory, relative to the addon directory, to the UUID of its pack
+		behaviorPacks int
+		resourcePacks int
+	}{
+		{name: "single pack", packDirs: map[string]string{".": behaviorUUID}, behaviorPacks: 1},
+		{name: "pack subdirectories", packDirs: map[string]string{"BP": behaviorUUID, "nested/RP": resourceUUID}, behaviorPacks: 1, resourcePacks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addonDir := t.TempDir()
+			for dir, uuid := range tt.packDirs {
+				writeTestPack(t, filepath.Join(addonDir, dir), uuid, [3]int{1, 0, 0})
+			}
+			if !IsAddonDirectory(addonDir) || IsAddonDirectory(filepath.Join(addonDir, "manifest.json")) {
+				t.Fatal("Expected only the directory to be an addon directory")
+			}
+
+			addon, err := LoadAddonDirectory(addonDir, true)
+			if err != nil {
+				t.Fatalf("LoadAddonDirectory failed: %v", err)
+			}
+			if len(addon.BehaviorPacks) != tt.behaviorPacks || len(addon.ResourcePacks) != tt.resourcePacks {
+				t.Fatalf("Expected %d behavior and %d resource pack(s), got %d and %d",
+					tt.behaviorPacks, tt.resourcePacks, len(addon.BehaviorPacks), len(addon.ResourcePacks))
+			}
+			if !addon.IsDryRun || addon.TempDir != "" {
+				t.Errorf("Expected a dry-run addon without a temporary directory, got dry run %v and %q", addon.IsDryRun, addon.TempDir)
+			}
+			for _, pack := range addon.GetAllPacks() {
+				dir, err := filepath.Rel(addonDir, pack.Path)
+				if err != nil || tt.packDirs[filepath.ToSlash(dir)] != pack.Manifest.Header.UUID {
+					t.Errorf("Expected pack %s loaded from its own directory, got %s", pack.Manifest.Header.UUID, pack.Path)
+				}
+				if pack.InArchive() {
+					t.Errorf("Expected pack %s installed from the directory, not an archive", pack.Manifest.Header.UUID)
+				}
+			}
+
+			// Cleanup leaves the source directory alone
+			if err := addon.Cleanup(); err != nil {
+				t.Fatalf("Cleanup failed: %v", err)
+			}
+			for _, pack := range addon.GetAllPacks() {
+				if _, err := os.Stat(filepath.Join(pack.Path, "manifest.json")); err != nil {
+					t.Errorf("Expected Cleanup to leave the pack files in place: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package addon
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/history"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// PackHistoryRecord cross-references one server history entry with blockbench's own records
+type PackHistoryRecord struct {
+	minecraft.PackHistoryEntry
+	Type            minecraft.PackType `json:"type"`
+	Active          bool               `json:"active"`                    // Still registered in the world config
+	FirstInstalled  *time.Time         `json:"first_installed,omitempty"` // Earliest blockbench install of the pack
+	HistoryModified time.Time          `json:"history_modified"`          // When the server last wrote the history file
+	OutOfBand       bool               `json:"out_of_band"`               // Recorded by the server but never installed by blockbench
+}
+
+// AnalyzePackHistory reads the server's pack history files and flags packs that
+// were never installed through blockbench, based on install backups in backupDir.
+func AnalyzePackHistory(server *minecraft.Server, backupDir string) ([]PackHistoryRecord, error) {
+	installs, err := blockbenchInstallTimes(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []struct {
+		packType    minecraft.PackType
+		historyFile string
+		configFile  string
+	}{
+		{minecraft.PackTypeBehavior, server.Paths.WorldBehaviorHistory, server.Paths.WorldBehaviorPacks},
+		{minecraft.PackTypeResource, server.Paths.WorldResourceHistory, server.Paths.WorldResourcePacks},
+	}
+
+	var records []PackHistoryRecord
+	for _, source := range sources {
+		history, err := minecraft.LoadPackHistory(source.historyFile)
+		if err != nil {
+			return nil, err
+		}
+		config, err := minecraft.LoadWorldConfigFS(server.FS, source.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load world config: %w", err)
+		}
+
+		for _, entry := range history.Packs {
+			record := PackHistoryRecord{
+				PackHistoryEntry: entry,
+				Type:             source.packType,
+				Active:           config.HasPack(entry.UUID),
+				HistoryModified:  history.Modified,
+			}
+			if installed, ok := installs[entry.UUID]; ok {
+				installedAt := installed
+				record.FirstInstalled = &installedAt
+			}
+			record.OutOfBand = record.FirstInstalled == nil
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// blockbenchInstallTimes maps each pack UUID to the time of its earliest install backup
+func blockbenchInstallTimes(backupDir string) (map[string]time.Time, error) {
+	installs := make(map[string]time.Time)
+	// ListBackups creates the backup root; keep this read-only when there are no backups yet
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		return installs, nil
+	}
+
+	backups, err := filesystem.NewBackupManager(backupDir).ListBackups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	for _, backup := range backups {
+		if backup.Operation != "install" {
+			continue
+		}
+		uuids := backup.PackUUIDs
+		if len(uuids) == 0 && backup.AddonUUID != "" {
+			uuids = []string{backup.AddonUUID}
+		}
+		for _, uuid := range uuids {
+			if first, ok := installs[uuid]; !ok || backup.Timestamp.Before(first) {
+				installs[uuid] = backup.Timestamp
+			}
+		}
+	}
+
+	return installs, nil
+}
+
+// recordHistory appends an operation to the server's history file. The
+// operation has already happened, so a failure to record it is only logged.
+func recordHistory(server *minecraft.Server, entry history.Entry) {
+	entry.User = history.CurrentUser()
+	if err := history.Append(server.Paths.HistoryFile, entry); err != nil {
+		slog.Warn("Failed to record operation history", "error", err)
+	}
+}
+
+// installHistoryEntry describes a finished install or update
+func installHistoryEntry(addonPath string, result *InstallResult, err error) history.Entry {
+	entry := history.Entry{Operation: history.OperationInstall, Addon: addonPath, Success: err == nil}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if result != nil {
+		if result.Updated {
+			entry.Operation = history.OperationUpdate
+		}
+		entry.Success = err == nil && result.Success
+		entry.Packs = result.Packs
+		entry.RolledBack = result.RolledBack
+		if result.BackupMetadata != nil {
+			entry.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return entry
+}
+
+// uninstallHistoryEntry describes a finished uninstall
+func uninstallHistoryEntry(identifier string, result *UninstallResult, err error) history.Entry {
+	entry := history.Entry{Operation: history.OperationUninstall, Target: identifier, Success: err == nil}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if result != nil {
+		entry.Success = err == nil && result.Success
+		entry.Packs = result.Packs
+		entry.RolledBack = result.RolledBack
+		if result.BackupMetadata != nil {
+			entry.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return entry
+}
+
+// cleanHistoryEntry describes a finished removal of unreferenced packs, an
+// uninstall of every pack it removed
+func cleanHistoryEntry(result *CleanResult, err error) history.Entry {
+	entry := history.Entry{Operation: history.OperationUninstall, Target: history.TargetUnreferenced, Success: err == nil}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if result != nil {
+		entry.Success = err == nil && result.Success
+		entry.Packs = result.HookPacks()
+		entry.RolledBack = result.RolledBack
+		if result.BackupMetadata != nil {
+			entry.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return entry
+}
+
+// rollbackHistoryEntry describes a restored backup; target names what was
+// rolled back when it was not a 'backup restore', such as a failed batch
+func rollbackHistoryEntry(target, backupID string, err error) history.Entry {
+	entry := history.Entry{Operation: history.OperationRollback, Target: target, BackupID: backupID, Success: err == nil}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
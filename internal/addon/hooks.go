@@ -0,0 +1,55 @@
+package addon
+
+import (
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+// hookPayload starts the payload of an event on the server
+func hookPayload(server *minecraft.Server, event hooks.Event) hooks.Payload {
+	return hooks.Payload{
+		Event:  event,
+		Server: server.Paths.ServerRoot,
+		World:  filepath.Base(filepath.Dir(server.Paths.WorldBehaviorPacks)),
+	}
+}
+
+// extractedHookPacks describes an addon's packs for a hook payload
+func extractedHookPacks(packs []*ExtractedPack) []hooks.Pack {
+	result := make([]hooks.Pack, 0, len(packs))
+	for _, pack := range packs {
+		result = append(result, hooks.Pack{
+			UUID:    pack.Manifest.Header.UUID,
+			Name:    pack.Manifest.GetDisplayName(),
+			Type:    string(pack.PackType),
+			Version: pack.Manifest.Header.Version,
+		})
+	}
+	return result
+}
+
+// installedHookPack describes an installed pack for a hook payload
+func installedHookPack(pack *minecraft.InstalledPack) hooks.Pack {
+	return hooks.Pack{UUID: pack.PackID, Name: pack.Name, Type: string(pack.Type), Version: pack.Version}
+}
+
+// runPostHooks runs the hooks of an event that cannot stop the operation,
+// returning a warning for each failure
+func runPostHooks(runner *hooks.Runner, payload hooks.Payload) []string {
+	if err := runner.Run(payload); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// rollbackPayload describes a backup restored after an operation failed
+func rollbackPayload(server *minecraft.Server, operation string, packs []hooks.Pack, backupID string, cause error) hooks.Payload {
+	payload := hookPayload(server, hooks.PostRollback)
+	payload.Operation = operation
+	payload.Packs = packs
+	payload.BackupID = backupID
+	payload.Error = cause.Error()
+	return payload
+}
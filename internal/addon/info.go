@@ -0,0 +1,60 @@
+package addon
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+// PackDetails contains everything blockbench knows about a single installed pack
+type PackDetails struct {
+	minecraft.InstalledPack
+	Directory     string                         `json:"directory"`
+	MinEngine     [3]int                         `json:"min_engine_version"`
+	ConfigFile    string                         `json:"config_file"`
+	ConfigIndex   int                            `json:"config_index"`
+	Modules       []minecraft.ManifestModule     `json:"modules"`
+	Dependencies  []minecraft.ManifestDependency `json:"dependencies"`
+	ManifestError string                         `json:"manifest_error,omitempty"`
+}
+
+// GetPackDetails resolves an installed pack by name or UUID and gathers its manifest and config details
+func GetPackDetails(server *minecraft.Server, identifier string, byUUID bool) (*PackDetails, error) {
+	pack, err := FindInstalledPack(server, identifier, byUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &PackDetails{
+		InstalledPack: *pack,
+		ConfigIndex:   -1,
+	}
+
+	configFile, err := server.Paths.WorldConfigFor(pack.Type)
+	if err != nil {
+		return nil, err
+	}
+	config, err := minecraft.LoadWorldConfigFS(server.FS, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load world config: %w", err)
+	}
+	details.ConfigFile = configFile
+	details.ConfigIndex = config.IndexOf(pack.PackID)
+
+	// A pack may be registered in the world config without its files being present
+	if dir, err := server.FindPackDirectory(pack.PackID, pack.Type); err == nil {
+		details.Directory = dir
+		details.Size, _ = server.PackSize(pack.PackID, pack.Type)
+	}
+
+	manifest, err := server.FindAndLoadManifestByUUID(pack.PackID, pack.Type)
+	if err != nil {
+		details.ManifestError = err.Error()
+		return details, nil
+	}
+	details.MinEngine = manifest.Header.MinVersion
+	details.Modules = manifest.Modules
+	details.Dependencies = manifest.Dependencies
+
+	return details, nil
+}
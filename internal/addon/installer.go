@@ -1,13 +1,18 @@
 package addon
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/makutaku/blockbench/internal/hooks"
 	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/prompt"
+	"github.com/makutaku/blockbench/internal/signature"
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
 	"github.com/makutaku/blockbench/pkg/filesystem"
 	"github.com/makutaku/blockbench/pkg/validation"
 )
@@ -19,15 +24,125 @@ type InstallOptions struct {
 	BackupDir   string
 	ForceUpdate bool
 	Interactive bool
+	Prompter    prompt.Prompter        // Asks the --interactive confirmations; nil asks on standard input
+	VerifyCopy  bool                   // Hash-verify every copied pack file
+	Subpack     string                 // Subpack folder name to activate on packs that declare it
+	Position    minecraft.PackPosition // Where the addon's packs go in their world configs; the zero value appends
+	Packs       PackFilter             // Which of the addon's packs to install; the zero value installs all of them
+
+	DenyCapabilities []string // Manifest capabilities that cause the install to be rejected
+	AllowScripts     bool     // Permit packs with script modules or .js files
+	Strict           bool     // Reject packs whose JSON content or assets fail deep validation
+
+	EnableExperiments bool // Turn on the server.properties settings experimental packs need, and, once the Prompter confirms, the world experiments the packs need; both files are backed up first
+	RequireOnClients  bool // Set texture-pack-required in server.properties so every player downloads the resource packs
+
+	ExtractLimits filesystem.ExtractLimits // Decompression limits; zero fields use the defaults
+	Direct        bool                     // Stream pack files from the archive into the server instead of extracting to a temporary directory first
+	Progress      filesystem.Progress      // Optional; receives the bytes processed by extraction, backup, and copy steps
+	Dedupe        bool                     // Hard-link installed files to identical content in the server's content store
+	Link          bool                     // Symlink the packs of an unpacked addon directory into the server instead of copying them
+	ReplaceFiles  bool                     // Remove the files of the installed versions of the packs before copying, instead of copying over them
+	Ownership     *filesystem.Ownership    // Owner and mode given to installed files; nil keeps them as copied
+	PathPolicy    *filesystem.PathPolicy   // When set, the install fails before any change if it would write outside the allowed paths
+
+	Hooks *hooks.Runner // Runs the pre-install, post-install, and post-rollback hooks; nil runs none
+
+	TrustedKeys      []signature.PublicKey  // Keys whose signatures (addon.sig or addon.minisig) are accepted
+	ContentKeys      *minecraft.ContentKeys // Keys to decrypt encrypted Marketplace packs; without one, such packs are rejected
+	RequireSignature bool                   // Reject addons without a valid signature by a trusted key
+
+	batchBackup *filesystem.BackupMetadata // Backup taken by a Batch; used instead of creating one
 }
 
 // InstallResult contains the result of an installation
 type InstallResult struct {
-	Success        bool
-	InstalledPacks []string
-	BackupMetadata *filesystem.BackupMetadata
-	Errors         []string
-	Warnings       []string
+	Success          bool                                 `json:"success"`
+	InstalledPacks   []string                             `json:"installed_packs"`
+	PackResults      []PackResult                         `json:"pack_results"`    // What happened to each of the addon's packs
+	Packs            []hooks.Pack                         `json:"packs,omitempty"` // The addon's packs with their versions; set once validation passes
+	Scripts          []PackScripts                        `json:"scripts,omitempty"`
+	Signature        *signature.Verified                  `json:"signature,omitempty"` // Set when the addon's signature was verified
+	BackupMetadata   *filesystem.BackupMetadata           `json:"backup,omitempty"`
+	RolledBack       bool                                 `json:"rolled_back,omitempty"`       // The backup was restored after the install failed
+	Updated          bool                                 `json:"updated,omitempty"`           // The install replaced installed versions of its packs
+	AlreadyInstalled bool                                 `json:"already_installed,omitempty"` // Every pack was installed at the same version, so nothing changed
+	Linked           bool                                 `json:"linked,omitempty"`            // The packs were linked to their source directories instead of copied
+	ConfigPlacements []ConfigPlacement                    `json:"config_placements,omitempty"`
+	FinalOrder       map[string][]minecraft.PackReference `json:"final_order,omitempty"`         // Keyed by world config file
+	PropertyChanges  []PropertyChange                     `json:"property_changes,omitempty"`    // server.properties settings changed by EnableExperiments and RequireOnClients
+	Experiments      []string                             `json:"experiments_enabled,omitempty"` // level.dat experiment keys turned on by EnableExperiments
+	Plan             *DryRunPlan                          `json:"plan,omitempty"`                // Dry run only: every change the install would make
+	Errors           []string                             `json:"errors"`
+	Warnings         []string                             `json:"warnings"`
+}
+
+// PackInstallStatus is what an install did with one of an addon's packs
+type PackInstallStatus string
+
+const (
+	PackNotInstalled     PackInstallStatus = "not-installed"     // The install stopped before reaching the pack
+	PackInstalled        PackInstallStatus = "installed"         // Copied or linked and registered in its world config
+	PackAlreadyInstalled PackInstallStatus = "already-installed" // Installed at the same version, so left as it was
+	PackExcluded         PackInstallStatus = "excluded"          // Left out by the pack filter
+	PackWouldInstall     PackInstallStatus = "would-install"     // Dry run only
+	PackFailed           PackInstallStatus = "failed"            // Installing the pack failed
+	PackRolledBack       PackInstallStatus = "rolled-back"       // Installed, then undone when the install failed
+)
+
+// PackResult is the outcome of an install for one of the addon's packs
+type PackResult struct {
+	PackID    string             `json:"pack_id"`
+	Name      string             `json:"name"`
+	Type      minecraft.PackType `json:"type"`
+	Version   [3]int             `json:"version"`
+	Status    PackInstallStatus  `json:"status"`
+	Directory string             `json:"directory,omitempty"` // Where the pack is, or would be, installed
+	Error     string             `json:"error,omitempty"`     // Why the pack failed
+}
+
+// setPackStatus sets the status of the pack with the UUID
+func (r *InstallResult) setPackStatus(packID string, status PackInstallStatus) {
+	for i := range r.PackResults {
+		if r.PackResults[i].PackID == packID {
+			r.PackResults[i].Status = status
+		}
+	}
+}
+
+// replacePackStatus sets every pack with status from to status to
+func (r *InstallResult) replacePackStatus(from, to PackInstallStatus) {
+	for i := range r.PackResults {
+		if r.PackResults[i].Status == from {
+			r.PackResults[i].Status = to
+		}
+	}
+}
+
+// packResult describes one of the addon's packs before anything is done with it
+func (i *Installer) packResult(pack *ExtractedPack, status PackInstallStatus) PackResult {
+	packResult := PackResult{
+		PackID:  pack.Manifest.Header.UUID,
+		Name:    pack.Manifest.GetDisplayName(),
+		Type:    pack.Manifest.GetPackType(),
+		Version: pack.Manifest.Header.Version,
+		Status:  status,
+	}
+	if status != PackExcluded {
+		if dir, _, err := i.server.PackInstallPaths(pack.Manifest); err == nil {
+			packResult.Directory = dir
+		}
+	}
+	return packResult
+}
+
+// ConfigPlacement records the activation index a pack was registered at in a world config file.
+// Pack precedence follows this order, so it matters when troubleshooting overrides.
+type ConfigPlacement struct {
+	PackID     string `json:"pack_id"`
+	Name       string `json:"name"`
+	ConfigFile string `json:"config_file"`
+	Index      int    `json:"index"`
 }
 
 // Installer handles addon installation operations
@@ -46,8 +161,36 @@ func NewInstaller(server *minecraft.Server, backupDir string) *Installer {
 
 // InstallAddon installs an addon with full validation and rollback support
 func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*InstallResult, error) {
+	logger := slog.With("addon", addonPath, "server", i.server.Paths.ServerRoot)
+	logger.Info("Installing addon", "dry_run", options.DryRun)
+
+	result, err := i.installAddon(addonPath, options)
+	switch {
+	case err != nil:
+		// The caller reports the error itself, so keep this record off the console
+		logger.Info("Install failed", "error", err, "rolled_back", result != nil && result.RolledBack)
+	case options.DryRun:
+		logger.Info("Install dry run finished")
+	case result.AlreadyInstalled:
+		logger.Info("Addon already installed")
+	default:
+		attrs := []any{"packs", result.InstalledPacks}
+		if result.BackupMetadata != nil {
+			attrs = append(attrs, "backup", result.BackupMetadata.ID)
+		}
+		logger.Info("Installed addon", attrs...)
+	}
+
+	if !options.DryRun && (err != nil || !result.AlreadyInstalled) {
+		recordHistory(i.server, installHistoryEntry(addonPath, result, err))
+	}
+	return result, err
+}
+
+func (i *Installer) installAddon(addonPath string, options InstallOptions) (*InstallResult, error) {
 	result := &InstallResult{
 		InstalledPacks: make([]string, 0),
+		PackResults:    make([]PackResult, 0),
 		Errors:         make([]string, 0),
 		Warnings:       make([]string, 0),
 	}
@@ -56,6 +199,34 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 		fmt.Printf("Starting installation of %s\n", addonPath)
 	}
 
+	if options.Direct && options.Strict {
+		err := fmt.Errorf("strict content validation needs extracted files and cannot be combined with direct installation")
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+
+	if options.Link {
+		if err := i.checkLink(addonPath, options); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result, err
+		}
+	}
+
+	if err := options.PathPolicy.Check(extractionWrites(addonPath)); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+
+	verified, warning, err := checkSignature(addonPath, options)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	result.Signature = verified
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+
 	// Step 1: Pre-installation validation
 	if err := i.preInstallValidation(addonPath, options.Verbose); err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Pre-installation validation failed: %v", err))
@@ -66,16 +237,33 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	validationDetails := []string{
 		fmt.Sprintf("Validated addon file: %s", addonPath),
 		fmt.Sprintf("Server directory structure verified: %s", i.server.Paths.ServerRoot),
-		"Archive format and integrity confirmed",
 	}
-	if err := showStepResult("Pre-installation validation", validationDetails, "Archive extraction", "Extract the .mcaddon/.mcpack file and any nested .mcpack files to a temporary directory for processing.", options); err != nil {
+	unpacked := IsAddonDirectory(addonPath)
+	direct := options.Direct && !unpacked
+	// Name step 2 for what it does with this kind of addon
+	extractionStep, extractionDescription := "Archive extraction", "Extract the .mcaddon/.mcpack file and any nested .mcpack files to a temporary directory for processing."
+	if unpacked {
+		validationDetails = append(validationDetails, "Unpacked addon directory detected - archive extraction will be skipped")
+		extractionStep, extractionDescription = "Pack discovery", "Find the packs in the unpacked addon directory; their files are installed straight from it without extraction."
+	} else if direct {
+		validationDetails = append(validationDetails, "Archive format and integrity confirmed - pack files will be streamed directly into the server")
+		extractionStep, extractionDescription = "Manifest scan", "Read the pack manifests from the archive without extracting it; pack files are streamed into the server during installation."
+	} else {
+		validationDetails = append(validationDetails, "Archive format and integrity confirmed")
+	}
+	if err := showStepResult("Pre-installation validation", validationDetails, extractionStep, extractionDescription, options); err != nil {
 		return result, err
 	}
 
 	// Continue with full analysis even in dry-run mode to provide detailed information
 
-	// Step 2: Extract addon
-	extractedAddon, err := ExtractAddon(addonPath, options.DryRun)
+	// Step 2: Extract addon (or only scan its manifests for direct installation)
+	var extractedAddon *ExtractedAddon
+	if direct {
+		extractedAddon, err = ScanAddonArchive(addonPath, options.DryRun, options.ExtractLimits, options.Progress)
+	} else {
+		extractedAddon, err = ExtractAddon(addonPath, options.DryRun, options.ExtractLimits, options.Progress)
+	}
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Extraction failed: %v", err))
 		return result, err
@@ -89,8 +277,13 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	}()
 
 	// Show extraction results with pack details
-	extractionDetails := []string{
-		fmt.Sprintf("Extracted to temporary directory: %s", extractedAddon.TempDir),
+	extractionDetails := []string{}
+	if direct {
+		extractionDetails = append(extractionDetails, fmt.Sprintf("Scanned manifests without extracting: %s", addonPath))
+	} else if extractedAddon.TempDir != "" {
+		extractionDetails = append(extractionDetails, fmt.Sprintf("Extracted to temporary directory: %s", extractedAddon.TempDir))
+	} else {
+		extractionDetails = append(extractionDetails, fmt.Sprintf("Using unpacked addon directory: %s", addonPath))
 	}
 
 	// Add behavior pack details
@@ -116,7 +309,26 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 				pack.Path))
 		}
 	}
-	if err := showStepResult("Archive extraction", extractionDetails, "Content validation", "Analyze extracted pack contents, validate manifest.json files, and determine pack types (behavior/resource).", options); err != nil {
+	excluded, err := selectPacks(extractedAddon, options.Packs)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Pack selection failed: %v", err))
+		return result, err
+	}
+	for _, pack := range extractedAddon.GetAllPacks() {
+		result.PackResults = append(result.PackResults, i.packResult(pack, PackNotInstalled))
+	}
+	for _, pack := range excluded {
+		result.PackResults = append(result.PackResults, i.packResult(pack, PackExcluded))
+		extractionDetails = append(extractionDetails, fmt.Sprintf("Excluded from this install: %s (UUID: %s)",
+			pack.Manifest.GetDisplayName(), pack.Manifest.Header.UUID))
+	}
+	decrypted, err := decryptPacks(extractedAddon, options.ContentKeys, options.Link)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	extractionDetails = append(extractionDetails, decrypted...)
+	if err := showStepResult(extractionStep, extractionDetails, "Content validation", "Analyze the pack contents, validate manifest.json files, and determine pack types (behavior/resource).", options); err != nil {
 		return result, err
 	}
 
@@ -125,6 +337,60 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 		result.Errors = append(result.Errors, fmt.Sprintf("Content validation failed: %v", err))
 		return result, err
 	}
+	if options.Strict {
+		problems, err := validatePackContents(extractedAddon)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Content validation failed: %v", err))
+			return result, err
+		}
+		if len(problems) > 0 {
+			result.Errors = append(result.Errors, problems...)
+			return result, fmt.Errorf("strict content validation found %d problem(s)", len(problems))
+		}
+	}
+	assetProblems, err := validatePackAssets(extractedAddon)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Content validation failed: %v", err))
+		return result, err
+	}
+	if direct {
+		result.Warnings = append(result.Warnings, "Asset integrity checks were skipped because pack files are streamed directly from the archive")
+	}
+	if len(assetProblems) > 0 {
+		if options.Strict {
+			result.Errors = append(result.Errors, assetProblems...)
+			return result, fmt.Errorf("strict asset validation found %d problem(s)", len(assetProblems))
+		}
+		for _, problem := range assetProblems {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Asset problem: %s", problem))
+		}
+	}
+	if err := checkDeniedCapabilities(extractedAddon, options.DenyCapabilities); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Capability policy violation: %v", err))
+		return result, err
+	}
+	scripts, err := findScripts(extractedAddon)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Content validation failed: %v", err))
+		return result, err
+	}
+	result.Scripts = scripts
+	if len(scripts) > 0 {
+		if !options.AllowScripts {
+			err := fmt.Errorf("addon contains scripts; re-run with --allow-scripts to install them:\n  %s",
+				strings.Join(describeScripts(scripts), "\n  "))
+			result.Errors = append(result.Errors, fmt.Sprintf("Script policy violation: %v", err))
+			return result, err
+		}
+		for _, line := range describeScripts(scripts) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Installing script content: %s", line))
+		}
+	}
+	if options.Subpack != "" && !addonDeclaresSubpack(extractedAddon, options.Subpack) {
+		err := fmt.Errorf("no pack in the addon declares subpack %q", options.Subpack)
+		result.Errors = append(result.Errors, fmt.Sprintf("Content validation failed: %v", err))
+		return result, err
+	}
 
 	// Show content validation results
 	contentValidationDetails := []string{}
@@ -134,7 +400,22 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	for _, pack := range extractedAddon.ResourcePacks {
 		contentValidationDetails = append(contentValidationDetails, fmt.Sprintf("Validated resource pack: %s", pack.Manifest.GetDisplayName()))
 	}
+	for _, pack := range extractedAddon.GetAllPacks() {
+		if len(pack.Manifest.Capabilities) > 0 {
+			contentValidationDetails = append(contentValidationDetails, fmt.Sprintf("%s requests capabilities: %s",
+				pack.Manifest.GetDisplayName(), strings.Join(pack.Manifest.Capabilities, ", ")))
+		}
+	}
+	for _, line := range describeScripts(scripts) {
+		contentValidationDetails = append(contentValidationDetails, fmt.Sprintf("Script content: %s", line))
+	}
+	for _, problem := range assetProblems {
+		contentValidationDetails = append(contentValidationDetails, fmt.Sprintf("Asset problem: %s", problem))
+	}
 	contentValidationDetails = append(contentValidationDetails, "All manifest.json files are valid")
+	if options.Strict {
+		contentValidationDetails = append(contentValidationDetails, "All pack JSON files and assets passed deep validation")
+	}
 	if err := showStepResult("Content validation", contentValidationDetails, "Conflict detection", "Check for UUID conflicts with existing installed packs that could cause issues.", options); err != nil {
 		return result, err
 	}
@@ -147,7 +428,7 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	}
 
 	// Check for missing dependencies
-	missingDeps, err := i.validateDependencies(extractedAddon)
+	missingDeps, err := i.validateDependencies(extractedAddon, excluded)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Dependency validation failed: %v", err))
 		return result, err
@@ -155,10 +436,19 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 
 	// Show conflict check and dependency validation results
 	conflictDetails := []string{}
-	if len(conflicts) == 0 {
+	for _, current := range conflicts.current {
+		conflictDetails = append(conflictDetails, fmt.Sprintf("Already installed: %s", current))
+	}
+	for _, upgrade := range conflicts.upgrades {
+		conflictDetails = append(conflictDetails, fmt.Sprintf("Upgrade: %s", upgrade))
+	}
+	for _, repair := range conflicts.repairs {
+		conflictDetails = append(conflictDetails, fmt.Sprintf("Repair: %s", repair))
+	}
+	if len(conflicts.conflicts) == 0 {
 		conflictDetails = append(conflictDetails, "No UUID conflicts detected")
 	} else {
-		for _, conflict := range conflicts {
+		for _, conflict := range conflicts.conflicts {
 			conflictDetails = append(conflictDetails, fmt.Sprintf("⚠️  Conflict: %s", conflict))
 		}
 	}
@@ -172,44 +462,176 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 		}
 	}
 
-	conflictDetails = append(conflictDetails, fmt.Sprintf("Checked against %d existing pack(s)", len(conflicts)))
+	conflictDetails = append(conflictDetails, fmt.Sprintf("Checked against %d existing pack(s)", conflicts.installed))
 	if err := showStepResult("Conflict detection", conflictDetails, "Backup creation", "Create a backup of the current server state to enable rollback if the installation fails.", options); err != nil {
 		return result, err
 	}
 
-	if len(conflicts) > 0 && !options.ForceUpdate {
-		for _, conflict := range conflicts {
+	// Reinstalling exactly what is installed is a no-op unless forced
+	if len(conflicts.current) == len(extractedAddon.GetAllPacks()) && !options.ForceUpdate {
+		result.Packs = extractedHookPacks(extractedAddon.GetAllPacks())
+		result.AlreadyInstalled = true
+		result.replacePackStatus(PackNotInstalled, PackAlreadyInstalled)
+		result.Success = true
+		if options.DryRun {
+			result.Plan = newDryRunPlan("install", "") // Nothing would change
+		}
+		return result, nil
+	}
+
+	if len(conflicts.conflicts) > 0 && !options.ForceUpdate {
+		for _, conflict := range conflicts.conflicts {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Conflict detected: %s", conflict))
 		}
-		return result, fmt.Errorf("conflicts detected, use --force to override")
+		return result, bberrors.Mark(fmt.Errorf("conflicts detected, use --force to override"), bberrors.ErrConflict)
 	}
 
 	if len(missingDeps) > 0 && !options.ForceUpdate {
-		return result, fmt.Errorf("missing dependencies detected. Install required packs first or use --force to proceed anyway (may cause issues)")
+		return result, bberrors.Mark(fmt.Errorf("missing dependencies detected. Install required packs first or use --force to proceed anyway (may cause issues)"), bberrors.ErrMissingDependency)
+	}
+	for _, upgrade := range conflicts.upgrades {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Upgrading %s", upgrade))
+	}
+	for _, repair := range conflicts.repairs {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Repairing %s", repair))
+	}
+	result.Updated = conflicts.replacing()
+
+	positions, positionWarnings, err := i.resolvePositions(extractedAddon, options.Position)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	result.Warnings = append(result.Warnings, positionWarnings...)
+
+	// Verify every planned write before anything changes, dry run included
+	if options.PathPolicy != nil {
+		writes, err := i.plannedInstallWrites(extractedAddon, options)
+		if err == nil {
+			err = options.PathPolicy.Check(writes)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result, err
+		}
+	}
+
+	if warning := i.ownershipWarning(options); warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	longPaths, err := i.longPathWarnings(extractedAddon, options.Link)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	result.Warnings = append(result.Warnings, longPaths...)
+	if err := i.checkDiskSpace(extractedAddon, options.Link); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+
+	propertyChanges, propertyWarnings, err := i.propertyChanges(extractedAddon, options)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+	result.Warnings = append(result.Warnings, propertyWarnings...)
+
+	missingExperiments, experimentWarnings := i.missingExperiments(extractedAddon)
+	var experiments []string
+	if options.EnableExperiments {
+		experiments = missingExperiments
+		if len(experiments) > 0 && !options.DryRun {
+			confirmed, err := confirmExperiments(experiments, options)
+			if err != nil {
+				return result, err
+			}
+			if !confirmed {
+				experiments = nil
+			}
+		}
+	}
+	if len(experiments) == 0 {
+		for _, warning := range experimentWarnings {
+			if !options.EnableExperiments {
+				warning += " (use --enable-experiments to turn it on)"
+			}
+			result.Warnings = append(result.Warnings, warning)
+		}
 	}
 
 	// For dry-run, simulate the installation operations and show detailed information
 	if options.DryRun {
-		return i.performDryRunSimulation(extractedAddon, conflicts, options)
+		dryRunResult, err := i.performDryRunSimulation(extractedAddon, conflicts, positions, options)
+		if dryRunResult != nil {
+			// Keep script and dependency warnings gathered during validation
+			dryRunResult.Scripts = result.Scripts
+			dryRunResult.Signature = result.Signature
+			dryRunResult.PackResults = result.PackResults
+			dryRunResult.PropertyChanges = propertyChanges
+			dryRunResult.Experiments = experiments
+			if dryRunResult.Plan != nil {
+				dryRunResult.Plan.SetProperties = propertyChanges
+				dryRunResult.Plan.EnableExperiments = experiments
+			}
+			if dryRunResult.Success {
+				dryRunResult.replacePackStatus(PackNotInstalled, PackWouldInstall)
+			}
+			dryRunResult.Warnings = append(result.Warnings, dryRunResult.Warnings...)
+		}
+		return dryRunResult, err
 	}
 
 	// Step 5: Create backup
-	var addonName, addonUUID string
+	var addonName string
+	var packUUIDs []string
 	allPacks := extractedAddon.GetAllPacks()
 	if len(allPacks) > 0 {
 		addonName = allPacks[0].Manifest.GetDisplayName()
-		addonUUID = allPacks[0].Manifest.Header.UUID
 	}
-
-	if options.Verbose {
-		fmt.Println("Creating backup before installation...")
+	for _, pack := range allPacks {
+		packUUIDs = append(packUUIDs, pack.Manifest.Header.UUID)
 	}
 
-	backup, err := i.backupManager.CreateInstallBackup(addonName, addonUUID)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+	hookPacks := extractedHookPacks(allPacks)
+	result.Packs = hookPacks
+	preInstall := hookPayload(i.server, hooks.PreInstall)
+	preInstall.Addon = addonPath
+	preInstall.Packs = hookPacks
+	preInstall.Warnings = result.Warnings
+	if err := options.Hooks.Run(preInstall); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Installation aborted: %v", err))
 		return result, err
 	}
+
+	backup := options.batchBackup
+	if backup == nil {
+		if options.Verbose {
+			fmt.Println("Creating backup before installation...")
+		}
+
+		i.backupManager.Progress = options.Progress
+		var extraFiles []string
+		if len(propertyChanges) > 0 {
+			extraFiles = append(extraFiles, i.server.Paths.ServerProperties)
+		}
+		if len(experiments) > 0 {
+			extraFiles = append(extraFiles, i.server.Paths.LevelDat)
+		}
+		if result.Updated {
+			replacedDirs, err := i.replacedPackDirs(extractedAddon)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+				return result, err
+			}
+			extraFiles = append(extraFiles, replacedDirs...)
+		}
+		backup, err = i.backupManager.CreateInstallBackup(addonName, packUUIDs, extraFiles...)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+			return result, err
+		}
+	}
 	result.BackupMetadata = backup
 
 	// Show backup creation results
@@ -230,28 +652,49 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	}
 
 	// Step 6: Install packs (with rollback on failure)
-	if err := i.installPacks(extractedAddon, options.Verbose); err != nil {
+	i.server.VerifyCopies = options.VerifyCopy
+	i.server.Ownership = options.Ownership
+	i.server.Progress = options.Progress
+	if options.Dedupe {
+		i.server.Store = filesystem.NewContentStore(i.server.Paths.StoreDir)
+	}
+	placements, err := i.installPacks(extractedAddon, result, positions, options)
+	if err != nil {
 		if options.Verbose {
 			fmt.Println("Installation failed, rolling back...")
 		}
 
 		// Rollback on failure
-		if rollbackErr := i.backupManager.RestoreBackup(backup.ID); rollbackErr != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", rollbackErr))
-		} else if options.Verbose {
-			fmt.Println("Successfully rolled back changes")
-		}
+		rollbackErr := i.rollback(backup.ID, hookPacks, err, result, options)
 
 		result.Errors = append(result.Errors, fmt.Sprintf("Installation failed: %v", err))
-		return result, err
+		return result, rollbackErr
+	}
+	if err := i.applyPropertyChanges(propertyChanges); err != nil {
+		rollbackErr := i.rollback(backup.ID, hookPacks, err, result, options)
+		result.Errors = append(result.Errors, fmt.Sprintf("Updating server.properties failed: %v", err))
+		return result, rollbackErr
+	}
+	result.PropertyChanges = propertyChanges
+	if len(experiments) > 0 {
+		enabled, err := i.server.EnableWorldExperiments(experiments)
+		if err != nil {
+			rollbackErr := i.rollback(backup.ID, hookPacks, err, result, options)
+			result.Errors = append(result.Errors, fmt.Sprintf("Updating level.dat failed: %v", err))
+			return result, rollbackErr
+		}
+		result.Experiments = enabled
 	}
 
 	// Show pack installation results with specific paths
 	installDetails := []string{}
+	created := "Created %s pack directory: %s"
+	if options.Link {
+		created = "Linked %s pack directory: %s"
+	}
 	for _, pack := range extractedAddon.BehaviorPacks {
-		packDirName := fmt.Sprintf("%s_%s", pack.Manifest.GetDisplayName(), validation.GetSafeUUIDPrefix(pack.Manifest.Header.UUID))
-		finalPackDir := filepath.Join(i.server.Paths.BehaviorPacksDir, packDirName)
-		installDetails = append(installDetails, fmt.Sprintf("Created behavior pack directory: %s", finalPackDir))
+		finalPackDir, _, _ := i.server.PackInstallPaths(pack.Manifest)
+		installDetails = append(installDetails, fmt.Sprintf(created, "behavior", finalPackDir))
 		installDetails = append(installDetails, fmt.Sprintf("Updated world config file: %s", i.server.Paths.WorldBehaviorPacks))
 		installDetails = append(installDetails, fmt.Sprintf("  • Added pack: %s (UUID: %s, Version: %d.%d.%d)",
 			pack.Manifest.GetDisplayName(),
@@ -259,15 +702,24 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 			pack.Manifest.Header.Version[0], pack.Manifest.Header.Version[1], pack.Manifest.Header.Version[2]))
 	}
 	for _, pack := range extractedAddon.ResourcePacks {
-		packDirName := fmt.Sprintf("%s_%s", pack.Manifest.GetDisplayName(), validation.GetSafeUUIDPrefix(pack.Manifest.Header.UUID))
-		finalPackDir := filepath.Join(i.server.Paths.ResourcePacksDir, packDirName)
-		installDetails = append(installDetails, fmt.Sprintf("Created resource pack directory: %s", finalPackDir))
+		finalPackDir, _, _ := i.server.PackInstallPaths(pack.Manifest)
+		installDetails = append(installDetails, fmt.Sprintf(created, "resource", finalPackDir))
 		installDetails = append(installDetails, fmt.Sprintf("Updated world config file: %s", i.server.Paths.WorldResourcePacks))
 		installDetails = append(installDetails, fmt.Sprintf("  • Added pack: %s (UUID: %s, Version: %d.%d.%d)",
 			pack.Manifest.GetDisplayName(),
 			pack.Manifest.Header.UUID,
 			pack.Manifest.Header.Version[0], pack.Manifest.Header.Version[1], pack.Manifest.Header.Version[2]))
 	}
+	for _, placement := range placements {
+		installDetails = append(installDetails, fmt.Sprintf("Registered %s at index %d in %s",
+			placement.Name, placement.Index, filepath.Base(placement.ConfigFile)))
+	}
+	for _, change := range propertyChanges {
+		installDetails = append(installDetails, fmt.Sprintf("Set %s in %s", change, i.server.Paths.ServerProperties))
+	}
+	for _, key := range result.Experiments {
+		installDetails = append(installDetails, fmt.Sprintf("Turned on the %s experiment in %s", minecraft.ExperimentTitle(key), i.server.Paths.LevelDat))
+	}
 	if err := showStepResult("Pack installation", installDetails, "Post-installation validation", "Verify that all packs were successfully installed and are properly registered with the server.", options); err != nil {
 		return result, err
 	}
@@ -279,12 +731,10 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 		}
 
 		// Rollback on validation failure
-		if rollbackErr := i.backupManager.RestoreBackup(backup.ID); rollbackErr != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", rollbackErr))
-		}
+		rollbackErr := i.rollback(backup.ID, hookPacks, err, result, options)
 
 		result.Errors = append(result.Errors, fmt.Sprintf("Post-installation validation failed: %v", err))
-		return result, err
+		return result, rollbackErr
 	}
 
 	// Show post-installation validation results
@@ -302,15 +752,96 @@ func (i *Installer) InstallAddon(addonPath string, options InstallOptions) (*Ins
 	for _, pack := range allPacks {
 		result.InstalledPacks = append(result.InstalledPacks, pack.Manifest.GetDisplayName())
 	}
+	result.ConfigPlacements = placements
+	finalOrder, err := i.loadFinalOrder(placements)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not read final world config order: %v", err))
+	}
+	result.FinalOrder = finalOrder
+	result.Linked = options.Link
 	result.Success = true
 
+	postInstall := hookPayload(i.server, hooks.PostInstall)
+	postInstall.Addon = addonPath
+	postInstall.Packs = hookPacks
+	postInstall.BackupID = backup.ID
+	postInstall.Warnings = result.Warnings
+	result.Warnings = append(result.Warnings, runPostHooks(options.Hooks, postInstall)...)
+
 	if options.Verbose {
 		fmt.Printf("Successfully installed %d packs\n", len(result.InstalledPacks))
+		printConfigPlacements(placements, finalOrder)
 	}
 
 	return result, nil
 }
 
+// rollback restores the backup taken before a failed install and runs the
+// post-rollback hooks. It returns the cause, joined with an ErrRollbackFailed
+// error when the backup could not be restored.
+func (i *Installer) rollback(backupID string, packs []hooks.Pack, cause error, result *InstallResult, options InstallOptions) error {
+	slog.Warn("Rolling back failed install", "backup", backupID, "error", cause)
+	if err := i.backupManager.RestoreBackup(backupID); err != nil {
+		slog.Error("Rollback failed", "backup", backupID, "error", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", err))
+		return rollbackFailed(cause, err)
+	}
+	result.RolledBack = true
+	result.replacePackStatus(PackInstalled, PackRolledBack)
+	if options.Verbose {
+		fmt.Println("Successfully rolled back changes")
+	}
+	payload := rollbackPayload(i.server, "install", packs, backupID, cause)
+	result.Warnings = append(result.Warnings, runPostHooks(options.Hooks, payload)...)
+	return cause
+}
+
+// rollbackFailed returns the error of an operation whose rollback failed too
+func rollbackFailed(cause, err error) error {
+	return errors.Join(cause, fmt.Errorf("%w: %v", bberrors.ErrRollbackFailed, err))
+}
+
+// loadFinalOrder reads the resulting pack order of every world config touched by the installation
+func (i *Installer) loadFinalOrder(placements []ConfigPlacement) (map[string][]minecraft.PackReference, error) {
+	finalOrder := make(map[string][]minecraft.PackReference)
+	for _, placement := range placements {
+		if _, loaded := finalOrder[placement.ConfigFile]; loaded {
+			continue
+		}
+		config, err := minecraft.LoadWorldConfigFS(i.server.FS, placement.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		finalOrder[placement.ConfigFile] = config
+	}
+	return finalOrder, nil
+}
+
+// printConfigPlacements shows where each pack was registered and the resulting activation order
+func printConfigPlacements(placements []ConfigPlacement, finalOrder map[string][]minecraft.PackReference) {
+	if len(placements) == 0 {
+		return
+	}
+
+	fmt.Println("World config placement:")
+	for _, placement := range placements {
+		fmt.Printf("  [%d] %s (%s) in %s\n", placement.Index, placement.Name, placement.PackID, filepath.Base(placement.ConfigFile))
+	}
+
+	configFiles := make([]string, 0, len(finalOrder))
+	for configFile := range finalOrder {
+		configFiles = append(configFiles, configFile)
+	}
+	sort.Strings(configFiles)
+
+	for _, configFile := range configFiles {
+		fmt.Printf("Final order of %s:\n", filepath.Base(configFile))
+		for index, ref := range finalOrder[configFile] {
+			fmt.Printf("  [%d] %s (v%d.%d.%d)\n", index, ref.PackID, ref.Version[0], ref.Version[1], ref.Version[2])
+		}
+	}
+}
+
 // preInstallValidation performs validation before installation
 func (i *Installer) preInstallValidation(addonPath string, verbose bool) error {
 	if verbose {
@@ -347,30 +878,121 @@ func (i *Installer) validateExtractedAddon(addon *ExtractedAddon) error {
 	return nil
 }
 
-// checkForConflicts checks if the addon conflicts with existing installations
-func (i *Installer) checkForConflicts(addon *ExtractedAddon) ([]string, error) {
-	var conflicts []string
+// installConflicts sorts an addon's packs that are already installed by
+// what installing them would do
+type installConflicts struct {
+	current   []string // Installed at the same version: reinstalling changes nothing
+	upgrades  []string // Installed at an older version: installing upgrades them
+	repairs   []string // Installed but broken: installing repairs them
+	conflicts []string // Ambiguous, so installing needs --force
+	installed int      // Installed packs checked against
+}
 
+// replacing reports whether the install replaces installed packs
+func (c *installConflicts) replacing() bool {
+	return len(c.current)+len(c.upgrades)+len(c.repairs)+len(c.conflicts) > 0
+}
+
+// checkForConflicts compares the addon's packs with the installed ones. The
+// same version of a pack is already installed, a newer version is an
+// upgrade, and any version of a broken pack is a repair; an older version,
+// another pack type, or a pack installed more than once is a conflict.
+func (i *Installer) checkForConflicts(addon *ExtractedAddon) (*installConflicts, error) {
 	installedPacks, err := i.server.ListInstalledPacks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list installed packs: %w", err)
 	}
+	result := &installConflicts{installed: len(installedPacks)}
 
-	// Check for UUID conflicts
 	for _, newPack := range addon.GetAllPacks() {
+		var matches []minecraft.InstalledPack
 		for _, installedPack := range installedPacks {
-			if newPack.Manifest.Header.UUID == installedPack.PackID {
-				conflicts = append(conflicts, fmt.Sprintf("Pack %s (UUID: %s) is already installed",
-					installedPack.Name, installedPack.PackID))
+			if strings.EqualFold(newPack.Manifest.Header.UUID, installedPack.PackID) {
+				matches = append(matches, installedPack)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		name := newPack.Manifest.GetDisplayName()
+		version := newPack.Manifest.Header.Version
+		installed := matches[0]
+		switch {
+		case len(matches) > 1:
+			result.conflicts = append(result.conflicts, fmt.Sprintf("Pack %s (UUID: %s) is installed %d times",
+				installed.Name, installed.PackID, len(matches)))
+		case installed.Type != newPack.Manifest.GetPackType():
+			result.conflicts = append(result.conflicts, fmt.Sprintf("Pack %s (UUID: %s) is installed as a %s pack, not a %s pack",
+				installed.Name, installed.PackID, installed.Type, newPack.Manifest.GetPackType()))
+		case installed.Status != minecraft.PackStatusOK:
+			result.repairs = append(result.repairs, fmt.Sprintf("%s %s (%s)", name, formatVersion(version), installed.Status))
+		default:
+			switch validation.CompareVersions(version, installed.Version) {
+			case 0:
+				result.current = append(result.current, fmt.Sprintf("%s %s", name, formatVersion(version)))
+			case 1:
+				result.upgrades = append(result.upgrades, fmt.Sprintf("%s %s -> %s",
+					name, formatVersion(installed.Version), formatVersion(version)))
+			default:
+				result.conflicts = append(result.conflicts, fmt.Sprintf("Pack %s (UUID: %s) is installed at %s, newer than %s",
+					installed.Name, installed.PackID, formatVersion(installed.Version), formatVersion(version)))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// replacedPackDirs returns the pack directories installing the addon
+// overwrites: every directory holding a pack with the UUID of one of its
+// packs, and the directory each such pack installs to, which may not exist
+// yet. The install backup includes them, so undoing a forced reinstall or an
+// upgrade brings back the replaced files. Linked packs are left out, since an
+// install drops the link rather than writing through it.
+func (i *Installer) replacedPackDirs(addon *ExtractedAddon) ([]string, error) {
+	installedDirs := make(map[string][]string)
+	for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+		packs, err := i.server.IndexedPacks(packType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed %s packs: %w", packType, err)
+		}
+		for _, pack := range packs {
+			if pack.Manifest != nil {
+				uuid := strings.ToLower(pack.Manifest.Header.UUID)
+				installedDirs[uuid] = append(installedDirs[uuid], pack.Dir)
 			}
 		}
 	}
 
-	return conflicts, nil
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if _, linked := minecraft.LinkedPackTarget(dir); !linked && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, pack := range addon.GetAllPacks() {
+		existing := installedDirs[strings.ToLower(pack.Manifest.Header.UUID)]
+		if len(existing) == 0 {
+			continue
+		}
+		for _, dir := range existing {
+			add(dir)
+		}
+		targetDir, _, err := i.server.PackInstallPaths(pack.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		add(targetDir)
+	}
+	return dirs, nil
 }
 
-// validateDependencies checks that all pack dependencies are satisfied
-func (i *Installer) validateDependencies(addon *ExtractedAddon) ([]string, error) {
+// validateDependencies checks that all pack dependencies are satisfied. The
+// packs excluded from the install don't satisfy any, unless installed.
+func (i *Installer) validateDependencies(addon *ExtractedAddon, excluded []*ExtractedPack) ([]string, error) {
 	var missingDeps []string
 
 	// Get all currently installed packs
@@ -379,15 +1001,15 @@ func (i *Installer) validateDependencies(addon *ExtractedAddon) ([]string, error
 		return nil, fmt.Errorf("failed to list installed packs: %w", err)
 	}
 
-	// Build set of installed UUIDs
+	// Build set of installed UUIDs, lowercased since UUIDs match in any case
 	installedUUIDs := make(map[string]bool)
 	for _, pack := range installedPacks {
-		installedUUIDs[pack.PackID] = true
+		installedUUIDs[strings.ToLower(pack.PackID)] = true
 	}
 
 	// Add UUIDs from packs being installed (self-satisfied dependencies)
 	for _, newPack := range addon.GetAllPacks() {
-		installedUUIDs[newPack.Manifest.Header.UUID] = true
+		installedUUIDs[strings.ToLower(newPack.Manifest.Header.UUID)] = true
 	}
 
 	// Check each pack's dependencies
@@ -395,7 +1017,13 @@ func (i *Installer) validateDependencies(addon *ExtractedAddon) ([]string, error
 		for _, dep := range newPack.Manifest.Dependencies {
 			if dep.UUID != "" {
 				// Check if dependency exists
-				if !installedUUIDs[dep.UUID] {
+				if !installedUUIDs[strings.ToLower(dep.UUID)] {
+					if name, ok := excludedPackName(excluded, dep.UUID); ok {
+						missingDeps = append(missingDeps,
+							fmt.Sprintf("Pack '%s' requires %s, which is excluded from this install and not installed",
+								newPack.Manifest.GetDisplayName(), name))
+						continue
+					}
 					missingDeps = append(missingDeps,
 						fmt.Sprintf("Pack '%s' requires dependency UUID %s which is not installed",
 							newPack.Manifest.GetDisplayName(), dep.UUID))
@@ -409,20 +1037,183 @@ func (i *Installer) validateDependencies(addon *ExtractedAddon) ([]string, error
 	return missingDeps, nil
 }
 
-// installPacks installs all packs in the addon
-func (i *Installer) installPacks(addon *ExtractedAddon, verbose bool) error {
+// excludedPackName returns the name of the excluded pack with the UUID
+func excludedPackName(excluded []*ExtractedPack, uuid string) (string, bool) {
+	for _, pack := range excluded {
+		if strings.EqualFold(pack.Manifest.Header.UUID, uuid) {
+			return pack.Manifest.GetDisplayName(), true
+		}
+	}
+	return "", false
+}
+
+// checkDeniedCapabilities rejects addons whose packs request any of the denied capabilities
+func checkDeniedCapabilities(addon *ExtractedAddon, denied []string) error {
+	var violations []string
+	for _, pack := range addon.GetAllPacks() {
+		for _, capability := range denied {
+			if pack.Manifest.HasCapability(capability) {
+				violations = append(violations, fmt.Sprintf("%s requires %s", pack.Manifest.GetDisplayName(), capability))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("denied capabilities requested: %s", strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// addonDeclaresSubpack reports whether any pack in the addon declares the given subpack folder
+func addonDeclaresSubpack(addon *ExtractedAddon, subpack string) bool {
+	for _, pack := range addon.GetAllPacks() {
+		if _, ok := pack.Manifest.GetSubpack(subpack); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePositions works out where each pack of the addon goes in its world
+// config. The first pack of each type takes the requested position and the
+// others follow it in addon order. A before or after anchor must be in the
+// world config of at least one of the addon's pack types; packs of the other
+// type are appended, with a warning.
+func (i *Installer) resolvePositions(addon *ExtractedAddon, position minecraft.PackPosition) (map[string]minecraft.PackPosition, []string, error) {
+	positions := make(map[string]minecraft.PackPosition)
+	if position.IsDefault() {
+		return positions, nil, nil
+	}
+
+	var warnings []string
+	anchored := position.Anchor == ""
+	previous := make(map[minecraft.PackType]string)
+	for _, pack := range addon.GetAllPacks() {
+		uuid := pack.Manifest.Header.UUID
+		if uuid == position.Anchor {
+			return nil, nil, fmt.Errorf("pack %s cannot be positioned relative to itself", pack.Manifest.GetDisplayName())
+		}
+		if last, ok := previous[pack.PackType]; ok {
+			positions[uuid] = minecraft.PackPosition{Kind: minecraft.PositionAfter, Anchor: last}
+			previous[pack.PackType] = uuid
+			continue
+		}
+		previous[pack.PackType] = uuid
+
+		if position.Anchor == "" {
+			positions[uuid] = position
+			continue
+		}
+		configFile, err := i.server.Paths.WorldConfigFor(pack.PackType)
+		if err != nil {
+			return nil, nil, err
+		}
+		config, err := minecraft.LoadWorldConfigFS(i.server.FS, configFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load config %s: %w", configFile, err)
+		}
+		if config.HasPack(position.Anchor) {
+			positions[uuid] = position
+			anchored = true
+		} else {
+			positions[uuid] = minecraft.PackPosition{Kind: minecraft.PositionBottom}
+			warnings = append(warnings, fmt.Sprintf("Pack %s goes to the bottom of %s: %s is not in it",
+				pack.Manifest.GetDisplayName(), filepath.Base(configFile), position.Anchor))
+		}
+	}
+
+	if !anchored {
+		return nil, nil, fmt.Errorf("pack %s is not active in any world config the addon's packs go into", position.Anchor)
+	}
+	return positions, warnings, nil
+}
+
+// installPacks installs all packs in the addon as one transaction and reports
+// where each was registered: every pack's files are written before any world
+// config changes, so a pack that fails leaves the others inactive too. The
+// subpack selection is only applied to packs whose manifest declares it. With
+// Link, each pack is a link to its directory in the unpacked addon.
+func (i *Installer) installPacks(addon *ExtractedAddon, result *InstallResult, positions map[string]minecraft.PackPosition, options InstallOptions) ([]ConfigPlacement, error) {
+	subpack, link, verbose := options.Subpack, options.Link, options.Verbose
 	allPacks := addon.GetAllPacks()
+	placements := make([]ConfigPlacement, 0, len(allPacks))
 
+	tx := i.server.BeginTransaction()
 	for _, pack := range allPacks {
 		if verbose {
 			fmt.Printf("Installing %s pack: %s\n", pack.PackType, pack.Manifest.GetDisplayName())
 		}
 
-		if err := i.server.InstallPack(pack.Manifest, pack.Path); err != nil {
-			return fmt.Errorf("failed to install pack %s: %w", pack.Manifest.GetDisplayName(), err)
+		packOpts := minecraft.PackInstallOptions{Position: positions[pack.Manifest.Header.UUID], Replace: options.ReplaceFiles}
+		if _, ok := pack.Manifest.GetSubpack(subpack); subpack != "" && ok {
+			packOpts.Subpack = subpack
+			if verbose {
+				fmt.Printf("  Activating subpack: %s\n", subpack)
+			}
+		}
+
+		var err error
+		if link {
+			err = tx.LinkPack(pack.Manifest, pack.Path, packOpts)
+		} else if pack.InArchive() {
+			err = tx.InstallPackFrom(pack.Manifest, addon.writeArchivePack(pack), packOpts)
+		} else {
+			err = tx.InstallPack(pack.Manifest, pack.Path, packOpts)
+		}
+		if err != nil {
+			err = failPack(result, pack, fmt.Errorf("failed to install pack %s: %w", pack.Manifest.GetDisplayName(), err))
+			return placements, errors.Join(err, tx.Rollback())
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return placements, errors.Join(fmt.Errorf("failed to activate the addon's packs: %w", err), tx.Rollback())
+	}
+
+	for _, pack := range allPacks {
+		result.setPackStatus(pack.Manifest.Header.UUID, PackInstalled)
+
+		configFile, err := i.server.Paths.WorldConfigFor(pack.PackType)
+		if err != nil {
+			return placements, failPack(result, pack, err)
+		}
+		config, err := minecraft.LoadWorldConfigFS(i.server.FS, configFile)
+		if err != nil {
+			return placements, failPack(result, pack, fmt.Errorf("failed to read config after installing %s: %w", pack.Manifest.GetDisplayName(), err))
+		}
+
+		placements = append(placements, ConfigPlacement{
+			PackID:     pack.Manifest.Header.UUID,
+			Name:       pack.Manifest.GetDisplayName(),
+			ConfigFile: configFile,
+			Index:      config.IndexOf(pack.Manifest.Header.UUID),
+		})
+	}
+
+	return placements, nil
+}
+
+// failPack marks a pack as failed with the error, and returns the error
+func failPack(result *InstallResult, pack *ExtractedPack, err error) error {
+	for i := range result.PackResults {
+		if result.PackResults[i].PackID == pack.Manifest.Header.UUID {
+			result.PackResults[i].Status = PackFailed
+			result.PackResults[i].Error = err.Error()
 		}
 	}
+	return err
+}
 
+// checkLink rejects linked installs that can't work: links need an unpacked
+// addon directory to point to, and only the development pack directories are
+// reread when a world is reloaded
+func (i *Installer) checkLink(addonPath string, options InstallOptions) error {
+	switch {
+	case !IsAddonDirectory(addonPath):
+		return fmt.Errorf("a linked install needs an unpacked pack or addon directory, not %s", addonPath)
+	case options.Direct || options.Dedupe || options.VerifyCopy:
+		return fmt.Errorf("a linked install copies no files and cannot be combined with direct installation, deduplication, or copy verification")
+	case !strings.HasPrefix(filepath.Base(i.server.Paths.BehaviorPacksDir), "development_"):
+		return fmt.Errorf("a linked install goes into the development pack directories, but this server installs into %s", i.server.Paths.BehaviorPacksDir)
+	}
 	return nil
 }
 
@@ -460,48 +1251,64 @@ func showStepResult(stepName string, details []string, nextStep, nextStepDesc st
 		fmt.Printf("   • %s\n", detail)
 	}
 
+	question := "\nFinish installation?"
 	if nextStep != "" {
 		fmt.Printf("\n📋 Next Step: %s\n", nextStep)
 		fmt.Printf("   %s\n", nextStepDesc)
-		fmt.Print("Proceed with this step? (y/N): ")
-	} else {
-		fmt.Print("\nFinish installation? (y/N): ")
+		question = "Proceed with this step?"
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	prompter := options.Prompter
+	if prompter == nil {
+		prompter = prompt.Stdin()
+	}
+	proceed, err := prompter.Confirm(question)
+	if errors.Is(err, prompt.ErrNoInput) {
+		return fmt.Errorf("installation aborted due to end of input")
+	}
 	if err != nil {
-		// Handle EOF (when input is piped or redirected)
-		if strings.Contains(err.Error(), "EOF") {
-			fmt.Println("n")
-			return fmt.Errorf("installation aborted due to end of input")
-		}
-		return fmt.Errorf("failed to read user input: %w", err)
+		return err
 	}
-
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "y" && response != "yes" {
+	if !proceed {
 		return fmt.Errorf("installation aborted by user")
 	}
 
 	return nil
 }
 
+// simulatedDependencyStatus describes whether a dependency would be met once
+// the simulated install is applied
+func simulatedDependencyStatus(simulator *DryRunSimulator, packID string) string {
+	switch {
+	case simulator.IsPendingPack(packID):
+		return "installed by this addon"
+	case simulator.IsPackActive(packID):
+		return "installed"
+	}
+	return "not installed"
+}
+
 // performDryRunSimulation simulates installation operations and shows detailed information
-func (i *Installer) performDryRunSimulation(extractedAddon *ExtractedAddon, conflicts []string, options InstallOptions) (*InstallResult, error) {
+func (i *Installer) performDryRunSimulation(extractedAddon *ExtractedAddon, conflicts *installConflicts, positions map[string]minecraft.PackPosition, options InstallOptions) (*InstallResult, error) {
 	result := &InstallResult{
 		InstalledPacks: make([]string, 0),
+		PackResults:    make([]PackResult, 0),
 		Errors:         make([]string, 0),
 		Warnings:       make([]string, 0),
 	}
 
 	// Add conflict warnings
-	for _, conflict := range conflicts {
+	for _, conflict := range conflicts.conflicts {
 		result.Warnings = append(result.Warnings, fmt.Sprintf("Conflict detected: %s", conflict))
 	}
 
 	simulator := NewDryRunSimulator(i.server)
 	allPacks := extractedAddon.GetAllPacks()
+	backupDir := ""
+	if options.batchBackup == nil {
+		backupDir = i.backupManager.BackupRoot
+	}
+	result.Plan = newDryRunPlan("install", backupDir)
 
 	if options.Verbose {
 		fmt.Println("DRY RUN: Simulating installation operations...")
@@ -512,8 +1319,8 @@ func (i *Installer) performDryRunSimulation(extractedAddon *ExtractedAddon, conf
 		"DRY RUN: Backup would be created with timestamp-based ID",
 		fmt.Sprintf("DRY RUN: Backup would be stored in: %s/backups/", i.server.Paths.ServerRoot),
 	}
-	if len(conflicts) > 0 && options.ForceUpdate {
-		backupDetails = append(backupDetails, "DRY RUN: Would backup existing conflicting packs")
+	if conflicts.replacing() {
+		backupDetails = append(backupDetails, "DRY RUN: Would backup the installed packs being replaced")
 	} else {
 		backupDetails = append(backupDetails, "DRY RUN: No existing files to backup (fresh installation)")
 	}
@@ -521,31 +1328,53 @@ func (i *Installer) performDryRunSimulation(extractedAddon *ExtractedAddon, conf
 		return result, err
 	}
 
-	// Simulate installation for each pack
-	var installationDetails []string
+	// Simulate installation for each pack. Every pack is simulated before any
+	// is reported, so a dependency on a pack later in the addon is seen.
+	var simulations []*SimulatedInstallOperation
+	failed := 0
 	for _, pack := range allPacks {
-		simulation, err := simulator.SimulatePackInstallation(pack)
+		simulation, err := simulator.SimulatePackInstallation(pack, positions[pack.Manifest.Header.UUID])
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("Installation simulation failed for pack %s: %v", pack.Manifest.GetDisplayName(), err))
+			failed++
 			continue
 		}
+		result.Plan.addInstall(simulation, positions[simulation.PackUUID], options.Link)
+		for _, conflict := range simulation.OverlayConflicts {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Conflict detected: %s", conflict))
+		}
+		simulations = append(simulations, simulation)
+	}
+	// The real install would fail on the same packs, so the dry run does too
+	if failed > 0 {
+		return result, fmt.Errorf("installation simulation failed for %d of %d pack(s)", failed, len(allPacks))
+	}
 
+	var installationDetails []string
+	for _, simulation := range simulations {
 		packTypeStr := "behavior"
 		if simulation.PackType == minecraft.PackTypeResource {
 			packTypeStr = "resource"
 		}
 
-		installationDetails = append(installationDetails, fmt.Sprintf("DRY RUN: Would create %s pack directory: %s", packTypeStr, simulation.TargetDirectory))
+		if options.Link {
+			installationDetails = append(installationDetails, fmt.Sprintf("DRY RUN: Would link %s pack directory %s to %s", packTypeStr, simulation.TargetDirectory, simulation.SourcePath))
+		} else {
+			installationDetails = append(installationDetails, fmt.Sprintf("DRY RUN: Would create %s pack directory: %s", packTypeStr, simulation.TargetDirectory))
+		}
 		installationDetails = append(installationDetails, fmt.Sprintf("DRY RUN: Would update config file: %s", simulation.ConfigFile))
 		installationDetails = append(installationDetails, fmt.Sprintf("  • Would add pack entry: %s (UUID: %s, Version: %d.%d.%d)",
 			simulation.PackName, simulation.PackUUID,
 			simulation.PackVersion[0], simulation.PackVersion[1], simulation.PackVersion[2]))
+		if position, ok := positions[simulation.PackUUID]; ok {
+			installationDetails = append(installationDetails, fmt.Sprintf("  • Would place it at: %s", position))
+		}
 
 		if len(simulation.Dependencies) > 0 {
 			installationDetails = append(installationDetails, fmt.Sprintf("  • Pack has %d dependencies:", len(simulation.Dependencies)))
 			for _, dep := range simulation.Dependencies {
 				if dep.UUID != "" {
-					installationDetails = append(installationDetails, fmt.Sprintf("    - UUID: %s", dep.UUID))
+					installationDetails = append(installationDetails, fmt.Sprintf("    - UUID: %s (%s)", dep.UUID, simulatedDependencyStatus(simulator, dep.UUID)))
 				}
 				if dep.ModuleName != "" {
 					installationDetails = append(installationDetails, fmt.Sprintf("    - Module: %s@%s", dep.ModuleName, dep.ModuleVersion))
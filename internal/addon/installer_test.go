@@ -0,0 +1,295 @@
+package addon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+func TestInstallInstalledPack(t *testing.T) {
+	tests := []struct {
+		name    string
+		version [3]int
+		check   func(t *testing.T, result *InstallResult, err error)
+	}{
+		{
+			name:    "same version is a no-op",
+			version: [3]int{1, 1, 0},
+			check: func(t *testing.T, result *InstallResult, err error) {
+				if err != nil {
+					t.Fatalf("Expected reinstalling the same version to succeed, got %v", err)
+				}
+				if !result.Success || !result.AlreadyInstalled || result.Updated {
+					t.Errorf("Expected an already-installed no-op, got success=%v already=%v updated=%v",
+						result.Success, result.AlreadyInstalled, result.Updated)
+				}
+				if result.BackupMetadata != nil {
+					t.Error("Expected no backup for a no-op")
+				}
+			},
+		},
+		{
+			name:    "newer version upgrades",
+			version: [3]int{1, 2, 0},
+			check: func(t *testing.T, result *InstallResult, err error) {
+				if err != nil {
+					t.Fatalf("Expected the upgrade to succeed, got %v", err)
+				}
+				if !result.Success || !result.Updated || result.AlreadyInstalled {
+					t.Errorf("Expected an update, got success=%v already=%v updated=%v",
+						result.Success, result.AlreadyInstalled, result.Updated)
+				}
+				if !containsString(result.Warnings, "Upgrading Pack 1 1.1.0 -> 1.2.0") {
+					t.Errorf("Expected an upgrade warning, got %v", result.Warnings)
+				}
+			},
+		},
+		{
+			name:    "older version conflicts",
+			version: [3]int{1, 0, 0},
+			check: func(t *testing.T, result *InstallResult, err error) {
+				if !errors.Is(err, bberrors.ErrConflict) {
+					t.Fatalf("Expected a conflict error, got %v", err)
+				}
+				if result.Success {
+					t.Error("Expected the conflicting install to fail")
+				}
+				if !containsString(result.Warnings, "is installed at 1.1.0, newer than 1.0.0") {
+					t.Errorf("Expected a conflict warning naming both versions, got %v", result.Warnings)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			installer := NewInstaller(server, t.TempDir())
+			installed := filepath.Join(t.TempDir(), "BP")
+			writeTestPack(t, installed, behaviorUUID, [3]int{1, 1, 0})
+			if _, err := installer.InstallAddon(installed, InstallOptions{}); err != nil {
+				t.Fatalf("Failed to install the pack: %v", err)
+			}
+
+			addonDir := filepath.Join(t.TempDir(), "BP")
+			writeTestPack(t, addonDir, behaviorUUID, tt.version)
+			result, err := installer.InstallAddon(addonDir, InstallOptions{})
+			tt.check(t, result, err)
+
+			packs, listErr := server.ListInstalledPacks()
+			if listErr != nil || len(packs) != 1 {
+				t.Fatalf("Expected one installed pack, got %v (%v)", packs, listErr)
+			}
+			want := [3]int{1, 1, 0}
+			if err == nil && !result.AlreadyInstalled {
+				want = tt.version
+			}
+			if packs[0].Version != want {
+				t.Errorf("Expected version %v installed, got %v", want, packs[0].Version)
+			}
+		})
+	}
+}
+
+func TestInstallUpgradeBackupRestore(t *testing.T) {
+	server := newTestServer(t)
+	// Both installed packs live in directories of the same name
+	oldDirs := []string{filepath.Join(server.Paths.BehaviorPacksDir, "Pack"), filepath.Join(server.Paths.ResourcePacksDir, "Pack")}
+	for i, uuid := range []string{behaviorUUID, resourceUUID} {
+		writeTestPack(t, oldDirs[i], uuid, [3]int{1, 0, 0})
+		if err := os.WriteFile(filepath.Join(oldDirs[i], "old.txt"), []byte(uuid), 0600); err != nil {
+			t.Fatalf("Failed to write pack file: %v", err)
+		}
+	}
+	for config, uuid := range map[string]string{server.Paths.WorldBehaviorPacks: behaviorUUID, server.Paths.WorldResourcePacks: resourceUUID} {
+		entry := `[{"pack_id": "` + uuid + `", "version": [1, 0, 0]}]`
+		if err := os.WriteFile(config, []byte(entry), 0600); err != nil {
+			t.Fatalf("Failed to write world config: %v", err)
+		}
+	}
+	before := make(map[string]map[string]string)
+	for _, dir := range oldDirs {
+		hashes, err := filesystem.HashTree(dir)
+		if err != nil {
+			t.Fatalf("Failed to hash %s: %v", dir, err)
+		}
+		before[dir] = hashes
+	}
+
+	addonDir := t.TempDir()
+	writeTestPack(t, filepath.Join(addonDir, "BP"), behaviorUUID, [3]int{1, 1, 0})
+	writeTestPack(t, filepath.Join(addonDir, "RP"), resourceUUID, [3]int{1, 1, 0})
+	installer := NewInstaller(server, t.TempDir())
+	result, err := installer.InstallAddon(addonDir, InstallOptions{})
+	if err != nil {
+		t.Fatalf("Failed to upgrade the packs: %v", err)
+	}
+	if !result.Updated || result.BackupMetadata == nil {
+		t.Fatalf("Expected a backed-up upgrade, got updated=%v backup=%v", result.Updated, result.BackupMetadata)
+	}
+
+	if err := installer.backupManager.RestoreBackup(result.BackupMetadata.ID); err != nil {
+		t.Fatalf("Failed to restore the install backup: %v", err)
+	}
+	for _, dir := range oldDirs {
+		after, err := filesystem.HashTree(dir)
+		if err != nil {
+			t.Fatalf("Failed to hash %s: %v", dir, err)
+		}
+		if diff := filesystem.DiffHashes(before[dir], after); !diff.Empty() {
+			t.Errorf("Expected %s restored byte for byte, got %+v", dir, diff)
+		}
+	}
+}
+
+func TestInstallDependencyInAnyCase(t *testing.T) {
+	const dependentUUID = "33333333-3333-3333-3333-33333333333c"
+	server := newTestServer(t)
+	installer := NewInstaller(server, t.TempDir())
+	installTestPack(t, installer, behaviorUUID, [3]int{1, 0, 0})
+
+	// The dependency is declared in upper case but installed in lower case
+	dir := filepath.Join(t.TempDir(), "pack")
+	writeTestPack(t, dir, dependentUUID, [3]int{1, 0, 0}, strings.ToUpper(behaviorUUID))
+	result, err := installer.InstallAddon(dir, InstallOptions{})
+	if err != nil {
+		t.Fatalf("Expected the installed dependency found in any case, got %v", err)
+	}
+	if containsString(result.Warnings, "not installed") {
+		t.Errorf("Expected no missing dependency, got %v", result.Warnings)
+	}
+}
+
+// containsString reports whether any of values contains substr
+func containsString(values []string, substr string) bool {
+	for _, value := range values {
+		if strings.Contains(value, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInstallPackSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    PackFilter
+		installed []string // UUIDs expected installed
+		wantErr   string
+	}{
+		{name: "exclude by name", filter: PackFilter{Exclude: []string{"pack 2"}}, installed: []string{behaviorUUID}},
+		{name: "only one type", filter: PackFilter{Only: minecraft.PackTypeResource}, installed: []string{resourceUUID}},
+		{name: "include by UUID", filter: PackFilter{Include: []string{strings.ToUpper(behaviorUUID)}}, installed: []string{behaviorUUID}},
+		{name: "unknown pack", filter: PackFilter{Exclude: []string{"No Such Pack"}}, wantErr: `no pack in the addon matches "No Such Pack"`},
+		{name: "nothing left", filter: PackFilter{Exclude: []string{behaviorUUID, resourceUUID}}, wantErr: "leaves none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			addonDir := t.TempDir()
+			writeTestPack(t, filepath.Join(addonDir, "BP"), behaviorUUID, [3]int{1, 0, 0})
+			writeTestPack(t, filepath.Join(addonDir, "RP"), resourceUUID, [3]int{1, 0, 0})
+
+			installer := NewInstaller(server, t.TempDir())
+			_, err := installer.InstallAddon(addonDir, InstallOptions{Packs: tt.filter})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("InstallAddon failed: %v", err)
+			}
+
+			// Excluded packs are neither copied nor registered
+			packs, err := server.ListInstalledPacks()
+			if err != nil {
+				t.Fatalf("ListInstalledPacks failed: %v", err)
+			}
+			if len(packs) != len(tt.installed) {
+				t.Fatalf("Expected %d installed pack(s), got %v", len(tt.installed), packs)
+			}
+			for i, pack := range packs {
+				if pack.PackID != tt.installed[i] {
+					t.Errorf("Expected %s installed, got %s", tt.installed[i], pack.PackID)
+				}
+			}
+			var copied int
+			for _, dir := range []string{server.Paths.BehaviorPacksDir, server.Paths.ResourcePacksDir} {
+				entries, err := os.ReadDir(dir)
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", dir, err)
+				}
+				copied += len(entries)
+			}
+			if copied != len(tt.installed) {
+				t.Errorf("Expected %d pack directories copied, got %d", len(tt.installed), copied)
+			}
+		})
+	}
+}
+
+func TestInstallPackResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed bool // Both packs are installed beforehand
+		options   InstallOptions
+		want      map[string]PackInstallStatus
+	}{
+		{
+			name: "fresh install",
+			want: map[string]PackInstallStatus{behaviorUUID: PackInstalled, resourceUUID: PackInstalled},
+		},
+		{
+			name:    "excluded pack",
+			options: InstallOptions{Packs: PackFilter{Only: minecraft.PackTypeBehavior}},
+			want:    map[string]PackInstallStatus{behaviorUUID: PackInstalled, resourceUUID: PackExcluded},
+		},
+		{
+			name:    "dry run",
+			options: InstallOptions{DryRun: true},
+			want:    map[string]PackInstallStatus{behaviorUUID: PackWouldInstall, resourceUUID: PackWouldInstall},
+		},
+		{
+			name:      "already installed",
+			installed: true,
+			want:      map[string]PackInstallStatus{behaviorUUID: PackAlreadyInstalled, resourceUUID: PackAlreadyInstalled},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			installer := NewInstaller(server, t.TempDir())
+			if tt.installed {
+				installTestPack(t, installer, behaviorUUID, [3]int{1, 0, 0})
+				installTestPack(t, installer, resourceUUID, [3]int{1, 0, 0})
+			}
+			addonDir := t.TempDir()
+			writeTestPack(t, filepath.Join(addonDir, "BP"), behaviorUUID, [3]int{1, 0, 0})
+			writeTestPack(t, filepath.Join(addonDir, "RP"), resourceUUID, [3]int{1, 0, 0})
+
+			result, err := installer.InstallAddon(addonDir, tt.options)
+			if err != nil {
+				t.Fatalf("InstallAddon failed: %v", err)
+			}
+			if len(result.PackResults) != len(tt.want) {
+				t.Fatalf("Expected one result per pack, got %+v", result.PackResults)
+			}
+			for _, packResult := range result.PackResults {
+				if packResult.Status != tt.want[packResult.PackID] {
+					t.Errorf("Expected %s %s, got %s", packResult.PackID, tt.want[packResult.PackID], packResult.Status)
+				}
+				if packResult.Version != [3]int{1, 0, 0} {
+					t.Errorf("Expected %s at 1.0.0, got %v", packResult.PackID, packResult.Version)
+				}
+			}
+		})
+	}
+}
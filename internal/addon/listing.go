@@ -0,0 +1,134 @@
+package addon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// PackSort orders a pack listing
+type PackSort string
+
+const (
+	PackSortConfig  PackSort = ""        // World config order
+	PackSortName    PackSort = "name"    // By name, A to Z
+	PackSortVersion PackSort = "version" // Newest version first
+	PackSortSize    PackSort = "size"    // Largest pack directory first
+)
+
+// ParsePackSort parses a --sort value
+func ParsePackSort(value string) (PackSort, error) {
+	switch sortBy := PackSort(strings.ToLower(value)); sortBy {
+	case PackSortConfig, PackSortName, PackSortVersion, PackSortSize:
+		return sortBy, nil
+	}
+	return "", fmt.Errorf("invalid sort %q: use name, version, or size", value)
+}
+
+// PackListOptions selects and orders the installed packs to list. The zero
+// value lists every pack in world config order.
+type PackListOptions struct {
+	Filter string             // Keep packs whose name or UUID contains this, ignoring case
+	Type   minecraft.PackType // Keep packs of this type; empty for both
+	Module string             // Keep packs depending on this script module, e.g. @minecraft/server
+	Sort   PackSort
+}
+
+// IsEmpty reports whether the options keep every pack in config order
+func (o PackListOptions) IsEmpty() bool {
+	return o == PackListOptions{}
+}
+
+// SelectInstalledPacks returns the installed packs the options keep, in the
+// order they ask for, with each pack's Size filled in; a pack whose directory
+// is missing counts as empty. Ties keep world config order.
+func SelectInstalledPacks(server *minecraft.Server, packs []minecraft.InstalledPack, options PackListOptions) []minecraft.InstalledPack {
+	filter := strings.ToLower(options.Filter)
+	selected := make([]minecraft.InstalledPack, 0, len(packs))
+	for _, pack := range packs {
+		if filter != "" && !strings.Contains(strings.ToLower(pack.Name), filter) && !strings.Contains(strings.ToLower(pack.PackID), filter) {
+			continue
+		}
+		if options.Type != "" && pack.Type != options.Type {
+			continue
+		}
+		if options.Module != "" && !packUsesModule(server, pack, options.Module) {
+			continue
+		}
+		selected = append(selected, pack)
+	}
+	server.MeasurePackSizes(selected)
+
+	switch options.Sort {
+	case PackSortName:
+		sort.SliceStable(selected, func(i, j int) bool {
+			return strings.ToLower(selected[i].Name) < strings.ToLower(selected[j].Name)
+		})
+	case PackSortVersion:
+		sort.SliceStable(selected, func(i, j int) bool {
+			return validation.CompareVersions(selected[i].Version, selected[j].Version) > 0
+		})
+	case PackSortSize:
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Size > selected[j].Size
+		})
+	}
+	return selected
+}
+
+// SelectDependencyGroup narrows a dependency group to the given packs, as
+// returned by SelectInstalledPacks, ordering each category like them
+func SelectDependencyGroup(group *DependencyGroup, packs []minecraft.InstalledPack) *DependencyGroup {
+	position := make(map[string]int, len(packs))
+	for i, pack := range packs {
+		position[packKey(pack)] = i
+	}
+	selectRelationships := func(relationships []PackRelationship) []PackRelationship {
+		var selected []PackRelationship
+		for _, rel := range relationships {
+			if i, ok := position[packKey(rel.Pack)]; ok {
+				rel.Pack = packs[i]
+				selected = append(selected, rel)
+			}
+		}
+		sort.SliceStable(selected, func(i, j int) bool {
+			return position[packKey(selected[i].Pack)] < position[packKey(selected[j].Pack)]
+		})
+		return selected
+	}
+
+	selected := &DependencyGroup{
+		RootPacks:       selectRelationships(group.RootPacks),
+		DependentPacks:  selectRelationships(group.DependentPacks),
+		StandalonePacks: selectRelationships(group.StandalonePacks),
+	}
+	for _, circle := range group.CircularGroups {
+		if members := selectRelationships(circle); len(members) > 0 {
+			selected.CircularGroups = append(selected.CircularGroups, members)
+		}
+	}
+	return selected
+}
+
+// packKey identifies a pack in a listing; a UUID may be installed as both types
+func packKey(pack minecraft.InstalledPack) string {
+	return string(pack.Type) + "/" + pack.PackID
+}
+
+// packUsesModule reports whether an installed pack's manifest depends on a
+// script module, ignoring case
+func packUsesModule(server *minecraft.Server, pack minecraft.InstalledPack, module string) bool {
+	manifest, err := server.FindAndLoadManifestByUUID(pack.PackID, pack.Type)
+	if err != nil {
+		return false
+	}
+	for _, dependency := range manifest.Dependencies {
+		if strings.EqualFold(dependency.ModuleName, module) {
+			return true
+		}
+	}
+	return false
+}
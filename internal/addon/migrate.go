@@ -0,0 +1,294 @@
+package addon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// ConflictPolicy decides what migrate does with a pack the destination has at
+// another version
+type ConflictPolicy string
+
+const (
+	ConflictFail    ConflictPolicy = "fail"    // Refuse to migrate until the conflict is resolved
+	ConflictSkip    ConflictPolicy = "skip"    // Keep the destination's version
+	ConflictReplace ConflictPolicy = "replace" // Install the source's version over it
+)
+
+// ParseConflictPolicy checks a --on-conflict value
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case ConflictFail, ConflictSkip, ConflictReplace:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (expected %s, %s, or %s)", value, ConflictFail, ConflictSkip, ConflictReplace)
+	}
+}
+
+// MigrateAction is what migrate does with one pack of the source server
+type MigrateAction string
+
+const (
+	MigrateInstall  MigrateAction = "install"  // Not on the destination
+	MigrateReplace  MigrateAction = "replace"  // On the destination at another version, replaced under ConflictReplace
+	MigrateSkip     MigrateAction = "skip"     // Already on the destination, unreadable on the source, or kept under ConflictSkip
+	MigrateConflict MigrateAction = "conflict" // On the destination at another version under ConflictFail
+)
+
+// MigratePack is one pack of the source server and what migrate does with it
+type MigratePack struct {
+	UUID        string             `json:"uuid"`
+	Name        string             `json:"name"`
+	Type        minecraft.PackType `json:"type"`
+	Version     [3]int             `json:"version"`
+	DestVersion *[3]int            `json:"destination_version,omitempty"` // Version on the destination, if installed there
+	Subpack     string             `json:"subpack,omitempty"`
+	Action      MigrateAction      `json:"action"`
+	Reason      string             `json:"reason,omitempty"`
+
+	force bool // Install even though a pack dependency is on neither server
+}
+
+// MigrationPlan is what migrate changes on the destination server. Packs are
+// listed in install order, with every pack after the packs it depends on.
+type MigrationPlan struct {
+	Packs    []MigratePack        `json:"packs"`
+	Moves    []minecraft.PackMove `json:"moves"` // Restore the source's enable order after installing
+	Warnings []string             `json:"warnings"`
+}
+
+// Count returns how many packs the plan gives an action
+func (p *MigrationPlan) Count(action MigrateAction) int {
+	count := 0
+	for _, pack := range p.Packs {
+		if pack.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Empty reports whether the destination already has the source's packs in order
+func (p *MigrationPlan) Empty() bool {
+	return p.Count(MigrateInstall) == 0 && p.Count(MigrateReplace) == 0 && len(p.Moves) == 0
+}
+
+// MigrateOptions contains options for executing a migration
+type MigrateOptions struct {
+	Verbose      bool
+	BackupDir    string
+	AllowScripts bool
+	Description  string // Recorded in the destination backup metadata
+}
+
+// PlanMigration works out how to copy the installed packs of source to dest.
+// Packs the destination already has at the same version are skipped, and
+// packs it has at another version are handled by policy. Afterwards the
+// source's packs take the world config slots they occupy on the destination
+// in the order they have on the source; the destination's other packs keep
+// their positions.
+func PlanMigration(source, dest *minecraft.Server, policy ConflictPolicy) (*MigrationPlan, error) {
+	sourcePacks, err := source.ListInstalledPacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packs on the source server: %w", err)
+	}
+	destPacks, err := dest.ListInstalledPacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packs on the destination server: %w", err)
+	}
+
+	destByUUID := make(map[string]minecraft.InstalledPack, len(destPacks))
+	for _, pack := range destPacks {
+		destByUUID[pack.PackID] = pack
+	}
+	sourceUUIDs := make(map[string]bool, len(sourcePacks))
+	for _, pack := range sourcePacks {
+		sourceUUIDs[pack.PackID] = true
+	}
+
+	plan := &MigrationPlan{Packs: make([]MigratePack, 0, len(sourcePacks)), Moves: make([]minecraft.PackMove, 0), Warnings: make([]string, 0)}
+	dependencies := make(map[string][]string)
+	for _, pack := range sourcePacks {
+		item := MigratePack{UUID: pack.PackID, Name: pack.Name, Type: pack.Type, Version: pack.Version, Subpack: pack.Subpack}
+		if item.Name == "" {
+			item.Name = pack.PackID
+		}
+
+		current, installed := destByUUID[pack.PackID]
+		if installed {
+			version := current.Version
+			item.DestVersion = &version
+		}
+
+		switch {
+		case pack.Status != "" && pack.Status != minecraft.PackStatusOK:
+			item.Action = MigrateSkip
+			item.Reason = fmt.Sprintf("unreadable on the source (%s)", pack.Status)
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s is not migrated: %s", item.Name, item.Reason))
+		case !installed:
+			item.Action = MigrateInstall
+		case validation.CompareVersions(current.Version, pack.Version) == 0:
+			item.Action = MigrateSkip
+			item.Reason = "already installed"
+		case policy == ConflictReplace:
+			item.Action = MigrateReplace
+		case policy == ConflictSkip:
+			item.Action = MigrateSkip
+			item.Reason = "destination keeps its version"
+		default:
+			item.Action = MigrateConflict
+			item.Reason = "destination has another version"
+		}
+
+		if item.Action == MigrateInstall || item.Action == MigrateReplace {
+			manifest, err := source.FindAndLoadManifestByUUID(pack.PackID, pack.Type)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest of %s: %w", item.Name, err)
+			}
+			for _, dep := range manifest.Dependencies {
+				if dep.UUID == "" {
+					continue
+				}
+				dependencies[pack.PackID] = append(dependencies[pack.PackID], dep.UUID)
+				if _, onDest := destByUUID[dep.UUID]; !sourceUUIDs[dep.UUID] && !onDest {
+					item.force = true
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s depends on %s, which is on neither server", item.Name, dep.UUID))
+				}
+			}
+		}
+		plan.Packs = append(plan.Packs, item)
+	}
+
+	plan.Packs = orderMigration(plan.Packs, dependencies)
+	plan.Moves = migrationMoves(plan.Packs, sourcePacks, destPacks)
+	return plan, nil
+}
+
+// orderMigration puts every pack after the packs it depends on, so each
+// install finds its dependencies on the destination. Packs keep their source
+// order otherwise; packs in a dependency cycle keep their original order.
+func orderMigration(packs []MigratePack, dependencies map[string][]string) []MigratePack {
+	pending := make(map[string]bool, len(packs))
+	for _, pack := range packs {
+		pending[pack.UUID] = true
+	}
+
+	ordered := make([]MigratePack, 0, len(packs))
+	for len(ordered) < len(packs) {
+		progressed := false
+		for _, pack := range packs {
+			if !pending[pack.UUID] {
+				continue
+			}
+			blocked := false
+			for _, dep := range dependencies[pack.UUID] {
+				blocked = blocked || (pending[dep] && dep != pack.UUID)
+			}
+			if blocked {
+				continue
+			}
+			ordered = append(ordered, pack)
+			pending[pack.UUID] = false
+			progressed = true
+		}
+		if !progressed {
+			// A cycle: install what's left in the original order
+			for _, pack := range packs {
+				if pending[pack.UUID] {
+					ordered = append(ordered, pack)
+					pending[pack.UUID] = false
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+// migrationMoves works out the moves that give the source's packs their
+// source order on the destination. Installs append new packs to their world
+// config, so the destination order after installing is predicted first; the
+// slots the source's packs occupy then get those packs in source order.
+func migrationMoves(packs []MigratePack, sourcePacks, destPacks []minecraft.InstalledPack) []minecraft.PackMove {
+	moves := make([]minecraft.PackMove, 0)
+	for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+		current := make([]string, 0)
+		present := make(map[string]bool)
+		for _, pack := range destPacks {
+			if pack.Type == packType {
+				current = append(current, pack.PackID)
+				present[pack.PackID] = true
+			}
+		}
+		for _, pack := range packs {
+			if pack.Type == packType && pack.Action == MigrateInstall {
+				current = append(current, pack.UUID)
+				present[pack.UUID] = true
+			}
+		}
+
+		migrated := make(map[string]bool)
+		sourceOrder := make([]string, 0)
+		for _, pack := range sourcePacks {
+			if pack.Type == packType && present[pack.PackID] {
+				sourceOrder = append(sourceOrder, pack.PackID)
+				migrated[pack.PackID] = true
+			}
+		}
+
+		target := make([]string, len(current))
+		next := 0
+		for i, uuid := range current {
+			if migrated[uuid] {
+				target[i] = sourceOrder[next]
+				next++
+			} else {
+				target[i] = uuid
+			}
+		}
+
+		moves = append(moves, minecraft.MovesToOrder(current, target)...)
+	}
+	return moves
+}
+
+// Migrate carries out a migration plan: each pack to install is exported from
+// the source as a .mcpack and installed on the destination, then the moves
+// restore the source's enable order. The destination changes run as one
+// batch with a single backup, restored if any step fails.
+func Migrate(source, dest *minecraft.Server, plan *MigrationPlan, options MigrateOptions) (*BatchResult, error) {
+	if count := plan.Count(MigrateConflict); count > 0 {
+		return nil, fmt.Errorf("%d pack(s) conflict with the destination; choose a conflict policy", count)
+	}
+
+	tempDir, err := os.MkdirTemp("", "blockbench-migrate-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	batch := NewBatch(dest, options.BackupDir)
+	for i, pack := range plan.Packs {
+		if pack.Action != MigrateInstall && pack.Action != MigrateReplace {
+			continue
+		}
+		output := filepath.Join(tempDir, fmt.Sprintf("%03d.mcpack", i))
+		if _, err := ExportPack(source, pack.UUID, true, output, ExportOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to export %s from the source server: %w", pack.Name, err)
+		}
+		batch.Install(output, InstallOptions{
+			BackupDir:    options.BackupDir,
+			ForceUpdate:  pack.Action == MigrateReplace || pack.force,
+			AllowScripts: options.AllowScripts,
+			Subpack:      pack.Subpack,
+		})
+	}
+	for _, move := range plan.Moves {
+		batch.Reorder(move.PackID, move.Index)
+	}
+
+	return batch.Execute(BatchOptions{Verbose: options.Verbose, Description: options.Description})
+}
@@ -0,0 +1,115 @@
+package addon
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+// installTestPack installs a pack of uuid at version with installer
+func installTestPack(t *testing.T, installer *Installer, uuid string, version [3]int, dependsOn ...string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "pack")
+	writeTestPack(t, dir, uuid, version, dependsOn...)
+	if _, err := installer.InstallAddon(dir, InstallOptions{}); err != nil {
+		t.Fatalf("Failed to install %s: %v", uuid, err)
+	}
+}
+
+// worldConfigOrder returns the UUIDs and versions in a world config, in order
+func worldConfigOrder(t *testing.T, configFile string) ([]string, map[string][3]int) {
+	t.Helper()
+	config, err := minecraft.LoadWorldConfig(configFile)
+	if err != nil {
+		t.Fatalf("Failed to load %s: %v", configFile, err)
+	}
+	var order []string
+	versions := make(map[string][3]int)
+	for _, pack := range config {
+		order = append(order, pack.PackID)
+		versions[pack.PackID] = pack.Version
+	}
+	return order, versions
+}
+
+func TestMigrate(t *testing.T) {
+	const (
+		dependentUUID = "33333333-3333-3333-3333-33333333333c"
+		destOnlyUUID  = "44444444-4444-4444-4444-44444444444d"
+	)
+	source := newTestServer(t)
+	installer := NewInstaller(source, t.TempDir())
+	installTestPack(t, installer, behaviorUUID, [3]int{1, 0, 0})
+	installTestPack(t, installer, dependentUUID, [3]int{1, 0, 0}, behaviorUUID)
+	installTestPack(t, installer, resourceUUID, [3]int{1, 0, 0})
+	// The source enables the dependent pack before its dependency
+	config := minecraft.WorldConfig{{PackID: dependentUUID, Version: [3]int{1, 0, 0}}, {PackID: behaviorUUID, Version: [3]int{1, 0, 0}}}
+	if err := minecraft.SaveWorldConfig(source.Paths.WorldBehaviorPacks, config); err != nil {
+		t.Fatalf("Failed to reorder the source: %v", err)
+	}
+
+	dest := newTestServer(t)
+	installer = NewInstaller(dest, t.TempDir())
+	installTestPack(t, installer, destOnlyUUID, [3]int{1, 0, 0})
+	installTestPack(t, installer, behaviorUUID, [3]int{0, 9, 0})
+	installTestPack(t, installer, resourceUUID, [3]int{1, 0, 0})
+
+	plan, err := PlanMigration(source, dest, ConflictFail)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if plan.Count(MigrateConflict) != 1 {
+		t.Errorf("Expected the pack at another version to conflict, got %+v", plan.Packs)
+	}
+	if _, err := Migrate(source, dest, plan, MigrateOptions{BackupDir: t.TempDir()}); err == nil {
+		t.Fatal("Expected a migration with conflicts to fail")
+	}
+
+	plan, err = PlanMigration(source, dest, ConflictReplace)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	actions := make(map[string]MigrateAction)
+	position := make(map[string]int)
+	for i, pack := range plan.Packs {
+		actions[pack.UUID] = pack.Action
+		position[pack.UUID] = i
+	}
+	if actions[dependentUUID] != MigrateInstall || actions[behaviorUUID] != MigrateReplace || actions[resourceUUID] != MigrateSkip {
+		t.Errorf("Expected install, replace, and skip (already installed), got %v", actions)
+	}
+	if position[behaviorUUID] > position[dependentUUID] {
+		t.Errorf("Expected the dependency planned before the pack depending on it, got %+v", plan.Packs)
+	}
+
+	result, err := Migrate(source, dest, plan, MigrateOptions{BackupDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("Expected the migration to succeed, got %+v", result)
+	}
+
+	// The migrated packs take the source's order in the slots they occupy
+	order, versions := worldConfigOrder(t, dest.Paths.WorldBehaviorPacks)
+	want := []string{destOnlyUUID, dependentUUID, behaviorUUID}
+	if len(order) != len(want) || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("Expected behavior packs %v, got %v", want, order)
+	}
+	if versions[behaviorUUID] != [3]int{1, 0, 0} || versions[dependentUUID] != [3]int{1, 0, 0} {
+		t.Errorf("Expected the source's versions on the destination, got %v", versions)
+	}
+	if order, _ := worldConfigOrder(t, dest.Paths.WorldResourcePacks); len(order) != 1 || order[0] != resourceUUID {
+		t.Errorf("Expected the resource pack kept once, got %v", order)
+	}
+
+	// Everything is on the destination now
+	plan, err = PlanMigration(source, dest, ConflictFail)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Expected nothing left to migrate, got %+v", plan)
+	}
+}
@@ -0,0 +1,44 @@
+package addon
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// ownershipWarning warns when the installed files would belong to another
+// user than the server directory: a server running as its owner may then be
+// unable to read the packs or rewrite its world configs. It is empty when the
+// install sets an owner, links the packs, or the owners match.
+func (i *Installer) ownershipWarning(options InstallOptions) string {
+	if options.Link || (options.Ownership != nil && options.Ownership.Owner != nil) {
+		return ""
+	}
+	uid, gid, ok := filesystem.OwnerOf(i.server.Paths.ServerRoot)
+	euid := os.Geteuid()
+	if !ok || euid < 0 || euid == uid {
+		return ""
+	}
+	owner := userName(uid)
+	return fmt.Sprintf("The server directory is owned by %s, but the installed files will be owned by %s; use --chown %s:%s so a server running as %s can read them",
+		owner, userName(euid), owner, groupName(gid), owner)
+}
+
+// userName returns the name of a user ID, or the ID when it has no account
+func userName(uid int) string {
+	if account, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return account.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// groupName returns the name of a group ID, or the ID when it has no group
+func groupName(gid int) string {
+	if group, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		return group.Name
+	}
+	return strconv.Itoa(gid)
+}
@@ -0,0 +1,43 @@
+package addon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// longPathWarnings warns about packs with files whose installed paths are
+// too long for Windows programs without long path support, which may include
+// the server. Linked packs are skipped; their files stay where they are.
+func (i *Installer) longPathWarnings(addon *ExtractedAddon, link bool) ([]string, error) {
+	style := filesystem.HostPathStyle
+	if !style.Windows || link {
+		return nil, nil
+	}
+	var warnings []string
+	for _, pack := range addon.GetAllPacks() {
+		packDir, _, err := i.server.PackInstallPaths(pack.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		if packDir, err = filepath.Abs(packDir); err != nil {
+			return nil, err
+		}
+		_, files, err := packContents(pack)
+		if err != nil {
+			return nil, err
+		}
+		long := 0
+		for _, file := range files {
+			if style.TooLong(filepath.Join(packDir, filepath.FromSlash(file))) {
+				long++
+			}
+		}
+		if long > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d file(s) of %s would have paths too long for programs without Windows long path support under %s; a shorter server directory avoids this",
+				long, pack.Manifest.GetDisplayName(), packDir))
+		}
+	}
+	return warnings, nil
+}
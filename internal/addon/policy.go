@@ -0,0 +1,74 @@
+package addon
+
+import (
+	"os"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// worldFileWrites lists the server files every install and uninstall may
+// write: the configs directly, the histories when a failure restores the
+// backup, and the manifest index cache whenever pack directories change
+func worldFileWrites(paths *minecraft.ServerPaths) []filesystem.PlannedWrite {
+	files := []string{paths.WorldBehaviorPacks, paths.WorldResourcePacks, paths.WorldBehaviorHistory, paths.WorldResourceHistory}
+	writes := make([]filesystem.PlannedWrite, 0, len(files)+1)
+	for _, file := range files {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "update world file", Path: file})
+	}
+	return append(writes, filesystem.PlannedWrite{Operation: "update manifest index", Path: paths.IndexFile})
+}
+
+// extractionWrites lists the temporary directory used to unpack an archive
+func extractionWrites(addonPath string) []filesystem.PlannedWrite {
+	if IsAddonDirectory(addonPath) {
+		return nil
+	}
+	return []filesystem.PlannedWrite{{Operation: "extract to temporary directory (set TMPDIR to an allowed path)", Path: os.TempDir()}}
+}
+
+// plannedInstallWrites lists every location an install of the extracted addon writes to
+func (i *Installer) plannedInstallWrites(addon *ExtractedAddon, options InstallOptions) ([]filesystem.PlannedWrite, error) {
+	writes := worldFileWrites(i.server.Paths)
+	if options.batchBackup == nil {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "create backup", Path: i.backupManager.BackupRoot})
+	}
+	if options.Dedupe {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "deduplicate into content store", Path: i.server.Paths.StoreDir})
+	}
+	if options.EnableExperiments || options.RequireOnClients {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "update server.properties", Path: i.server.Paths.ServerProperties})
+	}
+	if options.EnableExperiments {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "turn on world experiments", Path: i.server.Paths.LevelDat})
+	}
+	for _, pack := range addon.GetAllPacks() {
+		packDir, _, err := i.server.PackInstallPaths(pack.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		operation := "install pack files"
+		if options.Link {
+			operation = "link pack"
+		}
+		writes = append(writes, filesystem.PlannedWrite{Operation: operation, Path: packDir})
+	}
+	return writes, nil
+}
+
+// plannedUninstallWrites lists every location removing a pack writes to
+func (u *Uninstaller) plannedUninstallWrites(packID string, options UninstallOptions) ([]filesystem.PlannedWrite, error) {
+	writes := worldFileWrites(u.server.Paths)
+	if options.batchBackup == nil {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "create backup", Path: u.backupManager.BackupRoot})
+	}
+
+	packDirs, err := u.backupManager.findAddonDirectories(packID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range packDirs {
+		writes = append(writes, filesystem.PlannedWrite{Operation: "remove pack files", Path: dir})
+	}
+	return writes, nil
+}
@@ -0,0 +1,93 @@
+package addon
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/pkg/minecraft/properties"
+)
+
+// experimentProperties are the server.properties settings EnableExperiments
+// turns on. Cheats are what command-driven and experimental packs need from
+// the server; the world's experiment toggles live in level.dat and are only
+// turned on after a confirmation.
+var experimentProperties = []PropertyChange{
+	{Key: "allow-cheats", New: "true"},
+}
+
+// clientPackProperties are the server.properties settings RequireOnClients
+// turns on. With texture-pack-required, players must accept the world's
+// resource packs to join, so every client downloads them; without it they
+// may decline and play without the packs.
+var clientPackProperties = []PropertyChange{
+	{Key: "texture-pack-required", New: "true"},
+}
+
+// PropertyChange is a server.properties setting an install changed, or would
+// change on a dry run
+type PropertyChange struct {
+	Key string `json:"key"`
+	Old string `json:"old"` // Empty when the key was not set
+	New string `json:"new"`
+}
+
+func (c PropertyChange) String() string {
+	if c.Old == "" {
+		return fmt.Sprintf("%s=%s", c.Key, c.New)
+	}
+	return fmt.Sprintf("%s=%s (was %s)", c.Key, c.New, c.Old)
+}
+
+// propertyChanges returns the server.properties settings the options turn on
+// that the server doesn't have yet, and a warning when the addon has resource
+// packs that players may decline
+func (i *Installer) propertyChanges(extractedAddon *ExtractedAddon, options InstallOptions) ([]PropertyChange, []string, error) {
+	var wanted []PropertyChange
+	if options.EnableExperiments {
+		wanted = append(wanted, experimentProperties...)
+	}
+	if options.RequireOnClients {
+		wanted = append(wanted, clientPackProperties...)
+	}
+	hasResourcePacks := len(extractedAddon.ResourcePacks) > 0
+	if len(wanted) == 0 && !hasResourcePacks {
+		return nil, nil, nil
+	}
+
+	file, err := properties.Load(i.server.Paths.ServerProperties)
+	if err != nil {
+		if len(wanted) == 0 {
+			return nil, nil, nil // Only needed for the warning
+		}
+		return nil, nil, err
+	}
+
+	var changes []PropertyChange
+	for _, change := range wanted {
+		value, _ := file.Get(change.Key)
+		if value != change.New {
+			change.Old = value
+			changes = append(changes, change)
+		}
+	}
+
+	var warnings []string
+	if required, _ := file.Get("texture-pack-required"); hasResourcePacks && !options.RequireOnClients && required != "true" {
+		warnings = append(warnings, "texture-pack-required is not true in server.properties, so players may decline the resource packs and join without them (use --require-on-clients to require them)")
+	}
+	return changes, warnings, nil
+}
+
+// applyPropertyChanges writes changes to the server's server.properties
+func (i *Installer) applyPropertyChanges(changes []PropertyChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	file, err := properties.Load(i.server.Paths.ServerProperties)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		file.Set(change.Key, change.New)
+	}
+	return file.Save()
+}
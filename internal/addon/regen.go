@@ -0,0 +1,178 @@
+package addon
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// UUIDChange is a header or module UUID RegenerateUUIDs replaced
+type UUIDChange struct {
+	Directory string `json:"directory"`
+	Field     string `json:"field"` // "header", or "module" with the module's type
+	Old       string `json:"old"`
+	New       string `json:"new"`
+}
+
+// RewrittenManifest is a manifest.json RegenerateUUIDs rewrote, with how
+// many of its UUIDs were replaced, references to the regenerated packs
+// included
+type RewrittenManifest struct {
+	Directory string `json:"directory"`
+	Replaced  int    `json:"replaced"`
+}
+
+// RegenResult describes the UUIDs RegenerateUUIDs replaced
+type RegenResult struct {
+	Changes   []UUIDChange        `json:"changes"`
+	Manifests []RewrittenManifest `json:"manifests"`
+	Originals []string            `json:"originals,omitempty"` // Siblings left alone because they have a regenerated pack's original UUID
+}
+
+// RegenerateUUIDs gives packs new header and module UUIDs, for forking a pack
+// without conflicting with the original. dir is either a pack directory,
+// with a manifest.json at its root, or an addon directory whose
+// subdirectories are packs, in which case every pack is regenerated. Every
+// occurrence of an old UUID is replaced consistently, so the dependencies
+// between the regenerated packs keep working, and the dependencies of sibling
+// packs in the same parent directory on a regenerated pack are updated too. A
+// sibling with a regenerated pack's original header UUID is the pack it was
+// copied from, and is left alone.
+func RegenerateUUIDs(dir string, dryRun bool) (*RegenResult, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	targets, siblings, err := findRegenPacks(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RegenResult{}
+	mapping := make(map[string]string)
+	regenerate := func(dir, field, uuid string) error {
+		if !validation.ValidateUUID(uuid) {
+			return fmt.Errorf("%s: invalid %s UUID %q", dir, field, uuid)
+		}
+		key := strings.ToLower(uuid)
+		if _, ok := mapping[key]; ok {
+			// The same UUID twice within the packs keeps one replacement
+			return nil
+		}
+		replacement, err := validation.NewUUID()
+		if err != nil {
+			return err
+		}
+		mapping[key] = replacement
+		result.Changes = append(result.Changes, UUIDChange{Directory: dir, Field: field, Old: uuid, New: replacement})
+		return nil
+	}
+	for _, target := range targets {
+		manifest, err := minecraft.ParseManifest(filepath.Join(target, "manifest.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		if err := regenerate(target, "header", manifest.Header.UUID); err != nil {
+			return nil, err
+		}
+		for _, module := range manifest.Modules {
+			if err := regenerate(target, "module "+module.Type, module.UUID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	dirs := append([]string{}, targets...)
+	for _, sibling := range siblings {
+		manifest, err := minecraft.ParseManifest(filepath.Join(sibling, "manifest.json"))
+		if err != nil {
+			slog.Warn("Could not read sibling pack; its references are not updated", "dir", sibling, "error", err)
+			continue
+		}
+		if _, ok := mapping[strings.ToLower(manifest.Header.UUID)]; ok {
+			result.Originals = append(result.Originals, sibling)
+			continue
+		}
+		dirs = append(dirs, sibling)
+	}
+
+	// Open every manifest before saving any, so a broken one leaves all of
+	// them untouched
+	files := make([]*minecraft.ManifestFile, len(dirs))
+	for i, packDir := range dirs {
+		file, err := minecraft.OpenManifestFile(filepath.Join(packDir, "manifest.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", packDir, err)
+		}
+		files[i] = file
+	}
+	for i, file := range files {
+		replaced := file.ReplaceUUIDs(mapping)
+		if replaced == 0 {
+			continue
+		}
+		if !dryRun {
+			if err := file.Save(); err != nil {
+				return nil, err
+			}
+		}
+		result.Manifests = append(result.Manifests, RewrittenManifest{Directory: dirs[i], Replaced: replaced})
+	}
+	return result, nil
+}
+
+// findRegenPacks returns the pack directories to regenerate and the sibling
+// pack directories whose references to them are updated; dir is absolute
+func findRegenPacks(dir string) (targets, siblings []string, err error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if isPackDir(dir) {
+		for _, packDir := range packSubdirs(filepath.Dir(dir)) {
+			if packDir != dir {
+				siblings = append(siblings, packDir)
+			}
+		}
+		return []string{dir}, siblings, nil
+	}
+
+	targets = packSubdirs(dir)
+	if len(targets) == 0 {
+		return nil, nil, fmt.Errorf("%s has no manifest.json and no pack subdirectories", dir)
+	}
+	return targets, nil, nil
+}
+
+// packSubdirs returns the subdirectories of dir with a manifest.json, sorted
+func packSubdirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && isPackDir(path) {
+			dirs = append(dirs, path)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// isPackDir reports whether dir has a manifest.json at its root
+func isPackDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "manifest.json"))
+	return err == nil && !info.IsDir()
+}
@@ -2,8 +2,11 @@ package addon
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 
+	"github.com/makutaku/blockbench/internal/history"
+	"github.com/makutaku/blockbench/internal/hooks"
 	"github.com/makutaku/blockbench/internal/minecraft"
 	"github.com/makutaku/blockbench/pkg/filesystem"
 )
@@ -12,36 +15,136 @@ import (
 type RollbackManager struct {
 	server        *minecraft.Server
 	backupManager *BackupManager
+	undoBackups   *BackupManager // Takes the backups of the files a restore overwrites
 }
 
 // NewRollbackManager creates a new rollback manager
 func NewRollbackManager(server *minecraft.Server, backupDir string) *RollbackManager {
+	backupManager := NewBackupManager(server, backupDir)
 	return &RollbackManager{
 		server:        server,
-		backupManager: NewBackupManager(server, backupDir),
+		backupManager: backupManager,
+		undoBackups:   backupManager,
 	}
 }
 
+// NewCrossServerRollbackManager creates a rollback manager that restores the
+// backups in backupDir, taken of another server, into server: their files are
+// rebased from the server they were taken of onto server's root. The backups
+// of the files a restore overwrites go to undoBackupDir, server's own backup
+// directory, so 'undo' on server reverses the restore.
+func NewCrossServerRollbackManager(server *minecraft.Server, backupDir, undoBackupDir string) *RollbackManager {
+	backupManager := NewBackupManager(server, backupDir)
+	backupManager.RestoreRoot = server.Paths.ServerRoot
+	return &RollbackManager{
+		server:        server,
+		backupManager: backupManager,
+		undoBackups:   NewBackupManager(server, undoBackupDir),
+	}
+}
+
+// Server returns the server backups are restored to
+func (rm *RollbackManager) Server() *minecraft.Server {
+	return rm.server
+}
+
 // RollbackOptions contains options for rollback operations
 type RollbackOptions struct {
 	Verbose bool
 	DryRun  bool
+	Merge   bool          // Keep packs activated after the backup was taken instead of deactivating them
+	Only    string        // Restore only this pack UUID or file of the backup; see BackupManager.SelectFiles
+	Hooks   *hooks.Runner // Runs the post-rollback hooks; nil runs none
 }
 
 // RollbackResult contains the result of a rollback operation
 type RollbackResult struct {
-	Success       bool
-	BackupID      string
-	RestoredFiles []string
-	Errors        []string
+	Success       bool                    `json:"success"`
+	BackupID      string                  `json:"backup_id"`
+	RestoredFiles []string                `json:"restored_files"`
+	Plan          *filesystem.RestorePlan `json:"plan,omitempty"` // Changes the restore makes (or would make in a dry run)
+	LaterPacks    []LaterPack             `json:"later_packs,omitempty"`
+	Merged        bool                    `json:"merged"`
+	UndoBackupID  string                  `json:"undo_backup_id,omitempty"` // Backup of the files the restore overwrote, taken first so it can be undone
+	Health        *RestoreHealth          `json:"health,omitempty"`         // Checks of the server right after the restore
+	Errors        []string                `json:"errors"`
+	Warnings      []string                `json:"warnings"`
+}
+
+// LaterPack is a pack activated in a world config after a backup was taken.
+// Restoring the backup's world configs deactivates it unless the restore merges.
+type LaterPack struct {
+	ConfigFile string                  `json:"config_file"`
+	Pack       minecraft.PackReference `json:"pack"`
 }
 
 // RollbackToBackup performs a rollback to a specific backup
 func (rm *RollbackManager) RollbackToBackup(backupID string, options RollbackOptions) (*RollbackResult, error) {
+	logger := slog.With("backup", backupID, "server", rm.server.Paths.ServerRoot)
+	logger.Info("Restoring backup", "merge", options.Merge, "dry_run", options.DryRun)
+
+	result, err := rm.rollbackToBackup(backupID, options)
+	switch {
+	case err != nil:
+		// The caller reports the error itself, so keep this record off the console
+		logger.Info("Restore failed", "error", err)
+	case !options.DryRun:
+		logger.Info("Backup restore finished", "files", len(result.RestoredFiles), "merged", result.Merged)
+	}
+
+	if !options.DryRun {
+		entry := rollbackHistoryEntry("", backupID, err)
+		entry.UndoBackupID = result.UndoBackupID
+		recordHistory(rm.server, entry)
+	}
+	return result, err
+}
+
+// Undo reverses an operation from the server's history by restoring the
+// backup that undoes it, and records the undo in the history
+func (rm *RollbackManager) Undo(entry history.Entry, options RollbackOptions) (*RollbackResult, error) {
+	backupID, err := entry.UndoBackup()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := slog.With("operation", entry.Operation, "time", entry.Time, "backup", backupID, "server", rm.server.Paths.ServerRoot)
+	logger.Info("Undoing operation", "dry_run", options.DryRun)
+
+	result, err := rm.rollbackToBackup(backupID, options)
+	if err != nil {
+		// The caller reports the error itself, so keep this record off the console
+		logger.Info("Undo failed", "error", err)
+	} else if !options.DryRun {
+		logger.Info("Undid operation")
+	}
+
+	if !options.DryRun {
+		undoes := entry.Time
+		undo := history.Entry{
+			Operation:    history.OperationUndo,
+			Success:      err == nil,
+			Addon:        entry.Addon,
+			Target:       entry.Target,
+			Packs:        entry.Packs,
+			BackupID:     backupID,
+			UndoBackupID: result.UndoBackupID,
+			Undoes:       &undoes,
+		}
+		if err != nil {
+			undo.Error = err.Error()
+		}
+		recordHistory(rm.server, undo)
+	}
+	return result, err
+}
+
+func (rm *RollbackManager) rollbackToBackup(backupID string, options RollbackOptions) (*RollbackResult, error) {
 	result := &RollbackResult{
 		BackupID:      backupID,
 		RestoredFiles: make([]string, 0),
 		Errors:        make([]string, 0),
+		Warnings:      make([]string, 0),
 	}
 
 	if options.Verbose {
@@ -55,44 +158,210 @@ func (rm *RollbackManager) RollbackToBackup(backupID string, options RollbackOpt
 		return result, err
 	}
 
+	files, err := rm.backupManager.SelectFiles(metadata, options.Only)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result, err
+	}
+
 	if options.Verbose {
 		fmt.Printf("Backup found: %s (created: %s)\n", metadata.Description, metadata.Timestamp.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Files to restore: %d\n", len(metadata.Files))
+		fmt.Printf("Files to restore: %d\n", len(files))
+	}
+
+	plan, err := rm.backupManager.PlanRestoreFiles(backupID, files)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to plan rollback: %v", err))
+		return result, err
+	}
+	result.Plan = plan
+
+	laterPacks, err := rm.findLaterPacks(metadata, files)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to check for packs added after the backup: %v", err))
+		return result, err
+	}
+	result.LaterPacks = laterPacks
+	for _, later := range laterPacks {
+		if options.Merge {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Pack %s was activated after the backup and will be kept", later.Pack.PackID))
+		} else {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Pack %s was activated after the backup and will be deactivated", later.Pack.PackID))
+		}
 	}
 
 	if options.DryRun {
 		if options.Verbose {
-			fmt.Println("DRY RUN: Would restore the following files:")
-			for _, file := range metadata.Files {
-				fmt.Printf("  - %s\n", file)
-			}
+			fmt.Printf("DRY RUN: Restore would change %d file(s)\n", len(plan.Changes))
 		}
 		result.Success = true
-		result.RestoredFiles = metadata.Files
+		result.RestoredFiles = files
 		return result, nil
 	}
 
+	// Back up what the restore overwrites so it can be undone
+	undoBackup, err := rm.undoBackups.CreateRestoreBackup(metadata, files)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+		return result, fmt.Errorf("failed to back up the files the restore overwrites: %w", err)
+	}
+	result.UndoBackupID = undoBackup.ID
+
 	// Perform the rollback
-	if err := rm.backupManager.RestoreBackup(backupID); err != nil {
+	health, err := rm.backupManager.RestoreFilesAndVerify(backupID, files)
+	result.Health = health
+	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", err))
 		return result, err
 	}
+	result.Warnings = append(result.Warnings, health.Warnings...)
+
+	if options.Merge && len(laterPacks) > 0 {
+		err := mergeLaterPacks(rm.server.FS, laterPacks)
+		rm.server.InvalidatePacks()
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to keep packs added after the backup: %v", err))
+			return result, err
+		}
+		result.Merged = true
+		if options.Verbose {
+			fmt.Printf("Kept %d pack(s) activated after the backup\n", len(laterPacks))
+		}
+	}
 
 	result.Success = true
-	result.RestoredFiles = metadata.Files
+	result.RestoredFiles = files
 
 	if options.Verbose {
 		fmt.Printf("Successfully rolled back %d files\n", len(result.RestoredFiles))
 	}
 
+	payload := hookPayload(rm.server, hooks.PostRollback)
+	payload.Operation = "restore"
+	payload.BackupID = backupID
+	payload.Warnings = result.Warnings
+	result.Warnings = append(result.Warnings, runPostHooks(options.Hooks, payload)...)
+
 	return result, nil
 }
 
+// PlanRollback lists the files a rollback to the backup would create, overwrite, or delete,
+// with line diffs for changed config files. only limits the rollback as RollbackOptions.Only does.
+func (rm *RollbackManager) PlanRollback(backupID, only string) (*filesystem.RestorePlan, error) {
+	metadata, err := rm.backupManager.LoadMetadata(backupID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := rm.backupManager.SelectFiles(metadata, only)
+	if err != nil {
+		return nil, err
+	}
+	return rm.backupManager.PlanRestoreFiles(backupID, files)
+}
+
+// FindLaterPacks lists the packs activated in the world configs since the backup
+// was taken, excluding the packs of the backed-up operation itself. Restoring
+// the backup deactivates these packs unless RollbackOptions.Merge is set. only
+// limits the restore as RollbackOptions.Only does, so packs are only found when
+// it restores a world config.
+func (rm *RollbackManager) FindLaterPacks(backupID, only string) ([]LaterPack, error) {
+	metadata, err := rm.backupManager.LoadMetadata(backupID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := rm.backupManager.SelectFiles(metadata, only)
+	if err != nil {
+		return nil, err
+	}
+	return rm.findLaterPacks(metadata, files)
+}
+
+func (rm *RollbackManager) findLaterPacks(metadata *filesystem.BackupMetadata, files []string) ([]LaterPack, error) {
+	operationPacks := make(map[string]bool, len(metadata.PackUUIDs))
+	for _, uuid := range metadata.PackUUIDs {
+		operationPacks[uuid] = true
+	}
+
+	worldConfigs := map[string]bool{
+		rm.server.Paths.WorldBehaviorPacks: true,
+		rm.server.Paths.WorldResourcePacks: true,
+	}
+
+	var laterPacks []LaterPack
+	for _, file := range files {
+		if !worldConfigs[file] {
+			continue
+		}
+
+		// A config that did not exist at backup time is removed by the restore
+		backedUp := minecraft.WorldConfig{}
+		copyPath, existed, err := rm.backupManager.BackedUpCopy(metadata, file)
+		if err != nil {
+			return nil, err
+		}
+		if existed {
+			backedUp, err = minecraft.LoadWorldConfigFS(rm.server.FS, copyPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load backed up config %s: %w", file, err)
+			}
+		}
+
+		current, err := minecraft.LoadWorldConfigFS(rm.server.FS, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config %s: %w", file, err)
+		}
+
+		for _, pack := range current {
+			if !backedUp.HasPack(pack.PackID) && !operationPacks[pack.PackID] {
+				laterPacks = append(laterPacks, LaterPack{ConfigFile: file, Pack: pack})
+			}
+		}
+	}
+
+	return laterPacks, nil
+}
+
+// mergeLaterPacks re-appends packs to the restored world configs on fsys, keeping their subpack and extra fields
+func mergeLaterPacks(fsys filesystem.FS, laterPacks []LaterPack) error {
+	byConfig := make(map[string][]minecraft.PackReference)
+	var order []string
+	for _, later := range laterPacks {
+		if _, seen := byConfig[later.ConfigFile]; !seen {
+			order = append(order, later.ConfigFile)
+		}
+		byConfig[later.ConfigFile] = append(byConfig[later.ConfigFile], later.Pack)
+	}
+
+	for _, configFile := range order {
+		restored, err := minecraft.LoadWorldConfigFS(fsys, configFile)
+		if err != nil {
+			return fmt.Errorf("failed to load restored config %s: %w", configFile, err)
+		}
+		for _, pack := range byConfig[configFile] {
+			if !restored.HasPack(pack.PackID) {
+				restored = append(restored, pack)
+			}
+		}
+		if err := minecraft.SaveWorldConfigFS(fsys, configFile, restored); err != nil {
+			return fmt.Errorf("failed to save merged config %s: %w", configFile, err)
+		}
+	}
+	return nil
+}
+
 // ListAvailableBackups returns a list of available backups for rollback
 func (rm *RollbackManager) ListAvailableBackups() ([]filesystem.BackupMetadata, error) {
 	return rm.backupManager.ListBackups()
 }
 
+// ResolveBackupID returns the backup ID a selector names, such as
+// filesystem.LatestBackup for the newest backup
+func (rm *RollbackManager) ResolveBackupID(selector string) (string, error) {
+	return rm.backupManager.ResolveBackupID(selector)
+}
+
 // GetBackupInfo returns detailed information about a specific backup
 func (rm *RollbackManager) GetBackupInfo(backupID string) (*filesystem.BackupMetadata, error) {
 	backups, err := rm.ListAvailableBackups()
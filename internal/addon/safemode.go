@@ -0,0 +1,317 @@
+package addon
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// safeModeStateFile records the snapshot backing an active safe mode
+const safeModeStateFile = "state.json"
+
+//go:embed schemas/safe_mode_state.json
+var safeModeStateSchemaData []byte
+
+// SafeModeOptions contains options for safe mode operations
+type SafeModeOptions struct {
+	DryRun  bool
+	Verbose bool
+}
+
+// SafeModeState describes an active safe mode snapshot
+type SafeModeState struct {
+	BackupID      string    `json:"backup_id"`
+	EnabledAt     time.Time `json:"enabled_at"`
+	BehaviorPacks int       `json:"behavior_packs"`
+	ResourcePacks int       `json:"resource_packs"`
+}
+
+// SafeModeResult contains the result of a safe mode operation
+type SafeModeResult struct {
+	Success       bool
+	BehaviorPacks int // Packs deactivated (enable) or reactivated (disable)
+	ResourcePacks int
+	Warnings      []string
+}
+
+// SafeModeManager deactivates all packs in the world configs while keeping
+// their files, and restores the previous configs on demand
+type SafeModeManager struct {
+	server        *minecraft.Server
+	backupManager *filesystem.BackupManager
+	stateDir      string
+}
+
+// NewSafeModeManager creates a new safe mode manager
+func NewSafeModeManager(server *minecraft.Server) *SafeModeManager {
+	stateDir := filepath.Join(server.Paths.StateDir, "safe-mode")
+	backups := filesystem.NewBackupManager(stateDir)
+	backups.FS = server.FS
+	backups.ServerRoot = server.Paths.ServerRoot
+	return &SafeModeManager{
+		server:        server,
+		backupManager: backups,
+		stateDir:      stateDir,
+	}
+}
+
+// fs returns the filesystem of the server, where the state and snapshots live too
+func (sm *SafeModeManager) fs() filesystem.FS {
+	if sm.server.FS == nil {
+		return filesystem.OSFS{}
+	}
+	return sm.server.FS
+}
+
+// Status returns the active safe mode state, or nil if safe mode is not enabled
+func (sm *SafeModeManager) Status() (*SafeModeState, error) {
+	// #nosec G304 - state file lives in the server's blockbench state directory
+	data, err := filesystem.ReadFile(sm.server.FS, sm.StateFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read safe mode state: %w", err)
+	}
+
+	problems, err := validateSafeModeState(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid safe mode state %s: %s (run 'blockbench state fsck' to repair)",
+			sm.StateFile(), strings.Join(problems, "; "))
+	}
+
+	var state SafeModeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse safe mode state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// CheckState validates the state file against its schema and returns the
+// problems found; it returns nil if the file is valid or safe mode is not enabled
+func (sm *SafeModeManager) CheckState() ([]string, error) {
+	// #nosec G304 - state file lives in the server's blockbench state directory
+	data, err := filesystem.ReadFile(sm.server.FS, sm.StateFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read safe mode state: %w", err)
+	}
+	return validateSafeModeState(data)
+}
+
+func validateSafeModeState(data []byte) ([]string, error) {
+	schema, err := validation.ParseRecordSchema(safeModeStateSchemaData)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Validate(data), nil
+}
+
+// StateFile returns the path of the safe mode state file
+func (sm *SafeModeManager) StateFile() string {
+	return filepath.Join(sm.stateDir, safeModeStateFile)
+}
+
+// BackupManager returns the manager of the safe mode snapshots
+func (sm *SafeModeManager) BackupManager() *filesystem.BackupManager {
+	return sm.backupManager
+}
+
+// QuarantineState moves an unreadable state file aside, which leaves safe mode
+// disabled. The snapshot it pointed to is kept and can still be restored.
+func (sm *SafeModeManager) QuarantineState() (string, error) {
+	quarantine := filepath.Join(sm.stateDir, filesystem.QuarantineDir)
+	if err := sm.fs().MkdirAll(quarantine, filesystem.DefaultDirPerm); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	target := filepath.Join(quarantine, fmt.Sprintf("%s.%d", safeModeStateFile, time.Now().Unix()))
+	if err := sm.fs().Rename(sm.StateFile(), target); err != nil {
+		return "", fmt.Errorf("failed to quarantine safe mode state: %w", err)
+	}
+	return target, nil
+}
+
+// Enable snapshots the current world configs and replaces them with empty pack lists
+func (sm *SafeModeManager) Enable(options SafeModeOptions) (*SafeModeResult, error) {
+	result := &SafeModeResult{Warnings: make([]string, 0)}
+
+	state, err := sm.Status()
+	if err != nil {
+		return result, err
+	}
+	if state != nil {
+		return result, fmt.Errorf("safe mode is already enabled (since %s). Run 'blockbench safe-mode disable <server-path>' first",
+			state.EnabledAt.Format("2006-01-02 15:04:05"))
+	}
+
+	behaviorConfig, err := minecraft.LoadWorldConfigFS(sm.server.FS, sm.server.Paths.WorldBehaviorPacks)
+	if err != nil {
+		return result, fmt.Errorf("failed to load behavior config: %w", err)
+	}
+	resourceConfig, err := minecraft.LoadWorldConfigFS(sm.server.FS, sm.server.Paths.WorldResourcePacks)
+	if err != nil {
+		return result, fmt.Errorf("failed to load resource config: %w", err)
+	}
+
+	result.BehaviorPacks = len(behaviorConfig)
+	result.ResourcePacks = len(resourceConfig)
+
+	if options.DryRun {
+		if options.Verbose {
+			fmt.Printf("DRY RUN: Would snapshot %s and %s\n", sm.server.Paths.WorldBehaviorPacks, sm.server.Paths.WorldResourcePacks)
+			fmt.Printf("DRY RUN: Would deactivate %d behavior pack(s) and %d resource pack(s)\n", result.BehaviorPacks, result.ResourcePacks)
+		}
+		result.Success = true
+		return result, nil
+	}
+
+	if options.Verbose {
+		fmt.Println("Snapshotting world configs...")
+	}
+
+	backup, err := sm.backupManager.CreateBackup("safe-mode", "World configs before enabling safe mode", []string{
+		sm.server.Paths.WorldBehaviorPacks,
+		sm.server.Paths.WorldResourcePacks,
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to snapshot world configs: %w", err)
+	}
+
+	state = &SafeModeState{
+		BackupID:      backup.ID,
+		EnabledAt:     backup.Timestamp,
+		BehaviorPacks: result.BehaviorPacks,
+		ResourcePacks: result.ResourcePacks,
+	}
+	if err := sm.saveState(state); err != nil {
+		return result, err
+	}
+
+	defer sm.server.InvalidatePacks()
+	for _, configFile := range []string{sm.server.Paths.WorldBehaviorPacks, sm.server.Paths.WorldResourcePacks} {
+		if err := minecraft.SaveWorldConfigFS(sm.server.FS, configFile, minecraft.WorldConfig{}); err != nil {
+			// Put back whatever was already emptied so the world is left untouched
+			if restoreErr := sm.backupManager.RestoreBackup(backup.ID); restoreErr != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to restore world configs: %v", restoreErr))
+			} else if clearErr := sm.clearState(backup.ID); clearErr != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to clear safe mode state: %v", clearErr))
+			}
+			return result, fmt.Errorf("failed to write empty config %s: %w", configFile, err)
+		}
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// Disable restores the world configs captured by Enable. Packs registered
+// while safe mode was active are kept and appended after the restored entries.
+func (sm *SafeModeManager) Disable(options SafeModeOptions) (*SafeModeResult, error) {
+	result := &SafeModeResult{Warnings: make([]string, 0)}
+
+	state, err := sm.Status()
+	if err != nil {
+		return result, err
+	}
+	if state == nil {
+		return result, fmt.Errorf("safe mode is not enabled on this server")
+	}
+
+	result.BehaviorPacks = state.BehaviorPacks
+	result.ResourcePacks = state.ResourcePacks
+
+	// Capture entries added while in safe mode so restoring doesn't drop them
+	added := make(map[string]minecraft.WorldConfig)
+	for _, configFile := range []string{sm.server.Paths.WorldBehaviorPacks, sm.server.Paths.WorldResourcePacks} {
+		current, err := minecraft.LoadWorldConfigFS(sm.server.FS, configFile)
+		if err != nil {
+			return result, fmt.Errorf("failed to load config %s: %w", configFile, err)
+		}
+		added[configFile] = current
+		for _, pack := range current {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("Pack %s was activated while in safe mode and will be kept", pack.PackID))
+		}
+	}
+
+	if options.DryRun {
+		if options.Verbose {
+			fmt.Printf("DRY RUN: Would restore world configs from snapshot %s\n", state.BackupID)
+			fmt.Printf("DRY RUN: Would reactivate %d behavior pack(s) and %d resource pack(s)\n", result.BehaviorPacks, result.ResourcePacks)
+		}
+		result.Success = true
+		return result, nil
+	}
+
+	if options.Verbose {
+		fmt.Printf("Restoring world configs from snapshot %s...\n", state.BackupID)
+	}
+
+	defer sm.server.InvalidatePacks()
+	if err := sm.backupManager.RestoreBackup(state.BackupID); err != nil {
+		return result, fmt.Errorf("failed to restore world configs: %w", err)
+	}
+
+	for configFile, extra := range added {
+		if len(extra) == 0 {
+			continue
+		}
+		restored, err := minecraft.LoadWorldConfigFS(sm.server.FS, configFile)
+		if err != nil {
+			return result, fmt.Errorf("failed to load restored config %s: %w", configFile, err)
+		}
+		// Append the whole entries, keeping their subpack and extra fields
+		for _, pack := range extra {
+			if !restored.HasPack(pack.PackID) {
+				restored = append(restored, pack)
+			}
+		}
+		if err := minecraft.SaveWorldConfigFS(sm.server.FS, configFile, restored); err != nil {
+			return result, fmt.Errorf("failed to save merged config %s: %w", configFile, err)
+		}
+	}
+
+	if err := sm.clearState(state.BackupID); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to clear safe mode state: %v", err))
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// saveState writes the safe mode state file
+func (sm *SafeModeManager) saveState(state *SafeModeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal safe mode state: %w", err)
+	}
+
+	if err := filesystem.WriteFile(sm.server.FS, sm.StateFile(), data, filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write safe mode state: %w", err)
+	}
+
+	return nil
+}
+
+// clearState removes the safe mode state file and its snapshot
+func (sm *SafeModeManager) clearState(backupID string) error {
+	if err := sm.fs().Remove(sm.StateFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return sm.backupManager.DeleteBackup(backupID)
+}
@@ -0,0 +1,90 @@
+package addon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// newSafeModeTestServer creates a server whose files live in memory, with
+// one active behavior and one active resource pack
+func newSafeModeTestServer(t *testing.T) *minecraft.Server {
+	t.Helper()
+	server := newTestServer(t)
+	server.FS = filesystem.NewMemFS()
+	for config, uuid := range map[string]string{server.Paths.WorldBehaviorPacks: behaviorUUID, server.Paths.WorldResourcePacks: resourceUUID} {
+		if err := server.FS.MkdirAll(filepath.Dir(config), 0750); err != nil {
+			t.Fatalf("Failed to create world dir: %v", err)
+		}
+		entries := minecraft.WorldConfig{{PackID: uuid, Version: [3]int{1, 0, 0}}}
+		if err := minecraft.SaveWorldConfigFS(server.FS, config, entries); err != nil {
+			t.Fatalf("Failed to write world config: %v", err)
+		}
+	}
+	return server
+}
+
+func TestSafeMode(t *testing.T) {
+	server := newSafeModeTestServer(t)
+	safeMode := NewSafeModeManager(server)
+
+	result, err := safeMode.Enable(SafeModeOptions{})
+	if err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	if result.BehaviorPacks != 1 || result.ResourcePacks != 1 {
+		t.Errorf("Expected 1 behavior and 1 resource pack deactivated, got %d and %d", result.BehaviorPacks, result.ResourcePacks)
+	}
+	for _, config := range []string{server.Paths.WorldBehaviorPacks, server.Paths.WorldResourcePacks} {
+		if entries, err := minecraft.LoadWorldConfigFS(server.FS, config); err != nil || len(entries) != 0 {
+			t.Errorf("Expected %s emptied, got %v (%v)", config, entries, err)
+		}
+	}
+	if state, err := safeMode.Status(); err != nil || state == nil {
+		t.Fatalf("Expected safe mode enabled, got %v (%v)", state, err)
+	}
+	if _, err := os.Stat(server.Paths.StateDir); !os.IsNotExist(err) {
+		t.Error("Expected the safe mode state kept on the server's filesystem")
+	}
+
+	if _, err := safeMode.Enable(SafeModeOptions{}); err == nil || !strings.Contains(err.Error(), "already enabled") {
+		t.Errorf("Expected enabling twice to fail, got %v", err)
+	}
+
+	// A pack activated while in safe mode is kept after the restored ones
+	const addedUUID = "33333333-3333-3333-3333-33333333333c"
+	added := minecraft.WorldConfig{{PackID: addedUUID, Version: [3]int{2, 0, 0}}}
+	if err := minecraft.SaveWorldConfigFS(server.FS, server.Paths.WorldBehaviorPacks, added); err != nil {
+		t.Fatalf("Failed to activate a pack: %v", err)
+	}
+
+	result, err = safeMode.Disable(SafeModeOptions{})
+	if err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	if !containsString(result.Warnings, addedUUID) {
+		t.Errorf("Expected a warning about the pack added in safe mode, got %v", result.Warnings)
+	}
+	behavior, err := minecraft.LoadWorldConfigFS(server.FS, server.Paths.WorldBehaviorPacks)
+	if err != nil || len(behavior) != 2 || behavior[0].PackID != behaviorUUID || behavior[1].PackID != addedUUID {
+		t.Errorf("Expected the restored pack followed by the added one, got %v (%v)", behavior, err)
+	}
+	resource, err := minecraft.LoadWorldConfigFS(server.FS, server.Paths.WorldResourcePacks)
+	if err != nil || len(resource) != 1 || resource[0].PackID != resourceUUID {
+		t.Errorf("Expected the resource pack restored, got %v (%v)", resource, err)
+	}
+	if state, err := safeMode.Status(); err != nil || state != nil {
+		t.Errorf("Expected safe mode disabled, got %v (%v)", state, err)
+	}
+	if backups, err := safeMode.BackupManager().ListBackups(); err != nil || len(backups) != 0 {
+		t.Errorf("Expected the snapshot deleted, got %d (%v)", len(backups), err)
+	}
+
+	if _, err := safeMode.Disable(SafeModeOptions{}); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("Expected disabling twice to fail, got %v", err)
+	}
+}
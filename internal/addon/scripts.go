@@ -0,0 +1,76 @@
+package addon
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// PackScripts lists the scripting content found in one pack
+type PackScripts struct {
+	PackName     string   `json:"pack_name"`
+	ScriptModule bool     `json:"script_module"` // Manifest declares a "script" module
+	Files        []string `json:"files"`         // .js files, relative to the pack root
+}
+
+// findScripts returns the scripting content of every pack that has any
+func findScripts(addon *ExtractedAddon) ([]PackScripts, error) {
+	var found []PackScripts
+	for _, pack := range addon.GetAllPacks() {
+		var files []string
+		if pack.InArchive() {
+			files = archiveScriptFiles(pack)
+		} else {
+			var err error
+			files, err = findScriptFiles(pack.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan %s for scripts: %w", pack.Manifest.GetDisplayName(), err)
+			}
+		}
+
+		hasModule := pack.Manifest.HasScriptModule()
+		if hasModule || len(files) > 0 {
+			found = append(found, PackScripts{
+				PackName:     pack.Manifest.GetDisplayName(),
+				ScriptModule: hasModule,
+				Files:        files,
+			})
+		}
+	}
+	return found, nil
+}
+
+// findScriptFiles walks a pack directory for JavaScript files
+func findScriptFiles(packDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(packDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".js") {
+			return nil
+		}
+		rel, err := filepath.Rel(packDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// describeScripts renders one line per pack and script file for step output and errors
+func describeScripts(scripts []PackScripts) []string {
+	var lines []string
+	for _, pack := range scripts {
+		if pack.ScriptModule {
+			lines = append(lines, fmt.Sprintf("%s declares a script module", pack.PackName))
+		}
+		for _, file := range pack.Files {
+			lines = append(lines, fmt.Sprintf("%s: %s", pack.PackName, file))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,103 @@
+package addon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+// PackFilter chooses which of an addon's packs to install. The zero value
+// installs every pack.
+type PackFilter struct {
+	Only    minecraft.PackType // Install only packs of this type; empty for both
+	Include []string           // Install only packs matching one of these UUIDs or names
+	Exclude []string           // Leave out packs matching one of these UUIDs or names
+}
+
+// ParsePackFilterType parses an --only value
+func ParsePackFilterType(value string) (minecraft.PackType, error) {
+	switch packType := minecraft.PackType(strings.ToLower(value)); packType {
+	case "", minecraft.PackTypeBehavior, minecraft.PackTypeResource:
+		return packType, nil
+	}
+	return "", fmt.Errorf("invalid pack type %q: use behavior or resource", value)
+}
+
+// IsEmpty reports whether the filter keeps every pack
+func (f PackFilter) IsEmpty() bool {
+	return f.Only == "" && len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// selectPacks removes the packs the filter leaves out from the addon and
+// returns them. A pattern matches a pack by UUID or by case-insensitive
+// partial name, like FindInstalledPack; a pattern that matches none of the
+// addon's packs is an error, as is a filter that leaves no pack to install.
+func selectPacks(addon *ExtractedAddon, filter PackFilter) ([]*ExtractedPack, error) {
+	if filter.IsEmpty() {
+		return nil, nil
+	}
+	all := addon.GetAllPacks()
+	for _, pattern := range append(append([]string{}, filter.Include...), filter.Exclude...) {
+		if !anyPackMatches(all, pattern) {
+			return nil, fmt.Errorf("no pack in the addon matches %q", pattern)
+		}
+	}
+
+	var excluded []*ExtractedPack
+	keep := func(packs []*ExtractedPack) []*ExtractedPack {
+		var kept []*ExtractedPack
+		for _, pack := range packs {
+			if filter.keeps(pack) {
+				kept = append(kept, pack)
+			} else {
+				excluded = append(excluded, pack)
+			}
+		}
+		return kept
+	}
+	addon.BehaviorPacks = keep(addon.BehaviorPacks)
+	addon.ResourcePacks = keep(addon.ResourcePacks)
+
+	if len(addon.GetAllPacks()) == 0 {
+		return nil, fmt.Errorf("the pack selection leaves none of the addon's %d pack(s) to install", len(all))
+	}
+	return excluded, nil
+}
+
+// keeps reports whether the filter installs a pack
+func (f PackFilter) keeps(pack *ExtractedPack) bool {
+	if f.Only != "" && pack.Manifest.GetPackType() != f.Only {
+		return false
+	}
+	if len(f.Include) > 0 && !packMatchesAny(pack, f.Include) {
+		return false
+	}
+	return !packMatchesAny(pack, f.Exclude)
+}
+
+// packMatchesAny reports whether a pack matches any of the patterns
+func packMatchesAny(pack *ExtractedPack, patterns []string) bool {
+	for _, pattern := range patterns {
+		if packMatches(pack, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPackMatches reports whether any of the packs matches the pattern
+func anyPackMatches(packs []*ExtractedPack, pattern string) bool {
+	for _, pack := range packs {
+		if packMatches(pack, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// packMatches reports whether a pack has the pattern as its UUID or in its name
+func packMatches(pack *ExtractedPack, pattern string) bool {
+	return strings.EqualFold(pack.Manifest.Header.UUID, pattern) ||
+		containsIgnoreCase(pack.Manifest.GetDisplayName(), pattern)
+}
@@ -0,0 +1,42 @@
+package addon
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/signature"
+)
+
+// checkSignature verifies the detached signature next to an addon file
+// against the trusted keys. A signature that is present but invalid always
+// rejects the addon; a missing one only does when RequireSignature is set.
+// It returns the verified signer, or nil with an optional warning when the
+// addon was not checked.
+func checkSignature(addonPath string, options InstallOptions) (*signature.Verified, string, error) {
+	if IsAddonDirectory(addonPath) {
+		if options.RequireSignature {
+			return nil, "", fmt.Errorf("a signature is required, but %s is an unpacked directory, which cannot be signed", addonPath)
+		}
+		return nil, "", nil
+	}
+
+	sigPath := signature.FindSignatureFile(addonPath)
+	if sigPath == "" {
+		if options.RequireSignature {
+			return nil, "", fmt.Errorf("a signature is required, but %s has no .sig or .minisig file next to it", addonPath)
+		}
+		return nil, "", nil
+	}
+
+	if len(options.TrustedKeys) == 0 {
+		if options.RequireSignature {
+			return nil, "", fmt.Errorf("a signature is required, but no public keys are trusted; add one with 'blockbench trust add'")
+		}
+		return nil, fmt.Sprintf("Signature %s was not checked: no public keys are trusted", sigPath), nil
+	}
+
+	verified, err := signature.VerifyFile(addonPath, sigPath, options.TrustedKeys)
+	if err != nil {
+		return nil, "", fmt.Errorf("signature verification failed: %w", err)
+	}
+	return verified, "", nil
+}
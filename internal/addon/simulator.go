@@ -2,37 +2,222 @@ package addon
 
 import (
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/makutaku/blockbench/internal/minecraft"
-	"github.com/makutaku/blockbench/pkg/validation"
 )
 
-// DryRunSimulator provides simulation of file operations for dry-run mode
+// DryRunSimulator provides simulation of file operations for dry-run mode.
+// Each simulated operation is applied to an in-memory overlay of the server,
+// so later operations see the config entries and pack directories of earlier
+// ones, as they would in the real run.
 type DryRunSimulator struct {
 	server *minecraft.Server
+
+	configs     map[string]minecraft.WorldConfig // World configs as the simulated operations leave them, by file
+	directories map[string]bool                  // Pack directories the simulated operations create (true) or remove (false)
+	manifests   map[string]*minecraft.Manifest   // Manifests of the packs the simulated operations install, by lower-case UUID
 }
 
 // NewDryRunSimulator creates a new dry-run simulator
 func NewDryRunSimulator(server *minecraft.Server) *DryRunSimulator {
 	return &DryRunSimulator{
-		server: server,
+		server:      server,
+		configs:     make(map[string]minecraft.WorldConfig),
+		directories: make(map[string]bool),
+		manifests:   make(map[string]*minecraft.Manifest),
+	}
+}
+
+// config returns a world config with the simulated changes so far applied
+func (s *DryRunSimulator) config(configFile string) (minecraft.WorldConfig, error) {
+	if config, ok := s.configs[configFile]; ok {
+		return config, nil
+	}
+	config, err := minecraft.LoadWorldConfigFS(s.server.FS, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	s.configs[configFile] = config
+	return config, nil
+}
+
+// IsPackActive reports whether a pack is registered in either world config
+// once the simulated operations so far are applied
+func (s *DryRunSimulator) IsPackActive(packID string) bool {
+	for _, configFile := range []string{s.server.Paths.WorldBehaviorPacks, s.server.Paths.WorldResourcePacks} {
+		if config, err := s.config(configFile); err == nil && config.HasPack(packID) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPendingPack reports whether an earlier simulated operation installs the pack
+func (s *DryRunSimulator) IsPendingPack(packID string) bool {
+	_, ok := s.manifests[strings.ToLower(packID)]
+	return ok
+}
+
+// pendingManifest returns the manifest of a pack an earlier simulated
+// operation installs, whatever the case of its UUID
+func (s *DryRunSimulator) pendingManifest(packID string) (*minecraft.Manifest, bool) {
+	manifest, ok := s.manifests[strings.ToLower(packID)]
+	return manifest, ok
 }
 
 // SimulatedInstallOperation represents a simulated installation operation
 type SimulatedInstallOperation struct {
-	PackName        string
-	PackUUID        string
-	PackVersion     [3]int
-	PackType        minecraft.PackType
-	SourcePath      string
-	TargetDirectory string
-	ConfigFile      string
-	ConfigEntry     minecraft.PackReference
-	Conflicts       []string
-	Dependencies    []minecraft.ManifestDependency
+	PackName         string
+	PackUUID         string
+	PackVersion      [3]int
+	PackType         minecraft.PackType
+	SourcePath       string
+	TargetDirectory  string
+	ConfigFile       string
+	ConfigEntry      minecraft.PackReference
+	ReplacedEntry    *minecraft.PackReference // The config entry of an installed copy the install replaces
+	Conflicts        []string                 // Installed packs with the pack's UUID
+	OverlayConflicts []string                 // Earlier simulated packs with the pack's UUID or directory
+	Dependencies     []minecraft.ManifestDependency
+	Directories      []string // Subdirectories the pack's files go into, relative to the pack root
+	Files            []string // The pack's files, relative to the pack root
+}
+
+// DryRunPlan lists every change a dry run found an install or uninstall would
+// make, for scripts to check before the real run. Paths are absolute, except
+// the files of PlannedCopy, which are relative to the pack root.
+type DryRunPlan struct {
+	Operation           string               `json:"operation"`            // install or uninstall
+	BackupDir           string               `json:"backup_dir,omitempty"` // Where the backup taken first would be stored
+	CreateDirectories   []string             `json:"create_directories"`
+	CopyFiles           []PlannedCopy        `json:"copy_files"`
+	CreateLinks         []PlannedLink        `json:"create_links"`
+	RemoveDirectories   []string             `json:"remove_directories"`
+	AddConfigEntries    []PlannedConfigEntry `json:"add_config_entries"`
+	RemoveConfigEntries []PlannedConfigEntry `json:"remove_config_entries"`
+	SetProperties       []PropertyChange     `json:"set_properties,omitempty"`     // server.properties settings an install with EnableExperiments would change
+	EnableExperiments   []string             `json:"enable_experiments,omitempty"` // level.dat experiment keys it would turn on, once confirmed
+}
+
+// PlannedCopy is a pack file an install would copy into the server
+type PlannedCopy struct {
+	PackID string `json:"pack_id"`
+	File   string `json:"file"`
+	Target string `json:"target"`
+}
+
+// PlannedLink is a pack directory an install would symlink to its source
+type PlannedLink struct {
+	PackID string `json:"pack_id"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// PlannedConfigEntry is a pack entry added to or removed from a world config file
+type PlannedConfigEntry struct {
+	ConfigFile string `json:"config_file"`
+	PackID     string `json:"pack_id"`
+	Name       string `json:"name"`
+	Version    [3]int `json:"version"`
+	Position   string `json:"position,omitempty"` // Where an added entry goes; empty appends it
+}
+
+// newDryRunPlan returns an empty plan whose lists encode as [] rather than null
+func newDryRunPlan(operation, backupDir string) *DryRunPlan {
+	return &DryRunPlan{
+		Operation:           operation,
+		BackupDir:           backupDir,
+		CreateDirectories:   []string{},
+		CopyFiles:           []PlannedCopy{},
+		CreateLinks:         []PlannedLink{},
+		RemoveDirectories:   []string{},
+		AddConfigEntries:    []PlannedConfigEntry{},
+		RemoveConfigEntries: []PlannedConfigEntry{},
+	}
+}
+
+// addInstall adds a simulated pack installation to the plan
+func (p *DryRunPlan) addInstall(simulation *SimulatedInstallOperation, position minecraft.PackPosition, link bool) {
+	if link {
+		p.CreateLinks = append(p.CreateLinks, PlannedLink{PackID: simulation.PackUUID, Source: simulation.SourcePath, Target: simulation.TargetDirectory})
+	} else {
+		p.CreateDirectories = append(p.CreateDirectories, simulation.TargetDirectory)
+		for _, dir := range simulation.Directories {
+			p.CreateDirectories = append(p.CreateDirectories, filepath.Join(simulation.TargetDirectory, filepath.FromSlash(dir)))
+		}
+		for _, file := range simulation.Files {
+			p.CopyFiles = append(p.CopyFiles, PlannedCopy{PackID: simulation.PackUUID, File: file,
+				Target: filepath.Join(simulation.TargetDirectory, filepath.FromSlash(file))})
+		}
+	}
+
+	if simulation.ReplacedEntry != nil {
+		p.RemoveConfigEntries = append(p.RemoveConfigEntries, PlannedConfigEntry{ConfigFile: simulation.ConfigFile,
+			PackID: simulation.ReplacedEntry.PackID, Name: simulation.PackName, Version: simulation.ReplacedEntry.Version})
+	}
+	entry := PlannedConfigEntry{ConfigFile: simulation.ConfigFile, PackID: simulation.PackUUID, Name: simulation.PackName, Version: simulation.PackVersion}
+	if !position.IsDefault() {
+		entry.Position = position.String()
+	}
+	p.AddConfigEntries = append(p.AddConfigEntries, entry)
+}
+
+// addUninstall adds a simulated pack uninstallation to the plan
+func (p *DryRunPlan) addUninstall(simulation *SimulatedUninstallOperation) {
+	p.RemoveDirectories = append(p.RemoveDirectories, simulation.DirectoryToRemove)
+	p.RemoveConfigEntries = append(p.RemoveConfigEntries, PlannedConfigEntry{ConfigFile: simulation.ConfigFile,
+		PackID: simulation.PackUUID, Name: simulation.PackName, Version: simulation.ConfigEntryToRemove.Version})
+}
+
+// packContents lists a pack's subdirectories and files, relative to the pack
+// root with forward slashes, from its archive entries or its directory
+func packContents(pack *ExtractedPack) (directories, files []string, err error) {
+	if pack.InArchive() {
+		seen := make(map[string]bool)
+		for _, file := range pack.archive.files {
+			name := strings.TrimPrefix(file.Name, pack.archive.prefix)
+			if file.FileInfo().IsDir() || name == "" {
+				continue
+			}
+			files = append(files, name)
+			for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+				seen[dir] = true
+			}
+		}
+		for dir := range seen {
+			directories = append(directories, dir)
+		}
+		sort.Strings(directories)
+		sort.Strings(files)
+		return directories, files, nil
+	}
+
+	err = filepath.WalkDir(pack.Path, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(pack.Path, filePath)
+		if err != nil || rel == "." {
+			return err
+		}
+		if entry.IsDir() {
+			directories = append(directories, filepath.ToSlash(rel))
+		} else {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	return directories, files, err
 }
 
 // SimulatedUninstallOperation represents a simulated uninstallation operation
@@ -47,52 +232,72 @@ type SimulatedUninstallOperation struct {
 	FilesToBackup       []string
 }
 
-// SimulatePackInstallation simulates the installation of a single pack
-func (s *DryRunSimulator) SimulatePackInstallation(pack *ExtractedPack) (*SimulatedInstallOperation, error) {
+// SimulatePackInstallation simulates the installation of a single pack at a
+// position in its world config and adds it to the overlay
+func (s *DryRunSimulator) SimulatePackInstallation(pack *ExtractedPack, position minecraft.PackPosition) (*SimulatedInstallOperation, error) {
 	manifest := pack.Manifest
 	packType := manifest.GetPackType()
 
-	var targetDir string
-	var configFile string
-
-	switch packType {
-	case minecraft.PackTypeBehavior:
-		targetDir = s.server.Paths.BehaviorPacksDir
-		configFile = s.server.Paths.WorldBehaviorPacks
-	case minecraft.PackTypeResource:
-		targetDir = s.server.Paths.ResourcePacksDir
-		configFile = s.server.Paths.WorldResourcePacks
-	default:
-		return nil, fmt.Errorf("unknown pack type for pack %s", manifest.Header.UUID)
+	// Same paths as the real installation
+	finalPackDir, configFile, err := s.server.PackInstallPaths(manifest)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create pack directory name (same logic as real installation)
-	packDirName := fmt.Sprintf("%s_%s", manifest.GetDisplayName(), validation.GetSafeUUIDPrefix(manifest.Header.UUID))
-	finalPackDir := filepath.Join(targetDir, packDirName)
-
 	// Simulate config entry that would be added
 	configEntry := minecraft.PackReference{
 		PackID:  manifest.Header.UUID,
 		Version: manifest.Header.Version,
 	}
 
-	// Check for conflicts with existing packs
+	// Check for conflicts with existing and earlier simulated packs
 	conflicts, err := s.checkInstallationConflicts(manifest.Header.UUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check conflicts: %w", err)
 	}
+	overlayConflicts := s.checkOverlayConflicts(manifest.Header.UUID, finalPackDir)
+
+	config, err := s.config(configFile)
+	if err != nil {
+		return nil, err
+	}
+	var replaced *minecraft.PackReference
+	if entry, ok := config.GetPack(manifest.Header.UUID); ok {
+		replaced = entry
+	}
+
+	directories, files, err := packContents(pack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pack files: %w", err)
+	}
+
+	// Apply the install to the overlay; a position whose anchor is missing
+	// appends the pack, as the real install does after warning
+	config = minecraft.AddPackToConfig(config, manifest.Header.UUID, manifest.Header.Version)
+	if !position.IsDefault() {
+		if positioned, err := minecraft.PositionPackInConfig(config, manifest.Header.UUID, position); err == nil {
+			config = positioned
+		}
+	}
+	s.configs[configFile] = config
+	s.directories[finalPackDir] = true
+	s.manifests[strings.ToLower(manifest.Header.UUID)] = manifest
 
 	return &SimulatedInstallOperation{
-		PackName:        manifest.GetDisplayName(),
-		PackUUID:        manifest.Header.UUID,
-		PackVersion:     manifest.Header.Version,
-		PackType:        packType,
-		SourcePath:      pack.Path,
-		TargetDirectory: finalPackDir,
-		ConfigFile:      configFile,
-		ConfigEntry:     configEntry,
-		Conflicts:       conflicts,
-		Dependencies:    manifest.Dependencies,
+		PackName:         manifest.GetDisplayName(),
+		PackUUID:         manifest.Header.UUID,
+		PackVersion:      manifest.Header.Version,
+		PackType:         packType,
+		SourcePath:       pack.Path,
+		TargetDirectory:  finalPackDir,
+		ConfigFile:       configFile,
+		ConfigEntry:      configEntry,
+		ReplacedEntry:    replaced,
+		Conflicts:        conflicts,
+		OverlayConflicts: overlayConflicts,
+		Dependencies:     manifest.Dependencies,
+		Directories:      directories,
+		Files:            files,
 	}, nil
 }
 
@@ -106,15 +311,23 @@ func (s *DryRunSimulator) SimulatePackUninstallation(packID string) (*SimulatedU
 
 	var targetPack *minecraft.InstalledPack
 	for _, pack := range installedPacks {
-		if pack.PackID == packID {
+		if strings.EqualFold(pack.PackID, packID) {
 			targetPack = &pack
 			break
 		}
 	}
+	if manifest, ok := s.pendingManifest(packID); ok {
+		packID = manifest.Header.UUID // As written in the overlay's config entry
+		targetPack = &minecraft.InstalledPack{PackID: packID, Name: manifest.GetDisplayName(),
+			Version: manifest.Header.Version, Type: manifest.GetPackType()}
+	}
 
 	if targetPack == nil {
 		return nil, fmt.Errorf("pack %s is not installed", packID)
 	}
+	if !s.IsPackActive(packID) {
+		return nil, fmt.Errorf("pack %s is already removed by an earlier simulated operation", packID)
+	}
 
 	// Determine what would be removed
 	var configFile string
@@ -127,8 +340,13 @@ func (s *DryRunSimulator) SimulatePackUninstallation(packID string) (*SimulatedU
 		return nil, fmt.Errorf("unknown pack type for pack %s", packID)
 	}
 
-	// Find the pack directory path
-	packPath, err := s.findPackDirectory(targetPack.PackID, targetPack.Type)
+	// Find the pack directory path, which a simulated install may create
+	var packPath string
+	if manifest, ok := s.pendingManifest(packID); ok {
+		packPath, _, err = s.server.PackInstallPaths(manifest)
+	} else {
+		packPath, err = s.findPackDirectory(targetPack.PackID, targetPack.Type)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find pack directory: %w", err)
 	}
@@ -151,6 +369,15 @@ func (s *DryRunSimulator) SimulatePackUninstallation(packID string) (*SimulatedU
 		Version: targetPack.Version, // Use the version from the installed pack
 	}
 
+	// Apply the removal to the overlay
+	config, err := s.config(configFile)
+	if err != nil {
+		return nil, err
+	}
+	s.configs[configFile] = minecraft.RemovePackFromConfig(config, packID)
+	s.directories[packPath] = false
+	delete(s.manifests, strings.ToLower(packID))
+
 	return &SimulatedUninstallOperation{
 		PackName:            targetPack.Name,
 		PackUUID:            targetPack.PackID,
@@ -164,6 +391,7 @@ func (s *DryRunSimulator) SimulatePackUninstallation(packID string) (*SimulatedU
 }
 
 // checkInstallationConflicts checks for UUID conflicts during installation
+// with the installed packs that the simulated operations leave in place
 func (s *DryRunSimulator) checkInstallationConflicts(newPackUUID string) ([]string, error) {
 	var conflicts []string
 
@@ -173,7 +401,7 @@ func (s *DryRunSimulator) checkInstallationConflicts(newPackUUID string) ([]stri
 	}
 
 	for _, installedPack := range installedPacks {
-		if installedPack.PackID == newPackUUID {
+		if installedPack.PackID == newPackUUID && s.IsPackActive(installedPack.PackID) && !s.IsPendingPack(newPackUUID) {
 			conflicts = append(conflicts, fmt.Sprintf("Pack %s (UUID: %s) is already installed",
 				installedPack.Name, installedPack.PackID))
 		}
@@ -182,6 +410,19 @@ func (s *DryRunSimulator) checkInstallationConflicts(newPackUUID string) ([]stri
 	return conflicts, nil
 }
 
+// checkOverlayConflicts checks whether an earlier simulated operation
+// installs a pack with the same UUID or into the same directory
+func (s *DryRunSimulator) checkOverlayConflicts(newPackUUID, packDir string) []string {
+	var conflicts []string
+	if manifest, ok := s.pendingManifest(newPackUUID); ok {
+		conflicts = append(conflicts, fmt.Sprintf("Pack %s (UUID: %s) is already installed by an earlier simulated operation",
+			manifest.GetDisplayName(), newPackUUID))
+	} else if s.directories[packDir] {
+		conflicts = append(conflicts, fmt.Sprintf("Pack directory %s is already created by an earlier simulated operation", packDir))
+	}
+	return conflicts
+}
+
 // checkUninstallationDependencies checks what packs depend on the pack being removed
 func (s *DryRunSimulator) checkUninstallationDependencies(packID string) ([]string, error) {
 	var dependents []string
@@ -191,28 +432,37 @@ func (s *DryRunSimulator) checkUninstallationDependencies(packID string) ([]stri
 		return nil, fmt.Errorf("failed to list installed packs: %w", err)
 	}
 
+	manifests := make([]*minecraft.Manifest, 0, len(installedPacks)+len(s.manifests))
 	for _, installedPack := range installedPacks {
-		// Skip the pack being removed
-		if installedPack.PackID == packID {
+		// Skip the pack being removed, packs already removed, and packs a
+		// simulated operation replaces
+		if strings.EqualFold(installedPack.PackID, packID) || !s.IsPackActive(installedPack.PackID) || s.IsPendingPack(installedPack.PackID) {
 			continue
 		}
 
 		// Try to load the pack's manifest to check dependencies
-		packPath, err := s.findPackDirectory(installedPack.PackID, installedPack.Type)
+		manifest, err := s.server.FindAndLoadManifestByUUID(installedPack.PackID, installedPack.Type)
 		if err != nil {
-			// If we can't find the pack directory, skip dependency check for this pack
+			// If we can't find the pack's manifest, we can't check dependencies
 			continue
 		}
-		manifestPath := filepath.Join(packPath, "manifest.json")
-		manifest, err := minecraft.ParseManifest(manifestPath)
-		if err != nil {
-			// If we can't load the manifest, we can't check dependencies
-			continue
+		manifests = append(manifests, manifest)
+	}
+	pending := make([]string, 0, len(s.manifests))
+	for uuid := range s.manifests {
+		if uuid != strings.ToLower(packID) {
+			pending = append(pending, uuid)
 		}
+	}
+	sort.Strings(pending)
+	for _, uuid := range pending {
+		manifests = append(manifests, s.manifests[uuid])
+	}
 
+	for _, manifest := range manifests {
 		for _, dep := range manifest.Dependencies {
-			if dep.UUID == packID {
-				dependents = append(dependents, installedPack.Name)
+			if strings.EqualFold(dep.UUID, packID) {
+				dependents = append(dependents, manifest.GetDisplayName())
 				break
 			}
 		}
@@ -221,44 +471,21 @@ func (s *DryRunSimulator) checkUninstallationDependencies(packID string) ([]stri
 	return dependents, nil
 }
 
-// findPackDirectory finds the directory path for an installed pack by searching pack directories
+// findPackDirectory finds the directory path for an installed pack using the server's manifest index
 func (s *DryRunSimulator) findPackDirectory(packID string, packType minecraft.PackType) (string, error) {
-	var baseDir string
-	switch packType {
-	case minecraft.PackTypeBehavior:
-		baseDir = s.server.Paths.BehaviorPacksDir
-	case minecraft.PackTypeResource:
-		baseDir = s.server.Paths.ResourcePacksDir
-	default:
-		return "", fmt.Errorf("unknown pack type")
-	}
-
-	entries, err := os.ReadDir(baseDir)
+	packs, err := s.server.IndexedPacks(packType)
 	if err != nil {
-		return "", fmt.Errorf("failed to read directory %s: %w", baseDir, err)
+		return "", err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, pack := range packs {
+		if pack.Error != "" {
+			// Warn about broken manifests but continue searching
+			slog.Warn("Failed to parse manifest", "path", filepath.Join(pack.Dir, "manifest.json"), "error", pack.Error)
 			continue
 		}
-
-		// Check if this directory contains the pack by looking for the UUID in manifest
-		packPath := filepath.Join(baseDir, entry.Name())
-		manifestPath := filepath.Join(packPath, "manifest.json")
-
-		manifest, err := minecraft.ParseManifest(manifestPath)
-		if err != nil {
-			// Log warning for non-trivial errors but continue searching
-			if !os.IsNotExist(err) {
-				// Only warn if it's not just a missing manifest file
-				fmt.Fprintf(os.Stderr, "Warning: Failed to parse manifest at %s: %v\n", manifestPath, err)
-			}
-			continue
-		}
-
-		if manifest.Header.UUID == packID {
-			return packPath, nil
+		if pack.Manifest != nil && pack.Manifest.Header.UUID == packID {
+			return pack.Dir, nil
 		}
 	}
 
@@ -0,0 +1,226 @@
+package addon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+const (
+	behaviorUUID = "11111111-1111-1111-1111-11111111111a"
+	resourceUUID = "22222222-2222-2222-2222-22222222222b"
+)
+
+// newTestServer creates a server with an empty world and empty pack directories
+func newTestServer(t *testing.T) *minecraft.Server {
+	t.Helper()
+	tempDir := t.TempDir()
+	for _, dir := range []string{"worlds/W", "behavior_packs", "resource_packs", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := minecraft.NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return server
+}
+
+// writeTestPack writes a pack with a manifest and a lang file to dir and
+// loads it. It is a resource pack when its UUID starts with 2 and a behavior
+// pack otherwise, and depends on the packs in dependsOn.
+func writeTestPack(t *testing.T, dir, uuid string, version [3]int, dependsOn ...string) *ExtractedPack {
+	t.Helper()
+	moduleType := "data"
+	if strings.HasPrefix(uuid, "2") {
+		moduleType = "resources"
+	}
+	var deps []string
+	for _, dep := range dependsOn {
+		deps = append(deps, fmt.Sprintf(`{"uuid": %q, "version": [1, 0, 0]}`, dep))
+	}
+	manifest := fmt.Sprintf(`{"format_version": 2, "header": {"name": "Pack %s", "uuid": %q, "version": [%d, %d, %d], "min_engine_version": [1, 20, 0]},
+		"modules": [{"type": %q, "uuid": "99999999-9999-9999-9999-99999999999%c", "version": [1, 0, 0]}], "dependencies": [%s]}`,
+		uuid[:1], uuid, version[0], version[1], version[2], moduleType, uuid[0], strings.Join(deps, ", "))
+	if err := os.MkdirAll(filepath.Join(dir, "texts"), 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "texts", "en_US.lang"), []byte("pack.name=Pack\n"), 0600); err != nil {
+		t.Fatalf("Failed to write pack file: %v", err)
+	}
+	pack, err := processManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to load pack: %v", err)
+	}
+	return pack
+}
+
+func TestDryRunSimulator(t *testing.T) {
+	tests := []struct {
+		name  string
+		packs func(t *testing.T, dir string) []*ExtractedPack
+		check func(t *testing.T, simulator *DryRunSimulator, simulations []*SimulatedInstallOperation)
+	}{
+		{
+			name: "dependency on a later pack",
+			packs: func(t *testing.T, dir string) []*ExtractedPack {
+				return []*ExtractedPack{
+					writeTestPack(t, filepath.Join(dir, "BP"), behaviorUUID, [3]int{1, 0, 0}, resourceUUID),
+					writeTestPack(t, filepath.Join(dir, "RP"), resourceUUID, [3]int{1, 0, 0}),
+				}
+			},
+			check: func(t *testing.T, simulator *DryRunSimulator, simulations []*SimulatedInstallOperation) {
+				if len(simulations[0].OverlayConflicts) != 0 || len(simulations[1].OverlayConflicts) != 0 {
+					t.Errorf("Expected no overlay conflicts, got %v and %v", simulations[0].OverlayConflicts, simulations[1].OverlayConflicts)
+				}
+				// The dependency is looked up once every pack is simulated, in any case
+				if status := simulatedDependencyStatus(simulator, strings.ToUpper(resourceUUID)); status != "installed by this addon" {
+					t.Errorf("Expected the dependency installed by this addon, got %q", status)
+				}
+				if !simulator.IsPackActive(behaviorUUID) || !simulator.IsPackActive(resourceUUID) {
+					t.Error("Expected both packs active in the overlay")
+				}
+			},
+		},
+		{
+			name: "duplicate UUID within one addon",
+			packs: func(t *testing.T, dir string) []*ExtractedPack {
+				return []*ExtractedPack{
+					writeTestPack(t, filepath.Join(dir, "A"), behaviorUUID, [3]int{1, 0, 0}),
+					writeTestPack(t, filepath.Join(dir, "B"), strings.ToUpper(behaviorUUID), [3]int{1, 0, 1}),
+				}
+			},
+			check: func(t *testing.T, simulator *DryRunSimulator, simulations []*SimulatedInstallOperation) {
+				if len(simulations[0].OverlayConflicts) != 0 {
+					t.Errorf("Expected no overlay conflicts for the first pack, got %v", simulations[0].OverlayConflicts)
+				}
+				if len(simulations[1].OverlayConflicts) != 1 || !strings.Contains(simulations[1].OverlayConflicts[0], "earlier simulated operation") {
+					t.Errorf("Expected the second pack to conflict with the first, got %v", simulations[1].OverlayConflicts)
+				}
+			},
+		},
+		{
+			name: "install followed by uninstall",
+			packs: func(t *testing.T, dir string) []*ExtractedPack {
+				return []*ExtractedPack{writeTestPack(t, filepath.Join(dir, "BP"), behaviorUUID, [3]int{1, 0, 0})}
+			},
+			check: func(t *testing.T, simulator *DryRunSimulator, simulations []*SimulatedInstallOperation) {
+				uninstall, err := simulator.SimulatePackUninstallation(strings.ToUpper(behaviorUUID))
+				if err != nil {
+					t.Fatalf("SimulatePackUninstallation failed: %v", err)
+				}
+				if uninstall.DirectoryToRemove != simulations[0].TargetDirectory {
+					t.Errorf("Expected %s removed, got %s", simulations[0].TargetDirectory, uninstall.DirectoryToRemove)
+				}
+				if uninstall.ConfigEntryToRemove.Version != [3]int{1, 0, 0} {
+					t.Errorf("Expected the installed version removed, got %v", uninstall.ConfigEntryToRemove.Version)
+				}
+				if simulator.IsPendingPack(behaviorUUID) || simulator.IsPackActive(behaviorUUID) {
+					t.Error("Expected the pack gone from the overlay after the uninstall")
+				}
+				if _, err := simulator.SimulatePackUninstallation(behaviorUUID); err == nil {
+					t.Error("Expected a second uninstall of the pack to fail")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			simulator := NewDryRunSimulator(server)
+
+			var simulations []*SimulatedInstallOperation
+			for _, pack := range tt.packs(t, t.TempDir()) {
+				simulation, err := simulator.SimulatePackInstallation(pack, minecraft.PackPosition{})
+				if err != nil {
+					t.Fatalf("SimulatePackInstallation failed: %v", err)
+				}
+				if len(simulation.Files) != 2 {
+					t.Errorf("Expected the pack's 2 files planned, got %v", simulation.Files)
+				}
+				simulations = append(simulations, simulation)
+			}
+			tt.check(t, simulator, simulations)
+
+			// Nothing is written to the server
+			if config, err := minecraft.LoadWorldConfig(server.Paths.WorldBehaviorPacks); err != nil || len(config) != 0 {
+				t.Errorf("Expected the world config left empty, got %v (%v)", config, err)
+			}
+			for _, simulation := range simulations {
+				if _, err := os.Stat(simulation.TargetDirectory); !os.IsNotExist(err) {
+					t.Errorf("Expected %s not created", simulation.TargetDirectory)
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunSimulationFailure(t *testing.T) {
+	server := newTestServer(t)
+	dir := t.TempDir()
+	valid := writeTestPack(t, filepath.Join(dir, "BP"), behaviorUUID, [3]int{1, 0, 0})
+	unknown := &ExtractedPack{Path: filepath.Join(dir, "Unknown"), Manifest: &minecraft.Manifest{
+		Header: minecraft.ManifestHeader{Name: "Unknown", UUID: resourceUUID, Version: [3]int{1, 0, 0}}}}
+	extracted := &ExtractedAddon{BehaviorPacks: []*ExtractedPack{valid, unknown}}
+
+	installer := NewInstaller(server, t.TempDir())
+	result, err := installer.performDryRunSimulation(extracted, &installConflicts{}, nil, InstallOptions{DryRun: true})
+	if err == nil {
+		t.Fatal("Expected the dry run to fail when a pack's simulation fails")
+	}
+	if result.Success || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "Unknown") {
+		t.Errorf("Expected an unsuccessful result naming the failed pack, got success=%v errors=%v", result.Success, result.Errors)
+	}
+}
+
+func TestDryRunPlanJSON(t *testing.T) {
+	server := newTestServer(t)
+	addonDir := filepath.Join(t.TempDir(), "BP")
+	writeTestPack(t, addonDir, behaviorUUID, [3]int{1, 0, 0})
+	backupDir := t.TempDir()
+
+	installer := NewInstaller(server, backupDir)
+	result, err := installer.InstallAddon(addonDir, InstallOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	data, err := json.Marshal(result.Plan)
+	if err != nil {
+		t.Fatalf("Failed to marshal plan: %v", err)
+	}
+
+	// Scripts read this schema, so its field names and empty lists are fixed
+	packDir := "$SERVER/development_behavior_packs/Pack 1_11111111"
+	expected := `{"operation":"install","backup_dir":"$BACKUPS",` +
+		`"create_directories":["` + packDir + `","` + packDir + `/texts"],` +
+		`"copy_files":[{"pack_id":"` + behaviorUUID + `","file":"manifest.json","target":"` + packDir + `/manifest.json"},` +
+		`{"pack_id":"` + behaviorUUID + `","file":"texts/en_US.lang","target":"` + packDir + `/texts/en_US.lang"}],` +
+		`"create_links":[],"remove_directories":[],` +
+		`"add_config_entries":[{"config_file":"$SERVER/worlds/W/world_behavior_packs.json","pack_id":"` + behaviorUUID + `","name":"Pack 1","version":[1,0,0]}],` +
+		`"remove_config_entries":[]}`
+	if got := strings.NewReplacer(server.Paths.ServerRoot, "$SERVER", backupDir, "$BACKUPS").Replace(string(data)); got != expected {
+		t.Errorf("Unexpected plan JSON:\n got: %s\nwant: %s", got, expected)
+	}
+
+	var decoded DryRunPlan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal plan: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, result.Plan) {
+		t.Errorf("Expected the plan to survive a JSON round trip, got %+v", decoded)
+	}
+}
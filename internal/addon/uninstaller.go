@@ -1,11 +1,19 @@
 package addon
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/makutaku/blockbench/internal/hooks"
 	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/prompt"
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
 	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/fuzzy"
 )
 
 // UninstallOptions contains options for addon uninstallation
@@ -15,13 +23,21 @@ type UninstallOptions struct {
 	BackupDir   string
 	ByUUID      bool
 	Interactive bool
+	Prompter    prompt.Prompter        // Asks the --interactive confirmations; nil asks on standard input
+	PathPolicy  *filesystem.PathPolicy // When set, the uninstall fails before any change if it would write outside the allowed paths
+	Hooks       *hooks.Runner          // Runs the pre-uninstall, post-uninstall, and post-rollback hooks; nil runs none
+
+	batchBackup *filesystem.BackupMetadata // Backup taken by a Batch; used instead of creating one
 }
 
 // UninstallResult contains the result of an uninstallation
 type UninstallResult struct {
 	Success        bool
 	RemovedPacks   []string
+	Packs          []hooks.Pack // The pack being removed, with its version
 	BackupMetadata *filesystem.BackupMetadata
+	RolledBack     bool        // The backup was restored after the uninstall failed
+	Plan           *DryRunPlan // Dry run only: every change the uninstall would make
 	Errors         []string
 	Warnings       []string
 }
@@ -42,6 +58,31 @@ func NewUninstaller(server *minecraft.Server, backupDir string) *Uninstaller {
 
 // UninstallAddon removes an addon with validation and rollback support
 func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions) (*UninstallResult, error) {
+	logger := slog.With("target", identifier, "server", u.server.Paths.ServerRoot)
+	logger.Info("Uninstalling addon", "by_uuid", options.ByUUID, "dry_run", options.DryRun)
+
+	result, err := u.uninstallAddon(identifier, options)
+	switch {
+	case err != nil:
+		// The caller reports the error itself, so keep this record off the console
+		logger.Info("Uninstall failed", "error", err, "rolled_back", result != nil && result.RolledBack)
+	case options.DryRun:
+		logger.Info("Uninstall dry run finished")
+	default:
+		attrs := []any{"packs", result.RemovedPacks}
+		if result.BackupMetadata != nil {
+			attrs = append(attrs, "backup", result.BackupMetadata.ID)
+		}
+		logger.Info("Uninstalled addon", attrs...)
+	}
+
+	if !options.DryRun {
+		recordHistory(u.server, uninstallHistoryEntry(identifier, result, err))
+	}
+	return result, err
+}
+
+func (u *Uninstaller) uninstallAddon(identifier string, options UninstallOptions) (*UninstallResult, error) {
 	result := &UninstallResult{
 		RemovedPacks: make([]string, 0),
 		Errors:       make([]string, 0),
@@ -57,7 +98,18 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 	}
 
 	// Step 1: Find the addon to uninstall
-	packToRemove, err := u.findAddonPack(identifier, options.ByUUID)
+	var packToRemove *minecraft.InstalledPack
+	var err error
+	if options.Interactive {
+		// Ask which pack was meant when several match
+		prompter := options.Prompter
+		if prompter == nil {
+			prompter = prompt.Stdin()
+		}
+		packToRemove, err = PickInstalledPack(u.server, identifier, options.ByUUID, prompter)
+	} else {
+		packToRemove, err = FindInstalledPack(u.server, identifier, options.ByUUID)
+	}
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to find addon: %v", err))
 		return result, err
@@ -68,6 +120,18 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 			packToRemove.Name, packToRemove.PackID, packToRemove.Type)
 	}
 
+	// Verify every planned write before anything changes, dry run included
+	if options.PathPolicy != nil {
+		writes, err := u.plannedUninstallWrites(packToRemove.PackID, options)
+		if err == nil {
+			err = options.PathPolicy.Check(writes)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return result, err
+		}
+	}
+
 	if options.DryRun {
 		return u.performDryRunSimulation(packToRemove, options)
 	}
@@ -87,15 +151,28 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 		// For now, we'll allow removal but warn the user
 	}
 
-	// Step 3: Create backup
-	if options.Verbose {
-		fmt.Println("Creating backup before uninstallation...")
+	hookPacks := []hooks.Pack{installedHookPack(packToRemove)}
+	result.Packs = hookPacks
+	preUninstall := hookPayload(u.server, hooks.PreUninstall)
+	preUninstall.Packs = hookPacks
+	preUninstall.Warnings = result.Warnings
+	if err := options.Hooks.Run(preUninstall); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Uninstallation aborted: %v", err))
+		return result, err
 	}
 
-	backup, err := u.backupManager.CreateUninstallBackup(packToRemove.Name, packToRemove.PackID)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
-		return result, err
+	// Step 3: Create backup
+	backup := options.batchBackup
+	if backup == nil {
+		if options.Verbose {
+			fmt.Println("Creating backup before uninstallation...")
+		}
+
+		backup, err = u.backupManager.CreateUninstallBackup(packToRemove.Name, packToRemove.PackID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Backup creation failed: %v", err))
+			return result, err
+		}
 	}
 	result.BackupMetadata = backup
 
@@ -106,14 +183,10 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 		}
 
 		// Rollback on failure
-		if rollbackErr := u.backupManager.RestoreBackup(backup.ID); rollbackErr != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", rollbackErr))
-		} else if options.Verbose {
-			fmt.Println("Successfully rolled back changes")
-		}
+		rollbackErr := u.rollback(backup.ID, hookPacks, err, result, options)
 
 		result.Errors = append(result.Errors, fmt.Sprintf("Uninstallation failed: %v", err))
-		return result, err
+		return result, rollbackErr
 	}
 
 	// Step 5: Post-uninstallation validation
@@ -123,18 +196,22 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 		}
 
 		// Rollback on validation failure
-		if rollbackErr := u.backupManager.RestoreBackup(backup.ID); rollbackErr != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", rollbackErr))
-		}
+		rollbackErr := u.rollback(backup.ID, hookPacks, err, result, options)
 
 		result.Errors = append(result.Errors, fmt.Sprintf("Post-uninstallation validation failed: %v", err))
-		return result, err
+		return result, rollbackErr
 	}
 
 	// Success!
 	result.RemovedPacks = append(result.RemovedPacks, packToRemove.Name)
 	result.Success = true
 
+	postUninstall := hookPayload(u.server, hooks.PostUninstall)
+	postUninstall.Packs = hookPacks
+	postUninstall.BackupID = backup.ID
+	postUninstall.Warnings = result.Warnings
+	result.Warnings = append(result.Warnings, runPostHooks(options.Hooks, postUninstall)...)
+
 	if options.Verbose {
 		fmt.Printf("Successfully uninstalled pack: %s\n", packToRemove.Name)
 	}
@@ -142,9 +219,28 @@ func (u *Uninstaller) UninstallAddon(identifier string, options UninstallOptions
 	return result, nil
 }
 
-// findAddonPack finds an addon pack by name or UUID
-func (u *Uninstaller) findAddonPack(identifier string, byUUID bool) (*minecraft.InstalledPack, error) {
-	installedPacks, err := u.server.ListInstalledPacks()
+// rollback restores the backup taken before a failed uninstall and runs the
+// post-rollback hooks. It returns the cause, joined with an ErrRollbackFailed
+// error when the backup could not be restored.
+func (u *Uninstaller) rollback(backupID string, packs []hooks.Pack, cause error, result *UninstallResult, options UninstallOptions) error {
+	slog.Warn("Rolling back failed uninstall", "backup", backupID, "error", cause)
+	if err := u.backupManager.RestoreBackup(backupID); err != nil {
+		slog.Error("Rollback failed", "backup", backupID, "error", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("Rollback failed: %v", err))
+		return rollbackFailed(cause, err)
+	}
+	result.RolledBack = true
+	if options.Verbose {
+		fmt.Println("Successfully rolled back changes")
+	}
+	payload := rollbackPayload(u.server, "uninstall", packs, backupID, cause)
+	result.Warnings = append(result.Warnings, runPostHooks(options.Hooks, payload)...)
+	return cause
+}
+
+// FindInstalledPack finds an installed pack by UUID or by case-insensitive partial name match
+func FindInstalledPack(server *minecraft.Server, identifier string, byUUID bool) (*minecraft.InstalledPack, error) {
+	installedPacks, err := server.ListInstalledPacks()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list installed packs: %w", err)
 	}
@@ -156,7 +252,7 @@ func (u *Uninstaller) findAddonPack(identifier string, byUUID bool) (*minecraft.
 				return &pack, nil
 			}
 		}
-		return nil, fmt.Errorf("no pack found with UUID: %s", identifier)
+		return nil, bberrors.Mark(fmt.Errorf("no pack found with UUID: %s", identifier), bberrors.ErrPackNotFound)
 	}
 
 	// Search by name (case-insensitive partial match)
@@ -168,20 +264,92 @@ func (u *Uninstaller) findAddonPack(identifier string, byUUID bool) (*minecraft.
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no pack found with name containing: %s", identifier)
+		var names []string
+		for _, pack := range installedPacks {
+			names = append(names, pack.Name)
+		}
+		message := fmt.Sprintf("no pack found with name containing: %s", identifier)
+		if suggestions := fuzzy.Suggest(identifier, names, 3); len(suggestions) > 0 {
+			message += fmt.Sprintf(". Did you mean %s?", quotedAlternatives(suggestions))
+		}
+		return nil, bberrors.Mark(errors.New(message), bberrors.ErrPackNotFound)
 	}
 
 	if len(matches) > 1 {
-		var names []string
-		for _, match := range matches {
-			names = append(names, match.Name)
-		}
-		return nil, fmt.Errorf("multiple packs found matching '%s': %v. Use UUID for precise identification", identifier, names)
+		// Closest first: the match with the fewest characters beyond the identifier
+		query := strings.ToLower(identifier)
+		sort.SliceStable(matches, func(i, j int) bool {
+			di := fuzzy.Distance(query, strings.ToLower(matches[i].Name))
+			dj := fuzzy.Distance(query, strings.ToLower(matches[j].Name))
+			if di != dj {
+				return di < dj
+			}
+			return matches[i].Name < matches[j].Name
+		})
+		return nil, &AmbiguousPackError{Identifier: identifier, Matches: matches}
 	}
 
 	return &matches[0], nil
 }
 
+// AmbiguousPackError is returned by FindInstalledPack when several installed
+// packs match a name. Matches are sorted closest first.
+type AmbiguousPackError struct {
+	Identifier string
+	Matches    []minecraft.InstalledPack
+}
+
+func (e *AmbiguousPackError) Error() string {
+	var names []string
+	for _, match := range e.Matches {
+		names = append(names, fmt.Sprintf("%s (%s)", match.Name, match.PackID))
+	}
+	return fmt.Sprintf("multiple packs found matching '%s': %s. Use UUID for precise identification", e.Identifier, strings.Join(names, ", "))
+}
+
+// PickInstalledPack finds an installed pack like FindInstalledPack, but when
+// several packs match a name it lists them, closest first, and asks which
+// one was meant. Without an answer, the ambiguity is returned as the error.
+func PickInstalledPack(server *minecraft.Server, identifier string, byUUID bool, prompter prompt.Prompter) (*minecraft.InstalledPack, error) {
+	pack, err := FindInstalledPack(server, identifier, byUUID)
+	var ambiguous *AmbiguousPackError
+	if !errors.As(err, &ambiguous) {
+		return pack, err
+	}
+
+	var question strings.Builder
+	fmt.Fprintf(&question, "Multiple packs match '%s':\n", identifier)
+	for i, match := range ambiguous.Matches {
+		fmt.Fprintf(&question, "  %d) %s (%s pack, %s)\n", i+1, match.Name, match.Type, match.PackID)
+	}
+	fmt.Fprintf(&question, "Choose a pack [1-%d]: ", len(ambiguous.Matches))
+
+	answer, askErr := prompter.Ask(question.String())
+	if askErr != nil {
+		if errors.Is(askErr, prompt.ErrNoInput) {
+			return nil, err
+		}
+		return nil, askErr
+	}
+	choice, convErr := strconv.Atoi(answer)
+	if convErr != nil || choice < 1 || choice > len(ambiguous.Matches) {
+		return nil, fmt.Errorf("invalid choice %q: enter a number from 1 to %d", answer, len(ambiguous.Matches))
+	}
+	return &ambiguous.Matches[choice-1], nil
+}
+
+// quotedAlternatives formats names as 'a', 'b', or 'c'
+func quotedAlternatives(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
 // checkDependencies checks if other packs depend on the pack being removed
 func (u *Uninstaller) checkDependencies(packID string, verbose bool, result *UninstallResult) ([]string, error) {
 	var dependents []string
@@ -295,6 +463,12 @@ func (u *Uninstaller) performDryRunSimulation(packToRemove *minecraft.InstalledP
 		result.Errors = append(result.Errors, fmt.Sprintf("Uninstallation simulation failed: %v", err))
 		return result, err
 	}
+	backupDir := ""
+	if options.batchBackup == nil {
+		backupDir = u.backupManager.BackupRoot
+	}
+	result.Plan = newDryRunPlan("uninstall", backupDir)
+	result.Plan.addUninstall(simulation)
 
 	// Show dependency check results
 	if err := showStepResult("Dependency check simulation", dependencyDetails, "Backup simulation", "Simulate creating a backup of current state before removal.", convertToInstallOptions(options)); err != nil {
@@ -364,6 +538,7 @@ func (u *Uninstaller) performDryRunSimulation(packToRemove *minecraft.InstalledP
 func convertToInstallOptions(uninstallOpts UninstallOptions) InstallOptions {
 	return InstallOptions{
 		Interactive: uninstallOpts.Interactive,
+		Prompter:    uninstallOpts.Prompter,
 		Verbose:     uninstallOpts.Verbose,
 		DryRun:      uninstallOpts.DryRun,
 	}
@@ -0,0 +1,115 @@
+package addon
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// PackValidation holds the validation outcome for one pack of an addon
+type PackValidation struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description,omitempty"`
+	UUID        string                   `json:"uuid"`
+	Version     [3]int                   `json:"version"`
+	Type        minecraft.PackType       `json:"type"`
+	Issues      []minecraft.ContentIssue `json:"issues,omitempty"`
+}
+
+// ValidationReport is the result of validating an addon without installing it
+type ValidationReport struct {
+	Valid bool             `json:"valid"`
+	Deep  bool             `json:"deep"`
+	Packs []PackValidation `json:"packs"`
+}
+
+// ValidateAddon extracts an addon and validates its manifests. With deep set,
+// the JSON content of every pack is also checked against the embedded schemas
+// and resource pack textures and sounds are checked for integrity. Archives
+// are extracted within the given limits; zero fields use the defaults.
+func ValidateAddon(addonPath string, deep bool, limits filesystem.ExtractLimits) (*ValidationReport, error) {
+	if err := ValidateAddonFile(addonPath); err != nil {
+		return nil, err
+	}
+
+	extractedAddon, err := ExtractAddon(addonPath, true, limits, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cleanupErr := extractedAddon.Cleanup(); cleanupErr != nil {
+			slog.Warn("Failed to cleanup temporary files", "error", cleanupErr)
+		}
+	}()
+
+	if len(extractedAddon.GetAllPacks()) == 0 {
+		return nil, fmt.Errorf("no valid packs found in addon")
+	}
+
+	report := &ValidationReport{Valid: true, Deep: deep}
+	for _, pack := range extractedAddon.GetAllPacks() {
+		result := PackValidation{
+			Name:        pack.Manifest.GetDisplayName(),
+			Description: pack.Manifest.Header.Description,
+			UUID:        pack.Manifest.Header.UUID,
+			Version:     pack.Manifest.Header.Version,
+			Type:        pack.PackType,
+		}
+		if deep {
+			issues, err := minecraft.ValidatePackContent(pack.Path, pack.PackType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to validate content of %s: %w", result.Name, err)
+			}
+			assetIssues, err := minecraft.ValidatePackAssets(pack.Path, pack.PackType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to validate assets of %s: %w", result.Name, err)
+			}
+			result.Issues = append(issues, assetIssues...)
+			if len(result.Issues) > 0 {
+				report.Valid = false
+			}
+		}
+		report.Packs = append(report.Packs, result)
+	}
+
+	return report, nil
+}
+
+// validatePackContents runs deep content validation on every pack of an extracted addon
+func validatePackContents(addon *ExtractedAddon) ([]string, error) {
+	var problems []string
+	for _, pack := range addon.GetAllPacks() {
+		if pack.InArchive() {
+			continue // Only extracted files can be validated
+		}
+		issues, err := minecraft.ValidatePackContent(pack.Path, pack.PackType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate content of %s: %w", pack.Manifest.GetDisplayName(), err)
+		}
+		for _, issue := range issues {
+			problems = append(problems, fmt.Sprintf("%s: %s", pack.Manifest.GetDisplayName(), issue))
+		}
+	}
+	return problems, nil
+}
+
+// validatePackAssets checks the textures and sounds of every pack of an extracted addon.
+// Packs installed straight from their archive are skipped.
+func validatePackAssets(addon *ExtractedAddon) ([]string, error) {
+	var problems []string
+	for _, pack := range addon.GetAllPacks() {
+		if pack.InArchive() {
+			continue
+		}
+		issues, err := minecraft.ValidatePackAssets(pack.Path, pack.PackType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate assets of %s: %w", pack.Manifest.GetDisplayName(), err)
+		}
+		for _, issue := range issues {
+			problems = append(problems, fmt.Sprintf("%s: %s", pack.Manifest.GetDisplayName(), issue))
+		}
+	}
+	return problems, nil
+}
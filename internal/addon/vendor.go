@@ -0,0 +1,146 @@
+package addon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// VendoredPack is one pack written to a vendored bundle
+type VendoredPack struct {
+	PackID     string             `json:"pack_id"`
+	Name       string             `json:"name"`
+	Type       minecraft.PackType `json:"type"`
+	Version    [3]int             `json:"version"`
+	Directory  string             `json:"directory"`
+	Folder     string             `json:"folder"`     // Top-level folder in the bundle; empty in a .mcpack
+	Dependency bool               `json:"dependency"` // Pulled in as a dependency of the requested pack
+}
+
+// VendorResult describes a bundle written by VendorPack or ExportPack
+type VendorResult struct {
+	Output   string         `json:"output"`
+	Packs    []VendoredPack `json:"packs"`
+	Missing  []string       `json:"missing_dependencies,omitempty"`  // Pack UUIDs depended on but not installed
+	Excluded []string       `json:"excluded_dependencies,omitempty"` // Pack UUIDs depended on but not exported without ExportOptions.Dependencies
+}
+
+// ExportOptions controls what ExportPack writes
+type ExportOptions struct {
+	Dependencies bool // Also export every installed pack the pack depends on, directly or transitively
+}
+
+// VendorPack exports an installed pack together with every installed pack it
+// depends on, directly or transitively, as a single .mcaddon at output.
+// Module dependencies such as @minecraft/server are provided by the game and
+// are not bundled; pack dependencies that are not installed are reported in
+// Missing rather than failing the export.
+func VendorPack(server *minecraft.Server, identifier string, byUUID bool, output string) (*VendorResult, error) {
+	return ExportPack(server, identifier, byUUID, output, ExportOptions{Dependencies: true})
+}
+
+// ExportPack re-archives an installed pack's directory at output, so it can
+// be installed elsewhere. Without options.Dependencies only the pack itself is
+// written and its pack dependencies are listed in Excluded. An output ending
+// in .mcpack holds the single pack at its root; any other output is a
+// .mcaddon with one folder per pack.
+func ExportPack(server *minecraft.Server, identifier string, byUUID bool, output string, options ExportOptions) (*VendorResult, error) {
+	mcpack := strings.EqualFold(filepath.Ext(output), ".mcpack")
+	if mcpack && options.Dependencies {
+		return nil, fmt.Errorf("a .mcpack holds a single pack; write a .mcaddon to include dependencies")
+	}
+
+	root, err := FindInstalledPack(server, identifier, byUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VendorResult{Output: output, Packs: make([]VendoredPack, 0)}
+	visited := make(map[string]bool)
+	folders := make(map[string]bool)
+	var dirs []filesystem.ArchiveDir
+
+	queue := []string{root.PackID}
+	for len(queue) > 0 {
+		packID := queue[0]
+		queue = queue[1:]
+		if visited[packID] {
+			continue
+		}
+		visited[packID] = true
+
+		manifest, dir, err := findVendoredPack(server, packID)
+		if err != nil {
+			if packID == root.PackID {
+				return nil, fmt.Errorf("failed to locate pack %s: %w", root.Name, err)
+			}
+			result.Missing = append(result.Missing, packID)
+			continue
+		}
+
+		folder := uniqueFolder(folders, filepath.Base(dir))
+		dirs = append(dirs, filesystem.ArchiveDir{Name: folder, Source: dir})
+		result.Packs = append(result.Packs, VendoredPack{
+			PackID:     packID,
+			Name:       manifest.Header.Name,
+			Type:       manifest.GetPackType(),
+			Version:    manifest.Header.Version,
+			Directory:  dir,
+			Folder:     folder,
+			Dependency: packID != root.PackID,
+		})
+
+		for _, dep := range manifest.Dependencies {
+			if dep.UUID == "" || visited[dep.UUID] {
+				continue
+			}
+			if options.Dependencies {
+				queue = append(queue, dep.UUID)
+			} else {
+				result.Excluded = append(result.Excluded, dep.UUID)
+			}
+		}
+	}
+
+	if mcpack {
+		dirs[0].Name = ""
+		result.Packs[0].Folder = ""
+	}
+	if err := filesystem.CreateArchive(output, dirs); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findVendoredPack locates an installed pack of either type by UUID; a
+// behavior pack commonly depends on a resource pack and vice versa
+func findVendoredPack(server *minecraft.Server, packID string) (*minecraft.Manifest, string, error) {
+	var lastErr error
+	for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+		dir, err := server.FindPackDirectory(packID, packType)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		manifest, err := server.FindAndLoadManifestByUUID(packID, packType)
+		if err != nil {
+			return nil, "", err
+		}
+		return manifest, dir, nil
+	}
+	return nil, "", lastErr
+}
+
+// uniqueFolder returns name, or name with a numeric suffix if a bundle folder
+// already uses it
+func uniqueFolder(used map[string]bool, name string) string {
+	folder := name
+	for i := 2; used[folder]; i++ {
+		folder = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[folder] = true
+	return folder
+}
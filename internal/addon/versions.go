@@ -0,0 +1,80 @@
+package addon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// VersionSwitch is a move of an installed pack to another of its retained versions
+type VersionSwitch struct {
+	PackID    string                    `json:"pack_id"`
+	Name      string                    `json:"name"`
+	Installed [3]int                    `json:"installed"`
+	Target    minecraft.RetainedVersion `json:"target"`
+}
+
+// PlanVersionSwitch finds the installed version of a pack and the retained
+// version to switch it to. A nil version picks the newest retained version
+// for an upgrade (up) and the newest older one for a downgrade; a given
+// version must be newer than the installed one for an upgrade and older for
+// a downgrade.
+func PlanVersionSwitch(server *minecraft.Server, packID string, version *[3]int, up bool) (*VersionSwitch, error) {
+	packs, err := server.ListInstalledPacks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed packs: %w", err)
+	}
+	var installed *minecraft.InstalledPack
+	for i := range packs {
+		if strings.EqualFold(packs[i].PackID, packID) {
+			installed = &packs[i]
+			break
+		}
+	}
+	if installed == nil {
+		return nil, fmt.Errorf("pack %s is not installed", packID)
+	}
+
+	retained, err := server.RetainedVersions(packID)
+	if err != nil {
+		return nil, err
+	}
+	plan := &VersionSwitch{PackID: installed.PackID, Name: installed.Name, Installed: installed.Version}
+
+	direction, want := "older", -1
+	if up {
+		direction, want = "newer", 1
+	}
+	for _, candidate := range retained {
+		if version != nil && candidate.Version != *version {
+			continue
+		}
+		if validation.CompareVersions(candidate.Version, installed.Version) != want {
+			if version != nil {
+				return nil, fmt.Errorf("version %s of %s is not %s than the installed %s",
+					formatVersion(candidate.Version), installed.Name, direction, formatVersion(installed.Version))
+			}
+			continue
+		}
+		plan.Target = candidate
+		return plan, nil
+	}
+
+	if version != nil {
+		return nil, fmt.Errorf("version %s of %s is not retained", formatVersion(*version), installed.Name)
+	}
+	return nil, fmt.Errorf("no %s version of %s than the installed %s is retained", direction, installed.Name, formatVersion(installed.Version))
+}
+
+// SwitchVersion installs the retained version a switch targets in place of
+// the installed one. It is an install of the retained archive that replaces
+// the pack's files rather than copying over them, so it is backed up,
+// recorded in the history, and can be undone like any other install.
+func (i *Installer) SwitchVersion(plan *VersionSwitch, options InstallOptions) (*InstallResult, error) {
+	options.ForceUpdate = true // A downgrade is a conflict otherwise
+	options.ReplaceFiles = true
+	options.Link = false
+	return i.InstallAddon(plan.Target.Path, options)
+}
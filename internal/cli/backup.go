@@ -0,0 +1,512 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/webhook"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewBackupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Create, list, restore, and consolidate backups",
+		Long: `Manage the backups blockbench creates before every install and uninstall.
+'backup create' takes a backup on demand or on a schedule.
+
+'backup restore' always previews the files it would create, overwrite, or delete
+(with diffs of changed config files) and asks for confirmation before restoring.
+Use the global --dry-run flag to only print the preview. The files a restore
+overwrites are backed up first, so 'blockbench undo' can reverse it. The
+backup ID 'latest' selects the newest backup.
+
+Restoring a backup's world configs deactivates any pack activated after the
+backup was taken; such packs are listed before confirming. Use 'restore --merge'
+to keep them active after the restore. 'restore --only' restores just one pack
+directory, by the pack's UUID, or one file, by its path, leaving the rest of the
+server as it is.
+
+'restore --target-server' restores a backup of server-path into another server,
+such as a rebuilt machine or a staging clone: the paths the backup recorded are
+rebased from the server it was taken of onto the target's root. The backup of
+the files the restore overwrites goes to the target's own backup directory.`,
+	}
+	cmd.PersistentFlags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+
+	listCmd := &cobra.Command{
+		Use:               "list [server-path]",
+		Short:             "List available backups",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runBackupList,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	latestCmd := &cobra.Command{
+		Use:               "latest [server-path]",
+		Short:             "Print the ID of the newest backup",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runBackupLatest,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	latestCmd.Flags().Bool("json", false, "Output the backup's metadata in JSON format")
+	cmd.AddCommand(latestCmd)
+
+	gcCmd := &cobra.Command{
+		Use:   "gc [server-path]",
+		Short: "Consolidate chains of incremental backups",
+		Long: `Make incremental backups full where their chain is longer than --max-chain,
+so restoring one never depends on too many other backups, and remove what
+interrupted restores left behind. Incremental backups whose chain is missing a
+backup are reported; 'blockbench state fsck --repair' quarantines them.
+Respects the global --dry-run flag.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runBackupGC,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	gcCmd.Flags().Int("max-chain", filesystem.DefaultMaxBackupChain, "Incremental backups a backup may depend on; 0 makes every backup full")
+	gcCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(gcCmd)
+
+	createCmd := &cobra.Command{
+		Use:   "create [server-path]",
+		Short: "Back up the world configs and installed packs now or on a schedule",
+		Long: `Back up the world configs and every installed pack directory, independent of
+any install or uninstall; linked packs are skipped. Use --full to take a full
+backup even when the server's backups are incremental.
+
+With --backup-interval, blockbench keeps running and takes a backup at that
+interval until interrupted; it stops with an error once 3 backups in a row
+have failed. --keep and --max-age set the retention policy for these backups,
+applied after each one; backups taken before operations are never pruned.
+Without --backup-interval, one backup is taken, as for cron.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runBackupCreate,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	createCmd.Flags().Bool("full", false, "Take a full backup even when backups are incremental")
+	createCmd.Flags().Duration("backup-interval", 0, "Keep running and take a backup at this interval, e.g. 6h")
+	createCmd.Flags().Int("keep", 0, "Snapshot backups to keep; older ones are deleted (default: keep all)")
+	createCmd.Flags().Duration("max-age", 0, "Delete snapshot backups older than this, e.g. 168h, always keeping the newest (default: no limit)")
+	createCmd.Flags().Bool("json", false, "Output each backup's metadata in JSON format")
+	cmd.AddCommand(createCmd)
+
+	restoreCmd := &cobra.Command{
+		Use:               "restore [backup-id|latest] [server-path]",
+		Short:             "Restore the files captured in a backup",
+		Args:              cobra.ExactArgs(2),
+		RunE:              runBackupRestore,
+		ValidArgsFunction: completeArgs(completeBackupIDs, completeServerPath),
+	}
+	restoreCmd.Flags().Bool("json", false, "Output the restore result in JSON format (implies --yes)")
+	restoreCmd.Flags().Bool("merge", false, "Keep packs activated after the backup was taken instead of deactivating them")
+	restoreCmd.Flags().String("only", "", "Restore only this pack (by UUID) or file of the backup (by path)")
+	restoreCmd.Flags().String("target-server", "", "Restore into this server path or profile instead of the one the backup was taken of")
+	_ = restoreCmd.RegisterFlagCompletionFunc("target-server", completeServerPath)
+	addNoHooksFlag(restoreCmd)
+	addNoWebhooksFlag(restoreCmd)
+	cmd.AddCommand(restoreCmd)
+
+	return cmd
+}
+
+// newRollbackManager resolves the server and backup directory shared by backup subcommands
+func newRollbackManager(cmd *cobra.Command, serverPath string) (*addon.RollbackManager, error) {
+	target, err := resolveServerTarget(cmd, serverPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := target.newServer()
+	if err != nil {
+		return nil, err
+	}
+	return addon.NewRollbackManager(server, target.backupDir(cmd)), nil
+}
+
+// newCrossServerRollbackManager restores the backups of the server at
+// serverPath into the server at targetPath
+func newCrossServerRollbackManager(cmd *cobra.Command, serverPath, targetPath string) (*addon.RollbackManager, error) {
+	source, err := resolveServerTarget(cmd, serverPath)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveServerTarget(cmd, targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := target.newServer()
+	if err != nil {
+		return nil, err
+	}
+	return addon.NewCrossServerRollbackManager(server, source.backupDir(cmd), target.ownBackupDir()), nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	manager, err := newRollbackManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	backups, err := manager.ListAvailableBackups()
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+	filesystem.SortBackups(backups)
+
+	if jsonOutput {
+		if backups == nil {
+			backups = []filesystem.BackupMetadata{}
+		}
+		data, err := json.MarshalIndent(backups, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(backups) == 0 {
+		fmt.Println("No backups found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tOPERATION\tADDON")
+	fmt.Fprintln(w, "--\t-------\t---------\t-----")
+	for _, backup := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			backup.ID, backup.Timestamp.Format("2006-01-02 15:04:05"), backup.Operation, backup.AddonName)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+
+	return nil
+}
+
+func runBackupLatest(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	manager, err := newRollbackManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	backupID, err := manager.ResolveBackupID(filesystem.LatestBackup)
+	if err != nil {
+		return err
+	}
+
+	if !jsonOutput {
+		fmt.Println(backupID)
+		return nil
+	}
+	backup, err := manager.GetBackupInfo(backupID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runBackupGC(cmd *cobra.Command, args []string) error {
+	maxChain, _ := cmd.Flags().GetInt("max-chain")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if maxChain < 0 {
+		return fmt.Errorf("--max-chain must not be negative")
+	}
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	result, err := filesystem.NewBackupManager(target.backupDir(cmd)).GC(maxChain, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	verb := "Consolidated"
+	if dryRun {
+		verb = "Would consolidate"
+	}
+	for _, id := range result.Consolidated {
+		fmt.Printf("%s %s into a full backup\n", verb, id)
+	}
+	for _, dir := range result.Cleaned {
+		if dryRun {
+			fmt.Printf("Would remove %s\n", dir)
+		} else {
+			fmt.Printf("Removed %s\n", dir)
+		}
+	}
+	for _, id := range result.Broken {
+		slog.Warn("Backup is missing a backup of its chain and can't be restored", "id", id)
+	}
+	if len(result.Consolidated) == 0 && len(result.Cleaned) == 0 {
+		fmt.Println("No backup chains to consolidate")
+	}
+	return nil
+}
+
+// maxScheduledBackupFailures is how many scheduled backups in a row may fail
+// before the schedule stops with an error
+const maxScheduledBackupFailures = 3
+
+func runBackupCreate(cmd *cobra.Command, args []string) error {
+	full, _ := cmd.Flags().GetBool("full")
+	interval, _ := cmd.Flags().GetDuration("backup-interval")
+	keep, _ := cmd.Flags().GetInt("keep")
+	maxAge, _ := cmd.Flags().GetDuration("max-age")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if interval < 0 || keep < 0 || maxAge < 0 {
+		return fmt.Errorf("--backup-interval, --keep, and --max-age must not be negative")
+	}
+	retention := filesystem.Retention{Keep: keep, MaxAge: maxAge}
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+	backups := addon.NewBackupManager(server, target.backupDir(cmd))
+
+	if interval == 0 {
+		return createSnapshot(backups, full, retention, jsonOutput)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Taking scheduled backups", "server", server.Paths.ServerRoot, "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for failures := 0; ; {
+		// A failed backup is retried at the next interval; only repeated
+		// failures end the schedule, so a broken setup doesn't go unnoticed
+		server.InvalidatePacks()
+		if err := createSnapshot(backups, full, retention, jsonOutput); err != nil {
+			failures++
+			if failures == maxScheduledBackupFailures {
+				return fmt.Errorf("stopped scheduled backups after %d failed in a row: %w", failures, err)
+			}
+			slog.Warn("Scheduled backup failed", "error", err, "failures", failures)
+		} else {
+			failures = 0
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopped scheduled backups")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// createSnapshot takes a snapshot backup, reports it, and prunes the snapshot
+// backups the retention policy no longer keeps
+func createSnapshot(backups *addon.BackupManager, full bool, retention filesystem.Retention, jsonOutput bool) error {
+	metadata, err := backups.CreateSnapshotBackup(full)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		kind := "full"
+		if metadata.Base != "" {
+			kind = "incremental"
+		}
+		fmt.Printf("Created %s backup %s of %d file(s)\n", kind, metadata.ID, len(metadata.Files))
+	}
+
+	pruned, err := backups.Prune(addon.SnapshotOperation, retention, time.Now(), false)
+	for _, id := range pruned {
+		slog.Info("Pruned backup past the retention policy", "id", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply the retention policy: %w", err)
+	}
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	serverPath := args[1]
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	merge, _ := cmd.Flags().GetBool("merge")
+	only, _ := cmd.Flags().GetString("only")
+	targetServer, _ := cmd.Flags().GetString("target-server")
+
+	manager, err := newRollbackManager(cmd, serverPath)
+	if targetServer != "" {
+		manager, err = newCrossServerRollbackManager(cmd, serverPath, targetServer)
+	}
+	if err != nil {
+		return err
+	}
+	backupID, err := manager.ResolveBackupID(args[0])
+	if err != nil {
+		return err
+	}
+
+	runner, err := hookRunner(cmd)
+	if err != nil {
+		return err
+	}
+	options := addon.RollbackOptions{Verbose: verbose && !jsonOutput, DryRun: dryRun, Merge: merge, Only: only, Hooks: runner}
+
+	if !jsonOutput {
+		plan, err := manager.PlanRollback(backupID, only)
+		if err != nil {
+			return fmt.Errorf("failed to plan restore: %w", err)
+		}
+		renderRestorePlan(plan)
+
+		laterPacks, err := manager.FindLaterPacks(backupID, only)
+		if err != nil {
+			return fmt.Errorf("failed to check for packs added after the backup: %w", err)
+		}
+		renderLaterPacks(laterPacks, merge)
+
+		if dryRun {
+			fmt.Println("DRY RUN: No files were changed")
+			return nil
+		}
+		if len(plan.Changes) == 0 && len(laterPacks) == 0 {
+			fmt.Println("Server already matches the backup; nothing to restore")
+			return nil
+		}
+		if !yes {
+			confirmed, err := confirm(cmd, "Restore these changes?")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return fmt.Errorf("restore aborted by user")
+			}
+		}
+	}
+
+	result, err := manager.RollbackToBackup(backupID, options)
+	if !dryRun {
+		event := serverEvent(manager.Server(), webhook.OperationRollback, err)
+		event.BackupID = backupID
+		event.Warnings = result.Warnings
+		notifyWebhooks(cmd, event)
+	}
+
+	if jsonOutput {
+		data, marshalErr := json.MarshalIndent(result, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	for _, warning := range result.Warnings {
+		slog.Warn(warning)
+	}
+	if health := result.Health; health != nil {
+		fmt.Printf("Verified %d file(s), %d world config(s), and %d active pack(s) against the backup\n",
+			health.FilesVerified, health.ConfigsChecked, health.PacksChecked)
+	}
+	if result.Merged {
+		fmt.Printf("Restored backup %s, keeping %d pack(s) activated after it\n", backupID, len(result.LaterPacks))
+		return nil
+	}
+	if only != "" {
+		fmt.Printf("Restored %s from backup %s\n", strings.Join(result.RestoredFiles, ", "), backupID)
+		return nil
+	}
+	if targetServer != "" {
+		fmt.Printf("Restored backup %s into %s\n", backupID, manager.Server().Paths.ServerRoot)
+		return nil
+	}
+	fmt.Printf("Restored backup %s\n", backupID)
+	return nil
+}
+
+// renderRestorePlan prints each change a restore would make, with config diffs
+func renderRestorePlan(plan *filesystem.RestorePlan) {
+	if len(plan.Changes) == 0 {
+		fmt.Printf("Backup %s: no changes\n", plan.BackupID)
+		return
+	}
+
+	fmt.Printf("Backup %s would make %d change(s):\n", plan.BackupID, len(plan.Changes))
+	for _, change := range plan.Changes {
+		fmt.Printf("  %-9s %s\n", change.Action, change.Path)
+		if change.Diff != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(change.Diff, "\n"), "\n") {
+				fmt.Printf("      %s\n", line)
+			}
+		}
+	}
+}
+
+// renderLaterPacks lists the packs activated since the backup and what the restore does with them
+func renderLaterPacks(laterPacks []addon.LaterPack, merge bool) {
+	if len(laterPacks) == 0 {
+		return
+	}
+
+	if merge {
+		fmt.Printf("%d pack(s) were activated after this backup and will be kept (--merge):\n", len(laterPacks))
+	} else {
+		fmt.Printf("Warning: %d pack(s) were activated after this backup and will be deactivated:\n", len(laterPacks))
+	}
+	for _, later := range laterPacks {
+		fmt.Printf("  %s v%d.%d.%d (%s)\n", later.Pack.PackID,
+			later.Pack.Version[0], later.Pack.Version[1], later.Pack.Version[2], filepath.Base(later.ConfigFile))
+	}
+	if merge {
+		fmt.Println("The changes above are shown before these packs are re-added.")
+	} else {
+		fmt.Println("Use --merge to keep them active.")
+	}
+}
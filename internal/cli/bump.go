@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewBumpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bump [pack-dir]",
+		Short: "Increment a pack's version in its manifest.json",
+		Long: `Set a new version in a pack source directory's manifest.json: the header
+version and the version of every module, which are kept equal. --patch (the
+default), --minor, or --major increments that part of the version; --set gives
+the version explicitly. A version that is not newer than the current one is an
+error, so versions never move backwards.
+
+Sibling packs in the same parent directory that depend on the pack's old
+version are updated to depend on the new one.
+
+--changelog adds an entry under a heading for the new version to CHANGELOG.md
+in the pack directory, or the file given with --changelog-file, creating it
+if needed. Repeat it for several entries:
+
+  blockbench bump ./MyBP --minor --changelog "Add the copper golem"
+
+Manifests are rewritten with two-space indentation, keeping their fields and
+order. With the global --dry-run flag, nothing is written.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBump,
+	}
+
+	cmd.Flags().Bool("patch", false, "Increment the patch version (the default)")
+	cmd.Flags().Bool("minor", false, "Increment the minor version")
+	cmd.Flags().Bool("major", false, "Increment the major version")
+	cmd.Flags().String("set", "", "Set this version instead, e.g. 2.0.0")
+	cmd.Flags().StringArray("changelog", nil, "Add this entry to the changelog under the new version (repeatable)")
+	cmd.Flags().String("changelog-file", "", "Changelog to add entries to (default: pack-dir/CHANGELOG.md)")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runBump(cmd *cobra.Command, args []string) error {
+	setValue, _ := cmd.Flags().GetString("set")
+	changelog, _ := cmd.Flags().GetStringArray("changelog")
+	changelogFile, _ := cmd.Flags().GetString("changelog-file")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	options := addon.BumpOptions{
+		Part:          addon.BumpPatch,
+		Changelog:     changelog,
+		ChangelogFile: changelogFile,
+		DryRun:        dryRun,
+	}
+	var chosen []string
+	for _, part := range []addon.BumpPart{addon.BumpPatch, addon.BumpMinor, addon.BumpMajor} {
+		if set, _ := cmd.Flags().GetBool(string(part)); set {
+			options.Part = part
+			chosen = append(chosen, "--"+string(part))
+		}
+	}
+	if setValue != "" {
+		version, err := validation.ParseVersion(setValue)
+		if err != nil {
+			return err
+		}
+		options.Set = &version
+		chosen = append(chosen, "--set")
+	}
+	if len(chosen) > 1 {
+		return fmt.Errorf("%s cannot be used together: choose one", strings.Join(chosen, ", "))
+	}
+	for _, entry := range changelog {
+		if strings.TrimSpace(entry) == "" || strings.Contains(entry, "\n") {
+			return fmt.Errorf("invalid --changelog entry %q: use a single non-empty line", entry)
+		}
+	}
+
+	result, err := addon.BumpVersion(args[0], options)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	verb := "Bumped"
+	if dryRun {
+		verb = "Would bump"
+	}
+	fmt.Printf("%s %s from %s to %s\n", verb, result.Name, formatVersion(result.PreviousVersion), formatVersion(result.Version))
+	if result.Modules > 0 {
+		fmt.Printf("  %d module version(s) set to match\n", result.Modules)
+	}
+	for _, dependent := range result.Dependents {
+		fmt.Printf("  Updated the dependency of %s\n", dependent)
+	}
+	if result.Changelog != "" {
+		fmt.Printf("  %d changelog entry(s) added to %s\n", len(changelog), result.Changelog)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/updates"
+	"github.com/spf13/cobra"
+)
+
+func NewCheckUpdatesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-updates [server-path]",
+		Short: "Find newer versions of installed packs in the configured addon sources",
+		Long: `Compare the server's installed packs with the addons offered by the configured
+addon sources (see 'blockbench sources') and list the packs with a newer
+version available. --source checks the given sources instead of the configured
+ones.
+
+With --apply, the addons providing the newest versions are checked against
+their SHA-256, downloaded into blockbench's cache directory when they are
+URLs, and installed as one batch with a single backup; if any install fails,
+the server is restored from it. Use --dry-run to only list the updates.
+
+The exit status is 0 whether or not updates are available, so the command can
+run from cron, e.g. nightly with --apply --restart-server.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runCheckUpdates,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().StringArray("source", nil, "Addon source to check instead of the configured ones (repeatable)")
+	cmd.Flags().Bool("apply", false, "Install the available updates")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	cmd.Flags().Bool("allow-scripts", false, "Allow packs with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	cmd.Flags().Bool("json", false, "Output the updates and result in JSON format")
+	addServerControlFlags(cmd)
+	addExtractLimitFlags(cmd)
+
+	return cmd
+}
+
+// checkUpdatesResult is the JSON output of check-updates
+type checkUpdatesResult struct {
+	Updates []updates.Update   `json:"updates"`
+	Batch   *addon.BatchResult `json:"batch,omitempty"`
+}
+
+func runCheckUpdates(cmd *cobra.Command, args []string) error {
+	sources, _ := cmd.Flags().GetStringArray("source")
+	apply, _ := cmd.Flags().GetBool("apply")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 0 {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return err
+		}
+		sources = cfg.Sources
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no addon sources configured: add one with 'blockbench sources add' or pass --source")
+	}
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	backupDir := target.backupDir(cmd)
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	scan := func(path string) ([]lockfile.Pack, error) {
+		report, err := addon.ValidateAddon(path, false, limits)
+		if err != nil {
+			return nil, err
+		}
+		packs := make([]lockfile.Pack, 0, len(report.Packs))
+		for _, pack := range report.Packs {
+			packs = append(packs, lockfile.Pack{UUID: pack.UUID, Name: pack.Name, Version: pack.Version, Type: pack.Type})
+		}
+		return packs, nil
+	}
+	var candidates []updates.Candidate
+	for _, source := range sources {
+		offered, err := updates.Load(source, scan)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, offered...)
+	}
+
+	installed, err := server.ListInstalledPacks()
+	if err != nil {
+		return fmt.Errorf("failed to list installed packs: %w", err)
+	}
+	result := checkUpdatesResult{Updates: updates.Find(installed, candidates)}
+	if result.Updates == nil {
+		result.Updates = []updates.Update{}
+	}
+
+	if !jsonOutput {
+		renderUpdates(result.Updates)
+	}
+	if len(result.Updates) == 0 || !apply || dryRun {
+		return printCheckUpdatesJSON(jsonOutput, result)
+	}
+
+	// Verify every addon before changing anything
+	cacheDir, err := downloadCacheDir()
+	if err != nil {
+		return err
+	}
+	batch := addon.NewBatch(server, backupDir)
+	for _, candidate := range updates.Addons(result.Updates) {
+		path, err := updates.Fetch(candidate, cacheDir)
+		if err != nil {
+			return err
+		}
+		batch.Install(path, addon.InstallOptions{
+			BackupDir:    backupDir,
+			ForceUpdate:  true, // Replaces the installed versions of the addon's packs
+			AllowScripts: allowScripts,
+		})
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	batchResult, err := batch.Execute(addon.BatchOptions{
+		Verbose:     verbose,
+		Description: "Before applying updates",
+	})
+	result.Batch = batchResult
+
+	if jsonOutput {
+		if jsonErr := printCheckUpdatesJSON(true, result); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+	if err != nil {
+		for _, errMsg := range batchResult.Errors {
+			fmt.Printf("  - %s\n", errMsg)
+		}
+		if batchResult.RolledBack {
+			fmt.Println("All changes were rolled back")
+		}
+		return err
+	}
+	fmt.Printf("Applied %d update(s)\n", len(result.Updates))
+	return nil
+}
+
+// renderUpdates prints the available updates as a table
+func renderUpdates(available []updates.Update) {
+	if len(available) == 0 {
+		fmt.Println("All packs are up to date")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tUUID\tINSTALLED\tAVAILABLE\tSOURCE")
+	fmt.Fprintln(w, "----\t----\t----\t---------\t---------\t------")
+	for _, update := range available {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			update.Name,
+			update.Type,
+			update.UUID,
+			formatVersion(update.Installed),
+			formatVersion(update.Available),
+			update.Addon.Source)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	fmt.Printf("\n%d update(s) available\n", len(available))
+}
+
+func printCheckUpdatesJSON(jsonOutput bool, result checkUpdatesResult) error {
+	if !jsonOutput {
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewCleanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean [server-path]",
+		Short: "Find and remove pack directories no world uses",
+		Long: `Find pack directories that no world uses, such as packs copied in by hand and
+never activated or left behind when a pack was removed from a world by hand.
+
+--unreferenced lists every directory in the server's pack directories whose
+pack UUID is listed in no world config of any world in the worlds directory,
+with the space removing it would reclaim. Directories without a valid
+manifest.json are left alone, since nothing says which pack they are.
+
+With --delete, the listed directories are backed up and removed after asking
+for confirmation (or with --yes); a linked pack loses only its link, never its
+source. The removal is recorded in the history as an uninstall, so
+'blockbench undo' puts the directories back, though not the links. Use the
+global --dry-run flag to only list them.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runClean,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().Bool("unreferenced", false, "Find pack directories whose UUID no world config lists")
+	cmd.Flags().Bool("delete", false, "Back up and remove the directories found")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	cmd.Flags().Bool("json", false, "Output the result in JSON format (with --delete, implies --yes)")
+	addPathPolicyFlag(cmd)
+	addNoWebhooksFlag(cmd)
+	addServerControlFlags(cmd)
+
+	return cmd
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	unreferenced, _ := cmd.Flags().GetBool("unreferenced")
+	remove, _ := cmd.Flags().GetBool("delete")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if !unreferenced {
+		return fmt.Errorf("nothing to clean: use --unreferenced to find pack directories no world config lists")
+	}
+	policy, err := pathPolicyFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	packs, err := server.UnreferencedPacks()
+	if err != nil {
+		return err
+	}
+	if !remove || len(packs) == 0 {
+		return printUnreferencedPacks(packs, jsonOutput)
+	}
+
+	if !jsonOutput {
+		if err := printUnreferencedPacks(packs, false); err != nil {
+			return err
+		}
+		if dryRun {
+			fmt.Println("DRY RUN: No files were changed")
+			return nil
+		}
+		if !yes {
+			confirmed, err := confirm(cmd, fmt.Sprintf("Back up and remove these %d pack(s)?", len(packs)))
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return fmt.Errorf("clean aborted by user")
+			}
+		}
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	cleaner := addon.NewCleaner(server, target.backupDir(cmd))
+	result, err := cleaner.RemoveUnreferenced(packs, addon.CleanOptions{
+		DryRun:     dryRun,
+		Verbose:    verbose && !jsonOutput,
+		PathPolicy: policy,
+	})
+	if !dryRun {
+		notifyWebhooks(cmd, cleanEvent(server, result, err))
+	}
+
+	if jsonOutput {
+		data, marshalErr := json.MarshalIndent(result, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+		return err
+	}
+	if err != nil {
+		if result != nil && result.RolledBack {
+			fmt.Println("Removal failed; the backup was restored")
+		}
+		return err
+	}
+	fmt.Printf("Removed %d pack(s), reclaiming %s (backup: %s)\n",
+		len(result.Packs), filesystem.FormatSize(result.Reclaimed), result.BackupMetadata.ID)
+	return nil
+}
+
+// printUnreferencedPacks lists unreferenced packs with the space they take
+func printUnreferencedPacks(packs []minecraft.UnreferencedPack, jsonOutput bool) error {
+	if jsonOutput {
+		if packs == nil {
+			packs = []minecraft.UnreferencedPack{}
+		}
+		data, err := json.MarshalIndent(struct {
+			Packs       []minecraft.UnreferencedPack `json:"packs"`
+			Reclaimable int64                        `json:"reclaimable"`
+		}{packs, addon.ReclaimableSize(packs)}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(packs) == 0 {
+		fmt.Println("No unreferenced pack directories: every pack is listed in a world config")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PACK\tTYPE\tVERSION\tSIZE\tDIRECTORY")
+	fmt.Fprintln(w, "----\t----\t-------\t----\t---------")
+	for _, pack := range packs {
+		size := filesystem.FormatSize(pack.Size)
+		if pack.Link != "" {
+			size = "link"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", pack.Name, pack.Type, formatVersion(pack.Version), size, pack.Dir)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d unreferenced pack(s), %s reclaimable\n", len(packs), filesystem.FormatSize(addon.ReclaimableSize(packs)))
+	return nil
+}
@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate a shell completion script",
+		Long: `Print a completion script for a shell. Besides commands and flags, it completes
+server profile names wherever a server-path is expected, the names and UUIDs of
+installed packs for uninstall, info, and export, and backup IDs for 'backup
+restore'. Packs and backups are looked up on the server profiles of --servers,
+or on every server profile, since the server-path comes after them.
+
+To load completions for the current session:
+
+  bash:        source <(blockbench completion bash)
+  zsh:         source <(blockbench completion zsh)
+  fish:        blockbench completion fish | source
+  powershell:  blockbench completion powershell | Out-String | Invoke-Expression
+
+To load them for every session, write the script to your shell's completion
+directory, e.g. for bash:
+
+  blockbench completion bash > /etc/bash_completion.d/blockbench`,
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE:      runCompletion,
+	}
+
+	cmd.Flags().Bool("no-descriptions", false, "Leave descriptions out of the completions")
+
+	return cmd
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	noDescriptions, _ := cmd.Flags().GetBool("no-descriptions")
+	root := cmd.Root()
+
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(os.Stdout, !noDescriptions)
+	case "zsh":
+		if noDescriptions {
+			return root.GenZshCompletionNoDesc(os.Stdout)
+		}
+		return root.GenZshCompletion(os.Stdout)
+	case "fish":
+		return root.GenFishCompletion(os.Stdout, !noDescriptions)
+	case "powershell":
+		if noDescriptions {
+			return root.GenPowerShellCompletion(os.Stdout)
+		}
+		return root.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+	return fmt.Errorf("unsupported shell %q: use bash, zsh, fish, or powershell", args[0])
+}
+
+// completeArgs completes each positional argument with the function at its
+// position; a nil function leaves the argument to the shell's file completion
+func completeArgs(funcs ...cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= len(funcs) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if funcs[len(args)] == nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return funcs[len(args)](cmd, args, toComplete)
+	}
+}
+
+// completeServerPath completes a server-path with the server profile names,
+// falling back to the shell's file completion when none matches. With
+// --servers the server-path is omitted, so nothing is completed.
+func completeServerPath(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if servers, _ := cmd.Flags().GetString("servers"); servers != "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	completions, _ := completeProfiles(cmd, args, toComplete)
+	return completions, cobra.ShellCompDirectiveDefault
+}
+
+// completeProfiles completes the server profile names, described by their paths
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []cobra.Completion
+	for _, name := range cfg.ProfileNames() {
+		if strings.HasPrefix(name, toComplete) {
+			profile, _ := cfg.Profile(name)
+			completions = append(completions, cobra.CompletionWithDesc(name, profile.Path))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeServersFlag completes the comma-separated profile list of --servers
+func completeServersFlag(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	done, current := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		done, current = toComplete[:i+1], toComplete[i+1:]
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	chosen := make(map[string]bool)
+	for _, name := range strings.Split(done, ",") {
+		chosen[strings.TrimSpace(name)] = true
+	}
+	var completions []cobra.Completion
+	if done == "" && strings.HasPrefix("all", current) {
+		completions = append(completions, cobra.CompletionWithDesc("all", "every server profile"))
+	}
+	for _, name := range cfg.ProfileNames() {
+		if !chosen[name] && strings.HasPrefix(name, current) {
+			profile, _ := cfg.Profile(name)
+			completions = append(completions, cobra.CompletionWithDesc(done+name, profile.Path))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completionTargets returns the servers to look packs and backups up on for
+// completion: the profiles of --servers, or every profile. Profiles that
+// can't be resolved are skipped.
+func completionTargets(cmd *cobra.Command) []*serverTarget {
+	names, err := selectedServers(cmd)
+	if err != nil {
+		return nil
+	}
+	if names == nil {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return nil
+		}
+		names = cfg.ProfileNames()
+	}
+
+	var targets []*serverTarget
+	for _, name := range names {
+		target, err := resolveServerTarget(cmd, name)
+		if err == nil {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// completeInstalledPacks completes the names of the packs installed on the
+// completion targets, described by their type and UUID
+func completeInstalledPacks(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return installedPackCompletions(cmd, toComplete, false), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeInstalledPackUUIDs completes the UUIDs of the packs installed on
+// the completion targets, described by their names
+func completeInstalledPackUUIDs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	return installedPackCompletions(cmd, toComplete, true), cobra.ShellCompDirectiveNoFileComp
+}
+
+// installedPackCompletions lists each installed pack once, by name or UUID,
+// matching toComplete as a case-insensitive prefix
+func installedPackCompletions(cmd *cobra.Command, toComplete string, byUUID bool) []cobra.Completion {
+	var completions []cobra.Completion
+	seen := make(map[string]bool)
+	for _, target := range completionTargets(cmd) {
+		server, err := target.newServer()
+		if err != nil {
+			continue
+		}
+		packs, err := server.ListInstalledPacks()
+		if err != nil {
+			continue
+		}
+		for _, pack := range packs {
+			value, description := pack.Name, fmt.Sprintf("%s pack %s", pack.Type, pack.PackID)
+			if byUUID {
+				value, description = pack.PackID, fmt.Sprintf("%s pack %s", pack.Type, pack.Name)
+			}
+			if seen[value] || !strings.HasPrefix(strings.ToLower(value), strings.ToLower(toComplete)) {
+				continue
+			}
+			seen[value] = true
+			completions = append(completions, cobra.CompletionWithDesc(value, description))
+		}
+	}
+	return completions
+}
+
+// completeBackupIDs completes the IDs of the backups of the completion
+// targets, newest first, described by when and why they were taken
+func completeBackupIDs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	type backupCompletion struct {
+		completion cobra.Completion
+		timestamp  int64
+	}
+	var backups []backupCompletion
+	seen := make(map[string]bool)
+	for _, target := range completionTargets(cmd) {
+		server, err := target.newServer()
+		if err != nil {
+			continue
+		}
+		list, err := addon.NewRollbackManager(server, target.backupDir(cmd)).ListAvailableBackups()
+		if err != nil {
+			continue
+		}
+		for _, backup := range list {
+			if seen[backup.ID] || !strings.HasPrefix(backup.ID, toComplete) {
+				continue
+			}
+			seen[backup.ID] = true
+			description := fmt.Sprintf("%s %s %s", backup.Timestamp.Format("2006-01-02 15:04"), backup.Operation, backup.AddonName)
+			backups = append(backups, backupCompletion{
+				completion: cobra.CompletionWithDesc(backup.ID, strings.TrimSpace(description)),
+				timestamp:  backup.Timestamp.UnixNano(),
+			})
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].timestamp > backups[j].timestamp
+	})
+
+	completions := make([]cobra.Completion, 0, len(backups)+1)
+	if strings.HasPrefix(filesystem.LatestBackup, toComplete) {
+		completions = append(completions, cobra.CompletionWithDesc(filesystem.LatestBackup, "The newest backup"))
+	}
+	for _, backup := range backups {
+		completions = append(completions, backup.completion)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/spf13/cobra"
+)
+
+func NewDevCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dev [pack-dir] [server-path]",
+		Short: "Keep an installed pack in sync with its source while developing it",
+		Long: `Watch a pack's source directory and sync every change into the pack's installed
+directory on a server: the inner loop for script and behavior developers.
+
+On every change, the manifest is validated first; a broken manifest is
+reported and nothing is synced until it is fixed. Only the files that changed
+are copied, and files deleted from the source are deleted from the server,
+like rsync --delete. Hidden files, node_modules, package.json, and
+package-lock.json are left out. A new manifest version is recorded in the
+world config.
+
+A pack that isn't installed yet is installed first, with the same checks as
+'blockbench install'. With --notify, the server's reload command is sent
+through its console after each sync, so the change shows up without a restart.
+
+The source is polled every --interval until interrupted; --once syncs once
+and exits.`,
+		Args:              cobra.ExactArgs(2),
+		RunE:              runDev,
+		ValidArgsFunction: completeArgs(nil, completeServerPath),
+	}
+
+	cmd.Flags().Duration("interval", time.Second, "How often to check the source for changes")
+	cmd.Flags().Bool("once", false, "Sync once and exit instead of watching")
+	cmd.Flags().Bool("allow-scripts", false, "Allow installing a pack with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory for the first install (default: server-path/backups)")
+	cmd.Flags().String("notify", "", "Send reload to the server console after each sync: pipe:<path>, screen:<session>, tmux:<target>, or docker (or BLOCKBENCH_NOTIFY)")
+
+	return cmd
+}
+
+func runDev(cmd *cobra.Command, args []string) error {
+	source := args[0]
+	interval, _ := cmd.Flags().GetDuration("interval")
+	once, _ := cmd.Flags().GetBool("once")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return fmt.Errorf("dev changes the server as the source changes and has no dry run")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("invalid --interval %s: must be positive", interval)
+	}
+	console, err := notifyTarget(cmd)
+	if err != nil {
+		return err
+	}
+	target, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := minecraft.ParseManifest(filepath.Join(source, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	if _, err := server.FindPackDirectory(manifest.Header.UUID, manifest.GetPackType()); err != nil {
+		fmt.Printf("Installing %s from %s\n", manifest.GetDisplayName(), source)
+		installer := addon.NewInstaller(server, target.backupDir(cmd))
+		result, err := installer.InstallAddon(source, addon.InstallOptions{
+			Verbose:      verbose,
+			AllowScripts: allowScripts,
+		})
+		if err != nil {
+			return err
+		}
+		for _, warning := range result.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+
+	session, err := addon.NewDevSession(server, source)
+	if err != nil {
+		return err
+	}
+	sync := func() {
+		synced, err := session.Sync()
+		stamp := time.Now().Format("15:04:05")
+		if err != nil {
+			fmt.Printf("[%s] Not synced: %v\n", stamp, err)
+			return
+		}
+		if synced.Empty() && synced.PreviousVersion == nil {
+			if verbose {
+				fmt.Printf("[%s] Up to date\n", stamp)
+			}
+			return
+		}
+		line := fmt.Sprintf("[%s] Synced %d file(s), removed %d", stamp, len(synced.Copied), len(synced.Removed))
+		if synced.PreviousVersion != nil {
+			line += fmt.Sprintf(", version %s -> %s", formatVersion(*synced.PreviousVersion), formatVersion(synced.Version))
+		}
+		fmt.Println(line)
+		if verbose {
+			for _, file := range synced.Copied {
+				fmt.Printf("  + %s\n", file)
+			}
+			for _, file := range synced.Removed {
+				fmt.Printf("  - %s\n", file)
+			}
+		}
+		if console != nil {
+			if err := sendConsoleCommands(cmd, *console, []string{"reload"}); err != nil {
+				slog.Warn("Failed to reload the server", "error", err)
+			}
+		}
+	}
+
+	// The first scan only records the source's state to compare against
+	if _, err := session.Changed(); err != nil {
+		return err
+	}
+	sync()
+	if once {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Watching %s for changes to %s (Ctrl+C to stop)\n", source, session.Target())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			changed, err := session.Changed()
+			if err != nil {
+				slog.Warn("Failed to scan the pack source", "error", err)
+				continue
+			}
+			if changed {
+				sync()
+			}
+		}
+	}
+}
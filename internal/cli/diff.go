@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [installed-uuid-or-name] [addon-file] [server-path]",
+		Short: "Show what a new version of an addon changes in an installed pack",
+		Long: `Compare an installed pack with the pack of the same UUID in a new addon file
+before updating it: the version, the manifest's dependencies and modules, and
+every file by SHA-256 (added, removed, and changed files).
+
+The pack is looked up by UUID when the argument is one, and by name otherwise.
+Nothing on the server is changed.`,
+		Args:              cobra.ExactArgs(3),
+		RunE:              runDiff,
+		ValidArgsFunction: completeArgs(completeInstalledPacks, nil, completeServerPath),
+	}
+
+	cmd.Flags().Bool("json", false, "Output the differences in JSON format")
+	addExtractLimitFlags(cmd)
+
+	return cmd
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	identifier, addonFile := args[0], args[1]
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	target, err := resolveServerTarget(cmd, args[2])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	byUUID := validation.ValidateUUID(identifier)
+	if byUUID {
+		identifier = validation.NormalizeUUID(identifier)
+	}
+	diff, err := addon.DiffPack(server, identifier, byUUID, addonFile, limits)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("%s (%s, %s pack)\n", diff.Name, diff.UUID, diff.Type)
+	if diff.Empty() {
+		fmt.Println("No differences: the addon has the installed version of the pack")
+		return nil
+	}
+
+	if diff.InstalledVersion != diff.NewVersion {
+		fmt.Printf("Version: %s -> %s\n", formatVersion(diff.InstalledVersion), formatVersion(diff.NewVersion))
+	} else {
+		fmt.Printf("Version: %s (unchanged)\n", formatVersion(diff.NewVersion))
+	}
+	printEntryChanges("Dependencies", diff.Dependencies)
+	printEntryChanges("Modules", diff.Modules)
+
+	files := diff.Files
+	fmt.Printf("Files: %d added, %d removed, %d changed, %d unchanged\n",
+		len(files.Added), len(files.Removed), len(files.Changed), files.Unchanged)
+	for _, path := range files.Added {
+		fmt.Printf("  + %s\n", path)
+	}
+	for _, path := range files.Removed {
+		fmt.Printf("  - %s\n", path)
+	}
+	for _, path := range files.Changed {
+		fmt.Printf("  ~ %s\n", path)
+	}
+	return nil
+}
+
+// printEntryChanges prints the manifest entries an update adds (+), removes
+// (-), or changes (~)
+func printEntryChanges(title string, changes []addon.EntryChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, change := range changes {
+		switch {
+		case change.From == "":
+			fmt.Printf("  + %s %s\n", change.Key, change.To)
+		case change.To == "":
+			fmt.Printf("  - %s %s\n", change.Key, change.From)
+		default:
+			fmt.Printf("  ~ %s %s -> %s\n", change.Key, change.From, change.To)
+		}
+	}
+}
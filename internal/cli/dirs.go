@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/userdirs"
+	"github.com/spf13/cobra"
+)
+
+func NewDirsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dirs",
+		Short: "Show where blockbench keeps its own config, cache, and state",
+		Long: `Show blockbench's per-user directories, which hold files that do not belong
+to a single server. They follow the XDG base directory specification on Linux
+(XDG_CONFIG_HOME, XDG_CACHE_HOME, XDG_STATE_HOME) and the platform conventions
+on macOS and Windows.
+
+BLOCKBENCH_CONFIG_DIR, BLOCKBENCH_CACHE_DIR, and BLOCKBENCH_STATE_DIR override
+each directory.`,
+		Args: cobra.NoArgs,
+		RunE: runDirs,
+	}
+
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runDirs(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	dirs, err := userdirs.Resolve()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(dirs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Config: %s\n", dirs.Config)
+	fmt.Printf("Cache:  %s\n", dirs.Cache)
+	fmt.Printf("State:  %s\n", dirs.State)
+	return nil
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/spf13/cobra"
+)
+
+func NewDiscoverCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover [root-dir...]",
+		Short: "Find Minecraft Bedrock server installations on this host",
+		Long: `Scan directories for Bedrock dedicated server installations: any directory
+containing both server.properties and a worlds/ directory.
+
+Without arguments, the home directory, /home, /opt, /srv, and Docker volumes
+(/var/lib/docker/volumes) are scanned. Hidden directories are skipped.`,
+		Args: cobra.ArbitraryArgs,
+		RunE: runDiscover,
+	}
+
+	cmd.Flags().Int("max-depth", minecraft.DefaultDiscoveryDepth, "Maximum directory depth to search below each root")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runDiscover(cmd *cobra.Command, args []string) error {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	maxDepth, _ := cmd.Flags().GetInt("max-depth")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	roots := args
+	if len(roots) == 0 {
+		roots = minecraft.DefaultDiscoveryRoots()
+	}
+
+	if verbose && !jsonOutput {
+		fmt.Printf("Scanning %v (max depth %d)\n", roots, maxDepth)
+	}
+
+	servers, err := minecraft.DiscoverServers(roots, maxDepth)
+	if err != nil {
+		return fmt.Errorf("failed to scan for servers: %w", err)
+	}
+
+	if jsonOutput {
+		if servers == nil {
+			servers = []minecraft.DiscoveredServer{}
+		}
+		data, err := json.MarshalIndent(servers, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(servers) == 0 {
+		fmt.Println("No Bedrock servers found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tWORLD\tSTATUS")
+	fmt.Fprintln(w, "----\t-----\t------")
+	for _, server := range servers {
+		status := "ok"
+		if server.Problem != "" {
+			status = server.Problem
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", server.Path, server.WorldName, status)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+
+	return nil
+}
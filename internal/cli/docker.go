@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+// dockerStopTimeout is how long a restarted container gets to save the world and stop
+const dockerStopTimeout = 30 * time.Second
+
+// resolveServerPath returns serverPath unchanged, or with --docker, the host
+// directory behind serverPath inside the container (through the volume or
+// bind mount that holds it), so configs and packs are edited in place
+func resolveServerPath(cmd *cobra.Command, serverPath string) (string, error) {
+	name, _ := cmd.Flags().GetString("docker")
+	if name == "" {
+		return serverPath, nil
+	}
+
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", err
+	}
+	container, err := client.Inspect(name)
+	if err != nil {
+		return "", err
+	}
+	return container.HostPath(serverPath)
+}
+
+// addRestartFlag adds --restart to commands that change installed packs
+func addRestartFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("restart", false, "Restart the --docker container after a successful change so the server loads it")
+}
+
+// restartContainer restarts the --docker container when --restart is given
+func restartContainer(cmd *cobra.Command) error {
+	restart, _ := cmd.Flags().GetBool("restart")
+	if !restart {
+		return nil
+	}
+	name, _ := cmd.Flags().GetString("docker")
+
+	client, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restarting container %s...\n", name)
+	return client.Restart(name, dockerStopTimeout)
+}
+
+// checkRestartFlag rejects --restart without a container to restart
+func checkRestartFlag(cmd *cobra.Command) error {
+	restart, _ := cmd.Flags().GetBool("restart")
+	name, _ := cmd.Flags().GetString("docker")
+	if restart && name == "" {
+		return fmt.Errorf("--restart requires --docker")
+	}
+	return nil
+}
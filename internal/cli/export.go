@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [uuid-or-name] [server-path]",
+		Short: "Export an installed pack back into a .mcaddon or .mcpack file",
+		Long: `Re-archive an installed pack's directory into a distributable file, for moving
+an addon to another server when the original file is lost.
+
+The pack is looked up by UUID when the argument is one, and by name otherwise.
+With --with-deps, every installed pack it depends on is exported too (like
+'blockbench pack vendor'); without it, pack dependencies are only listed. An
+output ending in .mcpack holds the single pack; anything else is written as a
+.mcaddon.`,
+		Args:              cobra.ExactArgs(2),
+		RunE:              runExport,
+		ValidArgsFunction: completeArgs(completeInstalledPacks, completeServerPath),
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Path of the .mcaddon or .mcpack to write (required)")
+	cmd.Flags().Bool("with-deps", false, "Also export the installed packs it depends on, directly or transitively")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	identifier := args[0]
+	output, _ := cmd.Flags().GetString("output")
+	withDeps, _ := cmd.Flags().GetBool("with-deps")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if output == "" {
+		return fmt.Errorf("--output is required")
+	}
+
+	target, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	byUUID := validation.ValidateUUID(identifier)
+	result, err := addon.ExportPack(server, identifier, byUUID, output, addon.ExportOptions{Dependencies: withDeps})
+	if err != nil {
+		return err
+	}
+	return printExportResult(result, jsonOutput)
+}
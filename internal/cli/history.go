@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/history"
+	"github.com/spf13/cobra"
+)
+
+func NewHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [server-path]",
+		Short: "Show the installs, updates, uninstalls, and rollbacks run on a server",
+		Long: `Show the server's operation history, oldest first. blockbench appends an entry
+to server-path/.blockbench/history.jsonl for every install, update (an install
+that replaced installed versions of its packs), uninstall, and rollback it
+runs, and every 'blockbench undo', whether it succeeded or failed. Each entry
+records the time, the user, the packs with their versions, and the ID of the
+backup taken before the operation or restored by the rollback or undo. Dry
+runs are not recorded.
+
+--since takes a duration such as 12h or 7d, or a date such as 2024-05-01.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runHistory,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().String("operation", "", "Only show this operation: install, update, uninstall, rollback, or undo")
+	cmd.Flags().String("pack", "", "Only show operations on packs with this UUID or a name containing this text")
+	cmd.Flags().String("since", "", "Only show operations since this duration ago or date")
+	cmd.Flags().Bool("failed", false, "Only show failed operations")
+	cmd.Flags().Int("limit", 0, "Only show the most recent N matching operations")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	operation, _ := cmd.Flags().GetString("operation")
+	pack, _ := cmd.Flags().GetString("pack")
+	since, _ := cmd.Flags().GetString("since")
+	failed, _ := cmd.Flags().GetBool("failed")
+	limit, _ := cmd.Flags().GetInt("limit")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	filter := history.Filter{Operation: operation, Pack: pack, FailedOnly: failed}
+	if operation != "" && !containsString(history.Operations, operation) {
+		return fmt.Errorf("unknown operation %q: use %s", operation, strings.Join(history.Operations, ", "))
+	}
+	if since != "" {
+		sinceTime, err := parseSince(since, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Since = sinceTime
+	}
+	if limit < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	entries, err := history.Load(server.Paths.HistoryFile)
+	if err != nil {
+		return err
+	}
+	entries = filter.Apply(entries)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if jsonOutput {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No matching operations in the server's history")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOPERATION\tUSER\tSTATUS\tPACKS\tBACKUP")
+	fmt.Fprintln(w, "----\t---------\t----\t------\t-----\t------")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Operation,
+			orDefault(entry.User, "-"),
+			historyStatus(entry),
+			historySubject(entry),
+			orDefault(entry.BackupID, "-"))
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+// historyStatus describes how a history entry's operation ended
+func historyStatus(entry history.Entry) string {
+	switch {
+	case entry.Success:
+		return "ok"
+	case entry.RolledBack:
+		return "failed, rolled back"
+	default:
+		return "failed"
+	}
+}
+
+// historySubject names the packs of a history entry, or what the operation
+// was given when no packs were recorded
+func historySubject(entry history.Entry) string {
+	if len(entry.Packs) > 0 {
+		names := make([]string, len(entry.Packs))
+		for i, pack := range entry.Packs {
+			names[i] = pack.Name + " " + formatVersion(pack.Version)
+		}
+		return strings.Join(names, ", ")
+	}
+	if entry.Addon != "" {
+		return entry.Addon
+	}
+	return orDefault(entry.Target, "-")
+}
+
+// parseSince parses a duration before now, with a d suffix for days, or a
+// date or RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q: use a duration such as 12h or 7d, or a date such as 2024-05-01", value)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
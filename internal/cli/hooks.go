@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/spf13/cobra"
+)
+
+func NewHooksCommand() *cobra.Command {
+	events := make([]string, len(hooks.Events))
+	for i, event := range hooks.Events {
+		events[i] = string(event)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage executables run before and after installs and uninstalls",
+		Long: `Manage lifecycle hooks: executables run at points of install, uninstall, and
+backup restore, stored in the config file in blockbench's config directory
+(see 'blockbench dirs'). Hooks can snapshot worlds, post announcements, or
+restart servers without blockbench supporting each integration itself.
+
+Events:
+  pre-install     After the addon is validated, before the backup and any change
+  post-install    After a successful install
+  pre-uninstall   Before the backup and any change
+  post-uninstall  After a successful uninstall
+  post-rollback   After a backup is restored, when an install or uninstall
+                  fails or by 'blockbench backup restore'
+
+Each hook receives a JSON description of the event on stdin: the event, time,
+server directory and world, the addon file, the packs involved (UUID, name,
+type, version), the backup ID, and any warnings; post-rollback adds the
+operation rolled back and the error that caused it. BLOCKBENCH_EVENT and
+BLOCKBENCH_SERVER are set, and hooks run in the server directory with their
+output sent to stderr.
+
+A pre-install or pre-uninstall hook that exits non-zero aborts the operation
+before anything changes. Other hooks failing only produce warnings. Hooks of
+an event run in the order added, each for at most --timeout (default 1m).
+Dry runs run no hooks, and --no-hooks skips them.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [--timeout duration] [event] [command] [args...]",
+		Short: "Run a command on an event (" + strings.Join(events, ", ") + ")",
+		Args:  cobra.MinimumNArgs(2),
+		RunE:  runHooksAdd,
+	}
+	addCmd.Flags().String("timeout", "", "How long the hook may run, e.g. 30s or 5m (default 1m)")
+	// Everything after the command is passed to it, flags included
+	addCmd.Flags().SetInterspersed(false)
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List hooks",
+		Args:  cobra.NoArgs,
+		RunE:  runHooksList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [number]",
+		Short: "Remove a hook by its number in 'hooks list'",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runHooksRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
+
+func runHooksAdd(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetString("timeout")
+
+	event, err := hooks.ParseEvent(args[0])
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(args[1], "-") {
+		return fmt.Errorf("hook command %q looks like a flag: give flags before the event", args[1])
+	}
+	hook := hooks.Hook{Event: event, Command: args[1], Args: args[2:], Timeout: timeout}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.AddHook(hook); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would add %s hook %s to %s\n", hook.Event, hook.String(), cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s hook %s\n", hook.Event, hook.String())
+	return nil
+}
+
+func runHooksList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		list := cfg.Hooks
+		if list == nil {
+			list = []hooks.Hook{}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(cfg.Hooks) == 0 {
+		fmt.Println("No hooks configured")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tEVENT\tTIMEOUT\tCOMMAND")
+	fmt.Fprintln(w, "-\t-----\t-------\t-------")
+	for i, hook := range cfg.Hooks {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, hook.Event, orDefault(hook.Timeout, hooks.DefaultTimeout.String()), hook.String())
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+func runHooksRemove(cmd *cobra.Command, args []string) error {
+	number, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid hook number %q: use the number shown by 'blockbench hooks list'", args[0])
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	hook, err := cfg.RemoveHook(number)
+	if err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would remove %s hook %s from %s\n", hook.Event, hook.String(), cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s hook %s\n", hook.Event, hook.String())
+	return nil
+}
+
+// addNoHooksFlag adds --no-hooks to a command that runs lifecycle hooks
+func addNoHooksFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-hooks", false, "Don't run the lifecycle hooks configured with 'blockbench hooks'")
+}
+
+// hookRunner returns a runner for the hooks in the config file, or nil when
+// --no-hooks is given or no hooks are configured. Without a home directory
+// there is no config file and so no hooks.
+func hookRunner(cmd *cobra.Command) (*hooks.Runner, error) {
+	if noHooks, _ := cmd.Flags().GetBool("no-hooks"); noHooks {
+		return nil, nil
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return nil, nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return hooks.NewRunner(cfg.Hooks), nil
+}
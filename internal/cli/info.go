@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewInfoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "info [addon-name] [server-path]",
+		Short: "Show details of an installed addon pack",
+		Long: `Show details of a single installed pack: version, directory, world config
+position, modules, dependencies, capabilities, metadata, and declared subpacks
+(with the active one marked).
+
+With --world, only server-path is given and the world's level.dat is shown
+instead: the world name, the game version that last opened it, and every
+experiment it lists, on or off.
+
+A pack name or description given as a lang key, such as pack.name, is shown
+as resolved from the pack's texts/<locale>.lang file, in en_US unless --lang
+chooses another locale. The name resolved in that locale is also the one
+addon-name matches.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if world, _ := cmd.Flags().GetBool("world"); world {
+				return cobra.ExactArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE:              runInfo,
+		ValidArgsFunction: completeArgs(completeInstalledPacks, completeServerPath),
+	}
+
+	cmd.Flags().String("uuid", "", "Look up the pack by UUID instead of name")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.Flags().Bool("world", false, "Show the world's level.dat details instead of a pack")
+	cmd.Flags().String("lang", "", "Locale to resolve the pack name and description in, such as de_DE (default en_US)")
+
+	return cmd
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	if world, _ := cmd.Flags().GetBool("world"); world {
+		return runWorldInfo(cmd, args[0])
+	}
+
+	identifier := args[0]
+	target, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+
+	uuid, _ := cmd.Flags().GetString("uuid")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	byUUID := uuid != ""
+	if byUUID {
+		identifier = uuid
+	}
+
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+	if server.Locale, err = packLocale(cmd); err != nil {
+		return err
+	}
+
+	details, err := addon.GetPackDetails(server, identifier, byUUID)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	renderPackDetails(details)
+	return nil
+}
+
+func renderPackDetails(details *addon.PackDetails) {
+	fmt.Printf("Name:        %s\n", details.Name)
+	fmt.Printf("UUID:        %s\n", details.PackID)
+	fmt.Printf("Type:        %s\n", details.Type)
+	fmt.Printf("Version:     %d.%d.%d\n", details.Version[0], details.Version[1], details.Version[2])
+	fmt.Printf("Status:      %s\n", details.Status)
+	if details.Description != "" {
+		fmt.Printf("Description: %s\n", details.Description)
+	}
+	if details.Directory != "" {
+		fmt.Printf("Directory:   %s\n", details.Directory)
+		fmt.Printf("Size:        %s\n", filesystem.FormatSize(details.Size))
+	} else {
+		fmt.Println("Directory:   (missing)")
+	}
+	if details.Link != "" {
+		fmt.Printf("Linked to:   %s\n", details.Link)
+	}
+	fmt.Printf("Config:      %s (index %d)\n", details.ConfigFile, details.ConfigIndex)
+
+	if details.ManifestError != "" {
+		fmt.Printf("\nManifest could not be loaded: %s\n", details.ManifestError)
+		return
+	}
+
+	fmt.Printf("Min engine:  %d.%d.%d\n", details.MinEngine[0], details.MinEngine[1], details.MinEngine[2])
+	if len(details.Capabilities) > 0 {
+		fmt.Printf("Capabilities: %s\n", strings.Join(details.Capabilities, ", "))
+	}
+
+	if meta := details.Metadata; meta != nil {
+		fmt.Println("\nMetadata:")
+		if len(meta.Authors) > 0 {
+			fmt.Printf("  Authors: %s\n", strings.Join(meta.Authors, ", "))
+		}
+		if meta.License != "" {
+			fmt.Printf("  License: %s\n", meta.License)
+		}
+		if meta.URL != "" {
+			fmt.Printf("  URL:     %s\n", meta.URL)
+		}
+		if len(meta.GeneratedWith) > 0 {
+			tools := make([]string, 0, len(meta.GeneratedWith))
+			for tool, versions := range meta.GeneratedWith {
+				tools = append(tools, fmt.Sprintf("%s %s", tool, strings.Join(versions, "/")))
+			}
+			sort.Strings(tools)
+			fmt.Printf("  Generated with: %s\n", strings.Join(tools, ", "))
+		}
+	}
+
+	if len(details.Modules) > 0 {
+		fmt.Println("\nModules:")
+		for _, module := range details.Modules {
+			fmt.Printf("  - %s (%s)\n", module.Type, module.UUID)
+		}
+	}
+
+	if len(details.Dependencies) > 0 {
+		fmt.Println("\nDependencies:")
+		for _, dep := range details.Dependencies {
+			if dep.ModuleName != "" {
+				fmt.Printf("  - %s %s\n", dep.ModuleName, dep.ModuleVersion)
+			} else {
+				fmt.Printf("  - %s (%d.%d.%d)\n", dep.UUID, dep.Version[0], dep.Version[1], dep.Version[2])
+			}
+		}
+	}
+
+	if len(details.Subpacks) > 0 {
+		fmt.Println("\nSubpacks:")
+		for _, subpack := range details.Subpacks {
+			marker := " "
+			if subpack.FolderName == details.Subpack {
+				marker = "*"
+			}
+			fmt.Printf("  %s %s (%s)", marker, subpack.Name, subpack.FolderName)
+			if subpack.MemoryTier > 0 {
+				fmt.Printf(" memory tier %d", subpack.MemoryTier)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// runWorldInfo prints what the world's level.dat says about it
+func runWorldInfo(cmd *cobra.Command, serverPath string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	target, err := resolveServerTarget(cmd, serverPath)
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	info, err := server.WorldInfo()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("World:        %s\n", info.Name)
+	fmt.Printf("Level.dat:    %s (storage version %d)\n", info.LevelDat, info.StorageVersion)
+	if info.GameVersion != "" {
+		fmt.Printf("Game version: %s\n", info.GameVersion)
+	}
+	if len(info.Experiments) == 0 {
+		fmt.Println("\nExperiments:  none")
+		return nil
+	}
+	fmt.Println("\nExperiments:")
+	for _, experiment := range info.Experiments {
+		state := "off"
+		if experiment.Enabled {
+			state = "on"
+		}
+		fmt.Printf("  - %s (%s): %s\n", experiment.Title, experiment.Key, state)
+	}
+	if info.ExperimentsEverUsed {
+		fmt.Println("The world has used experiments")
+	}
+	return nil
+}
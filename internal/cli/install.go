@@ -1,54 +1,302 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/config"
 	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/signature"
+	"github.com/makutaku/blockbench/pkg/filesystem"
 	"github.com/spf13/cobra"
 )
 
 func NewInstallCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "install [addon-file] [server-path]",
+		Use:   "install [addon-file | name@version] [server-path]",
 		Short: "Install a Minecraft Bedrock addon to a server",
 		Long: `Install a Minecraft Bedrock addon to a server.
 
 Supports both .mcaddon files (containing multiple packs) and individual .mcpack files.
-The addon will be extracted, validated, and installed with automatic backup creation.`,
-		Args: cobra.ExactArgs(2),
-		RunE: runInstall,
+The addon will be extracted, validated, and installed with automatic backup creation.
+
+The addon may also be an unpacked directory containing a manifest.json (or several
+pack subdirectories). Extraction is skipped, but manifest validation, conflict
+detection, backup, and config registration still run.
+
+An addon that isn't a file may be named as name or name@version, which is
+looked up in the configured registries (see 'blockbench registry'), checked
+against the registry's SHA-256, and downloaded into blockbench's cache
+directory; without a version the newest is installed.
+
+Installing packs that are already on the server at the same version changes
+nothing and succeeds; a newer version of an installed pack is installed as an
+upgrade. An older version, a pack installed as another pack type, or a pack
+installed more than once is a conflict that needs --force, which also
+reinstalls packs that are already installed.
+
+--only, --include, and --exclude install a subset of the addon's packs, for
+example to leave out an optional resource pack. --include and --exclude match
+a pack by UUID or by part of its name. Dependencies are still checked: a pack
+that depends on a pack left out needs it installed already.
+
+Packs with script modules or .js files are rejected unless --allow-scripts is
+given or BLOCKBENCH_ALLOW_SCRIPTS is set to a true value; every script file is
+listed either way.
+
+A detached minisign signature next to the addon (foo.mcaddon.sig or
+foo.mcaddon.minisig) is verified against the public keys trusted with
+'blockbench trust add', and an invalid signature rejects the install. With
+--require-signature, or BLOCKBENCH_REQUIRE_SIGNATURE set to a true value,
+addons without a valid signature by a trusted key are rejected too.
+
+Packs that depend on beta script module versions need the world's Beta APIs
+experiment, and a warning names them when it is off. --enable-experiments sets
+allow-cheats=true in server.properties, which packs driven by commands or
+experimental features need, and, after asking (or with --yes), turns on the
+experiments the packs need in the world's level.dat. A world that has used
+experiments stays marked as such. server.properties is edited in place,
+keeping its comments and order, and both files are included in the install's
+backup so a rollback or restore puts them back.
+
+Marketplace packs are encrypted, with a contents.json naming their content ID,
+and are rejected (exit code 10): a dedicated server can't read them without the
+content key that comes with a license. If you hold the license and its key,
+--content-key names a file with the key, and the pack is decrypted as it is
+installed. Keys are never written to the server.
+
+Players only get a server's resource packs if they accept the download when
+joining, unless texture-pack-required is true in server.properties; installing
+resource packs warns when it isn't, and --require-on-clients sets it, backing
+up server.properties the same way.
+
+Pack files are written as the user running blockbench, and a warning says so
+when the server directory belongs to someone else, since a server running as
+that user may not be able to read them. --chown gives the installed packs and
+the world configs listing them to a user and group, and --chmod sets their
+mode; a server profile can set defaults for both.
+
+With --link, an unpacked directory's packs are symlinked into the server's
+development pack directories instead of copied, so changes to the source show
+up when the world is reloaded. Uninstalling a linked pack removes only the
+link, never the source. Links point at host paths, so --link can't be used
+with --docker.
+
+Packs earlier in a world config override the packs after them. New packs are
+appended; --position puts the addon's packs at the top or bottom, or right
+before or after another pack, keeping the addon's own packs together.
+
+With --servers, server-path is omitted and the command runs on each named
+server profile (or all of them) in turn; see 'blockbench server'. Each server
+is backed up and rolled back on its own, so a failure on one server leaves the
+others changed, and a summary of every server's result is printed at the end.
+
+With --dry-run, --output json prints the plan as JSON instead of prose: every
+directory to create, file to copy, link to make, and world config entry to add
+or remove, so a CI job can check it before the real deployment.`,
+		Args:              serverArgs(2),
+		RunE:              runInstall,
+		ValidArgsFunction: completeArgs(nil, completeServerPath),
 	}
 
-	cmd.Flags().Bool("force", false, "Force installation even if conflicts are detected")
+	cmd.Flags().Bool("force", false, "Force installation even if conflicts are detected, or reinstall the same version")
 	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
 	cmd.Flags().Bool("interactive", false, "Interactive mode - confirm each step before proceeding")
+	cmd.Flags().Bool("verify", false, "Verify each copied file by SHA-256 hash and re-copy once on mismatch")
+	cmd.Flags().Bool("json", false, "Output the installation result in JSON format")
+	addPlanOutputFlag(cmd)
+	cmd.Flags().String("subpack", "", "Subpack folder name to activate for packs that declare it")
+	cmd.Flags().String("only", "", "Install only the addon's packs of this type: behavior or resource")
+	cmd.Flags().StringSlice("include", nil, "Install only the addon's packs with this UUID or name (repeatable)")
+	cmd.Flags().StringSlice("exclude", nil, "Leave out the addon's packs with this UUID or name (repeatable)")
+	cmd.Flags().String("position", "", "Where the packs go in their world configs, which sets override priority: top, bottom, before=<uuid>, or after=<uuid> (default: new packs at the bottom)")
+	cmd.Flags().Bool("strict", false, "Reject the install if any pack JSON file fails deep content validation or an asset problem is found")
+	cmd.Flags().Bool("require-on-clients", false, "Set texture-pack-required=true in server.properties so every player downloads the resource packs")
+	cmd.Flags().Bool("enable-experiments", false, "Set allow-cheats=true in server.properties and, after confirmation, turn on the world experiments the packs need in level.dat")
+	cmd.Flags().Bool("allow-scripts", false, "Allow packs with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	cmd.Flags().Bool("require-signature", false, "Reject the addon unless it has a valid signature by a trusted key (or set BLOCKBENCH_REQUIRE_SIGNATURE=1)")
+	cmd.Flags().String("content-key", "", "File with the content keys of encrypted Marketplace packs you hold a license for: a key per line, alone or as <content-id or pack UUID>=<key>")
+	cmd.Flags().StringSlice("deny-capability", nil, "Reject the install if any pack requests this manifest capability (repeatable, e.g. script_eval)")
+	addPathPolicyFlag(cmd)
+	addNoHooksFlag(cmd)
+	addNoWebhooksFlag(cmd)
+	cmd.Flags().Bool("dedupe", false, "Hard-link installed files to identical content already in the server's content store (see 'blockbench store gc')")
+	cmd.Flags().String("chown", "", "Give installed pack files and world configs to this owner, as user or user:group (default: the server profile's owner)")
+	cmd.Flags().String("chmod", "", "Give installed pack files and world configs this mode, in octal such as 644; directories also get search permission (default: the server profile's mode)")
+	cmd.Flags().Bool("link", false, "Symlink the packs of an unpacked pack or addon directory into the development pack directories instead of copying them")
+	cmd.Flags().Bool("direct", false, "Stream pack files from the archive straight into the server, skipping the temporary extraction (halves disk I/O; not compatible with --strict)")
+	addExtractLimitFlags(cmd)
+	addRestartFlag(cmd)
+	addServerControlFlags(cmd)
+	addNotifyFlag(cmd)
+	addServersFlag(cmd)
+	addRegistryFlag(cmd)
 
 	return cmd
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
-	addonFile := args[0]
-	serverPath := args[1]
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	planOutput, err := planOutputMode(cmd)
+	if err != nil {
+		return err
+	}
+	if planOutput && jsonOutput {
+		return fmt.Errorf("--json and --output json cannot be used together; the --json result of a dry run includes the plan")
+	}
+
+	servers, err := selectedServers(cmd)
+	if err != nil {
+		return err
+	}
+	addonFile, err := resolveAddonArg(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if servers != nil {
+		return runOnServers(cmd, servers, func(server string) (any, error) {
+			result, err := installOnServer(cmd, addonFile, server)
+			if planOutput {
+				return installPlan(result), err
+			}
+			if jsonOutput {
+				return result, err
+			}
+			return nil, printInstallResult(cmd, result, err)
+		})
+	}
+
+	result, err := installOnServer(cmd, addonFile, args[1])
+
+	if planOutput {
+		return printDryRunPlan(installPlan(result), err)
+	}
+	if jsonOutput {
+		data, marshalErr := json.MarshalIndent(result, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+		if err == nil && result.Success && !dryRun {
+			notifyConsole(cmd, "Installed", result.InstalledPacks)
+			return restartContainer(cmd)
+		}
+		return err
+	}
+
+	if err := printInstallResult(cmd, result, err); err != nil {
+		return err
+	}
+	if result.Success && !dryRun {
+		notifyConsole(cmd, "Installed", result.InstalledPacks)
+		return restartContainer(cmd)
+	}
+	return nil
+}
 
+// installOnServer installs addonFile on the server named by serverArg, a path or profile
+func installOnServer(cmd *cobra.Command, addonFile, serverArg string) (*addon.InstallResult, error) {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	verbose, _ := cmd.Flags().GetBool("verbose")
 	force, _ := cmd.Flags().GetBool("force")
-	interactive, _ := cmd.Flags().GetBool("interactive")
-	backupDir, _ := cmd.Flags().GetString("backup-dir")
-
-	// Set default backup directory
-	if backupDir == "" {
-		backupDir = filepath.Join(serverPath, "backups")
+	interactive := interactiveMode(cmd)
+	verify, _ := cmd.Flags().GetBool("verify")
+	jsonOutput := wantsJSON(cmd)
+	subpack, _ := cmd.Flags().GetString("subpack")
+	positionSpec, _ := cmd.Flags().GetString("position")
+	only, _ := cmd.Flags().GetString("only")
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	denyCapabilities, _ := cmd.Flags().GetStringSlice("deny-capability")
+	strict, _ := cmd.Flags().GetBool("strict")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	direct, _ := cmd.Flags().GetBool("direct")
+	dedupe, _ := cmd.Flags().GetBool("dedupe")
+	link, _ := cmd.Flags().GetBool("link")
+	requireSignature, _ := cmd.Flags().GetBool("require-signature")
+	enableExperiments, _ := cmd.Flags().GetBool("enable-experiments")
+	requireOnClients, _ := cmd.Flags().GetBool("require-on-clients")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+	if !requireSignature {
+		requireSignature = signatureRequiredByEnvironment()
+	}
+	keys, err := trustedKeys()
+	if err != nil {
+		return nil, err
+	}
+	var contentKeys *minecraft.ContentKeys
+	if keyFile, _ := cmd.Flags().GetString("content-key"); keyFile != "" {
+		if contentKeys, err = minecraft.LoadContentKeys(keyFile); err != nil {
+			return nil, err
+		}
+	}
+	position, err := minecraft.ParsePackPosition(positionSpec)
+	if err != nil {
+		return nil, err
+	}
+	onlyType, err := addon.ParsePackFilterType(only)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if docker, _ := cmd.Flags().GetString("docker"); link && docker != "" {
+		return nil, fmt.Errorf("--link cannot be used with --docker: the links would point at host paths the container can't see")
+	}
+	policy, err := pathPolicyFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRestartFlag(cmd); err != nil {
+		return nil, err
+	}
+	if _, err := notifyTarget(cmd); err != nil {
+		return nil, err
+	}
+	runner, err := hookRunner(cmd)
+	if err != nil {
+		return nil, err
+	}
+	target, err := resolveServerTarget(cmd, serverArg)
+	if err != nil {
+		return nil, err
+	}
+	backupDir := target.backupDir(cmd)
+	ownership, err := target.ownership(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if link {
+		if cmd.Flags().Changed("chown") || cmd.Flags().Changed("chmod") {
+			return nil, fmt.Errorf("--chown and --chmod cannot be used with --link: linked packs are their source directories")
+		}
+		ownership = nil // A profile's defaults don't apply to someone's source
 	}
 
 	// Create server instance
-	server, err := minecraft.NewServer(serverPath)
+	server, err := target.newServer()
 	if err != nil {
-		return fmt.Errorf("failed to initialize server: %w", err)
+		return nil, err
 	}
 
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer serverDone()
+
 	// Create installer
 	installer := addon.NewInstaller(server, backupDir)
 
@@ -59,12 +307,89 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		BackupDir:   backupDir,
 		ForceUpdate: force,
 		Interactive: interactive,
+		Prompter:    prompter(cmd),
+		VerifyCopy:  verify,
+		Subpack:     subpack,
+		Position:    position,
+		Packs:       addon.PackFilter{Only: onlyType, Include: include, Exclude: exclude},
+
+		DenyCapabilities: denyCapabilities,
+		AllowScripts:     allowScripts,
+		Strict:           strict,
+
+		EnableExperiments: enableExperiments,
+		RequireOnClients:  requireOnClients,
+
+		ExtractLimits: limits,
+		Direct:        direct,
+		Progress:      newProgress(jsonOutput),
+		Dedupe:        dedupe,
+		Link:          link,
+		Ownership:     ownership,
+		PathPolicy:    policy,
+
+		TrustedKeys:      keys,
+		RequireSignature: requireSignature,
+		ContentKeys:      contentKeys,
+
+		Hooks: runner,
 	}
 
-	// Perform installation
 	result, err := installer.InstallAddon(addonFile, options)
+	if err != nil || !result.AlreadyInstalled {
+		notifyWebhooks(cmd, installEvent(server, addonFile, result, err))
+	}
+	return result, err
+}
+
+// addPlanOutputFlag adds --output to commands whose dry run can print its plan as JSON
+func addPlanOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output", "", "Output format: text, or json to print the --dry-run plan as JSON (default text)")
+}
+
+// planOutputMode reports whether --output json asks for the dry-run plan
+func planOutputMode(cmd *cobra.Command) (bool, error) {
+	output, _ := cmd.Flags().GetString("output")
+	switch strings.ToLower(output) {
+	case "", "text":
+		return false, nil
+	case "json":
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); !dryRun {
+			return false, fmt.Errorf("--output json prints the dry-run plan; use it with --dry-run")
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid output format %q: use text or json", output)
+}
+
+// installPlan returns the dry-run plan of an install, or nil when it failed first
+func installPlan(result *addon.InstallResult) *addon.DryRunPlan {
+	if result == nil {
+		return nil
+	}
+	return result.Plan
+}
+
+// printDryRunPlan prints a dry-run plan as JSON, if there is one, and returns err
+func printDryRunPlan(plan *addon.DryRunPlan, err error) error {
+	if plan == nil {
+		return err
+	}
+	data, marshalErr := json.MarshalIndent(plan, "", "  ")
+	if marshalErr != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", marshalErr)
+	}
+	fmt.Println(string(data))
+	return err
+}
+
+// printInstallResult prints the outcome of an install and returns its error
+func printInstallResult(cmd *cobra.Command, result *addon.InstallResult, err error) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-	// Display results
+	if result == nil {
+		return err
+	}
 	if len(result.Warnings) > 0 {
 		fmt.Println("Warnings:")
 		for _, warning := range result.Warnings {
@@ -79,19 +404,155 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if result.Success {
+	printPackResults(result.PackResults)
+
+	if !result.Success {
+		return err
+	}
+	if result.Signature != nil {
+		fmt.Printf("Signature verified: signed by %s\n", result.Signature.Signer())
+	}
+	for _, change := range result.PropertyChanges {
 		if dryRun {
-			fmt.Println("DRY RUN: Installation would succeed")
+			fmt.Printf("DRY RUN: Would set %s in server.properties\n", change)
 		} else {
-			fmt.Printf("Successfully installed addon with %d pack(s)\n", len(result.InstalledPacks))
-			if verbose {
-				for _, pack := range result.InstalledPacks {
-					fmt.Printf("  - %s\n", pack)
-				}
-			}
+			fmt.Printf("Set %s in server.properties\n", change)
 		}
-		return nil
 	}
+	for _, key := range result.Experiments {
+		if dryRun {
+			fmt.Printf("DRY RUN: Would turn on the %s experiment in level.dat (after confirmation)\n", minecraft.ExperimentTitle(key))
+		} else {
+			fmt.Printf("Turned on the %s experiment in level.dat\n", minecraft.ExperimentTitle(key))
+		}
+	}
+	if result.AlreadyInstalled {
+		fmt.Println("Already installed: every pack is on the server at the same version (use --force to reinstall)")
+	} else if dryRun {
+		fmt.Println("DRY RUN: Installation would succeed")
+	} else {
+		fmt.Printf("Successfully installed addon with %d pack(s)\n", len(result.InstalledPacks))
+		if result.Linked {
+			fmt.Println("The packs are linked to their source directories; reload the world to pick up changes")
+		}
+	}
+	return nil
+}
 
-	return err
+// printPackResults prints what an install did with each of the addon's packs
+func printPackResults(packs []addon.PackResult) {
+	if len(packs) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PACK\tTYPE\tVERSION\tSTATUS\tDIRECTORY")
+	fmt.Fprintln(w, "----\t----\t-------\t------\t---------")
+	for _, pack := range packs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", pack.Name, pack.Type, formatVersion(pack.Version), pack.Status, pack.Directory)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+}
+
+// scriptsAllowedByEnvironment reports whether BLOCKBENCH_ALLOW_SCRIPTS permits script content
+func scriptsAllowedByEnvironment() bool {
+	return enabledByEnvironment("BLOCKBENCH_ALLOW_SCRIPTS", "scripts stay disallowed")
+}
+
+// signatureRequiredByEnvironment reports whether BLOCKBENCH_REQUIRE_SIGNATURE
+// makes installs require a trusted signature
+func signatureRequiredByEnvironment() bool {
+	return enabledByEnvironment("BLOCKBENCH_REQUIRE_SIGNATURE", "signatures stay optional")
+}
+
+// enabledByEnvironment reports whether the environment variable is set to a
+// true value, warning that it is ignored (with what that means) when it is not
+// a boolean
+func enabledByEnvironment(name, ignored string) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid environment value, "+ignored, "variable", name, "value", value)
+		return false
+	}
+	return enabled
+}
+
+// trustedKeys returns the public keys trusted in the config file; without a
+// home directory there is no config file and so no trusted keys
+func trustedKeys() ([]signature.PublicKey, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return nil, nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.PublicKeys()
+}
+
+func addExtractLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().String("max-file-size", "", "Largest decompressed file allowed, e.g. 500MB (default 100MB or BLOCKBENCH_MAX_FILE_SIZE)")
+	cmd.Flags().String("max-total-size", "", "Largest total decompressed archive size, e.g. 4GB (default 2GB or BLOCKBENCH_MAX_TOTAL_SIZE)")
+	cmd.Flags().Int("max-files", 0, "Most files an archive may contain (default 50000 or BLOCKBENCH_MAX_FILES)")
+	cmd.Flags().Int("extract-workers", 0, "Extract archive files with this many parallel workers (default 1 or BLOCKBENCH_EXTRACT_WORKERS)")
+}
+
+// extractLimitsFromFlags reads the decompression limit flags; unset flags leave zero fields for the defaults
+func extractLimitsFromFlags(cmd *cobra.Command) (filesystem.ExtractLimits, error) {
+	var limits filesystem.ExtractLimits
+
+	for _, flag := range []struct {
+		name   string
+		target *int64
+	}{
+		{"max-file-size", &limits.MaxFileSize},
+		{"max-total-size", &limits.MaxTotalSize},
+	} {
+		value, _ := cmd.Flags().GetString(flag.name)
+		if value == "" {
+			continue
+		}
+		size, err := filesystem.ParseSize(value)
+		if err != nil || size <= 0 {
+			return limits, fmt.Errorf("invalid --%s value %q: expected a positive size such as 500MB", flag.name, value)
+		}
+		*flag.target = size
+	}
+
+	maxFiles, _ := cmd.Flags().GetInt("max-files")
+	if maxFiles < 0 {
+		return limits, fmt.Errorf("invalid --max-files value %d: must be positive", maxFiles)
+	}
+	limits.MaxFiles = maxFiles
+
+	workers, _ := cmd.Flags().GetInt("extract-workers")
+	if workers < 0 {
+		return limits, fmt.Errorf("invalid --extract-workers value %d: must be positive", workers)
+	}
+	limits.Workers = workers
+
+	return limits, nil
+}
+
+// addPathPolicyFlag registers the restricted-filesystem flag
+func addPathPolicyFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("allowed-path", nil, "Only write inside these directories, checked before any change (repeatable, or BLOCKBENCH_ALLOWED_PATHS)")
+}
+
+// pathPolicyFromFlags builds the write policy from --allowed-path or
+// BLOCKBENCH_ALLOWED_PATHS (a list separated like PATH); nil when neither is set
+func pathPolicyFromFlags(cmd *cobra.Command) (*filesystem.PathPolicy, error) {
+	allowed, _ := cmd.Flags().GetStringSlice("allowed-path")
+	if len(allowed) == 0 {
+		if value := os.Getenv("BLOCKBENCH_ALLOWED_PATHS"); value != "" {
+			allowed = filepath.SplitList(value)
+		}
+	}
+	return filesystem.NewPathPolicy(allowed)
 }
@@ -1,16 +1,21 @@
 package cli
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/makutaku/blockbench/internal/addon"
 	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
 	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/makutaku/blockbench/pkg/yaml"
 	"github.com/spf13/cobra"
 )
 
@@ -19,86 +24,363 @@ func NewListCommand() *cobra.Command {
 		Use:   "list [server-path]",
 		Short: "List installed Minecraft Bedrock addons",
 		Long: `List all addons currently installed on a Minecraft Bedrock server.
-Shows addon names, UUIDs, versions, and types (behavior/resource packs).`,
-		Args: cobra.ExactArgs(1),
-		RunE: runList,
+Shows addon names, UUIDs, versions, and types (behavior/resource packs).
+
+With --history, shows the packs the server recorded in its world pack history
+files instead, flagging packs that blockbench has no install record for
+(out-of-band installs).
+
+--output csv writes one row per pack, for spreadsheets; --output yaml writes
+the same data as --json. Both work with the dependency views too.
+
+--filter, --type, and --module narrow the listing, and --sort orders it, in
+the table, the dependency views, and JSON alike:
+
+  blockbench list /server --module @minecraft/server --sort size
+
+Pack names and descriptions given as lang keys, such as pack.name, are
+resolved from the pack's texts/<locale>.lang file, in en_US unless --lang
+chooses another locale; packs without that file fall back to en_US.
+
+With --servers, server-path is omitted and the command runs on each named
+server profile (or all of them) in turn; see 'blockbench server'.`,
+		Args:              serverArgs(1),
+		RunE:              runList,
+		ValidArgsFunction: completeArgs(completeServerPath),
 	}
 
-	cmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.Flags().Bool("json", false, "Output in JSON format (short for --output json)")
+	cmd.Flags().String("output", "", "Output format: table, json, csv, or yaml (default table)")
 	cmd.Flags().Bool("grouped", false, "Group packs by dependency relationships")
 	cmd.Flags().Bool("tree", false, "Show dependency tree visualization")
 	cmd.Flags().Bool("standalone", false, "Show only standalone packs (no dependencies)")
 	cmd.Flags().Bool("roots", false, "Show only root packs (packs that others depend on)")
+	cmd.Flags().Bool("history", false, "Show the server's pack history cross-referenced with blockbench install records")
+	cmd.Flags().String("backup-dir", "", "Backup directory holding blockbench install records (default: server-path/backups)")
+	cmd.Flags().String("filter", "", "Show only packs whose name or UUID contains this, ignoring case")
+	cmd.Flags().String("type", "", "Show only packs of this type: behavior or resource")
+	cmd.Flags().String("module", "", "Show only packs that depend on this script module, e.g. @minecraft/server")
+	cmd.Flags().String("sort", "", "Sort by name, version (newest first), or size (largest first) instead of world config order")
+	cmd.Flags().String("lang", "", "Locale to resolve pack names and descriptions in, such as de_DE (default en_US)")
+	addServersFlag(cmd)
 
 	return cmd
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	serverPath := args[0]
+	options, err := listOptions(cmd)
+	if err != nil {
+		return err
+	}
+	format, err := listFormat(cmd)
+	if err != nil {
+		return err
+	}
+	locale, err := packLocale(cmd)
+	if err != nil {
+		return err
+	}
+	servers, err := selectedServers(cmd)
+	if err != nil {
+		return err
+	}
+	if servers == nil {
+		return listServer(cmd, args[0])
+	}
 
-	verbose, _ := cmd.Flags().GetBool("verbose")
+	if format == outputCSV || format == outputYAML {
+		return fmt.Errorf("--output %s lists a single server; use --json with --servers", format)
+	}
+	if format == outputTable {
+		return runOnServers(cmd, servers, func(server string) (any, error) {
+			return nil, listServer(cmd, server)
+		})
+	}
+	for _, flag := range []string{"grouped", "tree", "standalone", "roots", "history"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--json with --servers lists installed packs only and can't be combined with --%s", flag)
+		}
+	}
+	return runOnServers(cmd, servers, func(name string) (any, error) {
+		target, err := resolveServerTarget(cmd, name)
+		if err != nil {
+			return nil, err
+		}
+		server, err := target.newServer()
+		if err != nil {
+			return nil, err
+		}
+		server.Locale = locale
+		packs, err := server.ListInstalledPacks()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installed packs: %w", err)
+		}
+		return addon.SelectInstalledPacks(server, packs, options), nil
+	})
+}
+
+// Output formats of list
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputYAML  = "yaml"
+)
+
+// listFormat reads --output, with --json as a shorthand for --output json
+func listFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
 	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	format = strings.ToLower(format)
+	switch format {
+	case "", outputTable, outputJSON, outputCSV, outputYAML:
+	default:
+		return "", fmt.Errorf("invalid output format %q: use table, json, csv, or yaml", format)
+	}
+	if jsonOutput {
+		if format != "" && format != outputJSON {
+			return "", fmt.Errorf("--json and --output %s cannot be used together", format)
+		}
+		return outputJSON, nil
+	}
+	if format == "" {
+		return outputTable, nil
+	}
+	return format, nil
+}
+
+// printStructured prints v as indented JSON or as YAML
+func printStructured(format string, v any) error {
+	if format == outputYAML {
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printCSV writes a header and rows as CSV
+func printCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// packLocale reads --lang, returning "" when it isn't given
+func packLocale(cmd *cobra.Command) (string, error) {
+	value, _ := cmd.Flags().GetString("lang")
+	if value == "" {
+		return "", nil
+	}
+	return minecraft.ParseLocale(value)
+}
+
+// listOptions reads the --filter, --type, --module, and --sort flags
+func listOptions(cmd *cobra.Command) (addon.PackListOptions, error) {
+	filter, _ := cmd.Flags().GetString("filter")
+	typeValue, _ := cmd.Flags().GetString("type")
+	module, _ := cmd.Flags().GetString("module")
+	sortValue, _ := cmd.Flags().GetString("sort")
+
+	packType, err := addon.ParsePackFilterType(typeValue)
+	if err != nil {
+		return addon.PackListOptions{}, err
+	}
+	sortBy, err := addon.ParsePackSort(sortValue)
+	if err != nil {
+		return addon.PackListOptions{}, err
+	}
+	options := addon.PackListOptions{Filter: filter, Type: packType, Module: module, Sort: sortBy}
+	if history, _ := cmd.Flags().GetBool("history"); history && !options.IsEmpty() {
+		return options, fmt.Errorf("--history lists the server's pack history and can't be combined with --filter, --type, --module, or --sort")
+	}
+	return options, nil
+}
+
+// listServer lists the addons of the server named by serverArg, a path or profile
+func listServer(cmd *cobra.Command, serverArg string) error {
+	target, err := resolveServerTarget(cmd, serverArg)
+	if err != nil {
+		return err
+	}
+
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	format, err := listFormat(cmd)
+	if err != nil {
+		return err
+	}
 	grouped, _ := cmd.Flags().GetBool("grouped")
 	tree, _ := cmd.Flags().GetBool("tree")
 	standaloneOnly, _ := cmd.Flags().GetBool("standalone")
 	rootsOnly, _ := cmd.Flags().GetBool("roots")
+	history, _ := cmd.Flags().GetBool("history")
 
 	if verbose {
-		fmt.Printf("Listing addons for server at %s\n", serverPath)
+		fmt.Printf("Listing addons for server at %s\n", target.Path)
 	}
 
 	// Create server instance
-	server, err := minecraft.NewServer(serverPath)
+	server, err := target.newServer()
 	if err != nil {
-		return fmt.Errorf("failed to initialize server: %w", err)
+		return err
+	}
+	if server.Locale, err = packLocale(cmd); err != nil {
+		return err
+	}
+
+	if history {
+		return runHistoryList(server, target.backupDir(cmd), format, verbose)
+	}
+
+	options, err := listOptions(cmd)
+	if err != nil {
+		return err
 	}
 
 	// Check if dependency analysis is needed
 	if grouped || tree || standaloneOnly || rootsOnly {
-		return runListWithDependencies(server, jsonOutput, verbose, grouped, tree, standaloneOnly, rootsOnly)
+		return runListWithDependencies(server, options, format, verbose, grouped, tree, standaloneOnly, rootsOnly)
 	}
 
 	// Default behavior - simple flat list
-	return runSimpleList(server, jsonOutput, verbose)
+	return runSimpleList(server, options, format, verbose)
 }
 
-func runSimpleList(server *minecraft.Server, jsonOutput, verbose bool) error {
+func runSimpleList(server *minecraft.Server, options addon.PackListOptions, format string, verbose bool) error {
 	// Get installed packs
-	installedPacks, err := server.ListInstalledPacks()
+	allPacks, err := server.ListInstalledPacks()
 	if err != nil {
 		return fmt.Errorf("failed to list installed packs: %w", err)
 	}
+	installedPacks := addon.SelectInstalledPacks(server, allPacks, options)
+
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(format, installedPacks)
+	case outputCSV:
+		rows := make([][]string, 0, len(installedPacks))
+		for _, pack := range installedPacks {
+			rows = append(rows, []string{pack.Name, string(pack.Type), pack.PackID, formatVersion(pack.Version),
+				strconv.FormatInt(pack.Size, 10), string(pack.Status), pack.Description})
+		}
+		return printCSV([]string{"name", "type", "uuid", "version", "size", "status", "description"}, rows)
+	}
 
 	if len(installedPacks) == 0 {
-		if !jsonOutput {
-			fmt.Println("No addons installed")
+		if len(allPacks) > 0 {
+			fmt.Printf("No packs match the filters (%d pack(s) installed)\n", len(allPacks))
 		} else {
-			fmt.Println("[]")
+			fmt.Println("No addons installed")
 		}
 		return nil
 	}
 
-	if jsonOutput {
-		// Output as JSON
-		data, err := json.MarshalIndent(installedPacks, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+	// Output as table
+	renderSimpleTable(installedPacks)
+	renderDiskUsage(installedPacks)
+	renderPackProblems(installedPacks)
+	renderSubpacks(installedPacks)
+	renderCapabilities(installedPacks)
+
+	if verbose {
+		if len(installedPacks) < len(allPacks) {
+			fmt.Printf("\nShowing %d of %d pack(s) installed\n", len(installedPacks), len(allPacks))
+		} else {
+			fmt.Printf("\nTotal: %d pack(s) installed\n", len(installedPacks))
 		}
-		fmt.Println(string(data))
+	}
+
+	return nil
+}
+
+func runHistoryList(server *minecraft.Server, backupDir, format string, verbose bool) error {
+	records, err := addon.AnalyzePackHistory(server, backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to analyze pack history: %w", err)
+	}
+
+	switch format {
+	case outputJSON, outputYAML:
+		if records == nil {
+			records = []addon.PackHistoryRecord{}
+		}
+		return printStructured(format, records)
+	case outputCSV:
+		rows := make([][]string, 0, len(records))
+		for _, record := range records {
+			installed := ""
+			if record.FirstInstalled != nil {
+				installed = record.FirstInstalled.Format(time.RFC3339)
+			}
+			rows = append(rows, []string{record.Name, string(record.Type), record.UUID, formatVersion(record.Version),
+				strconv.FormatBool(record.Active), installed})
+		}
+		return printCSV([]string{"name", "type", "uuid", "version", "active", "first_installed"}, rows)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No pack history recorded by the server")
 		return nil
 	}
 
-	// Output as table
-	renderSimpleTable(installedPacks)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tUUID\tVERSION\tACTIVE\tINSTALLED BY BLOCKBENCH")
+	fmt.Fprintln(w, "----\t----\t----\t-------\t------\t----------------------")
+
+	outOfBand := 0
+	for _, record := range records {
+		active := "no"
+		if record.Active {
+			active = "yes"
+		}
+		installed := "no (out-of-band)"
+		if record.FirstInstalled != nil {
+			installed = record.FirstInstalled.Format("2006-01-02 15:04:05")
+		} else {
+			outOfBand++
+		}
+		version := fmt.Sprintf("%d.%d.%d", record.Version[0], record.Version[1], record.Version[2])
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Name, record.Type, record.UUID, version, active, installed)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
 
 	if verbose {
-		fmt.Printf("\nTotal: %d pack(s) installed\n", len(installedPacks))
+		for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+			for _, record := range records {
+				if record.Type == packType {
+					fmt.Printf("\n%s pack history last written by the server: %s", packType, record.HistoryModified.Format("2006-01-02 15:04:05"))
+					break
+				}
+			}
+		}
+		fmt.Println()
+	}
+	if outOfBand > 0 {
+		fmt.Printf("\n%d pack(s) were recorded by the server without a blockbench install record\n", outOfBand)
 	}
 
 	return nil
 }
 
-func runListWithDependencies(server *minecraft.Server, jsonOutput, verbose, grouped, tree, standaloneOnly, rootsOnly bool) error {
+func runListWithDependencies(server *minecraft.Server, options addon.PackListOptions, format string, verbose, grouped, tree, standaloneOnly, rootsOnly bool) error {
 	// Create dependency analyzer
 	analyzer := addon.NewDependencyAnalyzer(server)
 
@@ -108,14 +390,30 @@ func runListWithDependencies(server *minecraft.Server, jsonOutput, verbose, grou
 		return fmt.Errorf("failed to analyze dependencies: %w", err)
 	}
 
-	// Handle JSON output for dependency data
-	if jsonOutput {
-		return outputDependencyJSON(dependencyGroup, standaloneOnly, rootsOnly)
+	// Narrow every view to the packs the filters keep, in the requested order,
+	// with their sizes
+	packs, err := server.ListInstalledPacks()
+	if err != nil {
+		return fmt.Errorf("failed to list installed packs: %w", err)
+	}
+	selected := addon.SelectInstalledPacks(server, packs, options)
+	if len(selected) == 0 && len(packs) > 0 && format == outputTable {
+		fmt.Printf("No packs match the filters (%d pack(s) installed)\n", len(packs))
+		return nil
+	}
+	dependencyGroup = addon.SelectDependencyGroup(dependencyGroup, selected)
+
+	// Handle structured output for dependency data
+	switch format {
+	case outputJSON, outputYAML:
+		return outputDependencies(format, dependencyGroup, standaloneOnly, rootsOnly)
+	case outputCSV:
+		return outputDependencyCSV(dependencyGroup, standaloneOnly, rootsOnly)
 	}
 
 	// Handle different display modes
 	if tree {
-		return renderTreeView(analyzer, dependencyGroup)
+		return renderTreeView(analyzer, dependencyGroup, options.Sort == addon.PackSortConfig)
 	}
 
 	if grouped {
@@ -136,8 +434,8 @@ func runListWithDependencies(server *minecraft.Server, jsonOutput, verbose, grou
 
 func renderSimpleTable(packs []minecraft.InstalledPack) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tTYPE\tUUID\tVERSION\tDESCRIPTION")
-	fmt.Fprintln(w, "----\t----\t----\t-------\t-----------")
+	fmt.Fprintln(w, "NAME\tTYPE\tUUID\tVERSION\tSIZE\tSTATUS\tDESCRIPTION")
+	fmt.Fprintln(w, "----\t----\t----\t-------\t----\t------\t-----------")
 
 	for _, pack := range packs {
 		name := pack.Name
@@ -152,8 +450,8 @@ func renderSimpleTable(packs []minecraft.InstalledPack) {
 
 		version := fmt.Sprintf("%d.%d.%d", pack.Version[0], pack.Version[1], pack.Version[2])
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			name, pack.Type, pack.PackID, version, description)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			name, pack.Type, pack.PackID, version, filesystem.FormatSize(pack.Size), pack.Status, description)
 	}
 
 	if err := w.Flush(); err != nil {
@@ -161,6 +459,85 @@ func renderSimpleTable(packs []minecraft.InstalledPack) {
 	}
 }
 
+// renderDiskUsage prints the total size of the packs on disk, by pack type
+func renderDiskUsage(packs []minecraft.InstalledPack) {
+	var total int64
+	byType := make(map[minecraft.PackType]int64)
+	for _, pack := range packs {
+		total += pack.Size
+		byType[pack.Type] += pack.Size
+	}
+	fmt.Printf("\nDisk usage: %s (behavior packs %s, resource packs %s)\n",
+		filesystem.FormatSize(total), filesystem.FormatSize(byType[minecraft.PackTypeBehavior]), filesystem.FormatSize(byType[minecraft.PackTypeResource]))
+}
+
+// renderPackProblems explains every pack whose directory or manifest could not be read
+func renderPackProblems(packs []minecraft.InstalledPack) {
+	printedHeader := false
+	for _, pack := range packs {
+		if pack.Status == minecraft.PackStatusOK {
+			continue
+		}
+		if !printedHeader {
+			fmt.Println("\nProblems:")
+			printedHeader = true
+		}
+
+		name := pack.Name
+		if name == "" {
+			name = fmt.Sprintf("Pack-%s", pack.PackID[:validation.UUIDShortDisplayLength])
+		}
+		fmt.Printf("  %s (%s): %s\n", name, pack.Status, pack.StatusDetail)
+	}
+}
+
+// renderSubpacks lists declared subpacks for packs that have them, marking the active selection
+func renderSubpacks(packs []minecraft.InstalledPack) {
+	printedHeader := false
+	for _, pack := range packs {
+		if len(pack.Subpacks) == 0 {
+			continue
+		}
+		if !printedHeader {
+			fmt.Println("\nSubpacks:")
+			printedHeader = true
+		}
+
+		name := pack.Name
+		if name == "" {
+			name = fmt.Sprintf("Pack-%s", pack.PackID[:validation.UUIDShortDisplayLength])
+		}
+		fmt.Printf("  %s:\n", name)
+		for _, subpack := range pack.Subpacks {
+			marker := " "
+			if subpack.FolderName == pack.Subpack {
+				marker = "*"
+			}
+			fmt.Printf("    %s %s (%s)\n", marker, subpack.Name, subpack.FolderName)
+		}
+	}
+}
+
+// renderCapabilities lists the manifest capabilities requested by installed packs
+func renderCapabilities(packs []minecraft.InstalledPack) {
+	printedHeader := false
+	for _, pack := range packs {
+		if len(pack.Capabilities) == 0 {
+			continue
+		}
+		if !printedHeader {
+			fmt.Println("\nCapabilities:")
+			printedHeader = true
+		}
+
+		name := pack.Name
+		if name == "" {
+			name = fmt.Sprintf("Pack-%s", pack.PackID[:validation.UUIDShortDisplayLength])
+		}
+		fmt.Printf("  %s: %s\n", name, strings.Join(pack.Capabilities, ", "))
+	}
+}
+
 func renderGroupedView(group *addon.DependencyGroup, standaloneOnly, rootsOnly bool, verbose bool) error {
 	totalPacks := len(group.RootPacks) + len(group.DependentPacks) + len(group.StandalonePacks)
 
@@ -311,7 +688,7 @@ func renderSimpleRelationshipTable(relationships []addon.PackRelationship) {
 	}
 }
 
-func outputDependencyJSON(group *addon.DependencyGroup, standaloneOnly, rootsOnly bool) error {
+func outputDependencies(format string, group *addon.DependencyGroup, standaloneOnly, rootsOnly bool) error {
 	type JSONOutput struct {
 		RootPacks       []addon.PackRelationship `json:"root_packs,omitempty"`
 		DependentPacks  []addon.PackRelationship `json:"dependent_packs,omitempty"`
@@ -330,15 +707,35 @@ func outputDependencyJSON(group *addon.DependencyGroup, standaloneOnly, rootsOnl
 		output.StandalonePacks = group.StandalonePacks
 	}
 
-	data, err := json.MarshalIndent(output, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+	return printStructured(format, output)
+}
+
+// outputDependencyCSV writes one row per pack with its dependency category
+// and the UUIDs of its dependencies and dependents
+func outputDependencyCSV(group *addon.DependencyGroup, standaloneOnly, rootsOnly bool) error {
+	var rows [][]string
+	add := func(category string, relationships []addon.PackRelationship) {
+		for _, rel := range relationships {
+			rows = append(rows, []string{category, rel.Pack.Name, string(rel.Pack.Type), rel.Pack.PackID,
+				formatVersion(rel.Pack.Version), strconv.FormatInt(rel.Pack.Size, 10),
+				strings.Join(rel.Dependencies, ";"), strings.Join(rel.Dependents, ";"), strings.Join(rel.Modules, ";")})
+		}
 	}
-	fmt.Println(string(data))
-	return nil
+	if !standaloneOnly {
+		add("root", group.RootPacks)
+	}
+	if !standaloneOnly && !rootsOnly {
+		add("dependent", group.DependentPacks)
+	}
+	if !rootsOnly {
+		add("standalone", group.StandalonePacks)
+	}
+	return printCSV([]string{"category", "name", "type", "uuid", "version", "size", "dependencies", "dependents", "modules"}, rows)
 }
 
-func renderTreeView(analyzer *addon.DependencyAnalyzer, group *addon.DependencyGroup) error {
+// renderTreeView prints each root pack with its dependents; sortRoots sorts
+// the roots by name rather than keeping the group's order
+func renderTreeView(analyzer *addon.DependencyAnalyzer, group *addon.DependencyGroup, sortRoots bool) error {
 	fmt.Println("📦 ADDON DEPENDENCY TREE")
 	fmt.Println()
 
@@ -347,9 +744,11 @@ func renderTreeView(analyzer *addon.DependencyAnalyzer, group *addon.DependencyG
 	// Sort root packs for consistent output
 	var roots []addon.PackRelationship
 	roots = append(roots, group.RootPacks...)
-	sort.Slice(roots, func(i, j int) bool {
-		return roots[i].Pack.Name < roots[j].Pack.Name
-	})
+	if sortRoots {
+		sort.Slice(roots, func(i, j int) bool {
+			return roots[i].Pack.Name < roots[j].Pack.Name
+		})
+	}
 
 	for i, root := range roots {
 		isLast := i == len(roots)-1
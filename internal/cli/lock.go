@@ -0,0 +1,423 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/userdirs"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewLockCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Edit the lockfile listing the addons a server should have",
+		Long: `Edit blockbench.lock, which lists the addons a server should have with the
+UUIDs and versions of their packs and the SHA-256 of each addon file. Apply it
+to a server with 'blockbench sync'.
+
+Addon sources are paths (stored relative to the lockfile) or http(s) URLs,
+which are downloaded into blockbench's cache directory.`,
+	}
+	cmd.PersistentFlags().String("lockfile", lockfile.FileName, "Lockfile to edit")
+
+	addCmd := &cobra.Command{
+		Use:   "add [addon-file-or-url]",
+		Short: "Add an addon to the lockfile, replacing entries that provide the same packs",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runLockAdd,
+	}
+	addExtractLimitFlags(addCmd)
+	cmd.AddCommand(addCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [source-or-pack-uuid]",
+		Short: "Remove an addon from the lockfile by its source or the UUID of one of its packs",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runLockRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
+
+func NewSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [server-path]",
+		Short: "Make a server's addons match the lockfile",
+		Long: `Make a server's installed packs match blockbench.lock: addons with missing or
+outdated packs are installed, and packs no addon in the lockfile provides are
+removed. Every addon file is checked against the SHA-256 in the lockfile first.
+Use 'blockbench apply' to review the plan before applying it.
+
+The changes run as one batch with a single backup; if any step fails, the
+server is restored from it. Use --dry-run to only print the plan.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runSync,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	addReconcileFlags(cmd)
+
+	return cmd
+}
+
+func NewApplyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply [server-path]",
+		Short: "Show and apply the changes that make a server match the lockfile",
+		Long: `Compare a server with blockbench.lock and print a plan of the packs to install,
+change, and remove, like 'blockbench sync', then ask before applying it.
+
+The plan detects drift: besides missing, outdated, and unlisted packs, packs at
+the locked version whose files are missing or unreadable are reinstalled. The
+server's dependency graph orders removals so dependents go before the packs
+they depend on, and the plan warns about kept packs that depend on removed
+ones. The changes run as one batch with a single backup, restored if any step
+fails.
+
+Use --plan to only print the plan (exit status 0 whether or not there are
+changes), and --yes to apply without asking; --json implies --yes.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runApply,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().Bool("plan", false, "Only print the plan")
+	addReconcileFlags(cmd)
+
+	return cmd
+}
+
+// addReconcileFlags adds the flags shared by sync and apply
+func addReconcileFlags(cmd *cobra.Command) {
+	cmd.Flags().String("lockfile", lockfile.FileName, "Lockfile to make the server match")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	cmd.Flags().Bool("allow-scripts", false, "Allow packs with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	cmd.Flags().Bool("json", false, "Output the plan and result in JSON format")
+	addServerControlFlags(cmd)
+}
+
+func runLockAdd(cmd *cobra.Command, args []string) error {
+	source := args[0]
+	lockPath, _ := cmd.Flags().GetString("lockfile")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	lock, err := lockfile.Load(lockPath)
+	if err != nil {
+		return err
+	}
+
+	var path, digest string
+	if lockfile.IsURL(source) {
+		cacheDir, err := downloadCacheDir()
+		if err != nil {
+			return err
+		}
+		if path, digest, err = lockfile.Download(source, cacheDir); err != nil {
+			return err
+		}
+	} else {
+		path = source
+		if info, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cannot read addon %s: %w", path, err)
+		} else if info.IsDir() {
+			return fmt.Errorf("%s is a directory; the lockfile pins addon files by hash, so package it first", path)
+		}
+		if digest, err = filesystem.HashFile(path); err != nil {
+			return err
+		}
+		if source, err = lockSourcePath(lockPath, path); err != nil {
+			return err
+		}
+	}
+
+	report, err := addon.ValidateAddon(path, false, limits)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	entry := lockfile.Addon{Source: source, SHA256: digest}
+	for _, pack := range report.Packs {
+		entry.Packs = append(entry.Packs, lockfile.Pack{UUID: pack.UUID, Name: pack.Name, Version: pack.Version, Type: pack.Type})
+	}
+	lock.Add(entry)
+
+	for _, pack := range entry.Packs {
+		fmt.Printf("%s pack: %s %s (%s)\n", pack.Type, pack.Name, formatVersion(pack.Version), pack.UUID)
+	}
+	if dryRun {
+		fmt.Printf("Would add %s to %s\n", source, lockPath)
+		return nil
+	}
+	if err := lock.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s to %s\n", source, lockPath)
+	return nil
+}
+
+func runLockRemove(cmd *cobra.Command, args []string) error {
+	lockPath, _ := cmd.Flags().GetString("lockfile")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	lock, err := lockfile.Load(lockPath)
+	if err != nil {
+		return err
+	}
+
+	kept := lock.Addons[:0]
+	var removed []string
+	for _, entry := range lock.Addons {
+		match := entry.Source == args[0]
+		for _, pack := range entry.Packs {
+			match = match || strings.EqualFold(pack.UUID, args[0])
+		}
+		if match {
+			removed = append(removed, entry.Source)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if len(removed) == 0 {
+		return fmt.Errorf("no addon in %s has source or pack UUID %s", lockPath, args[0])
+	}
+	lock.Addons = kept
+
+	if dryRun {
+		fmt.Printf("Would remove %s from %s\n", strings.Join(removed, ", "), lockPath)
+		return nil
+	}
+	if err := lock.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s from %s\n", strings.Join(removed, ", "), lockPath)
+	return nil
+}
+
+// syncResult is the JSON output of sync and apply
+type syncResult struct {
+	Changes  []lockfile.Change  `json:"changes"`
+	Warnings []string           `json:"warnings"`
+	Batch    *addon.BatchResult `json:"batch,omitempty"`
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	return reconcileServer(cmd, args[0], false, false)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	planOnly, _ := cmd.Flags().GetBool("plan")
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	return reconcileServer(cmd, args[0], planOnly, !yes && !jsonOutput)
+}
+
+// reconcileServer makes a server match the lockfile: it prints the plan,
+// stops there for a dry run or planOnly, asks first when prompt is set, and
+// applies the changes as one batch with a single backup
+func reconcileServer(cmd *cobra.Command, serverArg string, planOnly, prompt bool) error {
+	lockPath, _ := cmd.Flags().GetString("lockfile")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+
+	// An empty lockfile removes every pack, so a mistyped path must not count as one
+	if _, err := os.Stat(lockPath); err != nil {
+		return fmt.Errorf("cannot read lockfile: %w", err)
+	}
+	lock, err := lockfile.Load(lockPath)
+	if err != nil {
+		return err
+	}
+
+	target, err := resolveServerTarget(cmd, serverArg)
+	if err != nil {
+		return err
+	}
+	backupDir := target.backupDir(cmd)
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	plan, err := addon.PlanApply(server, lock)
+	if err != nil {
+		return err
+	}
+	result := syncResult{Changes: plan.Changes, Warnings: plan.Warnings}
+
+	if !jsonOutput {
+		renderApplyPlan(plan)
+	}
+	if plan.Empty() || dryRun || planOnly {
+		return printSyncJSON(jsonOutput, result)
+	}
+
+	// Verify every addon before changing anything
+	cacheDir, err := downloadCacheDir()
+	if err != nil {
+		return err
+	}
+	batch := addon.NewBatch(server, backupDir)
+	for _, uuid := range plan.Remove {
+		batch.Uninstall(uuid, addon.UninstallOptions{ByUUID: true, BackupDir: backupDir})
+	}
+	for _, entry := range plan.Install {
+		path, err := lock.Fetch(entry, cacheDir)
+		if err != nil {
+			return err
+		}
+		batch.Install(path, addon.InstallOptions{
+			BackupDir:    backupDir,
+			ForceUpdate:  true, // Replaces the outdated packs of the addon
+			AllowScripts: allowScripts,
+		})
+	}
+
+	if prompt {
+		confirmed, err := confirm(cmd, "Apply these changes?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Apply cancelled")
+			return nil
+		}
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	batchResult, err := batch.Execute(addon.BatchOptions{
+		Verbose:     verbose,
+		Description: fmt.Sprintf("Before applying %s", lockPath),
+	})
+	result.Batch = batchResult
+
+	if jsonOutput {
+		if jsonErr := printSyncJSON(true, result); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+	if err != nil {
+		for _, errMsg := range batchResult.Errors {
+			fmt.Printf("  - %s\n", errMsg)
+		}
+		if batchResult.RolledBack {
+			fmt.Println("All changes were rolled back")
+		}
+		return err
+	}
+	fmt.Printf("Server matches %s after %d change(s)\n", lockPath, len(plan.Changes))
+	return nil
+}
+
+// renderApplyPlan prints the changes a plan makes, one pack per line, with a
+// summary and any dependency warnings
+func renderApplyPlan(plan *addon.ApplyPlan) {
+	if plan.Empty() {
+		fmt.Println("No changes: the server matches the lockfile")
+		return
+	}
+
+	symbols := map[lockfile.ChangeKind]string{
+		lockfile.ChangeInstall:   "+",
+		lockfile.ChangeUpgrade:   "~",
+		lockfile.ChangeDowngrade: "~",
+		lockfile.ChangeRepair:    "!",
+		lockfile.ChangeRemove:    "-",
+	}
+	counts := make(map[lockfile.ChangeKind]int)
+	for _, change := range plan.Changes {
+		counts[change.Kind]++
+		versions := ""
+		switch {
+		case change.From != nil && change.To != nil && *change.From != *change.To:
+			versions = fmt.Sprintf("%s -> %s", formatVersion(*change.From), formatVersion(*change.To))
+		case change.To != nil:
+			versions = formatVersion(*change.To)
+		case change.From != nil:
+			versions = formatVersion(*change.From)
+		}
+		line := fmt.Sprintf("  %s %-9s %s %s (%s)", symbols[change.Kind], change.Kind, change.Name, versions, change.UUID)
+		if change.Detail != "" {
+			line += ": " + change.Detail
+		}
+		fmt.Println(line)
+	}
+
+	changed := counts[lockfile.ChangeUpgrade] + counts[lockfile.ChangeDowngrade] + counts[lockfile.ChangeRepair]
+	fmt.Printf("\nPlan: %d to install, %d to change, %d to remove.\n",
+		counts[lockfile.ChangeInstall], changed, counts[lockfile.ChangeRemove])
+
+	if len(plan.Warnings) > 0 {
+		fmt.Println("Warnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+}
+
+// printSyncJSON prints the sync result when --json is set
+func printSyncJSON(jsonOutput bool, result syncResult) error {
+	if !jsonOutput {
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// lockSourcePath returns how a local addon is recorded in the lockfile:
+// relative to the lockfile's directory when it is below it, absolute otherwise
+func lockSourcePath(lockPath, addonPath string) (string, error) {
+	absAddon, err := filepath.Abs(addonPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", addonPath, err)
+	}
+	absLockDir, err := filepath.Abs(filepath.Dir(lockPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", lockPath, err)
+	}
+	rel, err := filepath.Rel(absLockDir, absAddon)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return absAddon, nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// downloadCacheDir returns where addons downloaded from URLs are cached
+func downloadCacheDir() (string, error) {
+	dirs, err := userdirs.Resolve()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dirs.Cache, "downloads"), nil
+}
+
+// formatVersion formats a pack version as major.minor.patch
+func formatVersion(version [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
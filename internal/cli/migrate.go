@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/spf13/cobra"
+)
+
+func NewMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate [source-server] [dest-server]",
+		Short: "Copy every installed pack from one server to another",
+		Long: `Copy the installed packs of the source server to the destination server. Each
+pack is exported from the source and installed on the destination, dependencies
+first, with the subpack it has selected on the source.
+
+Packs the destination already has at the same version are skipped. For packs
+it has at another version, --on-conflict decides: fail (the default) stops
+before changing anything, skip keeps the destination's version, and replace
+installs the source's version.
+
+The migrated packs then take the enable order they have in the source's world
+configs; the destination's other packs keep their positions. All changes to
+the destination run as one batch with a single backup, restored if any step
+fails. Use --dry-run to only print the plan.`,
+		Args:              cobra.ExactArgs(2),
+		RunE:              runMigrate,
+		ValidArgsFunction: completeArgs(completeServerPath, completeServerPath),
+	}
+
+	cmd.Flags().String("on-conflict", string(addon.ConflictFail), "What to do with packs the destination has at another version: fail, skip, or replace")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: dest-server/backups)")
+	cmd.Flags().Bool("allow-scripts", false, "Allow packs with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	cmd.Flags().Bool("json", false, "Output the plan and result in JSON format")
+	addServerControlFlags(cmd)
+
+	return cmd
+}
+
+// migrateResult is the JSON output of migrate
+type migrateResult struct {
+	*addon.MigrationPlan
+	Batch *addon.BatchResult `json:"batch,omitempty"`
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	onConflict, _ := cmd.Flags().GetString("on-conflict")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+
+	policy, err := addon.ParseConflictPolicy(onConflict)
+	if err != nil {
+		return err
+	}
+
+	sourceTarget, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	destTarget, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+	if sourceTarget.Path == destTarget.Path && sourceTarget.Options == destTarget.Options {
+		return fmt.Errorf("source and destination are the same server")
+	}
+	source, err := sourceTarget.newServer()
+	if err != nil {
+		return err
+	}
+	dest, err := destTarget.newServer()
+	if err != nil {
+		return err
+	}
+
+	plan, err := addon.PlanMigration(source, dest, policy)
+	if err != nil {
+		return err
+	}
+	result := migrateResult{MigrationPlan: plan}
+
+	if !jsonOutput {
+		renderMigrationPlan(plan)
+	}
+	if conflicts := plan.Count(addon.MigrateConflict); conflicts > 0 {
+		if err := printMigrateJSON(jsonOutput, result); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d pack(s) are installed on the destination at another version; use --on-conflict skip or replace", conflicts)
+	}
+	if plan.Empty() || dryRun {
+		return printMigrateJSON(jsonOutput, result)
+	}
+
+	serverDone, err := guardRunningServer(cmd, dest, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	batchResult, err := addon.Migrate(source, dest, plan, addon.MigrateOptions{
+		Verbose:      verbose,
+		BackupDir:    destTarget.backupDir(cmd),
+		AllowScripts: allowScripts,
+		Description:  fmt.Sprintf("Before migrating packs from %s", sourceTarget.Path),
+	})
+	result.Batch = batchResult
+
+	if jsonOutput {
+		if jsonErr := printMigrateJSON(true, result); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+	if err != nil {
+		if batchResult != nil {
+			for _, errMsg := range batchResult.Errors {
+				fmt.Printf("  - %s\n", errMsg)
+			}
+			if batchResult.RolledBack {
+				fmt.Println("All changes were rolled back")
+			}
+		}
+		return err
+	}
+	fmt.Printf("Migrated %d pack(s) to %s, moving %d to match the source's enable order\n",
+		plan.Count(addon.MigrateInstall)+plan.Count(addon.MigrateReplace), destTarget.Path, len(plan.Moves))
+	return nil
+}
+
+// renderMigrationPlan prints what migrate does with each source pack, in
+// install order, followed by a summary and any warnings
+func renderMigrationPlan(plan *addon.MigrationPlan) {
+	if len(plan.Packs) == 0 {
+		fmt.Println("No packs are installed on the source server")
+		return
+	}
+
+	symbols := map[addon.MigrateAction]string{
+		addon.MigrateInstall:  "+",
+		addon.MigrateReplace:  "~",
+		addon.MigrateSkip:     "=",
+		addon.MigrateConflict: "!",
+	}
+	for _, pack := range plan.Packs {
+		versions := formatVersion(pack.Version)
+		if pack.DestVersion != nil && *pack.DestVersion != pack.Version {
+			versions = fmt.Sprintf("%s -> %s", formatVersion(*pack.DestVersion), versions)
+		}
+		line := fmt.Sprintf("  %s %-8s %s %s (%s)", symbols[pack.Action], pack.Action, pack.Name, versions, pack.UUID)
+		if pack.Reason != "" {
+			line += ": " + pack.Reason
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\nPlan: %d to install, %d to replace, %d to skip, %d to reorder.\n",
+		plan.Count(addon.MigrateInstall), plan.Count(addon.MigrateReplace), plan.Count(addon.MigrateSkip), len(plan.Moves))
+
+	if len(plan.Warnings) > 0 {
+		fmt.Println("Warnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
+	}
+}
+
+// printMigrateJSON prints the migrate result when --json is set
+func printMigrateJSON(jsonOutput bool, result migrateResult) error {
+	if !jsonOutput {
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/scaffold"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewNewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new [behavior-pack|resource-pack|addon] [name]",
+		Short: "Generate the skeleton of a new pack or addon",
+		Long: `Generate a valid skeleton to start developing a pack or addon from: a
+manifest.json with fresh UUIDs, a min_engine_version, and the pack's module,
+and a placeholder pack_icon.png.
+
+An addon is a behavior pack and a resource pack in behavior_pack and
+resource_pack subdirectories, with the behavior pack depending on the resource
+pack. --script adds a script module to the behavior pack, running
+scripts/main.js against @minecraft/server, and a package.json for the script
+API's typings.
+
+The skeleton goes in --dir, by default a directory named after the name, which
+must not exist or be empty. Install it on a test server as it is with
+'blockbench install <dir> <server-path>'.`,
+		Args:      cobra.ExactArgs(2),
+		ValidArgs: []string{string(scaffold.KindBehaviorPack), string(scaffold.KindResourcePack), string(scaffold.KindAddon)},
+		RunE:      runNew,
+	}
+
+	cmd.Flags().String("dir", "", "Directory to generate into (default: the name in lower case with dashes)")
+	cmd.Flags().String("description", "", "Pack description")
+	cmd.Flags().StringArray("author", nil, "Author listed in the manifest metadata (repeatable)")
+	cmd.Flags().String("min-engine-version", formatVersion(scaffold.DefaultMinEngineVersion), "Oldest game version the packs support")
+	cmd.Flags().Bool("script", false, "Add a script module to the behavior pack")
+	cmd.Flags().String("script-api-version", scaffold.DefaultScriptAPIVersion, "@minecraft/server version the script module depends on")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runNew(cmd *cobra.Command, args []string) error {
+	kind := scaffold.Kind(args[0])
+	name := args[1]
+	dir, _ := cmd.Flags().GetString("dir")
+	description, _ := cmd.Flags().GetString("description")
+	authors, _ := cmd.Flags().GetStringArray("author")
+	minEngine, _ := cmd.Flags().GetString("min-engine-version")
+	script, _ := cmd.Flags().GetBool("script")
+	scriptAPIVersion, _ := cmd.Flags().GetString("script-api-version")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	minEngineVersion, err := validation.ParseVersion(minEngine)
+	if err != nil {
+		return fmt.Errorf("invalid --min-engine-version: %w", err)
+	}
+	if dir == "" {
+		dir = scaffold.DirName(name)
+	}
+	if dryRun {
+		fmt.Printf("Would generate %s %q in %s\n", kind, name, dir)
+		return nil
+	}
+
+	result, err := scaffold.Generate(dir, scaffold.Options{
+		Kind:             kind,
+		Name:             name,
+		Description:      description,
+		Authors:          authors,
+		MinEngineVersion: minEngineVersion,
+		Script:           script,
+		ScriptAPIVersion: scriptAPIVersion,
+	})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Generated %s %q in %s\n", kind, name, result.Dir)
+	for _, pack := range result.Packs {
+		fmt.Printf("  %s pack %s (%s)\n", pack.Type, pack.UUID, pack.Dir)
+	}
+	for _, file := range result.Files {
+		fmt.Printf("  %s\n", file)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/spf13/cobra"
+)
+
+func NewPackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack [pack-dir]...",
+		Short: "Build a .mcaddon from pack source directories, or work with installed packs",
+		Long: `Package pack source directories, each with a manifest.json at its root, into
+the file given with --output: a .mcaddon holding one .mcpack per pack, or a
+.mcpack holding a single pack. Every manifest is validated first, and two packs
+with the same UUID are rejected. Hidden files, node_modules, package.json, and
+package-lock.json are left out.
+
+--bump patch, minor, or major increments each pack's version in its
+manifest.json before packaging, along with the modules at that version and the
+other packs' dependencies on it, so a release is one command:
+
+  blockbench pack ./MyBP ./MyRP -o MyAddon.mcaddon --bump patch
+
+Manifests are rewritten with two-space indentation, keeping their fields and
+order. With the global --dry-run flag, nothing is written.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runPack,
+	}
+	cmd.Flags().StringP("output", "o", "", "Path of the .mcaddon or .mcpack to write (required)")
+	cmd.Flags().String("bump", "", "Increment every pack's version first: patch, minor, or major")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	vendorCmd := &cobra.Command{
+		Use:   "vendor [addon-name] [server-path]",
+		Short: "Export a pack and its installed dependencies as one .mcaddon",
+		Long: `Export an installed pack together with every installed pack it depends on,
+directly or transitively, as a single .mcaddon that can be installed on another
+server with 'blockbench install'.
+
+Module dependencies such as @minecraft/server come with the game and are not
+bundled. Dependencies that are not installed on this server are reported and
+left out of the bundle.`,
+		Args:              cobra.ExactArgs(2),
+		RunE:              runPackVendor,
+		ValidArgsFunction: completeArgs(completeInstalledPacks, completeServerPath),
+	}
+	vendorCmd.Flags().StringP("output", "o", "", "Path of the .mcaddon to write (required)")
+	vendorCmd.Flags().String("uuid", "", "Look up the pack by UUID instead of name")
+	vendorCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(vendorCmd)
+
+	return cmd
+}
+
+func runPack(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	bumpValue, _ := cmd.Flags().GetString("bump")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if output == "" {
+		return fmt.Errorf("--output is required")
+	}
+	bump, err := addon.ParseBumpPart(bumpValue)
+	if err != nil {
+		return err
+	}
+
+	result, err := addon.BuildAddon(args, output, addon.BuildOptions{Bump: bump, DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, pack := range result.Packs {
+		version := formatVersion(pack.Version)
+		if pack.PreviousVersion != nil {
+			version = formatVersion(*pack.PreviousVersion) + " -> " + version
+		}
+		fmt.Printf("%s pack: %s %s (%s)\n", pack.Type, pack.Name, version, pack.PackID)
+	}
+	if dryRun {
+		fmt.Printf("Would write %s with %d pack(s)\n", result.Output, len(result.Packs))
+		return nil
+	}
+	fmt.Printf("Wrote %s with %d pack(s)\n", result.Output, len(result.Packs))
+	return nil
+}
+
+func runPackVendor(cmd *cobra.Command, args []string) error {
+	identifier := args[0]
+	target, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	uuid, _ := cmd.Flags().GetString("uuid")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if output == "" {
+		return fmt.Errorf("--output is required")
+	}
+
+	byUUID := uuid != ""
+	if byUUID {
+		identifier = uuid
+	}
+
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	result, err := addon.VendorPack(server, identifier, byUUID, output)
+	if err != nil {
+		return err
+	}
+
+	return printExportResult(result, jsonOutput)
+}
+
+// printExportResult prints the packs written by pack vendor or export
+func printExportResult(result *addon.VendorResult, jsonOutput bool) error {
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, missing := range result.Missing {
+		slog.Warn("Dependency is not installed and was left out of the bundle", "uuid", missing)
+	}
+	for _, excluded := range result.Excluded {
+		slog.Warn("Pack depends on a pack that was not exported; use --with-deps to include it", "uuid", excluded)
+	}
+	fmt.Printf("Wrote %d pack(s) to %s\n", len(result.Packs), result.Output)
+	for _, pack := range result.Packs {
+		role := ""
+		if pack.Dependency {
+			role = " (dependency)"
+		}
+		fmt.Printf("  %s v%d.%d.%d [%s]%s\n", pack.Name, pack.Version[0], pack.Version[1], pack.Version[2], pack.Type, role)
+	}
+	return nil
+}
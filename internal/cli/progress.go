@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+const (
+	progressBarWidth = 30
+
+	// Steps that finish within progressDelay never draw, so fast operations stay quiet
+	progressDelay    = 250 * time.Millisecond
+	progressInterval = 100 * time.Millisecond
+)
+
+// newProgress returns a terminal progress bar on stderr, or nil when stderr is
+// not a terminal or machine-readable output was requested
+func newProgress(jsonOutput bool) filesystem.Progress {
+	if jsonOutput || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &terminalProgress{out: os.Stderr}
+}
+
+// isTerminal reports whether f is a character device such as a TTY
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalProgress redraws a single status line with a bar, byte counts, and an ETA
+type terminalProgress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	step    string
+	total   int64
+	done    int64
+	started time.Time
+	drawn   time.Time // Zero until the current step has drawn
+	width   int       // Length of the last line, for clearing leftovers
+}
+
+func (p *terminalProgress) Start(step string, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.step = step
+	p.total = total
+	p.done = 0
+	p.started = time.Now()
+	p.drawn = time.Time{}
+	p.width = 0
+}
+
+func (p *terminalProgress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	now := time.Now()
+	if now.Sub(p.started) < progressDelay || now.Sub(p.drawn) < progressInterval {
+		return
+	}
+	p.draw(now)
+}
+
+func (p *terminalProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.drawn.IsZero() {
+		return
+	}
+	p.draw(time.Now())
+	fmt.Fprintln(p.out)
+}
+
+// draw writes the status line; callers hold the lock
+func (p *terminalProgress) draw(now time.Time) {
+	var line string
+	if p.total > 0 {
+		done := p.done
+		if done > p.total {
+			done = p.total // Declared sizes can be understated
+		}
+		filled := int(done * progressBarWidth / p.total)
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+		line = fmt.Sprintf("%s [%s] %3d%% %s / %s", p.step, bar, done*100/p.total,
+			filesystem.FormatSize(done), filesystem.FormatSize(p.total))
+		if eta, ok := estimateRemaining(done, p.total, now.Sub(p.started)); ok && done < p.total {
+			line += "  ETA " + eta.String()
+		}
+	} else {
+		line = fmt.Sprintf("%s %s", p.step, filesystem.FormatSize(p.done))
+	}
+
+	padding := ""
+	if len(line) < p.width {
+		padding = strings.Repeat(" ", p.width-len(line))
+	}
+	fmt.Fprintf(p.out, "\r%s%s", line, padding)
+	p.width = len(line)
+	p.drawn = now
+}
+
+// estimateRemaining extrapolates the time left from the average rate so far
+func estimateRemaining(done, total int64, elapsed time.Duration) (time.Duration, bool) {
+	if done <= 0 || elapsed <= 0 {
+		return 0, false
+	}
+	remaining := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+	return remaining.Round(time.Second), true
+}
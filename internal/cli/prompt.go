@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/makutaku/blockbench/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+// prompter returns the Prompter for the command's questions: standard input,
+// with every confirmation answered yes under --yes
+func prompter(cmd *cobra.Command) prompt.Prompter {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return prompt.AssumeYes(prompt.Stdin(), os.Stdout)
+	}
+	return prompt.Stdin()
+}
+
+// confirm asks a yes/no question; end of input counts as no
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	confirmed, err := prompter(cmd).Confirm(question)
+	if errors.Is(err, prompt.ErrNoInput) {
+		return false, nil
+	}
+	return confirmed, err
+}
+
+// interactiveMode reports whether --interactive is on. Without a terminal on
+// standard input there is nobody to answer its confirmations, so it is turned
+// off with a warning, unless --yes answers them.
+func interactiveMode(cmd *cobra.Command) bool {
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	if !interactive {
+		return false
+	}
+	if yes, _ := cmd.Flags().GetBool("yes"); yes || prompt.IsTerminal(os.Stdin) {
+		return true
+	}
+	slog.Warn("Standard input is not a terminal, so --interactive is turned off; use --yes to show the steps without asking")
+	return false
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/spf13/cobra"
+)
+
+func NewRegenUUIDsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "regen-uuids [pack-dir]",
+		Short: "Give a forked pack or addon new UUIDs",
+		Long: `Replace the header and module UUIDs of a pack's manifest.json with fresh ones,
+so a fork installs next to the pack it was copied from instead of conflicting
+with it as the same pack.
+
+The argument is a pack directory, with a manifest.json at its root, or an
+addon directory whose subdirectories are packs, in which case every pack gets
+new UUIDs. Every occurrence of an old UUID is replaced with the same new one,
+so dependencies between the regenerated packs keep working. Sibling packs in
+the same parent directory that depend on a regenerated pack are updated to
+the new UUID; a sibling with the original UUID, the pack the fork was copied
+from, is left alone.
+
+Manifests are rewritten with two-space indentation, keeping their fields and
+order. With the global --dry-run flag, nothing is written.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRegenUUIDs,
+	}
+
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runRegenUUIDs(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	result, err := addon.RegenerateUUIDs(args[0], dryRun)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	verb := "Replaced"
+	if dryRun {
+		verb = "Would replace"
+	}
+	for _, change := range result.Changes {
+		fmt.Printf("%s: %s %s -> %s\n", change.Directory, change.Field, change.Old, change.New)
+	}
+	for _, manifest := range result.Manifests {
+		fmt.Printf("%s %d UUID(s) in %s\n", verb, manifest.Replaced, manifest.Directory)
+	}
+	for _, original := range result.Originals {
+		fmt.Printf("Left %s alone: it has the original UUID\n", original)
+	}
+	return nil
+}
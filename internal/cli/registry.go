@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/registry"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewRegistryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry",
+		Short: "Manage the registries 'blockbench search' and install by name look addons up in",
+		Long: `Manage the addon registries stored in the config file in blockbench's config
+directory (see 'blockbench dirs'). A registry is the http(s) URL or local path
+of an index listing named addons and their versions; 'blockbench search' finds
+addons in it and 'blockbench install name@version' installs them. When several
+registries list the same name, the one added first wins. 'blockbench registry
+serve' runs a registry for a directory of addon files.
+
+A registry index is JSON, where each url is a URL or a path relative to the
+index:
+
+  {"addons": [{"name": "foo-mobs", "uuid": "...", "description": "...",
+               "versions": [{"version": [1, 2, 0], "url": "foo-1.2.0.mcaddon", "sha256": "..."}]}]}`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [url-or-path]",
+		Short: "Add a registry",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRegistryAdd,
+	}
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List registries in priority order",
+		Args:  cobra.NoArgs,
+		RunE:  runRegistryList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [url-or-path]",
+		Short: "Remove a registry",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRegistryRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve [dir]",
+		Short: "Serve a directory of addon files as a registry over HTTP",
+		Long: `Scan a directory for .mcaddon and .mcpack files, build a registry index from
+their manifests, and serve it over HTTP at /index.json, with each listed file
+downloadable below /files/. Other files in the directory are not served.
+
+Each file is a version of the addon its main pack (the first behavior pack, or
+else the first pack) identifies, named after that pack and versioned by it:
+files of one addon at different versions list as one addon. Add the registry on
+clients with 'blockbench registry add http://host:port/index.json'.
+
+--rescan rebuilds the index at an interval so added files are picked up; a
+file that fails to scan keeps the previous index. With the global --dry-run
+flag, the index is printed instead of served. The server runs until
+interrupted. It has no authentication or TLS: serve on a trusted network, or
+behind a reverse proxy that adds them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRegistryServe,
+	}
+	serveCmd.Flags().String("listen", "localhost:8080", "Address to listen on, e.g. :8080 for every interface")
+	serveCmd.Flags().Duration("rescan", 0, "Rebuild the index at this interval, e.g. 5m (default: only at startup)")
+	addExtractLimitFlags(serveCmd)
+	cmd.AddCommand(serveCmd)
+
+	return cmd
+}
+
+func runRegistryAdd(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	registry, err := cfg.AddRegistry(args[0])
+	if err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would add registry %s to %s\n", registry, cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added registry %s\n", registry)
+	return nil
+}
+
+func runRegistryList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		list := cfg.Registries
+		if list == nil {
+			list = []string{}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(cfg.Registries) == 0 {
+		fmt.Println("No registries configured")
+		return nil
+	}
+	for _, registry := range cfg.Registries {
+		fmt.Println(registry)
+	}
+	return nil
+}
+
+func runRegistryRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.RemoveRegistry(args[0]); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would remove registry %s from %s\n", args[0], cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed registry %s\n", args[0])
+	return nil
+}
+
+func runRegistryServe(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	listen, _ := cmd.Flags().GetString("listen")
+	rescan, _ := cmd.Flags().GetDuration("rescan")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	scan := registryScanner(limits)
+	index, err := registry.Build(dir, scan)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		data, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	handler, err := registry.NewHandler(dir, index)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if rescan > 0 {
+		go func() {
+			ticker := time.NewTicker(rescan)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					rebuilt, err := registry.Build(dir, scan)
+					if err == nil {
+						err = handler.SetIndex(rebuilt)
+					}
+					if err != nil {
+						slog.Warn("Failed to rebuild the registry index; serving the previous one", "error", err)
+						continue
+					}
+					slog.Info("Rebuilt registry index", "dir", dir, "addons", len(rebuilt.Addons))
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Failed to shut down the registry server", "error", err)
+		}
+	}()
+
+	fmt.Printf("Serving %d addon(s) from %s at http://%s%s\n", len(index.Addons), dir, listener.Addr(), registry.IndexPath)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("registry server failed: %w", err)
+	}
+	return nil
+}
+
+// registryScanner reads an addon file's packs by validating it, reading each
+// file again only once it changed
+func registryScanner(limits filesystem.ExtractLimits) registry.Scanner {
+	return registry.CachedScanner(func(path string) ([]registry.Pack, error) {
+		report, err := addon.ValidateAddon(path, false, limits)
+		if err != nil {
+			return nil, err
+		}
+		packs := make([]registry.Pack, 0, len(report.Packs))
+		for _, pack := range report.Packs {
+			packs = append(packs, registry.Pack{
+				UUID:        pack.UUID,
+				Name:        pack.Name,
+				Description: pack.Description,
+				Version:     pack.Version,
+				Type:        pack.Type,
+			})
+		}
+		return packs, nil
+	})
+}
@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/prompt"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewReorderCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reorder [server-path]",
+		Short: "Change the order of packs in the world config files",
+		Long: `Change the order of active packs in world_behavior_packs.json and
+world_resource_packs.json. Packs earlier in a config override the packs after
+them, so the order decides which pack wins when two change the same thing.
+
+With --pack and --position, one pack is moved: to the top or bottom of its
+config, or right before or after another pack. Without them, each config with
+more than one pack is listed and the new order is asked for as the list
+numbers in the order wanted.
+
+The configs are backed up first and restored if a move fails. Use --dry-run to
+only print the new order.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runReorder,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().String("pack", "", "Name or UUID of the pack to move")
+	cmd.Flags().String("position", "", "Where to move the pack: top, bottom, before=<uuid>, or after=<uuid>")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	cmd.Flags().Bool("json", false, "Output the moves and result in JSON format")
+	addServerControlFlags(cmd)
+
+	return cmd
+}
+
+// reorderResult is the JSON output of reorder
+type reorderResult struct {
+	Moves []minecraft.PackMove `json:"moves"`
+	Batch *addon.BatchResult   `json:"batch,omitempty"`
+}
+
+// reorderedConfig is the new order of one world config
+type reorderedConfig struct {
+	configFile string
+	packs      []minecraft.InstalledPack // In the new order
+	moves      []minecraft.PackMove
+}
+
+func runReorder(cmd *cobra.Command, args []string) error {
+	packArg, _ := cmd.Flags().GetString("pack")
+	positionSpec, _ := cmd.Flags().GetString("position")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if (packArg == "") != (positionSpec == "") {
+		return fmt.Errorf("--pack and --position must be given together")
+	}
+	if packArg == "" && jsonOutput {
+		return fmt.Errorf("--json needs --pack and --position; the interactive mode has no JSON output")
+	}
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	var configs []reorderedConfig
+	if packArg != "" {
+		position, err := minecraft.ParsePackPosition(positionSpec)
+		if err != nil {
+			return err
+		}
+		config, err := positionPack(server, packArg, position)
+		if err != nil {
+			return err
+		}
+		configs = []reorderedConfig{*config}
+	} else {
+		configs, err = promptPackOrder(server, prompter(cmd))
+		if err != nil {
+			return err
+		}
+	}
+
+	result := reorderResult{Moves: make([]minecraft.PackMove, 0)}
+	for _, config := range configs {
+		result.Moves = append(result.Moves, config.moves...)
+	}
+
+	if !jsonOutput {
+		if len(result.Moves) == 0 {
+			fmt.Println("No changes: the packs are already in that order")
+		}
+		for _, config := range configs {
+			if len(config.moves) == 0 {
+				continue
+			}
+			fmt.Printf("New order of %s:\n", filepath.Base(config.configFile))
+			for index, pack := range config.packs {
+				fmt.Printf("  [%d] %s (%s)\n", index, pack.Name, pack.PackID)
+			}
+		}
+	}
+	if len(result.Moves) == 0 || dryRun {
+		return printReorderJSON(jsonOutput, result)
+	}
+
+	if packArg == "" {
+		apply, err := confirm(cmd, "Apply the new order?")
+		if err != nil {
+			return err
+		}
+		if !apply {
+			fmt.Println("Reorder cancelled")
+			return nil
+		}
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	batch := addon.NewBatch(server, target.backupDir(cmd))
+	for _, move := range result.Moves {
+		batch.Reorder(move.PackID, move.Index)
+	}
+	batchResult, err := batch.Execute(addon.BatchOptions{Verbose: verbose, Description: "Before reordering packs"})
+	result.Batch = batchResult
+
+	if jsonOutput {
+		if jsonErr := printReorderJSON(true, result); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+	if err != nil {
+		for _, errMsg := range batchResult.Errors {
+			fmt.Printf("  - %s\n", errMsg)
+		}
+		if batchResult.RolledBack {
+			fmt.Println("All changes were rolled back")
+		}
+		return err
+	}
+	fmt.Printf("Moved %d pack(s)\n", len(result.Moves))
+	return nil
+}
+
+// positionPack works out the new order of the config holding one pack when it
+// moves to a position
+func positionPack(server *minecraft.Server, identifier string, position minecraft.PackPosition) (*reorderedConfig, error) {
+	byUUID := validation.ValidateUUID(identifier)
+	if byUUID {
+		identifier = validation.NormalizeUUID(identifier)
+	}
+	pack, err := addon.FindInstalledPack(server, identifier, byUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, packs, err := configPacks(server, pack.Type)
+	if err != nil {
+		return nil, err
+	}
+	config := make(minecraft.WorldConfig, len(packs))
+	for i, installed := range packs {
+		config[i] = minecraft.PackReference{PackID: installed.PackID, Version: installed.Version}
+	}
+	moved, err := minecraft.PositionPackInConfig(config, pack.PackID, position)
+	if err != nil {
+		return nil, fmt.Errorf("cannot move %s in %s: %w", pack.Name, filepath.Base(configFile), err)
+	}
+
+	order := make([]string, len(moved))
+	for i, ref := range moved {
+		order[i] = ref.PackID
+	}
+	return newReorderedConfig(configFile, packs, order), nil
+}
+
+// promptPackOrder lists the packs of each world config with more than one
+// pack and asks for their new order
+func promptPackOrder(server *minecraft.Server, prompter prompt.Prompter) ([]reorderedConfig, error) {
+	var configs []reorderedConfig
+	listed := false
+	for _, packType := range []minecraft.PackType{minecraft.PackTypeBehavior, minecraft.PackTypeResource} {
+		configFile, packs, err := configPacks(server, packType)
+		if err != nil {
+			return nil, err
+		}
+		if len(packs) < 2 {
+			continue
+		}
+		listed = true
+
+		fmt.Printf("Current order of %s:\n", filepath.Base(configFile))
+		for i, pack := range packs {
+			fmt.Printf("  %d. %s (%s)\n", i+1, pack.Name, pack.PackID)
+		}
+		for {
+			answer, err := promptLine(prompter, fmt.Sprintf("New order of the %s packs as list numbers, e.g. %s (blank keeps it): ", packType, exampleOrder(len(packs))))
+			if err != nil {
+				return nil, err
+			}
+			if answer == "" {
+				break
+			}
+			order, err := parsePackOrder(answer, packs)
+			if err != nil {
+				fmt.Printf("  %v\n", err)
+				continue
+			}
+			configs = append(configs, *newReorderedConfig(configFile, packs, order))
+			break
+		}
+	}
+	if !listed {
+		return nil, fmt.Errorf("no world config has more than one pack to reorder")
+	}
+	return configs, nil
+}
+
+// configPacks returns the world config of a pack type and its active packs in order
+func configPacks(server *minecraft.Server, packType minecraft.PackType) (string, []minecraft.InstalledPack, error) {
+	configFile, err := server.Paths.WorldConfigFor(packType)
+	if err != nil {
+		return "", nil, err
+	}
+	installed, err := server.ListInstalledPacks()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list installed packs: %w", err)
+	}
+	packs := make([]minecraft.InstalledPack, 0)
+	for _, pack := range installed {
+		if pack.Type == packType {
+			if pack.Name == "" {
+				pack.Name = pack.PackID
+			}
+			packs = append(packs, pack)
+		}
+	}
+	return configFile, packs, nil
+}
+
+// newReorderedConfig puts packs in order and works out the moves that get there
+func newReorderedConfig(configFile string, packs []minecraft.InstalledPack, order []string) *reorderedConfig {
+	byUUID := make(map[string]minecraft.InstalledPack, len(packs))
+	current := make([]string, len(packs))
+	for i, pack := range packs {
+		byUUID[pack.PackID] = pack
+		current[i] = pack.PackID
+	}
+	ordered := make([]minecraft.InstalledPack, len(order))
+	for i, uuid := range order {
+		ordered[i] = byUUID[uuid]
+	}
+	return &reorderedConfig{configFile: configFile, packs: ordered, moves: minecraft.MovesToOrder(current, order)}
+}
+
+// parsePackOrder reads a new order given as 1-based list numbers. Every pack
+// must be listed exactly once.
+func parsePackOrder(answer string, packs []minecraft.InstalledPack) ([]string, error) {
+	fields := strings.FieldsFunc(answer, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != len(packs) {
+		return nil, fmt.Errorf("list all %d numbers, each once", len(packs))
+	}
+
+	seen := make(map[int]bool, len(fields))
+	order := make([]string, 0, len(fields))
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 1 || number > len(packs) {
+			return nil, fmt.Errorf("%q is not a number from 1 to %d", field, len(packs))
+		}
+		if seen[number] {
+			return nil, fmt.Errorf("%d is listed more than once", number)
+		}
+		seen[number] = true
+		order = append(order, packs[number-1].PackID)
+	}
+	return order, nil
+}
+
+// exampleOrder shows the reversed order of n packs as an example answer
+func exampleOrder(n int) string {
+	numbers := make([]string, 0, n)
+	for i := n; i >= 1 && len(numbers) < 3; i-- {
+		numbers = append(numbers, strconv.Itoa(i))
+	}
+	if n > 3 {
+		numbers = append(numbers, "...")
+	}
+	return strings.Join(numbers, " ")
+}
+
+// promptLine asks a question and returns the trimmed answer; end of input is
+// an empty answer
+func promptLine(prompter prompt.Prompter, question string) (string, error) {
+	answer, err := prompter.Ask(question)
+	if errors.Is(err, prompt.ErrNoInput) {
+		return "", nil
+	}
+	return answer, err
+}
+
+// printReorderJSON prints the reorder result when --json is set
+func printReorderJSON(jsonOutput bool, result reorderResult) error {
+	if !jsonOutput {
+		return nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
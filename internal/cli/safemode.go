@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/spf13/cobra"
+)
+
+func NewSafeModeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "safe-mode",
+		Short: "Boot a world without packs while keeping pack files installed",
+		Long: `Temporarily deactivate every pack in the world configuration files.
+
+'safe-mode enable' snapshots world_behavior_packs.json and world_resource_packs.json
+and replaces them with empty pack lists. Pack directories are left untouched, so
+'safe-mode disable' can restore the previous configuration in one step.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:               "enable [server-path]",
+		Short:             "Deactivate all packs and snapshot the world configs",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runSafeModeEnable,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:               "disable [server-path]",
+		Short:             "Restore the world configs captured by 'safe-mode enable'",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runSafeModeDisable,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:               "status [server-path]",
+		Short:             "Show whether safe mode is enabled",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runSafeModeStatus,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	})
+
+	return cmd
+}
+
+func newSafeModeManager(cmd *cobra.Command, serverPath string) (*addon.SafeModeManager, error) {
+	target, err := resolveServerTarget(cmd, serverPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := target.newServer()
+	if err != nil {
+		return nil, err
+	}
+	return addon.NewSafeModeManager(server), nil
+}
+
+func runSafeModeEnable(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	manager, err := newSafeModeManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := manager.Enable(addon.SafeModeOptions{DryRun: dryRun, Verbose: verbose})
+	printSafeModeWarnings(result)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("DRY RUN: Safe mode would be enabled")
+		return nil
+	}
+
+	fmt.Printf("Safe mode enabled: deactivated %d behavior pack(s) and %d resource pack(s)\n",
+		result.BehaviorPacks, result.ResourcePacks)
+	fmt.Println("Pack files were kept. Run 'blockbench safe-mode disable <server-path>' to restore them.")
+	return nil
+}
+
+func runSafeModeDisable(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	manager, err := newSafeModeManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	result, err := manager.Disable(addon.SafeModeOptions{DryRun: dryRun, Verbose: verbose})
+	printSafeModeWarnings(result)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("DRY RUN: Safe mode would be disabled")
+		return nil
+	}
+
+	fmt.Printf("Safe mode disabled: reactivated %d behavior pack(s) and %d resource pack(s)\n",
+		result.BehaviorPacks, result.ResourcePacks)
+	return nil
+}
+
+func runSafeModeStatus(cmd *cobra.Command, args []string) error {
+	manager, err := newSafeModeManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	state, err := manager.Status()
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		fmt.Println("Safe mode is disabled")
+		return nil
+	}
+
+	fmt.Printf("Safe mode is enabled since %s\n", state.EnabledAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Deactivated: %d behavior pack(s), %d resource pack(s)\n", state.BehaviorPacks, state.ResourcePacks)
+	return nil
+}
+
+func printSafeModeWarnings(result *addon.SafeModeResult) {
+	if result == nil || len(result.Warnings) == 0 {
+		return
+	}
+	fmt.Println("Warnings:")
+	for _, warning := range result.Warnings {
+		fmt.Printf("  - %s\n", warning)
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+func NewSearchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search [term]",
+		Short: "Search the configured registries for addons",
+		Long: `List the addons in the configured registries (see 'blockbench registry') whose
+name, description, or UUID contains the term, ignoring case, with their newest
+version. Install one with 'blockbench install name[@version] [server-path]'.
+--registry searches the given registries instead of the configured ones.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runSearch,
+	}
+
+	addRegistryFlag(cmd)
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+// addRegistryFlag adds the flag that overrides the configured registries
+func addRegistryFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("registry", nil, "Registry to use instead of the configured ones (repeatable)")
+}
+
+// registryEntries loads the addons of the registries given with --registry,
+// or of the configured registries
+func registryEntries(cmd *cobra.Command) ([]registry.Entry, error) {
+	registries, _ := cmd.Flags().GetStringArray("registry")
+	if len(registries) == 0 {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return nil, err
+		}
+		registries = cfg.Registries
+	}
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("no registries configured: add one with 'blockbench registry add' or pass --registry")
+	}
+	return registry.LoadAll(registries)
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	entries, err := registryEntries(cmd)
+	if err != nil {
+		return err
+	}
+	found := registry.Search(entries, args[0])
+
+	if jsonOutput {
+		if found == nil {
+			found = []registry.Entry{}
+		}
+		data, err := json.MarshalIndent(found, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(found) == 0 {
+		fmt.Printf("No addons matching %q\n", args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLATEST\tUUID\tDESCRIPTION\tREGISTRY")
+	fmt.Fprintln(w, "----\t------\t----\t-----------\t--------")
+	for _, entry := range found {
+		latest := "-"
+		if version, ok := entry.Latest(); ok {
+			latest = formatVersion(version.Version)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Name, latest, entry.UUID, orDefault(entry.Description, "-"), entry.Registry)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+// resolveAddonArg returns the addon file an install argument names. An
+// argument that isn't an existing file or directory but is a name or
+// name@version is looked up in the registries and downloaded into the cache.
+func resolveAddonArg(cmd *cobra.Command, arg string) (string, error) {
+	if _, err := os.Stat(arg); err == nil || !registry.IsRef(arg) {
+		return arg, nil
+	}
+	ref, err := registry.ParseRef(arg)
+	if err != nil {
+		return "", err
+	}
+	entries, err := registryEntries(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a file, and cannot be looked up by name: %w", arg, err)
+	}
+	entry, version, err := registry.Resolve(entries, ref)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := downloadCacheDir()
+	if err != nil {
+		return "", err
+	}
+	path, err := registry.Fetch(version, cacheDir)
+	if err != nil {
+		return "", err
+	}
+	slog.Info("Resolved addon", "name", entry.Name, "version", formatVersion(version.Version), "registry", entry.Registry, "url", version.URL)
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); !jsonOutput {
+		fmt.Printf("Resolved %s@%s from %s\n", entry.Name, formatVersion(version.Version), entry.Registry)
+	}
+	return path, nil
+}
@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewServerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "server",
+		Short: "Manage named server profiles",
+		Long: `Manage named server profiles, stored in the config file in blockbench's config
+directory (see 'blockbench dirs').
+
+Once a server is added, its name can be given wherever a command takes a
+server-path:
+
+  blockbench server add survival /opt/bedrock/survival
+  blockbench install foo.mcaddon survival
+
+A profile can also set the backup directory, the world to manage instead of
+the level-name in server.properties, whether packs live in the development
+pack directories or in behavior_packs/resource_packs, and the owner and mode
+installs give the pack files (the --chown and --chmod defaults), and whether
+backups are incremental. To refer to a directory that has the same name as a
+profile, write it as ./name.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [name] [server-path]",
+		Short: "Add or replace a server profile",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runServerAdd,
+	}
+	addCmd.Flags().String("backup-dir", "", "Backup directory for this server (default: server-path/backups)")
+	addCmd.Flags().String("world", "", "World to manage (default: level-name from server.properties)")
+	addCmd.Flags().String("pack-dirs", string(minecraft.PackDirsDevelopment), "Pack directories to install into: development or release")
+	addCmd.Flags().String("chown", "", "Owner for the files installs write to this server, as user or user:group")
+	addCmd.Flags().String("chmod", "", "Mode for the files installs write to this server, in octal such as 644")
+	addCmd.Flags().Bool("incremental-backups", false, "Store only the files changed since the previous backup in this server's backups")
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List server profiles",
+		Args:  cobra.NoArgs,
+		RunE:  runServerList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:               "remove [name]",
+		Short:             "Remove a server profile (the server itself is not touched)",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runServerRemove,
+		ValidArgsFunction: completeArgs(completeProfiles),
+	}
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
+
+func runServerAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	backupDir, _ := cmd.Flags().GetString("backup-dir")
+	world, _ := cmd.Flags().GetString("world")
+	packDirs, _ := cmd.Flags().GetString("pack-dirs")
+	owner, _ := cmd.Flags().GetString("chown")
+	fileMode, _ := cmd.Flags().GetString("chmod")
+	incremental, _ := cmd.Flags().GetBool("incremental-backups")
+
+	// Profiles are used from any working directory, so store absolute paths
+	serverPath, err := filepath.Abs(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to resolve server path: %w", err)
+	}
+	if backupDir != "" {
+		if backupDir, err = filepath.Abs(backupDir); err != nil {
+			return fmt.Errorf("failed to resolve backup directory: %w", err)
+		}
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	profile := config.Profile{
+		Path:      serverPath,
+		BackupDir: backupDir,
+		World:     world,
+		PackDirs:  minecraft.PackDirMode(packDirs),
+		Owner:     owner,
+		FileMode:  fileMode,
+
+		IncrementalBackups: incremental,
+	}
+	if profile.PackDirs == minecraft.PackDirsDevelopment {
+		profile.PackDirs = "" // The default; keep the file minimal
+	}
+	if err := cfg.AddProfile(name, profile); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would add server %s -> %s to %s\n", name, serverPath, cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added server %s -> %s\n", name, serverPath)
+	return nil
+}
+
+func runServerList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(cfg.Profiles, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		fmt.Println("No server profiles configured")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATH\tBACKUP DIR\tWORLD\tPACK DIRS")
+	fmt.Fprintln(w, "----\t----\t----------\t-----\t---------")
+	for _, name := range names {
+		profile, _ := cfg.Profile(name)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, profile.Path,
+			orDefault(profile.BackupDir, "-"), orDefault(profile.World, "-"),
+			orDefault(string(profile.PackDirs), string(minecraft.PackDirsDevelopment)))
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+func runServerRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.RemoveProfile(args[0]); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would remove server %s from %s\n", args[0], cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed server %s\n", args[0])
+	return nil
+}
+
+// orDefault returns value, or fallback when value is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// serverTarget is the server a command operates on: a directory given on the
+// command line, or the directory and settings of a named profile
+type serverTarget struct {
+	Path      string
+	Profile   string // Empty when the argument was a path
+	BackupDir string // The profile's backup directory; empty means Path/backups
+	Options   minecraft.PathOptions
+	Ownership *filesystem.Ownership // The profile's owner and mode for installed files; nil when it sets neither
+
+	IncrementalBackups bool // The profile takes incremental backups
+}
+
+// resolveServerTarget resolves a server-path argument. An argument without a
+// path separator that names a profile selects that profile; anything else is
+// a path. With --docker, the path is then mapped to the host directory behind
+// it in the container.
+func resolveServerTarget(cmd *cobra.Command, arg string) (*serverTarget, error) {
+	target := &serverTarget{Path: arg}
+
+	// Without a home directory there is no config file and so no profiles
+	if configPath, err := config.DefaultPath(); err == nil && !strings.ContainsAny(arg, `/\`) {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if profile, ok := cfg.Profile(arg); ok {
+			ownership, err := profile.Ownership()
+			if err != nil {
+				return nil, fmt.Errorf("invalid server profile %s: %w", arg, err)
+			}
+			target = &serverTarget{
+				Path:      profile.Path,
+				Profile:   arg,
+				BackupDir: profile.BackupDir,
+				Options:   profile.PathOptions(),
+				Ownership: ownership,
+
+				IncrementalBackups: profile.IncrementalBackups,
+			}
+		}
+	}
+
+	path, err := resolveServerPath(cmd, target.Path)
+	if err != nil {
+		return nil, err
+	}
+	target.Path = path
+	return target, nil
+}
+
+// newServer creates the server the target points at
+func (t *serverTarget) newServer() (*minecraft.Server, error) {
+	server, err := minecraft.NewServerWithOptions(t.Path, t.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize server: %w", err)
+	}
+	server.IncrementalBackups = t.IncrementalBackups
+	return server, nil
+}
+
+// backupDir returns the backup directory to use: --backup-dir, then the
+// profile's backup directory, then the backups directory in the server root
+func (t *serverTarget) backupDir(cmd *cobra.Command) string {
+	if backupDir, _ := cmd.Flags().GetString("backup-dir"); backupDir != "" {
+		return backupDir
+	}
+	return t.ownBackupDir()
+}
+
+// ownBackupDir returns the server's backup directory regardless of
+// --backup-dir: the profile's backup directory, then the backups directory
+// in the server root
+func (t *serverTarget) ownBackupDir() string {
+	if t.BackupDir != "" {
+		return t.BackupDir
+	}
+	return filepath.Join(t.Path, "backups")
+}
+
+// ownership returns the owner and mode for installed files: --chown and
+// --chmod, each falling back to the profile's setting
+func (t *serverTarget) ownership(cmd *cobra.Command) (*filesystem.Ownership, error) {
+	ownership := &filesystem.Ownership{}
+	if t.Ownership != nil {
+		*ownership = *t.Ownership
+	}
+	var err error
+	if owner, _ := cmd.Flags().GetString("chown"); owner != "" {
+		if ownership.Owner, err = filesystem.ParseOwner(owner); err != nil {
+			return nil, err
+		}
+	}
+	if fileMode, _ := cmd.Flags().GetString("chmod"); fileMode != "" {
+		if ownership.FileMode, err = filesystem.ParseFileMode(fileMode); err != nil {
+			return nil, err
+		}
+	}
+	if ownership.Owner == nil && ownership.FileMode == 0 {
+		return nil, nil
+	}
+	return ownership, nil
+}
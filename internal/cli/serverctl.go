@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/makutaku/blockbench/internal/docker"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/spf13/cobra"
+)
+
+// addServerControlFlags adds the running-server checks and stop/restart hooks
+// to commands that change installed packs
+func addServerControlFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-running", false, "Proceed even if the server appears to be running")
+	cmd.Flags().Bool("stop-server", false, "Stop a running server before the change and leave it stopped")
+	cmd.Flags().Bool("restart-server", false, "Stop a running server before the change and start it again afterwards")
+	cmd.Flags().String("server-unit", "", "systemd unit that runs the server, used to detect, stop, and start it (or BLOCKBENCH_SERVER_UNIT)")
+	cmd.Flags().String("stop-command", "", "Shell command that stops the server, run in the server directory (or BLOCKBENCH_STOP_COMMAND)")
+	cmd.Flags().String("start-command", "", "Shell command that starts the server, run in the server directory (or BLOCKBENCH_START_COMMAND)")
+}
+
+// serverControlFromFlags reads the server control flags, falling back to the environment
+func serverControlFromFlags(cmd *cobra.Command) minecraft.ServerControl {
+	flagOrEnv := func(flag, env string) string {
+		value, _ := cmd.Flags().GetString(flag)
+		if value == "" {
+			value = os.Getenv(env)
+		}
+		return value
+	}
+	return minecraft.ServerControl{
+		Unit:         flagOrEnv("server-unit", "BLOCKBENCH_SERVER_UNIT"),
+		StopCommand:  flagOrEnv("stop-command", "BLOCKBENCH_STOP_COMMAND"),
+		StartCommand: flagOrEnv("start-command", "BLOCKBENCH_START_COMMAND"),
+	}
+}
+
+// guardRunningServer keeps changes from racing a running server, which
+// rewrites its world configs. A running server is refused unless it is
+// stopped with --stop-server or --restart-server, or --allow-running is
+// given. The returned function must be called once the change is done; it
+// starts the server again after --restart-server. Containers selected with
+// --docker are managed with --restart instead and are not checked.
+func guardRunningServer(cmd *cobra.Command, server *minecraft.Server, dryRun bool) (func(), error) {
+	done := func() {}
+	if name, _ := cmd.Flags().GetString("docker"); name != "" {
+		return done, nil
+	}
+
+	allowRunning, _ := cmd.Flags().GetBool("allow-running")
+	stop, _ := cmd.Flags().GetBool("stop-server")
+	restart, _ := cmd.Flags().GetBool("restart-server")
+	control := serverControlFromFlags(cmd)
+
+	running, err := server.DetectRunning(control)
+	if err != nil {
+		slog.Warn("Could not check whether the server is running", "error", err)
+		return done, nil
+	}
+	if running == nil {
+		return done, nil
+	}
+
+	switch {
+	case dryRun:
+		slog.Warn("The server appears to be running", "source", running.Source)
+		return done, nil
+	case stop || restart:
+		// Refuse before stopping rather than leave a server down we can't bring back
+		if restart && control.Unit == "" && control.StartCommand == "" {
+			return done, fmt.Errorf("--restart-server needs --server-unit or --start-command to start the server again")
+		}
+		fmt.Printf("Stopping server (%s)...\n", running.Source)
+		if err := server.Stop(control, running); err != nil {
+			return done, fmt.Errorf("failed to stop the server: %w", err)
+		}
+		slog.Info("Stopped server", "server", server.Paths.ServerRoot, "source", running.Source)
+		if !restart {
+			return done, nil
+		}
+		return func() {
+			fmt.Println("Starting server...")
+			if err := server.Start(control); err != nil {
+				slog.Warn("Failed to start the server again", "server", server.Paths.ServerRoot, "error", err)
+				return
+			}
+			slog.Info("Started server", "server", server.Paths.ServerRoot)
+		}, nil
+	case allowRunning:
+		slog.Warn("The server appears to be running; it may overwrite the changes", "source", running.Source)
+		return done, nil
+	default:
+		return done, fmt.Errorf("the server appears to be running (%s); stop it first, or use --stop-server, --restart-server, or --allow-running", running.Source)
+	}
+}
+
+// addNotifyFlag adds --notify to commands that change installed packs
+func addNotifyFlag(cmd *cobra.Command) {
+	cmd.Flags().String("notify", "", "Announce the change to online players and reload packs through the server console: pipe:<path>, screen:<session>, tmux:<target>, or docker (or BLOCKBENCH_NOTIFY)")
+}
+
+// notifyTarget returns the --notify target, or nil when notifications are off
+func notifyTarget(cmd *cobra.Command) (*minecraft.ConsoleTarget, error) {
+	spec, _ := cmd.Flags().GetString("notify")
+	if spec == "" {
+		spec = os.Getenv("BLOCKBENCH_NOTIFY")
+	}
+	if spec == "" {
+		return nil, nil
+	}
+	target, err := minecraft.ParseConsoleTarget(spec)
+	if err != nil {
+		return nil, err
+	}
+	if name, _ := cmd.Flags().GetString("docker"); target.Kind == minecraft.ConsoleDocker && name == "" {
+		return nil, fmt.Errorf("--notify docker requires --docker")
+	}
+	return &target, nil
+}
+
+// notifyConsole announces changed packs on the server console when --notify
+// is set. The change has already happened, so failures are only warnings.
+// A server stopped with --stop-server or --restart-server isn't reading its
+// console and is skipped.
+func notifyConsole(cmd *cobra.Command, verb string, packs []string) {
+	stopped, _ := cmd.Flags().GetBool("stop-server")
+	restarted, _ := cmd.Flags().GetBool("restart-server")
+	commands := minecraft.PackChangeCommands(verb, packs)
+	if stopped || restarted || len(commands) == 0 {
+		return
+	}
+
+	target, err := notifyTarget(cmd)
+	if err == nil && target != nil {
+		err = sendConsoleCommands(cmd, *target, commands)
+	}
+	if err != nil {
+		slog.Warn("Failed to notify the server console", "error", err)
+	}
+}
+
+// sendConsoleCommands sends commands to a console target; docker targets go
+// through the send-command helper of itzg/minecraft-bedrock-server images
+func sendConsoleCommands(cmd *cobra.Command, target minecraft.ConsoleTarget, commands []string) error {
+	if target.Kind != minecraft.ConsoleDocker {
+		return target.Send(commands)
+	}
+
+	name, _ := cmd.Flags().GetString("docker")
+	client, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	for _, command := range commands {
+		if err := client.Exec(name, []string{"send-command", command}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
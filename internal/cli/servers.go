@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// singleServerFlags configure one particular server or container and can't
+// be shared by every server of --servers
+var singleServerFlags = []string{"docker", "notify", "server-unit", "stop-command", "start-command"}
+
+// addServersFlag adds --servers to commands that can run against several profiles
+func addServersFlag(cmd *cobra.Command) {
+	cmd.Flags().String("servers", "", "Run against these server profiles instead of a server-path: a comma-separated list, or all")
+	_ = cmd.RegisterFlagCompletionFunc("servers", completeServersFlag)
+}
+
+// wantsJSON reports whether --json or --output json asks for JSON output
+func wantsJSON(cmd *cobra.Command) bool {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	output, _ := cmd.Flags().GetString("output")
+	return jsonOutput || strings.EqualFold(output, "json")
+}
+
+// serverArgs accepts n positional arguments, the last being the server-path,
+// or n-1 when --servers selects the servers instead
+func serverArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if servers, _ := cmd.Flags().GetString("servers"); servers != "" {
+			return cobra.ExactArgs(n-1)(cmd, args)
+		}
+		return cobra.ExactArgs(n)(cmd, args)
+	}
+}
+
+// selectedServers returns the profile names selected by --servers, or nil
+// when the command runs against a single server-path
+func selectedServers(cmd *cobra.Command) ([]string, error) {
+	value, _ := cmd.Flags().GetString("servers")
+	if value == "" {
+		return nil, nil
+	}
+	for _, name := range singleServerFlags {
+		if cmd.Flags().Lookup(name) != nil && cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%s applies to a single server and can't be combined with --servers", name)
+		}
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	if value == "all" {
+		names := cfg.ProfileNames()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no server profiles configured; add one with 'blockbench server add'")
+		}
+		return names, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := cfg.Profile(name); !ok {
+			return nil, fmt.Errorf("no server profile named %s", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("--servers needs a comma-separated list of profiles, or all")
+	}
+	return names, nil
+}
+
+// serverRun is the outcome of a command on one server of --servers
+type serverRun struct {
+	Server  string `json:"server"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Result  any    `json:"result,omitempty"`
+}
+
+// runOnServers runs fn against each server profile in turn. A failure on one
+// server doesn't stop the others: every change is backed up and rolled back
+// on its own server. In text mode fn prints its own output under a header and
+// a summary follows; with --json, the results fn returns are printed as one
+// array. The error reports how many servers failed.
+func runOnServers(cmd *cobra.Command, servers []string, fn func(server string) (any, error)) error {
+	jsonOutput := wantsJSON(cmd)
+
+	runs := make([]serverRun, 0, len(servers))
+	failed := 0
+	for i, name := range servers {
+		if !jsonOutput {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s\n", name)
+		}
+
+		result, err := fn(name)
+		run := serverRun{Server: name, Success: err == nil, Result: result}
+		if err != nil {
+			failed++
+			run.Error = err.Error()
+			if !jsonOutput {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVER\tSTATUS\tERROR")
+		fmt.Fprintln(w, "------\t------\t-----")
+		for _, run := range runs {
+			status := "ok"
+			if !run.Success {
+				status = "failed"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", run.Server, status, run.Error)
+		}
+		if err := w.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d server(s) failed", failed, len(servers))
+	}
+	return nil
+}
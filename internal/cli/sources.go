@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/updates"
+	"github.com/spf13/cobra"
+)
+
+func NewSourcesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sources",
+		Short: "Manage the addon sources check-updates looks for newer versions in",
+		Long: `Manage the addon sources 'blockbench check-updates' compares installed packs
+against, stored in the config file in blockbench's config directory (see
+'blockbench dirs').
+
+A source is one of:
+  - the http(s) URL of a source index
+  - a local source index file
+  - a repository directory, read through its ` + updates.IndexFileName + ` when it
+    has one and otherwise scanned for .mcaddon and .mcpack files
+
+A source index is JSON in the lockfile's addon format, where each source is a
+URL or a path relative to the index:
+
+  {"addons": [{"source": "foo-1.2.0.mcaddon", "sha256": "...",
+               "packs": [{"uuid": "...", "name": "Foo", "version": [1, 2, 0], "type": "behavior"}]}]}`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [url-or-path]",
+		Short: "Add an addon source",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSourcesAdd,
+	}
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List addon sources",
+		Args:  cobra.NoArgs,
+		RunE:  runSourcesList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [url-or-path]",
+		Short: "Remove an addon source",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSourcesRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
+
+func runSourcesAdd(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	source, err := cfg.AddSource(args[0])
+	if err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would add source %s to %s\n", source, cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added source %s\n", source)
+	return nil
+}
+
+func runSourcesList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		list := cfg.Sources
+		if list == nil {
+			list = []string{}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(cfg.Sources) == 0 {
+		fmt.Println("No addon sources configured")
+		return nil
+	}
+	for _, source := range cfg.Sources {
+		fmt.Println(source)
+	}
+	return nil
+}
+
+func runSourcesRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.RemoveSource(args[0]); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would remove source %s from %s\n", args[0], cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed source %s\n", args[0])
+	return nil
+}
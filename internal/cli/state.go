@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+// stateFsckResult is the report of 'state fsck'
+type stateFsckResult struct {
+	Backups         []filesystem.MetadataCheck `json:"backups"`
+	SafeModeBackups []filesystem.MetadataCheck `json:"safe_mode_backups"`
+	SafeModeState   *filesystem.MetadataCheck  `json:"safe_mode_state,omitempty"` // Only present when the state file exists
+	Repaired        bool                       `json:"repaired"`
+}
+
+func NewStateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and repair blockbench's own state files",
+		Long: `Inspect and repair the files blockbench keeps about a server: backup metadata
+and the safe mode state.
+
+Every state file is validated against a schema embedded in blockbench when it is
+loaded. Files written by older versions are migrated in memory; 'state fsck'
+rewrites them and moves corrupt entries into a quarantine directory.`,
+	}
+
+	fsckCmd := &cobra.Command{
+		Use:   "fsck [server-path]",
+		Short: "Check state files and optionally migrate or quarantine bad entries",
+		Long: `Check backup metadata and safe mode state against their schemas.
+
+Without --repair, fsck only reports. With --repair, metadata written by an older
+version is rewritten at the current schema version, and corrupt metadata is moved
+with its backup directory into a 'quarantine' directory next to it. A corrupt
+safe mode state file is quarantined too, which leaves safe mode disabled; its
+snapshot stays available to 'backup restore'.
+
+fsck exits with an error while corrupt entries remain.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runStateFsck,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	fsckCmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	fsckCmd.Flags().Bool("repair", false, "Migrate old entries and quarantine corrupt ones")
+	fsckCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(fsckCmd)
+
+	return cmd
+}
+
+func runStateFsck(cmd *cobra.Command, args []string) error {
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	repair, _ := cmd.Flags().GetBool("repair")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	backupManager := filesystem.NewBackupManager(target.backupDir(cmd))
+	safeMode := addon.NewSafeModeManager(server)
+
+	result := &stateFsckResult{}
+	if result.Backups, err = backupManager.CheckBackups(); err != nil {
+		return fmt.Errorf("failed to check backups: %w", err)
+	}
+	if result.SafeModeBackups, err = safeMode.BackupManager().CheckBackups(); err != nil {
+		return fmt.Errorf("failed to check safe mode snapshots: %w", err)
+	}
+	result.SafeModeState = checkSafeModeState(safeMode)
+
+	if repair && !dryRun {
+		if err := backupManager.RepairBackups(result.Backups); err != nil {
+			return err
+		}
+		if err := safeMode.BackupManager().RepairBackups(result.SafeModeBackups); err != nil {
+			return err
+		}
+		if state := result.SafeModeState; state != nil && state.Status == filesystem.MetadataCorrupt {
+			if _, err := safeMode.QuarantineState(); err != nil {
+				return err
+			}
+			state.Action = "quarantined"
+		}
+		result.Repaired = true
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		renderStateFsck(result, repair && dryRun)
+	}
+
+	if corrupt := countUnrepaired(result); corrupt > 0 {
+		return fmt.Errorf("%d corrupt state file(s) found; run 'blockbench state fsck --repair %s' to quarantine them", corrupt, args[0])
+	}
+	return nil
+}
+
+// checkSafeModeState reports on the safe mode state file, or nil if safe mode is not enabled
+func checkSafeModeState(safeMode *addon.SafeModeManager) *filesystem.MetadataCheck {
+	if _, err := os.Stat(safeMode.StateFile()); os.IsNotExist(err) {
+		return nil
+	}
+
+	check := &filesystem.MetadataCheck{ID: "safe-mode", File: safeMode.StateFile(), Status: filesystem.MetadataOK}
+	problems, err := safeMode.CheckState()
+	if err != nil {
+		problems = []string{err.Error()}
+	}
+	if len(problems) > 0 {
+		check.Status = filesystem.MetadataCorrupt
+		check.Problems = problems
+	}
+	return check
+}
+
+// allStateChecks flattens a fsck result for rendering and counting
+func allStateChecks(result *stateFsckResult) []filesystem.MetadataCheck {
+	checks := append(append([]filesystem.MetadataCheck{}, result.Backups...), result.SafeModeBackups...)
+	if result.SafeModeState != nil {
+		checks = append(checks, *result.SafeModeState)
+	}
+	return checks
+}
+
+func countUnrepaired(result *stateFsckResult) int {
+	count := 0
+	for _, check := range allStateChecks(result) {
+		if check.Status == filesystem.MetadataCorrupt && check.Action == "" {
+			count++
+		}
+	}
+	return count
+}
+
+func renderStateFsck(result *stateFsckResult, dryRunRepair bool) {
+	checks := allStateChecks(result)
+	if len(checks) == 0 {
+		fmt.Println("No state files found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ENTRY\tSTATUS\tACTION\tPROBLEMS")
+	fmt.Fprintln(w, "-----\t------\t------\t--------")
+	for _, check := range checks {
+		action := check.Action
+		if action == "" {
+			action = "-"
+		}
+		problems := strings.Join(check.Problems, "; ")
+		if problems == "" {
+			problems = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.ID, check.Status, action, problems)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+
+	if dryRunRepair {
+		fmt.Println("DRY RUN: No files were changed")
+	}
+}
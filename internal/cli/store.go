@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/spf13/cobra"
+)
+
+func NewStoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store",
+		Short: "Manage the content store used by 'install --dedupe'",
+		Long: `Manage the server's content-addressed file store.
+
+'install --dedupe' hard-links installed pack files to blobs in
+server-path/.blockbench/store, so identical files shared by several packs or
+pack versions are stored once. Uninstalling a pack leaves its blobs in the
+store; 'store gc' removes blobs that no pack file links to any more.`,
+	}
+
+	gcCmd := &cobra.Command{
+		Use:               "gc [server-path]",
+		Short:             "Remove blobs no installed pack file links to",
+		Args:              cobra.ExactArgs(1),
+		RunE:              runStoreGC,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+	gcCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(gcCmd)
+
+	return cmd
+}
+
+func runStoreGC(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	paths, err := minecraft.NewServerPathsWithOptions(target.Path, target.Options)
+	if err != nil {
+		return fmt.Errorf("failed to initialize server: %w", err)
+	}
+
+	result, err := filesystem.NewContentStore(paths.StoreDir).GC(dryRun)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("DRY RUN: Would remove %d of %d blob(s), freeing %s\n", result.Removed, result.Blobs, filesystem.FormatSize(result.FreedBytes))
+		return nil
+	}
+	fmt.Printf("Removed %d of %d blob(s), freeing %s\n", result.Removed, result.Blobs, filesystem.FormatSize(result.FreedBytes))
+	return nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func NewTrustCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Manage the public keys trusted to sign addons",
+		Long: `Manage the minisign public keys whose signatures install accepts, stored in
+the config file in blockbench's config directory (see 'blockbench dirs').
+
+Addon publishers sign their files with minisign, producing a detached
+signature next to the addon:
+
+  minisign -S -m foo.mcaddon        # writes foo.mcaddon.minisig
+  blockbench trust add publisher minisign.pub
+  blockbench install foo.mcaddon /opt/bedrock --require-signature
+
+install verifies a signature whenever one is present and rejects the addon if
+it is invalid or made by a key that is not trusted.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [name] [public-key-file-or-key]",
+		Short: "Trust a minisign public key",
+		Long: `Trust a minisign public key under a name. The key is read from a .pub file,
+or given directly as the base64 line of one.`,
+		Args: cobra.ExactArgs(2),
+		RunE: runTrustAdd,
+	}
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List trusted public keys",
+		Args:  cobra.NoArgs,
+		RunE:  runTrustList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [name-or-key-id]",
+		Short: "Stop trusting a public key",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runTrustRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	return cmd
+}
+
+func runTrustAdd(cmd *cobra.Command, args []string) error {
+	name, key := args[0], args[1]
+	if info, err := os.Stat(key); err == nil && !info.IsDir() {
+		// #nosec G304 - the user names the public key file to trust
+		data, err := os.ReadFile(key)
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %w", err)
+		}
+		key = string(data)
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	publicKey, err := cfg.AddTrustedKey(name, key)
+	if err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would trust key %s as %s in %s\n", publicKey.KeyID(), name, cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Trusted key %s as %s\n", publicKey.KeyID(), name)
+	return nil
+}
+
+func runTrustList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	keys, err := cfg.PublicKeys()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		type trustedKey struct {
+			Name  string `json:"name"`
+			KeyID string `json:"key_id"`
+			Key   string `json:"key"`
+		}
+		output := make([]trustedKey, 0, len(keys))
+		for i, key := range keys {
+			output = append(output, trustedKey{Name: key.Name, KeyID: key.KeyID(), Key: cfg.TrustedKeys[i].Key})
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(keys) == 0 {
+		fmt.Println("No public keys are trusted")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKEY ID\tPUBLIC KEY")
+	fmt.Fprintln(w, "----\t------\t----------")
+	for i, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key.Name, key.KeyID(), cfg.TrustedKeys[i].Key)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+func runTrustRemove(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.RemoveTrustedKey(args[0]); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would stop trusting key %s in %s\n", args[0], cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped trusting key %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/history"
+	"github.com/makutaku/blockbench/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+func NewUndoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo [server-path]",
+		Short: "Reverse the most recent operations in the server's history",
+		Long: `Reverse the most recent install, update, uninstall, or rollback recorded in
+the server's history (see 'blockbench history'). Installs, updates, and
+uninstalls are reversed by restoring the backup taken before them; a rollback
+is reversed by restoring the backup of the files it overwrote, re-applying the
+state it replaced.
+
+--steps walks back several operations, most recent first. Failed operations
+and operations already undone are skipped, so running undo again continues
+further back. Undos are recorded in the history but cannot themselves be
+undone.
+
+The operations to undo are listed before asking for confirmation; use the
+global --dry-run flag to only list them. Like 'backup restore', restoring world
+configs deactivates packs activated by hand since the operation.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runUndo,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().Int("steps", 1, "Number of operations to undo")
+	cmd.Flags().Bool("json", false, "Output the undo results in JSON format (implies --yes)")
+	cmd.Flags().String("backup-dir", "", "Backup directory holding the operations' backups (default: server-path/backups)")
+	addNoHooksFlag(cmd)
+	addNoWebhooksFlag(cmd)
+
+	return cmd
+}
+
+// undoResult is the outcome of undoing one history entry
+type undoResult struct {
+	Undone history.Entry         `json:"undone"`
+	Result *addon.RollbackResult `json:"result,omitempty"`
+	Error  string                `json:"error,omitempty"`
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	steps, _ := cmd.Flags().GetInt("steps")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	yes, _ := cmd.Flags().GetBool("yes")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if steps < 1 {
+		return fmt.Errorf("--steps must be at least 1")
+	}
+
+	manager, err := newRollbackManager(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server := manager.Server()
+
+	entries, err := history.Load(server.Paths.HistoryFile)
+	if err != nil {
+		return err
+	}
+	undoable := history.Undoable(entries)
+	if len(undoable) == 0 {
+		return fmt.Errorf("nothing to undo: the server's history has no operations left to reverse")
+	}
+	if steps > len(undoable) {
+		return fmt.Errorf("cannot undo %d operation(s): the server's history has only %d left to reverse", steps, len(undoable))
+	}
+	undoable = undoable[:steps]
+
+	// Check every step has a backup before changing anything
+	for _, entry := range undoable {
+		if _, err := entry.UndoBackup(); err != nil {
+			return fmt.Errorf("cannot undo: %w", err)
+		}
+	}
+
+	if !jsonOutput {
+		fmt.Printf("Undoing %d operation(s), most recent first:\n", len(undoable))
+		for _, entry := range undoable {
+			backupID, _ := entry.UndoBackup()
+			fmt.Printf("  %s  %-9s  %s (restores %s)\n",
+				entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Operation, historySubject(entry), backupID)
+		}
+		if dryRun {
+			fmt.Println("DRY RUN: No files were changed")
+			return nil
+		}
+		if !yes {
+			confirmed, err := confirm(cmd, "Undo these operations?")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return fmt.Errorf("undo aborted by user")
+			}
+		}
+	}
+
+	runner, err := hookRunner(cmd)
+	if err != nil {
+		return err
+	}
+	options := addon.RollbackOptions{Verbose: verbose && !jsonOutput, DryRun: dryRun, Hooks: runner}
+
+	var results []undoResult
+	var undoErr error
+	for _, entry := range undoable {
+		result, err := manager.Undo(entry, options)
+		outcome := undoResult{Undone: entry, Result: result}
+		if !dryRun {
+			event := serverEvent(server, webhook.OperationRollback, err)
+			if result != nil {
+				event.BackupID = result.BackupID
+				event.Warnings = result.Warnings
+			}
+			event.Packs = entry.Packs
+			notifyWebhooks(cmd, event)
+		}
+		if err != nil {
+			outcome.Error = err.Error()
+			results = append(results, outcome)
+			undoErr = fmt.Errorf("undo of the %s at %s failed: %w", entry.Operation, entry.Time.Local().Format("2006-01-02 15:04:05"), err)
+			break
+		}
+		results = append(results, outcome)
+		if !jsonOutput {
+			for _, warning := range result.Warnings {
+				slog.Warn(warning)
+			}
+			fmt.Printf("Undid %s of %s\n", entry.Operation, historySubject(entry))
+		}
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	}
+	return undoErr
+}
@@ -2,10 +2,8 @@ package cli
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/makutaku/blockbench/internal/addon"
-	"github.com/makutaku/blockbench/internal/minecraft"
 	"github.com/spf13/cobra"
 )
 
@@ -14,32 +12,98 @@ func NewUninstallCommand() *cobra.Command {
 		Use:   "uninstall [addon-name] [server-path]",
 		Short: "Uninstall a Minecraft Bedrock addon from a server",
 		Long: `Uninstall an addon from a Minecraft Bedrock server by name.
-The addon will be safely removed with dependency checking and backup creation.`,
-		Args: cobra.ExactArgs(2),
-		RunE: runUninstall,
+The addon will be safely removed with dependency checking and backup creation.
+
+The name matches any installed pack whose name contains it, ignoring case.
+When none does, similar names are suggested; when several do, they are listed
+closest first, and with --interactive you can choose one of them.
+
+With --servers, server-path is omitted and the command runs on each named
+server profile (or all of them) in turn; see 'blockbench server'. Each server
+is backed up and rolled back on its own, so a failure on one server leaves the
+others changed, and a summary of every server's result is printed at the end.
+
+With --dry-run, --output json prints the plan as JSON instead: the directories
+that would be removed and the world config entries that would be dropped.`,
+		Args:              serverArgs(2),
+		RunE:              runUninstall,
+		ValidArgsFunction: completeArgs(completeInstalledPacks, completeServerPath),
 	}
 
 	cmd.Flags().String("uuid", "", "Uninstall addon by UUID instead of name")
+	_ = cmd.RegisterFlagCompletionFunc("uuid", completeInstalledPackUUIDs)
 	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
 	cmd.Flags().Bool("interactive", false, "Interactive mode - confirm each step before proceeding")
+	addPlanOutputFlag(cmd)
+	addPathPolicyFlag(cmd)
+	addNoHooksFlag(cmd)
+	addNoWebhooksFlag(cmd)
+	addRestartFlag(cmd)
+	addServerControlFlags(cmd)
+	addNotifyFlag(cmd)
+	addServersFlag(cmd)
 
 	return cmd
 }
 
 func runUninstall(cmd *cobra.Command, args []string) error {
-	identifier := args[0]
-	serverPath := args[1]
+	planOutput, err := planOutputMode(cmd)
+	if err != nil {
+		return err
+	}
+	servers, err := selectedServers(cmd)
+	if err != nil {
+		return err
+	}
+	if servers != nil {
+		return runOnServers(cmd, servers, func(server string) (any, error) {
+			result, err := uninstallOnServer(cmd, args[0], server)
+			if planOutput {
+				return uninstallPlan(result), err
+			}
+			return nil, printUninstallResult(cmd, result, err)
+		})
+	}
 
+	result, err := uninstallOnServer(cmd, args[0], args[1])
+	if planOutput {
+		return printDryRunPlan(uninstallPlan(result), err)
+	}
+	if err := printUninstallResult(cmd, result, err); err != nil {
+		return err
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); !dryRun {
+		notifyConsole(cmd, "Removed", result.RemovedPacks)
+		return restartContainer(cmd)
+	}
+	return nil
+}
+
+// uninstallOnServer uninstalls an addon from the server named by serverArg, a path or profile
+func uninstallOnServer(cmd *cobra.Command, identifier, serverArg string) (*addon.UninstallResult, error) {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	verbose, _ := cmd.Flags().GetBool("verbose")
-	interactive, _ := cmd.Flags().GetBool("interactive")
+	interactive := interactiveMode(cmd)
 	uuid, _ := cmd.Flags().GetString("uuid")
-	backupDir, _ := cmd.Flags().GetString("backup-dir")
-
-	// Set default backup directory
-	if backupDir == "" {
-		backupDir = filepath.Join(serverPath, "backups")
+	policy, err := pathPolicyFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRestartFlag(cmd); err != nil {
+		return nil, err
+	}
+	if _, err := notifyTarget(cmd); err != nil {
+		return nil, err
+	}
+	runner, err := hookRunner(cmd)
+	if err != nil {
+		return nil, err
 	}
+	target, err := resolveServerTarget(cmd, serverArg)
+	if err != nil {
+		return nil, err
+	}
+	backupDir := target.backupDir(cmd)
 
 	// Determine if we're searching by UUID
 	byUUID := uuid != ""
@@ -48,10 +112,16 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create server instance
-	server, err := minecraft.NewServer(serverPath)
+	server, err := target.newServer()
+	if err != nil {
+		return nil, err
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
 	if err != nil {
-		return fmt.Errorf("failed to initialize server: %w", err)
+		return nil, err
 	}
+	defer serverDone()
 
 	// Create uninstaller
 	uninstaller := addon.NewUninstaller(server, backupDir)
@@ -63,12 +133,32 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		BackupDir:   backupDir,
 		ByUUID:      byUUID,
 		Interactive: interactive,
+		Prompter:    prompter(cmd),
+		PathPolicy:  policy,
+		Hooks:       runner,
 	}
 
-	// Perform uninstallation
 	result, err := uninstaller.UninstallAddon(identifier, options)
+	notifyWebhooks(cmd, uninstallEvent(server, identifier, result, err))
+	return result, err
+}
 
-	// Display results
+// uninstallPlan returns the dry-run plan of an uninstall, or nil when it failed first
+func uninstallPlan(result *addon.UninstallResult) *addon.DryRunPlan {
+	if result == nil {
+		return nil
+	}
+	return result.Plan
+}
+
+// printUninstallResult prints the outcome of an uninstall and returns its error
+func printUninstallResult(cmd *cobra.Command, result *addon.UninstallResult, err error) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	if result == nil {
+		return err
+	}
 	if len(result.Warnings) > 0 {
 		fmt.Println("Warnings:")
 		for _, warning := range result.Warnings {
@@ -83,19 +173,18 @@ func runUninstall(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if result.Success {
-		if dryRun {
-			fmt.Println("DRY RUN: Uninstallation would succeed")
-		} else {
-			fmt.Printf("Successfully uninstalled %d pack(s)\n", len(result.RemovedPacks))
-			if verbose {
-				for _, pack := range result.RemovedPacks {
-					fmt.Printf("  - %s\n", pack)
-				}
+	if !result.Success {
+		return err
+	}
+	if dryRun {
+		fmt.Println("DRY RUN: Uninstallation would succeed")
+	} else {
+		fmt.Printf("Successfully uninstalled %d pack(s)\n", len(result.RemovedPacks))
+		if verbose {
+			for _, pack := range result.RemovedPacks {
+				fmt.Printf("  - %s\n", pack)
 			}
 		}
-		return nil
 	}
-
-	return err
+	return nil
 }
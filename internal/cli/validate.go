@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/spf13/cobra"
+)
+
+func NewValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [addon-file]",
+		Short: "Validate a Minecraft Bedrock addon without installing it",
+		Long: `Validate an addon file (.mcaddon/.mcpack) or unpacked addon directory without
+touching any server: archive structure, pack detection, and manifest.json checks.
+
+With --deep, every JSON file in each pack is also parsed, and well-known files
+(entities, items, recipes, and blocks in behavior packs; texture lists, texture
+atlases, and sound definitions in resource packs) are checked against embedded
+schemas, catching malformed files before they break a server at runtime.
+Resource pack assets are checked too: PNG and TGA images must decode, and
+texture atlases and sound definitions must reference files present in the pack.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runValidate,
+	}
+
+	cmd.Flags().Bool("deep", false, "Also validate pack JSON content against embedded schemas and check texture and sound assets")
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+	addExtractLimitFlags(cmd)
+
+	return cmd
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	deep, _ := cmd.Flags().GetBool("deep")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	limits, err := extractLimitsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	report, err := addon.ValidateAddon(args[0], deep, limits)
+	if err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		issueCount := 0
+		for _, pack := range report.Packs {
+			fmt.Printf("%s pack: %s (%s)\n", pack.Type, pack.Name, pack.UUID)
+			for _, issue := range pack.Issues {
+				fmt.Printf("  - %s\n", issue)
+				issueCount++
+			}
+		}
+		if report.Valid {
+			fmt.Println("Addon is valid")
+		} else {
+			fmt.Printf("Found %d problem(s)\n", issueCount)
+		}
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("addon failed validation")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/spf13/cobra"
+)
+
+func NewVerifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [server-path]",
+		Short: "Detect changes to installed pack files since installation",
+		Long: `Check every active pack's directory against the SHA-256 checksums recorded when
+blockbench installed it, reporting files modified, added, or deleted since.
+This detects corruption and edits made outside blockbench.
+
+Packs installed by hand or by an older blockbench have no checksums and are
+reported as untracked; reinstall them to start tracking. Packs whose
+checksums belong to another version than the active one are reported as
+stale. verify exits with an error when a pack is modified or its directory is
+missing.`,
+		Args:              cobra.ExactArgs(1),
+		RunE:              runVerify,
+		ValidArgsFunction: completeArgs(completeServerPath),
+	}
+
+	cmd.Flags().Bool("json", false, "Output in JSON format")
+
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	target, err := resolveServerTarget(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	results, err := server.VerifyPacks()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == minecraft.VerifyModified || result.Status == minecraft.VerifyMissing {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printVerifyResults(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d pack(s) changed since installation", failed)
+	}
+	return nil
+}
+
+// printVerifyResults prints a table of verified packs followed by the changed
+// files of each modified pack
+func printVerifyResults(results []minecraft.PackVerification) {
+	if len(results) == 0 {
+		fmt.Println("No packs are active on this server")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tUUID\tVERSION\tSTATUS\tDETAILS")
+	fmt.Fprintln(w, "----\t----\t----\t-------\t------\t-------")
+	for _, result := range results {
+		details := ""
+		if result.Status == minecraft.VerifyModified {
+			details = fmt.Sprintf("%d modified, %d added, %d deleted",
+				len(result.Files.Changed), len(result.Files.Added), len(result.Files.Removed))
+		} else if result.Status == minecraft.VerifyMissing {
+			details = result.Directory
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d.%d.%d\t%s\t%s\n", result.Name, result.Type, result.PackID,
+			result.Version[0], result.Version[1], result.Version[2], result.Status, details)
+	}
+	w.Flush()
+
+	for _, result := range results {
+		if result.Status != minecraft.VerifyModified {
+			continue
+		}
+		fmt.Printf("\n%s (%s):\n", result.Name, result.Directory)
+		for _, path := range result.Files.Changed {
+			fmt.Printf("  ~ %s\n", path)
+		}
+		for _, path := range result.Files.Added {
+			fmt.Printf("  + %s\n", path)
+		}
+		for _, path := range result.Files.Removed {
+			fmt.Printf("  - %s\n", path)
+		}
+	}
+}
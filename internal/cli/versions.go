@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+func NewUpgradeCommand() *cobra.Command {
+	cmd := newVersionSwitchCommand(true)
+	cmd.Use = "upgrade [pack-uuid] [server-path]"
+	cmd.Short = "Move an installed pack to a newer version kept in the server's version history"
+	cmd.Long = `Move an installed pack to a newer version kept in the server's version
+history, without needing the file it was installed from.
+
+Every version of a pack installed on the server is kept as an .mcpack archive
+under .blockbench/versions/<uuid>/ in the server directory, both the version an
+install brings and the version it replaces. After a downgrade, upgrade goes
+back up: to the newest retained version, or to the version given with --to.
+
+The installed pack directory is replaced with the retained version's files and
+its world config entries are updated. This is an install like any other: it is
+backed up first, recorded in the history, and can be undone with
+'blockbench undo'. As with install, a version with scripts needs
+--allow-scripts. --list prints the retained versions instead.`
+	return cmd
+}
+
+func NewDowngradeCommand() *cobra.Command {
+	cmd := newVersionSwitchCommand(false)
+	cmd.Use = "downgrade [pack-uuid] [server-path]"
+	cmd.Short = "Move an installed pack back to an older version kept in the server's version history"
+	cmd.Long = `Move an installed pack back to an older version kept in the server's version
+history, without needing the file it was installed from.
+
+Every version of a pack installed on the server is kept as an .mcpack archive
+under .blockbench/versions/<uuid>/ in the server directory, both the version an
+install brings and the version it replaces. downgrade goes back to the version
+given with --to, or without it to the newest retained version older than the
+installed one.
+
+The installed pack directory is replaced with the retained version's files, so
+files the newer version added are gone, and its world config entries are
+updated. This is an install like any other: it is backed up first, recorded in
+the history, and can be undone with 'blockbench undo'. As with install, a
+version with scripts needs --allow-scripts. --list prints the retained
+versions instead.`
+	return cmd
+}
+
+// newVersionSwitchCommand creates the upgrade (up) or downgrade command without its help text
+func newVersionSwitchCommand(up bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVersionSwitch(cmd, args, up)
+		},
+		ValidArgsFunction: completeArgs(completeInstalledPackUUIDs, completeServerPath),
+	}
+
+	cmd.Flags().String("to", "", "Version to move the pack to, as major.minor.patch")
+	cmd.Flags().Bool("list", false, "List the pack's retained versions instead of moving it")
+	cmd.Flags().String("backup-dir", "", "Custom backup directory (default: server-path/backups)")
+	cmd.Flags().Bool("verify", false, "Verify each copied file by SHA-256 hash and re-copy once on mismatch")
+	cmd.Flags().Bool("json", false, "Output the result in JSON format")
+	cmd.Flags().Bool("allow-scripts", false, "Allow a version with script modules or .js files (or set BLOCKBENCH_ALLOW_SCRIPTS=1)")
+	addNoHooksFlag(cmd)
+	addNoWebhooksFlag(cmd)
+	addRestartFlag(cmd)
+	addServerControlFlags(cmd)
+	addNotifyFlag(cmd)
+
+	return cmd
+}
+
+func runVersionSwitch(cmd *cobra.Command, args []string, up bool) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	verify, _ := cmd.Flags().GetBool("verify")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	list, _ := cmd.Flags().GetBool("list")
+	to, _ := cmd.Flags().GetString("to")
+	allowScripts, _ := cmd.Flags().GetBool("allow-scripts")
+	if !allowScripts {
+		allowScripts = scriptsAllowedByEnvironment()
+	}
+
+	var version *[3]int
+	if to != "" {
+		parsed, err := validation.ParseVersion(to)
+		if err != nil {
+			return fmt.Errorf("invalid --to version: %w", err)
+		}
+		version = &parsed
+	}
+	if list && version != nil {
+		return fmt.Errorf("--list and --to cannot be used together")
+	}
+	if err := checkRestartFlag(cmd); err != nil {
+		return err
+	}
+	if _, err := notifyTarget(cmd); err != nil {
+		return err
+	}
+	runner, err := hookRunner(cmd)
+	if err != nil {
+		return err
+	}
+	target, err := resolveServerTarget(cmd, args[1])
+	if err != nil {
+		return err
+	}
+	backupDir := target.backupDir(cmd)
+	server, err := target.newServer()
+	if err != nil {
+		return err
+	}
+
+	if list {
+		retained, err := server.RetainedVersions(args[0])
+		if err != nil {
+			return err
+		}
+		return printRetainedVersions(args[0], retained, jsonOutput)
+	}
+
+	plan, err := addon.PlanVersionSwitch(server, args[0], version, up)
+	if err != nil {
+		return err
+	}
+	if !jsonOutput {
+		fmt.Printf("%s: %s -> %s\n", plan.Name, formatVersion(plan.Installed), formatVersion(plan.Target.Version))
+	}
+
+	serverDone, err := guardRunningServer(cmd, server, dryRun)
+	if err != nil {
+		return err
+	}
+	defer serverDone()
+
+	installer := addon.NewInstaller(server, backupDir)
+	result, err := installer.SwitchVersion(plan, addon.InstallOptions{
+		DryRun:     dryRun,
+		Verbose:    verbose,
+		BackupDir:  backupDir,
+		VerifyCopy: verify,
+		Progress:   newProgress(jsonOutput),
+		Hooks:      runner,
+
+		AllowScripts: allowScripts,
+	})
+	if err != nil || !result.AlreadyInstalled {
+		notifyWebhooks(cmd, installEvent(server, plan.Target.Path, result, err))
+	}
+
+	if jsonOutput {
+		data, marshalErr := json.MarshalIndent(result, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+		if err != nil || !result.Success {
+			return err
+		}
+	} else if err := printInstallResult(cmd, result, err); err != nil {
+		return err
+	}
+	if result.Success && !dryRun {
+		notifyConsole(cmd, "Installed", result.InstalledPacks)
+		return restartContainer(cmd)
+	}
+	return nil
+}
+
+// printRetainedVersions prints the retained versions of a pack, newest first
+func printRetainedVersions(packID string, retained []minecraft.RetainedVersion, jsonOutput bool) error {
+	if jsonOutput {
+		if retained == nil {
+			retained = []minecraft.RetainedVersion{}
+		}
+		data, err := json.MarshalIndent(retained, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(retained) == 0 {
+		fmt.Printf("No versions of pack %s are retained\n", packID)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tRETAINED\tARCHIVE")
+	fmt.Fprintln(w, "-------\t--------\t-------")
+	for _, version := range retained {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", formatVersion(version.Version), version.RetainedAt.Format("2006-01-02 15:04:05"), version.Path)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/addon"
+	"github.com/makutaku/blockbench/internal/config"
+	"github.com/makutaku/blockbench/internal/history"
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+func NewWebhooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Manage webhooks notified when installs, uninstalls, and restores finish",
+		Long: `Manage webhook URLs that blockbench posts an event to whenever an install,
+uninstall, or backup restore completes or fails, stored in the config file in
+blockbench's config directory (see 'blockbench dirs').
+
+Each event names the server and world, the addon, the packs involved with
+their versions, the backup ID, whether a failed operation was rolled back, the
+error, and any warnings. The generic format posts that event as JSON; the
+discord and slack formats post a message for their incoming webhooks.
+
+A webhook that fails or times out (after 10s) produces a warning and never
+fails the operation. Dry runs notify no one, and --no-webhooks skips them.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [url]",
+		Short: "Post events to a URL",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWebhooksAdd,
+	}
+	addCmd.Flags().String("format", string(webhook.FormatGeneric), "Body format: generic, discord, or slack")
+	addCmd.Flags().StringSlice("operation", nil, "Only notify about these operations: install, uninstall, rollback (default all)")
+	addCmd.Flags().Bool("only-failed", false, "Only notify about failed operations")
+	cmd.AddCommand(addCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List webhooks (URL paths are hidden; use --json to show them)",
+		Args:  cobra.NoArgs,
+		RunE:  runWebhooksList,
+	}
+	listCmd.Flags().Bool("json", false, "Output in JSON format")
+	cmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [number]",
+		Short: "Remove a webhook by its number in 'webhooks list'",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWebhooksRemove,
+	}
+	cmd.AddCommand(removeCmd)
+
+	testCmd := &cobra.Command{
+		Use:   "test [number]",
+		Short: "Post a sample install event to a webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runWebhooksTest,
+	}
+	cmd.AddCommand(testCmd)
+
+	return cmd
+}
+
+func runWebhooksAdd(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	operations, _ := cmd.Flags().GetStringSlice("operation")
+	onlyFailed, _ := cmd.Flags().GetBool("only-failed")
+
+	hook := webhook.Webhook{URL: args[0], Format: webhook.Format(format), Operations: operations, OnlyFailed: onlyFailed}
+	if hook.Format == webhook.FormatGeneric {
+		hook.Format = "" // The default; keep the file minimal
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if err := cfg.AddWebhook(hook); err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would add webhook %s to %s\n", hook.Redacted(), cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Added webhook %d: %s\n", len(cfg.Webhooks), hook.Redacted())
+	return nil
+}
+
+func runWebhooksList(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		list := cfg.Webhooks
+		if list == nil {
+			list = []webhook.Webhook{}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(cfg.Webhooks) == 0 {
+		fmt.Println("No webhooks configured")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tURL\tFORMAT\tOPERATIONS")
+	fmt.Fprintln(w, "-\t---\t------\t----------")
+	for i, hook := range cfg.Webhooks {
+		operations := orDefault(strings.Join(hook.Operations, ","), "all")
+		if hook.OnlyFailed {
+			operations += " (failed only)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, hook.Redacted(), orDefault(string(hook.Format), string(webhook.FormatGeneric)), operations)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to flush output: %v\n", err)
+	}
+	return nil
+}
+
+func runWebhooksRemove(cmd *cobra.Command, args []string) error {
+	number, err := webhookNumber(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	hook, err := cfg.RemoveWebhook(number)
+	if err != nil {
+		return err
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would remove webhook %s from %s\n", hook.Redacted(), cfg.Path())
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed webhook %s\n", hook.Redacted())
+	return nil
+}
+
+func runWebhooksTest(cmd *cobra.Command, args []string) error {
+	number, err := webhookNumber(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if number < 1 || number > len(cfg.Webhooks) {
+		return fmt.Errorf("no webhook number %d: 'blockbench webhooks list' shows %d webhook(s)", number, len(cfg.Webhooks))
+	}
+	hook := cfg.Webhooks[number-1]
+	// Send the sample whatever the webhook's filters say
+	hook.Operations, hook.OnlyFailed = nil, false
+
+	event := webhook.Event{
+		Operation: webhook.OperationInstall,
+		Success:   true,
+		Time:      time.Now(),
+		Server:    "/path/to/server",
+		World:     "Bedrock level",
+		Addon:     "example.mcaddon",
+		Packs: []hooks.Pack{
+			{UUID: "00000000-0000-0000-0000-000000000000", Name: "Example Pack", Type: string(minecraft.PackTypeBehavior), Version: [3]int{1, 0, 0}},
+		},
+		BackupID: "backup_example",
+		Warnings: []string{"This is a test event from 'blockbench webhooks test'"},
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Printf("Would post a test event to %s\n", hook.Redacted())
+		return nil
+	}
+	if err := webhook.Send([]webhook.Webhook{hook}, event); err != nil {
+		return err
+	}
+	fmt.Printf("Posted a test event to %s\n", hook.Redacted())
+	return nil
+}
+
+// webhookNumber parses the number of a webhook in 'webhooks list'
+func webhookNumber(arg string) (int, error) {
+	number, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook number %q: use the number shown by 'blockbench webhooks list'", arg)
+	}
+	return number, nil
+}
+
+// addNoWebhooksFlag adds --no-webhooks to a command that notifies webhooks
+func addNoWebhooksFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-webhooks", false, "Don't notify the webhooks configured with 'blockbench webhooks'")
+}
+
+// serverEvent starts a webhook event about an operation on the server
+func serverEvent(server *minecraft.Server, operation string, err error) webhook.Event {
+	event := webhook.Event{
+		Operation: operation,
+		Success:   err == nil,
+		Time:      time.Now(),
+		Server:    server.Paths.ServerRoot,
+		World:     filepath.Base(filepath.Dir(server.Paths.WorldBehaviorPacks)),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// installEvent describes a finished install for webhooks
+func installEvent(server *minecraft.Server, addonFile string, result *addon.InstallResult, err error) webhook.Event {
+	event := serverEvent(server, webhook.OperationInstall, err)
+	event.Addon = addonFile
+	if result != nil {
+		event.Success = err == nil && result.Success
+		event.Packs = result.Packs
+		event.RolledBack = result.RolledBack
+		event.Warnings = result.Warnings
+		if result.BackupMetadata != nil {
+			event.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return event
+}
+
+// uninstallEvent describes a finished uninstall for webhooks
+func uninstallEvent(server *minecraft.Server, identifier string, result *addon.UninstallResult, err error) webhook.Event {
+	event := serverEvent(server, webhook.OperationUninstall, err)
+	event.Target = identifier
+	if result != nil {
+		event.Success = err == nil && result.Success
+		event.Packs = result.Packs
+		event.RolledBack = result.RolledBack
+		event.Warnings = result.Warnings
+		if result.BackupMetadata != nil {
+			event.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return event
+}
+
+// cleanEvent describes a finished removal of unreferenced packs for webhooks
+func cleanEvent(server *minecraft.Server, result *addon.CleanResult, err error) webhook.Event {
+	event := serverEvent(server, webhook.OperationUninstall, err)
+	event.Target = history.TargetUnreferenced
+	if result != nil {
+		event.Success = err == nil && result.Success
+		event.Packs = result.HookPacks()
+		event.RolledBack = result.RolledBack
+		if result.BackupMetadata != nil {
+			event.BackupID = result.BackupMetadata.ID
+		}
+	}
+	return event
+}
+
+// notifyWebhooks posts an event to the configured webhooks unless this is a
+// dry run or --no-webhooks is given. Failures are warnings only.
+func notifyWebhooks(cmd *cobra.Command, event webhook.Event) {
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return
+	}
+	if noWebhooks, _ := cmd.Flags().GetBool("no-webhooks"); noWebhooks {
+		return
+	}
+	// Without a home directory there is no config file and so no webhooks
+	path, err := config.DefaultPath()
+	if err != nil {
+		return
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		slog.Warn("Webhooks not notified", "error", err)
+		return
+	}
+	if err := webhook.Send(cfg.Webhooks, event); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			slog.Warn("Failed to notify " + line)
+		}
+	}
+}
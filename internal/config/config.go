@@ -0,0 +1,352 @@
+// Package config reads and writes blockbench's per-user config file, which
+// lives in the config directory resolved by userdirs.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/signature"
+	"github.com/makutaku/blockbench/internal/updates"
+	"github.com/makutaku/blockbench/internal/userdirs"
+	"github.com/makutaku/blockbench/internal/webhook"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// FileName is the name of the config file inside the config directory
+const FileName = "config.json"
+
+// Profile is a named server, so commands can take its name instead of a path
+type Profile struct {
+	Path      string                `json:"path"`
+	BackupDir string                `json:"backup_dir,omitempty"` // Empty means path/backups
+	World     string                `json:"world,omitempty"`      // Empty reads level-name from server.properties
+	PackDirs  minecraft.PackDirMode `json:"pack_dirs,omitempty"`  // Empty means development pack directories
+	Owner     string                `json:"owner,omitempty"`      // Default --chown for installs, as user or user:group
+	FileMode  string                `json:"file_mode,omitempty"`  // Default --chmod for installs, in octal
+
+	// IncrementalBackups makes backups store only the files changed since the previous one
+	IncrementalBackups bool `json:"incremental_backups,omitempty"`
+}
+
+// PathOptions returns the server path options the profile selects
+func (p Profile) PathOptions() minecraft.PathOptions {
+	return minecraft.PathOptions{World: p.World, PackDirs: p.PackDirs}
+}
+
+// Ownership returns the owner and mode the profile gives installed files, or
+// nil when it sets neither
+func (p Profile) Ownership() (*filesystem.Ownership, error) {
+	if p.Owner == "" && p.FileMode == "" {
+		return nil, nil
+	}
+	ownership := &filesystem.Ownership{}
+	var err error
+	if p.Owner != "" {
+		if ownership.Owner, err = filesystem.ParseOwner(p.Owner); err != nil {
+			return nil, err
+		}
+	}
+	if p.FileMode != "" {
+		if ownership.FileMode, err = filesystem.ParseFileMode(p.FileMode); err != nil {
+			return nil, err
+		}
+	}
+	return ownership, nil
+}
+
+// TrustedKey is a minisign public key whose signatures are accepted on addons
+type TrustedKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"` // Base64 minisign public key
+}
+
+// Config is the per-user config file
+type Config struct {
+	Profiles    map[string]Profile `json:"profiles,omitempty"`
+	TrustedKeys []TrustedKey       `json:"trusted_keys,omitempty"`
+	Hooks       []hooks.Hook       `json:"hooks,omitempty"`
+	Webhooks    []webhook.Webhook  `json:"webhooks,omitempty"`
+	Sources     []string           `json:"sources,omitempty"`    // Addon sources checked by check-updates
+	Registries  []string           `json:"registries,omitempty"` // Registries searched for addons by name
+
+	path string
+}
+
+// profileName restricts profile names so they can't be mistaken for paths
+var profileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// DefaultPath returns the config file in the user's config directory
+func DefaultPath() (string, error) {
+	dirs, err := userdirs.Resolve()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dirs.Config, FileName), nil
+}
+
+// Load reads the config file at path. A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	config := &Config{Profiles: make(map[string]Profile), path: path}
+
+	// #nosec G304 - path is the user's config file
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]Profile)
+	}
+	return config, nil
+}
+
+// LoadDefault reads the config file in the user's config directory
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// Path returns the file the config was loaded from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Save writes the config back to the file it was loaded from
+func (c *Config) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", c.path, err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// AddProfile registers a server under name, replacing an existing profile of that name
+func (c *Config) AddProfile(name string, profile Profile) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+	if profile.Path == "" {
+		return fmt.Errorf("profile %s needs a server path", name)
+	}
+	if _, err := minecraft.NewServerPathsWithOptions(profile.Path, profile.PathOptions()); err != nil {
+		return fmt.Errorf("invalid server for profile %s: %w", name, err)
+	}
+	if _, err := profile.Ownership(); err != nil {
+		return fmt.Errorf("invalid ownership for profile %s: %w", name, err)
+	}
+	c.Profiles[name] = profile
+	return nil
+}
+
+// RemoveProfile removes a profile
+func (c *Config) RemoveProfile(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("no server profile named %s", name)
+	}
+	delete(c.Profiles, name)
+	return nil
+}
+
+// Profile returns the profile registered under name
+func (c *Config) Profile(name string) (Profile, bool) {
+	profile, ok := c.Profiles[name]
+	return profile, ok
+}
+
+// ProfileNames returns the profile names in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddTrustedKey trusts a minisign public key under name. The key may be the
+// contents of a .pub file or just its base64 line.
+func (c *Config) AddTrustedKey(name, key string) (signature.PublicKey, error) {
+	if !profileName.MatchString(name) {
+		return signature.PublicKey{}, fmt.Errorf("invalid key name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+	parsed, err := signature.ParsePublicKey(key)
+	if err != nil {
+		return signature.PublicKey{}, fmt.Errorf("invalid public key: %w", err)
+	}
+	parsed.Name = name
+
+	for _, trusted := range c.TrustedKeys {
+		if trusted.Name == name {
+			return signature.PublicKey{}, fmt.Errorf("a key named %s is already trusted", name)
+		}
+		if existing, err := signature.ParsePublicKey(trusted.Key); err == nil && existing.ID == parsed.ID {
+			return signature.PublicKey{}, fmt.Errorf("key %s is already trusted as %s", parsed.KeyID(), trusted.Name)
+		}
+	}
+
+	c.TrustedKeys = append(c.TrustedKeys, TrustedKey{Name: name, Key: encodedKey(key)})
+	return parsed, nil
+}
+
+// RemoveTrustedKey stops trusting the key with the given name or key ID
+func (c *Config) RemoveTrustedKey(nameOrID string) error {
+	for i, trusted := range c.TrustedKeys {
+		key, err := signature.ParsePublicKey(trusted.Key)
+		if trusted.Name == nameOrID || (err == nil && strings.EqualFold(key.KeyID(), nameOrID)) {
+			c.TrustedKeys = append(c.TrustedKeys[:i], c.TrustedKeys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no trusted key named %s", nameOrID)
+}
+
+// PublicKeys parses the trusted keys
+func (c *Config) PublicKeys() ([]signature.PublicKey, error) {
+	keys := make([]signature.PublicKey, 0, len(c.TrustedKeys))
+	for _, trusted := range c.TrustedKeys {
+		key, err := signature.ParsePublicKey(trusted.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted key %s in %s: %w", trusted.Name, c.path, err)
+		}
+		key.Name = trusted.Name
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// AddHook appends a lifecycle hook; hooks of an event run in the order added
+func (c *Config) AddHook(hook hooks.Hook) error {
+	if err := hook.Validate(); err != nil {
+		return err
+	}
+	c.Hooks = append(c.Hooks, hook)
+	return nil
+}
+
+// RemoveHook removes the hook at a 1-based position in the hook list
+func (c *Config) RemoveHook(number int) (hooks.Hook, error) {
+	if number < 1 || number > len(c.Hooks) {
+		return hooks.Hook{}, fmt.Errorf("no hook number %d: 'blockbench hooks list' shows %d hook(s)", number, len(c.Hooks))
+	}
+	hook := c.Hooks[number-1]
+	c.Hooks = append(c.Hooks[:number-1], c.Hooks[number:]...)
+	return hook, nil
+}
+
+// AddWebhook appends a webhook that install, uninstall, and restore events are posted to
+func (c *Config) AddWebhook(hook webhook.Webhook) error {
+	if err := hook.Validate(); err != nil {
+		return err
+	}
+	c.Webhooks = append(c.Webhooks, hook)
+	return nil
+}
+
+// RemoveWebhook removes the webhook at a 1-based position in the webhook list
+func (c *Config) RemoveWebhook(number int) (webhook.Webhook, error) {
+	if number < 1 || number > len(c.Webhooks) {
+		return webhook.Webhook{}, fmt.Errorf("no webhook number %d: 'blockbench webhooks list' shows %d webhook(s)", number, len(c.Webhooks))
+	}
+	hook := c.Webhooks[number-1]
+	c.Webhooks = append(c.Webhooks[:number-1], c.Webhooks[number:]...)
+	return hook, nil
+}
+
+// AddSource adds an addon source: a source index URL, or a local index file
+// or repository directory, stored as an absolute path
+func (c *Config) AddSource(source string) (string, error) {
+	return addLocation(&c.Sources, "source", source)
+}
+
+// RemoveSource removes a source by its URL or path
+func (c *Config) RemoveSource(source string) error {
+	if !removeLocation(&c.Sources, source) {
+		return fmt.Errorf("no source %s: 'blockbench sources list' shows the configured sources", source)
+	}
+	return nil
+}
+
+// AddRegistry adds a registry: an index URL or a local index file, stored as
+// an absolute path. Registries added first take priority.
+func (c *Config) AddRegistry(registry string) (string, error) {
+	return addLocation(&c.Registries, "registry", registry)
+}
+
+// RemoveRegistry removes a registry by its URL or path
+func (c *Config) RemoveRegistry(registry string) error {
+	if !removeLocation(&c.Registries, registry) {
+		return fmt.Errorf("no registry %s: 'blockbench registry list' shows the configured registries", registry)
+	}
+	return nil
+}
+
+// addLocation appends a URL or local path to a list, rejecting duplicates
+func addLocation(list *[]string, kind, location string) (string, error) {
+	location, err := updates.ValidateSource(location)
+	if err != nil {
+		return "", err
+	}
+	for _, existing := range *list {
+		if existing == location {
+			return "", fmt.Errorf("%s %s is already configured", kind, location)
+		}
+	}
+	*list = append(*list, location)
+	return location, nil
+}
+
+// removeLocation removes a URL or path from a list, also matching a
+// relative path by its absolute form
+func removeLocation(list *[]string, location string) bool {
+	candidates := []string{location}
+	if abs, err := filepath.Abs(location); err == nil && abs != location {
+		candidates = append(candidates, abs)
+	}
+	for _, candidate := range candidates {
+		for i, existing := range *list {
+			if existing == candidate {
+				*list = append((*list)[:i], (*list)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodedKey keeps only the base64 line of a public key file
+func encodedKey(key string) string {
+	for _, line := range strings.Split(key, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			return line
+		}
+	}
+	return key
+}
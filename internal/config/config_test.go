@@ -0,0 +1,284 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/webhook"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-config-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	serverDir := filepath.Join(tempDir, "survival")
+	if err := os.MkdirAll(serverDir, 0750); err != nil {
+		t.Fatalf("Failed to create server dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+
+	path := filepath.Join(tempDir, "config", FileName)
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected a missing config to load empty: %v", err)
+	}
+	if len(config.Profiles) != 0 {
+		t.Fatalf("Expected no profiles, got %+v", config.Profiles)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		value   Profile
+		wantErr bool
+	}{
+		{"valid", "survival", Profile{Path: serverDir, BackupDir: "/backups/survival", PackDirs: minecraft.PackDirsRelease, FileMode: "644"}, false},
+		{"world override without server.properties entry", "creative", Profile{Path: serverDir, World: "Creative"}, false},
+		{"name that looks like a path", "../survival", Profile{Path: serverDir}, true},
+		{"missing path", "empty", Profile{}, true},
+		{"not a server", "nothing", Profile{Path: filepath.Join(tempDir, "missing")}, true},
+		{"unknown pack dir mode", "beta", Profile{Path: serverDir, PackDirs: "beta"}, true},
+		{"owner without group after colon", "owned", Profile{Path: serverDir, Owner: "0:"}, true},
+		{"file mode not octal", "moded", Profile{Path: serverDir, FileMode: "rw-r--r--"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.AddProfile(tt.profile, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	names := loaded.ProfileNames()
+	if len(names) != 2 || names[0] != "creative" || names[1] != "survival" {
+		t.Fatalf("Expected the creative and survival profiles, got %v", names)
+	}
+	profile, ok := loaded.Profile("survival")
+	if !ok || profile != config.Profiles["survival"] {
+		t.Errorf("Expected the survival profile to round-trip, got %+v", profile)
+	}
+
+	if err := loaded.RemoveProfile("creative"); err != nil {
+		t.Fatalf("RemoveProfile failed: %v", err)
+	}
+	if err := loaded.RemoveProfile("creative"); err == nil {
+		t.Error("Expected removing a missing profile to fail")
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0600); err != nil {
+		t.Fatalf("Failed to corrupt config: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected a corrupt config to fail to load")
+	}
+}
+
+func TestConfigTrustedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// minisign's own example key, as a .pub file
+	pub := "untrusted comment: minisign public key E7620F1842B4E81F\nRWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3\n"
+	key, err := config.AddTrustedKey("publisher", pub)
+	if err != nil {
+		t.Fatalf("AddTrustedKey failed: %v", err)
+	}
+	if key.KeyID() != "E7620F1842B4E81F" {
+		t.Errorf("Expected key ID E7620F1842B4E81F, got %s", key.KeyID())
+	}
+	if _, err := config.AddTrustedKey("again", pub); err == nil {
+		t.Error("Expected trusting the same key twice to fail")
+	}
+	if _, err := config.AddTrustedKey("bad", "not a key"); err == nil {
+		t.Error("Expected an invalid key to fail")
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	keys, err := loaded.PublicKeys()
+	if err != nil {
+		t.Fatalf("PublicKeys failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Name != "publisher" || keys[0].ID != key.ID {
+		t.Fatalf("Expected the publisher key to round-trip, got %+v", keys)
+	}
+
+	if err := loaded.RemoveTrustedKey("e7620f1842b4e81f"); err != nil {
+		t.Fatalf("RemoveTrustedKey by key ID failed: %v", err)
+	}
+	if err := loaded.RemoveTrustedKey("publisher"); err == nil {
+		t.Error("Expected removing a missing key to fail")
+	}
+}
+
+func TestConfigHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	snapshot := hooks.Hook{Event: hooks.PreInstall, Command: "/usr/local/bin/snapshot-world", Timeout: "5m"}
+	announce := hooks.Hook{Event: hooks.PostInstall, Command: "announce", Args: []string{"--channel", "ops"}}
+	for _, hook := range []hooks.Hook{snapshot, announce} {
+		if err := config.AddHook(hook); err != nil {
+			t.Fatalf("AddHook failed: %v", err)
+		}
+	}
+	if err := config.AddHook(hooks.Hook{Event: "during-install", Command: "x"}); err == nil {
+		t.Error("Expected a hook with an unknown event to fail")
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Hooks) != 2 || loaded.Hooks[1].String() != "announce --channel ops" {
+		t.Fatalf("Expected both hooks to round-trip, got %+v", loaded.Hooks)
+	}
+
+	removed, err := loaded.RemoveHook(1)
+	if err != nil || removed.Command != snapshot.Command {
+		t.Fatalf("Expected to remove the snapshot hook, got %+v, %v", removed, err)
+	}
+	if _, err := loaded.RemoveHook(2); err == nil {
+		t.Error("Expected removing a missing hook to fail")
+	}
+}
+
+func TestConfigWebhooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	discord := webhook.Webhook{URL: "https://discord.com/api/webhooks/1/token", Format: webhook.FormatDiscord}
+	if err := config.AddWebhook(discord); err != nil {
+		t.Fatalf("AddWebhook failed: %v", err)
+	}
+	if err := config.AddWebhook(webhook.Webhook{URL: "discord.com/api/webhooks/1/token"}); err == nil {
+		t.Error("Expected a URL without a scheme to fail")
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Webhooks) != 1 || loaded.Webhooks[0].URL != discord.URL || loaded.Webhooks[0].Format != webhook.FormatDiscord {
+		t.Fatalf("Expected the webhook to round-trip, got %+v", loaded.Webhooks)
+	}
+	if _, err := loaded.RemoveWebhook(1); err != nil {
+		t.Fatalf("RemoveWebhook failed: %v", err)
+	}
+	if _, err := loaded.RemoveWebhook(1); err == nil {
+		t.Error("Expected removing a missing webhook to fail")
+	}
+}
+
+func TestConfigSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	index := "https://addons.example.com/index.json"
+	if _, err := config.AddSource(index); err != nil {
+		t.Fatalf("AddSource failed: %v", err)
+	}
+	if _, err := config.AddSource(index); err == nil {
+		t.Error("Expected a duplicate source to fail")
+	}
+	if _, err := config.AddSource(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing directory to fail")
+	}
+	repo, err := config.AddSource(dir)
+	if err != nil || repo != dir {
+		t.Fatalf("AddSource(%s) = %s, %v", dir, repo, err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Sources) != 2 || loaded.Sources[0] != index {
+		t.Fatalf("Expected the sources to round-trip, got %v", loaded.Sources)
+	}
+	if err := loaded.RemoveSource(index); err != nil {
+		t.Fatalf("RemoveSource failed: %v", err)
+	}
+	if err := loaded.RemoveSource(index); err == nil {
+		t.Error("Expected removing a missing source to fail")
+	}
+}
+
+func TestConfigRegistries(t *testing.T) {
+	dir := t.TempDir()
+	config, err := Load(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	first := "https://registry.example.com/index.json"
+	second := filepath.Join(dir, "index.json")
+	if err := os.WriteFile(second, []byte(`{"addons": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, registry := range []string{first, second} {
+		if _, err := config.AddRegistry(registry); err != nil {
+			t.Fatalf("AddRegistry(%s) failed: %v", registry, err)
+		}
+	}
+	if _, err := config.AddRegistry(first); err == nil {
+		t.Error("Expected a duplicate registry to fail")
+	}
+	if len(config.Registries) != 2 || config.Registries[0] != first {
+		t.Fatalf("Expected registries in the order added, got %v", config.Registries)
+	}
+	if len(config.Sources) != 0 {
+		t.Errorf("Expected registries to be kept apart from sources, got %v", config.Sources)
+	}
+
+	if err := config.RemoveRegistry(second); err != nil {
+		t.Fatalf("RemoveRegistry failed: %v", err)
+	}
+	if err := config.RemoveRegistry(second); err == nil {
+		t.Error("Expected removing a missing registry to fail")
+	}
+}
@@ -0,0 +1,201 @@
+// Package docker is a minimal client for the parts of the Docker Engine API
+// blockbench needs to manage servers running in containers, such as
+// itzg/minecraft-bedrock-server: inspecting a container's mounts to find the
+// host directory behind its server root, and restarting it.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the Docker daemon socket used when DOCKER_HOST is not set
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// DefaultServerRoot is where itzg/minecraft-bedrock-server keeps the server
+const DefaultServerRoot = "/data"
+
+// Client talks to the Docker Engine API
+type Client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// Mount is a volume or bind mount of a container
+type Mount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name,omitempty"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	RW          bool   `json:"RW"`
+}
+
+// Container is the part of a container inspection blockbench uses
+type Container struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+	Mounts []Mount `json:"Mounts"`
+}
+
+// NewClient connects to DOCKER_HOST, or the default socket. unix:// and
+// tcp:// hosts are supported; TLS is not.
+func NewClient() (*Client, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DefaultHost
+	}
+	return newClient(host)
+}
+
+func newClient(host string) (*Client, error) {
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+
+	switch parsed.Scheme {
+	case "unix":
+		socket := parsed.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Client{http: &http.Client{Transport: transport, Timeout: 2 * time.Minute}, baseURL: "http://docker"}, nil
+	case "tcp", "http":
+		return &Client{http: &http.Client{Timeout: 2 * time.Minute}, baseURL: "http://" + parsed.Host}, nil
+	default:
+		return nil, fmt.Errorf("unsupported DOCKER_HOST %q (expected unix:// or tcp://)", host)
+	}
+}
+
+// Inspect returns a container by name or ID
+func (c *Client) Inspect(name string) (*Container, error) {
+	resp, err := c.http.Get(c.baseURL + "/containers/" + url.PathEscape(name) + "/json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the Docker daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+
+	var container Container
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return nil, fmt.Errorf("failed to decode container %s: %w", name, err)
+	}
+	return &container, nil
+}
+
+// Restart restarts a container, giving it timeout to stop before it is killed
+func (c *Client) Restart(name string, timeout time.Duration) error {
+	endpoint := fmt.Sprintf("%s/containers/%s/restart?t=%d", c.baseURL, url.PathEscape(name), int(timeout.Seconds()))
+	resp, err := c.http.Post(endpoint, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("failed to reach the Docker daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusNoContent); err != nil {
+		return fmt.Errorf("failed to restart container %s: %w", name, err)
+	}
+	return nil
+}
+
+// checkResponse turns an unexpected status into an error carrying the daemon's message
+func checkResponse(resp *http.Response, expected int) error {
+	if resp.StatusCode == expected {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("%s (HTTP %d)", apiErr.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+// HostPath maps a path inside the container to the host directory behind the
+// mount that holds it, so blockbench can edit it in place. Paths that are not
+// on a volume or bind mount live only in the container's own filesystem and
+// cannot be reached this way.
+func (c *Container) HostPath(containerPath string) (string, error) {
+	containerPath = path.Clean(containerPath)
+
+	var best *Mount
+	for i := range c.Mounts {
+		mount := &c.Mounts[i]
+		dest := path.Clean(mount.Destination)
+		if containerPath != dest && !strings.HasPrefix(containerPath, strings.TrimSuffix(dest, "/")+"/") {
+			continue
+		}
+		if best == nil || len(dest) > len(path.Clean(best.Destination)) {
+			best = mount
+		}
+	}
+
+	if best == nil {
+		return "", fmt.Errorf("%s in container %s is not on a volume or bind mount; mount the server directory so blockbench can reach it", containerPath, strings.TrimPrefix(c.Name, "/"))
+	}
+	if best.Source == "" {
+		return "", fmt.Errorf("mount %s of container %s has no host directory", best.Destination, strings.TrimPrefix(c.Name, "/"))
+	}
+	if !best.RW {
+		return "", fmt.Errorf("mount %s of container %s is read-only", best.Destination, strings.TrimPrefix(c.Name, "/"))
+	}
+
+	rel := strings.TrimPrefix(strings.TrimPrefix(containerPath, path.Clean(best.Destination)), "/")
+	return filepath.Join(best.Source, filepath.FromSlash(rel)), nil
+}
+
+// Exec starts a command in a running container without waiting for it to finish
+func (c *Client) Exec(name string, command []string) error {
+	body, err := json.Marshal(map[string]any{"Cmd": command})
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(c.baseURL+"/containers/"+url.PathEscape(name)+"/exec", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach the Docker daemon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return fmt.Errorf("failed to create exec in container %s: %w", name, err)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return fmt.Errorf("failed to decode exec of container %s: %w", name, err)
+	}
+
+	start, err := c.http.Post(c.baseURL+"/exec/"+url.PathEscape(created.ID)+"/start", "application/json", strings.NewReader(`{"Detach": true}`))
+	if err != nil {
+		return fmt.Errorf("failed to reach the Docker daemon: %w", err)
+	}
+	defer start.Body.Close()
+
+	if err := checkResponse(start, http.StatusOK); err != nil {
+		return fmt.Errorf("failed to start exec in container %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContainerHostPath(t *testing.T) {
+	container := &Container{
+		Name: "/bedrock",
+		Mounts: []Mount{
+			{Type: "volume", Source: "/var/lib/docker/volumes/bds/_data", Destination: "/data", RW: true},
+			{Type: "bind", Source: "/srv/worlds", Destination: "/data/worlds", RW: true},
+			{Type: "bind", Source: "/etc/readonly", Destination: "/config", RW: false},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		wantErr  string
+	}{
+		{"mount root", "/data", "/var/lib/docker/volumes/bds/_data", ""},
+		{"trailing slash", "/data/", "/var/lib/docker/volumes/bds/_data", ""},
+		{"below a mount", "/data/behavior_packs", "/var/lib/docker/volumes/bds/_data/behavior_packs", ""},
+		{"longest mount wins", "/data/worlds/W", "/srv/worlds/W", ""},
+		{"similar prefix is not a match", "/database", "", "not on a volume"},
+		{"unmounted path", "/opt/bedrock", "", "not on a volume"},
+		{"read-only mount", "/config", "", "read-only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := container.HostPath(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %q, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HostPath failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	var restarted string
+	var execCmd []string
+	var execStarted bool
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/bedrock/json":
+			_, _ = w.Write([]byte(`{"Id": "abc", "Name": "/bedrock", "State": {"Running": true},
+				"Mounts": [{"Type": "bind", "Source": "/srv/bds", "Destination": "/data", "RW": true}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/bedrock/exec":
+			var body struct{ Cmd []string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			execCmd = body.Cmd
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id": "exec1"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/exec/exec1/start":
+			execStarted = true
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/bedrock/restart":
+			restarted = r.URL.Query().Get("t")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "No such container: missing"}`))
+		}
+	}))
+	defer daemon.Close()
+
+	client, err := newClient(strings.Replace(daemon.URL, "http://", "tcp://", 1))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	container, err := client.Inspect("bedrock")
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if !container.State.Running || len(container.Mounts) != 1 {
+		t.Errorf("Unexpected container %+v", container)
+	}
+	if dir, err := container.HostPath(DefaultServerRoot); err != nil || dir != "/srv/bds" {
+		t.Errorf("Expected /srv/bds, got %s, %v", dir, err)
+	}
+
+	if _, err := client.Inspect("missing"); err == nil || !strings.Contains(err.Error(), "No such container") {
+		t.Errorf("Expected the daemon's message in the error, got %v", err)
+	}
+
+	if err := client.Restart("bedrock", 30*time.Second); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	if restarted != "30" {
+		t.Errorf("Expected a 30 second stop timeout, got %q", restarted)
+	}
+
+	if err := client.Exec("bedrock", []string{"send-command", "say hi"}); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if len(execCmd) != 2 || execCmd[1] != "say hi" || !execStarted {
+		t.Errorf("Expected the command to be created and started, got %v, started %v", execCmd, execStarted)
+	}
+
+	if _, err := newClient("ssh://host"); err == nil {
+		t.Error("Expected an error for an unsupported DOCKER_HOST scheme")
+	}
+}
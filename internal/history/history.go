@@ -0,0 +1,212 @@
+// Package history keeps a server's operation history: one JSON record per
+// install, update, uninstall, or rollback, appended to history.jsonl in the
+// server's .blockbench directory.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// FileName is the history file in a server's .blockbench directory
+const FileName = "history.jsonl"
+
+// Operations a history entry can record
+const (
+	OperationInstall   = "install"
+	OperationUpdate    = "update" // An install that replaced installed versions of its packs
+	OperationUninstall = "uninstall"
+	OperationRollback  = "rollback" // A backup restored with 'backup restore' or after a failed batch
+	OperationUndo      = "undo"     // An earlier operation reversed with 'undo'
+)
+
+// TargetUnreferenced is the target of the uninstall 'clean --unreferenced
+// --delete' records, which removes every pack no world config lists
+const TargetUnreferenced = "unreferenced"
+
+// Operations lists the operations in the order they are documented
+var Operations = []string{OperationInstall, OperationUpdate, OperationUninstall, OperationRollback, OperationUndo}
+
+// Entry is one operation on a server
+type Entry struct {
+	Time       time.Time    `json:"time"`
+	Operation  string       `json:"operation"`
+	User       string       `json:"user,omitempty"`
+	Success    bool         `json:"success"`
+	Addon      string       `json:"addon,omitempty"`  // Addon file or directory, for installs and updates
+	Target     string       `json:"target,omitempty"` // Pack name or UUID given, for uninstalls; "batch" for a failed batch's rollback; TargetUnreferenced for 'clean'
+	Packs      []hooks.Pack `json:"packs,omitempty"`
+	BackupID   string       `json:"backup_id,omitempty"`   // Backup taken before the operation, or restored by a rollback
+	RolledBack bool         `json:"rolled_back,omitempty"` // The failed operation's backup was restored
+	Error      string       `json:"error,omitempty"`
+
+	UndoBackupID string     `json:"undo_backup_id,omitempty"` // Backup of the files a rollback or undo overwrote
+	Undoes       *time.Time `json:"undoes,omitempty"`         // Time of the entry an undo reversed
+}
+
+// UndoBackup returns the backup that reverses the entry's operation: the
+// backup taken before an install, update, or uninstall, or the backup of the
+// files a rollback overwrote
+func (e Entry) UndoBackup() (string, error) {
+	var backupID string
+	switch e.Operation {
+	case OperationInstall, OperationUpdate, OperationUninstall:
+		backupID = e.BackupID
+	case OperationRollback:
+		backupID = e.UndoBackupID
+	default:
+		return "", fmt.Errorf("%s operations cannot be undone", e.Operation)
+	}
+	if backupID == "" {
+		return "", fmt.Errorf("the %s at %s recorded no backup to undo it with", e.Operation, e.Time.Local().Format("2006-01-02 15:04:05"))
+	}
+	return backupID, nil
+}
+
+// Undoable returns the operations undo can reverse, most recent first:
+// successful operations other than undos that no successful undo has
+// reversed
+func Undoable(entries []Entry) []Entry {
+	undone := make(map[time.Time]bool)
+	for _, entry := range entries {
+		if entry.Operation == OperationUndo && entry.Success && entry.Undoes != nil {
+			undone[entry.Undoes.UTC()] = true
+		}
+	}
+
+	var undoable []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Operation == OperationUndo || !entry.Success || undone[entry.Time.UTC()] {
+			continue
+		}
+		undoable = append(undoable, entry)
+	}
+	return undoable
+}
+
+// Append adds an entry to the history file, creating it if needed
+func Append(path string, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	// #nosec G304 - path is the history file in the server's state directory
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filesystem.DefaultFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// Load reads every entry of the history file, oldest first. A missing file
+// is an empty history.
+func Load(path string) ([]Entry, error) {
+	// #nosec G304 - path is the history file in the server's state directory
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d of %s: %w", line, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return entries, nil
+}
+
+// Filter selects history entries; zero fields match everything
+type Filter struct {
+	Operation  string    // Only this operation
+	Pack       string    // Only entries naming a pack with this UUID, or a name containing it
+	Since      time.Time // Only entries at or after this time
+	FailedOnly bool      // Only failed operations
+}
+
+// Match reports whether the entry passes the filter
+func (f Filter) Match(entry Entry) bool {
+	if f.Operation != "" && entry.Operation != f.Operation {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if f.FailedOnly && entry.Success {
+		return false
+	}
+	if f.Pack != "" && !mentionsPack(entry, f.Pack) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the entries that pass the filter
+func (f Filter) Apply(entries []Entry) []Entry {
+	var matched []Entry
+	for _, entry := range entries {
+		if f.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+func mentionsPack(entry Entry, pack string) bool {
+	needle := strings.ToLower(pack)
+	for _, p := range entry.Packs {
+		if strings.EqualFold(p.UUID, pack) || strings.Contains(strings.ToLower(p.Name), needle) {
+			return true
+		}
+	}
+	return entry.Target != "" && strings.Contains(strings.ToLower(entry.Target), needle)
+}
+
+// CurrentUser names the user running blockbench, or returns an empty string
+// when it cannot be determined
+func CurrentUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,125 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".blockbench", FileName)
+
+	entries, err := Load(path)
+	if err != nil || entries != nil {
+		t.Fatalf("Expected a missing history to be empty, got %v, %v", entries, err)
+	}
+
+	first := Entry{
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Operation: OperationInstall,
+		User:      "steve",
+		Success:   true,
+		Addon:     "/tmp/foo.mcaddon",
+		Packs:     []hooks.Pack{{UUID: "11111111-1111-1111-1111-111111111111", Name: "Foo BP", Type: "behavior", Version: [3]int{1, 0, 0}}},
+		BackupID:  "backup_1",
+	}
+	second := Entry{Operation: OperationUninstall, Target: "Foo", Error: "no pack found"}
+	for _, entry := range []Entry{first, second} {
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	entries, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].BackupID != "backup_1" || entries[0].Packs[0].Version != [3]int{1, 0, 0} || !entries[0].Time.Equal(first.Time) {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Time.IsZero() {
+		t.Error("Expected Append to stamp entries without a time")
+	}
+
+	if err := os.WriteFile(path, []byte("{\"operation\":\"install\"}\nnot json\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected the malformed line to be reported, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Time: now.Add(-48 * time.Hour), Operation: OperationInstall, Success: true,
+			Packs: []hooks.Pack{{UUID: "11111111-1111-1111-1111-111111111111", Name: "Foo BP"}}},
+		{Time: now.Add(-time.Hour), Operation: OperationUpdate, Success: false,
+			Packs: []hooks.Pack{{UUID: "22222222-2222-2222-2222-222222222222", Name: "Bar RP"}}},
+		{Time: now, Operation: OperationUninstall, Success: true, Target: "foo"},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"everything", Filter{}, 3},
+		{"operation", Filter{Operation: OperationUpdate}, 1},
+		{"pack by name", Filter{Pack: "foo"}, 2},
+		{"pack by UUID", Filter{Pack: "22222222-2222-2222-2222-222222222222"}, 1},
+		{"since", Filter{Since: now.Add(-2 * time.Hour)}, 2},
+		{"failed", Filter{FailedOnly: true}, 1},
+		{"combined", Filter{Pack: "foo", Operation: OperationInstall}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.filter.Apply(entries)); got != tt.want {
+				t.Errorf("Expected %d entries, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUndoable(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	undone := at(2)
+
+	entries := []Entry{
+		{Time: at(0), Operation: OperationInstall, Success: true, BackupID: "backup_a"},
+		{Time: at(1), Operation: OperationUninstall, Success: false, BackupID: "backup_b", RolledBack: true},
+		{Time: at(2), Operation: OperationUpdate, Success: true, BackupID: "backup_c"},
+		{Time: at(3), Operation: OperationUndo, Success: true, BackupID: "backup_c", Undoes: &undone},
+		{Time: at(4), Operation: OperationRollback, Success: true, BackupID: "backup_a", UndoBackupID: "backup_d"},
+	}
+
+	undoable := Undoable(entries)
+	if len(undoable) != 2 || !undoable[0].Time.Equal(at(4)) || !undoable[1].Time.Equal(at(0)) {
+		t.Fatalf("Expected the rollback then the install, got %+v", undoable)
+	}
+
+	tests := []struct {
+		entry   Entry
+		want    string
+		wantErr bool
+	}{
+		{undoable[0], "backup_d", false},
+		{undoable[1], "backup_a", false},
+		{Entry{Operation: OperationRollback, BackupID: "backup_a"}, "", true},
+		{Entry{Operation: OperationUndo, BackupID: "backup_c"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := tt.entry.UndoBackup()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("UndoBackup() of %s = %q, %v", tt.entry.Operation, got, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package hooks runs user-configured executables at points in blockbench's
+// install and uninstall lifecycle, passing each a JSON description of the
+// event on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Event is a point in an operation at which hooks run
+type Event string
+
+const (
+	PreInstall    Event = "pre-install"    // After validation, before the backup and any change; a failing hook aborts the install
+	PostInstall   Event = "post-install"   // After a successful install
+	PreUninstall  Event = "pre-uninstall"  // Before the backup and any change; a failing hook aborts the uninstall
+	PostUninstall Event = "post-uninstall" // After a successful uninstall
+	PostRollback  Event = "post-rollback"  // After a backup was restored, automatically on failure or by 'backup restore'
+)
+
+// Events lists every hook event in lifecycle order
+var Events = []Event{PreInstall, PostInstall, PreUninstall, PostUninstall, PostRollback}
+
+// DefaultTimeout is how long a hook may run when it sets no timeout
+const DefaultTimeout = time.Minute
+
+// ParseEvent parses a hook event name
+func ParseEvent(name string) (Event, error) {
+	for _, event := range Events {
+		if string(event) == name {
+			return event, nil
+		}
+	}
+	names := make([]string, len(Events))
+	for i, event := range Events {
+		names[i] = string(event)
+	}
+	return "", fmt.Errorf("unknown hook event %q: use one of %s", name, strings.Join(names, ", "))
+}
+
+// Blocking reports whether a failing hook for the event aborts the operation
+func (e Event) Blocking() bool {
+	return e == PreInstall || e == PreUninstall
+}
+
+// Hook is an executable run on an event
+type Hook struct {
+	Event   Event    `json:"event"`
+	Command string   `json:"command"`           // Executable path, or a name looked up in PATH
+	Args    []string `json:"args,omitempty"`    // Arguments passed to the command
+	Timeout string   `json:"timeout,omitempty"` // Go duration such as 30s; empty means DefaultTimeout
+}
+
+// Validate checks the hook's event, command, and timeout
+func (h Hook) Validate() error {
+	if _, err := ParseEvent(string(h.Event)); err != nil {
+		return err
+	}
+	if h.Command == "" {
+		return fmt.Errorf("%s hook needs a command", h.Event)
+	}
+	_, err := h.timeout()
+	return err
+}
+
+func (h Hook) timeout() (time.Duration, error) {
+	if h.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid hook timeout %q: use a duration such as 30s", h.Timeout)
+	}
+	return timeout, nil
+}
+
+// String formats the hook's command line
+func (h Hook) String() string {
+	return strings.Join(append([]string{h.Command}, h.Args...), " ")
+}
+
+// Pack identifies a pack an event is about
+type Pack struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version [3]int `json:"version"`
+}
+
+// Payload is the JSON document a hook receives on stdin
+type Payload struct {
+	Event     Event     `json:"event"`
+	Time      time.Time `json:"time"`
+	Server    string    `json:"server"`              // Server root directory
+	World     string    `json:"world,omitempty"`     // World directory name
+	Operation string    `json:"operation,omitempty"` // For post-rollback: install, uninstall, or restore
+	Addon     string    `json:"addon,omitempty"`     // Addon file or directory being installed
+	Packs     []Pack    `json:"packs,omitempty"`
+	BackupID  string    `json:"backup_id,omitempty"`
+	Error     string    `json:"error,omitempty"` // For post-rollback: why the operation was rolled back
+	Warnings  []string  `json:"warnings,omitempty"`
+}
+
+// Runner runs the configured hooks. A nil Runner runs nothing.
+type Runner struct {
+	Hooks  []Hook
+	Output io.Writer // Receives the hooks' stdout and stderr; nil means os.Stderr
+}
+
+// NewRunner creates a runner for hooks, or returns nil when there are none
+func NewRunner(hooks []Hook) *Runner {
+	if len(hooks) == 0 {
+		return nil
+	}
+	return &Runner{Hooks: hooks}
+}
+
+// Run runs every hook for the payload's event in order. For blocking events
+// the first failing hook stops the rest and its error is returned; otherwise
+// every hook runs and their errors are joined.
+func (r *Runner) Run(payload Payload) error {
+	if r == nil {
+		return nil
+	}
+	if payload.Time.IsZero() {
+		payload.Time = time.Now()
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s hook payload: %w", payload.Event, err)
+	}
+
+	var errs []error
+	for _, hook := range r.Hooks {
+		if hook.Event != payload.Event {
+			continue
+		}
+		if err := r.run(hook, payload, input); err != nil {
+			err = fmt.Errorf("%s hook %s failed: %w", hook.Event, hook.String(), err)
+			if payload.Event.Blocking() {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runner) run(hook Hook, payload Payload, input []byte) error {
+	timeout, err := hook.timeout()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output := r.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// #nosec G204 - hooks are executables the user configured to run
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Env = append(os.Environ(),
+		"BLOCKBENCH_EVENT="+string(payload.Event),
+		"BLOCKBENCH_SERVER="+payload.Server,
+	)
+	if payload.Server != "" {
+		cmd.Dir = payload.Server
+	}
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	for _, event := range Events {
+		parsed, err := ParseEvent(string(event))
+		if err != nil || parsed != event {
+			t.Errorf("ParseEvent(%q) = %q, %v", event, parsed, err)
+		}
+	}
+	if _, err := ParseEvent("mid-install"); err == nil {
+		t.Error("Expected an unknown event to fail")
+	}
+}
+
+func TestHookValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    Hook
+		wantErr bool
+	}{
+		{"valid", Hook{Event: PostInstall, Command: "notify"}, false},
+		{"timeout", Hook{Event: PreInstall, Command: "snapshot", Timeout: "5m"}, false},
+		{"unknown event", Hook{Event: "install", Command: "notify"}, true},
+		{"missing command", Hook{Event: PostInstall}, true},
+		{"bad timeout", Hook{Event: PostInstall, Command: "notify", Timeout: "soon"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hook.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tempDir := t.TempDir()
+	payloadFile := filepath.Join(tempDir, "payload.json")
+	save := Hook{Event: PostInstall, Command: "sh", Args: []string{"-c", `cat > "$0"; echo "ran $BLOCKBENCH_EVENT"`, payloadFile}}
+	fail := Hook{Event: PreInstall, Command: "sh", Args: []string{"-c", "exit 3"}}
+	slow := Hook{Event: PostRollback, Command: "sleep", Args: []string{"5"}, Timeout: "100ms"}
+
+	var output bytes.Buffer
+	runner := &Runner{Hooks: []Hook{save, fail, slow}, Output: &output}
+
+	payload := Payload{
+		Event:    PostInstall,
+		Server:   tempDir,
+		Packs:    []Pack{{UUID: "11111111-1111-1111-1111-111111111111", Name: "Test BP", Type: "behavior", Version: [3]int{1, 0, 0}}},
+		BackupID: "backup_1",
+	}
+	if err := runner.Run(payload); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "ran post-install") {
+		t.Errorf("Expected the hook's output, got %q", output.String())
+	}
+
+	data, err := os.ReadFile(payloadFile)
+	if err != nil {
+		t.Fatalf("Expected the hook to save its payload: %v", err)
+	}
+	var received Payload
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatalf("Hook received invalid JSON: %v", err)
+	}
+	if received.Event != PostInstall || received.BackupID != "backup_1" || len(received.Packs) != 1 || received.Time.IsZero() {
+		t.Errorf("Unexpected payload %+v", received)
+	}
+
+	if err := runner.Run(Payload{Event: PreInstall, Server: tempDir}); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Expected the pre-install hook to fail, got %v", err)
+	}
+	if err := runner.Run(Payload{Event: PostRollback, Server: tempDir}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected the post-rollback hook to time out, got %v", err)
+	}
+
+	// Events without hooks, and a nil runner, run nothing
+	if err := runner.Run(Payload{Event: PostUninstall}); err != nil {
+		t.Errorf("Expected no error without hooks, got %v", err)
+	}
+	var none *Runner
+	if err := none.Run(payload); err != nil {
+		t.Errorf("Expected a nil runner to do nothing, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Package lockfile reads and writes blockbench.lock, the list of addons a
+// server should have, and works out what sync has to change to match it.
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+const (
+	// FileName is the default lockfile name, looked up in the working directory
+	FileName = "blockbench.lock"
+
+	// SchemaVersion is the lockfile format written by this version
+	SchemaVersion = 1
+
+	// downloadTimeout bounds fetching one addon from a URL
+	downloadTimeout = 5 * time.Minute
+)
+
+// Pack is a pack an addon in the lockfile provides
+type Pack struct {
+	UUID    string             `json:"uuid"`
+	Name    string             `json:"name"`
+	Version [3]int             `json:"version"`
+	Type    minecraft.PackType `json:"type"`
+}
+
+// Addon is an addon file the server should have installed. Source is a path
+// relative to the lockfile, an absolute path, or an http(s) URL; SHA256 pins
+// its contents so every server provisioned from the lockfile gets the same packs.
+type Addon struct {
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+	Packs  []Pack `json:"packs"`
+}
+
+// Lockfile is the desired addon state of a server
+type Lockfile struct {
+	SchemaVersion int     `json:"schema_version"`
+	Addons        []Addon `json:"addons"`
+
+	path string
+}
+
+// Load reads the lockfile at path. A missing file is an empty lockfile.
+func Load(path string) (*Lockfile, error) {
+	lock := &Lockfile{SchemaVersion: SchemaVersion, path: path}
+
+	// #nosec G304 - path is the lockfile the user asked for
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("lockfile %s has schema version %d; this blockbench supports up to %d", path, lock.SchemaVersion, SchemaVersion)
+	}
+	for i, addon := range lock.Addons {
+		if addon.Source == "" || addon.SHA256 == "" {
+			return nil, fmt.Errorf("lockfile %s: addon %d needs a source and a sha256", path, i+1)
+		}
+	}
+	return lock, nil
+}
+
+// Path returns the file the lockfile was loaded from
+func (l *Lockfile) Path() string {
+	return l.path
+}
+
+// Save writes the lockfile back to the file it was loaded from
+func (l *Lockfile) Save() error {
+	l.SchemaVersion = SchemaVersion
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", l.path, err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Add records an addon, replacing an entry with the same source or one that
+// provides any of the same packs
+func (l *Lockfile) Add(addon Addon) {
+	provided := make(map[string]bool)
+	for _, pack := range addon.Packs {
+		provided[pack.UUID] = true
+	}
+
+	kept := l.Addons[:0]
+	for _, existing := range l.Addons {
+		replaced := existing.Source == addon.Source
+		for _, pack := range existing.Packs {
+			replaced = replaced || provided[pack.UUID]
+		}
+		if !replaced {
+			kept = append(kept, existing)
+		}
+	}
+	l.Addons = append(kept, addon)
+}
+
+// IsURL reports whether a source is downloaded rather than read from disk
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// SourcePath returns where a local source lives: relative sources are
+// relative to the lockfile's directory
+func (l *Lockfile) SourcePath(source string) string {
+	if filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(filepath.Dir(l.path), filepath.FromSlash(source))
+}
+
+// Fetch returns a local file with the addon's contents, verified against its
+// SHA256. URL sources are downloaded into cacheDir, where a previously
+// downloaded copy with the right digest is reused.
+func (l *Lockfile) Fetch(addon Addon, cacheDir string) (string, error) {
+	path := l.SourcePath(addon.Source)
+	if IsURL(addon.Source) {
+		path = filepath.Join(cacheDir, addon.SHA256+urlExt(addon.Source))
+		if digest, err := filesystem.HashFile(path); err == nil && digest == addon.SHA256 {
+			return path, nil
+		}
+		if err := download(addon.Source, path); err != nil {
+			return "", err
+		}
+	}
+
+	digest, err := filesystem.HashFile(path)
+	if err != nil {
+		return "", err
+	}
+	if digest != addon.SHA256 {
+		return "", fmt.Errorf("%s has SHA-256 %s, but the lockfile pins %s", addon.Source, digest, addon.SHA256)
+	}
+	return path, nil
+}
+
+// Download fetches a URL into cacheDir, for adding it to the lockfile, and
+// returns the cached file and its SHA-256 digest
+func Download(url, cacheDir string) (string, string, error) {
+	tmp := filepath.Join(cacheDir, "download"+urlExt(url))
+	if err := download(url, tmp); err != nil {
+		return "", "", err
+	}
+	digest, err := filesystem.HashFile(tmp)
+	if err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(cacheDir, digest+urlExt(url))
+	if err := os.Rename(tmp, path); err != nil {
+		return "", "", fmt.Errorf("failed to cache %s: %w", url, err)
+	}
+	return path, digest, nil
+}
+
+// urlExt returns the file extension of a URL's path, which the installer
+// uses to tell .mcaddon from .mcpack files
+func urlExt(source string) string {
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(parsed.Path)
+}
+
+// download fetches url into path through a temporary file
+func download(url, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create download cache: %w", err)
+	}
+
+	client := &http.Client{Timeout: downloadTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	tmp := path + ".tmp"
+	// #nosec G304 - tmp is inside the download cache
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filesystem.DefaultFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
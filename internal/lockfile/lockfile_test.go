@@ -0,0 +1,124 @@
+package lockfile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+func TestLockfileRoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-lockfile-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, FileName)
+	lock, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected a missing lockfile to load empty: %v", err)
+	}
+	if len(lock.Addons) != 0 {
+		t.Fatalf("Expected no addons, got %+v", lock.Addons)
+	}
+
+	lock.Add(Addon{Source: "a.mcaddon", SHA256: "aa", Packs: []Pack{{UUID: "u1", Version: [3]int{1, 0, 0}}}})
+	lock.Add(Addon{Source: "b.mcaddon", SHA256: "bb", Packs: []Pack{{UUID: "u2"}}})
+	// A new file providing u1 replaces a.mcaddon
+	lock.Add(Addon{Source: "a-1.1.mcaddon", SHA256: "cc", Packs: []Pack{{UUID: "u1", Version: [3]int{1, 1, 0}}}})
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Addons) != 2 || loaded.Addons[0].Source != "b.mcaddon" || loaded.Addons[1].Source != "a-1.1.mcaddon" {
+		t.Fatalf("Unexpected addons after round trip: %+v", loaded.Addons)
+	}
+	if loaded.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, loaded.SchemaVersion)
+	}
+
+	for name, content := range map[string]string{
+		"corrupt":        "{broken",
+		"future schema":  `{"schema_version": 99, "addons": []}`,
+		"missing digest": `{"schema_version": 1, "addons": [{"source": "a.mcaddon"}]}`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write lockfile: %v", err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected a %s lockfile to fail to load", name)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-lockfile-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	content := []byte("addon contents")
+	addonPath := filepath.Join(tempDir, "addons", "pack.mcaddon")
+	if err := os.MkdirAll(filepath.Dir(addonPath), 0750); err != nil {
+		t.Fatalf("Failed to create addon dir: %v", err)
+	}
+	if err := os.WriteFile(addonPath, content, 0600); err != nil {
+		t.Fatalf("Failed to write addon: %v", err)
+	}
+	digest, err := filesystem.HashFile(addonPath)
+	if err != nil {
+		t.Fatalf("Failed to hash addon: %v", err)
+	}
+
+	lock, err := Load(filepath.Join(tempDir, FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cacheDir := filepath.Join(tempDir, "cache")
+
+	path, err := lock.Fetch(Addon{Source: "addons/pack.mcaddon", SHA256: digest}, cacheDir)
+	if err != nil || path != addonPath {
+		t.Fatalf("Expected the relative source to resolve to %s, got %s (%v)", addonPath, path, err)
+	}
+	if _, err := lock.Fetch(Addon{Source: "addons/pack.mcaddon", SHA256: "0000"}, cacheDir); err == nil {
+		t.Error("Expected a digest mismatch to fail")
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/pack.mcaddon" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	url := Addon{Source: server.URL + "/pack.mcaddon", SHA256: digest}
+	for i := 0; i < 2; i++ {
+		path, err := lock.Fetch(url, cacheDir)
+		if err != nil {
+			t.Fatalf("Fetch from URL failed: %v", err)
+		}
+		if filepath.Dir(path) != cacheDir {
+			t.Errorf("Expected the download in %s, got %s", cacheDir, path)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the cached download to be reused, got %d requests", requests)
+	}
+
+	if _, err := lock.Fetch(Addon{Source: server.URL + "/missing.mcaddon", SHA256: "1111"}, cacheDir); err == nil {
+		t.Error("Expected a failed download to fail")
+	}
+}
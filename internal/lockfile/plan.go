@@ -0,0 +1,99 @@
+package lockfile
+
+import (
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// ChangeKind is what sync does to one pack
+type ChangeKind string
+
+const (
+	ChangeInstall   ChangeKind = "install"   // Pack in the lockfile but not on the server
+	ChangeUpgrade   ChangeKind = "upgrade"   // Server has an older version than the lockfile
+	ChangeDowngrade ChangeKind = "downgrade" // Server has a newer version than the lockfile
+	ChangeRepair    ChangeKind = "repair"    // Server has the locked version, but its files drifted (missing or unreadable)
+	ChangeRemove    ChangeKind = "remove"    // Pack on the server but not in the lockfile
+)
+
+// Change is one pack sync installs, replaces, or removes
+type Change struct {
+	Kind   ChangeKind         `json:"kind"`
+	UUID   string             `json:"uuid"`
+	Name   string             `json:"name"`
+	Type   minecraft.PackType `json:"type"`
+	From   *[3]int            `json:"from,omitempty"`   // Installed version; nil for installs
+	To     *[3]int            `json:"to,omitempty"`     // Locked version; nil for removals
+	Source string             `json:"source,omitempty"` // Addon providing the locked version
+	Detail string             `json:"detail,omitempty"` // What drifted, for repairs
+}
+
+// Plan is what sync changes to make a server match a lockfile
+type Plan struct {
+	Changes []Change `json:"changes"`
+	Install []Addon  `json:"-"` // Addons to (re)install, in lockfile order
+	Remove  []string `json:"-"` // UUIDs of installed packs to remove
+}
+
+// Empty reports whether the server already matches the lockfile
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// PlanSync compares the installed packs of a server with a lockfile. An addon
+// is reinstalled as a whole when any of its packs is missing, at another
+// version, or has drifted from its pack files, and installed packs no addon
+// in the lockfile provides are removed.
+func PlanSync(lock *Lockfile, installed []minecraft.InstalledPack) *Plan {
+	plan := &Plan{Changes: make([]Change, 0)}
+
+	installedByUUID := make(map[string]minecraft.InstalledPack, len(installed))
+	for _, pack := range installed {
+		installedByUUID[pack.PackID] = pack
+	}
+
+	locked := make(map[string]bool)
+	for _, addon := range lock.Addons {
+		outdated := false
+		for _, pack := range addon.Packs {
+			locked[pack.UUID] = true
+			to := pack.Version
+			change := Change{UUID: pack.UUID, Name: pack.Name, Type: pack.Type, To: &to, Source: addon.Source}
+
+			current, ok := installedByUUID[pack.UUID]
+			switch {
+			case !ok:
+				change.Kind = ChangeInstall
+			case validation.CompareVersions(current.Version, pack.Version) < 0:
+				change.Kind = ChangeUpgrade
+			case validation.CompareVersions(current.Version, pack.Version) > 0:
+				change.Kind = ChangeDowngrade
+			case current.Status != "" && current.Status != minecraft.PackStatusOK:
+				change.Kind = ChangeRepair
+				change.Detail = string(current.Status)
+			default:
+				continue
+			}
+			if ok {
+				from := current.Version
+				change.From = &from
+			}
+			plan.Changes = append(plan.Changes, change)
+			outdated = true
+		}
+		if outdated {
+			plan.Install = append(plan.Install, addon)
+		}
+	}
+
+	for _, pack := range installed {
+		if locked[pack.PackID] {
+			continue
+		}
+		from := pack.Version
+		plan.Changes = append(plan.Changes, Change{Kind: ChangeRemove, UUID: pack.PackID, Name: pack.Name, Type: pack.Type, From: &from})
+		plan.Remove = append(plan.Remove, pack.PackID)
+	}
+
+	return plan
+}
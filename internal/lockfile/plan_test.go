@@ -0,0 +1,67 @@
+package lockfile
+
+import (
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+func TestPlanSync(t *testing.T) {
+	lock := &Lockfile{Addons: []Addon{
+		{Source: "current.mcaddon", Packs: []Pack{{UUID: "current", Version: [3]int{1, 0, 0}}}},
+		{Source: "new.mcaddon", Packs: []Pack{{UUID: "new-bp", Version: [3]int{1, 0, 0}}, {UUID: "new-rp", Version: [3]int{1, 0, 0}}}},
+		{Source: "upgrade.mcaddon", Packs: []Pack{{UUID: "old", Version: [3]int{2, 0, 0}}, {UUID: "old-rp", Version: [3]int{2, 0, 0}}}},
+		{Source: "pinned.mcaddon", Packs: []Pack{{UUID: "newer", Version: [3]int{1, 0, 0}}}},
+		{Source: "drifted.mcaddon", Packs: []Pack{{UUID: "drifted", Version: [3]int{1, 0, 0}}}},
+	}}
+	installed := []minecraft.InstalledPack{
+		{PackID: "current", Version: [3]int{1, 0, 0}},
+		{PackID: "old", Version: [3]int{1, 5, 0}},
+		{PackID: "old-rp", Version: [3]int{2, 0, 0}},
+		{PackID: "newer", Version: [3]int{1, 0, 1}},
+		{PackID: "drifted", Version: [3]int{1, 0, 0}, Status: minecraft.PackStatusDirectoryMissing},
+		{PackID: "stray", Name: "Stray", Version: [3]int{0, 1, 0}},
+	}
+
+	plan := PlanSync(lock, installed)
+
+	want := map[string]ChangeKind{
+		"new-bp":  ChangeInstall,
+		"new-rp":  ChangeInstall,
+		"old":     ChangeUpgrade,
+		"newer":   ChangeDowngrade,
+		"drifted": ChangeRepair,
+		"stray":   ChangeRemove,
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), plan.Changes)
+	}
+	for _, change := range plan.Changes {
+		if want[change.UUID] != change.Kind {
+			t.Errorf("Pack %s: expected %s, got %s", change.UUID, want[change.UUID], change.Kind)
+		}
+	}
+
+	var sources []string
+	for _, addon := range plan.Install {
+		sources = append(sources, addon.Source)
+	}
+	if len(sources) != 4 || sources[0] != "new.mcaddon" || sources[1] != "upgrade.mcaddon" || sources[2] != "pinned.mcaddon" || sources[3] != "drifted.mcaddon" {
+		t.Errorf("Expected the new, upgrade, pinned, and drifted addons to be installed, got %v", sources)
+	}
+	if len(plan.Remove) != 1 || plan.Remove[0] != "stray" {
+		t.Errorf("Expected only the stray pack to be removed, got %v", plan.Remove)
+	}
+
+	if synced := PlanSync(lock, []minecraft.InstalledPack{
+		{PackID: "current", Version: [3]int{1, 0, 0}},
+		{PackID: "new-bp", Version: [3]int{1, 0, 0}},
+		{PackID: "new-rp", Version: [3]int{1, 0, 0}},
+		{PackID: "old", Version: [3]int{2, 0, 0}},
+		{PackID: "old-rp", Version: [3]int{2, 0, 0}},
+		{PackID: "newer", Version: [3]int{1, 0, 0}},
+		{PackID: "drifted", Version: [3]int{1, 0, 0}, Status: minecraft.PackStatusOK},
+	}); !synced.Empty() {
+		t.Errorf("Expected a synced server to need no changes, got %+v", synced.Changes)
+	}
+}
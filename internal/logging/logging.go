@@ -0,0 +1,221 @@
+// Package logging configures the process-wide slog logger from the global
+// --log-level, --log-file, and --log-format flags. Warnings and errors always
+// reach stderr in a readable form; with a log file, every record at the
+// selected level is also appended to it so operations leave an audit trail.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// Formats of log records
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options selects where log records go and which are kept
+type Options struct {
+	Level  string // debug, info, warn, or error; empty means info with a log file and warn without
+	File   string // File records are appended to; empty logs to stderr only
+	Format string // text or json; empty means text
+}
+
+// ParseLevel parses a level name
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q: use debug, info, warn, or error", name)
+}
+
+// Setup installs the default logger for the options, writing console records
+// to console. The returned function closes the log file.
+func Setup(opts Options, console io.Writer) (func() error, error) {
+	level := slog.LevelWarn
+	if opts.File != "" {
+		level = slog.LevelInfo
+	}
+	if opts.Level != "" {
+		parsed, err := ParseLevel(opts.Level)
+		if err != nil {
+			return nil, err
+		}
+		level = parsed
+	}
+	if opts.Format != "" && opts.Format != FormatText && opts.Format != FormatJSON {
+		return nil, fmt.Errorf("invalid log format %q: use text or json", opts.Format)
+	}
+
+	if opts.File == "" {
+		var handler slog.Handler
+		if opts.Format == FormatJSON {
+			handler = slog.NewJSONHandler(console, &slog.HandlerOptions{Level: level})
+		} else {
+			handler = NewConsoleHandler(console, level)
+		}
+		slog.SetDefault(slog.New(handler))
+		return func() error { return nil }, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.File), filesystem.DefaultDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	// #nosec G304 - the user names the log file
+	file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filesystem.DefaultFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	var fileHandler slog.Handler
+	if opts.Format == FormatJSON {
+		fileHandler = slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level})
+	} else {
+		fileHandler = slog.NewTextHandler(file, &slog.HandlerOptions{Level: level})
+	}
+	slog.SetDefault(slog.New(fanout{fileHandler, NewConsoleHandler(console, slog.LevelWarn)}))
+	return file.Close, nil
+}
+
+// consoleHandler writes records for people reading a terminal: the message
+// after a level prefix such as "Warning:", followed by key=value attributes
+type consoleHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string // Group prefix for attribute keys
+}
+
+// NewConsoleHandler creates a handler that writes readable records at or
+// above level to w
+func NewConsoleHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return &consoleHandler{w: w, mu: &sync.Mutex{}, level: level}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	b.WriteString(levelPrefix(record.Level))
+	b.WriteString(record.Message)
+	for _, attr := range h.attrs {
+		writeAttr(&b, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&b, h.prefix, attr)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func levelPrefix(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "Error: "
+	case level >= slog.LevelWarn:
+		return "Warning: "
+	case level >= slog.LevelInfo:
+		return "Info: "
+	default:
+		return "Debug: "
+	}
+}
+
+func writeAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, member := range attr.Value.Group() {
+			writeAttr(b, prefix+attr.Key+".", member)
+		}
+		return
+	}
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, attr.Key, value)
+}
+
+// fanout sends each record to every handler that accepts its level
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range f {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, handler := range f {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, handler := range f {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, handler := range f {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestConsoleHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewConsoleHandler(&out, slog.LevelWarn))
+
+	logger.Info("not shown")
+	logger.Warn("Failed to cleanup temporary files", "path", "/tmp/x y", "count", 2)
+	logger.With("server", "/srv").WithGroup("pack").Error("Install failed", "uuid", "1111")
+
+	expected := "Warning: Failed to cleanup temporary files path=\"/tmp/x y\" count=2\n" +
+		"Error: Install failed server=/srv pack.uuid=1111\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected console output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defaultLogger := slog.Default()
+	defer slog.SetDefault(defaultLogger)
+
+	if _, err := Setup(Options{Level: "loud"}, os.Stderr); err == nil {
+		t.Error("Expected an invalid level to fail")
+	}
+	if _, err := Setup(Options{Format: "xml"}, os.Stderr); err == nil {
+		t.Error("Expected an invalid format to fail")
+	}
+
+	// Without a file, warnings and up go to the console
+	var console bytes.Buffer
+	closeLog, err := Setup(Options{}, &console)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	slog.Info("installing")
+	slog.Warn("checksum mismatch")
+	closeLog()
+	if console.String() != "Warning: checksum mismatch\n" {
+		t.Errorf("Unexpected console output %q", console.String())
+	}
+
+	// With a file, info records are appended to it as JSON and the console
+	// still gets warnings
+	console.Reset()
+	path := filepath.Join(t.TempDir(), "logs", "blockbench.log")
+	closeLog, err = Setup(Options{File: path, Format: FormatJSON}, &console)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	slog.Debug("not logged")
+	slog.Info("Installed pack", "uuid", "1111")
+	slog.Warn("checksum mismatch")
+	if err := closeLog(); err != nil {
+		t.Fatalf("Closing the log failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log records, got %q", data)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Log record is not JSON: %v", err)
+	}
+	if record["msg"] != "Installed pack" || record["uuid"] != "1111" || record["level"] != "INFO" {
+		t.Errorf("Unexpected log record %v", record)
+	}
+	if console.String() != "Warning: checksum mismatch\n" {
+		t.Errorf("Unexpected console output %q", console.String())
+	}
+}
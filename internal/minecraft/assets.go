@@ -0,0 +1,265 @@
+package minecraft
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Extensions the game tries when resolving extensionless texture and sound paths
+var (
+	textureExtensions = []string{".png", ".tga", ".jpg", ".jpeg"}
+	soundExtensions   = []string{".ogg", ".wav", ".fsb"}
+)
+
+// textureAtlasFiles are the resource pack files whose texture_data entries reference images
+var textureAtlasFiles = []string{"textures/terrain_texture.json", "textures/item_texture.json"}
+
+// ValidatePackAssets checks the binary assets of a resource pack: every PNG and
+// TGA image must decode, texture atlas entries must reference images in the
+// pack, and sound definitions must reference audio files in the pack.
+// Packs of other types have no asset checks.
+func ValidatePackAssets(packDir string, packType PackType) ([]ContentIssue, error) {
+	if packType != PackTypeResource {
+		return nil, nil
+	}
+
+	var issues []ContentIssue
+
+	imageIssues, err := checkImages(packDir)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, imageIssues...)
+
+	for _, atlas := range textureAtlasFiles {
+		doc, ok := readPackJSON(packDir, atlas, &issues)
+		if !ok {
+			continue
+		}
+		textureData, _ := lookupJSONPath(doc, "texture_data")
+		for _, ref := range collectTextureRefs(textureData) {
+			if !packFileExists(packDir, ref, textureExtensions) {
+				issues = append(issues, ContentIssue{File: atlas, Problem: fmt.Sprintf("references missing texture %q", ref)})
+			}
+		}
+	}
+
+	const soundDefinitions = "sounds/sound_definitions.json"
+	if doc, ok := readPackJSON(packDir, soundDefinitions, &issues); ok {
+		definitions, found := lookupJSONPath(doc, "sound_definitions")
+		if !found {
+			definitions = doc // Legacy files keep definitions at the top level
+		}
+		for _, ref := range collectSoundRefs(definitions) {
+			if !packFileExists(packDir, ref, soundExtensions) {
+				issues = append(issues, ContentIssue{File: soundDefinitions, Problem: fmt.Sprintf("references missing sound %q", ref)})
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// checkImages decodes every PNG and validates every TGA header in the pack
+func checkImages(packDir string) ([]ContentIssue, error) {
+	var issues []ContentIssue
+	err := filepath.WalkDir(packDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		var check func(string) error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".png":
+			check = decodePNG
+		case ".tga":
+			check = checkTGA
+		default:
+			return nil
+		}
+
+		if err := check(path); err != nil {
+			rel, relErr := filepath.Rel(packDir, path)
+			if relErr != nil {
+				return relErr
+			}
+			issues = append(issues, ContentIssue{File: filepath.ToSlash(rel), Problem: fmt.Sprintf("image does not decode: %v", err)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan pack images: %w", err)
+	}
+	return issues, nil
+}
+
+func decodePNG(path string) error {
+	// #nosec G304 - path comes from walking the pack directory
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = png.Decode(file)
+	return err
+}
+
+// checkTGA validates a TGA header and, for uncompressed images, the pixel data length.
+// The standard library has no TGA decoder, so RLE-compressed data is not expanded.
+func checkTGA(path string) error {
+	// #nosec G304 - path comes from walking the pack directory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	const headerSize = 18
+	if len(data) < headerSize {
+		return fmt.Errorf("file too short for a TGA header")
+	}
+
+	idLength := int(data[0])
+	imageType := data[2]
+	width := int(binary.LittleEndian.Uint16(data[12:14]))
+	height := int(binary.LittleEndian.Uint16(data[14:16]))
+	bitsPerPixel := int(data[16])
+
+	switch imageType {
+	case 1, 2, 3, 9, 10, 11:
+	default:
+		return fmt.Errorf("unsupported TGA image type %d", imageType)
+	}
+	if width == 0 || height == 0 {
+		return fmt.Errorf("TGA image has zero size")
+	}
+	switch bitsPerPixel {
+	case 8, 15, 16, 24, 32:
+	default:
+		return fmt.Errorf("unsupported TGA pixel depth %d", bitsPerPixel)
+	}
+
+	// Uncompressed true-color and grayscale images have a fixed pixel data size
+	if imageType == 2 || imageType == 3 {
+		colorMapLength := int(binary.LittleEndian.Uint16(data[5:7])) * ((int(data[7]) + 7) / 8)
+		expected := headerSize + idLength + colorMapLength + width*height*((bitsPerPixel+7)/8)
+		if len(data) < expected {
+			return fmt.Errorf("TGA pixel data truncated (%d of %d bytes)", len(data), expected)
+		}
+	}
+	return nil
+}
+
+// readPackJSON parses an optional pack file, recording a parse failure as an issue
+func readPackJSON(packDir, rel string, issues *[]ContentIssue) (interface{}, bool) {
+	// #nosec G304 - rel is one of the well-known pack file paths
+	data, err := os.ReadFile(filepath.Join(packDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, false
+	}
+	var doc interface{}
+	if err := json.Unmarshal(stripJSONComments(data), &doc); err != nil {
+		*issues = append(*issues, ContentIssue{File: rel, Problem: fmt.Sprintf("malformed JSON: %v", err)})
+		return nil, false
+	}
+	return doc, true
+}
+
+// collectTextureRefs gathers texture paths from texture_data entries, whose
+// "textures" value may be a string, an object with "path" or "variations", or an array of these
+func collectTextureRefs(textureData interface{}) []string {
+	entries, ok := textureData.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+	for _, entry := range entries {
+		textures, found := lookupJSONPath(entry, "textures")
+		if !found {
+			continue
+		}
+		refs = append(refs, collectPaths(textures, "path")...)
+	}
+	return uniqueSorted(refs)
+}
+
+// collectSoundRefs gathers audio paths from sound definitions, whose "sounds"
+// array holds strings or objects with "name"
+func collectSoundRefs(definitions interface{}) []string {
+	entries, ok := definitions.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+	for _, entry := range entries {
+		sounds, found := lookupJSONPath(entry, "sounds")
+		if !found {
+			continue
+		}
+		refs = append(refs, collectPaths(sounds, "name")...)
+	}
+	return uniqueSorted(refs)
+}
+
+// collectPaths returns string values, or the given key of object values, from a value or array
+func collectPaths(value interface{}, key string) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		if path, ok := v[key].(string); ok {
+			return []string{path}
+		}
+		if variations, ok := v["variations"]; ok {
+			return collectPaths(variations, key)
+		}
+	case []interface{}:
+		var paths []string
+		for _, item := range v {
+			paths = append(paths, collectPaths(item, key)...)
+		}
+		return paths
+	}
+	return nil
+}
+
+// packFileExists reports whether an extensionless pack-relative path exists with any of the extensions
+func packFileExists(packDir, ref string, extensions []string) bool {
+	base := filepath.Join(packDir, filepath.FromSlash(ref))
+	if filepath.Ext(ref) != "" {
+		if _, err := os.Stat(base); err == nil {
+			return true
+		}
+	}
+	for _, ext := range extensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,84 @@
+package minecraft
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePackAssets(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-assets-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var validPNG bytes.Buffer
+	if err := png.Encode(&validPNG, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	// 2x1 uncompressed 24-bit TGA
+	validTGA := make([]byte, 18+2*1*3)
+	validTGA[2] = 2
+	validTGA[12] = 2
+	validTGA[14] = 1
+	validTGA[16] = 24
+
+	files := map[string][]byte{
+		"textures/blocks/ok.png":     validPNG.Bytes(),
+		"textures/blocks/broken.png": []byte("not a png"),
+		"textures/blocks/ok.tga":     validTGA,
+		"textures/blocks/short.tga":  validTGA[:20],
+		"sounds/mob/moo.ogg":         []byte("OggS"),
+		"textures/terrain_texture.json": []byte(`{
+			"texture_data": {
+				"ok": {"textures": "textures/blocks/ok"},
+				"tga": {"textures": ["textures/blocks/ok", {"path": "textures/blocks/ok.tga"}]},
+				"gone": {"textures": "textures/blocks/missing"}
+			}
+		}`),
+		"sounds/sound_definitions.json": []byte(`{
+			"format_version": "1.14.0",
+			"sound_definitions": {
+				"mob.moo": {"sounds": ["sounds/mob/moo", {"name": "sounds/mob/missing"}]}
+			}
+		}`),
+	}
+	for rel, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+
+	issues, err := ValidatePackAssets(tempDir, PackTypeResource)
+	if err != nil {
+		t.Fatalf("ValidatePackAssets failed: %v", err)
+	}
+
+	expected := map[string]bool{
+		"textures/blocks/broken.png":    true,
+		"textures/blocks/short.tga":     true,
+		"textures/terrain_texture.json": true,
+		"sounds/sound_definitions.json": true,
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("Expected %d issues, got %v", len(expected), issues)
+	}
+	for _, issue := range issues {
+		if !expected[issue.File] {
+			t.Errorf("Unexpected issue: %s", issue)
+		}
+	}
+
+	if issues, err := ValidatePackAssets(tempDir, PackTypeBehavior); err != nil || len(issues) != 0 {
+		t.Errorf("Expected no asset checks for behavior packs, got %v (err: %v)", issues, err)
+	}
+}
@@ -0,0 +1,157 @@
+package minecraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// PackChecksums is the sidecar record of an installed pack's files, written
+// to the server's state directory at install time so that later changes to
+// the pack directory can be detected
+type PackChecksums struct {
+	PackID      string            `json:"pack_id"`
+	Version     [3]int            `json:"version"`
+	Directory   string            `json:"directory"`
+	InstalledAt time.Time         `json:"installed_at"`
+	Files       map[string]string `json:"files"` // SHA-256 by slash-separated path relative to Directory
+}
+
+// VerifyStatus is the outcome of checking an installed pack against its checksums
+type VerifyStatus string
+
+const (
+	VerifyOK        VerifyStatus = "ok"        // Every recorded file is unchanged and no file was added
+	VerifyModified  VerifyStatus = "modified"  // Files were changed, added, or deleted since installation
+	VerifyMissing   VerifyStatus = "missing"   // The recorded pack directory no longer exists
+	VerifyStale     VerifyStatus = "stale"     // The checksums are for another version than the one installed
+	VerifyUntracked VerifyStatus = "untracked" // No checksums were recorded, e.g. installed by hand or by an older blockbench
+)
+
+// PackVerification is the result of checking one installed pack
+type PackVerification struct {
+	PackID    string               `json:"pack_id"`
+	Name      string               `json:"name"`
+	Type      PackType             `json:"type"`
+	Version   [3]int               `json:"version"`
+	Status    VerifyStatus         `json:"status"`
+	Directory string               `json:"directory,omitempty"`
+	Files     *filesystem.TreeDiff `json:"files,omitempty"` // Set when the pack was checked file by file
+}
+
+// checksumsFile returns where the checksums of a pack are recorded
+func (s *Server) checksumsFile(packID string) string {
+	return filepath.Join(s.Paths.ChecksumsDir, packID+".json")
+}
+
+// RecordChecksums re-records an installed pack's checksums after its files
+// were changed on purpose, so 'verify' treats them as the installed state
+func (s *Server) RecordChecksums(manifest *Manifest, packDir string) error {
+	return s.recordChecksums(manifest, packDir)
+}
+
+// recordChecksums hashes every file of an installed pack directory and
+// writes the checksums sidecar
+func (s *Server) recordChecksums(manifest *Manifest, packDir string) error {
+	files, err := filesystem.HashTreeFS(s.FS, packDir)
+	if err != nil {
+		return err
+	}
+	record := PackChecksums{
+		PackID:      manifest.Header.UUID,
+		Version:     manifest.Header.Version,
+		Directory:   packDir,
+		InstalledAt: time.Now(),
+		Files:       files,
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checksums: %w", err)
+	}
+
+	path := s.checksumsFile(manifest.Header.UUID)
+	if err := os.MkdirAll(filepath.Dir(path), filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create checksums directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+	return nil
+}
+
+// removeChecksums deletes the checksums of an uninstalled pack
+func (s *Server) removeChecksums(packID string) {
+	_ = os.Remove(s.checksumsFile(packID))
+}
+
+// LoadChecksums reads the checksums recorded for a pack, or returns nil if
+// none were recorded
+func (s *Server) LoadChecksums(packID string) (*PackChecksums, error) {
+	path := s.checksumsFile(packID)
+	// #nosec G304 - path is in the server's state directory
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums of %s: %w", packID, err)
+	}
+	var record PackChecksums
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse checksums of %s: %w", packID, err)
+	}
+	return &record, nil
+}
+
+// VerifyPacks checks every active pack's directory against the checksums
+// recorded when it was installed
+func (s *Server) VerifyPacks() ([]PackVerification, error) {
+	packs, err := s.ListInstalledPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PackVerification, 0, len(packs))
+	for _, pack := range packs {
+		result := PackVerification{PackID: pack.PackID, Name: pack.Name, Type: pack.Type, Version: pack.Version}
+		record, err := s.LoadChecksums(pack.PackID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case record == nil:
+			result.Status = VerifyUntracked
+		case record.Version != pack.Version:
+			result.Status = VerifyStale
+			result.Directory = record.Directory
+		default:
+			result.Directory = record.Directory
+			if _, err := s.fs().Stat(record.Directory); os.IsNotExist(err) {
+				result.Status = VerifyMissing
+				break
+			}
+			current, err := filesystem.HashTreeFS(s.FS, record.Directory)
+			if err != nil {
+				return nil, err
+			}
+			diff := filesystem.DiffHashes(record.Files, current)
+			result.Files = &diff
+			result.Status = VerifyOK
+			if !diff.Empty() {
+				result.Status = VerifyModified
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,119 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyPacks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-checksums-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	serverDir := filepath.Join(tempDir, "server")
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(serverDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(serverDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	const packID = "11111111-1111-1111-1111-111111111111"
+	source := filepath.Join(tempDir, "BP")
+	if err := os.MkdirAll(filepath.Join(source, "scripts"), 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	manifestJSON := `{"format_version": 2, "header": {"name": "Pack", "uuid": "` + packID + `", "version": [1, 0, 0]}, "modules": [{"type": "data", "uuid": "11111111-1111-1111-1111-222222222222", "version": [1, 0, 0]}]}`
+	for rel, content := range map[string]string{"manifest.json": manifestJSON, "scripts/main.js": "// main", "pack_icon.png": "icon"} {
+		if err := os.WriteFile(filepath.Join(source, filepath.FromSlash(rel)), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+	manifest, err := ParseManifest(filepath.Join(source, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if err := server.InstallPack(manifest, source, PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPack failed: %v", err)
+	}
+
+	verify := func() PackVerification {
+		t.Helper()
+		results, err := server.VerifyPacks()
+		if err != nil {
+			t.Fatalf("VerifyPacks failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 verified pack, got %+v", results)
+		}
+		return results[0]
+	}
+
+	if result := verify(); result.Status != VerifyOK || result.Files == nil || result.Files.Unchanged != 3 {
+		t.Fatalf("Expected a freshly installed pack to verify, got %+v", result)
+	}
+
+	packDir, _, err := server.PackInstallPaths(manifest)
+	if err != nil {
+		t.Fatalf("PackInstallPaths failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "scripts", "main.js"), []byte("// tampered"), 0600); err != nil {
+		t.Fatalf("Failed to modify pack file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "scripts", "extra.js"), []byte("// added"), 0600); err != nil {
+		t.Fatalf("Failed to add pack file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(packDir, "pack_icon.png")); err != nil {
+		t.Fatalf("Failed to delete pack file: %v", err)
+	}
+
+	result := verify()
+	if result.Status != VerifyModified {
+		t.Fatalf("Expected the pack to be modified, got %+v", result)
+	}
+	if len(result.Files.Changed) != 1 || result.Files.Changed[0] != "scripts/main.js" {
+		t.Errorf("Expected scripts/main.js changed, got %v", result.Files.Changed)
+	}
+	if len(result.Files.Added) != 1 || result.Files.Added[0] != "scripts/extra.js" {
+		t.Errorf("Expected scripts/extra.js added, got %v", result.Files.Added)
+	}
+	if len(result.Files.Removed) != 1 || result.Files.Removed[0] != "pack_icon.png" {
+		t.Errorf("Expected pack_icon.png removed, got %v", result.Files.Removed)
+	}
+
+	// Checksums of another version than the active one are stale
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packID, Version: [3]int{2, 0, 0}}}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if result := verify(); result.Status != VerifyStale {
+		t.Errorf("Expected stale checksums, got %+v", result)
+	}
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packID, Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// Uninstalling drops the record
+	if err := server.UninstallPack(packID); err != nil {
+		t.Fatalf("UninstallPack failed: %v", err)
+	}
+	if record, err := server.LoadChecksums(packID); err != nil || record != nil {
+		t.Errorf("Expected the checksums to be removed on uninstall, got %+v, %v", record, err)
+	}
+
+	// A pack activated by hand has no record
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packID, Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if result := verify(); result.Status != VerifyUntracked {
+		t.Errorf("Expected an untracked pack, got %+v", result)
+	}
+}
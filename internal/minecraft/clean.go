@@ -0,0 +1,111 @@
+package minecraft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UnreferencedPack is an installed pack directory that no world config lists
+type UnreferencedPack struct {
+	PackID  string   `json:"pack_id"`
+	Name    string   `json:"name"`
+	Version [3]int   `json:"version"`
+	Type    PackType `json:"type"`
+	Dir     string   `json:"dir"`
+	Link    string   `json:"link,omitempty"` // Source directory of a linked pack, which removing leaves alone
+	Size    int64    `json:"size"`           // Bytes removing the directory reclaims; 0 for a linked pack
+}
+
+// WorldConfigFiles returns the pack world configs of every world in the
+// worlds directory, including the server's own world when it has no
+// directory yet. Files that don't exist are left out.
+func (s *Server) WorldConfigFiles() ([]string, error) {
+	worldDirs := []string{filepath.Dir(s.Paths.WorldBehaviorPacks)}
+	entries, err := s.fs().ReadDir(s.Paths.WorldsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read worlds directory: %w", err)
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(s.Paths.WorldsDir, entry.Name())
+		if entry.IsDir() && dir != worldDirs[0] {
+			worldDirs = append(worldDirs, dir)
+		}
+	}
+
+	var files []string
+	for _, dir := range worldDirs {
+		for _, name := range []string{filepath.Base(s.Paths.WorldBehaviorPacks), filepath.Base(s.Paths.WorldResourcePacks)} {
+			file := filepath.Join(dir, name)
+			if _, err := s.fs().Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// UnreferencedPacks lists the installed pack directories whose UUID no
+// world config of any world lists, with the space removing each reclaims.
+// Directories without a valid manifest are left out, since nothing says
+// which pack they are.
+func (s *Server) UnreferencedPacks() ([]UnreferencedPack, error) {
+	configFiles, err := s.WorldConfigFiles()
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, configFile := range configFiles {
+		config, err := LoadWorldConfigFS(s.FS, configFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range config {
+			referenced[strings.ToLower(ref.PackID)] = true
+		}
+	}
+
+	var packs []UnreferencedPack
+	for _, packType := range []PackType{PackTypeBehavior, PackTypeResource} {
+		indexed, err := s.IndexedPacks(packType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s packs: %w", packType, err)
+		}
+		for _, pack := range indexed {
+			if pack.Manifest == nil || referenced[strings.ToLower(pack.Manifest.Header.UUID)] {
+				continue
+			}
+			unreferenced := UnreferencedPack{
+				PackID:  pack.Manifest.Header.UUID,
+				Name:    pack.Manifest.GetDisplayName(),
+				Version: pack.Manifest.Header.Version,
+				Type:    packType,
+				Dir:     pack.Dir,
+			}
+			if target, linked := LinkedPackTarget(pack.Dir); linked {
+				unreferenced.Link = target
+			} else if unreferenced.Size, err = s.packIndex().Size(pack); err != nil {
+				return nil, fmt.Errorf("failed to measure %s: %w", pack.Dir, err)
+			}
+			packs = append(packs, unreferenced)
+		}
+	}
+	s.packIndex().flush()
+	return packs, nil
+}
+
+// RemoveUnreferencedPack removes the directory of an unreferenced pack, or
+// only the link of a linked pack, and forgets its recorded checksums
+func (s *Server) RemoveUnreferencedPack(pack UnreferencedPack) error {
+	defer s.InvalidatePacks()
+
+	if linked, err := unlinkPack(pack.Dir); linked {
+		return err
+	}
+	if err := s.fs().RemoveAll(pack.Dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", pack.Dir, err)
+	}
+	s.removeChecksums(pack.PackID)
+	return nil
+}
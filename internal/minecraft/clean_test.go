@@ -0,0 +1,58 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnreferencedPacks(t *testing.T) {
+	server := newWorldTestServer(t)
+	behaviorDir := server.Paths.BehaviorPacksDir
+	writeIndexedPack(t, behaviorDir, "Active", "11111111-1111-1111-1111-111111111111", "Active")
+	writeIndexedPack(t, behaviorDir, "Elsewhere", "22222222-2222-2222-2222-22222222222a", "Elsewhere")
+	orphan := writeIndexedPack(t, behaviorDir, "Orphan", "33333333-3333-3333-3333-333333333333", "Orphan")
+	if err := os.WriteFile(filepath.Join(orphan, "data.bin"), make([]byte, 1000), 0600); err != nil {
+		t.Fatalf("Failed to write pack file: %v", err)
+	}
+	broken := filepath.Join(server.Paths.ResourcePacksDir, "Broken")
+	if err := os.MkdirAll(broken, 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "manifest.json"), []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	// The server's world lists one pack, and another world lists the other in upper case
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: "11111111-1111-1111-1111-111111111111", Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("SaveWorldConfig failed: %v", err)
+	}
+	otherWorld := filepath.Join(server.Paths.WorldsDir, "Other")
+	if err := os.MkdirAll(otherWorld, 0750); err != nil {
+		t.Fatalf("Failed to create world dir: %v", err)
+	}
+	if err := SaveWorldConfig(filepath.Join(otherWorld, "world_behavior_packs.json"), WorldConfig{{PackID: "22222222-2222-2222-2222-22222222222A", Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("SaveWorldConfig failed: %v", err)
+	}
+
+	packs, err := server.UnreferencedPacks()
+	if err != nil {
+		t.Fatalf("UnreferencedPacks failed: %v", err)
+	}
+	if len(packs) != 1 || packs[0].Dir != orphan || packs[0].Type != PackTypeBehavior {
+		t.Fatalf("Expected only the orphan pack unreferenced, got %+v", packs)
+	}
+	if packs[0].Size < 1000 {
+		t.Errorf("Expected the orphan's size to include its files, got %d", packs[0].Size)
+	}
+
+	if err := server.RemoveUnreferencedPack(packs[0]); err != nil {
+		t.Fatalf("RemoveUnreferencedPack failed: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Expected the orphan's directory to be removed")
+	}
+	if packs, _ := server.UnreferencedPacks(); len(packs) != 0 {
+		t.Errorf("Expected no unreferenced packs after the removal, got %+v", packs)
+	}
+}
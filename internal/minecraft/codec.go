@@ -0,0 +1,211 @@
+package minecraft
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// WorldConfigCodec reads and writes one on-disk layout of a world pack list.
+// Vanilla servers use a bare JSON array; some modified servers wrap the array
+// in an object or add fields to each entry.
+type WorldConfigCodec interface {
+	// Name identifies the codec in messages
+	Name() string
+	// Detect reports whether the raw file contents use this codec's layout
+	Detect(data []byte) bool
+	// Decode parses the raw file contents into a WorldConfig
+	Decode(data []byte) (WorldConfig, error)
+	// Encode serializes config, preserving anything in original the codec does not own.
+	// original is nil when the file does not exist yet.
+	Encode(config WorldConfig, original []byte) ([]byte, error)
+}
+
+// worldConfigCodecs holds registered codecs in detection order
+var worldConfigCodecs = []WorldConfigCodec{
+	arrayConfigCodec{},
+	wrappedConfigCodec{},
+}
+
+// RegisterWorldConfigCodec adds a codec that is tried before the built-in ones
+func RegisterWorldConfigCodec(codec WorldConfigCodec) {
+	worldConfigCodecs = append([]WorldConfigCodec{codec}, worldConfigCodecs...)
+}
+
+// DetectWorldConfigCodec returns the first registered codec that recognizes data
+func DetectWorldConfigCodec(data []byte) (WorldConfigCodec, error) {
+	for _, codec := range worldConfigCodecs {
+		if codec.Detect(data) {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognized world config format")
+}
+
+// arrayConfigCodec handles the vanilla layout: a bare array of pack references
+type arrayConfigCodec struct{}
+
+func (arrayConfigCodec) Name() string { return "array" }
+
+func (arrayConfigCodec) Detect(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	// Older releases wrote "null" after the last pack was removed
+	return bytes.Equal(trimmed, []byte("null")) || (len(trimmed) > 0 && trimmed[0] == '[')
+}
+
+func (arrayConfigCodec) Decode(data []byte) (WorldConfig, error) {
+	var config WorldConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (arrayConfigCodec) Encode(config WorldConfig, original []byte) ([]byte, error) {
+	if config == nil {
+		config = WorldConfig{}
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
+
+// wrappedConfigCodec handles an object with the pack array under one key,
+// e.g. {"packs": [...], "schema": 2}. Other top-level keys are preserved in
+// their order.
+type wrappedConfigCodec struct{}
+
+func (wrappedConfigCodec) Name() string { return "wrapped" }
+
+func (wrappedConfigCodec) Detect(data []byte) bool {
+	_, _, err := findWrappedPackList(data)
+	return err == nil
+}
+
+func (wrappedConfigCodec) Decode(data []byte) (WorldConfig, error) {
+	fields, index, err := findWrappedPackList(data)
+	if err != nil {
+		return nil, err
+	}
+	var config WorldConfig
+	if err := json.Unmarshal(fields[index].value, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (wrappedConfigCodec) Encode(config WorldConfig, original []byte) ([]byte, error) {
+	fields, index, err := findWrappedPackList(original)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = WorldConfig{}
+	}
+	packs, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	fields[index].value = packs
+
+	var compact bytes.Buffer
+	compact.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			compact.WriteByte(',')
+		}
+		name, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		compact.Write(name)
+		compact.WriteByte(':')
+		if err := json.Compact(&compact, field.value); err != nil {
+			return nil, err
+		}
+	}
+	compact.WriteByte('}')
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// wrappedField is a top-level field of a wrapped world config
+type wrappedField struct {
+	key   string
+	value json.RawMessage
+}
+
+// readObjectFields reads the top-level fields of a JSON object in file order
+func readObjectFields(data []byte) ([]wrappedField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, fmt.Errorf("world config is not a JSON object")
+	}
+
+	var fields []wrappedField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, wrappedField{key: key, value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// findWrappedPackList locates the top-level field holding the pack array: the
+// first field whose array entries carry a pack_id, or else an empty array
+// under "packs" or "pack_list", or else the only field holding an array, which
+// is what a pack list under any other key looks like once its last pack is
+// removed.
+func findWrappedPackList(data []byte) ([]wrappedField, int, error) {
+	fields, err := readObjectFields(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var arrays []int // Fields holding an array
+	for i, field := range fields {
+		var values []json.RawMessage
+		if err := json.Unmarshal(field.value, &values); err != nil || values == nil {
+			continue
+		}
+		arrays = append(arrays, i)
+		if len(values) == 0 {
+			continue
+		}
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(values[0], &entry); err != nil {
+			continue
+		}
+		if _, ok := entry["pack_id"]; ok {
+			return fields, i, nil
+		}
+	}
+
+	// Fall back to a conventional key holding an empty array
+	for _, key := range []string{"packs", "pack_list"} {
+		for i, field := range fields {
+			if field.key == key && bytes.Equal(bytes.TrimSpace(field.value), []byte("[]")) {
+				return fields, i, nil
+			}
+		}
+	}
+	if len(arrays) == 1 && bytes.Equal(bytes.TrimSpace(fields[arrays[0]].value), []byte("[]")) {
+		return fields, arrays[0], nil
+	}
+
+	return nil, 0, fmt.Errorf("no pack list found in world config object")
+}
@@ -1,20 +1,108 @@
 package minecraft
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
 	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/minecraft/properties"
+	"github.com/makutaku/blockbench/pkg/validation"
 )
 
 // PackReference represents a pack reference in world config files
 type PackReference struct {
 	PackID  string `json:"pack_id"`
 	Version [3]int `json:"version"`
+	Subpack string `json:"subpack,omitempty"` // Selected subpack folder name, if the pack declares subpacks
+
+	// Extra holds entry fields blockbench does not know about (added by modified
+	// servers) so they survive a load/save round trip
+	Extra map[string]json.RawMessage `json:"-"`
+
+	extraOrder []string // Keys of Extra in the order they were read
+}
+
+// packReferenceFields is PackReference without its custom JSON methods
+type packReferenceFields PackReference
+
+// UnmarshalJSON decodes the known fields and keeps any others in Extra
+func (pr *PackReference) UnmarshalJSON(data []byte) error {
+	var fields packReferenceFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	raw, err := readObjectFields(data)
+	if err != nil {
+		return err
+	}
+	for _, field := range raw {
+		switch field.key {
+		case "pack_id", "version", "subpack":
+			continue
+		}
+		if fields.Extra == nil {
+			fields.Extra = make(map[string]json.RawMessage)
+		}
+		if _, seen := fields.Extra[field.key]; !seen {
+			fields.extraOrder = append(fields.extraOrder, field.key)
+		}
+		fields.Extra[field.key] = field.value
+	}
+
+	*pr = PackReference(fields)
+	return nil
+}
+
+// MarshalJSON encodes the known fields first, followed by preserved Extra
+// fields in the order they were read; fields added since follow, sorted
+func (pr PackReference) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(packReferenceFields(pr))
+	if err != nil || len(pr.Extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(pr.Extra))
+	ordered := make(map[string]bool, len(pr.extraOrder))
+	for _, key := range pr.extraOrder {
+		if _, ok := pr.Extra[key]; ok && !ordered[key] {
+			keys = append(keys, key)
+			ordered[key] = true
+		}
+	}
+	var added []string
+	for key := range pr.Extra {
+		if !ordered[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	keys = append(keys, added...)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1]) // drop closing brace
+	for _, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(pr.Extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // WorldConfig represents the structure of world config files
@@ -23,6 +111,7 @@ type WorldConfig []PackReference
 // ServerPaths contains paths to important server directories and files
 type ServerPaths struct {
 	ServerRoot           string
+	ServerProperties     string
 	WorldsDir            string
 	BehaviorPacksDir     string
 	ResourcePacksDir     string
@@ -30,28 +119,81 @@ type ServerPaths struct {
 	WorldResourcePacks   string
 	WorldBehaviorHistory string
 	WorldResourceHistory string
+	LevelDat             string
+	StateDir             string // blockbench's own server-local state (.blockbench)
+	StoreDir             string // Content-addressed store for deduplicated pack files
+	IndexFile            string // Cached manifest index of installed packs
+	ChecksumsDir         string // File checksums of installed packs, recorded at install time
+	HistoryFile          string // Operation history, one JSON entry per line
+	VersionsDir          string // Archives of every version of each pack installed, for switching back to one
+}
+
+// PackDirMode selects which pack directories of a server packs are installed into
+type PackDirMode string
+
+const (
+	PackDirsDevelopment PackDirMode = "development" // development_behavior_packs and development_resource_packs
+	PackDirsRelease     PackDirMode = "release"     // behavior_packs and resource_packs
+)
+
+// PathOptions overrides how the paths of a server are derived
+type PathOptions struct {
+	World    string      // World directory name; empty reads level-name from server.properties
+	PackDirs PackDirMode // Pack directories; empty means PackDirsDevelopment
 }
 
 // NewServerPaths creates a ServerPaths struct with standard Bedrock server paths
 func NewServerPaths(serverRoot string) (*ServerPaths, error) {
+	return NewServerPathsWithOptions(serverRoot, PathOptions{})
+}
+
+// NewServerPathsWithOptions creates a ServerPaths struct, overriding the world
+// and pack directories as opts asks
+func NewServerPathsWithOptions(serverRoot string, opts PathOptions) (*ServerPaths, error) {
 	worldsDir := filepath.Join(serverRoot, "worlds")
 
 	// Get world name from server.properties - no fallbacks
-	worldName, err := getWorldNameFromProperties(serverRoot)
-	if err != nil {
-		return nil, err
+	worldName := opts.World
+	if worldName == "" {
+		var err error
+		worldName, err = getWorldNameFromProperties(serverRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// A level-name the platform can't name a directory, or one leading out of
+	// the worlds directory, is an error rather than a path
+	if err := filesystem.HostPathStyle.CheckName(filesystem.HostPathStyle.NormalizeName(worldName)); err != nil {
+		return nil, bberrors.Mark(fmt.Errorf("invalid world name: %w", err), bberrors.ErrServerStructure)
+	}
+	worldDir := filepath.Join(worldsDir, filesystem.HostPathStyle.NormalizeName(worldName))
+
+	packDirPrefix := "development_"
+	switch opts.PackDirs {
+	case "", PackDirsDevelopment:
+	case PackDirsRelease:
+		packDirPrefix = ""
+	default:
+		return nil, fmt.Errorf("unknown pack directory mode %q (expected %s or %s)", opts.PackDirs, PackDirsDevelopment, PackDirsRelease)
 	}
-	worldDir := filepath.Join(worldsDir, worldName)
 
 	return &ServerPaths{
 		ServerRoot:           serverRoot,
+		ServerProperties:     filepath.Join(serverRoot, "server.properties"),
 		WorldsDir:            worldsDir,
-		BehaviorPacksDir:     filepath.Join(serverRoot, "development_behavior_packs"),
-		ResourcePacksDir:     filepath.Join(serverRoot, "development_resource_packs"),
+		BehaviorPacksDir:     filepath.Join(serverRoot, packDirPrefix+"behavior_packs"),
+		ResourcePacksDir:     filepath.Join(serverRoot, packDirPrefix+"resource_packs"),
 		WorldBehaviorPacks:   filepath.Join(worldDir, "world_behavior_packs.json"),
 		WorldResourcePacks:   filepath.Join(worldDir, "world_resource_packs.json"),
 		WorldBehaviorHistory: filepath.Join(worldDir, "world_behavior_pack_history.json"),
 		WorldResourceHistory: filepath.Join(worldDir, "world_resource_pack_history.json"),
+		LevelDat:             filepath.Join(worldDir, "level.dat"),
+		StateDir:             filepath.Join(serverRoot, ".blockbench"),
+		StoreDir:             filepath.Join(serverRoot, ".blockbench", "store"),
+		IndexFile:            filepath.Join(serverRoot, ".blockbench", "index.json"),
+		ChecksumsDir:         filepath.Join(serverRoot, ".blockbench", "checksums"),
+		HistoryFile:          filepath.Join(serverRoot, ".blockbench", "history.jsonl"),
+		VersionsDir:          filepath.Join(serverRoot, ".blockbench", "versions"),
 	}, nil
 }
 
@@ -59,38 +201,40 @@ func NewServerPaths(serverRoot string) (*ServerPaths, error) {
 func getWorldNameFromProperties(serverRoot string) (string, error) {
 	propertiesPath := filepath.Join(serverRoot, "server.properties")
 
-	// #nosec G304 - propertiesPath is validated server properties file
-	file, err := os.Open(propertiesPath)
+	worldName, found, err := readServerProperty(serverRoot, "level-name")
 	if err != nil {
-		return "", fmt.Errorf("cannot read server.properties at %s: %w", propertiesPath, err)
+		return "", bberrors.Mark(err, bberrors.ErrServerStructure)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip comments and empty lines
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
-		}
-
-		// Look for level-name property
-		if strings.HasPrefix(line, "level-name=") {
-			worldName := strings.TrimPrefix(line, "level-name=")
-			worldName = strings.TrimSpace(worldName)
-			if worldName == "" {
-				return "", fmt.Errorf("level-name property is empty in %s", propertiesPath)
-			}
-			return worldName, nil
-		}
+	if !found {
+		return "", bberrors.Mark(fmt.Errorf("level-name property not found in %s. Ensure your server.properties file contains a valid 'level-name=' entry (e.g., 'level-name=Bedrock level')", propertiesPath), bberrors.ErrServerStructure)
+	}
+	if worldName == "" {
+		return "", bberrors.Mark(fmt.Errorf("level-name property is empty in %s", propertiesPath), bberrors.ErrServerStructure)
 	}
+	return worldName, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("error reading server.properties: %w", err)
+// readServerProperty returns the trimmed value of the first key= line in
+// server.properties, and whether the key was present
+func readServerProperty(serverRoot, key string) (string, bool, error) {
+	file, err := properties.Load(filepath.Join(serverRoot, "server.properties"))
+	if err != nil {
+		return "", false, err
 	}
+	value, found := file.Get(key)
+	return value, found, nil
+}
 
-	return "", fmt.Errorf("level-name property not found in %s. Ensure your server.properties file contains a valid 'level-name=' entry (e.g., 'level-name=Bedrock level')", propertiesPath)
+// WorldConfigFor returns the world config file that registers packs of the given type
+func (sp *ServerPaths) WorldConfigFor(packType PackType) (string, error) {
+	switch packType {
+	case PackTypeBehavior:
+		return sp.WorldBehaviorPacks, nil
+	case PackTypeResource:
+		return sp.WorldResourcePacks, nil
+	default:
+		return "", fmt.Errorf("unknown pack type: %s", packType)
+	}
 }
 
 // ValidateServerStructure checks if the server directory has the expected structure
@@ -103,57 +247,92 @@ func (sp *ServerPaths) ValidateServerStructure() error {
 
 	for _, dir := range requiredDirs {
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
-			return fmt.Errorf("required directory does not exist: %s", dir)
+			return bberrors.Mark(fmt.Errorf("required directory does not exist: %s", dir), bberrors.ErrServerStructure)
 		}
 	}
 
 	return nil
 }
 
-// LoadWorldConfig loads a world config file (behavior or resource packs)
+// LoadWorldConfig loads a world config file (behavior or resource packs).
+// The file layout is auto-detected from the registered WorldConfigCodecs.
+// Malformed entries (see CheckWorldConfig) are logged as warnings, or fail
+// the load after SetStrictWorldConfigs(true).
 func LoadWorldConfig(filePath string) (WorldConfig, error) {
-	// If file doesn't exist, return empty config
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return WorldConfig{}, nil
-	}
+	return LoadWorldConfigFS(nil, filePath)
+}
 
+// LoadWorldConfigFS is LoadWorldConfig on fsys; a nil fsys is the real filesystem
+func LoadWorldConfigFS(fsys filesystem.FS, filePath string) (WorldConfig, error) {
 	// #nosec G304 - filePath is validated by caller within server directory
-	data, err := os.ReadFile(filePath)
+	data, err := filesystem.ReadFile(fsys, filePath)
+	if os.IsNotExist(err) {
+		// If file doesn't exist, return empty config
+		return WorldConfig{}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
 	}
 
-	var config WorldConfig
-	if err := json.Unmarshal(data, &config); err != nil {
+	codec, err := DetectWorldConfigCodec(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	config, err := codec.Decode(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
 	}
 
+	if err := reportConfigProblems(filePath, CheckWorldConfig(filePath, data, config)); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
-// SaveWorldConfig saves a world config file using atomic write
+// SaveWorldConfig saves a world config file using atomic write.
+// An existing file keeps its detected layout; new files use the vanilla array layout.
 func SaveWorldConfig(filePath string, config WorldConfig) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	return SaveWorldConfigFS(nil, filePath, config)
+}
+
+// SaveWorldConfigFS is SaveWorldConfig on fsys; a nil fsys is the real filesystem
+func SaveWorldConfigFS(fsys filesystem.FS, filePath string, config WorldConfig) error {
+	if fsys == nil {
+		fsys = filesystem.OSFS{}
+	}
+	var codec WorldConfigCodec = arrayConfigCodec{}
+	// #nosec G304 - filePath is validated by caller within server directory
+	original, err := filesystem.ReadFile(fsys, filePath)
+	if err == nil {
+		if detected, detectErr := DetectWorldConfigCodec(original); detectErr == nil {
+			codec = detected
+		}
+	} else {
+		original = nil
+	}
+
+	data, err := codec.Encode(config, original)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, filesystem.DefaultDirPerm); err != nil {
+	if err := fsys.MkdirAll(dir, filesystem.DefaultDirPerm); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
 	// Write to temporary file first
 	tmpFile := filePath + ".tmp"
-	if err := os.WriteFile(tmpFile, data, filesystem.DefaultFilePerm); err != nil {
+	if err := filesystem.WriteFile(fsys, tmpFile, data, filesystem.DefaultFilePerm); err != nil {
 		return fmt.Errorf("failed to write temp config file: %w", err)
 	}
 
 	// Atomic rename (on same filesystem, this is atomic)
-	if err := os.Rename(tmpFile, filePath); err != nil {
+	if err := fsys.Rename(tmpFile, filePath); err != nil {
 		// Clean up temp file on error, ignore cleanup errors as we're already failing
-		_ = os.Remove(tmpFile) // #nosec G104 - cleanup on error path, already returning error
+		_ = fsys.Remove(tmpFile) // #nosec G104 - cleanup on error path, already returning error
 		return fmt.Errorf("failed to save config file: %w", err)
 	}
 
@@ -191,6 +370,147 @@ func RemovePackFromConfig(config WorldConfig, packID string) WorldConfig {
 	return result
 }
 
+// MovePackInConfig moves a pack to a new activation index, shifting the packs
+// between its old and new positions. Out-of-range indexes are rejected.
+func MovePackInConfig(config WorldConfig, packID string, index int) (WorldConfig, error) {
+	from := config.IndexOf(packID)
+	if from < 0 {
+		return nil, fmt.Errorf("pack %s is not in the config", packID)
+	}
+	if index < 0 || index >= len(config) {
+		return nil, fmt.Errorf("index %d is out of range (config has %d packs)", index, len(config))
+	}
+
+	pack := config[from]
+	result := make(WorldConfig, 0, len(config))
+	result = append(result, config[:from]...)
+	result = append(result, config[from+1:]...)
+	result = append(result[:index], append(WorldConfig{pack}, result[index:]...)...)
+	return result, nil
+}
+
+// PackPositionKind says where in its world config a pack is placed
+type PackPositionKind string
+
+const (
+	PositionBottom PackPositionKind = "bottom" // Last entry, applied below every other pack
+	PositionTop    PackPositionKind = "top"    // First entry, applied above every other pack
+	PositionBefore PackPositionKind = "before" // Right before the anchor pack
+	PositionAfter  PackPositionKind = "after"  // Right after the anchor pack
+)
+
+// PackPosition is where a pack is placed in its world config. Packs earlier in
+// the config override the packs after them.
+type PackPosition struct {
+	Kind   PackPositionKind `json:"kind"`
+	Anchor string           `json:"anchor,omitempty"` // Pack UUID for before and after
+}
+
+// ParsePackPosition parses top, bottom, before=<uuid>, or after=<uuid>. An
+// empty spec is the zero position, which keeps the default placement.
+func ParsePackPosition(spec string) (PackPosition, error) {
+	kind, anchor, hasAnchor := strings.Cut(strings.TrimSpace(spec), "=")
+	position := PackPosition{Kind: PackPositionKind(strings.ToLower(kind)), Anchor: strings.TrimSpace(anchor)}
+	switch position.Kind {
+	case "":
+		return PackPosition{}, nil
+	case PositionTop, PositionBottom:
+		if hasAnchor {
+			return PackPosition{}, fmt.Errorf("position %s does not take a pack UUID", position.Kind)
+		}
+		return position, nil
+	case PositionBefore, PositionAfter:
+		if !validation.ValidateUUID(position.Anchor) {
+			return PackPosition{}, fmt.Errorf("position %s needs a pack UUID, as in %s=<uuid>", position.Kind, position.Kind)
+		}
+		position.Anchor = validation.NormalizeUUID(position.Anchor)
+		return position, nil
+	default:
+		return PackPosition{}, fmt.Errorf("unknown position %q (expected top, bottom, before=<uuid>, or after=<uuid>)", spec)
+	}
+}
+
+// String formats the position the way ParsePackPosition reads it
+func (p PackPosition) String() string {
+	if p.Kind == "" {
+		return string(PositionBottom)
+	}
+	if p.Anchor != "" {
+		return fmt.Sprintf("%s=%s", p.Kind, p.Anchor)
+	}
+	return string(p.Kind)
+}
+
+// IsDefault reports whether the position is the zero position, which leaves
+// packs where AddPackToConfig puts them: new packs at the bottom and existing
+// packs in place
+func (p PackPosition) IsDefault() bool {
+	return p.Kind == ""
+}
+
+// PositionPackInConfig moves a pack in the config to a position. A before or
+// after position whose anchor is not in the config is rejected.
+func PositionPackInConfig(config WorldConfig, packID string, position PackPosition) (WorldConfig, error) {
+	from := config.IndexOf(packID)
+	if from < 0 {
+		return nil, fmt.Errorf("pack %s is not in the config", packID)
+	}
+
+	var index int
+	switch position.Kind {
+	case PositionTop:
+		index = 0
+	case "", PositionBottom:
+		index = len(config) - 1
+	case PositionBefore, PositionAfter:
+		if position.Anchor == packID {
+			return nil, fmt.Errorf("pack %s cannot be positioned relative to itself", packID)
+		}
+		index = config.IndexOf(position.Anchor)
+		if index < 0 {
+			return nil, fmt.Errorf("pack %s is not in the config", position.Anchor)
+		}
+		// Indexes after the pack shift down once it is taken out
+		if index > from {
+			index--
+		}
+		if position.Kind == PositionAfter {
+			index++
+		}
+	default:
+		return nil, fmt.Errorf("unknown position %q", position.Kind)
+	}
+	return MovePackInConfig(config, packID, index)
+}
+
+// PackMove moves a pack to an activation index of its world config
+type PackMove struct {
+	PackID string `json:"pack_id"`
+	Index  int    `json:"index"`
+}
+
+// MovesToOrder returns the moves that rearrange the pack UUIDs in current
+// into target, a permutation of them, when applied in order
+func MovesToOrder(current, target []string) []PackMove {
+	moves := make([]PackMove, 0)
+	current = append([]string(nil), current...)
+	for i := range target {
+		if i >= len(current) || current[i] == target[i] {
+			continue
+		}
+		moved := append(make([]string, 0, len(current)), current[:i]...)
+		moved = append(moved, target[i])
+		for _, uuid := range current[i:] {
+			if uuid != target[i] {
+				moved = append(moved, uuid)
+			}
+		}
+		current = moved
+		moves = append(moves, PackMove{PackID: target[i], Index: i})
+	}
+	return moves
+}
+
 // HasPack checks if a pack is present in the config
 func (wc WorldConfig) HasPack(packID string) bool {
 	for _, pack := range wc {
@@ -201,6 +521,16 @@ func (wc WorldConfig) HasPack(packID string) bool {
 	return false
 }
 
+// IndexOf returns the activation index of a pack in the config, or -1 if it is not present
+func (wc WorldConfig) IndexOf(packID string) int {
+	for i, pack := range wc {
+		if pack.PackID == packID {
+			return i
+		}
+	}
+	return -1
+}
+
 // GetPack retrieves a pack reference by ID
 func (wc WorldConfig) GetPack(packID string) (*PackReference, bool) {
 	for _, pack := range wc {
@@ -212,3 +542,44 @@ func (wc WorldConfig) GetPack(packID string) (*PackReference, bool) {
 	}
 	return nil, false
 }
+
+// PackHistoryEntry is one pack recorded by the server in a world pack history file
+type PackHistoryEntry struct {
+	UUID              string `json:"uuid"`
+	Name              string `json:"name"`
+	Version           [3]int `json:"version"`
+	CanBeRedownloaded bool   `json:"can_be_redownloaded"`
+}
+
+// PackHistory represents a world_*_pack_history.json file maintained by the server
+type PackHistory struct {
+	Packs    []PackHistoryEntry `json:"packs"`
+	Modified time.Time          `json:"-"` // When the server last wrote the file
+}
+
+// LoadPackHistory loads a world pack history file. A missing file yields an empty history.
+func LoadPackHistory(filePath string) (*PackHistory, error) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return &PackHistory{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat history file %s: %w", filePath, err)
+	}
+
+	// #nosec G304 - filePath is validated by caller within server directory
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file %s: %w", filePath, err)
+	}
+
+	var history PackHistory
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &history); err != nil {
+			return nil, fmt.Errorf("failed to parse history file %s: %w", filePath, err)
+		}
+	}
+	history.Modified = info.ModTime()
+
+	return &history, nil
+}
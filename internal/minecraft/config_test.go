@@ -1,8 +1,10 @@
 package minecraft
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +62,13 @@ gamemode=creative`,
 			expectedLevel:  "",
 			expectError:    true,
 		},
+		{
+			name: "level-name leading out of the worlds directory",
+			propertiesData: `level-name=..
+server-name=Test Server`,
+			expectedLevel: "",
+			expectError:   true,
+		},
 		{
 			name: "commented level-name",
 			propertiesData: `#level-name=Commented World
@@ -122,6 +131,53 @@ func TestNewServerPathsNonExistent(t *testing.T) {
 	}
 }
 
+func TestNewServerPathsWithOptions(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=Props World\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         PathOptions
+		expectWorld  string
+		expectBPDir  string
+		expectResDir string
+		expectError  bool
+	}{
+		{"defaults", PathOptions{}, "Props World", "development_behavior_packs", "development_resource_packs", false},
+		{"world override", PathOptions{World: "Other"}, "Other", "development_behavior_packs", "development_resource_packs", false},
+		{"release pack dirs", PathOptions{PackDirs: PackDirsRelease}, "Props World", "behavior_packs", "resource_packs", false},
+		{"unknown pack dir mode", PathOptions{PackDirs: "beta"}, "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := NewServerPathsWithOptions(tempDir, tt.opts)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if world := filepath.Base(filepath.Dir(paths.WorldBehaviorPacks)); world != tt.expectWorld {
+				t.Errorf("Expected world %q, got %q", tt.expectWorld, world)
+			}
+			if filepath.Base(paths.BehaviorPacksDir) != tt.expectBPDir || filepath.Base(paths.ResourcePacksDir) != tt.expectResDir {
+				t.Errorf("Expected pack dirs %s and %s, got %s and %s", tt.expectBPDir, tt.expectResDir, paths.BehaviorPacksDir, paths.ResourcePacksDir)
+			}
+		})
+	}
+}
+
 func TestLoadWorldConfig(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -345,6 +401,30 @@ func TestPackReference(t *testing.T) {
 	}
 }
 
+func TestPackReferenceExtraOrder(t *testing.T) {
+	var entry PackReference
+	data := `{"zeta": 1, "pack_id": "12345678-1234-1234-1234-123456789abc", "alpha": 2, "version": [1, 0, 0], "mid": {"b": 1, "a": 2}}`
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		t.Fatalf("Failed to unmarshal entry: %v", err)
+	}
+	entry.Extra["beta"] = json.RawMessage("3")
+
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %v", err)
+	}
+	// Read fields keep their order after the known ones; added ones follow
+	expected := `{"pack_id":"12345678-1234-1234-1234-123456789abc","version":[1,0,0],"zeta":1,"alpha":2,"mid":{"b":1,"a":2},"beta":3}`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+
+	var empty PackReference
+	if err := json.Unmarshal([]byte("null"), &empty); err != nil || empty.Extra != nil {
+		t.Errorf("Expected a null entry to decode to nothing, got %+v (%v)", empty, err)
+	}
+}
+
 func BenchmarkLoadWorldConfig(b *testing.B) {
 	// Create temporary config file
 	tempDir, err := os.MkdirTemp("", "blockbench-config-bench")
@@ -382,3 +462,415 @@ func BenchmarkLoadWorldConfig(b *testing.B) {
 		}
 	}
 }
+
+func TestWorldConfigIndexOf(t *testing.T) {
+	config := WorldConfig{
+		{PackID: "12345678-1234-1234-1234-123456789abc", Version: [3]int{1, 0, 0}},
+		{PackID: "87654321-4321-4321-4321-fedcba987654", Version: [3]int{2, 1, 0}},
+	}
+
+	if idx := config.IndexOf("87654321-4321-4321-4321-fedcba987654"); idx != 1 {
+		t.Errorf("Expected index 1, got %d", idx)
+	}
+	if idx := config.IndexOf("11111111-1111-1111-1111-111111111111"); idx != -1 {
+		t.Errorf("Expected index -1 for missing pack, got %d", idx)
+	}
+
+	config = AddPackToConfig(config, "11111111-1111-1111-1111-111111111111", [3]int{1, 2, 3})
+	if idx := config.IndexOf("11111111-1111-1111-1111-111111111111"); idx != 2 {
+		t.Errorf("Expected appended pack at index 2, got %d", idx)
+	}
+}
+
+func TestMovePackInConfig(t *testing.T) {
+	config := WorldConfig{{PackID: "a"}, {PackID: "b"}, {PackID: "c"}, {PackID: "d"}}
+
+	tests := []struct {
+		name        string
+		packID      string
+		index       int
+		expected    string
+		expectError bool
+	}{
+		{"move to front", "c", 0, "cabd", false},
+		{"move to back", "a", 3, "bcda", false},
+		{"move down one", "b", 2, "acbd", false},
+		{"same position", "b", 1, "abcd", false},
+		{"missing pack", "z", 0, "", true},
+		{"index out of range", "a", 4, "", true},
+		{"negative index", "a", -1, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved, err := MovePackInConfig(config, tt.packID, tt.index)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", moved)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MovePackInConfig failed: %v", err)
+			}
+
+			order := ""
+			for _, pack := range moved {
+				order += pack.PackID
+			}
+			if order != tt.expected {
+				t.Errorf("Expected order %s, got %s", tt.expected, order)
+			}
+		})
+	}
+
+	// The input config must not be modified
+	if config[0].PackID != "a" || config[2].PackID != "c" {
+		t.Errorf("Expected input config to be unchanged, got %v", config)
+	}
+}
+
+func TestParsePackPosition(t *testing.T) {
+	const uuid = "12345678-1234-1234-1234-123456789abc"
+
+	tests := []struct {
+		spec        string
+		expected    PackPosition
+		expectError bool
+	}{
+		{"", PackPosition{}, false},
+		{"top", PackPosition{Kind: PositionTop}, false},
+		{"Bottom", PackPosition{Kind: PositionBottom}, false},
+		{"before=" + uuid, PackPosition{Kind: PositionBefore, Anchor: uuid}, false},
+		{"after=12345678-1234-1234-1234-123456789ABC", PackPosition{Kind: PositionAfter, Anchor: uuid}, false},
+		{"top=" + uuid, PackPosition{}, true},
+		{"before", PackPosition{}, true},
+		{"after=not-a-uuid", PackPosition{}, true},
+		{"middle", PackPosition{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			position, err := ParsePackPosition(tt.spec)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", position)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePackPosition failed: %v", err)
+			}
+			if position != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, position)
+			}
+		})
+	}
+}
+
+func TestPositionPackInConfig(t *testing.T) {
+	config := WorldConfig{{PackID: "a"}, {PackID: "b"}, {PackID: "c"}, {PackID: "d"}}
+
+	tests := []struct {
+		name        string
+		packID      string
+		position    PackPosition
+		expected    string
+		expectError bool
+	}{
+		{"top", "c", PackPosition{Kind: PositionTop}, "cabd", false},
+		{"bottom", "b", PackPosition{Kind: PositionBottom}, "acdb", false},
+		{"default is bottom", "a", PackPosition{}, "bcda", false},
+		{"before a later pack", "a", PackPosition{Kind: PositionBefore, Anchor: "d"}, "bcad", false},
+		{"before an earlier pack", "d", PackPosition{Kind: PositionBefore, Anchor: "b"}, "adbc", false},
+		{"after a later pack", "a", PackPosition{Kind: PositionAfter, Anchor: "c"}, "bcad", false},
+		{"after an earlier pack", "d", PackPosition{Kind: PositionAfter, Anchor: "a"}, "adbc", false},
+		{"already in place", "b", PackPosition{Kind: PositionAfter, Anchor: "a"}, "abcd", false},
+		{"missing anchor", "a", PackPosition{Kind: PositionBefore, Anchor: "z"}, "", true},
+		{"relative to itself", "a", PackPosition{Kind: PositionAfter, Anchor: "a"}, "", true},
+		{"missing pack", "z", PackPosition{Kind: PositionTop}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved, err := PositionPackInConfig(config, tt.packID, tt.position)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", moved)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PositionPackInConfig failed: %v", err)
+			}
+
+			order := ""
+			for _, pack := range moved {
+				order += pack.PackID
+			}
+			if order != tt.expected {
+				t.Errorf("Expected order %s, got %s", tt.expected, order)
+			}
+		})
+	}
+}
+
+func TestMovesToOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		moves   int
+	}{
+		{"unchanged", "abcd", "abcd", 0},
+		{"one pack to the front", "abcd", "dabc", 1},
+		{"swap", "abcd", "bacd", 1},
+		{"reversed", "abcd", "dcba", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := strings.Split(tt.current, "")
+			moves := MovesToOrder(current, strings.Split(tt.target, ""))
+			if len(moves) != tt.moves {
+				t.Errorf("Expected %d moves, got %d: %v", tt.moves, len(moves), moves)
+			}
+
+			config := make(WorldConfig, len(current))
+			for i, id := range current {
+				config[i] = PackReference{PackID: id}
+			}
+			for _, move := range moves {
+				var err error
+				if config, err = MovePackInConfig(config, move.PackID, move.Index); err != nil {
+					t.Fatalf("MovePackInConfig failed: %v", err)
+				}
+			}
+			order := ""
+			for _, pack := range config {
+				order += pack.PackID
+			}
+			if order != tt.target {
+				t.Errorf("Expected order %s after the moves, got %s", tt.target, order)
+			}
+		})
+	}
+}
+
+func TestWorldConfigCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		configData    string
+		expectedCodec string
+		validate      func(*testing.T, map[string]interface{})
+	}{
+		{
+			name: "bare array with extra entry fields",
+			configData: `[
+				{"pack_id": "12345678-1234-1234-1234-123456789abc", "version": [1, 0, 0], "priority": 5}
+			]`,
+			expectedCodec: "array",
+		},
+		{
+			name: "wrapped object",
+			configData: `{
+				"schema": 2,
+				"packs": [
+					{"pack_id": "12345678-1234-1234-1234-123456789abc", "version": [1, 0, 0], "priority": 5}
+				]
+			}`,
+			expectedCodec: "wrapped",
+			validate: func(t *testing.T, root map[string]interface{}) {
+				if root["schema"] != float64(2) {
+					t.Errorf("Expected top-level schema field to be preserved, got %v", root["schema"])
+				}
+				if packs, ok := root["packs"].([]interface{}); !ok || len(packs) != 2 {
+					t.Errorf("Expected 2 packs under 'packs', got %v", root["packs"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "blockbench-config-test")
+			if err != nil {
+				t.Fatalf("Failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			codec, err := DetectWorldConfigCodec([]byte(tt.configData))
+			if err != nil {
+				t.Fatalf("Failed to detect codec: %v", err)
+			}
+			if codec.Name() != tt.expectedCodec {
+				t.Errorf("Expected codec %q, got %q", tt.expectedCodec, codec.Name())
+			}
+
+			configPath := filepath.Join(tempDir, "world_behavior_packs.json")
+			if err := os.WriteFile(configPath, []byte(tt.configData), 0600); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+
+			config, err := LoadWorldConfig(configPath)
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			config = AddPackToConfig(config, "87654321-4321-4321-4321-fedcba987654", [3]int{2, 0, 0})
+			if err := SaveWorldConfig(configPath, config); err != nil {
+				t.Fatalf("Failed to save config: %v", err)
+			}
+
+			reloaded, err := LoadWorldConfig(configPath)
+			if err != nil {
+				t.Fatalf("Failed to reload config: %v", err)
+			}
+			if len(reloaded) != 2 {
+				t.Fatalf("Expected 2 packs after round trip, got %d", len(reloaded))
+			}
+			if string(reloaded[0].Extra["priority"]) != "5" {
+				t.Errorf("Expected extra entry field to be preserved, got %v", reloaded[0].Extra)
+			}
+
+			if tt.validate != nil {
+				data, err := os.ReadFile(configPath)
+				if err != nil {
+					t.Fatalf("Failed to read saved config: %v", err)
+				}
+				var root map[string]interface{}
+				if err := json.Unmarshal(data, &root); err != nil {
+					t.Fatalf("Saved config is not a JSON object: %v", err)
+				}
+				tt.validate(t, root)
+			}
+		})
+	}
+}
+
+func TestSaveWorldConfigRemovedAllPacks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-config-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, "world_behavior_packs.json")
+	config := RemovePackFromConfig(WorldConfig{{PackID: "12345678-1234-1234-1234-123456789abc"}}, "12345678-1234-1234-1234-123456789abc")
+	if err := SaveWorldConfig(configPath, config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+	if string(data) != "[]" {
+		t.Errorf("Expected empty array, got %s", data)
+	}
+}
+
+func TestLoadPackHistory(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-config-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	historyPath := filepath.Join(tempDir, "world_behavior_pack_history.json")
+
+	history, err := LoadPackHistory(historyPath)
+	if err != nil {
+		t.Fatalf("Expected missing history to load, got: %v", err)
+	}
+	if len(history.Packs) != 0 {
+		t.Errorf("Expected empty history, got %d entries", len(history.Packs))
+	}
+
+	historyData := `{
+		"packs": [
+			{
+				"can_be_redownloaded": false,
+				"name": "Test Pack",
+				"uuid": "12345678-1234-1234-1234-123456789abc",
+				"version": [1, 2, 0]
+			}
+		]
+	}`
+	if err := os.WriteFile(historyPath, []byte(historyData), 0600); err != nil {
+		t.Fatalf("Failed to write history file: %v", err)
+	}
+
+	history, err = LoadPackHistory(historyPath)
+	if err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(history.Packs) != 1 {
+		t.Fatalf("Expected 1 history entry, got %d", len(history.Packs))
+	}
+	entry := history.Packs[0]
+	if entry.UUID != "12345678-1234-1234-1234-123456789abc" || entry.Name != "Test Pack" || entry.Version != [3]int{1, 2, 0} {
+		t.Errorf("Unexpected history entry: %+v", entry)
+	}
+	if history.Modified.IsZero() {
+		t.Error("Expected history modification time to be set")
+	}
+
+	if err := os.WriteFile(historyPath, []byte("{invalid"), 0600); err != nil {
+		t.Fatalf("Failed to write history file: %v", err)
+	}
+	if _, err := LoadPackHistory(historyPath); err == nil {
+		t.Error("Expected error for invalid history file")
+	}
+}
+
+func TestWrappedWorldConfigEmptied(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "world_behavior_packs.json")
+	const packID = "12345678-1234-1234-1234-123456789abc"
+	original := `{"schema": 2, "entries": [{"pack_id": "` + packID + `", "version": [1, 0, 0]}], "fork": {"name": "x"}}`
+	if err := os.WriteFile(configPath, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// Removing the last pack leaves an empty list under a key of the fork's own
+	config, err := LoadWorldConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := SaveWorldConfig(configPath, RemovePackFromConfig(config, packID)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+	want := "{\n  \"schema\": 2,\n  \"entries\": [],\n  \"fork\": {\n    \"name\": \"x\"\n  }\n}"
+	if string(data) != want {
+		t.Errorf("Expected the emptied config with its key order kept, got %s", data)
+	}
+
+	config, err = LoadWorldConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load the emptied config: %v", err)
+	}
+	if len(config) != 0 {
+		t.Fatalf("Expected no packs in the emptied config, got %+v", config)
+	}
+	if err := SaveWorldConfig(configPath, AddPackToConfig(config, packID, [3]int{1, 0, 0})); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	data, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved config: %v", err)
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("Saved config is not a JSON object: %v", err)
+	}
+	if _, ok := root["packs"]; ok {
+		t.Errorf("Expected the pack to go back under entries, got %s", data)
+	}
+	if config, err := LoadWorldConfig(configPath); err != nil || len(config) != 1 || config[0].PackID != packID {
+		t.Errorf("Expected the pack back in the config, got %+v, %v", config, err)
+	}
+}
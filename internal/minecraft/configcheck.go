@@ -0,0 +1,173 @@
+package minecraft
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// strictWorldConfigs makes LoadWorldConfig fail on malformed configs instead
+// of warning about them
+var strictWorldConfigs bool
+
+// SetStrictWorldConfigs sets whether LoadWorldConfig rejects world configs
+// with duplicate pack_id entries, invalid UUIDs, or negative versions. By
+// default they are loaded as they are, with a warning for each problem.
+func SetStrictWorldConfigs(strict bool) {
+	strictWorldConfigs = strict
+}
+
+// ConfigProblem is a malformed entry of a world config file
+type ConfigProblem struct {
+	File    string
+	Line    int // Line of the entry, or 0 when it could not be located
+	PackID  string
+	Message string
+}
+
+func (p ConfigProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// WorldConfigError reports the problems that made a strict load reject a
+// world config. It is marked as bberrors.ErrInvalidConfig.
+type WorldConfigError struct {
+	File     string
+	Problems []ConfigProblem
+}
+
+func (e *WorldConfigError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		if problem.Line > 0 {
+			messages = append(messages, fmt.Sprintf("line %d: %s", problem.Line, problem.Message))
+		} else {
+			messages = append(messages, problem.Message)
+		}
+	}
+	return fmt.Sprintf("malformed world config %s: %s", e.File, strings.Join(messages, "; "))
+}
+
+func (e *WorldConfigError) Unwrap() error {
+	return bberrors.ErrInvalidConfig
+}
+
+// CheckWorldConfig returns the malformed entries of a decoded world config:
+// pack_id values that repeat (ignoring case) or are not UUIDs, and versions
+// with negative parts. data is the raw file the config was decoded from, used
+// to find the line of each entry.
+func CheckWorldConfig(filePath string, data []byte, config WorldConfig) []ConfigProblem {
+	lines := worldConfigEntryLines(data, len(config))
+
+	var problems []ConfigProblem
+	firstLine := make(map[string]int, len(config))
+	for i, entry := range config {
+		report := func(format string, args ...any) {
+			problems = append(problems, ConfigProblem{File: filePath, Line: lines[i], PackID: entry.PackID, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if !validation.ValidateUUID(entry.PackID) {
+			report("invalid pack_id %q: not a UUID", entry.PackID)
+		}
+		key := strings.ToLower(entry.PackID)
+		if line, seen := firstLine[key]; seen {
+			if line > 0 {
+				report("duplicate pack_id %s (first listed at line %d)", entry.PackID, line)
+			} else {
+				report("duplicate pack_id %s", entry.PackID)
+			}
+		} else {
+			firstLine[key] = lines[i]
+		}
+		if entry.Version[0] < 0 || entry.Version[1] < 0 || entry.Version[2] < 0 {
+			report("negative version %d.%d.%d for pack_id %s", entry.Version[0], entry.Version[1], entry.Version[2], entry.PackID)
+		}
+	}
+	return problems
+}
+
+// worldConfigEntryLines returns the line of each of the count pack entries in
+// a world config file: the array elements with a pack_id key, in file order.
+// When the entries can't be matched up, every line is 0.
+func worldConfigEntryLines(data []byte, count int) []int {
+	lines := make([]int, count)
+	var offsets []int64
+	if err := walkConfigValue(json.NewDecoder(bytes.NewReader(data)), false, &offsets); err != nil || len(offsets) != count {
+		return lines
+	}
+	for i, offset := range offsets {
+		// The offset is where the previous token ended; the entry starts after
+		// the separators and whitespace that follow it
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+		lines[i] = bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	return lines
+}
+
+// walkConfigValue reads one JSON value, appending the offset of each array
+// element that is an object with a pack_id key
+func walkConfigValue(decoder *json.Decoder, inArray bool, offsets *[]int64) error {
+	start := decoder.InputOffset()
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if key == "pack_id" && inArray {
+				*offsets = append(*offsets, start)
+			}
+			if err := walkConfigValue(decoder, false, offsets); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for decoder.More() {
+			if err := walkConfigValue(decoder, true, offsets); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	_, err = decoder.Token() // The closing } or ]
+	return err
+}
+
+// warnedConfigProblems holds the problems already logged, so a config loaded
+// many times by one command is warned about once
+var warnedConfigProblems sync.Map
+
+// reportConfigProblems returns a WorldConfigError in strict mode, and
+// otherwise logs each problem once
+func reportConfigProblems(filePath string, problems []ConfigProblem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	if strictWorldConfigs {
+		return &WorldConfigError{File: filePath, Problems: problems}
+	}
+	for _, problem := range problems {
+		if _, warned := warnedConfigProblems.LoadOrStore(problem.String(), true); !warned {
+			slog.Warn("Malformed world config entry", "file", problem.File, "line", problem.Line, "pack_id", problem.PackID, "problem", problem.Message)
+		}
+	}
+	return nil
+}
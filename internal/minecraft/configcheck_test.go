@@ -0,0 +1,112 @@
+package minecraft
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+)
+
+const malformedConfig = `[
+  {
+    "pack_id": "12345678-1234-1234-1234-123456789abc",
+    "version": [1, 0, 0]
+  },
+  {"pack_id": "not-a-uuid", "version": [1, -2, 0]},
+  {
+    "pack_id": "12345678-1234-1234-1234-123456789ABC",
+    "version": [1, 0, 0],
+    "extra": {"pack_id": "ignored"}
+  }
+]`
+
+func TestCheckWorldConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "array layout",
+			data: malformedConfig,
+			expected: []string{
+				`config.json:6: invalid pack_id "not-a-uuid": not a UUID`,
+				"config.json:6: negative version 1.-2.0 for pack_id not-a-uuid",
+				"config.json:7: duplicate pack_id 12345678-1234-1234-1234-123456789ABC (first listed at line 2)",
+			},
+		},
+		{
+			name: "wrapped layout",
+			data: "{\n  \"schema\": 2,\n  \"packs\": " + malformedConfig + "\n}",
+			expected: []string{
+				`config.json:8: invalid pack_id "not-a-uuid": not a UUID`,
+				"config.json:8: negative version 1.-2.0 for pack_id not-a-uuid",
+				"config.json:9: duplicate pack_id 12345678-1234-1234-1234-123456789ABC (first listed at line 4)",
+			},
+		},
+		{
+			name:     "valid",
+			data:     `[{"pack_id": "12345678-1234-1234-1234-123456789abc", "version": [1, 0, 0]}]`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := DetectWorldConfigCodec([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("DetectWorldConfigCodec failed: %v", err)
+			}
+			config, err := codec.Decode([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			var got []string
+			for _, problem := range CheckWorldConfig("config.json", []byte(tt.data), config) {
+				got = append(got, problem.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("CheckWorldConfig =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestLoadWorldConfigStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "world_behavior_packs.json")
+	if err := os.WriteFile(path, []byte(malformedConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Lenient by default: the config loads as it is
+	config, err := LoadWorldConfig(path)
+	if err != nil {
+		t.Fatalf("Expected a lenient load to succeed, got: %v", err)
+	}
+	if len(config) != 3 {
+		t.Errorf("Expected 3 entries, got %d", len(config))
+	}
+
+	SetStrictWorldConfigs(true)
+	defer SetStrictWorldConfigs(false)
+
+	_, err = LoadWorldConfig(path)
+	var configErr *WorldConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected a WorldConfigError, got: %v", err)
+	}
+	if len(configErr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d", len(configErr.Problems))
+	}
+	if !errors.Is(err, bberrors.ErrInvalidConfig) {
+		t.Error("Expected the error to be marked as an invalid config")
+	}
+	if !strings.Contains(err.Error(), "line 7: duplicate pack_id") {
+		t.Errorf("Expected the line of the duplicate in %q", err.Error())
+	}
+}
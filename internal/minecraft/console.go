@@ -0,0 +1,119 @@
+package minecraft
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Console target kinds
+const (
+	ConsolePipe   = "pipe"   // Named pipe the server reads its input from
+	ConsoleScreen = "screen" // GNU screen session running the server
+	ConsoleTmux   = "tmux"   // tmux target (session, window, or pane) running the server
+	ConsoleDocker = "docker" // Container selected with --docker, through its send-command helper
+)
+
+// ConsoleTarget is where console commands for a running server are written
+type ConsoleTarget struct {
+	Kind   string
+	Target string // Pipe path, screen session, or tmux target; empty for docker
+}
+
+// ParseConsoleTarget parses kind:target, such as pipe:/srv/bds/console,
+// screen:bedrock, or tmux:bedrock:0, or plain docker
+func ParseConsoleTarget(spec string) (ConsoleTarget, error) {
+	kind, target, _ := strings.Cut(spec, ":")
+	switch kind {
+	case ConsolePipe, ConsoleScreen, ConsoleTmux:
+		if target == "" {
+			return ConsoleTarget{}, fmt.Errorf("console target %q needs a %s name after the colon", spec, kind)
+		}
+	case ConsoleDocker:
+		if target != "" {
+			return ConsoleTarget{}, fmt.Errorf("console target %q takes no argument; the container comes from --docker", spec)
+		}
+	default:
+		return ConsoleTarget{}, fmt.Errorf("unknown console target %q (expected pipe:<path>, screen:<session>, tmux:<target>, or docker)", spec)
+	}
+	return ConsoleTarget{Kind: kind, Target: target}, nil
+}
+
+// Send writes commands to the server console, one per line. Docker targets
+// are sent by the caller, which holds the container client.
+func (t ConsoleTarget) Send(commands []string) error {
+	lines := make([]string, len(commands))
+	for i, command := range commands {
+		lines[i] = consoleLine(command)
+	}
+
+	switch t.Kind {
+	case ConsolePipe:
+		return sendToPipe(t.Target, lines)
+	case ConsoleScreen:
+		for _, command := range lines {
+			// screen interprets ^ and \ sequences in stuffed text
+			command = strings.NewReplacer("^", "", "\\", "").Replace(command)
+			// #nosec G204 - arguments are passed directly, not through a shell
+			if output, err := exec.Command("screen", "-S", t.Target, "-p", "0", "-X", "stuff", command+"\r").CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to send to screen session %s: %w: %s", t.Target, err, strings.TrimSpace(string(output)))
+			}
+		}
+		return nil
+	case ConsoleTmux:
+		for _, command := range lines {
+			// -l sends the text literally, so key names in it aren't interpreted
+			// #nosec G204 - arguments are passed directly, not through a shell
+			if output, err := exec.Command("tmux", "send-keys", "-t", t.Target, "-l", command).CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to send to tmux target %s: %w: %s", t.Target, err, strings.TrimSpace(string(output)))
+			}
+			// #nosec G204 - arguments are passed directly, not through a shell
+			if output, err := exec.Command("tmux", "send-keys", "-t", t.Target, "Enter").CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to send to tmux target %s: %w: %s", t.Target, err, strings.TrimSpace(string(output)))
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("console target %s must be sent by the caller", t.Kind)
+	}
+}
+
+// sendToPipe writes commands to a named pipe. The pipe is opened without
+// blocking, so a server that isn't reading it is an error instead of a hang.
+func sendToPipe(path string, commands []string) error {
+	// #nosec G304 - the pipe path is configured by the user running blockbench
+	pipe, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open console pipe %s (is the server reading it?): %w", path, err)
+	}
+	if _, err := pipe.WriteString(strings.Join(commands, "\n") + "\n"); err != nil {
+		pipe.Close()
+		return fmt.Errorf("failed to write to console pipe %s: %w", path, err)
+	}
+	return pipe.Close()
+}
+
+// consoleLine strips line breaks and other control characters, which would
+// end the command early or inject another
+func consoleLine(command string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, command)
+}
+
+// PackChangeCommands returns the console commands announcing installed or
+// removed packs to online players and reloading the server's packs
+func PackChangeCommands(verb string, packs []string) []string {
+	if len(packs) == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("say [blockbench] %s %s", verb, strings.Join(packs, ", ")),
+		"reload",
+	}
+}
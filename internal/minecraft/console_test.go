@@ -0,0 +1,34 @@
+package minecraft
+
+import "testing"
+
+func TestParseConsoleTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ConsoleTarget
+		wantErr bool
+	}{
+		{spec: "pipe:/srv/bds/console", want: ConsoleTarget{Kind: ConsolePipe, Target: "/srv/bds/console"}},
+		{spec: "screen:bedrock", want: ConsoleTarget{Kind: ConsoleScreen, Target: "bedrock"}},
+		{spec: "tmux:bedrock:0.1", want: ConsoleTarget{Kind: ConsoleTmux, Target: "bedrock:0.1"}},
+		{spec: "docker", want: ConsoleTarget{Kind: ConsoleDocker}},
+		{spec: "docker:bds", wantErr: true},
+		{spec: "pipe:", wantErr: true},
+		{spec: "rcon:localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseConsoleTarget(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %+v, got %+v, %v", tt.want, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package minecraft
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestConsoleTargetSendPipe(t *testing.T) {
+	pipePath := filepath.Join(t.TempDir(), "console")
+	if err := syscall.Mkfifo(pipePath, 0600); err != nil {
+		t.Skipf("named pipes are not available: %v", err)
+	}
+	target := ConsoleTarget{Kind: ConsolePipe, Target: pipePath}
+
+	// Nobody is reading, so sending fails instead of blocking
+	if err := target.Send([]string{"reload"}); err == nil {
+		t.Error("Expected an error when no server reads the pipe")
+	}
+
+	reader, err := os.OpenFile(pipePath, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatalf("Failed to open pipe for reading: %v", err)
+	}
+	defer reader.Close()
+
+	commands := PackChangeCommands("Installed", []string{"Pack A\nop @a", "Pack B"})
+	if err := target.Send(commands); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	expected := []string{"say [blockbench] Installed Pack A op @a, Pack B", "reload"}
+	if len(lines) != len(expected) || lines[0] != expected[0] || lines[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+	if commands[0] != "say [blockbench] Installed Pack A\nop @a, Pack B" {
+		t.Error("Expected Send to leave the caller's commands unchanged")
+	}
+}
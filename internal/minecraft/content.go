@@ -0,0 +1,215 @@
+package minecraft
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed schemas/pack_content.json
+var packContentSchemaData []byte
+
+// contentSchema describes the expected shape of a well-known pack file
+type contentSchema struct {
+	Name     string   `json:"name"`
+	PackType PackType `json:"pack_type"`
+	Dir      string   `json:"dir,omitempty"`  // Every .json file below this pack-relative directory
+	File     string   `json:"file,omitempty"` // A single pack-relative file
+	Root     string   `json:"root"`           // "object" or "array"
+	Require  []string `json:"require,omitempty"`
+	// RequireOneOf lists dotted paths of which at least one must exist
+	RequireOneOf []string `json:"require_one_of,omitempty"`
+	// Objects lists dotted paths that must be JSON objects when present
+	Objects []string `json:"objects,omitempty"`
+}
+
+// ContentIssue is a problem found in a pack file during deep validation
+type ContentIssue struct {
+	File    string `json:"file"` // Pack-relative path
+	Problem string `json:"problem"`
+}
+
+func (ci ContentIssue) String() string {
+	return fmt.Sprintf("%s: %s", ci.File, ci.Problem)
+}
+
+// loadContentSchemas parses the embedded schema definitions
+func loadContentSchemas() ([]contentSchema, error) {
+	var schemas []contentSchema
+	if err := json.Unmarshal(packContentSchemaData, &schemas); err != nil {
+		return nil, fmt.Errorf("failed to parse embedded content schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// ValidatePackContent checks the JSON files of a pack beyond manifest.json.
+// Every .json file must parse (Bedrock permits comments), and well-known files
+// such as entities, items, recipes, blocks, texture atlases, and sound
+// definitions must match the embedded schemas.
+func ValidatePackContent(packDir string, packType PackType) ([]ContentIssue, error) {
+	schemas, err := loadContentSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []ContentIssue
+	err = filepath.WalkDir(packDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(packDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "manifest.json" {
+			return nil // Covered by ValidateManifest
+		}
+
+		// #nosec G304 - path comes from walking the pack directory
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+
+		var doc interface{}
+		if err := json.Unmarshal(stripJSONComments(data), &doc); err != nil {
+			issues = append(issues, ContentIssue{File: rel, Problem: fmt.Sprintf("malformed JSON: %v", err)})
+			return nil
+		}
+
+		for _, schema := range schemas {
+			if schema.PackType == packType && schema.matches(rel) {
+				for _, problem := range schema.check(doc) {
+					issues = append(issues, ContentIssue{File: rel, Problem: problem})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan pack content: %w", err)
+	}
+
+	return issues, nil
+}
+
+// matches reports whether a pack-relative path is covered by the schema
+func (cs contentSchema) matches(rel string) bool {
+	if cs.File != "" {
+		return rel == cs.File
+	}
+	return cs.Dir != "" && strings.HasPrefix(rel, cs.Dir+"/")
+}
+
+// check returns the schema violations of a parsed document
+func (cs contentSchema) check(doc interface{}) []string {
+	var problems []string
+
+	switch cs.Root {
+	case "object":
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return []string{fmt.Sprintf("%s file must be a JSON object", cs.Name)}
+		}
+	case "array":
+		if _, ok := doc.([]interface{}); !ok {
+			return []string{fmt.Sprintf("%s file must be a JSON array", cs.Name)}
+		}
+	}
+
+	for _, path := range cs.Require {
+		if _, ok := lookupJSONPath(doc, path); !ok {
+			problems = append(problems, fmt.Sprintf("%s is missing required field %q", cs.Name, path))
+		}
+	}
+
+	if len(cs.RequireOneOf) > 0 {
+		found := false
+		for _, path := range cs.RequireOneOf {
+			if _, ok := lookupJSONPath(doc, path); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s has no recognized definition (expected one of %s)",
+				cs.Name, strings.Join(cs.RequireOneOf, ", ")))
+		}
+	}
+
+	for _, path := range cs.Objects {
+		if value, ok := lookupJSONPath(doc, path); ok {
+			if _, isObject := value.(map[string]interface{}); !isObject {
+				problems = append(problems, fmt.Sprintf("%s field %q must be an object", cs.Name, path))
+			}
+		}
+	}
+
+	return problems
+}
+
+// lookupJSONPath resolves a dotted path of object keys in a parsed document
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// stripJSONComments removes // and /* */ comments outside of strings.
+// Bedrock's JSON parser accepts comments, so pack files commonly contain them.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++ // Skip the closing slash
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
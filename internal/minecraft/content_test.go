@@ -0,0 +1,118 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePackContent(t *testing.T) {
+	tests := []struct {
+		name           string
+		packType       PackType
+		files          map[string]string
+		expectedIssues []string // Pack-relative files expected to have issues
+	}{
+		{
+			name:     "valid behavior pack with comments",
+			packType: PackTypeBehavior,
+			files: map[string]string{
+				"entities/cow.json": `{
+					// Custom cow
+					"format_version": "1.20.0",
+					"minecraft:entity": {"description": {"identifier": "test:cow"}}
+				}`,
+				"recipes/stick.json": `{"format_version": "1.20.0", "minecraft:recipe_shaped": {"description": {"identifier": "test:stick"}}}`,
+			},
+		},
+		{
+			name:     "entity missing identifier and malformed item",
+			packType: PackTypeBehavior,
+			files: map[string]string{
+				"entities/cow.json": `{"format_version": "1.20.0", "minecraft:entity": {"description": {}}}`,
+				"items/sword.json":  `{"format_version": "1.20.0", "minecraft:item": {`,
+			},
+			expectedIssues: []string{"entities/cow.json", "items/sword.json"},
+		},
+		{
+			name:     "recipe with unknown type",
+			packType: PackTypeBehavior,
+			files: map[string]string{
+				"recipes/odd.json": `{"format_version": "1.20.0", "minecraft:recipe_unknown": {}}`,
+			},
+			expectedIssues: []string{"recipes/odd.json"},
+		},
+		{
+			name:     "resource pack texture files",
+			packType: PackTypeResource,
+			files: map[string]string{
+				"textures/textures_list.json":   `{"not": "an array"}`,
+				"textures/terrain_texture.json": `{"texture_data": []}`,
+				"sounds/sound_definitions.json": `{"format_version": "1.14.0", "sound_definitions": {}}`,
+			},
+			expectedIssues: []string{"textures/textures_list.json", "textures/terrain_texture.json"},
+		},
+		{
+			name:     "behavior schemas do not apply to resource packs",
+			packType: PackTypeResource,
+			files: map[string]string{
+				"entities/cow.entity.json": `{"format_version": "1.10.0", "minecraft:client_entity": {}}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir, err := os.MkdirTemp("", "blockbench-content-test")
+			if err != nil {
+				t.Fatalf("Failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			for rel, content := range tt.files {
+				path := filepath.Join(tempDir, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+					t.Fatalf("Failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+					t.Fatalf("Failed to write %s: %v", rel, err)
+				}
+			}
+
+			issues, err := ValidatePackContent(tempDir, tt.packType)
+			if err != nil {
+				t.Fatalf("ValidatePackContent failed: %v", err)
+			}
+
+			files := make(map[string]bool)
+			for _, issue := range issues {
+				files[issue.File] = true
+			}
+			if len(files) != len(tt.expectedIssues) {
+				t.Errorf("Expected issues in %v, got %v", tt.expectedIssues, issues)
+			}
+			for _, rel := range tt.expectedIssues {
+				if !files[rel] {
+					t.Errorf("Expected an issue for %s, got %v", rel, issues)
+				}
+			}
+		})
+	}
+}
+
+func TestStripJSONComments(t *testing.T) {
+	input := `{
+		// line comment
+		"url": "http://example.com", /* block
+		comment */ "escaped": "quote \" // not a comment"
+	}`
+
+	stripped := string(stripJSONComments([]byte(input)))
+	if strings.Contains(stripped, "line comment") || strings.Contains(stripped, "block") {
+		t.Errorf("Expected comments to be removed, got %s", stripped)
+	}
+	if !strings.Contains(stripped, "http://example.com") || !strings.Contains(stripped, `quote \" // not a comment`) {
+		t.Errorf("Expected string contents to be preserved, got %s", stripped)
+	}
+}
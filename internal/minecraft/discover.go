@@ -0,0 +1,112 @@
+package minecraft
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDiscoveryDepth is how many directory levels below each root are searched
+const DefaultDiscoveryDepth = 4
+
+// DiscoveredServer is a directory that looks like a Bedrock dedicated server
+type DiscoveredServer struct {
+	Path      string `json:"path"`
+	WorldName string `json:"world_name,omitempty"`
+	Problem   string `json:"problem,omitempty"` // Why the server cannot be managed as-is, if anything
+}
+
+// DefaultDiscoveryRoots returns the common locations servers are installed under
+func DefaultDiscoveryRoots() []string {
+	roots := []string{"/home", "/opt", "/srv", "/var/lib/docker/volumes"}
+	if home, err := os.UserHomeDir(); err == nil && !strings.HasPrefix(home, "/home/") {
+		roots = append([]string{home}, roots...)
+	}
+	return roots
+}
+
+// DiscoverServers walks each root up to maxDepth levels deep looking for directories
+// that contain both server.properties and a worlds/ directory. Unreadable
+// directories are skipped; missing roots are ignored.
+func DiscoverServers(roots []string, maxDepth int) ([]DiscoveredServer, error) {
+	var servers []DiscoveredServer
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		rootDepth := strings.Count(root, string(filepath.Separator))
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Permission errors and vanished directories are expected on a live host
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root && skipDiscoveryDir(d.Name()) {
+				return fs.SkipDir
+			}
+			if strings.Count(path, string(filepath.Separator))-rootDepth > maxDepth {
+				return fs.SkipDir
+			}
+
+			if !isServerDir(path) {
+				return nil
+			}
+			if !seen[path] {
+				seen[path] = true
+				servers = append(servers, inspectServerDir(path))
+			}
+			// Servers don't nest; skip the worlds and pack directories below
+			return fs.SkipDir
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return servers, nil
+}
+
+// skipDiscoveryDir reports whether a directory is never worth descending into
+func skipDiscoveryDir(name string) bool {
+	switch name {
+	case "node_modules", "proc", "sys", "dev":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// isServerDir reports whether dir has the files that mark a Bedrock server root
+func isServerDir(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "server.properties")); err != nil || info.IsDir() {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "worlds"))
+	return err == nil && info.IsDir()
+}
+
+// inspectServerDir resolves the world name and checks the structure blockbench needs
+func inspectServerDir(dir string) DiscoveredServer {
+	server := DiscoveredServer{Path: dir}
+
+	paths, err := NewServerPaths(dir)
+	if err != nil {
+		server.Problem = err.Error()
+		return server
+	}
+	server.WorldName = filepath.Base(filepath.Dir(paths.WorldBehaviorPacks))
+
+	if err := paths.ValidateServerStructure(); err != nil {
+		server.Problem = err.Error()
+	}
+	return server
+}
@@ -0,0 +1,68 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverServers(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-discover-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A complete server, an incomplete one, a hidden one, and one too deep to reach
+	servers := map[string]bool{
+		filepath.Join(tempDir, "opt", "bedrock"):           true,
+		filepath.Join(tempDir, "srv", "broken"):            false,
+		filepath.Join(tempDir, ".cache", "bedrock"):        true,
+		filepath.Join(tempDir, "a", "b", "c", "d", "deep"): true,
+	}
+	for dir, complete := range servers {
+		if err := os.MkdirAll(filepath.Join(dir, "worlds", "W"), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+			t.Fatalf("Failed to write server.properties: %v", err)
+		}
+		if complete {
+			for _, packDir := range []string{"development_behavior_packs", "development_resource_packs"} {
+				if err := os.MkdirAll(filepath.Join(dir, packDir), 0750); err != nil {
+					t.Fatalf("Failed to create pack dir: %v", err)
+				}
+			}
+		}
+	}
+
+	found, err := DiscoverServers([]string{tempDir, filepath.Join(tempDir, "missing")}, 3)
+	if err != nil {
+		t.Fatalf("DiscoverServers failed: %v", err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 servers, got %d: %+v", len(found), found)
+	}
+
+	byPath := make(map[string]DiscoveredServer)
+	for _, server := range found {
+		byPath[server.Path] = server
+	}
+
+	good, ok := byPath[filepath.Join(tempDir, "opt", "bedrock")]
+	if !ok {
+		t.Fatal("Expected to discover opt/bedrock")
+	}
+	if good.WorldName != "W" || good.Problem != "" {
+		t.Errorf("Unexpected result for complete server: %+v", good)
+	}
+
+	broken, ok := byPath[filepath.Join(tempDir, "srv", "broken")]
+	if !ok {
+		t.Fatal("Expected to discover srv/broken")
+	}
+	if broken.Problem == "" {
+		t.Error("Expected a problem to be reported for a server without pack directories")
+	}
+}
@@ -0,0 +1,252 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+)
+
+// Layout of the contents.json of an encrypted Marketplace pack: a 256-byte
+// header holding the magic number and content ID, then the file list
+// encrypted with the pack's content key
+const (
+	encryptedContentsMagic  = 0x9BCFB9FC
+	encryptedContentsHeader = 0x100
+	encryptedContentIDAt    = 0x10
+)
+
+// contentKeySize is the length of a content key and of the per-file keys:
+// 32 characters, used as an AES-256 key
+const contentKeySize = 32
+
+// EncryptedPackError reports a pack whose files are encrypted with a content
+// key blockbench wasn't given. It is marked as bberrors.ErrEncryptedPack.
+type EncryptedPackError struct {
+	Pack      string // Pack name
+	ContentID string
+}
+
+func (e *EncryptedPackError) Error() string {
+	return fmt.Sprintf("%s is an encrypted Marketplace pack (content ID %s): its files can only be read with the content key that comes with a license for it, so a dedicated server can't load it as it is", e.Pack, e.ContentID)
+}
+
+func (e *EncryptedPackError) Unwrap() error {
+	return bberrors.ErrEncryptedPack
+}
+
+// EncryptedContentID reports whether the pack in packDir is encrypted,
+// returning the content ID its contents.json header names
+func EncryptedContentID(packDir string) (string, bool, error) {
+	// #nosec G304 - contents.json of a pack being installed
+	file, err := os.Open(filepath.Join(packDir, "contents.json"))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	defer file.Close()
+	return ReadEncryptedContentID(file)
+}
+
+// ReadEncryptedContentID is EncryptedContentID for a pack's contents.json
+// read from r, such as an archive entry
+func ReadEncryptedContentID(r io.Reader) (string, bool, error) {
+	header := make([]byte, encryptedContentsHeader)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", false, nil // Too short for the header, so plain JSON
+	}
+	return parseEncryptedHeader(header)
+}
+
+// parseEncryptedHeader reads the content ID from an encrypted contents.json
+// header, reporting whether the header is one
+func parseEncryptedHeader(header []byte) (string, bool, error) {
+	if len(header) < encryptedContentsHeader || binary.LittleEndian.Uint32(header[4:8]) != encryptedContentsMagic {
+		return "", false, nil
+	}
+	length := int(header[encryptedContentIDAt])
+	if encryptedContentIDAt+1+length > encryptedContentsHeader {
+		return "", true, fmt.Errorf("invalid content ID length %d in encrypted contents.json", length)
+	}
+	return string(header[encryptedContentIDAt+1 : encryptedContentIDAt+1+length]), true, nil
+}
+
+// ContentKeys are content keys for encrypted packs, by content ID or pack
+// UUID. A key given without an ID applies to every pack.
+type ContentKeys struct {
+	byID     map[string]string
+	fallback string
+}
+
+// ParseContentKeys parses a content key file: one key per line, either on
+// its own or as <content-id or pack UUID>=<key>. Blank lines and lines
+// starting with # are skipped.
+func ParseContentKeys(data []byte) (*ContentKeys, error) {
+	keys := &ContentKeys{byID: make(map[string]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, key, hasID := strings.Cut(line, "=")
+		if !hasID {
+			id, key = "", line
+		}
+		id, key = strings.TrimSpace(id), strings.TrimSpace(key)
+		if len(key) != contentKeySize {
+			return nil, fmt.Errorf("line %d: content key is %d characters, expected %d", lineNumber, len(key), contentKeySize)
+		}
+		switch {
+		case !hasID:
+			if keys.fallback != "" {
+				return nil, fmt.Errorf("line %d: more than one key without an ID", lineNumber)
+			}
+			keys.fallback = key
+		case id == "":
+			return nil, fmt.Errorf("line %d: empty ID before =", lineNumber)
+		default:
+			keys.byID[strings.ToLower(id)] = key
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if keys.fallback == "" && len(keys.byID) == 0 {
+		return nil, fmt.Errorf("no content keys found")
+	}
+	return keys, nil
+}
+
+// LoadContentKeys reads a content key file
+func LoadContentKeys(path string) (*ContentKeys, error) {
+	// #nosec G304 - key file named by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read content key file: %w", err)
+	}
+	keys, err := ParseContentKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content key file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// For returns the key for a pack, looked up by content ID, then pack UUID,
+// then the key given without an ID
+func (k *ContentKeys) For(contentID, packUUID string) (string, bool) {
+	if k == nil {
+		return "", false
+	}
+	for _, id := range []string{contentID, packUUID} {
+		if key, ok := k.byID[strings.ToLower(id)]; ok && id != "" {
+			return key, true
+		}
+	}
+	return k.fallback, k.fallback != ""
+}
+
+// encryptedContents is the decrypted file list of an encrypted pack
+type encryptedContents struct {
+	Content []struct {
+		Path string `json:"path"`
+		Key  string `json:"key"` // Empty for files stored unencrypted
+	} `json:"content"`
+}
+
+// DecryptPack decrypts an encrypted pack in place with its content key: the
+// contents.json file list, then every file it gives a key for. contents.json
+// is rewritten as the plain file list.
+func DecryptPack(packDir, contentKey string) error {
+	contentsPath := filepath.Join(packDir, "contents.json")
+	// #nosec G304 - contents.json of a pack being installed
+	data, err := os.ReadFile(contentsPath)
+	if err != nil {
+		return err
+	}
+	if _, encrypted, err := parseEncryptedHeader(data); err != nil || !encrypted {
+		if err == nil {
+			err = fmt.Errorf("contents.json is not encrypted")
+		}
+		return err
+	}
+
+	plain, err := decryptCFB8(data[encryptedContentsHeader:], contentKey)
+	if err != nil {
+		return err
+	}
+	var contents encryptedContents
+	if err := json.Unmarshal(plain, &contents); err != nil {
+		return fmt.Errorf("wrong content key: contents.json does not decrypt to a file list")
+	}
+
+	for _, entry := range contents.Content {
+		if entry.Key == "" {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return fmt.Errorf("contents.json lists a path outside the pack: %q", entry.Path)
+		}
+		filePath := filepath.Join(packDir, filepath.FromSlash(entry.Path))
+		// #nosec G304 - path checked to stay inside the pack above
+		encryptedFile, err := os.ReadFile(filePath)
+		if os.IsNotExist(err) {
+			continue // Listed but not shipped, as directories are
+		}
+		if err != nil {
+			return err
+		}
+		decrypted, err := decryptCFB8(encryptedFile, entry.Key)
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Path, err)
+		}
+		if err := os.WriteFile(filePath, decrypted, 0600); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(contentsPath, plain, 0600)
+}
+
+// decryptCFB8 decrypts data with AES-256 in 8-bit cipher feedback mode, the
+// mode Marketplace packs use, with the first 16 bytes of the key as the IV
+func decryptCFB8(data []byte, key string) ([]byte, error) {
+	if len(key) != contentKeySize {
+		return nil, fmt.Errorf("key is %d characters, expected %d", len(key), contentKeySize)
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cfb8(block, []byte(key[:aes.BlockSize]), data, false), nil
+}
+
+// cfb8 runs AES-CFB8 over data, encrypting or decrypting
+func cfb8(block cipher.Block, iv, data []byte, encrypt bool) []byte {
+	shift := append([]byte(nil), iv...)
+	stream := make([]byte, aes.BlockSize)
+	out := make([]byte, len(data))
+	for i, b := range data {
+		block.Encrypt(stream, shift)
+		out[i] = b ^ stream[0]
+		feedback := b // The ciphertext byte feeds back
+		if encrypt {
+			feedback = out[i]
+		}
+		copy(shift, shift[1:])
+		shift[aes.BlockSize-1] = feedback
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package minecraft
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+)
+
+const (
+	testContentKey = "0123456789abcdefghijklmnopqrstuv"
+	testFileKey    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+)
+
+// encryptForTest encrypts data the way Marketplace packs are
+func encryptForTest(t *testing.T, data []byte, key string) []byte {
+	t.Helper()
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	return cfb8(block, []byte(key[:aes.BlockSize]), data, true)
+}
+
+// writeEncryptedPack writes a pack whose contents.json and texture are
+// encrypted, with its manifest in plain text
+func writeEncryptedPack(t *testing.T, dir, contentID string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "textures"), 0750); err != nil {
+		t.Fatalf("Failed to create pack: %v", err)
+	}
+	files := map[string][]byte{
+		"manifest.json":     []byte(`{"format_version":2}`),
+		"textures/sun.png":  encryptForTest(t, []byte("sunny pixels"), testFileKey),
+		"textures/moon.png": []byte("plain pixels"),
+	}
+	header := make([]byte, encryptedContentsHeader)
+	binary.LittleEndian.PutUint32(header[4:8], encryptedContentsMagic)
+	header[encryptedContentIDAt] = byte(len(contentID))
+	copy(header[encryptedContentIDAt+1:], contentID)
+	list := `{"content":[{"path":"manifest.json"},{"path":"textures/sun.png","key":"` + testFileKey + `"},{"path":"textures/moon.png","key":""}]}`
+	files["contents.json"] = append(header, encryptForTest(t, []byte(list), testContentKey)...)
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), data, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestEncryptedContentID(t *testing.T) {
+	encrypted := t.TempDir()
+	writeEncryptedPack(t, encrypted, "a1b2c3d4")
+	if id, ok, err := EncryptedContentID(encrypted); err != nil || !ok || id != "a1b2c3d4" {
+		t.Errorf("EncryptedContentID() = %q, %v, %v; want the content ID", id, ok, err)
+	}
+
+	plain := t.TempDir()
+	if err := os.WriteFile(filepath.Join(plain, "contents.json"), []byte(`{"content":[]}`), 0600); err != nil {
+		t.Fatalf("Failed to write contents.json: %v", err)
+	}
+	for _, dir := range []string{plain, t.TempDir()} {
+		if _, ok, err := EncryptedContentID(dir); ok || err != nil {
+			t.Errorf("Expected %s not to be encrypted, got %v, %v", dir, ok, err)
+		}
+	}
+}
+
+func TestDecryptPack(t *testing.T) {
+	dir := t.TempDir()
+	writeEncryptedPack(t, dir, "a1b2c3d4")
+
+	if err := DecryptPack(dir, testFileKey); err == nil {
+		t.Error("Expected the wrong key to be rejected")
+	}
+	if err := DecryptPack(dir, testContentKey); err != nil {
+		t.Fatalf("DecryptPack failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"textures/sun.png": "sunny pixels", "textures/moon.png": "plain pixels"} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	if _, ok, _ := EncryptedContentID(dir); ok {
+		t.Error("Expected contents.json to be plain after decrypting")
+	}
+}
+
+func TestContentKeys(t *testing.T) {
+	keys, err := ParseContentKeys([]byte("# keys\nA1B2C3D4=" + testContentKey + "\n\n" + testFileKey + "\n"))
+	if err != nil {
+		t.Fatalf("ParseContentKeys failed: %v", err)
+	}
+	if key, ok := keys.For("a1b2c3d4", ""); !ok || key != testContentKey {
+		t.Errorf("Expected the key for the content ID, got %q, %v", key, ok)
+	}
+	if key, ok := keys.For("other", "11111111-1111-1111-1111-111111111111"); !ok || key != testFileKey {
+		t.Errorf("Expected the key without an ID, got %q, %v", key, ok)
+	}
+
+	for _, data := range []string{"", "short", "=" + testContentKey, testContentKey + "\n" + testFileKey} {
+		if _, err := ParseContentKeys([]byte(data)); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestEncryptedPackError(t *testing.T) {
+	err := error(&EncryptedPackError{Pack: "Pack", ContentID: "a1b2c3d4"})
+	if !errors.Is(err, bberrors.ErrEncryptedPack) || bberrors.ExitCode(err) != bberrors.ExitEncryptedPack {
+		t.Errorf("Expected the error to be marked as an encrypted pack, got exit code %d", bberrors.ExitCode(err))
+	}
+}
@@ -0,0 +1,226 @@
+package minecraft
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/pkg/nbt"
+)
+
+// ExperimentBetaAPIs is the level.dat key of the Beta APIs experiment, which
+// beta versions of the script modules need
+const ExperimentBetaAPIs = "gametest"
+
+// experimentTitles are the names the world settings screen shows for the
+// experiment keys of level.dat
+var experimentTitles = map[string]string{
+	ExperimentBetaAPIs:             "Beta APIs",
+	"data_driven_items":            "Holiday Creator Features",
+	"upcoming_creator_features":    "Upcoming Creator Features",
+	"experimental_molang_features": "Experimental MoLang Features",
+	"data_driven_biomes":           "Custom Biomes",
+	"cameras":                      "Experimental Cameras",
+	"villager_trades_rebalance":    "Villager Trade Rebalancing",
+}
+
+// experimentBookkeeping are the keys of level.dat's experiments compound that
+// record experiment use rather than toggle one
+var experimentBookkeeping = []string{"experiments_ever_used", "saved_with_toggled_experiments"}
+
+// ExperimentTitle returns the display name of an experiment key, or the key
+// itself when it is not a known experiment
+func ExperimentTitle(key string) string {
+	if title, ok := experimentTitles[key]; ok {
+		return title
+	}
+	return key
+}
+
+// IsBetaModuleVersion reports whether a script module version, such as
+// 1.12.0-beta, is a beta API version
+func IsBetaModuleVersion(version string) bool {
+	return strings.Contains(strings.ToLower(version), "beta")
+}
+
+// RequiredExperiments returns the experiment keys a pack needs enabled on the
+// world: Beta APIs when it depends on a beta script module version
+func (m *Manifest) RequiredExperiments() []string {
+	for _, dep := range m.Dependencies {
+		if dep.ModuleName != "" && IsBetaModuleVersion(dep.ModuleVersion) {
+			return []string{ExperimentBetaAPIs}
+		}
+	}
+	return nil
+}
+
+// LoadLevelDat reads and decodes a world's level.dat
+func LoadLevelDat(path string) (*nbt.LevelDat, error) {
+	// #nosec G304 - path is the level.dat of the managed world
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read level.dat: %w", err)
+	}
+	level, err := nbt.ParseLevelDat(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return level, nil
+}
+
+// SaveLevelDat atomically replaces a world's level.dat, keeping its file mode
+func SaveLevelDat(path string, level *nbt.LevelDat) error {
+	data, err := level.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode level.dat: %w", err)
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, perm); err != nil {
+		return fmt.Errorf("failed to write temp level.dat: %w", err)
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		_ = os.Remove(tmpFile) // #nosec G104 - cleanup on error path, already returning error
+		return fmt.Errorf("failed to replace level.dat: %w", err)
+	}
+	return nil
+}
+
+// WorldExperiments returns the experiments of the server's world that are
+// turned on, sorted by key
+func (s *Server) WorldExperiments() ([]string, error) {
+	level, err := LoadLevelDat(s.Paths.LevelDat)
+	if err != nil {
+		return nil, err
+	}
+	return enabledExperiments(level.Root), nil
+}
+
+// enabledExperiments returns the experiment keys of a level.dat root that
+// are set to a non-zero byte
+func enabledExperiments(root nbt.Compound) []string {
+	experiments, _ := root.Compound("experiments")
+	var enabled []string
+	for _, tag := range experiments {
+		if isExperimentBookkeeping(tag.Name) {
+			continue
+		}
+		if value, ok := tag.Value.(int8); ok && value != 0 {
+			enabled = append(enabled, tag.Name)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+func isExperimentBookkeeping(key string) bool {
+	for _, bookkeeping := range experimentBookkeeping {
+		if key == bookkeeping {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableWorldExperiments turns experiments on in the world's level.dat,
+// marking the world as having used experiments as the game does, and returns
+// the keys that were off. level.dat is left untouched when every experiment
+// is already on. The server must be stopped, or it overwrites the change.
+func (s *Server) EnableWorldExperiments(keys []string) ([]string, error) {
+	level, err := LoadLevelDat(s.Paths.LevelDat)
+	if err != nil {
+		return nil, err
+	}
+
+	on := make(map[string]bool)
+	for _, key := range enabledExperiments(level.Root) {
+		on[key] = true
+	}
+	var changed []string
+	for _, key := range keys {
+		if !on[key] {
+			on[key] = true
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	experiments, _ := level.Root.Compound("experiments")
+	for _, key := range changed {
+		experiments = experiments.Set(key, int8(1))
+	}
+	for _, key := range experimentBookkeeping {
+		experiments = experiments.Set(key, int8(1))
+	}
+	level.Root = level.Root.Set("experiments", experiments)
+
+	if err := SaveLevelDat(s.Paths.LevelDat, level); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// WorldInfo is what a world's level.dat says about it
+type WorldInfo struct {
+	LevelDat            string            `json:"level_dat"`
+	Name                string            `json:"name"`                   // LevelName, the name shown in the world list
+	GameVersion         string            `json:"game_version,omitempty"` // Version that last opened the world, e.g. 1.21.50.7
+	StorageVersion      int32             `json:"storage_version"`
+	Experiments         []ExperimentState `json:"experiments"` // Every experiment level.dat lists, on or off
+	ExperimentsEverUsed bool              `json:"experiments_ever_used"`
+}
+
+// ExperimentState is one experiment toggle of a world
+type ExperimentState struct {
+	Key     string `json:"key"`
+	Title   string `json:"title"`
+	Enabled bool   `json:"enabled"`
+}
+
+// WorldInfo reads the server's world level.dat
+func (s *Server) WorldInfo() (*WorldInfo, error) {
+	level, err := LoadLevelDat(s.Paths.LevelDat)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &WorldInfo{LevelDat: s.Paths.LevelDat, StorageVersion: level.StorageVersion, Experiments: []ExperimentState{}}
+	info.Name, _ = level.Root.String("LevelName")
+	if value, ok := level.Root.Get("lastOpenedWithVersion"); ok {
+		if list, ok := value.(nbt.List); ok {
+			// The list is major, minor, patch, revision, and a fifth number
+			// that isn't part of the version
+			parts := make([]string, 0, 4)
+			for i, item := range list.Items {
+				if i < 4 {
+					parts = append(parts, fmt.Sprint(item))
+				}
+			}
+			info.GameVersion = strings.Join(parts, ".")
+		}
+	}
+
+	experiments, _ := level.Root.Compound("experiments")
+	for _, tag := range experiments {
+		value, ok := tag.Value.(int8)
+		if !ok {
+			continue
+		}
+		if tag.Name == "experiments_ever_used" {
+			info.ExperimentsEverUsed = value != 0
+		}
+		if isExperimentBookkeeping(tag.Name) {
+			continue
+		}
+		info.Experiments = append(info.Experiments, ExperimentState{Key: tag.Name, Title: ExperimentTitle(tag.Name), Enabled: value != 0})
+	}
+	sort.Slice(info.Experiments, func(i, j int) bool { return info.Experiments[i].Key < info.Experiments[j].Key })
+	return info, nil
+}
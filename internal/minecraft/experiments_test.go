@@ -0,0 +1,147 @@
+package minecraft
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/makutaku/blockbench/pkg/nbt"
+)
+
+// newWorldTestServer creates a server with an empty world W
+func newWorldTestServer(t *testing.T) *Server {
+	t.Helper()
+	tempDir := t.TempDir()
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return server
+}
+
+func TestEnableWorldExperiments(t *testing.T) {
+	server := newWorldTestServer(t)
+
+	level := &nbt.LevelDat{StorageVersion: 10, Root: nbt.Compound{
+		{Name: "LevelName", Value: "W"},
+		{Name: "experiments", Value: nbt.Compound{
+			{Name: "data_driven_items", Value: int8(1)},
+			{Name: "gametest", Value: int8(0)},
+			{Name: "experiments_ever_used", Value: int8(1)},
+		}},
+	}}
+	if err := SaveLevelDat(server.Paths.LevelDat, level); err != nil {
+		t.Fatalf("Failed to write level.dat: %v", err)
+	}
+
+	enabled, err := server.WorldExperiments()
+	if err != nil || !reflect.DeepEqual(enabled, []string{"data_driven_items"}) {
+		t.Fatalf("Expected data_driven_items on, got %v, %v", enabled, err)
+	}
+
+	changed, err := server.EnableWorldExperiments([]string{ExperimentBetaAPIs, "data_driven_items"})
+	if err != nil {
+		t.Fatalf("EnableWorldExperiments failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{ExperimentBetaAPIs}) {
+		t.Errorf("Expected only gametest to change, got %v", changed)
+	}
+
+	saved, err := LoadLevelDat(server.Paths.LevelDat)
+	if err != nil {
+		t.Fatalf("Failed to read level.dat: %v", err)
+	}
+	want := nbt.Compound{
+		{Name: "LevelName", Value: "W"},
+		{Name: "experiments", Value: nbt.Compound{
+			{Name: "data_driven_items", Value: int8(1)},
+			{Name: "gametest", Value: int8(1)},
+			{Name: "experiments_ever_used", Value: int8(1)},
+			{Name: "saved_with_toggled_experiments", Value: int8(1)},
+		}},
+	}
+	if !reflect.DeepEqual(saved.Root, want) {
+		t.Errorf("level.dat root = %#v, want %#v", saved.Root, want)
+	}
+
+	before, _ := os.ReadFile(server.Paths.LevelDat)
+	changed, err = server.EnableWorldExperiments([]string{ExperimentBetaAPIs})
+	if err != nil || changed != nil {
+		t.Errorf("Expected no change when the experiment is on, got %v, %v", changed, err)
+	}
+	if after, _ := os.ReadFile(server.Paths.LevelDat); !bytes.Equal(before, after) {
+		t.Error("Expected level.dat to be left untouched")
+	}
+}
+
+func TestRequiredExperiments(t *testing.T) {
+	tests := []struct {
+		name     string
+		deps     []ManifestDependency
+		expected []string
+	}{
+		{"stable module", []ManifestDependency{{ModuleName: "@minecraft/server", ModuleVersion: "1.11.0"}}, nil},
+		{"beta module", []ManifestDependency{{ModuleName: "@minecraft/server", ModuleVersion: "1.12.0-beta"}}, []string{ExperimentBetaAPIs}},
+		{"pack dependency", []ManifestDependency{{UUID: "11111111-1111-1111-1111-111111111111"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := &Manifest{Dependencies: tt.deps}
+			if got := manifest.RequiredExperiments(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("RequiredExperiments() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorldInfo(t *testing.T) {
+	server := newWorldTestServer(t)
+
+	if _, err := server.WorldInfo(); err == nil {
+		t.Error("Expected an error without level.dat")
+	}
+
+	level := &nbt.LevelDat{StorageVersion: 10, Root: nbt.Compound{
+		{Name: "LevelName", Value: "My World"},
+		{Name: "lastOpenedWithVersion", Value: nbt.List{Type: nbt.TagInt, Items: []any{int32(1), int32(21), int32(50), int32(7), int32(0)}}},
+		{Name: "experiments", Value: nbt.Compound{
+			{Name: "gametest", Value: int8(1)},
+			{Name: "data_driven_items", Value: int8(0)},
+			{Name: "experiments_ever_used", Value: int8(1)},
+			{Name: "saved_with_toggled_experiments", Value: int8(1)},
+		}},
+	}}
+	if err := SaveLevelDat(server.Paths.LevelDat, level); err != nil {
+		t.Fatalf("Failed to write level.dat: %v", err)
+	}
+
+	info, err := server.WorldInfo()
+	if err != nil {
+		t.Fatalf("WorldInfo failed: %v", err)
+	}
+	want := &WorldInfo{
+		LevelDat:       server.Paths.LevelDat,
+		Name:           "My World",
+		GameVersion:    "1.21.50.7",
+		StorageVersion: 10,
+		Experiments: []ExperimentState{
+			{Key: "data_driven_items", Title: "Holiday Creator Features", Enabled: false},
+			{Key: "gametest", Title: "Beta APIs", Enabled: true},
+		},
+		ExperimentsEverUsed: true,
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("WorldInfo() = %+v, want %+v", info, want)
+	}
+}
@@ -0,0 +1,285 @@
+package minecraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// packIndexVersion is bumped whenever the cached format changes; older indexes are rebuilt
+const packIndexVersion = 1
+
+// PackIndex caches the manifests of installed packs so that finding a pack by
+// UUID does not re-read every manifest.json. It is saved to the server's state
+// directory and kept fresh without trusting it blindly: a pack directory is
+// re-listed when its modification time changes, and a manifest is re-parsed
+// when its size or modification time changes.
+type PackIndex struct {
+	Version int                        `json:"version"`
+	Dirs    map[string]*indexedPackDir `json:"dirs"` // Keyed by pack base directory
+
+	path  string
+	dirty bool
+	fsys  filesystem.FS // The filesystem the packs are on; nil for the real one
+}
+
+type indexedPackDir struct {
+	ModTime time.Time               `json:"mod_time"`
+	Entries map[string]*IndexedPack `json:"entries"` // Keyed by directory name
+}
+
+// IndexedPack is the cached manifest of one directory in a pack base directory
+type IndexedPack struct {
+	Dir          string    `json:"dir"`
+	ManifestSize int64     `json:"manifest_size"`
+	ManifestTime time.Time `json:"manifest_mod_time"`
+	Manifest     *Manifest `json:"manifest,omitempty"` // nil when manifest.json is missing or invalid
+	Error        string    `json:"error,omitempty"`    // Why an existing manifest.json could not be parsed
+	Size         int64     `json:"size,omitempty"`     // Bytes in the directory's regular files, when measured
+	SizeTime     time.Time `json:"size_mod_time"`      // Modification time of the directory when Size was measured
+}
+
+// LoadPackIndex reads the index cache at path. A missing, unreadable, or
+// outdated cache yields an empty index that is rebuilt on first use.
+func LoadPackIndex(path string) *PackIndex {
+	index := &PackIndex{Version: packIndexVersion, Dirs: make(map[string]*indexedPackDir), path: path}
+
+	// #nosec G304 - path is the index file in the server's state directory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return index
+	}
+
+	var cached PackIndex
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Version != packIndexVersion || cached.Dirs == nil {
+		return index
+	}
+	cached.path = path
+	return &cached
+}
+
+// Packs returns the indexed directories of a pack base directory in name
+// order, refreshing stale entries and saving the index if anything changed
+func (idx *PackIndex) Packs(baseDir string) ([]*IndexedPack, error) {
+	if err := idx.refresh(baseDir); err != nil {
+		return nil, err
+	}
+	if idx.dirty {
+		idx.save()
+	}
+
+	dir := idx.Dirs[baseDir]
+	names := make([]string, 0, len(dir.Entries))
+	for name := range dir.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	packs := make([]*IndexedPack, 0, len(names))
+	for _, name := range names {
+		packs = append(packs, dir.Entries[name])
+	}
+	return packs, nil
+}
+
+// Find returns the first directory in baseDir whose manifest has the given UUID
+func (idx *PackIndex) Find(baseDir, packID string) (*IndexedPack, error) {
+	packs, err := idx.Packs(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		if pack.Manifest != nil && pack.Manifest.Header.UUID == packID {
+			return pack, nil
+		}
+	}
+	return nil, bberrors.Mark(fmt.Errorf("pack directory not found for pack ID %s", packID), bberrors.ErrPackNotFound)
+}
+
+// fs returns the filesystem the index's packs are on
+func (idx *PackIndex) fs() filesystem.FS {
+	if idx.fsys == nil {
+		return filesystem.OSFS{}
+	}
+	return idx.fsys
+}
+
+// refresh brings the entries of baseDir up to date with the filesystem
+func (idx *PackIndex) refresh(baseDir string) error {
+	info, err := idx.fs().Stat(baseDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", baseDir, err)
+	}
+
+	dir, ok := idx.Dirs[baseDir]
+	if !ok || !dir.ModTime.Equal(info.ModTime()) {
+		entries, err := idx.fs().ReadDir(baseDir)
+		if err != nil {
+			return fmt.Errorf("failed to read directory %s: %w", baseDir, err)
+		}
+
+		// Keep cached manifests of directories that still exist
+		fresh := &indexedPackDir{ModTime: info.ModTime(), Entries: make(map[string]*IndexedPack, len(entries))}
+		for _, entry := range entries {
+			if !isPackEntry(idx.fs(), baseDir, entry) {
+				continue
+			}
+			if ok && dir.Entries[entry.Name()] != nil {
+				fresh.Entries[entry.Name()] = dir.Entries[entry.Name()]
+			} else {
+				fresh.Entries[entry.Name()] = &IndexedPack{Dir: filepath.Join(baseDir, entry.Name())}
+			}
+		}
+		idx.Dirs[baseDir] = fresh
+		idx.dirty = true
+		dir = fresh
+	}
+
+	packs := make([]*IndexedPack, 0, len(dir.Entries))
+	for _, pack := range dir.Entries {
+		packs = append(packs, pack)
+	}
+	if refreshPacks(idx.fs(), packs, runtime.GOMAXPROCS(0)) {
+		idx.dirty = true
+	}
+	return nil
+}
+
+// refreshPacks refreshes packs with up to workers goroutines, so a cold index
+// on a server with hundreds of packs parses their manifests in parallel. Each
+// goroutine only writes its own pack, so the result does not depend on
+// scheduling. It reports whether any pack changed.
+func refreshPacks(fsys filesystem.FS, packs []*IndexedPack, workers int) bool {
+	if workers > len(packs) {
+		workers = len(packs)
+	}
+	if workers <= 1 {
+		changed := false
+		for _, pack := range packs {
+			if pack.refresh(fsys) {
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	changed := make([]bool, len(packs))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, pack := range packs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pack *IndexedPack) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			changed[i] = pack.refresh(fsys)
+		}(i, pack)
+	}
+	wg.Wait()
+
+	for _, c := range changed {
+		if c {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh re-parses the manifest on fsys if it changed and reports whether it did
+func (p *IndexedPack) refresh(fsys filesystem.FS) bool {
+	manifestPath := filepath.Join(p.Dir, "manifest.json")
+	info, err := fsys.Stat(manifestPath)
+	if err != nil {
+		changed := p.Manifest != nil || p.Error != "" || !p.ManifestTime.IsZero()
+		*p = IndexedPack{Dir: p.Dir}
+		return changed
+	}
+	if info.Size() == p.ManifestSize && info.ModTime().Equal(p.ManifestTime) {
+		return false
+	}
+
+	p.ManifestSize = info.Size()
+	p.ManifestTime = info.ModTime()
+	p.Manifest, p.Error = nil, ""
+	p.Size, p.SizeTime = 0, time.Time{}
+	manifest, err := ParseManifestFS(fsys, manifestPath)
+	if err != nil {
+		p.Error = err.Error()
+	} else {
+		p.Manifest = manifest
+	}
+	return true
+}
+
+// Size returns the total size of the regular files in an indexed pack's
+// directory. The size is cached with the directory's modification time and
+// measured again when the directory or its manifest changes; edits deep inside
+// a pack that touch neither are not noticed. A linked pack is measured every
+// time, since its source is edited in place.
+func (idx *PackIndex) Size(pack *IndexedPack) (int64, error) {
+	var info os.FileInfo
+	var err error
+	if idx.fsys == nil {
+		info, err = os.Lstat(pack.Dir)
+	} else {
+		info, err = idx.fsys.Stat(pack.Dir) // Only the real filesystem has links
+	}
+	if err != nil {
+		return 0, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return filesystem.TreeSizeFS(idx.fsys, pack.Dir)
+	}
+	if !pack.SizeTime.IsZero() && pack.SizeTime.Equal(info.ModTime()) {
+		return pack.Size, nil
+	}
+
+	size, err := filesystem.TreeSizeFS(idx.fsys, pack.Dir)
+	if err != nil {
+		return 0, err
+	}
+	pack.Size, pack.SizeTime = size, info.ModTime()
+	idx.dirty = true
+	return size, nil
+}
+
+// flush saves the index if anything changed since it was last saved
+func (idx *PackIndex) flush() {
+	if idx.dirty {
+		idx.save()
+	}
+}
+
+// save writes the index cache. The cache only saves work, so failures to
+// write it (e.g. on a read-only server directory) are ignored.
+func (idx *PackIndex) save() {
+	idx.dirty = false
+	if idx.path == "" {
+		return
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), filesystem.DefaultDirPerm); err != nil {
+		return
+	}
+
+	// Write then rename so concurrent readers never see a partial index
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
@@ -0,0 +1,240 @@
+package minecraft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+func writeIndexedPack(t testing.TB, baseDir, name, uuid, packName string) string {
+	t.Helper()
+	dir := filepath.Join(baseDir, name)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	manifest := fmt.Sprintf(`{"format_version": 2, "header": {"name": %q, "uuid": %q, "version": [1, 0, 0]}, "modules": [{"type": "data", "uuid": "99999999-9999-9999-9999-999999999999", "version": [1, 0, 0]}]}`, packName, uuid)
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return dir
+}
+
+// touch moves a modification time forward so that changes are
+// seen even on filesystems with coarse timestamps
+func touch(t *testing.T, dir string, step int) {
+	t.Helper()
+	when := time.Now().Add(time.Duration(step) * time.Second)
+	if err := os.Chtimes(dir, when, when); err != nil {
+		t.Fatalf("Failed to touch %s: %v", dir, err)
+	}
+}
+
+func TestPackIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-index-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	baseDir := filepath.Join(tempDir, "development_behavior_packs")
+	indexPath := filepath.Join(tempDir, ".blockbench", "index.json")
+	packA := writeIndexedPack(t, baseDir, "a", "11111111-1111-1111-1111-111111111111", "Pack A")
+	writeIndexedPack(t, baseDir, "b", "22222222-2222-2222-2222-222222222222", "Pack B")
+	if err := os.MkdirAll(filepath.Join(baseDir, "empty"), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "broken"), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "broken", "manifest.json"), []byte("{not json"), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	index := LoadPackIndex(indexPath)
+	packs, err := index.Packs(baseDir)
+	if err != nil {
+		t.Fatalf("Packs failed: %v", err)
+	}
+	if len(packs) != 4 {
+		t.Fatalf("Expected 4 indexed directories, got %d", len(packs))
+	}
+	for _, pack := range packs {
+		switch filepath.Base(pack.Dir) {
+		case "broken":
+			if pack.Manifest != nil || pack.Error == "" {
+				t.Errorf("Expected a parse error for the broken manifest, got %+v", pack)
+			}
+		case "empty":
+			if pack.Manifest != nil || pack.Error != "" {
+				t.Errorf("Expected no manifest and no error for a directory without manifest.json, got %+v", pack)
+			}
+		}
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("Expected the index to be saved: %v", err)
+	}
+
+	// A fresh load is served from the saved index
+	index = LoadPackIndex(indexPath)
+	if len(index.Dirs[baseDir].Entries) != 4 {
+		t.Fatalf("Expected the saved index to hold 4 directories, got %+v", index.Dirs)
+	}
+	pack, err := index.Find(baseDir, "22222222-2222-2222-2222-222222222222")
+	if err != nil || filepath.Base(pack.Dir) != "b" {
+		t.Fatalf("Expected to find pack B, got %+v, %v", pack, err)
+	}
+	if index.dirty {
+		t.Error("Expected an unchanged directory to be served without rebuilding the index")
+	}
+
+	// Adding a pack is picked up through the directory modification time
+	writeIndexedPack(t, baseDir, "c", "33333333-3333-3333-3333-333333333333", "Pack C")
+	touch(t, baseDir, 1)
+	if _, err := index.Find(baseDir, "33333333-3333-3333-3333-333333333333"); err != nil {
+		t.Errorf("Expected to find the added pack: %v", err)
+	}
+
+	// Removing a pack drops it from the index
+	if err := os.RemoveAll(filepath.Join(baseDir, "b")); err != nil {
+		t.Fatalf("Failed to remove pack: %v", err)
+	}
+	touch(t, baseDir, 2)
+	if _, err := index.Find(baseDir, "22222222-2222-2222-2222-222222222222"); err == nil {
+		t.Error("Expected the removed pack to be gone from the index")
+	}
+
+	// Editing a manifest in place is picked up through its size and modification time
+	writeIndexedPack(t, baseDir, "a", "11111111-1111-1111-1111-111111111111", "Pack A Renamed")
+	touch(t, filepath.Join(packA, "manifest.json"), 3)
+	pack, err = LoadPackIndex(indexPath).Find(baseDir, "11111111-1111-1111-1111-111111111111")
+	if err != nil {
+		t.Fatalf("Expected to find pack A: %v", err)
+	}
+	if pack.Manifest.Header.Name != "Pack A Renamed" {
+		t.Errorf("Expected the edited manifest to be re-parsed, got name %q", pack.Manifest.Header.Name)
+	}
+
+	// A missing base directory is an error
+	if _, err := index.Packs(filepath.Join(tempDir, "missing")); err == nil {
+		t.Error("Expected an error for a missing pack directory")
+	}
+}
+
+func TestLoadPackIndexInvalid(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-index-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	baseDir := filepath.Join(tempDir, "development_resource_packs")
+	writeIndexedPack(t, baseDir, "a", "11111111-1111-1111-1111-111111111111", "Pack A")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"corrupt", "{truncated"},
+		{"old version", `{"version": 0, "dirs": {}}`},
+		{"no dirs", `{"version": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexPath := filepath.Join(tempDir, tt.name+".json")
+			if err := os.WriteFile(indexPath, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to write index: %v", err)
+			}
+
+			index := LoadPackIndex(indexPath)
+			if len(index.Dirs) != 0 {
+				t.Fatalf("Expected an invalid index to load empty, got %+v", index.Dirs)
+			}
+			if _, err := index.Find(baseDir, "11111111-1111-1111-1111-111111111111"); err != nil {
+				t.Errorf("Expected the index to be rebuilt: %v", err)
+			}
+			if LoadPackIndex(indexPath).Version != packIndexVersion {
+				t.Error("Expected the rebuilt index to be saved")
+			}
+		})
+	}
+}
+
+func TestPackIndexSize(t *testing.T) {
+	tempDir := t.TempDir()
+	baseDir := filepath.Join(tempDir, "development_resource_packs")
+	indexPath := filepath.Join(tempDir, ".blockbench", "index.json")
+	dir := writeIndexedPack(t, baseDir, "a", "11111111-1111-1111-1111-111111111111", "Pack A")
+	if err := os.MkdirAll(filepath.Join(dir, "textures"), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "textures", "a.png"), make([]byte, 1000), 0600); err != nil {
+		t.Fatalf("Failed to write texture: %v", err)
+	}
+	manifest, err := os.Stat(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to stat manifest: %v", err)
+	}
+	want := manifest.Size() + 1000
+
+	index := LoadPackIndex(indexPath)
+	pack, err := index.Find(baseDir, "11111111-1111-1111-1111-111111111111")
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if size, err := index.Size(pack); err != nil || size != want {
+		t.Fatalf("Size = %d, %v, want %d", size, err, want)
+	}
+	index.flush()
+
+	// A cached size survives a reload and is reused while the directory is unchanged
+	if err := os.WriteFile(filepath.Join(dir, "textures", "b.png"), make([]byte, 500), 0600); err != nil {
+		t.Fatalf("Failed to write texture: %v", err)
+	}
+	index = LoadPackIndex(indexPath)
+	pack, _ = index.Find(baseDir, "11111111-1111-1111-1111-111111111111")
+	if size, _ := index.Size(pack); size != want {
+		t.Errorf("Expected the cached size %d, got %d", want, size)
+	}
+
+	// A change to the pack directory measures it again
+	touch(t, dir, 2)
+	if size, _ := index.Size(pack); size != want+500 {
+		t.Errorf("Expected the size to be measured again as %d, got %d", want+500, size)
+	}
+}
+
+// BenchmarkRefreshPacks compares sequential and parallel manifest parsing for
+// a cold index on a server with many packs
+func BenchmarkRefreshPacks(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "blockbench-bench")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	const packCount = 300
+	dirs := make([]string, packCount)
+	for i := range dirs {
+		uuid := fmt.Sprintf("%08d-1111-1111-1111-111111111111", i)
+		dirs[i] = writeIndexedPack(b, tempDir, fmt.Sprintf("pack_%d", i), uuid, fmt.Sprintf("Pack %d", i))
+	}
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				packs := make([]*IndexedPack, len(dirs))
+				for j, dir := range dirs {
+					packs[j] = &IndexedPack{Dir: dir}
+				}
+				if !refreshPacks(filesystem.OSFS{}, packs, workers) {
+					b.Fatal("Expected a cold refresh to parse every manifest")
+				}
+			}
+		})
+	}
+}
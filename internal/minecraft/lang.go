@@ -0,0 +1,124 @@
+package minecraft
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// DefaultLocale is the locale pack texts are resolved in when none is chosen,
+// and the one tried when a pack has no lang file for the chosen locale
+const DefaultLocale = "en_US"
+
+// ParseLocale checks a locale given as ll_CC, such as de_DE, and returns it
+// in the form lang files are named with. A hyphen is accepted in place of the
+// underscore, and case is ignored.
+func ParseLocale(value string) (string, error) {
+	language, country, ok := strings.Cut(strings.ReplaceAll(value, "-", "_"), "_")
+	if !ok || len(language) != 2 || len(country) != 2 || !isASCIILetters(language+country) {
+		return "", fmt.Errorf("invalid locale %q: use a language and country code such as en_US or de_DE", value)
+	}
+	return strings.ToLower(language) + "_" + strings.ToUpper(country), nil
+}
+
+func isASCIILetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLang reads a .lang file: one key=value pair per line, where ## starts
+// a comment, either on a line of its own or after a tab following the value
+func ParseLang(data []byte) map[string]string {
+	texts := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "##") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(value, "\t##"); i >= 0 {
+			value = value[:i]
+		}
+		key = strings.TrimSpace(key)
+		if key != "" {
+			texts[key] = strings.TrimRight(value, " \t\r")
+		}
+	}
+	return texts
+}
+
+// LoadPackTexts reads the lang file of a pack directory for locale, or the
+// DefaultLocale one when the pack has no file for locale. Lang file names are
+// matched ignoring case. It returns nil when the pack has neither.
+func LoadPackTexts(packDir, locale string) (map[string]string, error) {
+	return LoadPackTextsFS(nil, packDir, locale)
+}
+
+// LoadPackTextsFS is LoadPackTexts on fsys; a nil fsys is the real filesystem
+func LoadPackTextsFS(fsys filesystem.FS, packDir, locale string) (map[string]string, error) {
+	if fsys == nil {
+		fsys = filesystem.OSFS{}
+	}
+	entries, err := fsys.ReadDir(filepath.Join(packDir, "texts"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range []string{locale, DefaultLocale} {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(entry.Name(), candidate+".lang") {
+				continue
+			}
+			data, err := filesystem.ReadFile(fsys, filepath.Join(packDir, "texts", entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			return ParseLang(data), nil
+		}
+	}
+	return nil, nil
+}
+
+// isLangKey reports whether a manifest text may be a lang key, such as
+// pack.name, rather than the text itself: keys have no spaces
+func isLangKey(text string) bool {
+	return text != "" && !strings.ContainsAny(text, " \t")
+}
+
+// localizeManifestTexts returns the name and description of a manifest with
+// lang keys resolved from the texts of the pack in dir on fsys. Texts that
+// aren't keys in the lang file are returned as they are.
+func localizeManifestTexts(fsys filesystem.FS, manifest *Manifest, dir, locale string) (name, description string) {
+	name, description = manifest.GetDisplayName(), manifest.Header.Description
+	if !isLangKey(manifest.Header.Name) && !isLangKey(description) {
+		return name, description
+	}
+
+	texts, err := LoadPackTextsFS(fsys, dir, locale)
+	if err != nil {
+		return name, description
+	}
+	if text, ok := texts[manifest.Header.Name]; ok && text != "" {
+		name = text
+	}
+	if text, ok := texts[description]; ok {
+		description = text
+	}
+	return name, description
+}
@@ -0,0 +1,123 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLang(t *testing.T) {
+	data := "\xef\xbb\xbf## Pack texts\npack.name=Fire Pack\t## the name\r\npack.description=Adds fire = heat \n\nnot a pair\n  ## indented comment\nitem.x=\n"
+	texts := ParseLang([]byte(data))
+
+	expected := map[string]string{
+		"pack.name":        "Fire Pack",
+		"pack.description": "Adds fire = heat",
+		"item.x":           "",
+	}
+	if len(texts) != len(expected) {
+		t.Errorf("ParseLang = %q, want %q", texts, expected)
+	}
+	for key, value := range expected {
+		if texts[key] != value {
+			t.Errorf("ParseLang[%s] = %q, want %q", key, texts[key], value)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"en_US", "en_US", false},
+		{"de-de", "de_DE", false},
+		{"PT_br", "pt_BR", false},
+		{"en", "", true},
+		{"eng_US", "", true},
+		{"e1_US", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocale(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("ParseLocale(%q) = %q, %v, want %q (error %v)", tt.input, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func writeLangFile(t *testing.T, packDir, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(packDir, "texts"), 0750); err != nil {
+		t.Fatalf("Failed to create texts dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "texts", name), []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write lang file: %v", err)
+	}
+}
+
+func TestLoadPackTexts(t *testing.T) {
+	packDir := t.TempDir()
+	if texts, err := LoadPackTexts(packDir, "de_DE"); err != nil || texts != nil {
+		t.Errorf("LoadPackTexts without texts = %v, %v, want nil", texts, err)
+	}
+
+	writeLangFile(t, packDir, "en_us.lang", "pack.name=Fire Pack\n")
+	writeLangFile(t, packDir, "de_DE.lang", "pack.name=Feuerpaket\n")
+
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"de_DE", "Feuerpaket"},
+		{"en_US", "Fire Pack"},
+		{"fr_FR", "Fire Pack"}, // Falls back to en_US, matched ignoring case
+	}
+	for _, tt := range tests {
+		texts, err := LoadPackTexts(packDir, tt.locale)
+		if err != nil {
+			t.Fatalf("LoadPackTexts(%s) failed: %v", tt.locale, err)
+		}
+		if texts["pack.name"] != tt.expected {
+			t.Errorf("LoadPackTexts(%s) pack.name = %q, want %q", tt.locale, texts["pack.name"], tt.expected)
+		}
+	}
+}
+
+func TestListInstalledPacksLocalized(t *testing.T) {
+	server := newWorldTestServer(t)
+	const packID = "11111111-1111-1111-1111-111111111111"
+	const plainID = "22222222-2222-2222-2222-222222222222"
+	dir := writeIndexedPack(t, server.Paths.BehaviorPacksDir, "pack.name_11111111", packID, "pack.name")
+	writeLangFile(t, dir, "en_US.lang", "pack.name=Fire Pack\n")
+	writeLangFile(t, dir, "de_DE.lang", "pack.name=Feuerpaket\n")
+	plain := writeIndexedPack(t, server.Paths.BehaviorPacksDir, "Plain_22222222", plainID, "Plain Pack")
+	writeLangFile(t, plain, "en_US.lang", "Plain Pack=Not used\n")
+
+	config := WorldConfig{{PackID: packID, Version: [3]int{1, 0, 0}}, {PackID: plainID, Version: [3]int{1, 0, 0}}}
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	for _, tt := range []struct {
+		locale   string
+		expected string
+	}{
+		{"", "Fire Pack"},
+		{"de_DE", "Feuerpaket"},
+	} {
+		server.Locale = tt.locale
+		server.InvalidatePacks()
+		packs, err := server.ListInstalledPacks()
+		if err != nil || len(packs) != 2 {
+			t.Fatalf("ListInstalledPacks = %+v, %v, want 2 packs", packs, err)
+		}
+		if packs[0].Name != tt.expected {
+			t.Errorf("Name in locale %q = %q, want %q", tt.locale, packs[0].Name, tt.expected)
+		}
+		if packs[1].Name != "Plain Pack" {
+			t.Errorf("Name of a pack not named by a key = %q, want Plain Pack", packs[1].Name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package minecraft
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// LinkPack installs a pack like InstallPack, but as a symbolic link to
+// sourceDir instead of a copy, so changes to the source show up on the
+// server when the world is reloaded. Linked packs are not deduplicated and
+// have no checksums recorded, since their files are expected to change.
+func (s *Server) LinkPack(manifest *Manifest, sourceDir string, opts PackInstallOptions) error {
+	writeLink, err := s.linkPackFiles(manifest, sourceDir)
+	if err != nil {
+		return err
+	}
+	return s.InstallPackFrom(manifest, writeLink, opts)
+}
+
+// linkPackFiles returns a PackWriter that links a pack directory to sourceDir
+func (s *Server) linkPackFiles(manifest *Manifest, sourceDir string) (PackWriter, error) {
+	if s.FS != nil {
+		return nil, fmt.Errorf("linking pack %s needs the server's own filesystem", manifest.GetDisplayName())
+	}
+	source, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(targetDir string) error {
+		if _, err := os.Lstat(targetDir); err == nil {
+			return fmt.Errorf("%s already exists as a copy of the pack; uninstall it before linking", targetDir)
+		}
+		if err := os.MkdirAll(filepath.Dir(targetDir), filesystem.DefaultDirPerm); err != nil {
+			return err
+		}
+		return os.Symlink(source, targetDir)
+	}, nil
+}
+
+// LinkedPackTarget returns the source directory a pack directory links to,
+// and false when it is not a link
+func LinkedPackTarget(packDir string) (string, bool) {
+	info, err := os.Lstat(packDir)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return "", false
+	}
+	target, err := os.Readlink(packDir)
+	if err != nil {
+		return "", false
+	}
+	return target, true
+}
+
+// unlinkPack removes a linked pack directory, leaving the source it points
+// to untouched. Anything else is left for the caller.
+func unlinkPack(packDir string) (bool, error) {
+	if _, linked := LinkedPackTarget(packDir); !linked {
+		return false, nil
+	}
+	if err := os.Remove(packDir); err != nil {
+		return true, fmt.Errorf("failed to remove pack link %s: %w", packDir, err)
+	}
+	return true, nil
+}
+
+// isPackEntry reports whether a pack base directory entry can hold a pack: a
+// directory, or a link to one
+func isPackEntry(fsys filesystem.FS, baseDir string, entry fs.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	info, err := fsys.Stat(filepath.Join(baseDir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
+// brokenPackLink finds the link a pack was installed as once its source
+// directory is gone, by the name blockbench installs packs under (see
+// isPackDirName); a broken link has no manifest to find it by. Packs are only
+// linked on the real filesystem, so there is none on another FS.
+func (s *Server) brokenPackLink(baseDir, packID string) (string, bool) {
+	if s.FS != nil {
+		return "", false
+	}
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 || !isPackDirName(entry.Name(), packID) {
+			continue
+		}
+		path := filepath.Join(baseDir, entry.Name())
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,92 @@
+//go:build unix
+
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkPack(t *testing.T) {
+	tempDir := t.TempDir()
+	serverDir := filepath.Join(tempDir, "server")
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(serverDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(serverDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	const packID = "11111111-1111-1111-1111-111111111111"
+	source := filepath.Join(tempDir, "src", "BP")
+	writeIndexedPack(t, filepath.Dir(source), "BP", packID, "Pack")
+	manifest, err := ParseManifest(filepath.Join(source, "manifest.json"))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	if err := server.LinkPack(manifest, source, PackInstallOptions{}); err != nil {
+		t.Fatalf("LinkPack failed: %v", err)
+	}
+	packDir, _, _ := server.PackInstallPaths(manifest)
+	if target, ok := LinkedPackTarget(packDir); !ok || target != source {
+		t.Fatalf("Expected %s to link to %s, got %q", packDir, source, target)
+	}
+	packs, err := server.ListInstalledPacks()
+	if err != nil || len(packs) != 1 || packs[0].Status != PackStatusOK || packs[0].Link != source {
+		t.Fatalf("Expected the linked pack to be listed, got %+v, %v", packs, err)
+	}
+
+	// Copying the pack over the link replaces the link, not the source's files
+	copySource := writeIndexedPack(t, tempDir, "copy", packID, "Pack")
+	if err := os.WriteFile(filepath.Join(copySource, "copied.txt"), []byte("copied"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.InstallPack(manifest, copySource, PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPack failed: %v", err)
+	}
+	if _, ok := LinkedPackTarget(packDir); ok {
+		t.Error("Expected the copy to replace the link")
+	}
+	if _, err := os.Stat(filepath.Join(source, "copied.txt")); !os.IsNotExist(err) {
+		t.Error("Expected the copy not to write into the linked source")
+	}
+	if err := server.UninstallPack(packID); err != nil {
+		t.Fatalf("UninstallPack failed: %v", err)
+	}
+
+	// Uninstalling a linked pack removes the link and leaves the source
+	if err := server.LinkPack(manifest, source, PackInstallOptions{}); err != nil {
+		t.Fatalf("LinkPack failed: %v", err)
+	}
+	if err := server.UninstallPack(packID); err != nil {
+		t.Fatalf("UninstallPack failed: %v", err)
+	}
+	if _, err := os.Lstat(packDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the link to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(source, "manifest.json")); err != nil {
+		t.Errorf("Expected the source to be kept, got %v", err)
+	}
+
+	// A link whose source is gone can still be uninstalled
+	if err := server.LinkPack(manifest, source, PackInstallOptions{}); err != nil {
+		t.Fatalf("LinkPack failed: %v", err)
+	}
+	if err := os.RemoveAll(source); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.UninstallPack(packID); err != nil {
+		t.Fatalf("UninstallPack of a broken link failed: %v", err)
+	}
+	if _, err := os.Lstat(packDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the broken link to be removed, got %v", err)
+	}
+}
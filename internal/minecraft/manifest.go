@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+	"github.com/makutaku/blockbench/pkg/filesystem"
 	"github.com/makutaku/blockbench/pkg/validation"
 )
 
@@ -24,6 +25,8 @@ type ManifestModule struct {
 	UUID        string `json:"uuid"`
 	Version     [3]int `json:"version"`
 	Description string `json:"description,omitempty"`
+	Language    string `json:"language,omitempty"` // Script modules: javascript
+	Entry       string `json:"entry,omitempty"`    // Script modules: the script that runs first
 }
 
 // ManifestDependency represents a dependency on another pack or module
@@ -78,12 +81,31 @@ func (md *ManifestDependency) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ManifestSubpack represents an optional subpack of a resource pack, such as a texture
+// resolution variant gated behind a device memory tier
+type ManifestSubpack struct {
+	FolderName string `json:"folder_name"`
+	Name       string `json:"name"`
+	MemoryTier int    `json:"memory_tier,omitempty"`
+}
+
+// ManifestMetadata represents the optional metadata section of a manifest
+type ManifestMetadata struct {
+	Authors       []string            `json:"authors,omitempty"`
+	License       string              `json:"license,omitempty"`
+	URL           string              `json:"url,omitempty"`
+	GeneratedWith map[string][]string `json:"generated_with,omitempty"` // Tool name -> tool versions
+}
+
 // Manifest represents a complete manifest.json file
 type Manifest struct {
 	FormatVersion int                  `json:"format_version"`
 	Header        ManifestHeader       `json:"header"`
 	Modules       []ManifestModule     `json:"modules"`
 	Dependencies  []ManifestDependency `json:"dependencies,omitempty"`
+	Subpacks      []ManifestSubpack    `json:"subpacks,omitempty"`
+	Capabilities  []string             `json:"capabilities,omitempty"` // Engine features the pack requires, e.g. script_eval
+	Metadata      *ManifestMetadata    `json:"metadata,omitempty"`
 }
 
 // PackType represents the type of a Minecraft pack
@@ -120,6 +142,36 @@ func (m *Manifest) GetDisplayName() string {
 	return fmt.Sprintf("Pack-%s", m.Header.UUID)
 }
 
+// GetSubpack returns the subpack declared with the given folder name
+func (m *Manifest) GetSubpack(folderName string) (*ManifestSubpack, bool) {
+	for i := range m.Subpacks {
+		if m.Subpacks[i].FolderName == folderName {
+			return &m.Subpacks[i], true
+		}
+	}
+	return nil, false
+}
+
+// HasCapability reports whether the manifest requests the given capability
+func (m *Manifest) HasCapability(capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScriptModule reports whether the manifest declares a script module
+func (m *Manifest) HasScriptModule() bool {
+	for _, module := range m.Modules {
+		if module.Type == "script" {
+			return true
+		}
+	}
+	return false
+}
+
 // GetVersionString returns the version as a string
 func (m *Manifest) GetVersionString() string {
 	return fmt.Sprintf("%d.%d.%d", m.Header.Version[0], m.Header.Version[1], m.Header.Version[2])
@@ -127,8 +179,13 @@ func (m *Manifest) GetVersionString() string {
 
 // ParseManifest reads and parses a manifest.json file
 func ParseManifest(filePath string) (*Manifest, error) {
+	return ParseManifestFS(nil, filePath)
+}
+
+// ParseManifestFS is ParseManifest on fsys; a nil fsys is the real filesystem
+func ParseManifestFS(fsys filesystem.FS, filePath string) (*Manifest, error) {
 	// #nosec G304 - filePath is validated manifest.json within controlled extraction directory
-	file, err := os.Open(filePath)
+	file, err := filesystem.Open(fsys, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open manifest file: %w", err)
 	}
@@ -146,23 +203,29 @@ func ParseManifestFromReader(reader io.Reader) (*Manifest, error) {
 
 	var manifest Manifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest JSON: %w", err)
+		return nil, bberrors.Mark(fmt.Errorf("failed to parse manifest JSON: %w", err), bberrors.ErrInvalidManifest)
 	}
 
 	// Validate required fields
 	if manifest.Header.UUID == "" {
-		return nil, fmt.Errorf("manifest missing required UUID in header")
+		return nil, bberrors.Mark(fmt.Errorf("manifest missing required UUID in header"), bberrors.ErrInvalidManifest)
 	}
 
 	if len(manifest.Modules) == 0 {
-		return nil, fmt.Errorf("manifest missing required modules")
+		return nil, bberrors.Mark(fmt.Errorf("manifest missing required modules"), bberrors.ErrInvalidManifest)
 	}
 
 	return &manifest, nil
 }
 
-// ValidateManifest performs comprehensive validation on a manifest
+// ValidateManifest performs comprehensive validation on a manifest. Its
+// errors are marked as ErrInvalidManifest.
 func ValidateManifest(manifest *Manifest) error {
+	return bberrors.Mark(validateManifest(manifest), bberrors.ErrInvalidManifest)
+}
+
+// validateManifest returns the first problem ValidateManifest finds
+func validateManifest(manifest *Manifest) error {
 	if manifest.FormatVersion < 1 || manifest.FormatVersion > 2 {
 		return fmt.Errorf("unsupported format version: %d (expected 1 or 2)", manifest.FormatVersion)
 	}
@@ -227,6 +290,22 @@ func ValidateManifest(manifest *Manifest) error {
 		}
 	}
 
+	// Validate subpacks (if any)
+	subpackFolders := make(map[string]bool)
+	for i, subpack := range manifest.Subpacks {
+		if subpack.FolderName == "" {
+			return fmt.Errorf("subpack at index %d is missing folder_name", i)
+		}
+		if subpackFolders[subpack.FolderName] {
+			return fmt.Errorf("duplicate subpack folder_name: %s", subpack.FolderName)
+		}
+		subpackFolders[subpack.FolderName] = true
+
+		if subpack.MemoryTier < 0 {
+			return fmt.Errorf("subpack memory_tier cannot be negative: %d (subpack %s)", subpack.MemoryTier, subpack.FolderName)
+		}
+	}
+
 	// Validate dependencies (if any)
 	for i, dep := range manifest.Dependencies {
 		if dep.UUID != "" {
@@ -0,0 +1,294 @@
+package minecraft
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// ManifestFile is a manifest.json opened for editing in place. Unlike
+// Manifest, it keeps every field, including ones blockbench does not know
+// about, in its original order when it is written back.
+type ManifestFile struct {
+	path string
+	root *jsonValue
+}
+
+// OpenManifestFile reads a manifest.json for editing
+func OpenManifestFile(path string) (*ManifestFile, error) {
+	// #nosec G304 - path is a pack's manifest the user pointed at
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	root, err := parseJSONValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest JSON %s: %w", path, err)
+	}
+	if root.fields == nil {
+		return nil, fmt.Errorf("manifest %s is not a JSON object", path)
+	}
+	return &ManifestFile{path: path, root: root}, nil
+}
+
+// SetVersion sets the header version, along with the version of every
+// module that matched the old header version, and returns the old version
+func (m *ManifestFile) SetVersion(version [3]int) ([3]int, error) {
+	header := m.root.get("header")
+	if header == nil || header.get("version") == nil {
+		return [3]int{}, fmt.Errorf("manifest %s has no header version", m.path)
+	}
+	var old [3]int
+	if err := json.Unmarshal(header.get("version").raw, &old); err != nil {
+		return [3]int{}, fmt.Errorf("manifest %s has an invalid header version: %w", m.path, err)
+	}
+
+	header.set("version", version)
+	if modules := m.root.get("modules"); modules != nil {
+		for _, module := range modules.items {
+			if current := module.get("version"); current != nil && versionEquals(current, old) {
+				module.set("version", version)
+			}
+		}
+	}
+	return old, nil
+}
+
+// SetModuleVersions sets the version of every module to version, returning
+// how many were changed
+func (m *ManifestFile) SetModuleVersions(version [3]int) int {
+	modules := m.root.get("modules")
+	if modules == nil {
+		return 0
+	}
+	changed := 0
+	for _, module := range modules.items {
+		if current := module.get("version"); current == nil || !versionEquals(current, version) {
+			module.set("version", version)
+			changed++
+		}
+	}
+	return changed
+}
+
+// SetDependencyVersion sets the version of the dependencies on a pack
+// that currently require from, returning how many were changed
+func (m *ManifestFile) SetDependencyVersion(uuid string, from, to [3]int) int {
+	dependencies := m.root.get("dependencies")
+	if dependencies == nil {
+		return 0
+	}
+	changed := 0
+	for _, dependency := range dependencies.items {
+		var depUUID string
+		if value := dependency.get("uuid"); value == nil || json.Unmarshal(value.raw, &depUUID) != nil || !strings.EqualFold(depUUID, uuid) {
+			continue
+		}
+		if current := dependency.get("version"); current != nil && versionEquals(current, from) {
+			dependency.set("version", to)
+			changed++
+		}
+	}
+	return changed
+}
+
+// ReplaceUUIDs replaces every string in the manifest that is a UUID in
+// mapping, ignoring case, with the UUID it maps to, returning how many were
+// replaced. Keys of mapping must be lower case.
+func (m *ManifestFile) ReplaceUUIDs(mapping map[string]string) int {
+	return m.root.replaceStrings(func(s string) (string, bool) {
+		replacement, ok := mapping[strings.ToLower(s)]
+		return replacement, ok
+	})
+}
+
+// Save writes the manifest back with two-space indentation
+func (m *ManifestFile) Save() error {
+	var compact bytes.Buffer
+	if err := m.root.encode(&compact); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	indented.WriteByte('\n')
+	if err := os.WriteFile(m.path, indented.Bytes(), filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// versionEquals reports whether a JSON value is the given version array
+func versionEquals(value *jsonValue, version [3]int) bool {
+	var current [3]int
+	return json.Unmarshal(value.raw, &current) == nil && current == version
+}
+
+// jsonValue is a JSON value decoded with the order of object keys kept. For
+// objects fields is set, for arrays items, and raw holds the value's JSON
+// (for objects and arrays, as it was read).
+type jsonValue struct {
+	fields []jsonField
+	items  []*jsonValue
+	array  bool
+	raw    json.RawMessage
+}
+
+// jsonField is one key of a JSON object
+type jsonField struct {
+	key   string
+	value *jsonValue
+}
+
+// parseJSONValue decodes JSON keeping the order of object keys
+func parseJSONValue(data []byte) (*jsonValue, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty JSON value")
+	}
+	value := &jsonValue{raw: json.RawMessage(trimmed)}
+
+	switch trimmed[0] {
+	case '{':
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		value.fields = []jsonField{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := token.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key %v", token)
+			}
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return nil, err
+			}
+			field, err := parseJSONValue(raw)
+			if err != nil {
+				return nil, err
+			}
+			value.fields = append(value.fields, jsonField{key: key, value: field})
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case '[':
+		var raws []json.RawMessage
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, err
+		}
+		value.array = true
+		for _, raw := range raws {
+			item, err := parseJSONValue(raw)
+			if err != nil {
+				return nil, err
+			}
+			value.items = append(value.items, item)
+		}
+	default:
+		if !json.Valid(trimmed) {
+			return nil, fmt.Errorf("invalid JSON value %s", trimmed)
+		}
+	}
+	return value, nil
+}
+
+// get returns an object's field, or nil when it has none by that key
+func (v *jsonValue) get(key string) *jsonValue {
+	for _, field := range v.fields {
+		if field.key == key {
+			return field.value
+		}
+	}
+	return nil
+}
+
+// set replaces an object's field with the JSON encoding of value, keeping
+// its position, or appends it
+func (v *jsonValue) set(key string, value any) {
+	raw, _ := json.Marshal(value)
+	replacement, _ := parseJSONValue(raw)
+	for i, field := range v.fields {
+		if field.key == key {
+			v.fields[i].value = replacement
+			return
+		}
+	}
+	v.fields = append(v.fields, jsonField{key: key, value: replacement})
+}
+
+// replaceStrings replaces the string values, not keys, that replace maps
+func (v *jsonValue) replaceStrings(replace func(string) (string, bool)) int {
+	switch {
+	case v.fields != nil:
+		count := 0
+		for _, field := range v.fields {
+			count += field.value.replaceStrings(replace)
+		}
+		return count
+	case v.array:
+		count := 0
+		for _, item := range v.items {
+			count += item.replaceStrings(replace)
+		}
+		return count
+	}
+
+	var s string
+	if json.Unmarshal(v.raw, &s) != nil {
+		return 0
+	}
+	replacement, ok := replace(s)
+	if !ok {
+		return 0
+	}
+	v.raw, _ = json.Marshal(replacement)
+	return 1
+}
+
+// encode writes the value as compact JSON
+func (v *jsonValue) encode(buf *bytes.Buffer) error {
+	switch {
+	case v.fields != nil:
+		buf.WriteByte('{')
+		for i, field := range v.fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(field.key)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := field.value.encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case v.array:
+		buf.WriteByte('[')
+		for i, item := range v.items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := item.encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		return json.Compact(buf, v.raw)
+	}
+	return nil
+}
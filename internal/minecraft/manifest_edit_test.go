@@ -0,0 +1,111 @@
+package minecraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editedManifest = `{
+	"format_version": 2,
+	"header": {"name": "Foo", "uuid": "AAAAAAAA-1111-1111-1111-111111111111", "version": [1, 0, 0], "x_custom": {"keep": true}},
+	"modules": [
+		{"type": "data", "uuid": "aaaaaaaa-2222-2222-2222-222222222222", "version": [1, 0, 0]},
+		{"type": "script", "uuid": "aaaaaaaa-3333-3333-3333-333333333333", "version": [0, 5, 0], "entry": "scripts/main.js"}
+	],
+	"dependencies": [
+		{"uuid": "bbbbbbbb-1111-1111-1111-111111111111", "version": [2, 0, 0]},
+		{"module_name": "@minecraft/server", "version": "1.11.0"}
+	]
+}`
+
+func TestManifestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(editedManifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenManifestFile(path)
+	if err != nil {
+		t.Fatalf("OpenManifestFile failed: %v", err)
+	}
+	old, err := file.SetVersion([3]int{1, 0, 1})
+	if err != nil || old != [3]int{1, 0, 0} {
+		t.Fatalf("SetVersion() = %v, %v", old, err)
+	}
+	if n := file.SetDependencyVersion("BBBBBBBB-1111-1111-1111-111111111111", [3]int{2, 0, 0}, [3]int{2, 1, 0}); n != 1 {
+		t.Errorf("Expected one dependency to change, got %d", n)
+	}
+	if n := file.SetDependencyVersion("bbbbbbbb-1111-1111-1111-111111111111", [3]int{9, 0, 0}, [3]int{9, 1, 0}); n != 0 {
+		t.Errorf("Expected a dependency on another version to be kept, got %d changes", n)
+	}
+	mapping := map[string]string{
+		"aaaaaaaa-1111-1111-1111-111111111111": "cccccccc-1111-1111-1111-111111111111",
+		"aaaaaaaa-2222-2222-2222-222222222222": "cccccccc-2222-2222-2222-222222222222",
+	}
+	if n := file.ReplaceUUIDs(mapping); n != 2 {
+		t.Errorf("Expected 2 UUIDs replaced, got %d", n)
+	}
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	manifest, err := ParseManifest(path)
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if manifest.Header.Version != [3]int{1, 0, 1} || manifest.Header.UUID != "cccccccc-1111-1111-1111-111111111111" {
+		t.Errorf("Unexpected header %+v", manifest.Header)
+	}
+	if manifest.Modules[0].Version != [3]int{1, 0, 1} || manifest.Modules[1].Version != [3]int{0, 5, 0} {
+		t.Errorf("Expected only the module at the header version to change, got %+v", manifest.Modules)
+	}
+	if manifest.Modules[0].UUID != "cccccccc-2222-2222-2222-222222222222" || manifest.Modules[1].UUID != "aaaaaaaa-3333-3333-3333-333333333333" {
+		t.Errorf("Expected only the mapped module UUID to change, got %+v", manifest.Modules)
+	}
+	if manifest.Modules[1].Entry != "scripts/main.js" || manifest.Dependencies[0].Version != [3]int{2, 1, 0} || manifest.Dependencies[1].ModuleVersion != "1.11.0" {
+		t.Errorf("Unexpected modules or dependencies %+v %+v", manifest.Modules, manifest.Dependencies)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	if !strings.Contains(text, `"x_custom": {`) {
+		t.Errorf("Expected unknown fields to be kept, got %s", text)
+	}
+	if strings.Index(text, `"format_version"`) > strings.Index(text, `"header"`) ||
+		strings.Index(text, `"name"`) > strings.Index(text, `"uuid"`) {
+		t.Errorf("Expected the key order to be kept, got %s", text)
+	}
+}
+
+func TestManifestFileSetModuleVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(editedManifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenManifestFile(path)
+	if err != nil {
+		t.Fatalf("OpenManifestFile failed: %v", err)
+	}
+	if n := file.SetModuleVersions([3]int{1, 0, 0}); n != 1 {
+		t.Errorf("Expected only the module at another version to change, got %d changes", n)
+	}
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	manifest, err := ParseManifest(path)
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	for _, module := range manifest.Modules {
+		if module.Version != [3]int{1, 0, 0} {
+			t.Errorf("Expected module %s at 1.0.0, got %v", module.Type, module.Version)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -409,3 +410,118 @@ func BenchmarkParseManifest(b *testing.B) {
 		}
 	}
 }
+
+func TestParseManifestSubpacks(t *testing.T) {
+	manifestData := `{
+		"format_version": 2,
+		"header": {
+			"name": "HD Textures",
+			"uuid": "87654321-4321-4321-4321-abcdef123456",
+			"version": [1, 0, 0]
+		},
+		"modules": [
+			{
+				"type": "resources",
+				"uuid": "87654321-4321-4321-4321-abcdef123457",
+				"version": [1, 0, 0]
+			}
+		],
+		"subpacks": [
+			{"folder_name": "low", "name": "Low Resolution"},
+			{"folder_name": "high", "name": "High Resolution", "memory_tier": 2}
+		]
+	}`
+
+	manifest, err := ParseManifestFromReader(strings.NewReader(manifestData))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	if len(manifest.Subpacks) != 2 {
+		t.Fatalf("Expected 2 subpacks, got %d", len(manifest.Subpacks))
+	}
+
+	high, ok := manifest.GetSubpack("high")
+	if !ok {
+		t.Fatal("Expected to find subpack 'high'")
+	}
+	if high.Name != "High Resolution" || high.MemoryTier != 2 {
+		t.Errorf("Unexpected subpack data: %+v", high)
+	}
+
+	if _, ok := manifest.GetSubpack("medium"); ok {
+		t.Error("Did not expect to find subpack 'medium'")
+	}
+
+	if err := ValidateManifest(manifest); err != nil {
+		t.Errorf("Expected valid manifest, got: %v", err)
+	}
+
+	manifest.Subpacks = append(manifest.Subpacks, ManifestSubpack{FolderName: "low", Name: "Duplicate"})
+	if err := ValidateManifest(manifest); err == nil {
+		t.Error("Expected validation error for duplicate subpack folder_name")
+	}
+}
+
+func TestParseManifestCapabilitiesAndMetadata(t *testing.T) {
+	manifestData := `{
+		"format_version": 2,
+		"header": {
+			"name": "Scripted Pack",
+			"uuid": "12345678-1234-1234-1234-123456789abc",
+			"version": [1, 0, 0]
+		},
+		"modules": [
+			{
+				"type": "data",
+				"uuid": "12345678-1234-1234-1234-123456789abd",
+				"version": [1, 0, 0]
+			}
+		],
+		"capabilities": ["script_eval"],
+		"metadata": {
+			"authors": ["Alice", "Bob"],
+			"license": "MIT",
+			"url": "https://example.com",
+			"generated_with": {"bridge": ["2.7.0"]}
+		}
+	}`
+
+	manifest, err := ParseManifestFromReader(strings.NewReader(manifestData))
+	if err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+
+	if !manifest.HasCapability("script_eval") {
+		t.Error("Expected manifest to have capability script_eval")
+	}
+	if manifest.HasCapability("raytraced") {
+		t.Error("Did not expect manifest to have capability raytraced")
+	}
+
+	if manifest.Metadata == nil {
+		t.Fatal("Expected metadata to be parsed")
+	}
+	if len(manifest.Metadata.Authors) != 2 || manifest.Metadata.License != "MIT" || manifest.Metadata.URL != "https://example.com" {
+		t.Errorf("Unexpected metadata: %+v", manifest.Metadata)
+	}
+	if versions := manifest.Metadata.GeneratedWith["bridge"]; len(versions) != 1 || versions[0] != "2.7.0" {
+		t.Errorf("Unexpected generated_with: %v", manifest.Metadata.GeneratedWith)
+	}
+}
+
+func TestManifestHasScriptModule(t *testing.T) {
+	manifest := &Manifest{
+		Modules: []ManifestModule{
+			{Type: "data", UUID: "12345678-1234-1234-1234-123456789abd", Version: [3]int{1, 0, 0}},
+		},
+	}
+	if manifest.HasScriptModule() {
+		t.Error("Did not expect a script module")
+	}
+
+	manifest.Modules = append(manifest.Modules, ManifestModule{Type: "script", UUID: "12345678-1234-1234-1234-123456789abe", Version: [3]int{1, 0, 0}})
+	if !manifest.HasScriptModule() {
+		t.Error("Expected a script module")
+	}
+}
@@ -0,0 +1,76 @@
+package minecraft
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/slug"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// packDirName returns the directory name a new install of a pack gets: the
+// slug of its name, so emoji, color codes, and slashes never reach the
+// filesystem, then its UUID prefix
+func packDirName(manifest *Manifest) string {
+	name := slug.Make(manifest.GetDisplayName())
+	if name == "" {
+		name = "Pack" // A name of only emoji or symbols
+	}
+	return filesystem.HostPathStyle.SafeName(name) + "_" + validation.GetSafeUUIDPrefix(manifest.Header.UUID)
+}
+
+// isPackDirName reports whether dirName is a name blockbench installs the pack
+// with packID under: anything, then "_" and the UUID prefix, then "-n" when
+// the name was taken. Before slugs, the first part was the raw display name,
+// and those directories match too.
+func isPackDirName(dirName, packID string) bool {
+	suffix := "_" + validation.GetSafeUUIDPrefix(packID)
+	if strings.HasSuffix(dirName, suffix) {
+		return true
+	}
+	i := strings.LastIndexByte(dirName, '-')
+	if i < 0 || i == len(dirName)-1 || strings.Trim(dirName[i+1:], "0123456789") != "" {
+		return false
+	}
+	return strings.HasSuffix(dirName[:i], suffix)
+}
+
+// packInstallDir returns the directory in baseDir a pack installs to. A pack
+// that is already installed keeps its directory, whatever it is named, so
+// upgrades replace it in place. Otherwise the directory is named by
+// packDirName, with -2, -3, and so on appended while another pack or a file
+// has the name; a directory of the name without a readable manifest is
+// reused, as it is left over from a broken install of the pack.
+func (s *Server) packInstallDir(baseDir string, manifest *Manifest) string {
+	packID := manifest.Header.UUID
+	if pack, err := s.packIndex().Find(baseDir, packID); err == nil {
+		return pack.Dir
+	}
+	if link, ok := s.brokenPackLink(baseDir, packID); ok {
+		return link
+	}
+
+	indexed, _ := s.packIndex().Packs(baseDir) // Empty when baseDir doesn't exist yet
+	name := packDirName(manifest)
+	dir := filepath.Join(baseDir, name)
+	for n := 2; s.packDirTaken(dir, indexed); n++ {
+		dir = filepath.Join(baseDir, fmt.Sprintf("%s-%d", name, n))
+	}
+	return dir
+}
+
+// packDirTaken reports whether dir exists and holds something other than a
+// pack without a readable manifest
+func (s *Server) packDirTaken(dir string, indexed []*IndexedPack) bool {
+	if _, err := s.fs().Stat(dir); err != nil {
+		return false
+	}
+	for _, pack := range indexed {
+		if filesystem.HostPathStyle.SameName(pack.Dir, dir) {
+			return pack.Manifest != nil
+		}
+	}
+	return true // A file, or an entry the index skips
+}
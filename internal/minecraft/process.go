@@ -0,0 +1,215 @@
+package minecraft
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultServerPort is the IPv4 port Bedrock servers listen on unless server.properties says otherwise
+	DefaultServerPort = 19132
+
+	// serverExecutable is the base name of the Bedrock dedicated server binary
+	serverExecutable = "bedrock_server"
+
+	// DefaultStopTimeout is how long a stopping server gets to save the world and exit
+	DefaultStopTimeout = 30 * time.Second
+)
+
+// RunningServer describes how a running server was detected
+type RunningServer struct {
+	PID    int    `json:"pid,omitempty"` // Zero when not detected through a process
+	Source string `json:"source"`        // What gave it away: a process, a systemd unit, or the port
+}
+
+// ServerControl stops and starts a server. A systemd unit takes precedence
+// over commands; without either, Stop interrupts the detected process and
+// Start is not possible.
+type ServerControl struct {
+	Unit         string        // systemd unit running the server
+	StopCommand  string        // Shell command that stops the server
+	StartCommand string        // Shell command that starts the server
+	Timeout      time.Duration // How long to wait for the server to stop; zero means DefaultStopTimeout
+}
+
+// DetectRunning reports whether the server appears to be running. A server is
+// running when its systemd unit (if any) is active, or a bedrock_server
+// process works in the server root. Where processes cannot be listed, the
+// server's port being taken is used instead. It returns nil when no running
+// server was found.
+func (s *Server) DetectRunning(control ServerControl) (*RunningServer, error) {
+	if control.Unit != "" {
+		// is-active exits non-zero for inactive, failed, and unknown units
+		// #nosec G204 - the unit name is passed as a single argument, not through a shell
+		if err := exec.Command("systemctl", "is-active", "--quiet", control.Unit).Run(); err == nil {
+			return &RunningServer{Source: "systemd unit " + control.Unit}, nil
+		}
+	}
+
+	pid, err := findServerProcess(s.Paths.ServerRoot)
+	if err == nil {
+		if pid != 0 {
+			return &RunningServer{PID: pid, Source: fmt.Sprintf("%s process %d", serverExecutable, pid)}, nil
+		}
+		return nil, nil
+	}
+	if !errors.Is(err, errNoProcessList) {
+		return nil, err
+	}
+
+	port := s.serverPort()
+	if portInUse(port) {
+		return &RunningServer{Source: fmt.Sprintf("UDP port %d in use", port)}, nil
+	}
+	return nil, nil
+}
+
+// serverPort returns server-port from server.properties, or the default port
+func (s *Server) serverPort() int {
+	value, found, err := readServerProperty(s.Paths.ServerRoot, "server-port")
+	if err != nil || !found {
+		return DefaultServerPort
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 || port > 65535 {
+		return DefaultServerPort
+	}
+	return port
+}
+
+// errNoProcessList is returned where running processes cannot be inspected
+var errNoProcessList = errors.New("process list unavailable")
+
+// findServerProcess returns the pid of a bedrock_server process whose working
+// directory is serverRoot, or zero if there is none. Processes are read from
+// /proc, so other platforms get errNoProcessList.
+func findServerProcess(serverRoot string) (int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, errNoProcessList
+	}
+
+	root, err := filepath.EvalSymlinks(serverRoot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve server root: %w", err)
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve server root: %w", err)
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		// Processes of other users can't be inspected and are skipped
+		exe, err := os.Readlink(filepath.Join("/proc", entry.Name(), "exe"))
+		if err != nil || filepath.Base(strings.TrimSuffix(exe, " (deleted)")) != serverExecutable {
+			continue
+		}
+		cwd, err := os.Readlink(filepath.Join("/proc", entry.Name(), "cwd"))
+		if err != nil || filepath.Clean(cwd) != root {
+			continue
+		}
+		return pid, nil
+	}
+	return 0, nil
+}
+
+// portInUse reports whether a UDP port can't be bound because something else holds it
+func portInUse(port int) bool {
+	conn, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return true
+	}
+	conn.Close()
+	return false
+}
+
+// Stop stops a running server and waits for it to stop
+func (s *Server) Stop(control ServerControl, running *RunningServer) error {
+	switch {
+	case control.Unit != "":
+		// #nosec G204 - the unit name is passed as a single argument, not through a shell
+		if output, err := exec.Command("systemctl", "stop", control.Unit).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to stop %s: %w: %s", control.Unit, err, strings.TrimSpace(string(output)))
+		}
+	case control.StopCommand != "":
+		if err := runShell(control.StopCommand, s.Paths.ServerRoot); err != nil {
+			return fmt.Errorf("stop command failed: %w", err)
+		}
+	case running != nil && running.PID != 0:
+		// The dedicated server saves the world and exits on an interrupt
+		process, err := os.FindProcess(running.PID)
+		if err != nil {
+			return fmt.Errorf("failed to find server process %d: %w", running.PID, err)
+		}
+		if err := process.Signal(os.Interrupt); err != nil {
+			return fmt.Errorf("failed to interrupt server process %d: %w", running.PID, err)
+		}
+	default:
+		return fmt.Errorf("don't know how to stop the server: configure a systemd unit or a stop command")
+	}
+
+	timeout := control.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		still, err := s.DetectRunning(control)
+		if err != nil {
+			return err
+		}
+		if still == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server still running after %s (%s)", timeout, still.Source)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Start starts the server again after Stop
+func (s *Server) Start(control ServerControl) error {
+	switch {
+	case control.Unit != "":
+		// #nosec G204 - the unit name is passed as a single argument, not through a shell
+		if output, err := exec.Command("systemctl", "start", control.Unit).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to start %s: %w: %s", control.Unit, err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	case control.StartCommand != "":
+		if err := runShell(control.StartCommand, s.Paths.ServerRoot); err != nil {
+			return fmt.Errorf("start command failed: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("don't know how to start the server: configure a systemd unit or a start command")
+	}
+}
+
+// runShell runs a user-configured command through the platform shell in dir
+func runShell(command, dir string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		// #nosec G204 - the command is configured by the user running blockbench
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr // Keep stdout clean for --json output
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,132 @@
+package minecraft
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newProcessTestServer(t *testing.T, properties string) *Server {
+	t.Helper()
+	tempDir, err := os.MkdirTemp("", "blockbench-process-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte(properties), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return server
+}
+
+func TestServerPort(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string
+		expected   int
+	}{
+		{"default", "level-name=W\n", DefaultServerPort},
+		{"configured", "level-name=W\nserver-port=19200\n", 19200},
+		{"invalid", "level-name=W\nserver-port=abc\n", DefaultServerPort},
+		{"out of range", "level-name=W\nserver-port=70000\n", DefaultServerPort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newProcessTestServer(t, tt.properties)
+			if port := server.serverPort(); port != tt.expected {
+				t.Errorf("Expected port %d, got %d", tt.expected, port)
+			}
+		})
+	}
+}
+
+func TestDetectAndStopServerProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self/exe"); err != nil {
+		t.Skip("process detection reads /proc")
+	}
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	server := newProcessTestServer(t, "level-name=W\n")
+	if running, err := server.DetectRunning(ServerControl{}); err != nil || running != nil {
+		t.Fatalf("Expected no running server, got %+v, %v", running, err)
+	}
+
+	// A copy of sleep named like the dedicated server stands in for it
+	binary := filepath.Join(t.TempDir(), serverExecutable)
+	copyExecutable(t, sleep, binary)
+	process := exec.Command(binary, "60")
+	process.Dir = server.Paths.ServerRoot
+	if err := process.Start(); err != nil {
+		t.Fatalf("Failed to start fake server: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = process.Wait()
+		close(done)
+	}()
+	defer func() {
+		_ = process.Process.Kill()
+		<-done
+	}()
+
+	var running *RunningServer
+	for i := 0; i < 50 && running == nil; i++ {
+		running, err = server.DetectRunning(ServerControl{})
+		if err != nil {
+			t.Fatalf("DetectRunning failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running == nil || running.PID != process.Process.Pid {
+		t.Fatalf("Expected to detect pid %d, got %+v", process.Process.Pid, running)
+	}
+
+	// A server in another directory is not this one
+	other := newProcessTestServer(t, "level-name=W\n")
+	if running, err := other.DetectRunning(ServerControl{}); err != nil || running != nil {
+		t.Errorf("Expected a server elsewhere to be ignored, got %+v, %v", running, err)
+	}
+
+	if err := server.Stop(ServerControl{Timeout: 5 * time.Second}, running); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := server.Start(ServerControl{}); err == nil {
+		t.Error("Expected Start without a unit or command to fail")
+	}
+}
+
+func copyExecutable(t *testing.T, src, dst string) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", src, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE, 0700)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		t.Fatalf("Failed to copy %s: %v", src, err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Failed to close %s: %v", dst, err)
+	}
+}
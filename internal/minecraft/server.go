@@ -1,21 +1,66 @@
 package minecraft
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
-	"github.com/makutaku/blockbench/pkg/validation"
+	bberrors "github.com/makutaku/blockbench/pkg/errors"
+	"github.com/makutaku/blockbench/pkg/filesystem"
 )
 
-// Server represents a Minecraft Bedrock server instance
+// Server represents a Minecraft Bedrock server instance. A Server is not safe
+// for concurrent use; goroutines that share one go through a ServerMutator.
 type Server struct {
 	Paths *ServerPaths
+
+	// VerifyCopies enables post-copy SHA-256 verification of installed pack files,
+	// re-copying a mismatched file once before failing
+	VerifyCopies bool
+
+	// Progress optionally receives the bytes copied by InstallPack
+	Progress filesystem.Progress
+
+	// Store, when set, deduplicates installed pack files into hard links to its blobs
+	Store *filesystem.ContentStore
+
+	// Ownership, when set, is the owner and mode given to installed pack files
+	// and the world configs that list them, so a server running as another
+	// user can read them
+	Ownership *filesystem.Ownership
+
+	// FS, when set, is the filesystem pack files are copied on and removed
+	// from, including the source directory given to InstallPack, and the
+	// one world configs are kept on. Its manifest index is kept in memory
+	// only. Retained versions, checksums, and the history stay on the real
+	// filesystem, and packs can't be linked.
+	FS filesystem.FS
+
+	// IncrementalBackups makes the backups taken before changing the server
+	// store only the files changed since the previous backup
+	IncrementalBackups bool
+
+	// Locale is the locale pack names and descriptions that are lang keys,
+	// such as pack.name, are resolved in from the packs' texts; DefaultLocale
+	// when empty
+	Locale string
+
+	index *PackIndex    // Manifest index cache, loaded on first use
+	packs *packSnapshot // Last ListInstalledPacks result, dropped by InvalidatePacks
 }
 
 // NewServer creates a new Server instance
 func NewServer(serverRoot string) (*Server, error) {
-	paths, err := NewServerPaths(serverRoot)
+	return NewServerWithOptions(serverRoot, PathOptions{})
+}
+
+// NewServerWithOptions creates a new Server instance whose paths are derived with opts
+func NewServerWithOptions(serverRoot string, opts PathOptions) (*Server, error) {
+	paths, err := NewServerPathsWithOptions(serverRoot, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to configure server paths: %w", err)
 	}
@@ -29,15 +74,43 @@ func NewServer(serverRoot string) (*Server, error) {
 	}, nil
 }
 
-// InstallPack installs a pack to the server with atomic operations
-// Updates config first, then copies files. If file copy fails, config is rolled back.
-func (s *Server) InstallPack(manifest *Manifest, packDir string) error {
-	packType := manifest.GetPackType()
+// PackInstallOptions contains per-pack options for InstallPack
+type PackInstallOptions struct {
+	Subpack  string       // Subpack folder name to activate; must be declared in the manifest
+	Position PackPosition // Where the pack goes in its world config; the zero value appends new packs and leaves existing ones in place
+	Replace  bool         // Remove the files of an existing pack directory first, so none of the replaced version's files remain
+}
 
-	var targetDir string
-	var configFile string
+// InstallPack installs a pack to the server, copying its files from packDir
+// before saving the world config that activates it. If the copy fails, the
+// world config is left as it was and a pack directory the install created is
+// removed.
+func (s *Server) InstallPack(manifest *Manifest, packDir string, opts PackInstallOptions) error {
+	return s.InstallPackFrom(manifest, s.copyPackFiles(manifest, packDir), opts)
+}
 
-	switch packType {
+// copyPackFiles returns a PackWriter that copies a pack's files from packDir
+func (s *Server) copyPackFiles(manifest *Manifest, packDir string) PackWriter {
+	return func(targetDir string) error {
+		var progress filesystem.Progress
+		if s.Progress != nil {
+			total, err := filesystem.TreeSizeFS(s.fs(), packDir)
+			if err != nil {
+				return fmt.Errorf("failed to scan %s: %w", packDir, err)
+			}
+			progress = s.Progress
+			progress.Start("Copying "+manifest.Header.Name, total)
+			defer progress.Finish()
+		}
+		return copyDir(s.fs(), packDir, targetDir, s.VerifyCopies, progress)
+	}
+}
+
+// PackInstallPaths returns the directory a pack is installed to and the world
+// config file that activates it
+func (s *Server) PackInstallPaths(manifest *Manifest) (packDir, configFile string, err error) {
+	var targetDir string
+	switch manifest.GetPackType() {
 	case PackTypeBehavior:
 		targetDir = s.Paths.BehaviorPacksDir
 		configFile = s.Paths.WorldBehaviorPacks
@@ -45,63 +118,36 @@ func (s *Server) InstallPack(manifest *Manifest, packDir string) error {
 		targetDir = s.Paths.ResourcePacksDir
 		configFile = s.Paths.WorldResourcePacks
 	default:
-		return fmt.Errorf("unknown pack type for pack %s", manifest.Header.UUID)
-	}
-
-	// Create pack directory name
-	packDirName := fmt.Sprintf("%s_%s", manifest.GetDisplayName(), validation.GetSafeUUIDPrefix(manifest.Header.UUID))
-	finalPackDir := filepath.Join(targetDir, packDirName)
-
-	// ATOMIC OPERATION STEP 1: Update config FIRST (safer to rollback)
-	config, err := LoadWorldConfig(configFile)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return "", "", fmt.Errorf("unknown pack type for pack %s", manifest.Header.UUID)
 	}
 
-	// Track the original pack entry for rollback (if it exists)
-	// This handles --force updates where we're replacing an existing pack
-	originalPack, packExisted := config.GetPack(manifest.Header.UUID)
+	return s.packInstallDir(targetDir, manifest), configFile, nil
+}
 
-	config = AddPackToConfig(config, manifest.Header.UUID, manifest.Header.Version)
+// PackWriter writes the files of a pack into its final server directory
+type PackWriter func(targetDir string) error
 
-	if err := SaveWorldConfig(configFile, config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+// InstallPackFrom installs a pack like InstallPack, but lets writeFiles produce
+// the pack directory, e.g. by streaming it straight out of an archive. It is
+// a transaction of one pack; see BeginTransaction for installing several.
+func (s *Server) InstallPackFrom(manifest *Manifest, writeFiles PackWriter, opts PackInstallOptions) error {
+	tx := s.BeginTransaction()
+	if err := tx.InstallPackFrom(manifest, writeFiles, opts); err != nil {
+		return errors.Join(err, tx.Rollback())
 	}
-
-	// ATOMIC OPERATION STEP 2: Copy pack files (if this fails, rollback will restore old config)
-	if err := copyDir(packDir, finalPackDir); err != nil {
-		// Rollback config change
-		var rollbackConfig WorldConfig
-		if packExisted {
-			// Pack existed before - restore the original version
-			rollbackConfig = RemovePackFromConfig(config, manifest.Header.UUID)
-			rollbackConfig = AddPackToConfig(rollbackConfig, originalPack.PackID, originalPack.Version)
-		} else {
-			// Pack was new - just remove the entry we added
-			rollbackConfig = RemovePackFromConfig(config, manifest.Header.UUID)
-		}
-
-		if rollbackErr := SaveWorldConfig(configFile, rollbackConfig); rollbackErr != nil {
-			// Config rollback failed - log warning but return original error
-			fmt.Fprintf(os.Stderr, "Warning: Failed to rollback config after copy failure: %v\n", rollbackErr)
-			if packExisted {
-				fmt.Fprintf(os.Stderr, "Manual cleanup may be required: restore pack %s version %d.%d.%d in %s\n",
-					manifest.Header.UUID, originalPack.Version[0], originalPack.Version[1], originalPack.Version[2], configFile)
-			} else {
-				fmt.Fprintf(os.Stderr, "Manual cleanup may be required: remove pack %s from %s\n", manifest.Header.UUID, configFile)
-			}
-		}
-		return fmt.Errorf("failed to copy pack files: %w", err)
+	if err := tx.Commit(); err != nil {
+		return errors.Join(err, tx.Rollback())
 	}
-
 	return nil
 }
 
 // UninstallPack removes a pack from the server with atomic operations
 // Updates config first, then removes files. If file removal fails, config is rolled back.
 func (s *Server) UninstallPack(packID string) error {
+	defer s.InvalidatePacks()
+
 	// Try to find and remove from behavior packs
-	behaviorConfig, err := LoadWorldConfig(s.Paths.WorldBehaviorPacks)
+	behaviorConfig, err := LoadWorldConfigFS(s.FS, s.Paths.WorldBehaviorPacks)
 	if err != nil {
 		return fmt.Errorf("failed to load behavior config: %w", err)
 	}
@@ -109,7 +155,7 @@ func (s *Server) UninstallPack(packID string) error {
 	if behaviorConfig.HasPack(packID) {
 		// ATOMIC OPERATION STEP 1: Update config FIRST (remove from config)
 		updatedBehaviorConfig := RemovePackFromConfig(behaviorConfig, packID)
-		if err := SaveWorldConfig(s.Paths.WorldBehaviorPacks, updatedBehaviorConfig); err != nil {
+		if err := SaveWorldConfigFS(s.FS, s.Paths.WorldBehaviorPacks, updatedBehaviorConfig); err != nil {
 			return fmt.Errorf("failed to save behavior config: %w", err)
 		}
 
@@ -117,19 +163,21 @@ func (s *Server) UninstallPack(packID string) error {
 		// If this fails, rollback will restore the config with the pack entry
 		if err := s.removePackDir(s.Paths.BehaviorPacksDir, packID); err != nil {
 			// Rollback config change - restore the pack entry we just removed
-			if rollbackErr := SaveWorldConfig(s.Paths.WorldBehaviorPacks, behaviorConfig); rollbackErr != nil {
+			if rollbackErr := SaveWorldConfigFS(s.FS, s.Paths.WorldBehaviorPacks, behaviorConfig); rollbackErr != nil {
 				// Config rollback failed - log warning but return original error
-				fmt.Fprintf(os.Stderr, "Warning: Failed to rollback config after directory removal failure: %v\n", rollbackErr)
-				fmt.Fprintf(os.Stderr, "Manual cleanup may be required: re-add pack %s to %s\n", packID, s.Paths.WorldBehaviorPacks)
+				slog.Error("Failed to rollback config after directory removal failure; manual cleanup may be required: re-add the pack",
+					"uuid", packID, "config", s.Paths.WorldBehaviorPacks, "error", rollbackErr)
 			}
 			return fmt.Errorf("failed to remove behavior pack directory: %w", err)
 		}
 
+		s.removeChecksums(packID)
+		slog.Info("Removed pack", "uuid", packID, "config", s.Paths.WorldBehaviorPacks)
 		return nil
 	}
 
 	// Try to find and remove from resource packs
-	resourceConfig, err := LoadWorldConfig(s.Paths.WorldResourcePacks)
+	resourceConfig, err := LoadWorldConfigFS(s.FS, s.Paths.WorldResourcePacks)
 	if err != nil {
 		return fmt.Errorf("failed to load resource config: %w", err)
 	}
@@ -137,7 +185,7 @@ func (s *Server) UninstallPack(packID string) error {
 	if resourceConfig.HasPack(packID) {
 		// ATOMIC OPERATION STEP 1: Update config FIRST (remove from config)
 		updatedResourceConfig := RemovePackFromConfig(resourceConfig, packID)
-		if err := SaveWorldConfig(s.Paths.WorldResourcePacks, updatedResourceConfig); err != nil {
+		if err := SaveWorldConfigFS(s.FS, s.Paths.WorldResourcePacks, updatedResourceConfig); err != nil {
 			return fmt.Errorf("failed to save resource config: %w", err)
 		}
 
@@ -145,71 +193,198 @@ func (s *Server) UninstallPack(packID string) error {
 		// If this fails, rollback will restore the config with the pack entry
 		if err := s.removePackDir(s.Paths.ResourcePacksDir, packID); err != nil {
 			// Rollback config change - restore the pack entry we just removed
-			if rollbackErr := SaveWorldConfig(s.Paths.WorldResourcePacks, resourceConfig); rollbackErr != nil {
+			if rollbackErr := SaveWorldConfigFS(s.FS, s.Paths.WorldResourcePacks, resourceConfig); rollbackErr != nil {
 				// Config rollback failed - log warning but return original error
-				fmt.Fprintf(os.Stderr, "Warning: Failed to rollback config after directory removal failure: %v\n", rollbackErr)
-				fmt.Fprintf(os.Stderr, "Manual cleanup may be required: re-add pack %s to %s\n", packID, s.Paths.WorldResourcePacks)
+				slog.Error("Failed to rollback config after directory removal failure; manual cleanup may be required: re-add the pack",
+					"uuid", packID, "config", s.Paths.WorldResourcePacks, "error", rollbackErr)
 			}
 			return fmt.Errorf("failed to remove resource pack directory: %w", err)
 		}
 
+		s.removeChecksums(packID)
+		slog.Info("Removed pack", "uuid", packID, "config", s.Paths.WorldResourcePacks)
 		return nil
 	}
 
-	return fmt.Errorf("pack with UUID %s is not installed on this server. Use 'blockbench list <server-path>' to see all installed packs", packID)
+	return bberrors.Mark(fmt.Errorf("pack with UUID %s is not installed on this server. Use 'blockbench list <server-path>' to see all installed packs", packID), bberrors.ErrPackNotFound)
 }
 
-// ListInstalledPacks returns a list of all installed packs
-func (s *Server) ListInstalledPacks() ([]InstalledPack, error) {
-	var packs []InstalledPack
+// MovePack changes the activation index of an installed pack in whichever world config lists it
+func (s *Server) MovePack(packID string, index int) error {
+	defer s.InvalidatePacks()
+
+	for _, configFile := range []string{s.Paths.WorldBehaviorPacks, s.Paths.WorldResourcePacks} {
+		config, err := LoadWorldConfigFS(s.FS, configFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config %s: %w", configFile, err)
+		}
+		if !config.HasPack(packID) {
+			continue
+		}
+
+		moved, err := MovePackInConfig(config, packID, index)
+		if err != nil {
+			return err
+		}
+		if err := SaveWorldConfigFS(s.FS, configFile, moved); err != nil {
+			return fmt.Errorf("failed to save config %s: %w", configFile, err)
+		}
+		return nil
+	}
 
-	// Load behavior packs
-	behaviorConfig, err := LoadWorldConfig(s.Paths.WorldBehaviorPacks)
+	return fmt.Errorf("pack %s is not active in any world config", packID)
+}
+
+// packSnapshot is one scan of the installed packs, valid while the world
+// configs and pack directories it was taken from are unchanged
+type packSnapshot struct {
+	packs  []InstalledPack
+	stamps []fileStamp
+}
+
+// fileStamp identifies one version of a file or directory
+type fileStamp struct {
+	path    string
+	size    int64
+	modTime time.Time
+	exists  bool
+}
+
+func stampFile(fsys filesystem.FS, path string) fileStamp {
+	info, err := fsys.Stat(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load behavior config: %w", err)
+		return fileStamp{path: path}
 	}
+	return fileStamp{path: path, size: info.Size(), modTime: info.ModTime(), exists: true}
+}
 
-	for _, pack := range behaviorConfig {
-		installedPack := InstalledPack{
-			PackID:  pack.PackID,
-			Version: pack.Version,
-			Type:    PackTypeBehavior,
-		}
+// stampPackSources stamps everything a pack scan reads from
+func (s *Server) stampPackSources() []fileStamp {
+	return []fileStamp{
+		stampFile(s.fs(), s.Paths.WorldBehaviorPacks),
+		stampFile(s.fs(), s.Paths.WorldResourcePacks),
+		stampFile(s.fs(), s.Paths.BehaviorPacksDir),
+		stampFile(s.fs(), s.Paths.ResourcePacksDir),
+	}
+}
 
-		// Try to load manifest for more details
-		if manifest, err := s.loadPackManifest(s.Paths.BehaviorPacksDir, pack.PackID); err == nil {
-			installedPack.Name = manifest.GetDisplayName()
-			installedPack.Description = manifest.Header.Description
+// fresh reports whether none of the snapshot's sources changed since it was taken
+func (snap *packSnapshot) fresh(stamps []fileStamp) bool {
+	for i, stamp := range stamps {
+		if stamp != snap.stamps[i] {
+			return false
 		}
+	}
+	return true
+}
 
-		packs = append(packs, installedPack)
+// InvalidatePacks drops the pack list cached by ListInstalledPacks. The
+// Server's own mutations call it; code that changes world configs or pack
+// directories by other means must call it too, since the cache only notices
+// outside changes through file modification times.
+func (s *Server) InvalidatePacks() {
+	s.packs = nil
+}
+
+// ListInstalledPacks returns a list of all installed packs. Packs whose
+// directory or manifest can't be read are still listed, with a Status
+// saying why their details are missing. Within one Server the result is
+// cached, so repeated calls during a command cost a few stat calls.
+func (s *Server) ListInstalledPacks() ([]InstalledPack, error) {
+	stamps := s.stampPackSources()
+	if s.packs != nil && s.packs.fresh(stamps) {
+		return append([]InstalledPack(nil), s.packs.packs...), nil
 	}
 
-	// Load resource packs
-	resourceConfig, err := LoadWorldConfig(s.Paths.WorldResourcePacks)
+	packs, err := s.scanInstalledPacks()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load resource config: %w", err)
+		return nil, err
 	}
+	s.packs = &packSnapshot{packs: packs, stamps: stamps}
+	return append([]InstalledPack(nil), packs...), nil
+}
 
-	for _, pack := range resourceConfig {
-		installedPack := InstalledPack{
-			PackID:  pack.PackID,
-			Version: pack.Version,
-			Type:    PackTypeResource,
-		}
+// scanInstalledPacks reads the world configs and looks up every active pack
+func (s *Server) scanInstalledPacks() ([]InstalledPack, error) {
+	var packs []InstalledPack
 
-		// Try to load manifest for more details
-		if manifest, err := s.loadPackManifest(s.Paths.ResourcePacksDir, pack.PackID); err == nil {
-			installedPack.Name = manifest.GetDisplayName()
-			installedPack.Description = manifest.Header.Description
+	for _, source := range []struct {
+		packType   PackType
+		configFile string
+	}{
+		{PackTypeBehavior, s.Paths.WorldBehaviorPacks},
+		{PackTypeResource, s.Paths.WorldResourcePacks},
+	} {
+		config, err := LoadWorldConfigFS(s.FS, source.configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s config: %w", source.packType, err)
 		}
 
-		packs = append(packs, installedPack)
+		indexed, indexErr := s.IndexedPacks(source.packType)
+		for _, pack := range config {
+			installedPack := InstalledPack{
+				PackID:  pack.PackID,
+				Version: pack.Version,
+				Type:    source.packType,
+				Subpack: pack.Subpack,
+			}
+
+			if indexErr != nil {
+				installedPack.Status = PackStatusDirectoryMissing
+				installedPack.StatusDetail = indexErr.Error()
+			} else {
+				if entry := resolvePackStatus(&installedPack, indexed); entry != nil {
+					manifest := entry.Manifest
+					installedPack.Name, installedPack.Description = localizeManifestTexts(s.FS, manifest, entry.Dir, s.locale())
+					installedPack.Subpacks = manifest.Subpacks
+					installedPack.Capabilities = manifest.Capabilities
+					installedPack.Metadata = manifest.Metadata
+				}
+			}
+
+			packs = append(packs, installedPack)
+		}
 	}
 
 	return packs, nil
 }
 
+// resolvePackStatus sets the Status of an installed pack from the indexed pack
+// directories and returns its index entry when its manifest could be read. A directory
+// without a readable manifest is attributed to the pack through the directory
+// name blockbench installs packs under (see isPackDirName).
+func resolvePackStatus(pack *InstalledPack, indexed []*IndexedPack) *IndexedPack {
+	for _, entry := range indexed {
+		if entry.Manifest != nil && entry.Manifest.Header.UUID == pack.PackID {
+			pack.Status = PackStatusOK
+			pack.Link, _ = LinkedPackTarget(entry.Dir)
+			return entry
+		}
+	}
+
+	for _, entry := range indexed {
+		if !isPackDirName(filepath.Base(entry.Dir), pack.PackID) {
+			continue
+		}
+		switch {
+		case entry.Error != "":
+			pack.Status = PackStatusManifestInvalid
+			pack.StatusDetail = fmt.Sprintf("%s: %s", entry.Dir, entry.Error)
+		case entry.Manifest != nil:
+			pack.Status = PackStatusManifestInvalid
+			pack.StatusDetail = fmt.Sprintf("%s: manifest declares UUID %s", entry.Dir, entry.Manifest.Header.UUID)
+		default:
+			pack.Status = PackStatusManifestMissing
+			pack.StatusDetail = fmt.Sprintf("%s has no manifest.json", entry.Dir)
+		}
+		return nil
+	}
+
+	pack.Status = PackStatusDirectoryMissing
+	pack.StatusDetail = "no pack directory has a manifest with this UUID"
+	return nil
+}
+
 // ListInstalledPacksWithDependencies returns installed packs with their dependency information
 func (s *Server) ListInstalledPacksWithDependencies() ([]InstalledPackWithDependencies, error) {
 	packs, err := s.ListInstalledPacks()
@@ -245,26 +420,39 @@ func (s *Server) ListInstalledPacksWithDependencies() ([]InstalledPackWithDepend
 
 // loadPackManifestByType loads a pack manifest given its ID and type
 func (s *Server) loadPackManifestByType(packID string, packType PackType) (*Manifest, error) {
-	var baseDir string
-	switch packType {
-	case PackTypeBehavior:
-		baseDir = s.Paths.BehaviorPacksDir
-	case PackTypeResource:
-		baseDir = s.Paths.ResourcePacksDir
-	default:
-		return nil, fmt.Errorf("unknown pack type: %s", packType)
+	baseDir, err := s.packBaseDir(packType)
+	if err != nil {
+		return nil, err
 	}
 
 	return s.loadPackManifest(baseDir, packID)
 }
 
+// PackStatus says whether an installed pack's files could be read
+type PackStatus string
+
+const (
+	PackStatusOK               PackStatus = "ok"
+	PackStatusManifestMissing  PackStatus = "manifest-missing"  // Pack directory found, but without manifest.json
+	PackStatusManifestInvalid  PackStatus = "manifest-invalid"  // Pack directory found, but its manifest can't be parsed
+	PackStatusDirectoryMissing PackStatus = "directory-missing" // Active in the world config, but no pack directory found
+)
+
 // InstalledPack represents an installed pack
 type InstalledPack struct {
-	PackID      string   `json:"pack_id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Version     [3]int   `json:"version"`
-	Type        PackType `json:"type"`
+	PackID       string            `json:"pack_id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Version      [3]int            `json:"version"`
+	Type         PackType          `json:"type"`
+	Subpack      string            `json:"subpack,omitempty"`  // Selected subpack from the world config
+	Subpacks     []ManifestSubpack `json:"subpacks,omitempty"` // Subpacks declared by the pack manifest
+	Capabilities []string          `json:"capabilities,omitempty"`
+	Metadata     *ManifestMetadata `json:"metadata,omitempty"`
+	Status       PackStatus        `json:"status"`
+	StatusDetail string            `json:"status_detail,omitempty"` // Why the status is not ok
+	Link         string            `json:"link,omitempty"`          // Source directory of a pack installed with a link
+	Size         int64             `json:"size,omitempty"`          // Bytes in the pack directory, when measured with PackSize
 }
 
 // InstalledPackWithDependencies extends InstalledPack with dependency information
@@ -274,100 +462,157 @@ type InstalledPackWithDependencies struct {
 	Modules      []string `json:"modules"`      // Script API modules used
 }
 
-// removePackDir removes a pack directory by searching for directories containing the pack ID
+// removePackDir removes a pack directory by searching for directories containing the pack ID.
+// A linked pack only loses its link.
 func (s *Server) removePackDir(baseDir, packID string) error {
-	entries, err := os.ReadDir(baseDir)
+	pack, err := s.packIndex().Find(baseDir, packID)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", baseDir, err)
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		packPath := filepath.Join(baseDir, entry.Name())
-		manifestPath := filepath.Join(packPath, "manifest.json")
-
-		manifest, err := ParseManifest(manifestPath)
-		if err != nil {
-			continue // Skip if can't read manifest
-		}
-
-		if manifest.Header.UUID == packID {
-			return os.RemoveAll(packPath)
+		if link, ok := s.brokenPackLink(baseDir, packID); ok {
+			_, err = unlinkPack(link)
 		}
+		return err
 	}
-
-	return fmt.Errorf("pack directory not found for pack ID %s", packID)
+	if linked, err := unlinkPack(pack.Dir); linked {
+		return err
+	}
+	return s.fs().RemoveAll(pack.Dir)
 }
 
 // FindAndLoadManifestByUUID finds a pack's manifest by UUID
 // This is useful when you know the pack ID but not its directory name
 func (s *Server) FindAndLoadManifestByUUID(packID string, packType PackType) (*Manifest, error) {
-	var baseDir string
-	switch packType {
-	case PackTypeBehavior:
-		baseDir = s.Paths.BehaviorPacksDir
-	case PackTypeResource:
-		baseDir = s.Paths.ResourcePacksDir
-	default:
-		return nil, fmt.Errorf("unknown pack type: %s", packType)
+	baseDir, err := s.packBaseDir(packType)
+	if err != nil {
+		return nil, err
 	}
 
-	entries, err := os.ReadDir(baseDir)
+	pack, err := s.packIndex().Find(baseDir, packID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory %s: %w", baseDir, err)
+		return nil, bberrors.Mark(fmt.Errorf("manifest not found for pack ID %s in %s packs", packID, packType), bberrors.ErrPackNotFound)
 	}
+	return pack.Manifest, nil
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+// FindPackDirectory returns the installed directory of a pack by UUID
+func (s *Server) FindPackDirectory(packID string, packType PackType) (string, error) {
+	baseDir, err := s.packBaseDir(packType)
+	if err != nil {
+		return "", err
+	}
 
-		manifestPath := filepath.Join(baseDir, entry.Name(), "manifest.json")
-		manifest, err := ParseManifest(manifestPath)
-		if err != nil {
-			continue // Skip directories without valid manifests
+	pack, err := s.packIndex().Find(baseDir, packID)
+	if err != nil {
+		if link, ok := s.brokenPackLink(baseDir, packID); ok {
+			return link, nil
 		}
+		return "", err
+	}
+	return pack.Dir, nil
+}
 
-		if manifest.Header.UUID == packID {
-			return manifest, nil
-		}
+// PackSize returns the total size of the regular files in a pack's installed
+// directory, using the size cached in the pack index when it is fresh
+func (s *Server) PackSize(packID string, packType PackType) (int64, error) {
+	size, err := s.packSize(packID, packType)
+	s.packIndex().flush()
+	return size, err
+}
+
+// MeasurePackSizes sets the Size of each pack like PackSize, saving the pack
+// index once. A pack whose directory is missing is left at 0.
+func (s *Server) MeasurePackSizes(packs []InstalledPack) {
+	for i := range packs {
+		packs[i].Size, _ = s.packSize(packs[i].PackID, packs[i].Type)
 	}
+	s.packIndex().flush()
+}
 
-	return nil, fmt.Errorf("manifest not found for pack ID %s in %s packs", packID, packType)
+// packSize measures a pack's directory without saving the pack index
+func (s *Server) packSize(packID string, packType PackType) (int64, error) {
+	baseDir, err := s.packBaseDir(packType)
+	if err != nil {
+		return 0, err
+	}
+	pack, err := s.packIndex().Find(baseDir, packID)
+	if err != nil {
+		if _, ok := s.brokenPackLink(baseDir, packID); ok {
+			return 0, nil // A broken link has nothing to measure
+		}
+		return 0, err
+	}
+	return s.packIndex().Size(pack)
 }
 
-// loadPackManifest loads a manifest for an installed pack (internal helper)
-func (s *Server) loadPackManifest(baseDir, packID string) (*Manifest, error) {
-	entries, err := os.ReadDir(baseDir)
+// IndexedPacks returns the cached manifest of every directory installed for
+// packType, including directories whose manifest is missing or invalid
+func (s *Server) IndexedPacks(packType PackType) ([]*IndexedPack, error) {
+	baseDir, err := s.packBaseDir(packType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory %s: %w", baseDir, err)
+		return nil, err
 	}
+	return s.packIndex().Packs(baseDir)
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+// fs returns the filesystem pack files live on
+func (s *Server) fs() filesystem.FS {
+	if s.FS == nil {
+		return filesystem.OSFS{}
+	}
+	return s.FS
+}
 
-		manifestPath := filepath.Join(baseDir, entry.Name(), "manifest.json")
-		manifest, err := ParseManifest(manifestPath)
-		if err != nil {
-			continue // Skip if can't read manifest
-		}
+// locale returns the locale pack texts are resolved in
+func (s *Server) locale() string {
+	if s.Locale == "" {
+		return DefaultLocale
+	}
+	return s.Locale
+}
 
-		if manifest.Header.UUID == packID {
-			return manifest, nil
+// packIndex loads the manifest index cache on first use. The cache file
+// describes the real filesystem, so the index of another FS starts empty and
+// is never saved.
+func (s *Server) packIndex() *PackIndex {
+	if s.index == nil {
+		if s.FS != nil {
+			s.index = &PackIndex{Version: packIndexVersion, Dirs: make(map[string]*indexedPackDir), fsys: s.FS}
+		} else {
+			s.index = LoadPackIndex(s.Paths.IndexFile)
 		}
 	}
+	return s.index
+}
+
+// packBaseDir returns the directory packs of packType are installed into
+func (s *Server) packBaseDir(packType PackType) (string, error) {
+	switch packType {
+	case PackTypeBehavior:
+		return s.Paths.BehaviorPacksDir, nil
+	case PackTypeResource:
+		return s.Paths.ResourcePacksDir, nil
+	default:
+		return "", fmt.Errorf("unknown pack type: %s", packType)
+	}
+}
 
-	return nil, fmt.Errorf("manifest not found for pack ID %s", packID)
+// loadPackManifest loads a manifest for an installed pack (internal helper)
+func (s *Server) loadPackManifest(baseDir, packID string) (*Manifest, error) {
+	pack, err := s.packIndex().Find(baseDir, packID)
+	if err != nil {
+		return nil, err
+	}
+	return pack.Manifest, nil
 }
 
-// copyDir recursively copies a directory
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+// copyDir recursively copies a directory. When verify is set, each copied file
+// is hash-compared with its source and re-copied once on mismatch. Bytes
+// copied are reported to progress if it is not nil.
+func copyDir(fsys filesystem.FS, src, dst string, verify bool, progress filesystem.Progress) error {
+	return filesystem.WalkDir(fsys, src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
 		if err != nil {
 			return err
 		}
@@ -380,28 +625,75 @@ func copyDir(src, dst string) error {
 		dstPath := filepath.Join(dst, relPath)
 
 		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-
-		// Copy file
-		// #nosec G304 - path is within controlled extraction directory
-		srcFile, err := os.Open(path)
-		if err != nil {
-			return err
+			return fsys.MkdirAll(dstPath, info.Mode())
 		}
-		defer srcFile.Close()
 
-		// #nosec G304 - dstPath is within validated server directory structure
-		dstFile, err := os.Create(dstPath)
-		if err != nil {
+		if err := copyFile(fsys, path, dstPath, info.Mode(), progress); err != nil {
 			return err
 		}
-		defer dstFile.Close()
 
-		if _, err := srcFile.WriteTo(dstFile); err != nil {
-			return err
+		if !verify {
+			return nil
 		}
 
-		return os.Chmod(dstPath, info.Mode())
+		return verifyCopiedFile(fsys, path, dstPath, info.Mode())
 	})
 }
+
+// copyFile copies a single file and applies the given mode
+func copyFile(fsys filesystem.FS, src, dst string, mode os.FileMode, progress filesystem.Progress) error {
+	srcFile, err := filesystem.Open(fsys, src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	// Replace rather than truncate an existing file: it may be a hard link into
+	// the content store, and writing through it would change every linked pack
+	if err := fsys.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	dstFile, err := fsys.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	if _, err := filesystem.CopyWithProgress(dstFile, srcFile, progress); err != nil {
+		dstFile.Close()
+		return err
+	}
+
+	// Close explicitly so write-back errors surface before verification
+	if err := dstFile.Close(); err != nil {
+		return err
+	}
+
+	return fsys.Chmod(dst, mode)
+}
+
+// verifyCopiedFile compares a copied file with its source and retries the copy once on mismatch
+func verifyCopiedFile(fsys filesystem.FS, src, dst string, mode os.FileMode) error {
+	equal, err := filesystem.FilesEqualFS(fsys, src, dst)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", dst, err)
+	}
+	if equal {
+		return nil
+	}
+
+	slog.Warn("Checksum mismatch, re-copying", "path", dst)
+	if err := copyFile(fsys, src, dst, mode, nil); err != nil {
+		return fmt.Errorf("failed to re-copy %s after checksum mismatch: %w", dst, err)
+	}
+
+	equal, err = filesystem.FilesEqualFS(fsys, src, dst)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", dst, err)
+	}
+	if !equal {
+		return fmt.Errorf("checksum mismatch for %s persists after re-copy", dst)
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package minecraft
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/slug"
+)
+
+func TestResolvePackStatus(t *testing.T) {
+	const packID = "11111111-1111-1111-1111-111111111111"
+	manifest := &Manifest{Header: ManifestHeader{Name: "Pack", UUID: packID}}
+	other := &Manifest{Header: ManifestHeader{Name: "Other", UUID: "22222222-2222-2222-2222-222222222222"}}
+
+	tests := []struct {
+		name         string
+		indexed      []*IndexedPack
+		wantStatus   PackStatus
+		wantManifest bool
+	}{
+		{
+			name:         "manifest found under any directory name",
+			indexed:      []*IndexedPack{{Dir: "/packs/renamed", Manifest: manifest}},
+			wantStatus:   PackStatusOK,
+			wantManifest: true,
+		},
+		{
+			name:       "installed directory without manifest",
+			indexed:    []*IndexedPack{{Dir: "/packs/Pack_11111111"}},
+			wantStatus: PackStatusManifestMissing,
+		},
+		{
+			name:       "installed directory with unparseable manifest",
+			indexed:    []*IndexedPack{{Dir: "/packs/Pack_11111111", Error: "failed to parse manifest JSON"}},
+			wantStatus: PackStatusManifestInvalid,
+		},
+		{
+			name:       "installed directory whose manifest declares another UUID",
+			indexed:    []*IndexedPack{{Dir: "/packs/Pack_11111111", Manifest: other}},
+			wantStatus: PackStatusManifestInvalid,
+		},
+		{
+			name:       "no matching directory",
+			indexed:    []*IndexedPack{{Dir: "/packs/Other_22222222", Manifest: other}, {Dir: "/packs/broken", Error: "bad"}},
+			wantStatus: PackStatusDirectoryMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pack := InstalledPack{PackID: packID}
+			got := resolvePackStatus(&pack, tt.indexed)
+
+			if pack.Status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s (%s)", tt.wantStatus, pack.Status, pack.StatusDetail)
+			}
+			if (got != nil) != tt.wantManifest {
+				t.Errorf("Expected manifest returned = %v, got %+v", tt.wantManifest, got)
+			}
+			if pack.Status != PackStatusOK && pack.StatusDetail == "" {
+				t.Error("Expected a status detail for a pack that is not ok")
+			}
+		})
+	}
+}
+
+func TestListInstalledPacksSnapshot(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-server-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	const packA = "11111111-1111-1111-1111-111111111111"
+	const packB = "22222222-2222-2222-2222-222222222222"
+	writeIndexedPack(t, server.Paths.BehaviorPacksDir, "A_11111111", packA, "Pack A")
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packA, Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	packs, err := server.ListInstalledPacks()
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("Expected 1 pack, got %+v, %v", packs, err)
+	}
+	snapshot := server.packs
+
+	info, err := os.Stat(filepath.Join(server.Paths.BehaviorPacksDir, "A_11111111", "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to stat manifest: %v", err)
+	}
+	if size, err := server.PackSize(packA, PackTypeBehavior); err != nil || size != info.Size() {
+		t.Errorf("PackSize = %d, %v, want the size of the pack's only file, %d", size, err, info.Size())
+	}
+
+	// Unchanged sources reuse the snapshot, and callers can't modify it
+	packs[0].Name = "changed"
+	packs, err = server.ListInstalledPacks()
+	if err != nil {
+		t.Fatalf("ListInstalledPacks failed: %v", err)
+	}
+	if server.packs != snapshot {
+		t.Error("Expected unchanged sources to reuse the snapshot")
+	}
+	if packs[0].Name != "Pack A" {
+		t.Errorf("Expected the snapshot to be unaffected by callers, got name %q", packs[0].Name)
+	}
+
+	// A world config changed by someone else is noticed through its stamp
+	config := WorldConfig{{PackID: packA, Version: [3]int{1, 0, 0}}, {PackID: packB, Version: [3]int{1, 0, 0}}}
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, config); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if packs, _ := server.ListInstalledPacks(); len(packs) != 2 {
+		t.Errorf("Expected the changed config to be rescanned, got %d pack(s)", len(packs))
+	}
+
+	// The server's own mutations invalidate the snapshot explicitly
+	if err := server.MovePack(packB, 0); err != nil {
+		t.Fatalf("MovePack failed: %v", err)
+	}
+	if server.packs != nil {
+		t.Error("Expected MovePack to invalidate the snapshot")
+	}
+	packs, _ = server.ListInstalledPacks()
+	if len(packs) != 2 || packs[0].PackID != packB {
+		t.Errorf("Expected the moved pack first, got %+v", packs)
+	}
+}
+
+func TestInstallPackMemFS(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-server-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	// The source pack and the installed copy both live in memory
+	m := filesystem.NewMemFS()
+	server.FS = m
+	server.VerifyCopies = true
+	if err := m.MkdirAll("/extracted/BP/scripts", 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := filesystem.WriteFile(m, "/extracted/BP/scripts/main.js", []byte("// main"), 0600); err != nil {
+		t.Fatalf("Failed to write pack file: %v", err)
+	}
+	if err := filesystem.WriteFile(m, "/extracted/BP/manifest.json",
+		[]byte(`{"format_version": 2, "header": {"name": "Pack", "uuid": "11111111-1111-1111-1111-111111111111", "version": [1, 0, 0]}, "modules": [{"type": "data", "uuid": "99999999-9999-9999-9999-999999999999", "version": [1, 0, 0]}]}`), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	manifest := &Manifest{
+		Header:  ManifestHeader{Name: "Pack", UUID: "11111111-1111-1111-1111-111111111111", Version: [3]int{1, 0, 0}},
+		Modules: []ManifestModule{{Type: "data"}},
+	}
+	if err := server.InstallPack(manifest, "/extracted/BP", PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPack failed: %v", err)
+	}
+
+	packDir, _, err := server.PackInstallPaths(manifest)
+	if err != nil {
+		t.Fatalf("PackInstallPaths failed: %v", err)
+	}
+	data, err := filesystem.ReadFile(m, filepath.Join(packDir, "scripts", "main.js"))
+	if err != nil || string(data) != "// main" {
+		t.Errorf("Expected the pack to be copied in memory, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(packDir); !os.IsNotExist(err) {
+		t.Error("Expected no pack files on disk")
+	}
+
+	// The world config is kept in memory too
+	config, err := LoadWorldConfigFS(m, server.Paths.WorldBehaviorPacks)
+	if err != nil || len(config) != 1 || config[0].PackID != manifest.Header.UUID {
+		t.Errorf("Expected the pack in the world config, got %+v, %v", config, err)
+	}
+	if _, err := os.Stat(server.Paths.WorldBehaviorPacks); !os.IsNotExist(err) {
+		t.Error("Expected no world config on disk")
+	}
+
+	// Listing finds the pack's manifest in memory
+	packs, err := server.ListInstalledPacks()
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("Expected one installed pack, got %+v, %v", packs, err)
+	}
+	if packs[0].Status != PackStatusOK || packs[0].Name != "Pack" {
+		t.Errorf("Expected the pack listed with its manifest, got status %s (%s), name %q", packs[0].Status, packs[0].StatusDetail, packs[0].Name)
+	}
+	if size, err := server.PackSize(manifest.Header.UUID, PackTypeBehavior); err != nil || size == 0 {
+		t.Errorf("Expected the pack's size measured in memory, got %d, %v", size, err)
+	}
+
+	if err := server.UninstallPack(manifest.Header.UUID); err != nil {
+		t.Fatalf("UninstallPack failed: %v", err)
+	}
+	if _, err := m.Stat(packDir); !os.IsNotExist(err) {
+		t.Error("Expected the pack directory removed from memory")
+	}
+	if packs, err := server.ListInstalledPacks(); err != nil || len(packs) != 0 {
+		t.Errorf("Expected no installed packs after the uninstall, got %+v, %v", packs, err)
+	}
+}
+
+func TestInstallPackFromRollbackKeepsEntries(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"worlds/W", "development_behavior_packs", "development_resource_packs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0750); err != nil {
+			t.Fatalf("Failed to create server dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "server.properties"), []byte("level-name=W\n"), 0600); err != nil {
+		t.Fatalf("Failed to write server.properties: %v", err)
+	}
+	server, err := NewServer(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	// The pack being updated comes first, with a subpack and a field blockbench doesn't know
+	const original = `[
+  {"pack_id": "11111111-1111-1111-1111-111111111111", "version": [1, 0, 0], "subpack": "low", "priority": 5},
+  {"pack_id": "22222222-2222-2222-2222-222222222222", "version": [1, 0, 0]}
+]`
+	if err := os.WriteFile(server.Paths.WorldBehaviorPacks, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	before, err := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+	if err != nil {
+		t.Fatalf("LoadWorldConfig failed: %v", err)
+	}
+
+	manifest := &Manifest{
+		Header:  ManifestHeader{Name: "Pack", UUID: "11111111-1111-1111-1111-111111111111", Version: [3]int{2, 0, 0}},
+		Modules: []ManifestModule{{Type: "data"}},
+	}
+	failCopy := func(string) error { return errors.New("disk full") }
+	if err := server.InstallPackFrom(manifest, failCopy, PackInstallOptions{Position: PackPosition{Kind: PositionBottom}}); err == nil {
+		t.Fatal("Expected the failed copy to fail the install")
+	}
+
+	after, err := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+	if err != nil {
+		t.Fatalf("LoadWorldConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected the rollback to restore the config as it was:\n got %+v\nwant %+v", after, before)
+	}
+}
+
+func TestPackInstallPathsNames(t *testing.T) {
+	server := newWorldTestServer(t)
+	const packID = "11111111-1111-1111-1111-111111111111"
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Pack", "Pack_11111111"},
+		{"Blocks/Items", "Blocks Items_11111111"},
+		{"§6🔥 Fire: Pack", "Fire Pack_11111111"},
+		{"🔥", "Pack_11111111"},
+		{strings.Repeat("n", 300), strings.Repeat("n", slug.MaxLength) + "_11111111"},
+	}
+	for _, tt := range tests {
+		manifest := &Manifest{Header: ManifestHeader{Name: tt.name, UUID: packID}, Modules: []ManifestModule{{Type: "data"}}}
+		packDir, configFile, err := server.PackInstallPaths(manifest)
+		if err != nil {
+			t.Fatalf("PackInstallPaths failed: %v", err)
+		}
+		if filepath.Dir(packDir) != server.Paths.BehaviorPacksDir || filepath.Base(packDir) != tt.expected {
+			t.Errorf("PackInstallPaths(%.20q) = %s, want %s in %s", tt.name, packDir, tt.expected, server.Paths.BehaviorPacksDir)
+		}
+		if configFile != server.Paths.WorldBehaviorPacks {
+			t.Errorf("PackInstallPaths config = %s, want %s", configFile, server.Paths.WorldBehaviorPacks)
+		}
+	}
+}
+
+func TestPackInstallDirExisting(t *testing.T) {
+	server := newWorldTestServer(t)
+	baseDir := server.Paths.BehaviorPacksDir
+	const packID = "11111111-1111-1111-1111-111111111111"
+	manifest := &Manifest{Header: ManifestHeader{Name: "Fire: Pack", UUID: packID}, Modules: []ManifestModule{{Type: "data"}}}
+
+	// Another pack whose UUID starts the same took the name
+	writeIndexedPack(t, baseDir, "Fire Pack_11111111", "11111111-2222-2222-2222-222222222222", "Fire Pack")
+	if dir := server.packInstallDir(baseDir, manifest); filepath.Base(dir) != "Fire Pack_11111111-2" {
+		t.Errorf("packInstallDir with the name taken = %s, want Fire Pack_11111111-2", dir)
+	}
+
+	// A directory left by an install before slugs is upgraded in place
+	legacy := writeIndexedPack(t, baseDir, "Fire: Pack_11111111", packID, "Fire: Pack")
+	if dir := server.packInstallDir(baseDir, manifest); dir != legacy {
+		t.Errorf("packInstallDir with a legacy directory = %s, want %s", dir, legacy)
+	}
+}
+
+func TestIsPackDirName(t *testing.T) {
+	const packID = "11111111-1111-1111-1111-111111111111"
+	tests := []struct {
+		dirName  string
+		expected bool
+	}{
+		{"Fire Pack_11111111", true},
+		{"Fire: Pack_11111111", true},
+		{"Fire Pack_11111111-2", true},
+		{"Fire Pack_11111111-12", true},
+		{"Fire Pack_11111111-", false},
+		{"Fire Pack_11111111-x", false},
+		{"Fire Pack_22222222", false},
+		{"11111111", false},
+	}
+	for _, tt := range tests {
+		if got := isPackDirName(tt.dirName, packID); got != tt.expected {
+			t.Errorf("isPackDirName(%q) = %v, want %v", tt.dirName, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package minecraft
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ServerState is a read-only snapshot of a server's installed packs and world
+// configs. It never changes once taken, so any number of goroutines can read
+// it while the server is being modified; its accessors return copies.
+type ServerState struct {
+	packs    []InstalledPack
+	behavior WorldConfig
+	resource WorldConfig
+	takenAt  time.Time
+}
+
+// Snapshot reads the installed packs and world configs of s into a
+// ServerState. Like every other Server method, it must not run concurrently
+// with other uses of s; ServerMutator takes care of that.
+func (s *Server) Snapshot() (*ServerState, error) {
+	packs, err := s.ListInstalledPacks()
+	if err != nil {
+		return nil, err
+	}
+	behavior, err := LoadWorldConfigFS(s.FS, s.Paths.WorldBehaviorPacks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load behavior config: %w", err)
+	}
+	resource, err := LoadWorldConfigFS(s.FS, s.Paths.WorldResourcePacks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource config: %w", err)
+	}
+	return &ServerState{packs: packs, behavior: behavior, resource: resource, takenAt: time.Now()}, nil
+}
+
+// Packs returns the installed packs, as ListInstalledPacks did when the
+// snapshot was taken
+func (st *ServerState) Packs() []InstalledPack {
+	return append([]InstalledPack(nil), st.packs...)
+}
+
+// Pack returns the installed pack with the given UUID, ignoring case
+func (st *ServerState) Pack(packID string) (InstalledPack, bool) {
+	for _, pack := range st.packs {
+		if strings.EqualFold(pack.PackID, packID) {
+			return pack, true
+		}
+	}
+	return InstalledPack{}, false
+}
+
+// Config returns the world config of a pack type
+func (st *ServerState) Config(packType PackType) WorldConfig {
+	if packType == PackTypeResource {
+		return append(WorldConfig(nil), st.resource...)
+	}
+	return append(WorldConfig(nil), st.behavior...)
+}
+
+// TakenAt returns when the snapshot was taken
+func (st *ServerState) TakenAt() time.Time {
+	return st.takenAt
+}
+
+// ServerMutator shares a Server between goroutines. A Server is not safe for
+// concurrent use: its pack index and pack list are caches filled on demand.
+// ServerMutator runs every operation on the Server one at a time, and
+// publishes a ServerState after each one that readers get without waiting
+// for a running operation.
+type ServerMutator struct {
+	mu     sync.Mutex // Held while the server is in use
+	server *Server
+	state  atomic.Pointer[ServerState]
+}
+
+// NewServerMutator takes over server, which must not be used directly
+// afterwards, and takes its first snapshot
+func NewServerMutator(server *Server) (*ServerMutator, error) {
+	m := &ServerMutator{server: server}
+	if _, err := m.Refresh(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// State returns the latest snapshot without waiting for a running operation
+func (m *ServerMutator) State() *ServerState {
+	return m.state.Load()
+}
+
+// Refresh takes a new snapshot, picking up changes made outside the mutator,
+// such as by the Minecraft server or another blockbench process
+func (m *ServerMutator) Refresh() (*ServerState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The pack list cache only notices the world configs and pack
+	// directories changing, not a manifest edited in place
+	m.server.InvalidatePacks()
+	return m.snapshot()
+}
+
+// Do runs fn with the server to itself, then publishes a new snapshot and
+// returns it. The snapshot is taken even when fn fails, since a failed
+// operation may have changed the server part way; fn's error is returned
+// first. fn must not keep the server past its return.
+func (m *ServerMutator) Do(fn func(server *Server) error) (*ServerState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := fn(m.server)
+	// fn may change files itself, which the pack list cache only notices
+	// through modification times
+	m.server.InvalidatePacks()
+	state, snapErr := m.snapshot()
+	if err != nil {
+		return state, err
+	}
+	return state, snapErr
+}
+
+// snapshot takes and publishes a snapshot; m.mu must be held
+func (m *ServerMutator) snapshot() (*ServerState, error) {
+	state, err := m.server.Snapshot()
+	if err != nil {
+		return m.state.Load(), err
+	}
+	m.state.Store(state)
+	return state, nil
+}
+
+// InstallPack installs a pack with Server.InstallPack
+func (m *ServerMutator) InstallPack(manifest *Manifest, packDir string, opts PackInstallOptions) (*ServerState, error) {
+	return m.Do(func(server *Server) error {
+		return server.InstallPack(manifest, packDir, opts)
+	})
+}
+
+// UninstallPack removes a pack with Server.UninstallPack
+func (m *ServerMutator) UninstallPack(packID string) (*ServerState, error) {
+	return m.Do(func(server *Server) error {
+		return server.UninstallPack(packID)
+	})
+}
+
+// MovePack moves a pack in its world config with Server.MovePack
+func (m *ServerMutator) MovePack(packID string, index int) (*ServerState, error) {
+	return m.Do(func(server *Server) error {
+		return server.MovePack(packID, index)
+	})
+}
@@ -0,0 +1,152 @@
+package minecraft
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestServerMutatorConcurrent(t *testing.T) {
+	server := newWorldTestServer(t)
+	mutator, err := NewServerMutator(server)
+	if err != nil {
+		t.Fatalf("NewServerMutator failed: %v", err)
+	}
+	if packs := mutator.State().Packs(); len(packs) != 0 {
+		t.Fatalf("Expected no packs in the first snapshot, got %+v", packs)
+	}
+
+	const installs = 8
+	sourceDir := t.TempDir()
+	manifests := make([]*Manifest, installs)
+	for i := range manifests {
+		uuid := fmt.Sprintf("%08d-1111-1111-1111-111111111111", i)
+		dir := writeIndexedPack(t, sourceDir, fmt.Sprintf("P%d", i), uuid, fmt.Sprintf("Pack %d", i))
+		manifest, err := ParseManifest(filepath.Join(dir, "manifest.json"))
+		if err != nil {
+			t.Fatalf("Failed to parse manifest: %v", err)
+		}
+		manifests[i] = manifest
+	}
+
+	// Writers install at the same time as readers take snapshots; run with
+	// -race to check that nothing touches the server unserialized
+	var wg sync.WaitGroup
+	errs := make(chan error, installs)
+	done := make(chan struct{})
+	for i, manifest := range manifests {
+		wg.Add(1)
+		go func(manifest *Manifest, dir string) {
+			defer wg.Done()
+			if _, err := mutator.InstallPack(manifest, dir, PackInstallOptions{}); err != nil {
+				errs <- err
+			}
+		}(manifest, filepath.Join(sourceDir, fmt.Sprintf("P%d", i)))
+	}
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				state := mutator.State()
+				if packs, config := state.Packs(), state.Config(PackTypeBehavior); len(packs) != len(config) {
+					errs <- fmt.Errorf("snapshot has %d packs and %d config entries", len(packs), len(config))
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	state := mutator.State()
+	if packs := state.Packs(); len(packs) != installs {
+		t.Fatalf("Expected %d packs after the installs, got %d", installs, len(packs))
+	}
+	if pack, ok := state.Pack(manifests[3].Header.UUID); !ok || pack.Name != "Pack 3" || pack.Status != PackStatusOK {
+		t.Errorf("Pack(%s) = %+v, %v", manifests[3].Header.UUID, pack, ok)
+	}
+
+	// Snapshots are never changed by later operations
+	if _, err := mutator.UninstallPack(manifests[0].Header.UUID); err != nil {
+		t.Fatalf("UninstallPack failed: %v", err)
+	}
+	if len(state.Packs()) != installs || len(mutator.State().Packs()) != installs-1 {
+		t.Errorf("Expected the old snapshot to keep %d packs and the new one %d, got %d and %d",
+			installs, installs-1, len(state.Packs()), len(mutator.State().Packs()))
+	}
+}
+
+func TestServerMutatorDo(t *testing.T) {
+	server := newWorldTestServer(t)
+	mutator, err := NewServerMutator(server)
+	if err != nil {
+		t.Fatalf("NewServerMutator failed: %v", err)
+	}
+	const packID = "11111111-1111-1111-1111-111111111111"
+	writeIndexedPack(t, server.Paths.BehaviorPacksDir, "A_11111111", packID, "Pack A")
+
+	// A failed operation still publishes what it changed
+	failure := errors.New("failed part way")
+	state, err := mutator.Do(func(server *Server) error {
+		if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packID, Version: [3]int{1, 0, 0}}}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Do error = %v, want %v", err, failure)
+	}
+	if state == nil || state != mutator.State() {
+		t.Fatal("Expected Do to return the published snapshot")
+	}
+	if _, ok := state.Pack(packID); !ok {
+		t.Error("Expected the snapshot after a failed operation to show its changes")
+	}
+}
+
+func TestServerMutatorRefresh(t *testing.T) {
+	server := newWorldTestServer(t)
+	const packID = "11111111-1111-1111-1111-111111111111"
+	dir := writeIndexedPack(t, server.Paths.BehaviorPacksDir, "A_11111111", packID, "Pack A")
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: packID, Version: [3]int{1, 0, 0}}}); err != nil {
+		t.Fatalf("SaveWorldConfig failed: %v", err)
+	}
+	mutator, err := NewServerMutator(server)
+	if err != nil {
+		t.Fatalf("NewServerMutator failed: %v", err)
+	}
+	if pack, ok := mutator.State().Pack(packID); !ok || pack.Name != "Pack A" {
+		t.Fatalf("Expected Pack A in the first snapshot, got %+v", pack)
+	}
+
+	// Rename the pack and bump its version in place, as an editor would; the
+	// version listed stays the world config's
+	manifest := fmt.Sprintf(`{"format_version": 2, "header": {"name": "Pack B", "uuid": %q, "version": [1, 2, 0]}, "modules": [{"type": "data", "uuid": "99999999-9999-9999-9999-999999999999", "version": [1, 0, 0]}]}`, packID)
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	touch(t, filepath.Join(dir, "manifest.json"), 2)
+
+	state, err := mutator.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if pack, ok := state.Pack(packID); !ok || pack.Name != "Pack B" {
+		t.Errorf("Expected Refresh to pick up the edited manifest's name, got %+v", pack)
+	}
+}
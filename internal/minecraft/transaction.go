@@ -0,0 +1,238 @@
+package minecraft
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// InstallTransaction installs several packs as one change. Each pack's files
+// are written to its directory as the pack is added, where the game ignores
+// them until a world config lists the pack; the world config changes are only
+// staged, and Commit saves them once every pack's files are in place. A
+// failure part way through an addon never leaves some of its packs active.
+//
+// Rollback removes the pack directories the transaction created. Directories
+// that existed before, as with updates, are written over and are not restored;
+// that is what the install backup is for.
+type InstallTransaction struct {
+	server  *Server
+	configs map[string]*stagedConfig // Keyed by world config file
+	order   []string                 // World config files in the order first staged
+	packs   []stagedPack
+	done    bool
+}
+
+// stagedConfig is a world config with the changes of a transaction applied
+type stagedConfig struct {
+	original WorldConfig // As loaded, for restoring a config saved by a failed Commit
+	config   WorldConfig
+}
+
+// stagedPack is a pack whose files a transaction has written
+type stagedPack struct {
+	manifest   *Manifest
+	dir        string
+	configFile string
+	created    bool // The directory did not exist before the transaction
+}
+
+// BeginTransaction starts installing packs as one change; see InstallTransaction
+func (s *Server) BeginTransaction() *InstallTransaction {
+	return &InstallTransaction{server: s, configs: make(map[string]*stagedConfig)}
+}
+
+// InstallPack adds a pack copied from packDir, as Server.InstallPack does
+func (tx *InstallTransaction) InstallPack(manifest *Manifest, packDir string, opts PackInstallOptions) error {
+	return tx.InstallPackFrom(manifest, tx.server.copyPackFiles(manifest, packDir), opts)
+}
+
+// LinkPack adds a pack as a link to sourceDir, as Server.LinkPack does
+func (tx *InstallTransaction) LinkPack(manifest *Manifest, sourceDir string, opts PackInstallOptions) error {
+	writeLink, err := tx.server.linkPackFiles(manifest, sourceDir)
+	if err != nil {
+		return err
+	}
+	return tx.InstallPackFrom(manifest, writeLink, opts)
+}
+
+// InstallPackFrom adds a pack whose directory writeFiles produces, as
+// Server.InstallPackFrom does. Its world config entry is staged first, so an
+// invalid subpack or position fails before any file is written.
+func (tx *InstallTransaction) InstallPackFrom(manifest *Manifest, writeFiles PackWriter, opts PackInstallOptions) error {
+	if tx.done {
+		return errors.New("install transaction already ended")
+	}
+	s := tx.server
+	defer s.InvalidatePacks()
+
+	packDir, configFile, err := s.PackInstallPaths(manifest)
+	if err != nil {
+		return err
+	}
+	if opts.Subpack != "" {
+		if _, ok := manifest.GetSubpack(opts.Subpack); !ok {
+			return fmt.Errorf("pack %s does not declare subpack %q", manifest.GetDisplayName(), opts.Subpack)
+		}
+	}
+
+	staged, err := tx.stagedConfig(configFile)
+	if err != nil {
+		return err
+	}
+	config := AddPackToConfig(append(WorldConfig(nil), staged.config...), manifest.Header.UUID, manifest.Header.Version)
+	if opts.Subpack != "" {
+		config[config.IndexOf(manifest.Header.UUID)].Subpack = opts.Subpack
+	}
+	if !opts.Position.IsDefault() {
+		if config, err = PositionPackInConfig(config, manifest.Header.UUID, opts.Position); err != nil {
+			return fmt.Errorf("failed to position pack %s: %w", manifest.GetDisplayName(), err)
+		}
+	}
+
+	// The version history keeps what an install replaces; it only serves
+	// switching versions, so a failure to keep it is not fatal
+	if err := s.retainInstalledVersion(packDir); err != nil {
+		slog.Warn("Failed to retain the installed pack version", "path", packDir, "error", err)
+	}
+
+	// A linked pack's directory is someone's source, so replacing it drops the
+	// link rather than writing through it
+	created := !s.packDirExists(packDir)
+	tx.packs = append(tx.packs, stagedPack{manifest: manifest, dir: packDir, configFile: configFile, created: created})
+	linked, err := unlinkPack(packDir)
+	if err == nil && opts.Replace && !created && !linked {
+		err = s.fs().RemoveAll(packDir)
+	}
+	if err == nil {
+		err = writeFiles(packDir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy pack files: %w", err)
+	}
+	staged.config = config
+
+	if _, linked := LinkedPackTarget(packDir); linked {
+		s.removeChecksums(manifest.Header.UUID)
+		return nil
+	}
+
+	// The pack is complete either way; deduplication only saves space
+	if s.Store != nil {
+		if _, err := s.Store.Dedupe(packDir); err != nil {
+			slog.Warn("Failed to deduplicate pack files", "path", packDir, "error", err)
+		}
+	}
+	if err := s.Ownership.Apply(packDir); err != nil {
+		return fmt.Errorf("failed to set the owner of %s: %w", packDir, err)
+	}
+
+	// Checksums only serve 'verify', so a failure to record them is not fatal
+	if err := s.recordChecksums(manifest, packDir); err != nil {
+		slog.Warn("Failed to record pack checksums", "uuid", manifest.Header.UUID, "error", err)
+	}
+	return nil
+}
+
+// stagedConfig returns the staged copy of a world config, loading it on first use
+func (tx *InstallTransaction) stagedConfig(configFile string) (*stagedConfig, error) {
+	if staged, ok := tx.configs[configFile]; ok {
+		return staged, nil
+	}
+	config, err := LoadWorldConfigFS(tx.server.FS, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	staged := &stagedConfig{original: config, config: append(WorldConfig(nil), config...)}
+	tx.configs[configFile] = staged
+	tx.order = append(tx.order, configFile)
+	return staged, nil
+}
+
+// Commit saves the staged world configs, activating every added pack, and
+// retains each copied pack in the server's version history. When a config
+// fails to save, the configs already saved are put back as they were and the
+// transaction can still be rolled back.
+func (tx *InstallTransaction) Commit() error {
+	if tx.done {
+		return errors.New("install transaction already ended")
+	}
+	s := tx.server
+	defer s.InvalidatePacks()
+
+	for i, configFile := range tx.order {
+		if err := SaveWorldConfigFS(tx.server.FS, configFile, tx.configs[configFile].config); err != nil {
+			tx.restoreConfigs(tx.order[:i])
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	tx.done = true
+
+	for _, configFile := range tx.order {
+		if err := s.Ownership.Apply(configFile); err != nil {
+			return fmt.Errorf("failed to set the owner of %s: %w", configFile, err)
+		}
+	}
+	for _, pack := range tx.packs {
+		event := "Installed pack"
+		if _, linked := LinkedPackTarget(pack.dir); linked {
+			event = "Linked pack"
+		} else if err := s.retainVersion(pack.manifest, pack.dir); err != nil {
+			slog.Warn("Failed to retain the pack version", "uuid", pack.manifest.Header.UUID, "error", err)
+		}
+		slog.Info(event, "uuid", pack.manifest.Header.UUID, "name", pack.manifest.GetDisplayName(),
+			"version", pack.manifest.GetVersionString(), "path", pack.dir, "config", pack.configFile)
+	}
+	return nil
+}
+
+// restoreConfigs saves world configs back as they were loaded
+func (tx *InstallTransaction) restoreConfigs(configFiles []string) {
+	for _, configFile := range configFiles {
+		if err := SaveWorldConfigFS(tx.server.FS, configFile, tx.configs[configFile].original); err != nil {
+			slog.Error("Failed to rollback config after a failed install; manual cleanup may be required: restore it from the install backup",
+				"config", configFile, "error", err)
+		}
+	}
+}
+
+// Rollback ends the transaction without activating its packs, removing the
+// pack directories it created along with their checksums. After Commit it
+// does nothing.
+func (tx *InstallTransaction) Rollback() error {
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	s := tx.server
+	defer s.InvalidatePacks()
+
+	var errs []error
+	for _, pack := range tx.packs {
+		if !pack.created {
+			continue
+		}
+		if linked, err := unlinkPack(pack.dir); linked || err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.fs().RemoveAll(pack.dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove pack directory %s: %w", pack.dir, err))
+			continue
+		}
+		s.removeChecksums(pack.manifest.Header.UUID)
+	}
+	return errors.Join(errs...)
+}
+
+// packDirExists reports whether anything, including a broken link, is at a
+// pack directory path
+func (s *Server) packDirExists(dir string) bool {
+	if s.FS != nil {
+		_, err := s.FS.Stat(dir)
+		return err == nil
+	}
+	_, err := os.Lstat(dir)
+	return err == nil
+}
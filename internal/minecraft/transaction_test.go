@@ -0,0 +1,111 @@
+package minecraft
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func transactionTestManifests() (*Manifest, *Manifest, *Manifest) {
+	data := []ManifestModule{{Type: "data"}}
+	return &Manifest{Header: ManifestHeader{Name: "First", UUID: "11111111-1111-1111-1111-111111111111", Version: [3]int{1, 0, 0}}, Modules: data},
+		&Manifest{Header: ManifestHeader{Name: "Second", UUID: "22222222-2222-2222-2222-222222222222", Version: [3]int{1, 0, 0}}, Modules: data},
+		&Manifest{Header: ManifestHeader{Name: "Art", UUID: "33333333-3333-3333-3333-333333333333", Version: [3]int{1, 0, 0}}, Modules: []ManifestModule{{Type: "resources"}}}
+}
+
+// writePackFiles is a PackWriter that writes a manifest-less pack
+func writePackFiles(targetDir string) error {
+	if err := os.MkdirAll(targetDir, 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(targetDir, "pack_icon.png"), []byte("png"), 0600)
+}
+
+func TestInstallTransactionCommit(t *testing.T) {
+	server := newWorldTestServer(t)
+	first, second, art := transactionTestManifests()
+
+	tx := server.BeginTransaction()
+	for _, step := range []struct {
+		manifest *Manifest
+		opts     PackInstallOptions
+	}{
+		{first, PackInstallOptions{}},
+		{art, PackInstallOptions{}},
+		{second, PackInstallOptions{Position: PackPosition{Kind: PositionBefore, Anchor: first.Header.UUID}}},
+	} {
+		if err := tx.InstallPackFrom(step.manifest, writePackFiles, step.opts); err != nil {
+			t.Fatalf("InstallPackFrom(%s) failed: %v", step.manifest.Header.Name, err)
+		}
+	}
+
+	// Nothing is active until the commit
+	for _, configFile := range []string{server.Paths.WorldBehaviorPacks, server.Paths.WorldResourcePacks} {
+		if config, _ := LoadWorldConfig(configFile); len(config) != 0 {
+			t.Errorf("Expected %s to be unchanged before the commit, got %+v", filepath.Base(configFile), config)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	behavior, _ := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+	if len(behavior) != 2 || behavior[0].PackID != second.Header.UUID || behavior[1].PackID != first.Header.UUID {
+		t.Errorf("Expected the second pack positioned before the first, got %+v", behavior)
+	}
+	resource, _ := LoadWorldConfig(server.Paths.WorldResourcePacks)
+	if len(resource) != 1 || resource[0].PackID != art.Header.UUID {
+		t.Errorf("Expected the resource pack in its config, got %+v", resource)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Rollback after Commit = %v, want nil", err)
+	}
+	if packDir, _, _ := server.PackInstallPaths(first); !server.packDirExists(packDir) {
+		t.Error("Expected Rollback after Commit to keep the pack directories")
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("Expected a second Commit to fail")
+	}
+}
+
+func TestInstallTransactionRollback(t *testing.T) {
+	server := newWorldTestServer(t)
+	first, second, _ := transactionTestManifests()
+
+	// The second pack is already installed and is being updated
+	existing := writeIndexedPack(t, server.Paths.BehaviorPacksDir, "Second_22222222", second.Header.UUID, "Second")
+	if err := SaveWorldConfig(server.Paths.WorldBehaviorPacks, WorldConfig{{PackID: second.Header.UUID, Version: [3]int{0, 9, 0}}}); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	before, _ := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+
+	tx := server.BeginTransaction()
+	if err := tx.InstallPackFrom(first, writePackFiles, PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPackFrom failed: %v", err)
+	}
+	firstDir, _, _ := server.PackInstallPaths(first)
+	failCopy := func(string) error { return errors.New("disk full") }
+	if err := tx.InstallPackFrom(second, failCopy, PackInstallOptions{}); err == nil {
+		t.Fatal("Expected the failed copy to fail")
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	after, _ := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected the config unchanged, got %+v, want %+v", after, before)
+	}
+	if _, err := os.Stat(firstDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the directory created for the first pack to be removed, got %v", err)
+	}
+	if _, err := os.Stat(existing); err != nil {
+		t.Errorf("Expected the existing directory of the second pack to be kept: %v", err)
+	}
+	if err := tx.InstallPackFrom(first, writePackFiles, PackInstallOptions{}); err == nil {
+		t.Error("Expected InstallPackFrom after Rollback to fail")
+	}
+}
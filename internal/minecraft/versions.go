@@ -0,0 +1,111 @@
+package minecraft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// retainedVersionExt is the extension of retained pack versions, which are
+// .mcpack archives anything that installs packs can read
+const retainedVersionExt = ".mcpack"
+
+// RetainedVersion is a version of a pack kept in the server's version history
+type RetainedVersion struct {
+	PackID     string    `json:"pack_id"`
+	Version    [3]int    `json:"version"`
+	Path       string    `json:"path"`
+	RetainedAt time.Time `json:"retained_at"`
+}
+
+// versionsDir returns where the versions of a pack are retained
+func (s *Server) versionsDir(packID string) string {
+	return filepath.Join(s.Paths.VersionsDir, strings.ToLower(packID))
+}
+
+// retainVersion archives a pack directory into the version history under the
+// version of its manifest, replacing an archive of the same version. The pack
+// is read through the server's FS, but the archive goes to the host's
+// filesystem, where upgrades and downgrades extract it from.
+func (s *Server) retainVersion(manifest *Manifest, packDir string) error {
+	dir := s.versionsDir(manifest.Header.UUID)
+	if err := os.MkdirAll(dir, filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create version history directory: %w", err)
+	}
+	path := filepath.Join(dir, manifest.GetVersionString()+retainedVersionExt)
+	return filesystem.CreateArchiveFS(s.FS, path, []filesystem.ArchiveDir{{Source: packDir}})
+}
+
+// retainInstalledVersion archives the pack installed in packDir before an
+// install replaces it, unless its version is already retained
+func (s *Server) retainInstalledVersion(packDir string) error {
+	if _, linked := LinkedPackTarget(packDir); linked || !s.packDirExists(packDir) {
+		return nil
+	}
+	manifest, err := ParseManifestFS(s.FS, filepath.Join(packDir, "manifest.json"))
+	if err != nil {
+		return nil // Nothing usable to retain
+	}
+	path := filepath.Join(s.versionsDir(manifest.Header.UUID), manifest.GetVersionString()+retainedVersionExt)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return s.retainVersion(manifest, packDir)
+}
+
+// RetainedVersions lists the retained versions of a pack, newest version first
+func (s *Server) RetainedVersions(packID string) ([]RetainedVersion, error) {
+	entries, err := os.ReadDir(s.versionsDir(packID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version history: %w", err)
+	}
+
+	var versions []RetainedVersion
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, retainedVersionExt) {
+			continue
+		}
+		version, err := validation.ParseVersion(strings.TrimSuffix(name, retainedVersionExt))
+		if err != nil {
+			continue // Not an archive blockbench wrote
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, RetainedVersion{
+			PackID:     strings.ToLower(packID),
+			Version:    version,
+			Path:       filepath.Join(s.versionsDir(packID), name),
+			RetainedAt: info.ModTime(),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return validation.CompareVersions(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions, nil
+}
+
+// FindRetainedVersion returns a retained version of a pack
+func (s *Server) FindRetainedVersion(packID string, version [3]int) (*RetainedVersion, error) {
+	versions, err := s.RetainedVersions(packID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Version == version {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("version %d.%d.%d of pack %s is not retained", version[0], version[1], version[2], packID)
+}
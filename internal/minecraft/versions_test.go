@@ -0,0 +1,88 @@
+package minecraft
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/pkg/filesystem"
+)
+
+// versionWriter is a PackWriter that writes a pack's manifest and the given extra files
+func versionWriter(manifest *Manifest, extra ...string) PackWriter {
+	return func(targetDir string) error {
+		if err := os.MkdirAll(targetDir, 0750); err != nil {
+			return err
+		}
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(targetDir, "manifest.json"), data, 0600); err != nil {
+			return err
+		}
+		for _, name := range extra {
+			if err := os.WriteFile(filepath.Join(targetDir, name), []byte(name), 0600); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestRetainedVersions(t *testing.T) {
+	server := newWorldTestServer(t)
+	first, _, _ := transactionTestManifests()
+	older := *first
+	newer := *first
+	newer.Header.Version = [3]int{1, 1, 0}
+
+	if err := server.InstallPackFrom(&older, versionWriter(&older), PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPackFrom(1.0.0) failed: %v", err)
+	}
+	if err := server.InstallPackFrom(&newer, versionWriter(&newer, "new.js"), PackInstallOptions{}); err != nil {
+		t.Fatalf("InstallPackFrom(1.1.0) failed: %v", err)
+	}
+
+	retained, err := server.RetainedVersions(first.Header.UUID)
+	if err != nil {
+		t.Fatalf("RetainedVersions failed: %v", err)
+	}
+	if len(retained) != 2 || retained[0].Version != newer.Header.Version || retained[1].Version != older.Header.Version {
+		t.Fatalf("Expected 1.1.0 and 1.0.0 retained newest first, got %+v", retained)
+	}
+	if _, err := server.FindRetainedVersion(first.Header.UUID, [3]int{0, 9, 0}); err == nil {
+		t.Error("Expected FindRetainedVersion to fail for a version never installed")
+	}
+
+	// The retained archive is the pack as it was installed
+	found, err := server.FindRetainedVersion(first.Header.UUID, older.Header.Version)
+	if err != nil {
+		t.Fatalf("FindRetainedVersion failed: %v", err)
+	}
+	extracted := t.TempDir()
+	if err := filesystem.ExtractArchive(found.Path, extracted); err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	manifest, err := ParseManifest(filepath.Join(extracted, "manifest.json"))
+	if err != nil || manifest.Header.Version != older.Header.Version {
+		t.Fatalf("Expected the retained 1.0.0 archive to hold its manifest, got %+v, %v", manifest, err)
+	}
+	if _, err := os.Stat(filepath.Join(extracted, "new.js")); !os.IsNotExist(err) {
+		t.Error("Expected the retained 1.0.0 archive without the file 1.1.0 added")
+	}
+
+	// Replacing leaves none of the newer version's files behind
+	packDir, _, _ := server.PackInstallPaths(first)
+	if err := server.InstallPackFrom(&older, versionWriter(&older), PackInstallOptions{Replace: true}); err != nil {
+		t.Fatalf("InstallPackFrom with Replace failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(packDir, "new.js")); !os.IsNotExist(err) {
+		t.Error("Expected Replace to remove the files of the version it replaced")
+	}
+	config, _ := LoadWorldConfig(server.Paths.WorldBehaviorPacks)
+	if len(config) != 1 || config[0].Version != older.Header.Version {
+		t.Errorf("Expected the world config entry at 1.0.0, got %+v", config)
+	}
+}
@@ -0,0 +1,151 @@
+// Package prompt asks the user questions. Code that asks goes through a
+// Prompter, so the answers can come from the terminal, from a script or test,
+// or from the global --yes flag.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoInput is returned when the input ends before a question is answered
+var ErrNoInput = errors.New("end of input")
+
+// Prompter asks the user questions
+type Prompter interface {
+	// Confirm asks a yes/no question; only y or yes, in any case, is a yes
+	Confirm(question string) (bool, error)
+	// Ask asks for a line of text and returns it trimmed
+	Ask(question string) (string, error)
+}
+
+// Terminal asks questions on an output and reads the answers, one per line,
+// from an input. Every question shares one buffered reader, so answers piped
+// in ahead of the questions are not lost.
+type Terminal struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewTerminal returns a Terminal reading answers from in and writing
+// questions to out
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{in: bufio.NewReader(in), out: out}
+}
+
+var (
+	stdinOnce     sync.Once
+	stdinTerminal *Terminal
+)
+
+// Stdin returns the Terminal on the process's standard input and output
+func Stdin() *Terminal {
+	stdinOnce.Do(func() {
+		stdinTerminal = NewTerminal(os.Stdin, os.Stdout)
+	})
+	return stdinTerminal
+}
+
+// Confirm asks a yes/no question. At the end of input, "n" is echoed and
+// ErrNoInput returned.
+func (t *Terminal) Confirm(question string) (bool, error) {
+	fmt.Fprintf(t.out, "%s (y/N): ", question)
+	answer, err := t.readLine()
+	if err != nil {
+		if errors.Is(err, ErrNoInput) {
+			fmt.Fprintln(t.out, "n")
+		}
+		return false, err
+	}
+	return isYes(answer), nil
+}
+
+// Ask asks for a line of text. At the end of input, ErrNoInput is returned.
+func (t *Terminal) Ask(question string) (string, error) {
+	fmt.Fprint(t.out, question)
+	answer, err := t.readLine()
+	if errors.Is(err, ErrNoInput) {
+		fmt.Fprintln(t.out)
+	}
+	return answer, err
+}
+
+// readLine reads a trimmed line; a last line without a newline still counts
+func (t *Terminal) readLine() (string, error) {
+	line, err := t.in.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", ErrNoInput
+		}
+		return strings.TrimSpace(line), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read user input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Scripted answers questions from a list, in order, for tests and scripted
+// runs. The questions asked are recorded.
+type Scripted struct {
+	Answers   []string
+	Questions []string
+}
+
+// Confirm returns whether the next answer is a yes, or ErrNoInput when the
+// answers have run out
+func (s *Scripted) Confirm(question string) (bool, error) {
+	answer, err := s.Ask(question)
+	if err != nil {
+		return false, err
+	}
+	return isYes(answer), nil
+}
+
+// Ask returns the next answer, trimmed, or ErrNoInput when the answers have
+// run out
+func (s *Scripted) Ask(question string) (string, error) {
+	s.Questions = append(s.Questions, question)
+	if len(s.Answers) == 0 {
+		return "", ErrNoInput
+	}
+	answer := s.Answers[0]
+	s.Answers = s.Answers[1:]
+	return strings.TrimSpace(answer), nil
+}
+
+// assumeYes confirms everything and leaves other questions to a Prompter
+type assumeYes struct {
+	Prompter
+	out io.Writer
+}
+
+// AssumeYes returns a Prompter that answers yes to every confirmation,
+// echoing the question and answer to out, and asks p everything else
+func AssumeYes(p Prompter, out io.Writer) Prompter {
+	return &assumeYes{Prompter: p, out: out}
+}
+
+// Confirm echoes the question and answers yes
+func (a *assumeYes) Confirm(question string) (bool, error) {
+	fmt.Fprintf(a.out, "%s (y/N): y\n", question)
+	return true, nil
+}
+
+// IsTerminal reports whether f is a character device such as a terminal,
+// rather than a pipe or file
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// isYes reports whether an answer is a yes
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
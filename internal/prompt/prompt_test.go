@@ -0,0 +1,100 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	var out bytes.Buffer
+	terminal := NewTerminal(strings.NewReader("y\nYES\nno\n 2 1 \nlast"), &out)
+
+	// Answers piped in ahead of the questions are read one per question
+	for i, want := range []bool{true, true, false} {
+		got, err := terminal.Confirm("Proceed?")
+		if err != nil || got != want {
+			t.Errorf("Confirm #%d = %v, %v, want %v", i, got, err, want)
+		}
+	}
+	if answer, err := terminal.Ask("Order: "); err != nil || answer != "2 1" {
+		t.Errorf("Ask = %q, %v, want the trimmed line", answer, err)
+	}
+	if answer, err := terminal.Ask("Name: "); err != nil || answer != "last" {
+		t.Errorf("Ask = %q, %v, want the last line without a newline", answer, err)
+	}
+
+	got, err := terminal.Confirm("Finish?")
+	if got || !errors.Is(err, ErrNoInput) {
+		t.Errorf("Confirm at end of input = %v, %v, want ErrNoInput", got, err)
+	}
+	if !strings.HasSuffix(out.String(), "Finish? (y/N): n\n") {
+		t.Errorf("Expected the end of input to be echoed as n, got %q", out.String())
+	}
+	if !strings.HasPrefix(out.String(), "Proceed? (y/N): ") {
+		t.Errorf("Expected questions on the output, got %q", out.String())
+	}
+}
+
+func TestScripted(t *testing.T) {
+	scripted := &Scripted{Answers: []string{"y", "n", " 3 "}}
+
+	if yes, err := scripted.Confirm("First?"); err != nil || !yes {
+		t.Errorf("Confirm = %v, %v, want yes", yes, err)
+	}
+	if yes, err := scripted.Confirm("Second?"); err != nil || yes {
+		t.Errorf("Confirm = %v, %v, want no", yes, err)
+	}
+	if answer, err := scripted.Ask("Number: "); err != nil || answer != "3" {
+		t.Errorf("Ask = %q, %v", answer, err)
+	}
+	if _, err := scripted.Confirm("Third?"); !errors.Is(err, ErrNoInput) {
+		t.Errorf("Expected ErrNoInput once the answers run out, got %v", err)
+	}
+	if len(scripted.Questions) != 4 || scripted.Questions[0] != "First?" {
+		t.Errorf("Unexpected questions recorded: %q", scripted.Questions)
+	}
+}
+
+func TestAssumeYes(t *testing.T) {
+	var out bytes.Buffer
+	scripted := &Scripted{Answers: []string{"1 2"}}
+	prompter := AssumeYes(scripted, &out)
+
+	if yes, err := prompter.Confirm("Restore?"); err != nil || !yes {
+		t.Errorf("Confirm = %v, %v, want yes", yes, err)
+	}
+	if out.String() != "Restore? (y/N): y\n" {
+		t.Errorf("Expected the question and answer echoed, got %q", out.String())
+	}
+	if answer, err := prompter.Ask("Order: "); err != nil || answer != "1 2" {
+		t.Errorf("Expected other questions to be asked, got %q, %v", answer, err)
+	}
+	if len(scripted.Questions) != 1 {
+		t.Errorf("Expected only the question to be asked, got %q", scripted.Questions)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if IsTerminal(file) {
+		t.Error("Expected a regular file not to be a terminal")
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	if IsTerminal(reader) {
+		t.Error("Expected a pipe not to be a terminal")
+	}
+}
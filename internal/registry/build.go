@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// FilesPath is the URL path prefix Build gives addon files, relative to the
+// index
+const FilesPath = "files/"
+
+// Pack is a pack read from an addon file when building an index
+type Pack struct {
+	UUID        string
+	Name        string
+	Description string
+	Version     [3]int
+	Type        minecraft.PackType
+}
+
+// Scanner reads the packs of an addon file
+type Scanner func(path string) ([]Pack, error)
+
+// CachedScanner wraps scan so a file is only read again once its size or
+// modification time changes, for rebuilding an index repeatedly
+func CachedScanner(scan Scanner) Scanner {
+	type scanned struct {
+		size    int64
+		modTime time.Time
+		packs   []Pack
+	}
+	var mu sync.Mutex
+	cache := make(map[string]scanned)
+	return func(path string) ([]Pack, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		cached, ok := cache[path]
+		mu.Unlock()
+		if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+			return cached.packs, nil
+		}
+
+		packs, err := scan(path)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		cache[path] = scanned{size: info.Size(), modTime: info.ModTime(), packs: packs}
+		mu.Unlock()
+		return packs, nil
+	}
+}
+
+// Build scans a directory for .mcaddon and .mcpack files and returns an index
+// listing each file as a version of an addon. An addon is identified by its
+// main pack, the first behavior pack or else the first pack, and named after
+// it; the version is the main pack's. Version URLs are FilesPath followed by
+// the file's path relative to dir.
+func Build(dir string, scan Scanner) (*Index, error) {
+	byUUID := make(map[string]*Addon)
+	var order []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".mcaddon" && ext != ".mcpack" {
+			return nil
+		}
+
+		packs, err := scan(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		main, ok := mainPack(packs)
+		if !ok {
+			return fmt.Errorf("failed to read %s: no packs found", path)
+		}
+		if !validation.ValidateUUID(main.UUID) {
+			return fmt.Errorf("failed to read %s: pack %s has UUID %q", path, main.Name, main.UUID)
+		}
+		digest, err := filesystem.HashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		uuid := strings.ToLower(main.UUID)
+		addon, exists := byUUID[uuid]
+		if !exists {
+			addon = &Addon{UUID: main.UUID, Name: addonNameFor(main.Name), Description: main.Description}
+			byUUID[uuid] = addon
+			order = append(order, uuid)
+		}
+		for _, version := range addon.Versions {
+			if version.Version == main.Version {
+				slog.Warn("Skipping duplicate addon version", "file", path, "addon", addon.Name, "version", main.Version)
+				return nil
+			}
+		}
+		addon.Versions = append(addon.Versions, Version{
+			Version: main.Version,
+			URL:     FilesPath + filepath.ToSlash(rel),
+			SHA256:  digest,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	index := &Index{Addons: make([]Addon, 0, len(order))}
+	names := make(map[string]bool)
+	for _, uuid := range order {
+		addon := *byUUID[uuid]
+		// Different addons whose packs share a name get the UUID's first
+		// block appended, so every name resolves to one addon
+		if names[strings.ToLower(addon.Name)] {
+			addon.Name += "-" + strings.ToLower(addon.UUID[:8])
+		}
+		names[strings.ToLower(addon.Name)] = true
+		sort.SliceStable(addon.Versions, func(i, j int) bool {
+			return validation.CompareVersions(addon.Versions[i].Version, addon.Versions[j].Version) > 0
+		})
+		index.Addons = append(index.Addons, addon)
+	}
+	sort.SliceStable(index.Addons, func(i, j int) bool {
+		return index.Addons[i].Name < index.Addons[j].Name
+	})
+	return index, nil
+}
+
+// mainPack returns the pack that identifies an addon: its first behavior
+// pack, or else its first pack
+func mainPack(packs []Pack) (Pack, bool) {
+	for _, pack := range packs {
+		if pack.Type == minecraft.PackTypeBehavior {
+			return pack, true
+		}
+	}
+	if len(packs) == 0 {
+		return Pack{}, false
+	}
+	return packs[0], true
+}
+
+// addonNameFor turns a pack's display name into an addon name: lower case,
+// without § formatting codes, and with runs of other characters than letters
+// and digits replaced by dashes
+func addonNameFor(displayName string) string {
+	var b strings.Builder
+	dash := false
+	formatCode := false
+	for _, r := range strings.ToLower(displayName) {
+		if formatCode {
+			formatCode = false
+			continue
+		}
+		if r == '§' {
+			formatCode = true
+			continue
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "addon"
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+const (
+	barUUID = "22222222-2222-2222-2222-222222222222"
+	rpUUID  = "33333333-3333-3333-3333-333333333333"
+)
+
+// writeRepository creates addon files whose contents name the packs the
+// returned scanner reports for them
+func writeRepository(t *testing.T, files map[string][]Pack) (string, Scanner) {
+	t.Helper()
+	dir := t.TempDir()
+	for name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, func(path string) ([]Pack, error) {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		packs, ok := files[filepath.ToSlash(rel)]
+		if !ok {
+			return nil, fmt.Errorf("unexpected file %s", rel)
+		}
+		return packs, nil
+	}
+}
+
+func TestBuild(t *testing.T) {
+	rp := Pack{UUID: rpUUID, Name: "Mobs RP", Version: [3]int{1, 0, 0}, Type: minecraft.PackTypeResource}
+	dir, scan := writeRepository(t, map[string][]Pack{
+		"mobs-1.0.0.mcaddon":     {rp, {UUID: fooUUID, Name: "§aHostile Mobs!", Description: "Adds mobs", Version: [3]int{1, 0, 0}, Type: minecraft.PackTypeBehavior}},
+		"new/mobs-1.2.0.mcaddon": {rp, {UUID: fooUUID, Name: "Hostile Mobs", Version: [3]int{1, 2, 0}, Type: minecraft.PackTypeBehavior}},
+		"mobs-copy.mcaddon":      {{UUID: fooUUID, Name: "Hostile Mobs", Version: [3]int{1, 2, 0}, Type: minecraft.PackTypeBehavior}},
+		"other.mcpack":           {{UUID: barUUID, Name: "Hostile  mobs", Version: [3]int{0, 1, 0}, Type: minecraft.PackTypeResource}},
+		"readme.txt":             nil,
+	})
+
+	index, err := Build(dir, scan)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(index.Addons) != 2 {
+		t.Fatalf("Expected 2 addons, got %+v", index.Addons)
+	}
+
+	mobs := index.Addons[0]
+	if mobs.Name != "hostile-mobs" || mobs.UUID != fooUUID || mobs.Description != "Adds mobs" {
+		t.Errorf("Expected the addon to be named after its behavior pack, got %+v", mobs)
+	}
+	if len(mobs.Versions) != 2 {
+		t.Fatalf("Expected the duplicate version to be skipped, got %+v", mobs.Versions)
+	}
+	if mobs.Versions[0].Version != [3]int{1, 2, 0} || mobs.Versions[1].URL != "files/mobs-1.0.0.mcaddon" || mobs.Versions[0].SHA256 == "" {
+		t.Errorf("Unexpected versions %+v", mobs.Versions)
+	}
+
+	if other := index.Addons[1]; other.Name != "hostile-mobs-22222222" {
+		t.Errorf("Expected a clashing name to get the UUID appended, got %s", other.Name)
+	}
+}
+
+func TestAddonNameFor(t *testing.T) {
+	for displayName, want := range map[string]string{
+		"Hostile Mobs":       "hostile-mobs",
+		"§l§6Gold§r Tools 2": "gold-tools-2",
+		"  --Café--  ":       "caf",
+		"§§":                 "addon",
+	} {
+		if got := addonNameFor(displayName); got != want {
+			t.Errorf("addonNameFor(%q) = %q, want %q", displayName, got, want)
+		}
+	}
+}
+
+func TestCachedScanner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.mcaddon")
+	if err := os.WriteFile(path, []byte("v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	scan := CachedScanner(func(string) ([]Pack, error) {
+		calls++
+		return []Pack{{UUID: fooUUID}}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := scan(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected an unchanged file to be read once, got %d reads", calls)
+	}
+
+	if err := os.WriteFile(path, []byte("version 2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scan(path); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected a changed file to be read again, got %d reads", calls)
+	}
+}
@@ -0,0 +1,202 @@
+// Package registry resolves addons by name through registries: indexes
+// listing each addon's versions with their download URLs and checksums.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/updates"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// Version is one release of a registry addon
+type Version struct {
+	Version [3]int `json:"version"`
+	URL     string `json:"url"` // A URL or a path relative to the index
+	SHA256  string `json:"sha256"`
+}
+
+// Addon is a named addon in a registry. UUID is the header UUID of its main
+// pack.
+type Addon struct {
+	Name        string    `json:"name"`
+	UUID        string    `json:"uuid"`
+	Description string    `json:"description,omitempty"`
+	Versions    []Version `json:"versions"`
+}
+
+// Index is the document a registry serves
+type Index struct {
+	Addons []Addon `json:"addons"`
+}
+
+// Entry is an addon found in a registry
+type Entry struct {
+	Addon
+	Registry string `json:"registry"` // The configured registry that lists it
+}
+
+// addonName restricts addon names so a name@version reference can't be
+// mistaken for a path
+var addonName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Load reads a registry index from an http(s) URL or a local file, resolving
+// each version's URL against it
+func Load(registry string) ([]Entry, error) {
+	data, err := updates.ReadIndex(registry)
+	if err != nil {
+		return nil, err
+	}
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid registry index %s: %w", registry, err)
+	}
+
+	entries := make([]Entry, 0, len(index.Addons))
+	for _, addon := range index.Addons {
+		if !addonName.MatchString(addon.Name) {
+			return nil, fmt.Errorf("invalid registry index %s: invalid addon name %q", registry, addon.Name)
+		}
+		if !validation.ValidateUUID(addon.UUID) {
+			return nil, fmt.Errorf("invalid registry index %s: addon %s has UUID %q", registry, addon.Name, addon.UUID)
+		}
+		for i, version := range addon.Versions {
+			if version.URL == "" || version.SHA256 == "" {
+				return nil, fmt.Errorf("invalid registry index %s: addon %s version %d needs a url and a sha256", registry, addon.Name, i+1)
+			}
+			resolved, err := updates.ResolveRef(registry, version.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registry index %s: addon %s url %q: %w", registry, addon.Name, version.URL, err)
+			}
+			addon.Versions[i].URL = resolved
+		}
+		sort.SliceStable(addon.Versions, func(i, j int) bool {
+			return validation.CompareVersions(addon.Versions[i].Version, addon.Versions[j].Version) > 0
+		})
+		entries = append(entries, Entry{Addon: addon, Registry: registry})
+	}
+	return entries, nil
+}
+
+// LoadAll reads several registries, in order
+func LoadAll(registries []string) ([]Entry, error) {
+	var entries []Entry
+	for _, registry := range registries {
+		loaded, err := Load(registry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, loaded...)
+	}
+	return entries, nil
+}
+
+// Latest returns the addon's newest version; Load sorts versions newest first
+func (a Addon) Latest() (Version, bool) {
+	if len(a.Versions) == 0 {
+		return Version{}, false
+	}
+	return a.Versions[0], true
+}
+
+// Search returns the entries whose name, description, or UUID contains term,
+// ignoring case, sorted by name
+func Search(entries []Entry, term string) []Entry {
+	term = strings.ToLower(term)
+	var found []Entry
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Name), term) ||
+			strings.Contains(strings.ToLower(entry.Description), term) ||
+			strings.Contains(strings.ToLower(entry.UUID), term) {
+			found = append(found, entry)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return strings.ToLower(found[i].Name) < strings.ToLower(found[j].Name)
+	})
+	return found
+}
+
+// Ref is a name@version reference to a registry addon
+type Ref struct {
+	Name    string
+	Version *[3]int // Nil means the newest version
+}
+
+// ParseRef parses name, name@latest, or name@1.2.3
+func ParseRef(ref string) (Ref, error) {
+	name, version, hasVersion := strings.Cut(ref, "@")
+	if !addonName.MatchString(name) {
+		return Ref{}, fmt.Errorf("invalid addon name %q", name)
+	}
+	if !hasVersion || version == "latest" {
+		return Ref{Name: name}, nil
+	}
+	parsed, err := validation.ParseVersion(version)
+	if err != nil {
+		return Ref{}, err
+	}
+	return Ref{Name: name, Version: &parsed}, nil
+}
+
+// IsRef reports whether an install argument names a registry addon rather
+// than a file: a valid reference without path separators or an addon file
+// extension
+func IsRef(arg string) bool {
+	if strings.ContainsAny(arg, `/\`) {
+		return false
+	}
+	lower := strings.ToLower(arg)
+	for _, ext := range []string{".mcaddon", ".mcpack", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return false
+		}
+	}
+	_, err := ParseRef(arg)
+	return err == nil
+}
+
+// Resolve finds the addon a reference names and the version to install. The
+// first registry listing the name wins, so registries are in priority order.
+func Resolve(entries []Entry, ref Ref) (Entry, Version, error) {
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.Name, ref.Name) {
+			continue
+		}
+		if ref.Version == nil {
+			latest, ok := entry.Latest()
+			if !ok {
+				return Entry{}, Version{}, fmt.Errorf("addon %s in %s has no versions", entry.Name, entry.Registry)
+			}
+			return entry, latest, nil
+		}
+		available := make([]string, 0, len(entry.Versions))
+		for _, version := range entry.Versions {
+			if version.Version == *ref.Version {
+				return entry, version, nil
+			}
+			available = append(available, formatVersion(version.Version))
+		}
+		return Entry{}, Version{}, fmt.Errorf("addon %s has no version %s in %s (available: %s)",
+			entry.Name, formatVersion(*ref.Version), entry.Registry, strings.Join(available, ", "))
+	}
+	return Entry{}, Version{}, fmt.Errorf("no addon named %s in the configured registries", ref.Name)
+}
+
+// Fetch returns a local file with the version's contents, verified against
+// its SHA256; URLs are downloaded into cacheDir
+func Fetch(version Version, cacheDir string) (string, error) {
+	// Version URLs are absolute, so a lockfile without a path resolves them
+	// as they are
+	var lock lockfile.Lockfile
+	return lock.Fetch(lockfile.Addon{Source: version.URL, SHA256: version.SHA256}, cacheDir)
+}
+
+func formatVersion(version [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const fooUUID = "11111111-1111-1111-1111-111111111111"
+
+var index = fmt.Sprintf(`{"addons": [
+	{"name": "foo-mobs", "uuid": %q, "description": "Adds hostile mobs", "versions": [
+		{"version": [1, 0, 0], "url": "foo-1.0.0.mcaddon", "sha256": "aaa"},
+		{"version": [1, 2, 0], "url": "https://cdn.example.com/foo-1.2.0.mcaddon", "sha256": "bbb"}
+	]}
+]}`, fooUUID)
+
+func TestLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, index)
+	}))
+	defer server.Close()
+
+	entries, err := Load(server.URL + "/registry/index.json")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Registry != server.URL+"/registry/index.json" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	latest, ok := entries[0].Latest()
+	if !ok || latest.Version != [3]int{1, 2, 0} {
+		t.Errorf("Expected versions sorted newest first, got %+v", entries[0].Versions)
+	}
+	if got := entries[0].Versions[1].URL; got != server.URL+"/registry/foo-1.0.0.mcaddon" {
+		t.Errorf("Expected the relative URL to resolve against the index, got %s", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := os.WriteFile(path, []byte(index), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := entries[0].Versions[1].URL; got != filepath.Join(filepath.Dir(path), "foo-1.0.0.mcaddon") {
+		t.Errorf("Expected the relative URL to resolve against the index file, got %s", got)
+	}
+
+	for _, invalid := range []string{
+		`{"addons": [{"name": "../foo", "uuid": "` + fooUUID + `"}]}`,
+		`{"addons": [{"name": "foo", "uuid": "nope"}]}`,
+		`{"addons": [{"name": "foo", "uuid": "` + fooUUID + `", "versions": [{"version": [1, 0, 0], "url": "foo.mcaddon"}]}]}`,
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		version *[3]int
+		wantErr bool
+	}{
+		{"foo-mobs", nil, false},
+		{"foo-mobs@latest", nil, false},
+		{"foo-mobs@1.2.0", &[3]int{1, 2, 0}, false},
+		{"foo-mobs@1.2", nil, true},
+		{"foo-mobs@1.2.0-beta", nil, true},
+		{"@1.2.0", nil, true},
+	}
+	for _, tt := range tests {
+		ref, err := ParseRef(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRef(%q) error = %v", tt.ref, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (ref.Version == nil) != (tt.version == nil) || (ref.Version != nil && *ref.Version != *tt.version) {
+			t.Errorf("ParseRef(%q) version = %v, want %v", tt.ref, ref.Version, tt.version)
+		}
+	}
+
+	for arg, want := range map[string]bool{
+		"foo-mobs":       true,
+		"foo-mobs@1.0.0": true,
+		"foo.mcaddon":    false,
+		"./foo":          false,
+		"addons/foo":     false,
+	} {
+		if got := IsRef(arg); got != want {
+			t.Errorf("IsRef(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
+
+func TestSearchAndResolve(t *testing.T) {
+	entries := []Entry{
+		{Addon: Addon{Name: "foo-mobs", UUID: fooUUID, Description: "Adds hostile mobs", Versions: []Version{
+			{Version: [3]int{1, 2, 0}, URL: "https://a.example.com/foo-1.2.0.mcaddon"},
+			{Version: [3]int{1, 0, 0}, URL: "https://a.example.com/foo-1.0.0.mcaddon"},
+		}}, Registry: "a"},
+		{Addon: Addon{Name: "bar", Description: "Furniture", Versions: []Version{{Version: [3]int{2, 0, 0}}}}, Registry: "a"},
+		{Addon: Addon{Name: "foo-mobs", Versions: []Version{{Version: [3]int{9, 0, 0}}}}, Registry: "b"},
+	}
+
+	if found := Search(entries, "MOBS"); len(found) != 2 {
+		t.Errorf("Expected both foo-mobs entries, got %+v", found)
+	}
+	if found := Search(entries, "furniture"); len(found) != 1 || found[0].Name != "bar" {
+		t.Errorf("Expected the description to match, got %+v", found)
+	}
+
+	entry, version, err := Resolve(entries, Ref{Name: "FOO-MOBS"})
+	if err != nil || entry.Registry != "a" || version.Version != [3]int{1, 2, 0} {
+		t.Errorf("Expected the first registry's newest version, got %+v %+v %v", entry, version, err)
+	}
+	_, version, err = Resolve(entries, Ref{Name: "foo-mobs", Version: &[3]int{1, 0, 0}})
+	if err != nil || version.URL != "https://a.example.com/foo-1.0.0.mcaddon" {
+		t.Errorf("Expected version 1.0.0, got %+v %v", version, err)
+	}
+	if _, _, err := Resolve(entries, Ref{Name: "foo-mobs", Version: &[3]int{3, 0, 0}}); err == nil {
+		t.Error("Expected a missing version to fail")
+	}
+	if _, _, err := Resolve(entries, Ref{Name: "baz"}); err == nil {
+		t.Error("Expected a missing addon to fail")
+	}
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IndexPath is the URL path a Handler serves the index at
+const IndexPath = "/index.json"
+
+// Handler serves a registry built from a directory: the index at IndexPath
+// and the addon files it lists below FilesPath. Files the index doesn't list
+// are not served.
+type Handler struct {
+	dir string
+
+	mu    sync.RWMutex
+	index []byte
+	files map[string]string // URL path to file path
+}
+
+// NewHandler returns a handler serving index for the directory it was built from
+func NewHandler(dir string, index *Index) (*Handler, error) {
+	h := &Handler{dir: dir}
+	if err := h.SetIndex(index); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetIndex replaces the served index, such as after rebuilding it when the
+// directory changed
+func (h *Handler) SetIndex(index *Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := make(map[string]string)
+	for _, addon := range index.Addons {
+		for _, version := range addon.Versions {
+			if rel, ok := strings.CutPrefix(version.URL, FilesPath); ok {
+				files["/"+version.URL] = filepath.Join(h.dir, filepath.FromSlash(rel))
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.index = data
+	h.files = files
+	return nil
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.RLock()
+	index := h.index
+	path, listed := h.files[r.URL.Path]
+	h.mu.RUnlock()
+
+	if r.URL.Path == IndexPath {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(index)
+		return
+	}
+	if !listed {
+		http.NotFound(w, r)
+		return
+	}
+
+	// #nosec G304 - path is an addon file listed in the index
+	file, err := os.Open(path)
+	if err != nil {
+		slog.Warn("Failed to open addon file", "path", path, "error", err)
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "failed to read addon file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+}
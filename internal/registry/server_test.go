@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "foo.mcaddon"), []byte("addon"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	index := &Index{Addons: []Addon{{Name: "foo", UUID: fooUUID, Versions: []Version{
+		{Version: [3]int{1, 0, 0}, URL: FilesPath + "foo.mcaddon", SHA256: "abc"},
+	}}}}
+
+	handler, err := NewHandler(dir, index)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get(IndexPath)
+	var served Index
+	if status != http.StatusOK || json.Unmarshal([]byte(body), &served) != nil || len(served.Addons) != 1 {
+		t.Fatalf("Expected the index, got %d %s", status, body)
+	}
+	if status, body := get("/files/foo.mcaddon"); status != http.StatusOK || body != "addon" {
+		t.Errorf("Expected the addon file, got %d %s", status, body)
+	}
+	for _, path := range []string{"/files/secret.txt", "/files/../secret.txt", "/secret.txt"} {
+		if status, _ := get(path); status != http.StatusNotFound {
+			t.Errorf("Expected %s to be hidden, got %d", path, status)
+		}
+	}
+
+	// The registry client resolves the served index's relative URLs
+	entries, err := Load(server.URL + IndexPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := entries[0].Versions[0].URL; got != server.URL+"/files/foo.mcaddon" {
+		t.Errorf("Expected the file URL to resolve against the server, got %s", got)
+	}
+
+	if err := handler.SetIndex(&Index{}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := get("/files/foo.mcaddon"); status != http.StatusNotFound {
+		t.Errorf("Expected files dropped from the index to be hidden, got %d", status)
+	}
+}
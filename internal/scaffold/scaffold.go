@@ -0,0 +1,337 @@
+// Package scaffold generates the skeleton of a new behavior pack, resource
+// pack, or addon for addon developers.
+package scaffold
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/internal/version"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// Kind is what to generate
+type Kind string
+
+const (
+	KindBehaviorPack Kind = "behavior-pack"
+	KindResourcePack Kind = "resource-pack"
+	KindAddon        Kind = "addon" // A behavior pack depending on a resource pack
+)
+
+// Kinds lists the kinds that can be generated
+var Kinds = []Kind{KindBehaviorPack, KindResourcePack, KindAddon}
+
+const (
+	// DefaultScriptAPIVersion is the @minecraft/server version script modules
+	// depend on
+	DefaultScriptAPIVersion = "1.11.0"
+
+	// iconSize is the width and height of the placeholder pack icon
+	iconSize = 256
+)
+
+// DefaultMinEngineVersion is the min_engine_version of generated manifests
+var DefaultMinEngineVersion = [3]int{1, 21, 0}
+
+// Options controls what is generated
+type Options struct {
+	Kind             Kind
+	Name             string // Display name of the packs
+	Description      string
+	Authors          []string
+	MinEngineVersion [3]int // Zero uses DefaultMinEngineVersion
+	Script           bool   // Add a script module to the behavior pack
+	ScriptAPIVersion string // Empty uses DefaultScriptAPIVersion
+}
+
+// Pack is a generated pack
+type Pack struct {
+	Type minecraft.PackType `json:"type"`
+	UUID string             `json:"uuid"`
+	Dir  string             `json:"dir"`
+}
+
+// Result lists what was generated
+type Result struct {
+	Dir   string   `json:"dir"`
+	Packs []Pack   `json:"packs"`
+	Files []string `json:"files"` // Relative to Dir
+}
+
+// Generate writes a skeleton into dir, which must not exist or be empty. A
+// pack is written to dir itself; an addon gets behavior_pack and
+// resource_pack subdirectories. With Script, the behavior pack declares a
+// script module running scripts/main.js, and dir gets a package.json for the
+// @minecraft/server typings.
+func Generate(dir string, options Options) (*Result, error) {
+	if !validKind(options.Kind) {
+		return nil, fmt.Errorf("unknown kind %q: use %s", options.Kind, kindList())
+	}
+	if strings.TrimSpace(options.Name) == "" {
+		return nil, fmt.Errorf("a name is required")
+	}
+	if options.Script && options.Kind == KindResourcePack {
+		return nil, fmt.Errorf("resource packs cannot have a script module")
+	}
+	if options.MinEngineVersion == [3]int{} {
+		options.MinEngineVersion = DefaultMinEngineVersion
+	}
+	if options.ScriptAPIVersion == "" {
+		options.ScriptAPIVersion = DefaultScriptAPIVersion
+	}
+	if err := checkEmpty(dir); err != nil {
+		return nil, err
+	}
+
+	g := &generator{dir: dir, options: options, result: &Result{Dir: dir}}
+	var err error
+	switch options.Kind {
+	case KindBehaviorPack:
+		_, err = g.pack(".", minecraft.PackTypeBehavior, nil)
+	case KindResourcePack:
+		_, err = g.pack(".", minecraft.PackTypeResource, nil)
+	case KindAddon:
+		var rp Pack
+		rp, err = g.pack("resource_pack", minecraft.PackTypeResource, nil)
+		if err == nil {
+			_, err = g.pack("behavior_pack", minecraft.PackTypeBehavior, &rp)
+		}
+	}
+	if err == nil && options.Script {
+		err = g.packageJSON()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.result, nil
+}
+
+// generator writes the files of one skeleton
+type generator struct {
+	dir     string
+	options Options
+	result  *Result
+}
+
+// pack writes a pack into the subdirectory rel of the skeleton, depending
+// on dependency when it is given
+func (g *generator) pack(rel string, packType minecraft.PackType, dependency *Pack) (Pack, error) {
+	headerUUID, err := validation.NewUUID()
+	if err != nil {
+		return Pack{}, err
+	}
+	moduleUUID, err := validation.NewUUID()
+	if err != nil {
+		return Pack{}, err
+	}
+
+	name := g.options.Name
+	moduleType := "resources"
+	if packType == minecraft.PackTypeBehavior {
+		moduleType = "data"
+	}
+	if g.options.Kind == KindAddon {
+		// Both packs show up in the game's pack lists, so tell them apart
+		if packType == minecraft.PackTypeBehavior {
+			name += " BP"
+		} else {
+			name += " RP"
+		}
+	}
+
+	manifest := minecraft.Manifest{
+		FormatVersion: 2,
+		Header: minecraft.ManifestHeader{
+			Name:        name,
+			Description: g.options.Description,
+			UUID:        headerUUID,
+			Version:     [3]int{1, 0, 0},
+			MinVersion:  g.options.MinEngineVersion,
+		},
+		Modules: []minecraft.ManifestModule{{Type: moduleType, UUID: moduleUUID, Version: [3]int{1, 0, 0}}},
+		Metadata: &minecraft.ManifestMetadata{
+			Authors:       g.options.Authors,
+			GeneratedWith: map[string][]string{"blockbench": {version.Version}},
+		},
+	}
+	if dependency != nil {
+		manifest.Dependencies = append(manifest.Dependencies, packDependency(dependency.UUID, [3]int{1, 0, 0}))
+	}
+	if g.options.Script && packType == minecraft.PackTypeBehavior {
+		scriptUUID, err := validation.NewUUID()
+		if err != nil {
+			return Pack{}, err
+		}
+		manifest.Modules = append(manifest.Modules, minecraft.ManifestModule{
+			Type:     "script",
+			UUID:     scriptUUID,
+			Version:  [3]int{1, 0, 0},
+			Language: "javascript",
+			Entry:    "scripts/main.js",
+		})
+		manifest.Dependencies = append(manifest.Dependencies, moduleDependency("@minecraft/server", g.options.ScriptAPIVersion))
+		if err := g.write(filepath.Join(rel, "scripts", "main.js"), []byte(mainScript(g.options.Name))); err != nil {
+			return Pack{}, err
+		}
+	}
+	if err := minecraft.ValidateManifest(&manifest); err != nil {
+		return Pack{}, fmt.Errorf("generated an invalid manifest: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Pack{}, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := g.write(filepath.Join(rel, "manifest.json"), append(data, '\n')); err != nil {
+		return Pack{}, err
+	}
+	icon, err := packIcon(packType)
+	if err != nil {
+		return Pack{}, err
+	}
+	if err := g.write(filepath.Join(rel, "pack_icon.png"), icon); err != nil {
+		return Pack{}, err
+	}
+
+	pack := Pack{Type: packType, UUID: headerUUID, Dir: filepath.Join(g.dir, rel)}
+	g.result.Packs = append(g.result.Packs, pack)
+	return pack, nil
+}
+
+// packageJSON writes the npm package that provides the script API typings
+func (g *generator) packageJSON() error {
+	pkg := map[string]any{
+		"name":         packageName(g.options.Name),
+		"version":      "1.0.0",
+		"private":      true,
+		"type":         "module",
+		"dependencies": map[string]string{"@minecraft/server": g.options.ScriptAPIVersion},
+	}
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode package.json: %w", err)
+	}
+	return g.write("package.json", append(data, '\n'))
+}
+
+// write creates a file of the skeleton
+func (g *generator) write(rel string, data []byte) error {
+	path := filepath.Join(g.dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), filesystem.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, filesystem.DefaultFilePerm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	g.result.Files = append(g.result.Files, filepath.ToSlash(filepath.Clean(rel)))
+	return nil
+}
+
+// packDependency is a manifest dependency on another pack
+func packDependency(uuid string, version [3]int) minecraft.ManifestDependency {
+	raw, _ := json.Marshal(version)
+	return minecraft.ManifestDependency{UUID: uuid, Version: version, RawVersion: raw}
+}
+
+// moduleDependency is a manifest dependency on an engine module
+func moduleDependency(name, version string) minecraft.ManifestDependency {
+	raw, _ := json.Marshal(version)
+	return minecraft.ManifestDependency{ModuleName: name, ModuleVersion: version, RawVersion: raw}
+}
+
+// packIcon returns a placeholder icon: a square of solid color, green for
+// behavior packs and blue for resource packs
+func packIcon(packType minecraft.PackType) ([]byte, error) {
+	fill := color.RGBA{R: 0x3b, G: 0x8e, B: 0x3f, A: 0xff}
+	if packType == minecraft.PackTypeResource {
+		fill = color.RGBA{R: 0x2f, G: 0x6f, B: 0xb8, A: 0xff}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, iconSize, iconSize), color.Palette{fill})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode pack icon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mainScript is the entry script of a generated script module
+func mainScript(name string) string {
+	greeting, _ := json.Marshal("Hello from " + name + "!")
+	return `import { world } from "@minecraft/server";
+
+world.afterEvents.playerSpawn.subscribe((event) => {
+  if (event.initialSpawn) {
+    event.player.sendMessage(` + string(greeting) + `);
+  }
+});
+`
+}
+
+// packageName turns a display name into an npm package name
+func packageName(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if b.Len() == 0 {
+		return "addon"
+	}
+	return b.String()
+}
+
+// DirName returns the directory a skeleton named name goes in by default
+func DirName(name string) string {
+	return packageName(name)
+}
+
+// checkEmpty fails unless dir is missing or an empty directory
+func checkEmpty(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot use %s: %w", dir, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", dir)
+	}
+	return nil
+}
+
+func validKind(kind Kind) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func kindList() string {
+	names := make([]string, len(Kinds))
+	for i, kind := range Kinds {
+		names[i] = string(kind)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,112 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+func TestGeneratePack(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mobs")
+	result, err := Generate(dir, Options{Kind: KindResourcePack, Name: "Mobs", Description: "Mob textures", Authors: []string{"Alex"}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(result.Packs) != 1 || result.Packs[0].Dir != dir {
+		t.Fatalf("Expected one pack in the directory itself, got %+v", result.Packs)
+	}
+
+	manifest, err := minecraft.ParseManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if err := minecraft.ValidateManifest(manifest); err != nil {
+		t.Errorf("Generated manifest is invalid: %v", err)
+	}
+	if manifest.GetPackType() != minecraft.PackTypeResource || manifest.Header.UUID != result.Packs[0].UUID {
+		t.Errorf("Unexpected manifest %+v", manifest.Header)
+	}
+	if manifest.Header.MinVersion != DefaultMinEngineVersion || manifest.Metadata.Authors[0] != "Alex" {
+		t.Errorf("Expected the defaults and authors, got %+v %+v", manifest.Header, manifest.Metadata)
+	}
+
+	icon, err := os.Open(filepath.Join(dir, "pack_icon.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer icon.Close()
+	if _, err := png.Decode(icon); err != nil {
+		t.Errorf("Expected a valid pack icon: %v", err)
+	}
+
+	if _, err := Generate(dir, Options{Kind: KindResourcePack, Name: "Mobs"}); err == nil {
+		t.Error("Expected a non-empty directory to be rejected")
+	}
+	if _, err := Generate(t.TempDir(), Options{Kind: KindResourcePack, Name: "Mobs", Script: true}); err == nil {
+		t.Error("Expected a resource pack script module to be rejected")
+	}
+	if _, err := Generate(t.TempDir(), Options{Kind: "skin-pack", Name: "Mobs"}); err == nil {
+		t.Error("Expected an unknown kind to be rejected")
+	}
+}
+
+func TestGenerateAddonWithScript(t *testing.T) {
+	dir := t.TempDir()
+	result, err := Generate(dir, Options{Kind: KindAddon, Name: `Say "Hi"`, Script: true})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(result.Packs) != 2 {
+		t.Fatalf("Expected two packs, got %+v", result.Packs)
+	}
+	rp, bp := result.Packs[0], result.Packs[1]
+
+	manifest, err := minecraft.ParseManifest(filepath.Join(dir, "behavior_pack", "manifest.json"))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if err := minecraft.ValidateManifest(manifest); err != nil {
+		t.Errorf("Generated manifest is invalid: %v", err)
+	}
+	if manifest.Header.UUID != bp.UUID || manifest.Header.Name != `Say "Hi" BP` || !manifest.HasScriptModule() {
+		t.Errorf("Unexpected behavior pack manifest %+v", manifest)
+	}
+	var dependsOnRP, dependsOnAPI bool
+	for _, dep := range manifest.Dependencies {
+		dependsOnRP = dependsOnRP || (dep.UUID == rp.UUID && dep.Version == [3]int{1, 0, 0})
+		dependsOnAPI = dependsOnAPI || (dep.ModuleName == "@minecraft/server" && dep.ModuleVersion == DefaultScriptAPIVersion)
+	}
+	if !dependsOnRP || !dependsOnAPI {
+		t.Errorf("Expected dependencies on the resource pack and the script API, got %+v", manifest.Dependencies)
+	}
+	for _, module := range manifest.Modules {
+		if module.Type == "script" && (module.Entry != "scripts/main.js" || module.Language != "javascript") {
+			t.Errorf("Unexpected script module %+v", module)
+		}
+	}
+
+	script, err := os.ReadFile(filepath.Join(dir, "behavior_pack", "scripts", "main.js"))
+	if err != nil || !strings.Contains(string(script), `"Hello from Say \"Hi\"!"`) {
+		t.Errorf("Expected the entry script to greet with an escaped name, got %s, %v", script, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkg struct {
+		Name         string            `json:"name"`
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Name != "say-hi" || pkg.Dependencies["@minecraft/server"] != DefaultScriptAPIVersion {
+		t.Errorf("Unexpected package.json %+v", pkg)
+	}
+}
@@ -0,0 +1,200 @@
+// Package signature verifies detached minisign (Ed25519) signatures of addon
+// files against trusted public keys.
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Signature algorithms: legacy signatures sign the file itself, prehashed
+// ones (the minisign default) sign its BLAKE2b-512 digest
+const (
+	algorithmLegacy    = "Ed"
+	algorithmPrehashed = "ED"
+)
+
+// signatureExtensions are the detached signature files looked for next to an
+// addon, in order
+var signatureExtensions = []string{".sig", ".minisig"}
+
+// PublicKey is a trusted minisign public key
+type PublicKey struct {
+	Name string // Label given when the key was trusted; may be empty
+	ID   [8]byte
+	Key  ed25519.PublicKey
+}
+
+// KeyID formats the key ID the way minisign prints it
+func (k PublicKey) KeyID() string {
+	return formatKeyID(k.ID)
+}
+
+func formatKeyID(id [8]byte) string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+// ParsePublicKey reads a minisign public key, either the contents of a .pub
+// file or just its base64 line
+func ParsePublicKey(text string) (PublicKey, error) {
+	var encoded string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			encoded = line
+			break
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize {
+		return PublicKey{}, fmt.Errorf("not a minisign public key")
+	}
+	if string(data[:2]) != algorithmLegacy {
+		return PublicKey{}, fmt.Errorf("unsupported public key algorithm %q", data[:2])
+	}
+
+	var key PublicKey
+	copy(key.ID[:], data[2:10])
+	key.Key = ed25519.PublicKey(append([]byte(nil), data[10:]...))
+	return key, nil
+}
+
+// Verified describes a file whose signature checked out
+type Verified struct {
+	SignatureFile  string `json:"signature_file"`
+	KeyID          string `json:"key_id"`
+	KeyName        string `json:"key_name,omitempty"`
+	TrustedComment string `json:"trusted_comment"`
+}
+
+// Signer names the key that made the signature: its trusted name and key ID
+func (v *Verified) Signer() string {
+	if v.KeyName != "" {
+		return fmt.Sprintf("%s (%s)", v.KeyName, v.KeyID)
+	}
+	return v.KeyID
+}
+
+// signature is a parsed minisign signature file
+type signature struct {
+	algorithm      string
+	keyID          [8]byte
+	sig            []byte
+	trustedComment string
+	globalSig      []byte
+}
+
+// parseSignature reads a minisign signature file
+func parseSignature(data []byte) (*signature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "untrusted comment:") {
+		return nil, fmt.Errorf("not a minisign signature")
+	}
+
+	sigData, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sigData) != 2+8+ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed signature line")
+	}
+	trustedComment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return nil, fmt.Errorf("missing trusted comment")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed global signature line")
+	}
+
+	sig := &signature{
+		algorithm:      string(sigData[:2]),
+		sig:            sigData[10:],
+		trustedComment: trustedComment,
+		globalSig:      globalSig,
+	}
+	copy(sig.keyID[:], sigData[2:10])
+	if sig.algorithm != algorithmLegacy && sig.algorithm != algorithmPrehashed {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", sig.algorithm)
+	}
+	return sig, nil
+}
+
+// FindSignatureFile returns the detached signature next to path (path.sig or
+// path.minisig), or an empty string if there is none
+func FindSignatureFile(path string) string {
+	for _, ext := range signatureExtensions {
+		if info, err := os.Stat(path + ext); err == nil && !info.IsDir() {
+			return path + ext
+		}
+	}
+	return ""
+}
+
+// VerifyFile checks the detached signature of path, in sigPath, against the
+// trusted keys. The signature must be made by one of them and cover both the
+// file and its trusted comment.
+func VerifyFile(path, sigPath string, keys []PublicKey) (*Verified, error) {
+	// #nosec G304 - sigPath is the signature next to the addon being installed
+	sigData, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	sig, err := parseSignature(sigData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s: %w", sigPath, err)
+	}
+
+	var key *PublicKey
+	for i := range keys {
+		if keys[i].ID == sig.keyID {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s was signed with key %s, which is not trusted", path, formatKeyID(sig.keyID))
+	}
+
+	message, err := signedMessage(path, sig.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(key.Key, message, sig.sig) {
+		return nil, fmt.Errorf("signature of %s does not match its contents", path)
+	}
+	if !ed25519.Verify(key.Key, append(append([]byte(nil), sig.sig...), sig.trustedComment...), sig.globalSig) {
+		return nil, fmt.Errorf("trusted comment of %s was altered", sigPath)
+	}
+
+	return &Verified{SignatureFile: sigPath, KeyID: key.KeyID(), KeyName: key.Name, TrustedComment: sig.trustedComment}, nil
+}
+
+// signedMessage returns what a signature of the given algorithm signs: the
+// file itself, or its BLAKE2b-512 digest
+func signedMessage(path, algorithm string) ([]byte, error) {
+	// #nosec G304 - path is the addon being installed
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if algorithm == algorithmLegacy {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, file); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	hash := newBlake2b()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hash.Sum(), nil
+}
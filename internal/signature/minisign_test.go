@@ -0,0 +1,154 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlake2b(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{"abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	}
+	for _, tt := range tests {
+		hash := newBlake2b()
+		hash.Write([]byte(tt.input))
+		if got := hex.EncodeToString(hash.Sum()); got != tt.expected {
+			t.Errorf("blake2b(%q) = %s, expected %s", tt.input, got, tt.expected)
+		}
+	}
+
+	// Writes split across block boundaries hash like a single write
+	data := []byte(strings.Repeat("blockbench", 100))
+	whole := newBlake2b()
+	whole.Write(data)
+	pieces := newBlake2b()
+	for _, n := range []int{1, 127, 128, 256, 300} {
+		pieces.Write(data[:n])
+		data = data[n:]
+	}
+	pieces.Write(data)
+	if hex.EncodeToString(whole.Sum()) != hex.EncodeToString(pieces.Sum()) {
+		t.Error("Expected the same digest for split writes")
+	}
+}
+
+// testKey generates a key pair and returns it as a trusted PublicKey together
+// with a function that writes a minisign signature of a file
+func testKey(t *testing.T, name string) (PublicKey, func(path, algorithm, comment string)) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		t.Fatalf("Failed to generate key ID: %v", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(algorithmLegacy), id[:]...), pub...))
+	key, err := ParsePublicKey("untrusted comment: minisign public key\n" + encoded + "\n")
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+	key.Name = name
+
+	sign := func(path, algorithm, comment string) {
+		message, err := signedMessage(path, algorithm)
+		if err != nil {
+			t.Fatalf("signedMessage failed: %v", err)
+		}
+		sig := ed25519.Sign(priv, message)
+		global := ed25519.Sign(priv, append(append([]byte(nil), sig...), comment...))
+		content := "untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), id[:]...), sig...)) + "\n" +
+			"trusted comment: " + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n"
+		if err := os.WriteFile(path+".minisig", []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write signature: %v", err)
+		}
+	}
+	return key, sign
+}
+
+func TestVerifyFile(t *testing.T) {
+	tempDir := t.TempDir()
+	addon := filepath.Join(tempDir, "pack.mcaddon")
+	if err := os.WriteFile(addon, []byte(strings.Repeat("addon contents ", 50)), 0600); err != nil {
+		t.Fatalf("Failed to write addon: %v", err)
+	}
+
+	if got := FindSignatureFile(addon); got != "" {
+		t.Errorf("Expected no signature file, got %s", got)
+	}
+
+	key, sign := testKey(t, "publisher")
+	other, _ := testKey(t, "other")
+
+	for _, algorithm := range []string{algorithmPrehashed, algorithmLegacy} {
+		sign(addon, algorithm, "timestamp:1700000000\tfile:pack.mcaddon")
+		sigPath := FindSignatureFile(addon)
+		if sigPath != addon+".minisig" {
+			t.Fatalf("Expected the .minisig file, got %q", sigPath)
+		}
+
+		verified, err := VerifyFile(addon, sigPath, []PublicKey{other, key})
+		if err != nil {
+			t.Fatalf("VerifyFile (%s) failed: %v", algorithm, err)
+		}
+		if verified.KeyID != key.KeyID() || verified.KeyName != "publisher" {
+			t.Errorf("Expected signer %s (publisher), got %s (%s)", key.KeyID(), verified.KeyID, verified.KeyName)
+		}
+		if verified.TrustedComment != "timestamp:1700000000\tfile:pack.mcaddon" {
+			t.Errorf("Unexpected trusted comment %q", verified.TrustedComment)
+		}
+
+		if _, err := VerifyFile(addon, sigPath, []PublicKey{other}); err == nil || !strings.Contains(err.Error(), "not trusted") {
+			t.Errorf("Expected an untrusted key error, got %v", err)
+		}
+	}
+
+	// An altered trusted comment fails the global signature
+	data, _ := os.ReadFile(addon + ".minisig")
+	altered := strings.Replace(string(data), "timestamp:1700000000", "timestamp:1800000000", 1)
+	os.WriteFile(addon+".minisig", []byte(altered), 0600)
+	if _, err := VerifyFile(addon, addon+".minisig", []PublicKey{key}); err == nil || !strings.Contains(err.Error(), "trusted comment") {
+		t.Errorf("Expected a trusted comment error, got %v", err)
+	}
+
+	// A modified addon no longer matches
+	sign(addon, algorithmPrehashed, "comment")
+	os.WriteFile(addon, []byte("tampered"), 0600)
+	if _, err := VerifyFile(addon, addon+".minisig", []PublicKey{key}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a mismatch error, got %v", err)
+	}
+
+	os.WriteFile(addon+".minisig", []byte("not a signature"), 0600)
+	if _, err := VerifyFile(addon, addon+".minisig", []PublicKey{key}); err == nil {
+		t.Error("Expected an error for a malformed signature")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	if _, err := ParsePublicKey("not a key"); err == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+
+	// minisign's own example key
+	key, err := ParsePublicKey("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+	if key.KeyID() != "E7620F1842B4E81F" {
+		t.Errorf("Expected key ID E7620F1842B4E81F, got %s", key.KeyID())
+	}
+}
@@ -0,0 +1,292 @@
+// Package updates finds newer versions of installed packs in addon sources:
+// source indexes served over HTTP or stored on disk, and repository
+// directories of addon files.
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/minecraft"
+	"github.com/makutaku/blockbench/pkg/filesystem"
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+// IndexFileName is the index a repository directory can hold instead of
+// being scanned for addon files
+const IndexFileName = "blockbench-index.json"
+
+const (
+	// indexTimeout bounds fetching an index
+	indexTimeout = 30 * time.Second
+
+	// maxIndexSize bounds the size of an index
+	maxIndexSize = 16 * 1024 * 1024
+)
+
+// Index lists the addons a source offers, in the lockfile's addon format.
+// Each Source is a URL or a path relative to the index.
+type Index struct {
+	Addons []lockfile.Addon `json:"addons"`
+}
+
+// Candidate is an addon offered by a source. Its Source is an absolute path
+// or URL.
+type Candidate struct {
+	lockfile.Addon
+	Origin string `json:"origin"` // The configured source that offers it
+}
+
+// Scanner reads the packs of an addon file in a repository directory
+type Scanner func(path string) ([]lockfile.Pack, error)
+
+// ValidateSource checks that a source is an http(s) URL or an existing file
+// or directory, returning it with local paths made absolute
+func ValidateSource(source string) (string, error) {
+	if lockfile.IsURL(source) {
+		parsed, err := url.Parse(source)
+		if err != nil || parsed.Host == "" {
+			return "", fmt.Errorf("invalid source URL %q", source)
+		}
+		return source, nil
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid source path %q: %w", source, err)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("cannot read source %s: %w", source, err)
+	}
+	return abs, nil
+}
+
+// Load reads the addons a source offers. A URL or a file is a source index;
+// a directory is read through its index file when it has one and is
+// otherwise scanned for .mcaddon and .mcpack files with scan.
+func Load(source string, scan Scanner) ([]Candidate, error) {
+	if lockfile.IsURL(source) {
+		data, err := ReadIndex(source)
+		if err != nil {
+			return nil, err
+		}
+		return parseIndex(data, source, func(ref string) (string, error) {
+			return ResolveRef(source, ref)
+		})
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read source %s: %w", source, err)
+	}
+	indexPath := source
+	if info.IsDir() {
+		indexPath = filepath.Join(source, IndexFileName)
+		if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+			return scanDirectory(source, scan)
+		}
+	}
+
+	data, err := ReadIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndex(data, source, func(ref string) (string, error) {
+		return ResolveRef(indexPath, ref)
+	})
+}
+
+// ReadIndex reads an index from an http(s) URL or a local file
+func ReadIndex(source string) ([]byte, error) {
+	if lockfile.IsURL(source) {
+		return fetchIndex(source)
+	}
+	// #nosec G304 - source is an index the user configured
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	return data, nil
+}
+
+// ResolveRef resolves a URL or path found in an index against the index's
+// own URL or path: relative references are relative to the index
+func ResolveRef(index, ref string) (string, error) {
+	if lockfile.IsURL(index) {
+		base, err := url.Parse(index)
+		if err != nil {
+			return "", err
+		}
+		resolved, err := base.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return resolved.String(), nil
+	}
+	if lockfile.IsURL(ref) || filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	return filepath.Join(filepath.Dir(index), filepath.FromSlash(ref)), nil
+}
+
+// parseIndex decodes a source index, resolving each addon's source
+func parseIndex(data []byte, origin string, resolve func(string) (string, error)) ([]Candidate, error) {
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid source index %s: %w", origin, err)
+	}
+
+	candidates := make([]Candidate, 0, len(index.Addons))
+	for i, addon := range index.Addons {
+		if addon.Source == "" || addon.SHA256 == "" {
+			return nil, fmt.Errorf("invalid source index %s: addon %d needs a source and a sha256", origin, i+1)
+		}
+		for _, pack := range addon.Packs {
+			if !validation.ValidateUUID(pack.UUID) {
+				return nil, fmt.Errorf("invalid source index %s: addon %s has pack UUID %q", origin, addon.Source, pack.UUID)
+			}
+		}
+		resolved, err := resolve(addon.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source index %s: addon source %q: %w", origin, addon.Source, err)
+		}
+		addon.Source = resolved
+		candidates = append(candidates, Candidate{Addon: addon, Origin: origin})
+	}
+	return candidates, nil
+}
+
+// scanDirectory offers every addon file below a repository directory
+func scanDirectory(dir string, scan Scanner) ([]Candidate, error) {
+	var candidates []Candidate
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".mcaddon" && ext != ".mcpack" {
+			return nil
+		}
+
+		packs, err := scan(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		digest, err := filesystem.HashFile(path)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, Candidate{
+			Addon:  lockfile.Addon{Source: path, SHA256: digest, Packs: packs},
+			Origin: dir,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source %s: %w", dir, err)
+	}
+	return candidates, nil
+}
+
+// fetchIndex downloads an index
+func fetchIndex(source string) ([]byte, error) {
+	client := &http.Client{Timeout: indexTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch index %s: %s", source, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIndexSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index %s: %w", source, err)
+	}
+	if len(data) > maxIndexSize {
+		return nil, fmt.Errorf("index %s is larger than %d bytes", source, maxIndexSize)
+	}
+	return data, nil
+}
+
+// Update is a newer version of an installed pack offered by a source
+type Update struct {
+	UUID      string             `json:"uuid"`
+	Name      string             `json:"name"`
+	Type      minecraft.PackType `json:"type"`
+	Installed [3]int             `json:"installed"`
+	Available [3]int             `json:"available"`
+	Addon     Candidate          `json:"addon"` // The addon that provides the newer version
+}
+
+// Find returns an update for each installed pack that a candidate offers in
+// a newer version, using the newest version offered, sorted by pack name
+func Find(installed []minecraft.InstalledPack, candidates []Candidate) []Update {
+	var updates []Update
+	for _, pack := range installed {
+		var best *Update
+		for _, candidate := range candidates {
+			for _, offered := range candidate.Packs {
+				if !strings.EqualFold(offered.UUID, pack.PackID) {
+					continue
+				}
+				if validation.CompareVersions(offered.Version, pack.Version) <= 0 {
+					continue
+				}
+				if best != nil && validation.CompareVersions(offered.Version, best.Available) <= 0 {
+					continue
+				}
+				best = &Update{
+					UUID:      pack.PackID,
+					Name:      pack.Name,
+					Type:      pack.Type,
+					Installed: pack.Version,
+					Available: offered.Version,
+					Addon:     candidate,
+				}
+			}
+		}
+		if best != nil {
+			updates = append(updates, *best)
+		}
+	}
+	sort.SliceStable(updates, func(i, j int) bool {
+		return strings.ToLower(updates[i].Name) < strings.ToLower(updates[j].Name)
+	})
+	return updates
+}
+
+// Addons returns the distinct addons that provide the updates, in order, so
+// an addon updating several packs is installed once
+func Addons(updates []Update) []Candidate {
+	seen := make(map[string]bool)
+	var addons []Candidate
+	for _, update := range updates {
+		if seen[update.Addon.Source] {
+			continue
+		}
+		seen[update.Addon.Source] = true
+		addons = append(addons, update.Addon)
+	}
+	return addons
+}
+
+// Fetch returns a local file with the addon's contents, verified against its
+// SHA256; URL sources are downloaded into cacheDir
+func Fetch(candidate Candidate, cacheDir string) (string, error) {
+	// Candidate sources are absolute, so a lockfile without a path resolves
+	// them as they are
+	var lock lockfile.Lockfile
+	return lock.Fetch(candidate.Addon, cacheDir)
+}
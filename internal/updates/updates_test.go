@@ -0,0 +1,134 @@
+package updates
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makutaku/blockbench/internal/lockfile"
+	"github.com/makutaku/blockbench/internal/minecraft"
+)
+
+const (
+	fooUUID = "11111111-1111-1111-1111-111111111111"
+	barUUID = "22222222-2222-2222-2222-222222222222"
+)
+
+func TestLoadIndex(t *testing.T) {
+	index := fmt.Sprintf(`{"addons": [
+		{"source": "addons/foo-1.2.0.mcaddon", "sha256": "abc", "packs": [{"uuid": %q, "name": "Foo", "version": [1, 2, 0], "type": "behavior"}]},
+		{"source": "https://cdn.example.com/bar.mcpack", "sha256": "def", "packs": [{"uuid": %q, "name": "Bar", "version": [2, 0, 0], "type": "resource"}]}
+	]}`, fooUUID, barUUID)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repo/index.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, index)
+	}))
+	defer server.Close()
+
+	candidates, err := Load(server.URL+"/repo/index.json", nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %d", len(candidates))
+	}
+	if got := candidates[0].Source; got != server.URL+"/repo/addons/foo-1.2.0.mcaddon" {
+		t.Errorf("Expected the relative source to resolve against the index URL, got %s", got)
+	}
+	if got := candidates[1].Source; got != "https://cdn.example.com/bar.mcpack" {
+		t.Errorf("Expected the absolute source to be kept, got %s", got)
+	}
+
+	if _, err := Load(server.URL+"/missing.json", nil); err == nil {
+		t.Error("Expected a missing index to fail")
+	}
+
+	// A repository directory with an index file resolves sources against it
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, IndexFileName), []byte(index), 0600); err != nil {
+		t.Fatal(err)
+	}
+	candidates, err = Load(dir, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := candidates[0].Source; got != filepath.Join(dir, "addons", "foo-1.2.0.mcaddon") {
+		t.Errorf("Expected the source to resolve against the index directory, got %s", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, IndexFileName), []byte(`{"addons": [{"source": "foo.mcaddon"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, nil); err == nil {
+		t.Error("Expected an addon without a sha256 to fail")
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"foo.mcaddon", "nested/bar.mcpack", "readme.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var scanned []string
+	scan := func(path string) ([]lockfile.Pack, error) {
+		scanned = append(scanned, filepath.Base(path))
+		return []lockfile.Pack{{UUID: fooUUID, Name: filepath.Base(path), Version: [3]int{1, 0, 0}}}, nil
+	}
+	candidates, err := Load(dir, scan)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(candidates) != 2 || len(scanned) != 2 {
+		t.Fatalf("Expected both addon files to be scanned, got %v", scanned)
+	}
+	for _, candidate := range candidates {
+		if candidate.SHA256 == "" || candidate.Origin != dir || !filepath.IsAbs(candidate.Source) {
+			t.Errorf("Unexpected candidate %+v", candidate)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	installed := []minecraft.InstalledPack{
+		{PackID: fooUUID, Name: "Foo", Version: [3]int{1, 0, 0}, Type: minecraft.PackTypeBehavior},
+		{PackID: barUUID, Name: "Bar", Version: [3]int{2, 0, 0}, Type: minecraft.PackTypeResource},
+	}
+	candidates := []Candidate{
+		{Addon: lockfile.Addon{Source: "/repo/foo-1.1.0.mcaddon", Packs: []lockfile.Pack{{UUID: fooUUID, Version: [3]int{1, 1, 0}}}}},
+		{Addon: lockfile.Addon{Source: "/repo/foo-1.2.0.mcaddon", Packs: []lockfile.Pack{
+			{UUID: fooUUID, Version: [3]int{1, 2, 0}},
+			{UUID: barUUID, Version: [3]int{2, 0, 0}}, // Same version: no update
+		}}},
+		{Addon: lockfile.Addon{Source: "/repo/bar-1.0.0.mcpack", Packs: []lockfile.Pack{{UUID: barUUID, Version: [3]int{1, 0, 0}}}}},
+	}
+
+	found := Find(installed, candidates)
+	if len(found) != 1 {
+		t.Fatalf("Expected one update, got %+v", found)
+	}
+	if found[0].UUID != fooUUID || found[0].Available != [3]int{1, 2, 0} || found[0].Addon.Source != "/repo/foo-1.2.0.mcaddon" {
+		t.Errorf("Expected Foo to update to 1.2.0, got %+v", found[0])
+	}
+
+	// Two packs updated by one addon install it once
+	installed[1].Version = [3]int{1, 5, 0}
+	candidates[1].Packs[1].Version = [3]int{2, 1, 0}
+	found = Find(installed, candidates)
+	if len(found) != 2 || len(Addons(found)) != 1 {
+		t.Errorf("Expected two updates from one addon, got %+v", found)
+	}
+}
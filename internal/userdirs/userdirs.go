@@ -0,0 +1,104 @@
+// Package userdirs resolves where blockbench keeps its own files that do not
+// belong to a single server: configuration, caches, and state. It follows the
+// XDG base directory specification on Linux and other Unix systems, and the
+// platform conventions on macOS and Windows.
+package userdirs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appName is the directory blockbench's files are grouped under
+const appName = "blockbench"
+
+// Dirs are blockbench's per-user directories. They are not created until a
+// subsystem writes to them.
+type Dirs struct {
+	Config string `json:"config"` // Settings and profiles the user edits
+	Cache  string `json:"cache"`  // Data that can be deleted and rebuilt at any time
+	State  string `json:"state"`  // Data that should survive restarts but is not configuration
+}
+
+// Resolve returns the directories for the current user and platform.
+// BLOCKBENCH_CONFIG_DIR, BLOCKBENCH_CACHE_DIR, and BLOCKBENCH_STATE_DIR
+// override each directory; otherwise they are:
+//
+//	Linux/Unix  $XDG_CONFIG_HOME/blockbench  (~/.config/blockbench)
+//	            $XDG_CACHE_HOME/blockbench   (~/.cache/blockbench)
+//	            $XDG_STATE_HOME/blockbench   (~/.local/state/blockbench)
+//	macOS       ~/Library/Application Support/blockbench
+//	            ~/Library/Caches/blockbench
+//	            ~/Library/Application Support/blockbench/state
+//	Windows     %AppData%\blockbench
+//	            %LocalAppData%\blockbench\cache
+//	            %LocalAppData%\blockbench\state
+//
+// XDG variables are honoured on macOS too when they are set.
+func Resolve() (Dirs, error) {
+	home, _ := os.UserHomeDir() // Only needed when no variable covers a directory
+	return resolve(runtime.GOOS, os.Getenv, home)
+}
+
+// resolve is Resolve for a given platform, environment, and home directory
+func resolve(goos string, getenv func(string) string, home string) (Dirs, error) {
+	var dirs Dirs
+	for _, dir := range []struct {
+		target   *string
+		override string
+		xdg      string
+		fallback func() string
+	}{
+		{&dirs.Config, "BLOCKBENCH_CONFIG_DIR", "XDG_CONFIG_HOME", func() string {
+			switch goos {
+			case "windows":
+				return join(getenv("AppData"), appName)
+			case "darwin":
+				return join(home, "Library", "Application Support", appName)
+			}
+			return join(home, ".config", appName)
+		}},
+		{&dirs.Cache, "BLOCKBENCH_CACHE_DIR", "XDG_CACHE_HOME", func() string {
+			switch goos {
+			case "windows":
+				return join(getenv("LocalAppData"), appName, "cache")
+			case "darwin":
+				return join(home, "Library", "Caches", appName)
+			}
+			return join(home, ".cache", appName)
+		}},
+		{&dirs.State, "BLOCKBENCH_STATE_DIR", "XDG_STATE_HOME", func() string {
+			switch goos {
+			case "windows":
+				return join(getenv("LocalAppData"), appName, "state")
+			case "darwin":
+				return join(home, "Library", "Application Support", appName, "state")
+			}
+			return join(home, ".local", "state", appName)
+		}},
+	} {
+		switch {
+		case getenv(dir.override) != "":
+			*dir.target = getenv(dir.override)
+		case goos != "windows" && filepath.IsAbs(getenv(dir.xdg)):
+			// The specification says relative XDG paths are invalid and must be ignored
+			*dir.target = filepath.Join(getenv(dir.xdg), appName)
+		default:
+			*dir.target = dir.fallback()
+		}
+		if *dir.target == "" {
+			return Dirs{}, fmt.Errorf("cannot determine a directory for blockbench files: set %s", dir.override)
+		}
+	}
+	return dirs, nil
+}
+
+// join joins path elements, or returns "" when the base directory is unknown
+func join(base string, elem ...string) string {
+	if base == "" {
+		return ""
+	}
+	return filepath.Join(append([]string{base}, elem...)...)
+}
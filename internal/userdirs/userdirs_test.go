@@ -0,0 +1,115 @@
+package userdirs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		home     string
+		expected Dirs
+		wantErr  bool
+	}{
+		{
+			name: "linux defaults",
+			goos: "linux",
+			home: "/home/steve",
+			expected: Dirs{
+				Config: "/home/steve/.config/blockbench",
+				Cache:  "/home/steve/.cache/blockbench",
+				State:  "/home/steve/.local/state/blockbench",
+			},
+		},
+		{
+			name: "linux XDG variables",
+			goos: "linux",
+			env:  map[string]string{"XDG_CONFIG_HOME": "/xdg/config", "XDG_CACHE_HOME": "/xdg/cache", "XDG_STATE_HOME": "/xdg/state"},
+			home: "/home/steve",
+			expected: Dirs{
+				Config: "/xdg/config/blockbench",
+				Cache:  "/xdg/cache/blockbench",
+				State:  "/xdg/state/blockbench",
+			},
+		},
+		{
+			name: "relative XDG variable is ignored",
+			goos: "linux",
+			env:  map[string]string{"XDG_CACHE_HOME": "relative/cache"},
+			home: "/home/steve",
+			expected: Dirs{
+				Config: "/home/steve/.config/blockbench",
+				Cache:  "/home/steve/.cache/blockbench",
+				State:  "/home/steve/.local/state/blockbench",
+			},
+		},
+		{
+			name: "overrides win over XDG variables",
+			goos: "linux",
+			env:  map[string]string{"XDG_CONFIG_HOME": "/xdg/config", "BLOCKBENCH_CONFIG_DIR": "/opt/bb/config", "BLOCKBENCH_STATE_DIR": "/opt/bb/state"},
+			home: "/home/steve",
+			expected: Dirs{
+				Config: "/opt/bb/config",
+				Cache:  "/home/steve/.cache/blockbench",
+				State:  "/opt/bb/state",
+			},
+		},
+		{
+			name: "macOS defaults",
+			goos: "darwin",
+			home: "/Users/steve",
+			expected: Dirs{
+				Config: "/Users/steve/Library/Application Support/blockbench",
+				Cache:  "/Users/steve/Library/Caches/blockbench",
+				State:  "/Users/steve/Library/Application Support/blockbench/state",
+			},
+		},
+		{
+			name: "windows defaults ignore XDG variables",
+			goos: "windows",
+			env:  map[string]string{"AppData": "/appdata/roaming", "LocalAppData": "/appdata/local", "XDG_CONFIG_HOME": "/xdg/config"},
+			expected: Dirs{
+				Config: filepath.Join("/appdata/roaming", "blockbench"),
+				Cache:  filepath.Join("/appdata/local", "blockbench", "cache"),
+				State:  filepath.Join("/appdata/local", "blockbench", "state"),
+			},
+		},
+		{
+			name:    "no home directory and no variables",
+			goos:    "linux",
+			wantErr: true,
+		},
+		{
+			name: "no home directory but every override set",
+			goos: "linux",
+			env:  map[string]string{"BLOCKBENCH_CONFIG_DIR": "/c", "BLOCKBENCH_CACHE_DIR": "/k", "BLOCKBENCH_STATE_DIR": "/s"},
+			expected: Dirs{
+				Config: "/c",
+				Cache:  "/k",
+				State:  "/s",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			dirs, err := resolve(tt.goos, getenv, tt.home)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", dirs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve failed: %v", err)
+			}
+			if dirs != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, dirs)
+			}
+		})
+	}
+}
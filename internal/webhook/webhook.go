@@ -0,0 +1,278 @@
+// Package webhook posts install, uninstall, and rollback events to HTTP
+// endpoints, either as blockbench's own JSON or formatted for Discord and
+// Slack incoming webhooks.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+)
+
+// Format is the body format a webhook expects
+type Format string
+
+const (
+	FormatGeneric Format = "generic" // The Event as JSON
+	FormatDiscord Format = "discord" // A Discord incoming webhook message with an embed
+	FormatSlack   Format = "slack"   // A Slack incoming webhook message
+)
+
+// Operations an event can be about
+const (
+	OperationInstall   = "install"
+	OperationUninstall = "uninstall"
+	OperationRollback  = "rollback" // A backup restored with 'backup restore'
+)
+
+var operations = []string{OperationInstall, OperationUninstall, OperationRollback}
+
+// Timeout bounds each webhook request
+const Timeout = 10 * time.Second
+
+// Webhook is an endpoint events are posted to
+type Webhook struct {
+	URL        string   `json:"url"`
+	Format     Format   `json:"format,omitempty"`     // Empty means generic
+	Operations []string `json:"operations,omitempty"` // Operations to notify about; empty means all
+	OnlyFailed bool     `json:"only_failed,omitempty"`
+}
+
+// Validate checks the webhook's URL, format, and operations
+func (w Webhook) Validate() error {
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: use an http or https URL", w.URL)
+	}
+	switch w.Format {
+	case "", FormatGeneric, FormatDiscord, FormatSlack:
+	default:
+		return fmt.Errorf("unknown webhook format %q: use generic, discord, or slack", w.Format)
+	}
+	for _, operation := range w.Operations {
+		if !contains(operations, operation) {
+			return fmt.Errorf("unknown operation %q: use %s", operation, strings.Join(operations, ", "))
+		}
+	}
+	return nil
+}
+
+// Wants reports whether the webhook is notified about the event
+func (w Webhook) Wants(event Event) bool {
+	if w.OnlyFailed && event.Success {
+		return false
+	}
+	return len(w.Operations) == 0 || contains(w.Operations, event.Operation)
+}
+
+// Redacted returns the URL with its path hidden, since webhook URLs embed
+// their secret token
+func (w Webhook) Redacted() string {
+	parsed, err := url.Parse(w.URL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		return parsed.Scheme + "://" + parsed.Host
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/..."
+}
+
+// Event is a completed or failed operation
+type Event struct {
+	Operation  string       `json:"operation"` // install, uninstall, or rollback
+	Success    bool         `json:"success"`
+	Time       time.Time    `json:"time"`
+	Server     string       `json:"server"`
+	World      string       `json:"world,omitempty"`
+	Addon      string       `json:"addon,omitempty"`  // Addon file or directory, for installs
+	Target     string       `json:"target,omitempty"` // Pack name or UUID given, for uninstalls
+	Packs      []hooks.Pack `json:"packs,omitempty"`
+	BackupID   string       `json:"backup_id,omitempty"`
+	RolledBack bool         `json:"rolled_back,omitempty"` // The failed operation's backup was restored
+	Error      string       `json:"error,omitempty"`
+	Warnings   []string     `json:"warnings,omitempty"`
+}
+
+// Summary describes the event in one line
+func (e Event) Summary() string {
+	subject := e.Addon
+	if subject == "" {
+		subject = e.Target
+	}
+	if len(e.Packs) > 0 {
+		names := make([]string, len(e.Packs))
+		for i, pack := range e.Packs {
+			names[i] = fmt.Sprintf("%s %d.%d.%d", pack.Name, pack.Version[0], pack.Version[1], pack.Version[2])
+		}
+		subject = strings.Join(names, ", ")
+	}
+	where := e.Server
+	if e.World != "" {
+		where = fmt.Sprintf("%s (world %s)", e.Server, e.World)
+	}
+
+	switch {
+	case e.Operation == OperationRollback && e.Success:
+		return fmt.Sprintf("Restored backup %s on %s", e.BackupID, where)
+	case e.Operation == OperationRollback:
+		return fmt.Sprintf("Restoring backup %s failed on %s", e.BackupID, where)
+	case e.Success && e.Operation == OperationInstall:
+		return fmt.Sprintf("Installed %s on %s", subject, where)
+	case e.Success:
+		return fmt.Sprintf("Uninstalled %s on %s", subject, where)
+	case subject == "":
+		return fmt.Sprintf("%s failed on %s", capitalize(e.Operation), where)
+	case e.RolledBack:
+		return fmt.Sprintf("%s of %s failed on %s and was rolled back", capitalize(e.Operation), subject, where)
+	default:
+		return fmt.Sprintf("%s of %s failed on %s", capitalize(e.Operation), subject, where)
+	}
+}
+
+// Send posts the event to every webhook that wants it, returning the errors
+// of the requests that failed
+func Send(webhooks []Webhook, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	client := &http.Client{Timeout: Timeout}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		if !webhook.Wants(event) {
+			continue
+		}
+		if err := post(client, webhook, event); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.Redacted(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func post(client *http.Client, webhook Webhook, event Event) error {
+	body, err := Body(webhook.Format, event)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+// Body formats the event for a webhook format
+func Body(format Format, event Event) ([]byte, error) {
+	var body any
+	switch format {
+	case FormatDiscord:
+		body = discordMessage(event)
+	case FormatSlack:
+		body = slackMessage(event)
+	default:
+		body = event
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook body: %w", err)
+	}
+	return data, nil
+}
+
+// Discord embed colors
+const (
+	discordGreen = 0x2ECC71
+	discordRed   = 0xE74C3C
+)
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+func discordMessage(event Event) any {
+	embed := discordEmbed{
+		Title:     event.Summary(),
+		Color:     discordGreen,
+		Timestamp: event.Time.UTC().Format(time.RFC3339),
+	}
+	if !event.Success {
+		embed.Color = discordRed
+		embed.Description = event.Error
+	}
+	for _, field := range detailFields(event) {
+		embed.Fields = append(embed.Fields, discordField{Name: field[0], Value: field[1], Inline: field[0] == "Backup"})
+	}
+	return map[string]any{"username": "blockbench", "embeds": []discordEmbed{embed}}
+}
+
+func slackMessage(event Event) any {
+	lines := []string{"*" + event.Summary() + "*"}
+	if !event.Success && event.Error != "" {
+		lines = append(lines, "Error: "+event.Error)
+	}
+	for _, field := range detailFields(event) {
+		lines = append(lines, fmt.Sprintf("%s: %s", field[0], field[1]))
+	}
+	return map[string]any{"text": strings.Join(lines, "\n")}
+}
+
+// detailFields lists the packs, backup, and warnings of an event as
+// name/value pairs for chat messages
+func detailFields(event Event) [][2]string {
+	var fields [][2]string
+	if len(event.Packs) > 0 {
+		packs := make([]string, len(event.Packs))
+		for i, pack := range event.Packs {
+			packs[i] = fmt.Sprintf("%s %d.%d.%d (%s, %s)", pack.Name,
+				pack.Version[0], pack.Version[1], pack.Version[2], pack.Type, pack.UUID)
+		}
+		fields = append(fields, [2]string{"Packs", strings.Join(packs, "\n")})
+	}
+	if event.BackupID != "" {
+		fields = append(fields, [2]string{"Backup", event.BackupID})
+	}
+	if len(event.Warnings) > 0 {
+		fields = append(fields, [2]string{"Warnings", strings.Join(event.Warnings, "\n")})
+	}
+	return fields
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/makutaku/blockbench/internal/hooks"
+)
+
+func testEvent() Event {
+	return Event{
+		Operation: OperationInstall,
+		Success:   true,
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Server:    "/srv/bedrock",
+		World:     "Survival",
+		Addon:     "/tmp/foo.mcaddon",
+		Packs: []hooks.Pack{
+			{UUID: "11111111-1111-1111-1111-111111111111", Name: "Foo BP", Type: "behavior", Version: [3]int{1, 2, 0}},
+		},
+		BackupID: "backup_1",
+		Warnings: []string{"pack Foo BP has no icon"},
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		wantErr bool
+	}{
+		{"generic", Webhook{URL: "https://example.com/hook"}, false},
+		{"discord filtered", Webhook{URL: "https://discord.com/api/webhooks/1/x", Format: FormatDiscord, Operations: []string{"install"}}, false},
+		{"not http", Webhook{URL: "ftp://example.com"}, true},
+		{"no host", Webhook{URL: "https://"}, true},
+		{"unknown format", Webhook{URL: "https://example.com", Format: "teams"}, true},
+		{"unknown operation", Webhook{URL: "https://example.com", Operations: []string{"update"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.webhook.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := (Webhook{URL: "https://discord.com/api/webhooks/1/secret"}).Redacted(); got != "https://discord.com/..." {
+		t.Errorf("Expected the token to be hidden, got %s", got)
+	}
+}
+
+func TestEventSummary(t *testing.T) {
+	event := testEvent()
+	if got := event.Summary(); got != "Installed Foo BP 1.2.0 on /srv/bedrock (world Survival)" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	event.Success = false
+	event.RolledBack = true
+	if got := event.Summary(); got != "Install of Foo BP 1.2.0 failed on /srv/bedrock (world Survival) and was rolled back" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	event = Event{Operation: OperationUninstall, Server: "/srv/bedrock", Target: "Foo", Error: "no pack found"}
+	if got := event.Summary(); got != "Uninstall of Foo failed on /srv/bedrock" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+
+	event = Event{Operation: OperationRollback, Success: true, Server: "/srv/bedrock", BackupID: "backup_1"}
+	if got := event.Summary(); got != "Restored backup backup_1 on /srv/bedrock" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
+
+func TestSend(t *testing.T) {
+	received := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received[r.URL.Path] = string(body)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	webhooks := []Webhook{
+		{URL: server.URL + "/generic"},
+		{URL: server.URL + "/discord", Format: FormatDiscord},
+		{URL: server.URL + "/slack", Format: FormatSlack},
+		{URL: server.URL + "/uninstalls", Operations: []string{OperationUninstall}},
+		{URL: server.URL + "/failures", OnlyFailed: true},
+	}
+	if err := Send(webhooks, testEvent()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	var generic Event
+	if err := json.Unmarshal([]byte(received["/generic"]), &generic); err != nil {
+		t.Fatalf("Generic body is not an event: %v", err)
+	}
+	if generic.BackupID != "backup_1" || len(generic.Packs) != 1 || generic.Packs[0].Version != [3]int{1, 2, 0} {
+		t.Errorf("Unexpected generic event %+v", generic)
+	}
+
+	var discord struct {
+		Embeds []discordEmbed `json:"embeds"`
+	}
+	if err := json.Unmarshal([]byte(received["/discord"]), &discord); err != nil || len(discord.Embeds) != 1 {
+		t.Fatalf("Unexpected Discord body %s", received["/discord"])
+	}
+	if discord.Embeds[0].Color != discordGreen || len(discord.Embeds[0].Fields) != 3 {
+		t.Errorf("Expected a green embed with packs, backup, and warnings, got %+v", discord.Embeds[0])
+	}
+
+	var slack struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(received["/slack"]), &slack); err != nil || !strings.Contains(slack.Text, "Backup: backup_1") {
+		t.Errorf("Unexpected Slack body %s", received["/slack"])
+	}
+
+	if _, ok := received["/uninstalls"]; ok {
+		t.Error("Expected the uninstall-only webhook not to receive an install")
+	}
+	if _, ok := received["/failures"]; ok {
+		t.Error("Expected the failures-only webhook not to receive a success")
+	}
+
+	err := Send([]Webhook{{URL: server.URL + "/broken"}}, testEvent())
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected the failed request to be reported, got %v", err)
+	}
+}
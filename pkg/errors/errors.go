@@ -0,0 +1,89 @@
+// Package errors defines the kinds of failure blockbench reports, so library
+// consumers and the CLI can branch on them with errors.Is instead of matching
+// messages, and map them to exit codes.
+package errors
+
+import (
+	"errors"
+)
+
+// Kinds of failure. Errors returned by blockbench are marked with one of
+// these where it applies; errors.Is reports the kind.
+var (
+	ErrConflict          = errors.New("conflict")              // A pack is installed in a way that conflicts with the operation
+	ErrMissingDependency = errors.New("missing dependency")    // A pack depends on a pack that is not installed
+	ErrPackNotFound      = errors.New("pack not found")        // No installed pack matches the UUID or name given
+	ErrInvalidManifest   = errors.New("invalid manifest")      // A manifest.json can't be parsed or fails validation
+	ErrServerStructure   = errors.New("invalid server layout") // The server directory is missing a directory or setting blockbench needs
+	ErrInvalidConfig     = errors.New("invalid world config")  // A world config file has duplicate, malformed, or negative-version pack entries
+	ErrRollbackFailed    = errors.New("rollback failed")       // Restoring the backup after a failure failed too, so the server may be left half-changed
+	ErrEncryptedPack     = errors.New("encrypted pack")        // A pack is encrypted Marketplace content and no content key for it was given
+)
+
+// Exit codes of the blockbench command for each kind of failure; any other
+// failure exits with ExitFailure
+const (
+	ExitFailure           = 1
+	ExitConflict          = 3
+	ExitMissingDependency = 4
+	ExitPackNotFound      = 5
+	ExitInvalidManifest   = 6
+	ExitServerStructure   = 7
+	ExitRollbackFailed    = 8
+	ExitInvalidConfig     = 9
+	ExitEncryptedPack     = 10
+)
+
+// exitCodes maps the kinds to their exit codes, most serious first: a failed
+// rollback outranks the failure that caused it
+var exitCodes = []struct {
+	kind error
+	code int
+}{
+	{ErrRollbackFailed, ExitRollbackFailed},
+	{ErrServerStructure, ExitServerStructure},
+	{ErrInvalidConfig, ExitInvalidConfig},
+	{ErrInvalidManifest, ExitInvalidManifest},
+	{ErrEncryptedPack, ExitEncryptedPack},
+	{ErrConflict, ExitConflict},
+	{ErrMissingDependency, ExitMissingDependency},
+	{ErrPackNotFound, ExitPackNotFound},
+}
+
+// Mark returns err marked as kind: its message is unchanged, and both
+// errors.Is(err, kind) and the errors err wraps still match. A nil err stays
+// nil.
+func Mark(err, kind error) error {
+	if err == nil {
+		return nil
+	}
+	return &marked{err: err, kind: kind}
+}
+
+// marked is an error marked with a kind by Mark
+type marked struct {
+	err  error
+	kind error
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+func (m *marked) Unwrap() []error {
+	return []error{m.err, m.kind}
+}
+
+// ExitCode returns the exit code for an error: 0 for nil, the code of its
+// kind, or ExitFailure
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	for _, entry := range exitCodes {
+		if errors.Is(err, entry.kind) {
+			return entry.code
+		}
+	}
+	return ExitFailure
+}
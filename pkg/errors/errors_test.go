@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestMark(t *testing.T) {
+	cause := fmt.Errorf("failed to open manifest: %w", fs.ErrNotExist)
+	err := Mark(cause, ErrInvalidManifest)
+
+	if err.Error() != cause.Error() {
+		t.Errorf("Expected the message to be kept, got %q", err.Error())
+	}
+	if !errors.Is(err, ErrInvalidManifest) || !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Expected both the kind and the wrapped error to match")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Error("Expected another kind not to match")
+	}
+
+	// The kind survives further wrapping
+	wrapped := fmt.Errorf("validation failed for pack Foo: %w", err)
+	if !errors.Is(wrapped, ErrInvalidManifest) {
+		t.Error("Expected the kind to match through wrapping")
+	}
+	if Mark(nil, ErrConflict) != nil {
+		t.Error("Expected a nil error to stay nil")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"plain", errors.New("boom"), ExitFailure},
+		{"conflict", Mark(errors.New("conflicts detected"), ErrConflict), ExitConflict},
+		{"wrapped", fmt.Errorf("install: %w", Mark(errors.New("x"), ErrPackNotFound)), ExitPackNotFound},
+		{"sentinel", fmt.Errorf("%w: disk full", ErrRollbackFailed), ExitRollbackFailed},
+		{"invalid config", fmt.Errorf("load: %w", Mark(errors.New("duplicate pack_id"), ErrInvalidConfig)), ExitInvalidConfig},
+		{"encrypted pack", fmt.Errorf("install: %w", Mark(errors.New("no content key"), ErrEncryptedPack)), ExitEncryptedPack},
+		{"rollback outranks its cause", errors.Join(Mark(errors.New("x"), ErrConflict), ErrRollbackFailed), ExitRollbackFailed},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: ExitCode() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,16 +4,31 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
 	// DefaultMaxFileSize is the default maximum file size for decompression (100MB)
 	DefaultMaxFileSize = 100 * 1024 * 1024
 
+	// DefaultMaxTotalSize is the default maximum total decompressed size of an archive (2GB)
+	DefaultMaxTotalSize = 2 * 1024 * 1024 * 1024
+
+	// DefaultMaxFiles is the default maximum number of files extracted from an archive
+	DefaultMaxFiles = 50000
+
+	// extractBufferSize is the copy buffer size of each extraction worker
+	extractBufferSize = 256 * 1024
+
 	// DefaultDirPerm is the default permission for created directories
 	DefaultDirPerm = 0750
 
@@ -21,38 +36,195 @@ const (
 	DefaultFilePerm = 0600
 )
 
-// defaultMaxFileSize kept for backward compatibility with getMaxFileSize
-const defaultMaxFileSize = DefaultMaxFileSize
+// ExtractLimits bounds what extracting an archive may write, guarding against
+// decompression bombs. Zero fields fall back to DefaultExtractLimits.
+type ExtractLimits struct {
+	MaxFileSize  int64 // Largest decompressed size of a single file
+	MaxTotalSize int64 // Decompressed size of all files, including nested archives
+	MaxFiles     int   // Number of files, including nested archives
+	Workers      int   // Goroutines extracting files in parallel; 0 or 1 extracts sequentially
+}
+
+// DefaultExtractLimits returns the default limits, overridden by the
+// BLOCKBENCH_MAX_FILE_SIZE, BLOCKBENCH_MAX_TOTAL_SIZE, and BLOCKBENCH_MAX_FILES
+// environment variables, and the worker count by BLOCKBENCH_EXTRACT_WORKERS.
+// Sizes accept byte counts or KB/MB/GB suffixes.
+func DefaultExtractLimits() ExtractLimits {
+	return ExtractLimits{
+		MaxFileSize:  envSize("BLOCKBENCH_MAX_FILE_SIZE", DefaultMaxFileSize),
+		MaxTotalSize: envSize("BLOCKBENCH_MAX_TOTAL_SIZE", DefaultMaxTotalSize),
+		MaxFiles:     int(envSize("BLOCKBENCH_MAX_FILES", DefaultMaxFiles)),
+		Workers:      int(envSize("BLOCKBENCH_EXTRACT_WORKERS", 1)),
+	}
+}
 
-// getMaxFileSize returns the maximum file size for extraction
-// Can be configured via BLOCKBENCH_MAX_FILE_SIZE environment variable
-func getMaxFileSize() int64 {
-	if envSize := os.Getenv("BLOCKBENCH_MAX_FILE_SIZE"); envSize != "" {
-		if size, err := strconv.ParseInt(envSize, 10, 64); err == nil && size > 0 {
-			return size
-		}
+// WithDefaults fills zero fields from DefaultExtractLimits
+func (l ExtractLimits) WithDefaults() ExtractLimits {
+	defaults := DefaultExtractLimits()
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = defaults.MaxFileSize
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = defaults.MaxTotalSize
+	}
+	if l.MaxFiles <= 0 {
+		l.MaxFiles = defaults.MaxFiles
+	}
+	if l.Workers <= 0 {
+		l.Workers = defaults.Workers
+	}
+	return l
+}
+
+// envSize reads a positive size from an environment variable
+func envSize(name string, fallback int64) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	size, err := ParseSize(value)
+	if err != nil || size <= 0 {
 		// If invalid, fall back to default
-		fmt.Fprintf(os.Stderr, "Warning: Invalid BLOCKBENCH_MAX_FILE_SIZE value '%s', using default %d bytes\n", envSize, defaultMaxFileSize)
+		slog.Warn("Invalid size value, using default", "variable", name, "value", value, "default", fallback)
+		return fallback
+	}
+	return size
+}
+
+// ParseSize parses a byte count such as "104857600", "500MB", or "2GB".
+// KB, MB, and GB suffixes are powers of 1024 and are case-insensitive.
+func ParseSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(trimmed, unit.suffix) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	if number > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+	return number * multiplier, nil
+}
+
+// FormatSize renders a byte count with a binary unit, the way ParseSize reads it
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		value /= unit
+		if value < unit || suffix == "GB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
 	}
-	return defaultMaxFileSize
+	return fmt.Sprintf("%d B", n)
+}
+
+// Extractor extracts archives while enforcing ExtractLimits across every
+// archive it extracts, so nested archives share one total size and file budget.
+type Extractor struct {
+	Limits       ExtractLimits
+	Progress     Progress // Optional; receives the bytes written by each Extract and ExtractFiles call
+	FS           FS       // Filesystem extracted files are written to; nil is the real filesystem
+	filesWritten atomic.Int64
+	bytesWritten atomic.Int64
+}
+
+// NewExtractor creates an extractor; zero limits fall back to the defaults
+func NewExtractor(limits ExtractLimits) *Extractor {
+	return &Extractor{Limits: limits.WithDefaults()}
 }
 
-// ExtractArchive extracts a ZIP archive to a destination directory
+// ExtractArchive extracts a ZIP archive to a destination directory using the default limits
 func ExtractArchive(archivePath, destDir string) error {
+	return NewExtractor(ExtractLimits{}).Extract(archivePath, destDir)
+}
+
+// Extract extracts a ZIP archive to a destination directory. With
+// Limits.Workers above one, files are extracted by that many goroutines.
+func (e *Extractor) Extract(archivePath, destDir string) error {
 	reader, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to open archive: %w", err)
 	}
 	defer reader.Close()
 
+	if err := checkEntryNames(HostPathStyle, reader.File, ""); err != nil {
+		return err
+	}
+
+	// Reject archives whose declared contents already exceed the limits; the
+	// declared sizes may lie, so the copy below enforces the limits again
+	if err := e.checkDeclared(reader.File); err != nil {
+		return err
+	}
+
 	// Create destination directory
-	if err := os.MkdirAll(destDir, DefaultDirPerm); err != nil {
+	if err := fsOrOS(e.FS).MkdirAll(destDir, DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	return e.extractEntries(reader.File, "", destDir, "Extracting "+filepath.Base(archivePath))
+}
+
+// ExtractFiles streams the given entries of an open archive into destDir,
+// removing stripPrefix from their names. Entries outside the prefix are
+// rejected. This lets callers copy one pack straight from an archive into its
+// final location without extracting the rest of the archive.
+func (e *Extractor) ExtractFiles(files []*zip.File, stripPrefix, destDir string) error {
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name, stripPrefix) {
+			return fmt.Errorf("archive entry %s is outside %s", file.Name, stripPrefix)
+		}
+	}
+
+	if err := checkEntryNames(HostPathStyle, files, stripPrefix); err != nil {
+		return err
+	}
+	if err := e.checkDeclared(files); err != nil {
+		return err
+	}
+
+	if err := fsOrOS(e.FS).MkdirAll(destDir, DefaultDirPerm); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
+	return e.extractEntries(files, stripPrefix, destDir, "Writing "+filepath.Base(destDir))
+}
+
+// extractEntries extracts files sequentially or, with Limits.Workers above one,
+// in parallel, reporting progress against the declared size of the files
+func (e *Extractor) extractEntries(files []*zip.File, stripPrefix, destDir, step string) error {
+	progress := progressOrNone(e.Progress)
+	progress.Start(step, declaredSize(files))
+	defer progress.Finish()
+
+	if e.Limits.Workers > 1 {
+		return e.extractParallel(files, stripPrefix, destDir, progress)
+	}
+
 	// Extract files
-	for _, file := range reader.File {
-		if err := extractFile(file, destDir); err != nil {
+	buf := make([]byte, extractBufferSize)
+	for _, file := range files {
+		if err := e.extractFile(file, stripPrefix, destDir, buf, progress); err != nil {
 			return fmt.Errorf("failed to extract file %s: %w", file.Name, err)
 		}
 	}
@@ -60,19 +232,120 @@ func ExtractArchive(archivePath, destDir string) error {
 	return nil
 }
 
-// extractFile extracts a single file from a ZIP archive
-func extractFile(file *zip.File, destDir string) error {
+// extractParallel extracts files with a bounded pool of workers, each with its
+// own copy buffer. The first error stops the remaining work and is returned.
+func (e *Extractor) extractParallel(files []*zip.File, stripPrefix, destDir string, progress Progress) error {
+	jobs := make(chan *zip.File)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		failed   atomic.Bool
+	)
+
+	workers := e.Limits.Workers
+	if workers > len(files) {
+		workers = len(files)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, extractBufferSize)
+			for file := range jobs {
+				if failed.Load() {
+					continue // Drain remaining jobs after a failure
+				}
+				if err := e.extractFile(file, stripPrefix, destDir, buf, progress); err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("failed to extract file %s: %w", file.Name, err)
+					})
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for _, file := range files {
+		if failed.Load() {
+			break
+		}
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
+
+// checkEntryNames rejects archives with entries the style can't create, such
+// as aux.json or names with a colon on Windows, and entries that would
+// overwrite each other on a case-insensitive filesystem
+func checkEntryNames(style PathStyle, files []*zip.File, stripPrefix string) error {
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimPrefix(file.Name, stripPrefix)
+		if style.Windows {
+			name = strings.ReplaceAll(name, `\`, "/")
+		}
+		if name = path.Clean(name); name != "." {
+			names = append(names, name)
+		}
+	}
+	if err := style.CheckPaths(names); err != nil {
+		return fmt.Errorf("archive entry can't be extracted here: %w", err)
+	}
+	return nil
+}
+
+// checkDeclared compares the sizes and file count recorded in the archive directory against the remaining budget
+func (e *Extractor) checkDeclared(files []*zip.File) error {
+	count := e.filesWritten.Load()
+	total := uint64(e.bytesWritten.Load()) // #nosec G115 - bytesWritten is never negative
+	for _, file := range files {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		count++
+		if count > int64(e.Limits.MaxFiles) {
+			return fmt.Errorf("archive has too many files (exceeded %d file limit)", e.Limits.MaxFiles)
+		}
+		if file.UncompressedSize64 > uint64(e.Limits.MaxFileSize) { // #nosec G115 - limits are positive
+			return fmt.Errorf("file too large after decompression: %s (exceeded %d bytes limit)", file.Name, e.Limits.MaxFileSize)
+		}
+		total += file.UncompressedSize64
+		if total > uint64(e.Limits.MaxTotalSize) { // #nosec G115 - limits are positive
+			return fmt.Errorf("archive too large after decompression (exceeded %d bytes total limit)", e.Limits.MaxTotalSize)
+		}
+	}
+	return nil
+}
+
+// declaredSize sums the uncompressed sizes recorded in the archive directory;
+// callers have already checked them against the limits with checkDeclared
+func declaredSize(files []*zip.File) int64 {
+	var total int64
+	for _, file := range files {
+		total += int64(file.UncompressedSize64) // #nosec G115 - bounded by MaxTotalSize
+	}
+	return total
+}
+
+// extractFile extracts a single file from a ZIP archive, copying through buf.
+// It is safe to call from several goroutines with distinct buffers.
+func (e *Extractor) extractFile(file *zip.File, stripPrefix, destDir string, buf []byte, progress Progress) error {
 	// Clean the file path to prevent directory traversal
-	cleanPath := filepath.Clean(file.Name)
+	cleanPath := filepath.Clean(strings.TrimPrefix(file.Name, stripPrefix))
 	if strings.Contains(cleanPath, "..") {
 		return fmt.Errorf("invalid file path: %s", file.Name)
 	}
 
 	destPath := filepath.Join(destDir, cleanPath)
+	fsys := fsOrOS(e.FS)
 
 	// Create directory for file if needed
 	if file.FileInfo().IsDir() {
-		return os.MkdirAll(destPath, file.FileInfo().Mode())
+		return fsys.MkdirAll(destPath, file.FileInfo().Mode())
 	}
 
 	// Prevent symlink attacks - symlinks in archives are a security risk
@@ -80,8 +353,12 @@ func extractFile(file *zip.File, destDir string) error {
 		return fmt.Errorf("symlinks are not allowed in archives (security risk): %s", file.Name)
 	}
 
+	if e.filesWritten.Add(1) > int64(e.Limits.MaxFiles) {
+		return fmt.Errorf("archive has too many files (exceeded %d file limit)", e.Limits.MaxFiles)
+	}
+
 	// Create parent directories
-	if err := os.MkdirAll(filepath.Dir(destPath), DefaultDirPerm); err != nil {
+	if err := fsys.MkdirAll(filepath.Dir(destPath), DefaultDirPerm); err != nil {
 		return err
 	}
 
@@ -92,26 +369,39 @@ func extractFile(file *zip.File, destDir string) error {
 	}
 	defer srcFile.Close()
 
+	// Replace rather than truncate an existing file, which may be a hard link into a ContentStore
+	if err := fsys.Remove(destPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
 	// Create destination file
-	// #nosec G304 - destPath is validated by caller and within controlled temp directory
-	destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileInfo().Mode())
+	destFile, err := fsys.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileInfo().Mode())
 	if err != nil {
 		return err
 	}
 	defer destFile.Close()
 
-	// Copy file contents with size limit to prevent decompression bombs
-	maxFileSize := getMaxFileSize()
-	limitedReader := io.LimitReader(srcFile, maxFileSize)
-	written, err := io.Copy(destFile, limitedReader)
+	// Copy file contents with size limits to prevent decompression bombs.
+	// Read one byte past the tighter limit so exceeding it is detectable.
+	limit := e.Limits.MaxFileSize
+	if remaining := e.Limits.MaxTotalSize - e.bytesWritten.Load(); remaining < limit {
+		limit = remaining
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	written, err := io.CopyBuffer(ProgressWriter{W: destFile, Progress: progress}, io.LimitReader(srcFile, limit+1), buf)
+	total := e.bytesWritten.Add(written)
 	if err != nil {
 		return err
 	}
 
-	// Check if we hit the limit (potential decompression bomb)
 	// Use > instead of >= to allow files exactly at the size limit
-	if written > maxFileSize {
-		return fmt.Errorf("file too large after decompression: %s (exceeded %d bytes limit)", file.Name, maxFileSize)
+	if written > e.Limits.MaxFileSize {
+		return fmt.Errorf("file too large after decompression: %s (exceeded %d bytes limit)", file.Name, e.Limits.MaxFileSize)
+	}
+	if total > e.Limits.MaxTotalSize {
+		return fmt.Errorf("archive too large after decompression (exceeded %d bytes total limit)", e.Limits.MaxTotalSize)
 	}
 
 	return nil
@@ -154,6 +444,7 @@ type ArchiveInfo struct {
 	TopLevelDirs   []string
 	HasMcpackFiles bool
 	McpackFiles    []string
+	ZipFiles       []string // Nested .zip archives, which may hold double-zipped packs
 }
 
 // GetArchiveInfo analyzes a ZIP archive and returns information about it
@@ -168,6 +459,7 @@ func GetArchiveInfo(archivePath string) (*ArchiveInfo, error) {
 		ManifestFiles: make([]string, 0),
 		TopLevelDirs:  make([]string, 0),
 		McpackFiles:   make([]string, 0),
+		ZipFiles:      make([]string, 0),
 	}
 
 	topDirs := make(map[string]bool)
@@ -198,6 +490,9 @@ func GetArchiveInfo(archivePath string) (*ArchiveInfo, error) {
 			info.HasMcpackFiles = true
 			info.McpackFiles = append(info.McpackFiles, file.Name)
 		}
+		if strings.HasSuffix(strings.ToLower(file.Name), ".zip") {
+			info.ZipFiles = append(info.ZipFiles, file.Name)
+		}
 
 		// Track top-level directories
 		pathParts := strings.Split(file.Name, "/")
@@ -212,3 +507,103 @@ func GetArchiveInfo(archivePath string) (*ArchiveInfo, error) {
 
 	return info, nil
 }
+
+// ArchiveDir is a directory added to an archive by CreateArchive
+type ArchiveDir struct {
+	Name   string // Top-level folder the directory is stored under; empty stores it at the root, as in a .mcpack
+	Source string // Directory on the filesystem the archive is created from
+
+	// Skip, when set, reports files and directories to leave out, given their
+	// slash-separated path relative to Source
+	Skip func(rel string, entry fs.DirEntry) bool
+}
+
+// CreateArchive writes the given directories into a new ZIP archive, each
+// under its own top-level folder. The archive is written to a temporary file
+// and renamed into place, so a failure never leaves a partial archive behind.
+func CreateArchive(archivePath string, dirs []ArchiveDir) error {
+	return CreateArchiveFS(nil, archivePath, dirs)
+}
+
+// CreateArchiveFS is CreateArchive with the directories read from fsys; a
+// nil fsys is the real filesystem. The archive itself is always written to
+// the real filesystem, where archives are extracted from.
+func CreateArchiveFS(fsys FS, archivePath string, dirs []ArchiveDir) error {
+	tmp, err := os.CreateTemp(filepath.Dir(archivePath), "."+filepath.Base(archivePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	writer := zip.NewWriter(tmp)
+	for _, dir := range dirs {
+		if err := addArchiveDir(fsys, writer, dir); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to add %s to archive: %w", dir.Source, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// addArchiveDir stores the regular files below dir.Source on fsys under dir.Name
+func addArchiveDir(fsys FS, writer *zip.Writer, dir ArchiveDir) error {
+	return WalkDir(fsys, dir.Source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir.Source, path)
+		if err != nil {
+			return err
+		}
+		if dir.Skip != nil && rel != "." && dir.Skip(filepath.ToSlash(rel), d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", path)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if dir.Name != "" {
+			header.Name = dir.Name + "/" + header.Name
+		}
+		header.Method = zip.Deflate
+
+		entry, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		// #nosec G304 - path comes from walking a pack directory
+		file, err := Open(fsys, path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(entry, file)
+		return err
+	})
+}
@@ -3,6 +3,7 @@ package filesystem
 import (
 	"archive/zip"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -118,6 +119,218 @@ func TestExtractArchive(t *testing.T) {
 	}
 }
 
+func TestExtractorLimits(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	first := filepath.Join(tempDir, "first.zip")
+	createTestZip(t, first, map[string]string{
+		"a.txt": strings.Repeat("a", 60),
+		"b.txt": strings.Repeat("b", 30),
+	})
+	second := filepath.Join(tempDir, "second.zip")
+	createTestZip(t, second, map[string]string{
+		"c.txt": strings.Repeat("c", 20),
+	})
+
+	tests := []struct {
+		name        string
+		limits      ExtractLimits
+		expectError string // Empty when both archives should extract
+	}{
+		{"within limits", ExtractLimits{MaxFileSize: 60, MaxTotalSize: 110, MaxFiles: 3}, ""},
+		{"file exactly at size limit", ExtractLimits{MaxFileSize: 60}, ""},
+		{"file over size limit", ExtractLimits{MaxFileSize: 59}, "file too large"},
+		{"total shared across archives", ExtractLimits{MaxTotalSize: 100}, "total limit"},
+		{"file count shared across archives", ExtractLimits{MaxFiles: 2}, "too many files"},
+		{"parallel within limits", ExtractLimits{MaxFileSize: 60, MaxTotalSize: 110, MaxFiles: 3, Workers: 4}, ""},
+		{"parallel total limit", ExtractLimits{MaxTotalSize: 100, Workers: 4}, "total limit"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor := NewExtractor(tt.limits)
+			destDir := filepath.Join(tempDir, fmt.Sprintf("out%d", i))
+
+			err := extractor.Extract(first, filepath.Join(destDir, "first"))
+			if err == nil {
+				err = extractor.Extract(second, filepath.Join(destDir, "second"))
+			}
+
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("Expected extraction to succeed, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestExtractParallel(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		files[fmt.Sprintf("textures/blocks/dir%d/file%d.png", i%10, i)] = strings.Repeat(fmt.Sprintf("%d", i), 100+i)
+	}
+	files["textures/"] = ""
+	zipPath := filepath.Join(tempDir, "parallel.zip")
+	createTestZip(t, zipPath, files)
+
+	extractDir := filepath.Join(tempDir, "extracted")
+	if err := NewExtractor(ExtractLimits{Workers: 8}).Extract(zipPath, extractDir); err != nil {
+		t.Fatalf("Parallel extraction failed: %v", err)
+	}
+
+	for name, content := range files {
+		if content == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(extractDir, name))
+		if err != nil {
+			t.Errorf("Expected file %s to be extracted: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Content mismatch for %s", name)
+		}
+	}
+
+	// Path traversal protection applies to workers too
+	badZip := filepath.Join(tempDir, "bad.zip")
+	createTestZip(t, badZip, map[string]string{"ok.txt": "fine", "../../evil.txt": "malicious"})
+	if err := NewExtractor(ExtractLimits{Workers: 4}).Extract(badZip, filepath.Join(tempDir, "bad")); err == nil {
+		t.Error("Expected parallel extraction to reject path traversal")
+	}
+}
+
+func TestExtractFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	zipPath := filepath.Join(tempDir, "addon.zip")
+	createTestZip(t, zipPath, map[string]string{
+		"BP/manifest.json":       `{"format_version": 2}`,
+		"BP/scripts/main.js":     "console.log('test');",
+		"RP/manifest.json":       `{"format_version": 2}`,
+		"RP/textures/block.png":  "fake png data",
+		"BP/../../escape.txt":    "malicious",
+		"RP/textures/blocks/a.t": "x",
+	})
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer reader.Close()
+
+	var rpFiles, bpFiles []*zip.File
+	for _, file := range reader.File {
+		switch {
+		case strings.HasPrefix(file.Name, "RP/"):
+			rpFiles = append(rpFiles, file)
+		case strings.HasPrefix(file.Name, "BP/"):
+			bpFiles = append(bpFiles, file)
+		}
+	}
+
+	destDir := filepath.Join(tempDir, "Resource Pack")
+	if err := NewExtractor(ExtractLimits{}).ExtractFiles(rpFiles, "RP/", destDir); err != nil {
+		t.Fatalf("ExtractFiles failed: %v", err)
+	}
+	for _, name := range []string{"manifest.json", "textures/block.png", "textures/blocks/a.t"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("Expected %s to be extracted with the prefix stripped: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "RP")); !os.IsNotExist(err) {
+		t.Error("Expected prefix directory not to be created")
+	}
+
+	// Entries outside the prefix and traversal attempts are rejected
+	if err := NewExtractor(ExtractLimits{}).ExtractFiles(rpFiles, "BP/", filepath.Join(tempDir, "wrong")); err == nil {
+		t.Error("Expected error for entries outside the prefix")
+	}
+	if err := NewExtractor(ExtractLimits{}).ExtractFiles(bpFiles, "BP/", filepath.Join(tempDir, "bp")); err == nil {
+		t.Error("Expected error for path traversal entry")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("Path traversal entry escaped the destination directory")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    int64
+		expectError bool
+	}{
+		{"104857600", 104857600, false},
+		{"512B", 512, false},
+		{"4kb", 4096, false},
+		{"500MB", 500 * 1024 * 1024, false},
+		{" 2 GB ", 2 * 1024 * 1024 * 1024, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-5MB", 0, true},
+		{"1.5GB", 0, true},
+		{"99999999999GB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseSize(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %d", tt.input, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSize(%q) failed: %v", tt.input, err)
+			}
+			if size != tt.expected {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, size, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{100 * 1024 * 1024, "100.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+		{4096 * 1024 * 1024 * 1024, "4096.0 GB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSize(tt.size); got != tt.expected {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.size, got, tt.expected)
+		}
+	}
+}
+
 func TestExtractArchiveWithPathTraversal(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "blockbench-test")
 	if err != nil {
@@ -156,6 +369,7 @@ func TestGetArchiveInfo(t *testing.T) {
 		"pack_icon.png":     "fake png data (12 bytes)",
 		"textures/test.png": "more fake data",
 		"behaviors/":        "", // directory
+		"packs/rp.zip":      "double-zipped pack",
 	}
 	createTestZip(t, zipPath, testFiles)
 
@@ -165,8 +379,12 @@ func TestGetArchiveInfo(t *testing.T) {
 	}
 
 	// Verify basic info
-	if info.TotalFiles != 4 { // 3 files + 1 directory
-		t.Errorf("Expected 4 total files, got %d", info.TotalFiles)
+	if info.TotalFiles != 5 { // 4 files + 1 directory
+		t.Errorf("Expected 5 total files, got %d", info.TotalFiles)
+	}
+
+	if len(info.ZipFiles) != 1 || info.ZipFiles[0] != "packs/rp.zip" {
+		t.Errorf("Expected the nested zip to be listed, got %v", info.ZipFiles)
 	}
 
 	if !info.HasManifest {
@@ -304,3 +522,158 @@ func createTestZipForBench(b *testing.B, zipPath string, files map[string]string
 		}
 	}
 }
+
+// BenchmarkExtractArchiveParallel compares sequential and worker-pool extraction
+// of a texture-pack-like archive with many files
+func BenchmarkExtractArchiveParallel(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "blockbench-bench")
+	if err != nil {
+		b.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	zipPath := filepath.Join(tempDir, "bench.zip")
+	testFiles := make(map[string]string)
+	content := strings.Repeat("texture data ", 4096)
+	for i := 0; i < 2000; i++ {
+		testFiles[fmt.Sprintf("textures/blocks/dir_%d/texture_%d.png", i%50, i)] = content
+	}
+	createTestZipForBench(b, zipPath, testFiles)
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				extractDir := filepath.Join(tempDir, fmt.Sprintf("extract_%d_%d", workers, i))
+				if err := NewExtractor(ExtractLimits{Workers: workers}).Extract(zipPath, extractDir); err != nil {
+					b.Fatalf("Failed to extract archive: %v", err)
+				}
+				b.StopTimer()
+				os.RemoveAll(extractDir) // Clean up for next iteration
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestExtractMemFS(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	zipPath := filepath.Join(tempDir, "pack.zip")
+	createTestZip(t, zipPath, map[string]string{
+		"manifest.json":         `{"format_version": 2}`,
+		"textures/terrain.json": "{}",
+	})
+
+	m := NewMemFS()
+	extractor := NewExtractor(ExtractLimits{})
+	extractor.FS = m
+	if err := extractor.Extract(zipPath, "/extract"); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	data, err := ReadFile(m, "/extract/textures/terrain.json")
+	if err != nil || string(data) != "{}" {
+		t.Errorf("Expected the nested file in memory, got %q, %v", data, err)
+	}
+	if _, err := os.Stat("/extract"); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written to disk")
+	}
+}
+
+func TestCreateArchive(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"bp/manifest.json":        `{"format_version": 2}`,
+		"bp/scripts/main.js":      "// main",
+		"rp/manifest.json":        `{"format_version": 2}`,
+		"rp/textures/blocks.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, "src", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	archivePath := filepath.Join(tempDir, "bundle.mcaddon")
+	err = CreateArchive(archivePath, []ArchiveDir{
+		{Name: "Behavior", Source: filepath.Join(tempDir, "src", "bp")},
+		{Name: "Resource", Source: filepath.Join(tempDir, "src", "rp")},
+	})
+	if err != nil {
+		t.Fatalf("CreateArchive failed: %v", err)
+	}
+
+	info, err := GetArchiveInfo(archivePath)
+	if err != nil {
+		t.Fatalf("GetArchiveInfo failed: %v", err)
+	}
+	if info.TotalFiles != 4 || len(info.ManifestFiles) != 2 {
+		t.Errorf("Expected 4 files with 2 manifests, got %+v", info)
+	}
+
+	destDir := filepath.Join(tempDir, "out")
+	if err := ExtractArchive(archivePath, destDir); err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(destDir, "Behavior", "scripts", "main.js"))
+	if err != nil || string(data) != "// main" {
+		t.Errorf("Expected the round-tripped script, got %q, %v", data, err)
+	}
+
+	// An unnamed directory is stored at the archive root
+	packPath := filepath.Join(tempDir, "pack.mcpack")
+	if err := CreateArchive(packPath, []ArchiveDir{{Source: filepath.Join(tempDir, "src", "rp")}}); err != nil {
+		t.Fatalf("CreateArchive failed: %v", err)
+	}
+	packInfo, err := GetArchiveInfo(packPath)
+	if err != nil {
+		t.Fatalf("GetArchiveInfo failed: %v", err)
+	}
+	if len(packInfo.ManifestFiles) != 1 || packInfo.ManifestFiles[0] != "manifest.json" {
+		t.Errorf("Expected manifest.json at the archive root, got %v", packInfo.ManifestFiles)
+	}
+
+	// Skipped files and directories are left out
+	skipPath := filepath.Join(tempDir, "skip.mcpack")
+	err = CreateArchive(skipPath, []ArchiveDir{{
+		Source: filepath.Join(tempDir, "src", "bp"),
+		Skip: func(rel string, entry fs.DirEntry) bool {
+			return rel == "scripts"
+		},
+	}})
+	if err != nil {
+		t.Fatalf("CreateArchive failed: %v", err)
+	}
+	skipInfo, err := GetArchiveInfo(skipPath)
+	if err != nil {
+		t.Fatalf("GetArchiveInfo failed: %v", err)
+	}
+	if skipInfo.TotalFiles != 1 {
+		t.Errorf("Expected the skipped directory to be left out, got %d files", skipInfo.TotalFiles)
+	}
+	if err := os.Remove(skipPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed archive leaves nothing behind
+	missing := filepath.Join(tempDir, "missing.mcaddon")
+	if err := CreateArchive(missing, []ArchiveDir{{Name: "x", Source: filepath.Join(tempDir, "nope")}}); err == nil {
+		t.Error("Expected an error for a missing source directory")
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 4 {
+		t.Errorf("Expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}
@@ -5,28 +5,68 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // BackupMetadata contains information about a backup
 type BackupMetadata struct {
-	ID          string    `json:"id"`
-	Timestamp   time.Time `json:"timestamp"`
-	Operation   string    `json:"operation"`
-	AddonName   string    `json:"addon_name,omitempty"`
-	AddonUUID   string    `json:"addon_uuid,omitempty"`
-	ServerPath  string    `json:"server_path"`
-	BackupPath  string    `json:"backup_path"`
-	Files       []string  `json:"files"`
-	Description string    `json:"description,omitempty"`
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Operation     string    `json:"operation"`
+	AddonName     string    `json:"addon_name,omitempty"`
+	AddonUUID     string    `json:"addon_uuid,omitempty"`
+	PackUUIDs     []string  `json:"pack_uuids,omitempty"` // Every pack touched by the operation
+	ServerPath    string    `json:"server_path"`
+	BackupPath    string    `json:"backup_path"`
+	Files         []string  `json:"files"`
+	Description   string    `json:"description,omitempty"`
+	Base          string    `json:"base,omitempty"`   // For an incremental backup, the backup it stores the changes to
+	Layout        string    `json:"layout,omitempty"` // How the backup directory names the copies of Files; see storedName
+
+	// Checksums holds the SHA-256 digest of every backed-up file, keyed by
+	// its original path, for verifying a restore. Backups made before
+	// version 2 have none.
+	Checksums map[string]string `json:"checksums,omitempty"`
 }
 
+// LatestBackup selects the newest backup wherever a backup ID is expected
+const LatestBackup = "latest"
+
+// LayoutServerRelative is the Layout of backups that keep the copy of each
+// file at its path relative to ServerPath. Backups without a layout keep
+// every copy under its base name.
+const LayoutServerRelative = "server_relative"
+
 // BackupManager handles backup operations
 type BackupManager struct {
 	BackupRoot string
-	metadata   []BackupMetadata
+	Progress   Progress // Optional; receives the bytes copied by CreateBackup
+	FS         FS       // Filesystem backed-up files and backups live on; nil is the real filesystem
+
+	// ServerRoot is the server the backed-up files belong to. CreateBackup
+	// records it as ServerPath and keeps each file at its path relative to
+	// it, so files of the same name in different directories don't collide.
+	ServerRoot string
+
+	// Incremental makes CreateBackup store only the files that changed since
+	// the newest backup, which the new backup is then based on
+	Incremental bool
+
+	// RestoreRoot, when set, rebases the files of every backup loaded from
+	// the server they were taken of onto this server root, to restore them
+	// into another server. Such a manager only restores; it refuses to take
+	// or delete backups.
+	RestoreRoot string
+
+	metadata []BackupMetadata
 }
 
 // NewBackupManager creates a new backup manager
@@ -37,98 +77,199 @@ func NewBackupManager(backupRoot string) *BackupManager {
 	}
 }
 
+// fs returns the filesystem the backup manager works on
+func (bm *BackupManager) fs() FS {
+	return fsOrOS(bm.FS)
+}
+
 // CreateBackup creates a backup of specified files/directories
 func (bm *BackupManager) CreateBackup(operation, description string, files []string) (*BackupMetadata, error) {
-	// Generate backup ID
-	backupID := generateBackupID()
+	if err := bm.writable(); err != nil {
+		return nil, err
+	}
+	var base *BackupMetadata
+	if bm.Incremental {
+		var err error
+		if base, err = bm.incrementalBase(); err != nil {
+			return nil, fmt.Errorf("failed to find the base of an incremental backup: %w", err)
+		}
+	}
+
+	// Generate a backup ID that no backup in the root has, even one made at
+	// the same moment by another process
+	backupID, timestamp := generateBackupID(time.Now())
+	for attempt := 1; bm.backupExists(backupID); attempt++ {
+		if attempt == maxBackupIDAttempts {
+			return nil, fmt.Errorf("failed to generate an unused backup ID in %s", bm.BackupRoot)
+		}
+		backupID, timestamp = generateBackupID(time.Now())
+	}
 
 	// Create backup directory
 	backupDir := filepath.Join(bm.BackupRoot, backupID)
-	if err := os.MkdirAll(backupDir, 0750); err != nil {
+	if err := bm.fs().MkdirAll(backupDir, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
 	// Create metadata
 	metadata := BackupMetadata{
 		ID:          backupID,
-		Timestamp:   time.Now(),
+		Timestamp:   timestamp,
 		Operation:   operation,
+		ServerPath:  bm.ServerRoot,
 		BackupPath:  backupDir,
 		Files:       make([]string, 0),
 		Description: description,
 	}
+	if bm.ServerRoot != "" {
+		metadata.Layout = LayoutServerRelative
+	}
+	if base != nil {
+		metadata.Base = base.ID
+	}
+
+	// Two files kept under the same name would overwrite each other's copy
+	storedAs := make(map[string]string, len(files))
+	for _, file := range files {
+		name := metadata.storedName(file)
+		if other, ok := storedAs[name]; ok && other != file {
+			if rmErr := bm.fs().RemoveAll(backupDir); rmErr != nil {
+				slog.Warn("Failed to cleanup backup directory", "path", backupDir, "error", rmErr)
+			}
+			return nil, fmt.Errorf("failed to backup %s: its copy would overwrite the one of %s", file, other)
+		}
+		storedAs[name] = file
+	}
+
+	// Pre-scan the sources so progress can be reported against a total
+	var total int64
+	for _, file := range files {
+		size, err := TreeSizeFS(bm.fs(), file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", file, err)
+		}
+		total += size
+	}
+	progress := progressOrNone(bm.Progress)
+	progress.Start("Backing up", total)
+	defer progress.Finish()
 
 	// Backup each file/directory
 	for _, file := range files {
-		if err := bm.backupFile(file, backupDir); err != nil {
+		if base != nil {
+			if err := bm.backupFileIncremental(file, filepath.Join(backupDir, metadata.storedName(file)), base, &metadata, progress); err != nil {
+				if rmErr := bm.fs().RemoveAll(backupDir); rmErr != nil {
+					slog.Warn("Failed to cleanup backup directory", "path", backupDir, "error", rmErr)
+				}
+				return nil, fmt.Errorf("failed to backup %s: %w", file, err)
+			}
+			metadata.Files = append(metadata.Files, file)
+			continue
+		}
+
+		if err := bm.backupFile(file, filepath.Join(backupDir, metadata.storedName(file)), progress); err != nil {
 			// Cleanup on error
-			if rmErr := os.RemoveAll(backupDir); rmErr != nil {
+			if rmErr := bm.fs().RemoveAll(backupDir); rmErr != nil {
 				// Log cleanup failure but don't override original error
-				fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup backup directory: %v\n", rmErr)
+				slog.Warn("Failed to cleanup backup directory", "path", backupDir, "error", rmErr)
 			}
 			return nil, fmt.Errorf("failed to backup %s: %w", file, err)
 		}
 		metadata.Files = append(metadata.Files, file)
+		if err := bm.recordChecksums(&metadata, file); err != nil {
+			if rmErr := bm.fs().RemoveAll(backupDir); rmErr != nil {
+				slog.Warn("Failed to cleanup backup directory", "path", backupDir, "error", rmErr)
+			}
+			return nil, fmt.Errorf("failed to checksum the backup of %s: %w", file, err)
+		}
 	}
 
 	// Save metadata
 	if err := bm.saveMetadata(&metadata); err != nil {
-		if rmErr := os.RemoveAll(backupDir); rmErr != nil {
+		if rmErr := bm.fs().RemoveAll(backupDir); rmErr != nil {
 			// Log cleanup failure but don't override original error
-			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup backup directory: %v\n", rmErr)
+			slog.Warn("Failed to cleanup backup directory", "path", backupDir, "error", rmErr)
 		}
 		return nil, fmt.Errorf("failed to save backup metadata: %w", err)
 	}
 
+	slog.Info("Created backup", "id", backupID, "operation", operation, "path", backupDir, "files", len(metadata.Files), "base", metadata.Base)
 	return &metadata, nil
 }
 
 // RestoreBackup restores files from a backup
 func (bm *BackupManager) RestoreBackup(backupID string) error {
+	return bm.RestoreFiles(backupID, nil)
+}
+
+// RestoreFiles restores some of the files of a backup, each an entry of its
+// Files; none restores them all
+func (bm *BackupManager) RestoreFiles(backupID string, files []string) error {
 	metadata, err := bm.loadMetadata(backupID)
 	if err != nil {
 		return fmt.Errorf("failed to load backup metadata: %w", err)
 	}
+	files, err = metadata.selectFiles(files)
+	if err != nil {
+		return err
+	}
+
+	backupDir, cleanup, err := bm.materialize(metadata)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
 	// Restore each backed up file
-	for _, originalFile := range metadata.Files {
-		if err := bm.restoreFile(originalFile, metadata.BackupPath); err != nil {
+	for _, originalFile := range files {
+		if err := bm.restoreFile(originalFile, filepath.Join(backupDir, metadata.storedName(originalFile))); err != nil {
 			return fmt.Errorf("failed to restore %s: %w", originalFile, err)
 		}
+		slog.Debug("Restored file", "backup", backupID, "path", originalFile)
 	}
 
+	slog.Info("Restored backup", "id", backupID, "files", len(files))
 	return nil
 }
 
 // DeleteBackup removes a backup and its metadata
 func (bm *BackupManager) DeleteBackup(backupID string) error {
+	if err := bm.writable(); err != nil {
+		return err
+	}
 	// Load metadata to get backup path
 	metadata, err := bm.loadMetadata(backupID)
 	if err != nil {
 		return fmt.Errorf("failed to load backup metadata: %w", err)
 	}
 
+	// Backups based on this one must not lose the files they inherit from it
+	if err := bm.detachDependents(metadata); err != nil {
+		return fmt.Errorf("failed to detach the backups based on %s: %w", backupID, err)
+	}
+
 	// Remove backup directory
-	if err := os.RemoveAll(metadata.BackupPath); err != nil {
+	if err := bm.fs().RemoveAll(metadata.BackupPath); err != nil {
 		return fmt.Errorf("failed to remove backup directory: %w", err)
 	}
 
 	// Remove metadata file
 	metadataFile := filepath.Join(bm.BackupRoot, fmt.Sprintf("%s.json", backupID))
-	if err := os.Remove(metadataFile); err != nil {
+	if err := bm.fs().Remove(metadataFile); err != nil {
 		return fmt.Errorf("failed to remove metadata file: %w", err)
 	}
 
+	slog.Info("Deleted backup", "id", backupID)
 	return nil
 }
 
 // ListBackups returns a list of all backups
 func (bm *BackupManager) ListBackups() ([]BackupMetadata, error) {
-	if err := os.MkdirAll(bm.BackupRoot, 0750); err != nil {
+	if err := bm.fs().MkdirAll(bm.BackupRoot, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	entries, err := os.ReadDir(bm.BackupRoot)
+	entries, err := bm.fs().ReadDir(bm.BackupRoot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read backup directory: %w", err)
 	}
@@ -139,7 +280,9 @@ func (bm *BackupManager) ListBackups() ([]BackupMetadata, error) {
 			backupID := entry.Name()[:len(entry.Name())-5] // Remove .json extension
 			metadata, err := bm.loadMetadata(backupID)
 			if err != nil {
-				continue // Skip corrupted metadata
+				// Skip corrupted metadata, but say so; fsck can repair or quarantine it
+				slog.Warn("Skipping backup", "id", backupID, "error", err)
+				continue
 			}
 			backups = append(backups, *metadata)
 		}
@@ -148,141 +291,227 @@ func (bm *BackupManager) ListBackups() ([]BackupMetadata, error) {
 	return backups, nil
 }
 
-// backupFile backs up a single file or directory
-func (bm *BackupManager) backupFile(source, backupDir string) error {
-	// Get relative path for backup structure
-	basename := filepath.Base(source)
-	backupPath := filepath.Join(backupDir, basename)
+// storedName returns where in the backup directory the copy of file, an
+// entry of Files, is kept: its path relative to the server with
+// LayoutServerRelative, and its base name otherwise or when the file is
+// outside the server
+func (m *BackupMetadata) storedName(file string) string {
+	if m.Layout == LayoutServerRelative && m.ServerPath != "" {
+		rel, err := filepath.Rel(m.ServerPath, file)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+	return filepath.Base(file)
+}
+
+// markMissing records that the file whose copy belongs at backupPath did not exist
+func markMissing(fsys FS, backupPath string) error {
+	if err := fsys.MkdirAll(filepath.Dir(backupPath), 0750); err != nil {
+		return err
+	}
+	return WriteFile(fsys, backupPath+".missing", []byte(""), 0600)
+}
 
+// backupFile backs up a single file or directory to backupPath
+func (bm *BackupManager) backupFile(source, backupPath string, progress Progress) error {
 	// Check if source exists
-	sourceInfo, err := os.Stat(source)
+	sourceInfo, err := bm.fs().Stat(source)
 	if os.IsNotExist(err) {
 		// Create empty marker file for non-existent files
-		markerFile := backupPath + ".missing"
-		return os.WriteFile(markerFile, []byte(""), 0600)
+		return markMissing(bm.fs(), backupPath)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to stat source file: %w", err)
 	}
 
 	if sourceInfo.IsDir() {
-		return copyDir(source, backupPath)
+		return copyDir(bm.fs(), source, backupPath, progress)
 	}
 
-	return copyFile(source, backupPath)
+	return copyFile(bm.fs(), source, backupPath, progress)
 }
 
-// restoreFile restores a single file or directory
-func (bm *BackupManager) restoreFile(originalPath, backupDir string) error {
-	basename := filepath.Base(originalPath)
-	backupPath := filepath.Join(backupDir, basename)
-
+// restoreFile restores a single file or directory from its copy at backupPath
+func (bm *BackupManager) restoreFile(originalPath, backupPath string) error {
 	// Check if this was a missing file
 	markerFile := backupPath + ".missing"
-	if _, err := os.Stat(markerFile); err == nil {
+	if _, err := bm.fs().Stat(markerFile); err == nil {
 		// File was missing in original, remove it if it exists now
-		if _, err := os.Stat(originalPath); err == nil {
-			return os.RemoveAll(originalPath)
+		if _, err := bm.fs().Stat(originalPath); err == nil {
+			return bm.fs().RemoveAll(originalPath)
 		}
 		return nil
 	}
 
 	// Check if backup exists
-	backupInfo, err := os.Stat(backupPath)
+	backupInfo, err := bm.fs().Stat(backupPath)
 	if err != nil {
 		return fmt.Errorf("backup file not found: %w", err)
 	}
 
 	if backupInfo.IsDir() {
 		// Remove existing directory if it exists
-		if _, err := os.Stat(originalPath); err == nil {
-			if err := os.RemoveAll(originalPath); err != nil {
+		if _, err := bm.fs().Stat(originalPath); err == nil {
+			if err := bm.fs().RemoveAll(originalPath); err != nil {
 				return fmt.Errorf("failed to remove existing directory: %w", err)
 			}
 		}
-		return copyDir(backupPath, originalPath)
+		return copyDir(bm.fs(), backupPath, originalPath, nil)
 	}
 
-	return copyFile(backupPath, originalPath)
+	return copyFile(bm.fs(), backupPath, originalPath, nil)
+}
+
+// UpdateMetadata rewrites the stored metadata of an existing backup
+func (bm *BackupManager) UpdateMetadata(metadata *BackupMetadata) error {
+	if _, err := bm.fs().Stat(metadata.BackupPath); err != nil {
+		return fmt.Errorf("backup %s does not exist: %w", metadata.ID, err)
+	}
+	return bm.saveMetadata(metadata)
 }
 
 // saveMetadata saves backup metadata to a JSON file
 func (bm *BackupManager) saveMetadata(metadata *BackupMetadata) error {
+	if err := bm.writable(); err != nil {
+		return err
+	}
 	metadataFile := filepath.Join(bm.BackupRoot, fmt.Sprintf("%s.json", metadata.ID))
+	metadata.SchemaVersion = BackupMetadataVersion
 
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	return os.WriteFile(metadataFile, data, 0600)
+	return WriteFile(bm.fs(), metadataFile, data, 0600)
 }
 
-// loadMetadata loads backup metadata from a JSON file
+// loadMetadata loads backup metadata from a JSON file, validating it against
+// the embedded schema and migrating older versions in memory
 func (bm *BackupManager) loadMetadata(backupID string) (*BackupMetadata, error) {
 	metadataFile := filepath.Join(bm.BackupRoot, fmt.Sprintf("%s.json", backupID))
 
 	// #nosec G304 - metadataFile is constructed from validated backup root and ID
-	data, err := os.ReadFile(metadataFile)
+	data, err := ReadFile(bm.fs(), metadataFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read metadata file: %w", err)
 	}
 
-	var metadata BackupMetadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	metadata, _, err := decodeMetadata(metadataFile, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := bm.relocate(metadata); err != nil {
+		return nil, err
 	}
 
-	return &metadata, nil
+	return metadata, nil
 }
 
-// generateBackupID generates a unique backup ID
-// Uses timestamp + random bytes to prevent collisions when creating multiple backups per second
-func generateBackupID() string {
-	timestamp := time.Now().Unix()
+// maxBackupIDAttempts bounds the IDs CreateBackup tries before giving up
+const maxBackupIDAttempts = 5
+
+// lastBackupNanos is the time of the newest ID generateBackupID made, in
+// nanoseconds since the Unix epoch
+var lastBackupNanos atomic.Int64
+
+// generateBackupID generates a backup ID and the creation time it encodes:
+// the nanoseconds since the Unix epoch, zero-padded so IDs sort by time, and
+// random bytes against collisions with other processes. Within a process the
+// times only go forward, even when the clock is coarse or steps back. IDs of
+// older releases used Unix seconds and remain valid.
+func generateBackupID(now time.Time) (string, time.Time) {
+	nanos := now.UnixNano()
+	for {
+		last := lastBackupNanos.Load()
+		if nanos <= last {
+			nanos = last + 1
+		}
+		if lastBackupNanos.CompareAndSwap(last, nanos) {
+			break
+		}
+	}
+
 	randomBytes := make([]byte, 4)
 	// #nosec G104 - crypto/rand.Read only returns error on system failure, which would cause broader issues
 	_, _ = rand.Read(randomBytes)
-	return fmt.Sprintf("backup_%d_%s", timestamp, hex.EncodeToString(randomBytes))
+	return fmt.Sprintf("%s%019d_%s", backupIDPrefix, nanos, hex.EncodeToString(randomBytes)), time.Unix(0, nanos)
+}
+
+// backupExists reports whether the backup root has a directory or metadata file for backupID
+func (bm *BackupManager) backupExists(backupID string) bool {
+	for _, path := range []string{filepath.Join(bm.BackupRoot, backupID), filepath.Join(bm.BackupRoot, backupID+".json")} {
+		if _, err := bm.fs().Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SortBackups orders backups newest first. Backups taken at the same time,
+// as far as their timestamps tell, are ordered by ID.
+func SortBackups(backups []BackupMetadata) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		if !backups[i].Timestamp.Equal(backups[j].Timestamp) {
+			return backups[i].Timestamp.After(backups[j].Timestamp)
+		}
+		return backups[i].ID > backups[j].ID
+	})
+}
+
+// ResolveBackupID returns the backup ID a selector names: LatestBackup
+// selects the newest backup, and anything else is taken as an ID
+func (bm *BackupManager) ResolveBackupID(selector string) (string, error) {
+	if selector != LatestBackup {
+		return selector, nil
+	}
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no backups found in %s", bm.BackupRoot)
+	}
+	SortBackups(backups)
+	return backups[0].ID, nil
 }
 
-// copyFile copies a single file
-func copyFile(src, dst string) error {
+// copyFile copies a single file, reporting the bytes copied to progress if it is not nil
+func copyFile(fsys FS, src, dst string, progress Progress) error {
 	// Create parent directories
-	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+	if err := fsys.MkdirAll(filepath.Dir(dst), 0750); err != nil {
 		return err
 	}
 
-	// #nosec G304 - src path is controlled by backup operations
-	srcFile, err := os.Open(src)
+	srcFile, err := Open(fsys, src)
 	if err != nil {
 		return err
 	}
 	defer srcFile.Close()
 
-	// #nosec G304 - dst path is within controlled backup directory
-	dstFile, err := os.Create(dst)
+	dstFile, err := fsys.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
 	}
 	defer dstFile.Close()
 
-	if _, err := srcFile.WriteTo(dstFile); err != nil {
+	if _, err := CopyWithProgress(dstFile, srcFile, progress); err != nil {
 		return err
 	}
 
 	// Copy file permissions
-	srcInfo, err := srcFile.Stat()
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
 		return err
 	}
 
-	return os.Chmod(dst, srcInfo.Mode())
+	return fsys.Chmod(dst, srcInfo.Mode())
 }
 
-// copyDir recursively copies a directory
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+// copyDir recursively copies a directory, reporting the bytes copied to progress if it is not nil
+func copyDir(fsys FS, src, dst string, progress Progress) error {
+	return WalkDir(fsys, src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -294,10 +523,14 @@ func copyDir(src, dst string) error {
 		}
 		dstPath := filepath.Join(dst, relPath)
 
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return fsys.MkdirAll(dstPath, info.Mode().Perm())
 		}
 
-		return copyFile(path, dstPath)
+		return copyFile(fsys, path, dstPath, progress)
 	})
 }
@@ -197,7 +197,6 @@ func TestListBackups(t *testing.T) {
 		t.Fatalf("Failed to create first backup: %v", err)
 	}
 
-	time.Sleep(1001 * time.Millisecond) // Ensure different Unix seconds
 	_, err = bm.CreateBackup("uninstall", "Second backup", []string{testFile})
 	if err != nil {
 		t.Fatalf("Failed to create second backup: %v", err)
@@ -271,6 +270,73 @@ func TestDeleteBackup(t *testing.T) {
 	}
 }
 
+func TestUpdateMetadata(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-backup-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	metadata, err := bm.CreateBackup("install", "Test backup", []string{filepath.Join(tempDir, "missing.json")})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	metadata.AddonUUID = "12345678-1234-1234-1234-123456789abc"
+	metadata.PackUUIDs = []string{metadata.AddonUUID}
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+
+	loaded, err := bm.loadMetadata(metadata.ID)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if loaded.AddonUUID != metadata.AddonUUID || len(loaded.PackUUIDs) != 1 {
+		t.Errorf("Expected updated metadata to be persisted, got %+v", loaded)
+	}
+
+	missing := *metadata
+	missing.ID = "backup_missing"
+	missing.BackupPath = filepath.Join(tempDir, "backups", "backup_missing")
+	if err := bm.UpdateMetadata(&missing); err == nil {
+		t.Error("Expected error updating metadata of a nonexistent backup")
+	}
+}
+
+func TestBackedUpCopy(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-backup-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	existing := filepath.Join(tempDir, "world_behavior_packs.json")
+	if err := os.WriteFile(existing, []byte("[]"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	missing := filepath.Join(tempDir, "world_resource_packs.json")
+
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	metadata, err := bm.CreateBackup("install", "Test backup", []string{existing, missing})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	path, existed, err := bm.BackedUpCopy(metadata, existing)
+	if err != nil || !existed {
+		t.Error("Expected existing file to have a backed up copy")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "[]" {
+		t.Errorf("Expected backed up copy at %s, got %q (err: %v)", path, data, err)
+	}
+
+	if _, existed, err := bm.BackedUpCopy(metadata, missing); err != nil || existed {
+		t.Error("Expected missing file to be reported as not existing at backup time")
+	}
+}
+
 func TestBackupMetadataJSON(t *testing.T) {
 	// Test metadata serialization/deserialization
 	metadata := BackupMetadata{
@@ -314,13 +380,12 @@ func TestBackupMetadataJSON(t *testing.T) {
 }
 
 func TestGenerateBackupID(t *testing.T) {
-	// Test that generateBackupID produces unique IDs
-	id1 := generateBackupID()
-	time.Sleep(1001 * time.Millisecond) // Ensure different Unix seconds
-	id2 := generateBackupID()
-
-	if id1 == id2 {
-		t.Error("generateBackupID should produce unique IDs")
+	// IDs made at the same moment still differ and sort in creation order
+	now := time.Now()
+	id1, time1 := generateBackupID(now)
+	id2, time2 := generateBackupID(now)
+	if id1 == id2 || id2 <= id1 || !time2.After(time1) {
+		t.Errorf("Expected increasing IDs and times, got %s at %v and %s at %v", id1, time1, id2, time2)
 	}
 
 	// Test that IDs follow expected format
@@ -330,6 +395,60 @@ func TestGenerateBackupID(t *testing.T) {
 	}
 }
 
+func TestResolveBackupID(t *testing.T) {
+	tempDir := t.TempDir()
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	if _, err := bm.ResolveBackupID(LatestBackup); err == nil {
+		t.Error("Expected latest without backups to fail")
+	}
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("test content"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// A backup with an ID of an older release, taken before the others
+	legacy := filepath.Join(bm.BackupRoot, "backup_1704164645_0a0b0c0d")
+	if err := os.MkdirAll(legacy, 0750); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	data := `{"schema_version": 2, "id": "backup_1704164645_0a0b0c0d", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "server_path": "", "backup_path": "` + legacy + `", "files": []}`
+	if err := os.WriteFile(legacy+".json", []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	// Backups taken back to back, without waiting for the clock
+	var ids []string
+	for i := 0; i < 5; i++ {
+		metadata, err := bm.CreateBackup("install", "Backup", []string{testFile})
+		if err != nil {
+			t.Fatalf("CreateBackup failed: %v", err)
+		}
+		ids = append(ids, metadata.ID)
+	}
+
+	latest, err := bm.ResolveBackupID(LatestBackup)
+	if err != nil || latest != ids[len(ids)-1] {
+		t.Errorf("ResolveBackupID(latest) = %s, %v, want %s", latest, err, ids[len(ids)-1])
+	}
+	if id, err := bm.ResolveBackupID(ids[0]); err != nil || id != ids[0] {
+		t.Errorf("ResolveBackupID(%s) = %s, %v", ids[0], id, err)
+	}
+
+	backups, err := bm.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	SortBackups(backups)
+	if len(backups) != len(ids)+1 || backups[len(backups)-1].ID != "backup_1704164645_0a0b0c0d" {
+		t.Fatalf("Expected %d backups, the legacy one last, got %+v", len(ids)+1, backups)
+	}
+	for i, backup := range backups[:len(ids)] {
+		if want := ids[len(ids)-1-i]; backup.ID != want {
+			t.Errorf("Backup %d = %s, want %s", i, backup.ID, want)
+		}
+	}
+}
+
 // Helper function for string contains check
 func contains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
@@ -339,3 +458,119 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestBackupManagerMemFS(t *testing.T) {
+	m := NewMemFS()
+	if err := m.MkdirAll("/server/packs/pack", 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	manifest := "/server/packs/pack/manifest.json"
+	config := "/server/world_behavior_packs.json"
+	if err := WriteFile(m, manifest, []byte(`{"v": 1}`), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if err := WriteFile(m, config, []byte(`[]`), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	bm := NewBackupManager("/backups")
+	bm.FS = m
+	metadata, err := bm.CreateBackup("install", "MemFS backup", []string{"/server/packs/pack", config, "/server/absent.json"})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	// Change, add, and remove files after the backup
+	if err := WriteFile(m, manifest, []byte(`{"v": 2}`), 0600); err != nil {
+		t.Fatalf("Failed to edit manifest: %v", err)
+	}
+	if err := WriteFile(m, "/server/packs/pack/extra.json", []byte(`{}`), 0600); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	if err := m.Remove(config); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	if err := WriteFile(m, "/server/absent.json", []byte(`{}`), 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	plan, err := bm.PlanRestore(metadata.ID)
+	if err != nil {
+		t.Fatalf("Failed to plan restore: %v", err)
+	}
+	if len(plan.Changes) != 4 {
+		t.Errorf("Expected 4 planned changes, got %+v", plan.Changes)
+	}
+
+	if err := bm.RestoreBackup(metadata.ID); err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+	if data, err := ReadFile(m, manifest); err != nil || string(data) != `{"v": 1}` {
+		t.Errorf("Expected the manifest to be restored, got %q, %v", data, err)
+	}
+	if _, err := m.Stat(config); err != nil {
+		t.Errorf("Expected the removed config to be restored: %v", err)
+	}
+	for _, path := range []string{"/server/packs/pack/extra.json", "/server/absent.json"} {
+		if _, err := m.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed by the restore, got %v", path, err)
+		}
+	}
+
+	backups, err := bm.ListBackups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %d, %v", len(backups), err)
+	}
+	if err := bm.DeleteBackup(metadata.ID); err != nil {
+		t.Fatalf("Failed to delete backup: %v", err)
+	}
+	if entries, _ := m.ReadDir("/backups"); len(entries) != 0 {
+		t.Errorf("Expected the backup root to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestBackupSameNamedFiles(t *testing.T) {
+	m := NewMemFS()
+	packs := map[string]string{"/server/behavior_packs/pack/manifest.json": "behavior", "/server/resource_packs/pack/manifest.json": "resource"}
+	for path, content := range packs {
+		if err := m.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create pack dir: %v", err)
+		}
+		if err := WriteFile(m, path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	files := []string{"/server/behavior_packs/pack", "/server/resource_packs/pack"}
+
+	// Copies kept under their base names would collide
+	bm := NewBackupManager("/backups")
+	bm.FS = m
+	if _, err := bm.CreateBackup("clean", "Same names", files); err == nil || !contains(err.Error(), "would overwrite") {
+		t.Errorf("Expected the colliding copies to fail the backup, got %v", err)
+	}
+
+	bm.ServerRoot = "/server"
+	for _, incremental := range []bool{false, true} {
+		bm.Incremental = incremental
+		metadata, err := bm.CreateBackup("clean", "Same names", files)
+		if err != nil {
+			t.Fatalf("Failed to create backup (incremental %v): %v", incremental, err)
+		}
+		if metadata.ServerPath != "/server" || metadata.Layout != LayoutServerRelative {
+			t.Errorf("Expected a server-relative backup of /server, got %q of %q", metadata.Layout, metadata.ServerPath)
+		}
+		for _, file := range files {
+			if err := m.RemoveAll(file); err != nil {
+				t.Fatalf("Failed to remove %s: %v", file, err)
+			}
+		}
+		if err := bm.RestoreBackup(metadata.ID); err != nil {
+			t.Fatalf("Failed to restore backup (incremental %v): %v", incremental, err)
+		}
+		for path, content := range packs {
+			if data, err := ReadFile(m, path); err != nil || string(data) != content {
+				t.Errorf("Expected %s restored as %q, got %q, %v", path, content, data, err)
+			}
+		}
+	}
+}
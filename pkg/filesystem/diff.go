@@ -0,0 +1,112 @@
+package filesystem
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines are shown around each change
+const diffContextLines = 3
+
+// LineDiff returns a unified-style line diff between two texts, or an empty
+// string when they are identical. Lines are prefixed with "-", "+", or " ".
+func LineDiff(from, to string, fromName, toName string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	ops := diffLines(a, b)
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Only print unchanged lines within diffContextLines of a change
+	lastPrinted := -1
+	for i, op := range ops {
+		if op.kind == ' ' && !nearChange(ops, i) {
+			continue
+		}
+		if lastPrinted >= 0 && i > lastPrinted+1 {
+			sb.WriteString("@@\n")
+		}
+		fmt.Fprintf(&sb, "%c%s\n", op.kind, op.line)
+		lastPrinted = i
+	}
+
+	return sb.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-', or '+'
+	line string
+}
+
+// diffLines computes a minimal edit script using the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// nearChange reports whether ops[i] is within diffContextLines of a changed line
+func nearChange(ops []diffOp, i int) bool {
+	for k := i - diffContextLines; k <= i+diffContextLines; k++ {
+		if k >= 0 && k < len(ops) && ops[k].kind != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DiskSpace is what is left on the filesystem volume holding a path
+type DiskSpace struct {
+	Bytes int64 // Bytes available to unprivileged users
+	Files int64 // Free inodes, or -1 when the volume has no fixed inode count
+}
+
+// Fits reports whether files more files totalling bytes fit on the volume
+func (s DiskSpace) Fits(bytes, files int64) bool {
+	return bytes <= s.Bytes && (s.Files < 0 || files <= s.Files)
+}
+
+// InsufficientSpaceError reports a volume without room for a write
+type InsufficientSpaceError struct {
+	Path           string
+	NeededBytes    int64
+	NeededFiles    int64
+	AvailableSpace DiskSpace
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	if e.NeededBytes > e.AvailableSpace.Bytes {
+		return fmt.Sprintf("not enough disk space on %s: %s needed, %s available",
+			e.Path, FormatSize(e.NeededBytes), FormatSize(e.AvailableSpace.Bytes))
+	}
+	return fmt.Sprintf("not enough free inodes on %s: %d files needed, %d available",
+		e.Path, e.NeededFiles, e.AvailableSpace.Files)
+}
+
+// CheckFreeSpace returns an InsufficientSpaceError when the volume holding
+// path has less than bytes available or fewer than files free inodes. It
+// passes on platforms that can't report free space.
+func CheckFreeSpace(path string, bytes, files int64) error {
+	space, err := FreeSpace(path)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check free space on %s: %w", path, err)
+	}
+	if !space.Fits(bytes, files) {
+		return &InsufficientSpaceError{Path: path, NeededBytes: bytes, NeededFiles: files, AvailableSpace: space}
+	}
+	return nil
+}
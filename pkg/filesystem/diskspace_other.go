@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package filesystem
+
+import "errors"
+
+// FreeSpace is not available here; CheckFreeSpace lets every write through
+func FreeSpace(path string) (DiskSpace, error) {
+	return DiskSpace{}, errors.ErrUnsupported
+}
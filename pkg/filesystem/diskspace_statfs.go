@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd
+
+package filesystem
+
+import "syscall"
+
+// FreeSpace returns the space left on the volume holding path
+func FreeSpace(path string) (DiskSpace, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return DiskSpace{}, err
+	}
+	space := DiskSpace{
+		Bytes: int64(uint64(stat.Bavail) * uint64(stat.Bsize)), // #nosec G115 - volumes are far below 8 EB
+		Files: int64(stat.Ffree),                               // #nosec G115 - inode counts fit
+	}
+	if stat.Files == 0 {
+		space.Files = -1 // Inodes are allocated on demand, as on btrfs
+	}
+	return space, nil
+}
@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDiskSpaceFits(t *testing.T) {
+	tests := []struct {
+		name     string
+		space    DiskSpace
+		bytes    int64
+		files    int64
+		expected bool
+	}{
+		{"room for both", DiskSpace{Bytes: 1000, Files: 10}, 1000, 10, true},
+		{"too many bytes", DiskSpace{Bytes: 1000, Files: 10}, 1001, 1, false},
+		{"too many files", DiskSpace{Bytes: 1000, Files: 10}, 1, 11, false},
+		{"no fixed inode count", DiskSpace{Bytes: 1000, Files: -1}, 1000, 1000000, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.space.Fits(tt.bytes, tt.files); got != tt.expected {
+				t.Errorf("Fits(%d, %d) = %v, want %v", tt.bytes, tt.files, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInsufficientSpaceError(t *testing.T) {
+	err := &InsufficientSpaceError{Path: "/srv", NeededBytes: 3 * 1024 * 1024, NeededFiles: 5, AvailableSpace: DiskSpace{Bytes: 1024 * 1024, Files: 100}}
+	if got, want := err.Error(), "not enough disk space on /srv: 3.0 MB needed, 1.0 MB available"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	err = &InsufficientSpaceError{Path: "/srv", NeededBytes: 10, NeededFiles: 500, AvailableSpace: DiskSpace{Bytes: 1024, Files: 20}}
+	if got, want := err.Error(), "not enough free inodes on /srv: 500 files needed, 20 available"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+	space, err := FreeSpace(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("free space is not reported on this platform")
+	}
+	if err != nil {
+		t.Fatalf("FreeSpace failed: %v", err)
+	}
+	if space.Bytes <= 0 {
+		t.Fatalf("FreeSpace = %+v, want available bytes", space)
+	}
+
+	if err := CheckFreeSpace(dir, 1, 1); err != nil {
+		t.Errorf("CheckFreeSpace for one byte failed: %v", err)
+	}
+	err = CheckFreeSpace(dir, space.Bytes+1<<40, 1)
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("CheckFreeSpace beyond the volume = %v, want InsufficientSpaceError", err)
+	}
+	if !strings.Contains(err.Error(), dir) {
+		t.Errorf("error %q does not name %s", err, dir)
+	}
+}
@@ -0,0 +1,139 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FS is the filesystem backups, archive extraction, and pack copies go
+// through. OSFS is the real filesystem; MemFS keeps everything in memory so
+// these pipelines can be tested without touching disk, and other backends
+// can be plugged in without changing the callers.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error) // Sorted by name
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Chmod(name string, mode os.FileMode) error
+}
+
+// File is an open file of an FS
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+}
+
+// OSFS is the FS of the operating system
+type OSFS struct{}
+
+func (OSFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	// #nosec G304 - callers open paths inside server, backup, or extraction directories
+	return os.OpenFile(name, flag, perm)
+}
+
+func (OSFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) ReadDir(name string) ([]os.DirEntry, error)   { return os.ReadDir(name) }
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+func (OSFS) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+
+// Chmod does nothing on Windows, where a mode only sets the read-only
+// attribute, which would keep the file from being replaced or removed later
+func (OSFS) Chmod(name string, mode os.FileMode) error {
+	if HostPathStyle.Windows {
+		return nil
+	}
+	return os.Chmod(name, mode)
+}
+
+// fsOrOS returns fsys, or the real filesystem when it is nil
+func fsOrOS(fsys FS) FS {
+	if fsys == nil {
+		return OSFS{}
+	}
+	return fsys
+}
+
+// Open opens a file of fsys for reading
+func Open(fsys FS, name string) (File, error) {
+	return fsOrOS(fsys).OpenFile(name, os.O_RDONLY, 0)
+}
+
+// ReadFile reads a whole file of fsys
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	file, err := Open(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// WriteFile writes data to a file of fsys, creating or truncating it
+func WriteFile(fsys FS, name string, data []byte, perm os.FileMode) error {
+	file, err := fsOrOS(fsys).OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WalkDir walks the tree rooted at root like filepath.WalkDir, in lexical
+// order, but through fsys. Symlinks are reported, not followed.
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	fsys = fsOrOS(fsys)
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Report the failure to read the directory, as filepath.WalkDir does
+		if err = fn(path, d, err); err != nil {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		if err := walkDir(fsys, filepath.Join(path, entry.Name()), entry, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// HashFile returns the hex-encoded SHA-256 digest of a file's contents
+func HashFile(path string) (string, error) {
+	return hashFile(nil, path)
+}
+
+// hashFile hashes a file of fsys; a nil fsys is the real filesystem
+func hashFile(fsys FS, path string) (string, error) {
+	file, err := Open(fsys, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for hashing: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash file %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// FilesEqual reports whether two files have identical SHA-256 digests
+func FilesEqual(a, b string) (bool, error) {
+	return FilesEqualFS(nil, a, b)
+}
+
+// FilesEqualFS is FilesEqual on fsys; a nil fsys is the real filesystem
+func FilesEqualFS(fsys FS, a, b string) (bool, error) {
+	hashA, err := hashFile(fsys, a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(fsys, b)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+// HashTree returns the SHA-256 digest of every file below root, keyed by its
+// slash-separated path relative to root
+func HashTree(root string) (map[string]string, error) {
+	return HashTreeFS(nil, root)
+}
+
+// HashTreeFS is HashTree on fsys; a nil fsys is the real filesystem
+func HashTreeFS(fsys FS, root string) (map[string]string, error) {
+	files, err := listTree(fsOrOS(fsys), root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+
+	hashes := make(map[string]string, len(files))
+	for _, rel := range files {
+		digest, err := hashFile(fsys, joinTree(root, rel))
+		if err != nil {
+			return nil, err
+		}
+		hashes[filepath.ToSlash(rel)] = digest
+	}
+	return hashes, nil
+}
+
+// TreeDiff lists how the files of one tree differ from another's, by path
+type TreeDiff struct {
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// Empty reports whether the trees have the same files with the same contents
+func (d TreeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffHashes compares two HashTree results, with each list sorted by path
+func DiffHashes(from, to map[string]string) TreeDiff {
+	diff := TreeDiff{Added: make([]string, 0), Removed: make([]string, 0), Changed: make([]string, 0)}
+	for path, digest := range from {
+		toDigest, ok := to[path]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, path)
+		case toDigest != digest:
+			diff.Changed = append(diff.Changed, path)
+		default:
+			diff.Unchanged++
+		}
+	}
+	for path := range to {
+		if _, ok := from[path]; !ok {
+			diff.Added = append(diff.Added, path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
@@ -0,0 +1,115 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-hash-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	hash, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile failed: %v", err)
+	}
+
+	// sha256("hello")
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if hash != expected {
+		t.Errorf("Expected hash %s, got %s", expected, hash)
+	}
+
+	if _, err := HashFile(filepath.Join(tempDir, "missing.txt")); err == nil {
+		t.Error("Expected error hashing a missing file")
+	}
+}
+
+func TestFilesEqual(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-hash-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	a := filepath.Join(tempDir, "a.txt")
+	b := filepath.Join(tempDir, "b.txt")
+	c := filepath.Join(tempDir, "c.txt")
+	for path, content := range map[string]string{a: "same", b: "same", c: "different"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	if equal, err := FilesEqual(a, b); err != nil || !equal {
+		t.Errorf("Expected identical files to be equal, got %v (err: %v)", equal, err)
+	}
+	if equal, err := FilesEqual(a, c); err != nil || equal {
+		t.Errorf("Expected different files to differ, got %v (err: %v)", equal, err)
+	}
+}
+
+func TestHashTreeAndDiffHashes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-hash-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	write := func(root string, files map[string]string) {
+		for rel, content := range files {
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf("Failed to write %s: %v", path, err)
+			}
+		}
+	}
+	from := filepath.Join(tempDir, "from")
+	to := filepath.Join(tempDir, "to")
+	write(from, map[string]string{"manifest.json": "{}", "textures/a.png": "a", "old.txt": "old", "same/b.txt": "b"})
+	write(to, map[string]string{"manifest.json": "{}", "textures/a.png": "A", "new.txt": "new", "same/b.txt": "b"})
+
+	fromHashes, err := HashTree(from)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+	if len(fromHashes) != 4 {
+		t.Fatalf("Expected 4 hashed files, got %v", fromHashes)
+	}
+	if fromHashes["textures/a.png"] == "" {
+		t.Errorf("Expected slash-separated relative paths, got %v", fromHashes)
+	}
+	toHashes, err := HashTree(to)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+
+	diff := DiffHashes(fromHashes, toHashes)
+	if len(diff.Added) != 1 || diff.Added[0] != "new.txt" {
+		t.Errorf("Expected new.txt added, got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "old.txt" {
+		t.Errorf("Expected old.txt removed, got %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0] != "textures/a.png" {
+		t.Errorf("Expected textures/a.png changed, got %v", diff.Changed)
+	}
+	if diff.Unchanged != 2 || diff.Empty() {
+		t.Errorf("Expected 2 unchanged files and a non-empty diff, got %+v", diff)
+	}
+	if !DiffHashes(fromHashes, fromHashes).Empty() {
+		t.Error("Expected a tree to have no differences from itself")
+	}
+}
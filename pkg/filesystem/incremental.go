@@ -0,0 +1,424 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxBackupChain is how many incremental backups GC lets a backup
+// depend on before consolidating it
+const DefaultMaxBackupChain = 4
+
+// materializePrefix starts the temporary directories incremental backups are
+// assembled in for a restore, inside the backup root
+const materializePrefix = ".materialize-"
+
+// An incremental backup only stores the files whose contents differ from its
+// base, the backup taken before it; its checksums still list every file, so
+// the files it leaves out are found by walking the chain of bases. A file is
+// taken from the newest backup of the chain that stores it.
+
+// incrementalBase returns the backup a new incremental backup stores its
+// changes against: the newest backup, when it has checksums and its chain is
+// complete. nil means the backup has to be full.
+func (bm *BackupManager) incrementalBase() (*BackupMetadata, error) {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, nil
+	}
+	SortBackups(backups)
+	base := &backups[0]
+	if base.Checksums == nil {
+		return nil, nil
+	}
+	if _, err := bm.backupChain(base); err != nil {
+		slog.Warn("Taking a full backup instead of an incremental one", "base", base.ID, "error", err)
+		return nil, nil
+	}
+	return base, nil
+}
+
+// backupFileIncremental backs up source like backupFile, but copies only the
+// files whose contents differ from base, recording the digest of every file
+func (bm *BackupManager) backupFileIncremental(source, backupPath string, base, metadata *BackupMetadata, progress Progress) error {
+	sourceInfo, err := bm.fs().Stat(source)
+	if os.IsNotExist(err) {
+		return markMissing(bm.fs(), backupPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	if metadata.Checksums == nil {
+		metadata.Checksums = make(map[string]string)
+	}
+	if !sourceInfo.IsDir() {
+		return bm.backupChangedFile(source, backupPath, base, metadata, progress)
+	}
+
+	// Every directory is created, so the chain only has to supply files
+	return WalkDir(bm.fs(), source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(backupPath, relPath)
+
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return bm.fs().MkdirAll(dstPath, info.Mode().Perm())
+		}
+		return bm.backupChangedFile(path, dstPath, base, metadata, progress)
+	})
+}
+
+// backupChangedFile copies a file into an incremental backup unless base has
+// the same contents for it
+func (bm *BackupManager) backupChangedFile(source, dst string, base, metadata *BackupMetadata, progress Progress) error {
+	digest, err := hashFile(bm.fs(), source)
+	if err != nil {
+		return err
+	}
+	if base.Checksums[source] == digest {
+		metadata.Checksums[source] = digest
+		if info, err := bm.fs().Stat(source); err == nil {
+			progress.Add(info.Size())
+		}
+		return nil
+	}
+
+	if err := copyFile(bm.fs(), source, dst, progress); err != nil {
+		return err
+	}
+	// The copy is what a restore puts back, so its digest is the one recorded
+	if metadata.Checksums[source], err = hashFile(bm.fs(), dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+// backupChain returns a backup followed by its bases, newest first
+func (bm *BackupManager) backupChain(metadata *BackupMetadata) ([]*BackupMetadata, error) {
+	chain := []*BackupMetadata{metadata}
+	seen := map[string]bool{metadata.ID: true}
+	for current := metadata; current.Base != ""; {
+		if seen[current.Base] {
+			return nil, fmt.Errorf("backup %s is part of a cycle of bases", current.Base)
+		}
+		base, err := bm.loadMetadata(current.Base)
+		if err != nil {
+			return nil, fmt.Errorf("base backup %s of %s is unavailable: %w", current.Base, current.ID, err)
+		}
+		seen[base.ID] = true
+		chain = append(chain, base)
+		current = base
+	}
+	return chain, nil
+}
+
+// storedPath returns where a backup keeps originalPath, a file listed in
+// Files or one below a directory listed there. The file is only there when
+// the backup stores it rather than inheriting it from its base.
+func (m *BackupMetadata) storedPath(originalPath string) (string, bool) {
+	file, ok := m.listedFile(originalPath)
+	if !ok {
+		return "", false
+	}
+	return joinTree(filepath.Join(m.BackupPath, m.storedName(file)), relTree(file, originalPath)), true
+}
+
+// listedFile returns the entry of Files that is originalPath or a directory
+// containing it
+func (m *BackupMetadata) listedFile(originalPath string) (string, bool) {
+	for _, file := range m.Files {
+		if originalPath == file || strings.HasPrefix(originalPath, file+string(filepath.Separator)) {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// relTree is the inverse of joinTree
+func relTree(root, path string) string {
+	if path == root {
+		return "."
+	}
+	return strings.TrimPrefix(path, root+string(filepath.Separator))
+}
+
+// stores reports whether a backup holds its own copy of originalPath
+func (bm *BackupManager) stores(m *BackupMetadata, originalPath string) (string, bool) {
+	stored, ok := m.storedPath(originalPath)
+	if !ok {
+		return "", false
+	}
+	if _, err := bm.fs().Stat(stored); err != nil {
+		return "", false
+	}
+	return stored, true
+}
+
+// chainCopy finds the stored copy of originalPath with the given digest in
+// a chain of bases
+func (bm *BackupManager) chainCopy(chain []*BackupMetadata, originalPath, digest string) (string, error) {
+	for _, m := range chain {
+		if m.Checksums[originalPath] != digest {
+			return "", fmt.Errorf("backup %s has other contents for %s than the backups based on it", m.ID, originalPath)
+		}
+		if stored, ok := bm.stores(m, originalPath); ok {
+			return stored, nil
+		}
+	}
+	return "", fmt.Errorf("no backup of the chain stores %s", originalPath)
+}
+
+// materialize returns a directory laid out as the backup's own directory
+// would be if the backup were full. For an incremental backup it is assembled
+// from the chain in a temporary directory of the backup root, which cleanup
+// removes.
+func (bm *BackupManager) materialize(metadata *BackupMetadata) (dir string, cleanup func(), err error) {
+	if metadata.Base == "" {
+		return metadata.BackupPath, func() {}, nil
+	}
+	chain, err := bm.backupChain(metadata)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir = filepath.Join(bm.BackupRoot, materializePrefix+metadata.ID)
+	cleanup = func() {
+		if err := bm.fs().RemoveAll(dir); err != nil {
+			slog.Warn("Failed to remove assembled backup", "path", dir, "error", err)
+		}
+	}
+	if err := bm.fs().RemoveAll(dir); err != nil {
+		return "", nil, fmt.Errorf("failed to clear %s: %w", dir, err)
+	}
+	if err := bm.assemble(metadata, chain, dir); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to assemble backup %s: %w", metadata.ID, err)
+	}
+	return dir, cleanup, nil
+}
+
+// assemble copies the files of an incremental backup from its chain into dir
+func (bm *BackupManager) assemble(metadata *BackupMetadata, chain []*BackupMetadata, dir string) error {
+	if err := bm.fs().MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	for _, originalFile := range metadata.Files {
+		stored := filepath.Join(metadata.BackupPath, metadata.storedName(originalFile))
+		target := filepath.Join(dir, metadata.storedName(originalFile))
+
+		if _, err := bm.fs().Stat(stored + ".missing"); err == nil {
+			if err := markMissing(bm.fs(), target); err != nil {
+				return err
+			}
+			continue
+		}
+		if info, err := bm.fs().Stat(stored); err == nil {
+			copyStored := copyFile
+			if info.IsDir() {
+				copyStored = copyDir
+			}
+			if err := copyStored(bm.fs(), stored, target, nil); err != nil {
+				return err
+			}
+		}
+
+		for path, digest := range metadata.checksumsOf(originalFile) {
+			dst := joinTree(target, relTree(originalFile, path))
+			if _, err := bm.fs().Stat(dst); err == nil {
+				continue
+			}
+			source, err := bm.chainCopy(chain[1:], path, digest)
+			if err != nil {
+				return err
+			}
+			if err := copyFile(bm.fs(), source, dst, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BackedUpCopy returns where the copy of a file listed in Files, or of one
+// below a directory listed there, is stored: in the backup itself or, for an
+// incremental backup, in the backup of its chain that holds it. The boolean
+// is false when the file did not exist when the backup was taken.
+func (bm *BackupManager) BackedUpCopy(metadata *BackupMetadata, originalPath string) (string, bool, error) {
+	file, ok := metadata.listedFile(originalPath)
+	if !ok {
+		return "", false, fmt.Errorf("backup %s does not include %s", metadata.ID, originalPath)
+	}
+	backupPath, _ := metadata.storedPath(originalPath)
+	if _, err := bm.fs().Stat(filepath.Join(metadata.BackupPath, metadata.storedName(file)) + ".missing"); err == nil {
+		return backupPath, false, nil
+	}
+	if originalPath != file && metadata.Checksums != nil {
+		if _, ok := metadata.Checksums[originalPath]; !ok {
+			return backupPath, false, nil
+		}
+	}
+	if metadata.Base == "" {
+		if originalPath != file {
+			if _, err := bm.fs().Stat(backupPath); os.IsNotExist(err) {
+				return backupPath, false, nil
+			}
+		}
+		return backupPath, true, nil
+	}
+	if stored, ok := bm.stores(metadata, originalPath); ok {
+		return stored, true, nil
+	}
+
+	chain, err := bm.backupChain(metadata)
+	if err != nil {
+		return "", false, err
+	}
+	stored, err := bm.chainCopy(chain[1:], originalPath, metadata.Checksums[originalPath])
+	if err != nil {
+		return "", false, err
+	}
+	return stored, true, nil
+}
+
+// detachDependents makes the backups based on removed independent of it
+// before it is deleted: each takes over the files it inherited from removed,
+// and is rebased onto removed's own base
+func (bm *BackupManager) detachDependents(removed *BackupMetadata) error {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return err
+	}
+	for i := range backups {
+		dependent := &backups[i]
+		if dependent.Base != removed.ID {
+			continue
+		}
+		for path := range dependent.Checksums {
+			if _, ok := bm.stores(dependent, path); ok {
+				continue
+			}
+			source, ok := bm.stores(removed, path)
+			if !ok {
+				continue // Further down the chain, which the dependent keeps
+			}
+			target, _ := dependent.storedPath(path)
+			if err := copyFile(bm.fs(), source, target, nil); err != nil {
+				return fmt.Errorf("failed to move %s into backup %s: %w", path, dependent.ID, err)
+			}
+		}
+		dependent.Base = removed.Base
+		if err := bm.saveMetadata(dependent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consolidate copies every file an incremental backup inherits from its
+// chain into the backup, making it full
+func (bm *BackupManager) consolidate(metadata *BackupMetadata) error {
+	chain, err := bm.backupChain(metadata)
+	if err != nil {
+		return err
+	}
+	for path, digest := range metadata.Checksums {
+		if _, ok := bm.stores(metadata, path); ok {
+			continue
+		}
+		source, err := bm.chainCopy(chain[1:], path, digest)
+		if err != nil {
+			return err
+		}
+		target, _ := metadata.storedPath(path)
+		if err := copyFile(bm.fs(), source, target, nil); err != nil {
+			return fmt.Errorf("failed to copy %s into backup %s: %w", path, metadata.ID, err)
+		}
+	}
+	metadata.Base = ""
+	return bm.saveMetadata(metadata)
+}
+
+// BackupGCResult is the result of consolidating backup chains
+type BackupGCResult struct {
+	Consolidated []string `json:"consolidated"`      // Incremental backups made full
+	Broken       []string `json:"broken,omitempty"`  // Incremental backups missing a backup of their chain
+	Cleaned      []string `json:"cleaned,omitempty"` // Leftover directories of interrupted restores removed
+}
+
+// GC consolidates backup chains so no backup depends on more than maxChain
+// incremental backups: oldest first, a backup that would is made full, which
+// shortens the chains of the backups based on it too. Backups whose chain is
+// broken can't be repaired and are only reported; fsck quarantines them.
+// With dryRun nothing changes.
+func (bm *BackupManager) GC(maxChain int, dryRun bool) (*BackupGCResult, error) {
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	// Oldest first, so every base comes before the backups based on it
+	SortBackups(backups)
+	for i, j := 0, len(backups)-1; i < j; i, j = i+1, j-1 {
+		backups[i], backups[j] = backups[j], backups[i]
+	}
+
+	result := &BackupGCResult{Consolidated: make([]string, 0)}
+	depth := make(map[string]int, len(backups))
+	for i := range backups {
+		backup := &backups[i]
+		if backup.Base == "" {
+			depth[backup.ID] = 0
+			continue
+		}
+		baseDepth, ok := depth[backup.Base]
+		if !ok {
+			result.Broken = append(result.Broken, backup.ID)
+			continue
+		}
+		if baseDepth+1 <= maxChain {
+			depth[backup.ID] = baseDepth + 1
+			continue
+		}
+		if !dryRun {
+			if err := bm.consolidate(backup); err != nil {
+				return result, fmt.Errorf("failed to consolidate backup %s: %w", backup.ID, err)
+			}
+		}
+		depth[backup.ID] = 0
+		result.Consolidated = append(result.Consolidated, backup.ID)
+	}
+
+	entries, err := bm.fs().ReadDir(bm.BackupRoot)
+	if err != nil {
+		return result, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), materializePrefix) {
+			continue
+		}
+		dir := filepath.Join(bm.BackupRoot, entry.Name())
+		if !dryRun {
+			if err := bm.fs().RemoveAll(dir); err != nil {
+				return result, fmt.Errorf("failed to remove %s: %w", dir, err)
+			}
+		}
+		result.Cleaned = append(result.Cleaned, dir)
+	}
+	return result, nil
+}
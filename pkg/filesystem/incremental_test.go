@@ -0,0 +1,248 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// incrementalTestServer writes a config file and a pack directory, returning
+// their paths
+func incrementalTestServer(t *testing.T) (string, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "world_behavior_packs.json")
+	packDir := filepath.Join(tempDir, "pack")
+	writeTestFile(t, configFile, "[]")
+	writeTestFile(t, filepath.Join(packDir, "manifest.json"), `{"v": 1}`)
+	writeTestFile(t, filepath.Join(packDir, "scripts", "main.js"), "main")
+	if err := os.MkdirAll(filepath.Join(packDir, "empty"), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	return configFile, packDir
+}
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func expectFile(t *testing.T, path, data string) {
+	t.Helper()
+	if got, err := os.ReadFile(path); err != nil || string(got) != data {
+		t.Errorf("%s = %q, %v, want %q", path, got, err, data)
+	}
+}
+
+func TestIncrementalBackupRestore(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+	files := []string{configFile, packDir}
+
+	full, err := bm.CreateBackup("install", "First", files)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if full.Base != "" {
+		t.Fatalf("Expected the first backup to be full, got base %s", full.Base)
+	}
+
+	writeTestFile(t, filepath.Join(packDir, "manifest.json"), `{"v": 2}`)
+	incremental, err := bm.CreateBackup("install", "Second", files)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if incremental.Base != full.ID {
+		t.Fatalf("Expected the second backup based on %s, got %q", full.ID, incremental.Base)
+	}
+	// Only the changed file is stored, though every file is checksummed
+	stored, err := HashTree(incremental.BackupPath)
+	if err != nil {
+		t.Fatalf("HashTree failed: %v", err)
+	}
+	if len(stored) != 1 || stored["pack/manifest.json"] == "" {
+		t.Errorf("Expected only the changed manifest stored, got %v", stored)
+	}
+	if len(incremental.Checksums) != 3 {
+		t.Errorf("Expected checksums of all 3 files, got %v", incremental.Checksums)
+	}
+
+	// The server moves on, then goes back to the second backup
+	writeTestFile(t, configFile, `[{"pack_id": "a"}]`)
+	writeTestFile(t, filepath.Join(packDir, "scripts", "main.js"), "changed")
+	writeTestFile(t, filepath.Join(packDir, "extra.js"), "extra")
+
+	plan, err := bm.PlanRestore(incremental.ID)
+	if err != nil {
+		t.Fatalf("PlanRestore failed: %v", err)
+	}
+	if len(plan.Changes) != 3 {
+		t.Errorf("Expected 3 changes, got %+v", plan.Changes)
+	}
+	if err := bm.RestoreBackup(incremental.ID); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	expectFile(t, configFile, "[]")
+	expectFile(t, filepath.Join(packDir, "manifest.json"), `{"v": 2}`)
+	expectFile(t, filepath.Join(packDir, "scripts", "main.js"), "main")
+	if _, err := os.Stat(filepath.Join(packDir, "extra.js")); !os.IsNotExist(err) {
+		t.Errorf("Expected the file added since the backup removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(packDir, "empty")); err != nil {
+		t.Errorf("Expected the empty directory restored: %v", err)
+	}
+	if verification, err := bm.VerifyRestore(incremental.ID); err != nil || len(verification.Problems) != 0 {
+		t.Errorf("VerifyRestore = %+v, %v", verification, err)
+	}
+
+	// The unchanged config is read from the full backup
+	path, existed, err := bm.BackedUpCopy(incremental, configFile)
+	if err != nil || !existed || filepath.Dir(path) != full.BackupPath {
+		t.Errorf("BackedUpCopy = %s, %v, %v, want the copy in %s", path, existed, err, full.BackupPath)
+	}
+	entries, _ := os.ReadDir(bm.BackupRoot)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), materializePrefix) {
+			t.Errorf("Expected the assembled backup removed, found %s", entry.Name())
+		}
+	}
+}
+
+func TestDeleteBackupDetachesDependents(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+	files := []string{configFile, packDir}
+
+	full, err := bm.CreateBackup("install", "First", files)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	writeTestFile(t, configFile, `[{"pack_id": "a"}]`)
+	incremental, err := bm.CreateBackup("install", "Second", files)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	if err := bm.DeleteBackup(full.ID); err != nil {
+		t.Fatalf("DeleteBackup failed: %v", err)
+	}
+	detached, err := bm.loadMetadata(incremental.ID)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if detached.Base != "" {
+		t.Errorf("Expected the dependent rebased onto nothing, got base %s", detached.Base)
+	}
+
+	if err := os.RemoveAll(packDir); err != nil {
+		t.Fatalf("Failed to remove pack: %v", err)
+	}
+	if err := bm.RestoreBackup(incremental.ID); err != nil {
+		t.Fatalf("RestoreBackup after deleting the base failed: %v", err)
+	}
+	expectFile(t, configFile, `[{"pack_id": "a"}]`)
+	expectFile(t, filepath.Join(packDir, "scripts", "main.js"), "main")
+}
+
+func TestBackupGC(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		writeTestFile(t, configFile, string(rune('a'+i)))
+		metadata, err := bm.CreateBackup("install", "Backup", []string{configFile, packDir})
+		if err != nil {
+			t.Fatalf("CreateBackup failed: %v", err)
+		}
+		ids = append(ids, metadata.ID)
+	}
+	leftover := filepath.Join(bm.BackupRoot, materializePrefix+"backup_1_x")
+	writeTestFile(t, filepath.Join(leftover, "file"), "x")
+
+	result, err := bm.GC(1, true)
+	if err != nil {
+		t.Fatalf("GC dry run failed: %v", err)
+	}
+	if len(result.Consolidated) != 1 || result.Consolidated[0] != ids[2] || len(result.Cleaned) != 1 {
+		t.Errorf("GC dry run = %+v, want %s consolidated and 1 directory cleaned", result, ids[2])
+	}
+	if metadata, _ := bm.loadMetadata(ids[2]); metadata.Base != ids[1] {
+		t.Errorf("Expected a dry run to change nothing, got base %q", metadata.Base)
+	}
+
+	if _, err := bm.GC(1, false); err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("Expected the leftover directory removed, got %v", err)
+	}
+	bases := make(map[string]string)
+	for _, id := range ids {
+		metadata, err := bm.loadMetadata(id)
+		if err != nil {
+			t.Fatalf("Failed to load metadata: %v", err)
+		}
+		bases[id] = metadata.Base
+	}
+	if bases[ids[0]] != "" || bases[ids[1]] != ids[0] || bases[ids[2]] != "" || bases[ids[3]] != ids[2] {
+		t.Errorf("Expected chains of at most one incremental backup, got bases %v", bases)
+	}
+
+	for i, id := range ids {
+		if err := bm.RestoreBackup(id); err != nil {
+			t.Fatalf("RestoreBackup(%s) failed: %v", id, err)
+		}
+		expectFile(t, configFile, string(rune('a'+i)))
+		expectFile(t, filepath.Join(packDir, "manifest.json"), `{"v": 1}`)
+	}
+}
+
+func TestCheckBackupsBrokenChain(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+
+	full, err := bm.CreateBackup("install", "First", []string{configFile, packDir})
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	incremental, err := bm.CreateBackup("install", "Second", []string{configFile, packDir})
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	// Removed behind blockbench's back, without detaching the dependent
+	if err := os.Remove(filepath.Join(bm.BackupRoot, full.ID+".json")); err != nil {
+		t.Fatalf("Failed to remove metadata: %v", err)
+	}
+
+	checks, err := bm.CheckBackups()
+	if err != nil {
+		t.Fatalf("CheckBackups failed: %v", err)
+	}
+	if len(checks) != 1 || checks[0].ID != incremental.ID || checks[0].Status != MetadataCorrupt {
+		t.Errorf("Expected %s reported corrupt, got %+v", incremental.ID, checks)
+	}
+	if err := bm.RestoreBackup(incremental.ID); err == nil {
+		t.Error("Expected restoring a backup with a broken chain to fail")
+	}
+	result, err := bm.GC(DefaultMaxBackupChain, true)
+	if err != nil || len(result.Broken) != 1 {
+		t.Errorf("GC = %+v, %v, want the backup reported broken", result, err)
+	}
+
+	// A new incremental backup is not based on the broken one
+	next, err := bm.CreateBackup("install", "Third", []string{configFile})
+	if err != nil || next.Base != "" {
+		t.Errorf("CreateBackup = %+v, %v, want a full backup", next, err)
+	}
+}
@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS()
+
+	if err := WriteFile(m, "/missing/file.txt", []byte("x"), 0600); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing parent to fail with not-exist, got %v", err)
+	}
+
+	if err := m.MkdirAll("/a/b", 0750); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := WriteFile(m, "/a/b/file.txt", []byte("hello"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := m.MkdirAll("/a/b/file.txt/c", 0750); err == nil {
+		t.Error("Expected MkdirAll through a file to fail")
+	}
+
+	data, err := ReadFile(m, "/a/b/file.txt")
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Expected to read back %q, got %q, %v", "hello", data, err)
+	}
+	info, err := m.Stat("/a/b/file.txt")
+	if err != nil || info.Size() != 5 || info.Mode().Perm() != 0600 || info.IsDir() {
+		t.Fatalf("Unexpected file info %+v, %v", info, err)
+	}
+
+	// Appending extends the file, truncating empties it
+	file, err := m.OpenFile("/a/b/file.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if _, err := file.Write([]byte(" world")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	file.Close()
+	if data, _ := ReadFile(m, "/a/b/file.txt"); string(data) != "hello world" {
+		t.Errorf("Expected appended contents, got %q", data)
+	}
+	if _, err := m.OpenFile("/a/b/file.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); !os.IsExist(err) {
+		t.Errorf("Expected O_EXCL on an existing file to fail with exist, got %v", err)
+	}
+
+	if err := m.Remove("/a/b"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	before, _ := m.Stat("/a")
+	if err := m.Rename("/a/b", "/a/moved"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if after, _ := m.Stat("/a"); !after.ModTime().After(before.ModTime()) {
+		t.Error("Expected renaming an entry to update its directory's modification time")
+	}
+	if _, err := m.Stat("/a/moved/file.txt"); err != nil {
+		t.Errorf("Expected children to move with their directory: %v", err)
+	}
+	if _, err := m.Stat("/a/b/file.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected the old path to be gone, got %v", err)
+	}
+
+	if err := m.RemoveAll("/a"); err != nil {
+		t.Fatalf("RemoveAll failed: %v", err)
+	}
+	if entries, err := m.ReadDir("/"); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an empty root, got %v, %v", entries, err)
+	}
+}
+
+func TestWalkDirMemFS(t *testing.T) {
+	m := NewMemFS()
+	for path, content := range map[string]string{
+		"/root/b.txt":          "b",
+		"/root/a/one.txt":      "1",
+		"/root/a/two.txt":      "22",
+		"/root/skip/three.txt": "333",
+	} {
+		if err := m.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := WriteFile(m, path, []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	var visited []string
+	err := WalkDir(m, "/root", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir failed: %v", err)
+	}
+
+	expected := []string{"/root", "/root/a", "/root/a/one.txt", "/root/a/two.txt", "/root/b.txt"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected lexical walk %v, got %v", expected, visited)
+	}
+
+	if size, err := TreeSizeFS(m, "/root"); err != nil || size != 7 {
+		t.Errorf("Expected tree size 7, got %d, %v", size, err)
+	}
+}
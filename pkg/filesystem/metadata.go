@@ -0,0 +1,228 @@
+package filesystem
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/makutaku/blockbench/pkg/validation"
+)
+
+//go:embed schemas/backup_metadata.json
+var backupMetadataSchemaData []byte
+
+// BackupMetadataVersion is the schema version written to new backup metadata files
+const BackupMetadataVersion = 4
+
+// backupIDPrefix starts every ID made by generateBackupID
+const backupIDPrefix = "backup_"
+
+// QuarantineDir is where fsck moves corrupt entries, relative to the directory they were found in
+const QuarantineDir = "quarantine"
+
+// Metadata check statuses
+const (
+	MetadataOK      = "ok"
+	MetadataMigrate = "migrate" // Valid, but written by an older version
+	MetadataCorrupt = "corrupt"
+)
+
+// MetadataCheck is the fsck result for one backup metadata file
+type MetadataCheck struct {
+	ID       string   `json:"id"`
+	File     string   `json:"file"`
+	Status   string   `json:"status"`
+	Version  int      `json:"version"`
+	Problems []string `json:"problems,omitempty"`
+	Action   string   `json:"action,omitempty"` // What a repair did: "migrated" or "quarantined"
+}
+
+// MetadataError reports a backup metadata file that does not match its schema
+type MetadataError struct {
+	File     string
+	Problems []string
+}
+
+func (e *MetadataError) Error() string {
+	return fmt.Sprintf("invalid backup metadata %s: %s (run 'blockbench state fsck' to repair)",
+		e.File, strings.Join(e.Problems, "; "))
+}
+
+// decodeMetadata validates a metadata file against the embedded schema and
+// migrates older versions. migrated reports whether the in-memory result
+// differs from what is stored on disk.
+func decodeMetadata(file string, data []byte) (metadata *BackupMetadata, migrated bool, err error) {
+	schema, err := validation.ParseRecordSchema(backupMetadataSchemaData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if problems := schema.Validate(data); len(problems) > 0 {
+		return nil, false, &MetadataError{File: file, Problems: problems}
+	}
+
+	version, err := validation.SchemaVersionOf(data)
+	if err != nil {
+		return nil, false, &MetadataError{File: file, Problems: []string{err.Error()}}
+	}
+	if version < 0 {
+		return nil, false, &MetadataError{File: file, Problems: []string{fmt.Sprintf("schema_version: %d is negative", version)}}
+	}
+	if version > BackupMetadataVersion {
+		return nil, false, fmt.Errorf("backup metadata %s has schema version %d, newer than supported version %d; upgrade blockbench",
+			file, version, BackupMetadataVersion)
+	}
+
+	var decoded BackupMetadata
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, false, &MetadataError{File: file, Problems: []string{err.Error()}}
+	}
+
+	migrated = migrateMetadata(&decoded)
+	return &decoded, migrated, nil
+}
+
+// metadataMigrations upgrades metadata one schema version at a time: the
+// migration at index i takes version i to version i+1. A format change adds
+// its migration here and bumps BackupMetadataVersion, so backups written by
+// any older release keep loading.
+var metadataMigrations = []func(metadata *BackupMetadata){
+	// Version 0 predates pack_uuids; the addon UUID was the only pack recorded
+	func(metadata *BackupMetadata) {
+		if len(metadata.PackUUIDs) == 0 && metadata.AddonUUID != "" {
+			metadata.PackUUIDs = []string{metadata.AddonUUID}
+		}
+	},
+	// Version 1 predates checksums, which can't be recovered; restores of
+	// these backups are verified against the backed-up copies instead
+	func(metadata *BackupMetadata) {},
+	// Version 2 predates incremental backups; every backup is full. Newer
+	// versions are refused by older releases, which would restore an
+	// incremental backup as if it were full.
+	func(metadata *BackupMetadata) {},
+	// Version 3 predates layouts; an empty layout keeps copies under their
+	// base names. Older releases would look for the copies of a
+	// server-relative backup there, so they have to refuse it.
+	func(metadata *BackupMetadata) {},
+}
+
+// migrateMetadata upgrades metadata in place to BackupMetadataVersion and reports whether anything changed
+func migrateMetadata(metadata *BackupMetadata) bool {
+	if metadata.SchemaVersion >= BackupMetadataVersion {
+		return false
+	}
+
+	for version := metadata.SchemaVersion; version < BackupMetadataVersion; version++ {
+		metadataMigrations[version](metadata)
+	}
+	metadata.SchemaVersion = BackupMetadataVersion
+	return true
+}
+
+// CheckBackups validates every metadata file in the backup root without changing anything.
+// Metadata whose backup directory is missing is reported as corrupt.
+func (bm *BackupManager) CheckBackups() ([]MetadataCheck, error) {
+	entries, err := bm.fs().ReadDir(bm.BackupRoot)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var checks []MetadataCheck
+	for _, entry := range entries {
+		// Other state files can share a backup root (safe mode keeps state.json
+		// next to its snapshots), so only files named like backup IDs are checked
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" || !strings.HasPrefix(entry.Name(), backupIDPrefix) {
+			continue
+		}
+
+		check := MetadataCheck{
+			ID:     strings.TrimSuffix(entry.Name(), ".json"),
+			File:   filepath.Join(bm.BackupRoot, entry.Name()),
+			Status: MetadataOK,
+		}
+
+		data, err := ReadFile(bm.fs(), check.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", check.File, err)
+		}
+
+		metadata, migrated, err := decodeMetadata(check.File, data)
+		switch {
+		case err != nil:
+			check.Status = MetadataCorrupt
+			var metadataErr *MetadataError
+			if errors.As(err, &metadataErr) {
+				check.Problems = metadataErr.Problems
+			} else {
+				check.Problems = []string{err.Error()}
+			}
+		case metadata.ID != check.ID:
+			check.Status = MetadataCorrupt
+			check.Problems = []string{fmt.Sprintf("id %q does not match file name", metadata.ID)}
+		default:
+			check.Version = metadata.SchemaVersion
+			if migrated {
+				check.Status = MetadataMigrate
+				check.Version, _ = validation.SchemaVersionOf(data)
+			}
+			bm.findBackupDir(metadata)
+			if _, statErr := bm.fs().Stat(metadata.BackupPath); statErr != nil {
+				check.Status = MetadataCorrupt
+				check.Problems = []string{fmt.Sprintf("backup directory is missing: %s", metadata.BackupPath)}
+			} else if _, chainErr := bm.backupChain(metadata); chainErr != nil {
+				check.Status = MetadataCorrupt
+				check.Problems = []string{chainErr.Error()}
+			}
+		}
+
+		checks = append(checks, check)
+	}
+
+	sort.Slice(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+	return checks, nil
+}
+
+// RepairBackups applies the results of CheckBackups: older metadata is
+// rewritten at the current schema version, and corrupt metadata is moved with
+// its backup directory (if any) into the quarantine directory of the backup root.
+func (bm *BackupManager) RepairBackups(checks []MetadataCheck) error {
+	for i := range checks {
+		check := &checks[i]
+		switch check.Status {
+		case MetadataMigrate:
+			metadata, err := bm.loadMetadata(check.ID)
+			if err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", check.File, err)
+			}
+			if err := bm.saveMetadata(metadata); err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", check.File, err)
+			}
+			check.Action = "migrated"
+
+		case MetadataCorrupt:
+			quarantine := filepath.Join(bm.BackupRoot, QuarantineDir)
+			if err := bm.fs().MkdirAll(quarantine, DefaultDirPerm); err != nil {
+				return fmt.Errorf("failed to create quarantine directory: %w", err)
+			}
+			if err := bm.fs().Rename(check.File, filepath.Join(quarantine, filepath.Base(check.File))); err != nil {
+				return fmt.Errorf("failed to quarantine %s: %w", check.File, err)
+			}
+			backupDir := filepath.Join(bm.BackupRoot, check.ID)
+			if _, err := bm.fs().Stat(backupDir); err == nil {
+				if err := bm.fs().Rename(backupDir, filepath.Join(quarantine, check.ID)); err != nil {
+					return fmt.Errorf("failed to quarantine %s: %w", backupDir, err)
+				}
+			}
+			check.Action = "quarantined"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMetadataValidation(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-metadata-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	bm := NewBackupManager(tempDir)
+
+	tests := []struct {
+		name        string
+		data        string
+		expectError string
+	}{
+		{"current version", `{"schema_version": 2, "checksums": {"/srv/a.json": "00"}, "id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "server_path": "/srv", "backup_path": "/b", "files": []}`, ""},
+		{"version 1", `{"schema_version": 1, "id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "server_path": "/srv", "backup_path": "/b", "files": []}`, ""},
+		{"version 0", `{"id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "server_path": "", "backup_path": "/b", "files": null}`, ""},
+		{"negative version", `{"schema_version": -1, "id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "backup_path": "/b"}`, "is negative"},
+		{"newer version", `{"schema_version": 9, "id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "backup_path": "/b"}`, "newer than supported"},
+		{"missing operation", `{"id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "backup_path": "/b"}`, "operation: required field is missing"},
+		{"wrong type", `{"id": "backup_1_a", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "backup_path": "/b", "files": "x"}`, "files: expected array of strings"},
+		{"not JSON", `{"id": `, "not a valid JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(tempDir, "backup_1_a.json"), []byte(tt.data), 0600); err != nil {
+				t.Fatalf("Failed to write metadata: %v", err)
+			}
+
+			metadata, err := bm.loadMetadata("backup_1_a")
+			if tt.expectError == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if metadata.SchemaVersion != BackupMetadataVersion {
+					t.Errorf("Expected schema version %d after load, got %d", BackupMetadataVersion, metadata.SchemaVersion)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestMigrateMetadata(t *testing.T) {
+	metadata := &BackupMetadata{AddonUUID: "12345678-1234-1234-1234-123456789abc"}
+	if !migrateMetadata(metadata) {
+		t.Fatal("Expected version 0 metadata to migrate")
+	}
+	if len(metadata.PackUUIDs) != 1 || metadata.PackUUIDs[0] != metadata.AddonUUID {
+		t.Errorf("Expected pack UUIDs to be seeded from the addon UUID, got %v", metadata.PackUUIDs)
+	}
+	if migrateMetadata(metadata) {
+		t.Error("Expected current metadata not to migrate again")
+	}
+}
+
+func TestMetadataMigrationsCoverEveryVersion(t *testing.T) {
+	if len(metadataMigrations) != BackupMetadataVersion {
+		t.Errorf("Expected %d metadata migrations, one per older version, got %d", BackupMetadataVersion, len(metadataMigrations))
+	}
+}
+
+// writeLegacyBackup writes a backup of files by hand, laid out as older
+// releases did, with metadata at the given schema version and no checksums
+func writeLegacyBackup(t *testing.T, backupRoot, id string, version int, files []string) {
+	t.Helper()
+	backupPath := filepath.Join(backupRoot, id)
+	if err := os.MkdirAll(backupPath, 0750); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	for _, file := range files {
+		copyPath := filepath.Join(backupPath, filepath.Base(file))
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			data, copyPath = nil, copyPath+".missing"
+		} else if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if err := os.WriteFile(copyPath, data, 0600); err != nil {
+			t.Fatalf("Failed to write backup copy: %v", err)
+		}
+	}
+
+	metadata := map[string]any{
+		"id":          id,
+		"timestamp":   "2024-01-02T03:04:05Z",
+		"operation":   "install",
+		"addon_uuid":  "12345678-1234-1234-1234-123456789abc",
+		"server_path": filepath.Dir(files[0]),
+		"backup_path": backupPath,
+		"files":       files,
+	}
+	if version > 0 {
+		metadata["schema_version"] = version
+		metadata["pack_uuids"] = []string{metadata["addon_uuid"].(string)}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("Failed to marshal metadata: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupRoot, id+".json"), data, 0600); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+}
+
+func TestRestoreLegacyBackups(t *testing.T) {
+	for version := 0; version < BackupMetadataVersion; version++ {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			tempDir := t.TempDir()
+			configFile := filepath.Join(tempDir, "world_behavior_packs.json")
+			historyFile := filepath.Join(tempDir, "world_behavior_pack_history.json")
+			if err := os.WriteFile(configFile, []byte("[]"), 0600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			backupRoot := filepath.Join(tempDir, "backups")
+			const id = "backup_1_legacy"
+			writeLegacyBackup(t, backupRoot, id, version, []string{configFile, historyFile})
+			metadataFile := filepath.Join(backupRoot, id+".json")
+			stored, err := os.ReadFile(metadataFile)
+			if err != nil {
+				t.Fatalf("Failed to read metadata: %v", err)
+			}
+
+			// The server changes after the backup
+			if err := os.WriteFile(configFile, []byte(`[{"pack_id": "a"}]`), 0600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			if err := os.WriteFile(historyFile, []byte("{}"), 0600); err != nil {
+				t.Fatalf("Failed to write history: %v", err)
+			}
+
+			bm := NewBackupManager(backupRoot)
+			backups, err := bm.ListBackups()
+			if err != nil || len(backups) != 1 {
+				t.Fatalf("ListBackups = %+v, %v, want the legacy backup", backups, err)
+			}
+			if backups[0].SchemaVersion != BackupMetadataVersion || len(backups[0].PackUUIDs) != 1 {
+				t.Errorf("Expected the backup migrated to version %d with its addon UUID as pack, got version %d and %v",
+					BackupMetadataVersion, backups[0].SchemaVersion, backups[0].PackUUIDs)
+			}
+
+			if err := bm.RestoreBackup(id); err != nil {
+				t.Fatalf("RestoreBackup failed: %v", err)
+			}
+			if data, _ := os.ReadFile(configFile); string(data) != "[]" {
+				t.Errorf("Expected the config restored, got %s", data)
+			}
+			if _, err := os.Stat(historyFile); !os.IsNotExist(err) {
+				t.Errorf("Expected the file missing at backup time to be removed, got %v", err)
+			}
+			verification, err := bm.VerifyRestore(id)
+			if err != nil || len(verification.Problems) != 0 || verification.FilesVerified != 1 {
+				t.Errorf("VerifyRestore = %+v, %v, want 1 file verified without problems", verification, err)
+			}
+
+			// Reading migrates in memory only; fsck --repair rewrites the file
+			if data, _ := os.ReadFile(metadataFile); string(data) != string(stored) {
+				t.Errorf("Expected the metadata file unchanged by a restore, got %s", data)
+			}
+		})
+	}
+}
+
+func TestCheckAndRepairBackups(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-metadata-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	backupRoot := filepath.Join(tempDir, "backups")
+	bm := NewBackupManager(backupRoot)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("test content"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	good, err := bm.CreateBackup("install", "Good backup", []string{testFile})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	// An old-format backup: no schema_version, only the addon UUID
+	oldDir := filepath.Join(backupRoot, "backup_1_old")
+	if err := os.MkdirAll(oldDir, 0750); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	old := `{"id": "backup_1_old", "timestamp": "2024-01-02T03:04:05Z", "operation": "install", "addon_uuid": "u1", "server_path": "", "backup_path": "` + oldDir + `", "files": []}`
+	if err := os.WriteFile(filepath.Join(backupRoot, "backup_1_old.json"), []byte(old), 0600); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	// A corrupt backup with its directory, and a non-backup state file that must be ignored
+	corruptDir := filepath.Join(backupRoot, "backup_2_bad")
+	if err := os.MkdirAll(corruptDir, 0750); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupRoot, "backup_2_bad.json"), []byte(`{"id": "backup_2_bad"`), 0600); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupRoot, "state.json"), []byte(`{}`), 0600); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	checks, err := bm.CheckBackups()
+	if err != nil {
+		t.Fatalf("CheckBackups failed: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, check := range checks {
+		statuses[check.ID] = check.Status
+	}
+	expected := map[string]string{
+		good.ID:        MetadataOK,
+		"backup_1_old": MetadataMigrate,
+		"backup_2_bad": MetadataCorrupt,
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %d checks, got %v", len(expected), statuses)
+	}
+	for id, status := range expected {
+		if statuses[id] != status {
+			t.Errorf("Expected %s to be %s, got %s", id, status, statuses[id])
+		}
+	}
+
+	if err := bm.RepairBackups(checks); err != nil {
+		t.Fatalf("RepairBackups failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(backupRoot, QuarantineDir, "backup_2_bad.json")); err != nil {
+		t.Errorf("Expected corrupt metadata to be quarantined: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(backupRoot, QuarantineDir, "backup_2_bad")); err != nil {
+		t.Errorf("Expected corrupt backup directory to be quarantined: %v", err)
+	}
+
+	migrated, err := bm.loadMetadata("backup_1_old")
+	if err != nil {
+		t.Fatalf("Failed to load migrated backup: %v", err)
+	}
+	if len(migrated.PackUUIDs) != 1 || migrated.PackUUIDs[0] != "u1" {
+		t.Errorf("Expected migrated pack UUIDs [u1], got %v", migrated.PackUUIDs)
+	}
+
+	checks, err = bm.CheckBackups()
+	if err != nil {
+		t.Fatalf("CheckBackups failed: %v", err)
+	}
+	for _, check := range checks {
+		if check.Status != MetadataOK {
+			t.Errorf("Expected %s to be ok after repair, got %s %v", check.ID, check.Status, check.Problems)
+		}
+	}
+}
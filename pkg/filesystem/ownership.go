@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileOwner is a user and group that files are given to
+type FileOwner struct {
+	UID  int
+	GID  int
+	Name string // As given, e.g. minecraft:minecraft
+}
+
+func (o *FileOwner) String() string {
+	return o.Name
+}
+
+// ParseOwner parses a --chown value: user or user:group, each a name or a
+// numeric ID. Without a group, the user's primary group is used.
+func ParseOwner(spec string) (*FileOwner, error) {
+	if !ownershipSupported {
+		return nil, fmt.Errorf("file ownership can't be changed on this platform")
+	}
+	userName, groupName, hasGroup := strings.Cut(spec, ":")
+	if userName == "" || (hasGroup && groupName == "") {
+		return nil, fmt.Errorf("invalid owner %q: use user or user:group", spec)
+	}
+
+	account, err := lookupUser(userName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner %q: %w", spec, err)
+	}
+	owner := &FileOwner{Name: spec}
+	if owner.UID, err = strconv.Atoi(account.Uid); err != nil {
+		return nil, fmt.Errorf("invalid owner %q: user %s has no numeric ID", spec, userName)
+	}
+	gid := account.Gid
+	if hasGroup {
+		group, err := lookupGroup(groupName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid owner %q: %w", spec, err)
+		}
+		gid = group.Gid
+	}
+	if owner.GID, err = strconv.Atoi(gid); err != nil {
+		return nil, fmt.Errorf("invalid owner %q: group %s has no numeric ID", spec, gid)
+	}
+	return owner, nil
+}
+
+// lookupUser finds a user by name, or by ID when the name is numeric
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		if account, err := user.LookupId(name); err == nil {
+			return account, nil
+		}
+		// An ID without an account is still a valid owner
+		return &user.User{Uid: name, Gid: name, Username: name}, nil
+	}
+	return user.Lookup(name)
+}
+
+// lookupGroup finds a group by name, or by ID when the name is numeric
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return &user.Group{Gid: name, Name: name}, nil
+	}
+	return user.LookupGroup(name)
+}
+
+// ParseFileMode parses a --chmod value: octal permission bits such as 644
+// or 0640
+func ParseFileMode(spec string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(spec, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q: use octal permissions such as 644", spec)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Ownership is the owner and permissions given to installed files. A nil
+// Owner keeps the files owned by the process; a zero FileMode keeps each
+// file's mode.
+type Ownership struct {
+	Owner    *FileOwner
+	FileMode os.FileMode
+}
+
+// DirMode returns the mode for directories: FileMode with search permission
+// added wherever it grants read, so 644 gives directories 755
+func (o *Ownership) DirMode() os.FileMode {
+	return o.FileMode | (o.FileMode&0o444)>>2
+}
+
+// Apply gives path, and everything below it when it is a directory, the
+// owner and mode. Symbolic links are given the owner but keep their mode.
+func (o *Ownership) Apply(path string) error {
+	if o == nil || (o.Owner == nil && o.FileMode == 0) {
+		return nil
+	}
+	return filepath.WalkDir(path, func(entryPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if o.Owner != nil {
+			if err := os.Lchown(entryPath, o.Owner.UID, o.Owner.GID); err != nil {
+				return err
+			}
+		}
+		switch {
+		case o.FileMode == 0 || d.Type()&fs.ModeSymlink != 0:
+			return nil
+		case d.IsDir():
+			return OSFS{}.Chmod(entryPath, o.DirMode())
+		default:
+			return OSFS{}.Chmod(entryPath, o.FileMode)
+		}
+	})
+}
+
+// OwnerOf returns the user and group IDs owning path, or false on platforms
+// without them
+func OwnerOf(path string) (uid, gid int, ok bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	return fileOwnerIDs(info)
+}
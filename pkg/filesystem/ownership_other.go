@@ -0,0 +1,13 @@
+//go:build !unix
+
+package filesystem
+
+import "os"
+
+// ownershipSupported is false: files here have no user and group IDs
+const ownershipSupported = false
+
+// fileOwnerIDs is not available here; OwnerOf reports no owner
+func fileOwnerIDs(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,60 @@
+package filesystem
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    os.FileMode
+		expectError bool
+	}{
+		{"644", 0o644, false},
+		{"0640", 0o640, false},
+		{"600", 0o600, false},
+		{"1777", 0, true},
+		{"999", 0, true},
+		{"rw-r--r--", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := ParseFileMode(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %o", tt.input, mode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFileMode(%q) failed: %v", tt.input, err)
+			}
+			if mode != tt.expected {
+				t.Errorf("ParseFileMode(%q) = %o, want %o", tt.input, mode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOwnershipDirMode(t *testing.T) {
+	tests := []struct {
+		fileMode os.FileMode
+		expected os.FileMode
+	}{
+		{0o644, 0o755},
+		{0o640, 0o750},
+		{0o600, 0o700},
+		{0o664, 0o775},
+		{0o200, 0o200},
+	}
+
+	for _, tt := range tests {
+		ownership := &Ownership{FileMode: tt.fileMode}
+		if got := ownership.DirMode(); got != tt.expected {
+			t.Errorf("DirMode() for %o = %o, want %o", tt.fileMode, got, tt.expected)
+		}
+	}
+}
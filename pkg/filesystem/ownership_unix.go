@@ -0,0 +1,20 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownershipSupported reports whether files have a user and group to change
+const ownershipSupported = true
+
+// fileOwnerIDs returns the user and group IDs owning a file
+func fileOwnerIDs(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
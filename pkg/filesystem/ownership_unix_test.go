@@ -0,0 +1,85 @@
+//go:build unix
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseOwner(t *testing.T) {
+	owner, err := ParseOwner("0:0")
+	if err != nil {
+		t.Fatalf("ParseOwner failed: %v", err)
+	}
+	if owner.UID != 0 || owner.GID != 0 || owner.String() != "0:0" {
+		t.Errorf("ParseOwner(0:0) = %+v", owner)
+	}
+
+	// A numeric ID without an account is still an owner
+	owner, err = ParseOwner("54321")
+	if err != nil {
+		t.Fatalf("ParseOwner failed: %v", err)
+	}
+	if owner.UID != 54321 || owner.GID != 54321 {
+		t.Errorf("ParseOwner(54321) = %+v, want the ID as user and group", owner)
+	}
+
+	for _, spec := range []string{"", ":0", "0:", "no-such-user-blockbench"} {
+		if _, err := ParseOwner(spec); err == nil {
+			t.Errorf("Expected error for owner %q", spec)
+		}
+	}
+}
+
+func TestOwnershipApply(t *testing.T) {
+	root := t.TempDir()
+	packDir := filepath.Join(root, "pack")
+	if err := os.MkdirAll(filepath.Join(packDir, "textures"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{filepath.Join(packDir, "manifest.json"), filepath.Join(packDir, "textures", "a.png")}
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("manifest.json", filepath.Join(packDir, "link.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Giving the files to their current owner works without privileges
+	uid, gid, ok := OwnerOf(packDir)
+	if !ok {
+		t.Fatal("OwnerOf found no owner")
+	}
+	ownership := &Ownership{Owner: &FileOwner{UID: uid, GID: gid}, FileMode: 0o644}
+	if err := ownership.Apply(packDir); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o644 {
+			t.Errorf("%s has mode %o, want 644", file, info.Mode().Perm())
+		}
+	}
+	for _, dir := range []string{packDir, filepath.Join(packDir, "textures")} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o755 {
+			t.Errorf("%s has mode %o, want 755", dir, info.Mode().Perm())
+		}
+	}
+
+	var none *Ownership
+	if err := none.Apply(filepath.Join(root, "missing")); err != nil {
+		t.Errorf("Apply on nil ownership failed: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package filesystem
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest file name component most filesystems accept:
+// 255 bytes on Linux, and 255 UTF-16 units on Windows, which is never less
+const MaxNameLength = 255
+
+// windowsMaxPath is the path length Windows programs without long path
+// support can open, including the terminating NUL
+const windowsMaxPath = 260
+
+// windowsReservedNames are device names Windows won't create files under,
+// with or without an extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// windowsInvalidChars are the printable characters Windows rejects in names;
+// control characters are rejected too
+const windowsInvalidChars = `<>:"/\|?*`
+
+// PathStyle holds the file naming rules of an operating system. The checks
+// take the style as a value, so the Windows rules run, and are tested, on
+// every platform.
+type PathStyle struct {
+	Windows         bool // Reserved device names, the characters Windows rejects, trailing dots and spaces, and MAX_PATH apply
+	CaseInsensitive bool // Names that differ only in case are the same file
+}
+
+// HostPathStyle is the style of the platform blockbench runs on. macOS
+// volumes are case-insensitive unless formatted otherwise.
+var HostPathStyle = PathStyle{
+	Windows:         runtime.GOOS == "windows",
+	CaseInsensitive: runtime.GOOS == "windows" || runtime.GOOS == "darwin",
+}
+
+// CheckName returns an error when name can't be a file name component: it
+// is empty, "." or "..", holds a path separator or NUL, or is too long. In
+// the Windows style, reserved device names, the characters Windows rejects,
+// and names ending in a dot or space are errors too.
+func (s PathStyle) CheckName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("invalid file name %q", name)
+	case strings.ContainsAny(name, "/\x00"):
+		return fmt.Errorf("invalid file name %q: contains a path separator or NUL", name)
+	case len(name) > MaxNameLength:
+		return fmt.Errorf("file name %.32q... is longer than %d bytes", name, MaxNameLength)
+	case !s.Windows:
+		return nil
+	}
+
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(windowsInvalidChars, r) {
+			return fmt.Errorf("invalid file name %q: Windows doesn't allow %q in names", name, r)
+		}
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return fmt.Errorf("invalid file name %q: Windows drops trailing dots and spaces from names", name)
+	}
+	if isWindowsReserved(name) {
+		return fmt.Errorf("invalid file name %q: %s is a reserved device name on Windows", name, strings.ToUpper(windowsBaseName(name)))
+	}
+	return nil
+}
+
+// CheckPath checks each component of a relative path separated by slashes,
+// or also by backslashes in the Windows style, with CheckName
+func (s PathStyle) CheckPath(rel string) error {
+	if s.Windows {
+		rel = strings.ReplaceAll(rel, `\`, "/")
+	}
+	for _, name := range strings.Split(strings.TrimSuffix(rel, "/"), "/") {
+		if err := s.CheckName(name); err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// CheckPaths checks each relative path with CheckPath and, in a
+// case-insensitive style, that no two of them name the same file
+func (s PathStyle) CheckPaths(paths []string) error {
+	seen := make(map[string]string, len(paths))
+	for _, rel := range paths {
+		if err := s.CheckPath(rel); err != nil {
+			return err
+		}
+		key := s.pathKey(rel)
+		if first, ok := seen[key]; ok && first != rel {
+			return fmt.Errorf("%s and %s name the same file on a case-insensitive filesystem", first, rel)
+		}
+		seen[key] = rel
+	}
+	return nil
+}
+
+// pathKey returns the form of rel under which the style treats paths as equal
+func (s PathStyle) pathKey(rel string) string {
+	if s.Windows {
+		rel = strings.ReplaceAll(rel, `\`, "/")
+	}
+	rel = strings.TrimSuffix(rel, "/")
+	if s.CaseInsensitive {
+		rel = strings.ToLower(rel)
+	}
+	return rel
+}
+
+// SameName reports whether two file names refer to the same file, after
+// NormalizeName and, in a case-insensitive style, ignoring case
+func (s PathStyle) SameName(a, b string) bool {
+	a, b = s.NormalizeName(a), s.NormalizeName(b)
+	if s.CaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// NormalizeName returns the name a file created as name ends up with: in the
+// Windows style, trailing dots and spaces are dropped
+func (s PathStyle) NormalizeName(name string) string {
+	if s.Windows {
+		return strings.TrimRight(name, ". ")
+	}
+	return name
+}
+
+// SafeName turns any text, such as a pack name, into a valid file name
+// component: path separators, NUL, and, in the Windows style, the characters
+// Windows rejects become underscores, trailing dots and spaces are dropped,
+// and reserved device names get an underscore appended. Names are cut to
+// MaxNameLength bytes; an empty result is "_".
+func (s PathStyle) SafeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == 0 || (s.Windows && (r < 0x20 || strings.ContainsRune(windowsInvalidChars, r))) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = TruncateName(name, MaxNameLength)
+	if s.Windows {
+		name = strings.TrimRight(name, ". ")
+		if isWindowsReserved(name) {
+			name = windowsBaseName(name) + "_" + strings.TrimPrefix(name, windowsBaseName(name))
+		}
+	}
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// TruncateName cuts name to at most length bytes without splitting a
+// multi-byte character
+func TruncateName(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+	name = name[:length]
+	for len(name) > 0 && !utf8.ValidString(name) {
+		name = name[:len(name)-1]
+	}
+	return name
+}
+
+// TooLong reports whether an absolute path is longer than Windows programs
+// without long path support, such as older dedicated servers, can open. It is
+// always false outside the Windows style.
+func (s PathStyle) TooLong(absPath string) bool {
+	return s.Windows && utf8.RuneCountInString(absPath) >= windowsMaxPath
+}
+
+// isWindowsReserved reports whether name is a reserved device name, which
+// Windows also treats as one with an extension, as in aux.json
+func isWindowsReserved(name string) bool {
+	return windowsReservedNames[strings.ToUpper(strings.TrimRight(windowsBaseName(name), " "))]
+}
+
+// windowsBaseName returns name up to its first dot
+func windowsBaseName(name string) string {
+	base, _, _ := strings.Cut(name, ".")
+	return base
+}
@@ -0,0 +1,161 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	unixStyle    = PathStyle{}
+	windowsStyle = PathStyle{Windows: true, CaseInsensitive: true}
+)
+
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name       string
+		unixOK     bool
+		windowsOK  bool
+		errorMatch string // Part of the Windows error
+	}{
+		{"manifest.json", true, true, ""},
+		{"Lucky Blocks_1a2b3c4d", true, true, ""},
+		{"", false, false, "invalid file name"},
+		{"..", false, false, "invalid file name"},
+		{"a/b", false, false, "path separator"},
+		{"nul\x00byte", false, false, "NUL"},
+		{strings.Repeat("x", 256), false, false, "longer than"},
+		{"Boss: Fight", true, false, "doesn't allow"},
+		{`back\slash`, true, false, "doesn't allow"},
+		{"what?", true, false, "doesn't allow"},
+		{"tab\tname", true, false, "doesn't allow"},
+		{"My World ", true, false, "trailing dots and spaces"},
+		{"ends.", true, false, "trailing dots and spaces"},
+		{"CON", true, false, "reserved device name"},
+		{"aux.json", true, false, "reserved device name"},
+		{"lpt1", true, false, "reserved device name"},
+		{"COM10", true, true, ""},
+		{"console.png", true, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := unixStyle.CheckName(tt.name); (err == nil) != tt.unixOK {
+				t.Errorf("Unix CheckName(%q) = %v, want ok %v", tt.name, err, tt.unixOK)
+			}
+			err := windowsStyle.CheckName(tt.name)
+			if (err == nil) != tt.windowsOK {
+				t.Fatalf("Windows CheckName(%q) = %v, want ok %v", tt.name, err, tt.windowsOK)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.errorMatch) {
+				t.Errorf("Windows CheckName(%q) = %v, want it to mention %q", tt.name, err, tt.errorMatch)
+			}
+		})
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	if err := windowsStyle.CheckPaths([]string{"textures/blocks/a.png", `textures\items\b.png`, "textures/"}); err != nil {
+		t.Errorf("CheckPaths failed on valid paths: %v", err)
+	}
+	if err := windowsStyle.CheckPaths([]string{"textures/con/a.png"}); err == nil {
+		t.Error("Expected error for a reserved directory name")
+	}
+
+	collision := []string{"textures/Stone.png", "textures/stone.png"}
+	if err := unixStyle.CheckPaths(collision); err != nil {
+		t.Errorf("Case-sensitive CheckPaths failed: %v", err)
+	}
+	err := windowsStyle.CheckPaths(collision)
+	if err == nil || !strings.Contains(err.Error(), "case-insensitive") {
+		t.Errorf("Case-insensitive CheckPaths = %v, want a collision error", err)
+	}
+
+	// An archive may list the same entry twice
+	if err := windowsStyle.CheckPaths([]string{"a.png", "a.png"}); err != nil {
+		t.Errorf("CheckPaths failed on a repeated path: %v", err)
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		input   string
+		unix    string
+		windows string
+	}{
+		{"Lucky Blocks", "Lucky Blocks", "Lucky Blocks"},
+		{"Blocks/Items", "Blocks_Items", "Blocks_Items"},
+		{"Boss: Fight?", "Boss: Fight?", "Boss_ Fight_"},
+		{"Trailing dots...", "Trailing dots...", "Trailing dots"},
+		{"CON", "CON", "CON_"},
+		{"aux.pack", "aux.pack", "aux_.pack"},
+		{"..", "_", "_"},
+		{"", "_", "_"},
+		{"   ", "   ", "_"},
+	}
+
+	for _, tt := range tests {
+		if got := unixStyle.SafeName(tt.input); got != tt.unix {
+			t.Errorf("Unix SafeName(%q) = %q, want %q", tt.input, got, tt.unix)
+		}
+		got := windowsStyle.SafeName(tt.input)
+		if got != tt.windows {
+			t.Errorf("Windows SafeName(%q) = %q, want %q", tt.input, got, tt.windows)
+		}
+		if err := windowsStyle.CheckName(got); err != nil {
+			t.Errorf("Windows SafeName(%q) = %q, which CheckName rejects: %v", tt.input, got, err)
+		}
+	}
+
+	long := strings.Repeat("é", 200) // 400 bytes
+	if got := windowsStyle.SafeName(long); len(got) > MaxNameLength || got != strings.Repeat("é", 127) {
+		t.Errorf("SafeName of a long name = %d bytes, want 127 whole characters", len(got))
+	}
+}
+
+func TestSameName(t *testing.T) {
+	if !windowsStyle.SameName("My World ", "my world") {
+		t.Error("Windows SameName should ignore case and trailing spaces")
+	}
+	if unixStyle.SameName("My World ", "My World") || unixStyle.SameName("World", "world") {
+		t.Error("Unix SameName should compare names exactly")
+	}
+}
+
+func TestTooLong(t *testing.T) {
+	short := `C:\bedrock\development_behavior_packs\Pack_1a2b3c4d\manifest.json`
+	long := `C:\bedrock\development_behavior_packs\Pack_1a2b3c4d\` + strings.Repeat("a", 250) + ".png"
+	if windowsStyle.TooLong(short) {
+		t.Errorf("TooLong(%s) = true", short)
+	}
+	if !windowsStyle.TooLong(long) {
+		t.Error("TooLong of a path over 260 characters = false")
+	}
+	if unixStyle.TooLong(long) {
+		t.Error("Unix TooLong should always be false")
+	}
+}
+
+func TestCheckEntryNames(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "pack.zip")
+	createTestZip(t, zipPath, map[string]string{
+		"pack/manifest.json":      "{}",
+		"pack/textures/":          "",
+		"pack/textures/aux.png":   "png",
+		"pack/textures/Stone.png": "png",
+	})
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := checkEntryNames(unixStyle, reader.File, "pack/"); err != nil {
+		t.Errorf("checkEntryNames failed in the Unix style: %v", err)
+	}
+	err = checkEntryNames(windowsStyle, reader.File, "pack/")
+	if err == nil || !strings.Contains(err.Error(), "textures/aux.png") {
+		t.Errorf("checkEntryNames in the Windows style = %v, want an error naming textures/aux.png", err)
+	}
+}
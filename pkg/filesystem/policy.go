@@ -0,0 +1,133 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathPolicy restricts filesystem writes to a list of allowed directory
+// trees. Operations are checked before they run, so a restricted install on
+// shared hosting fails up front instead of partway through.
+type PathPolicy struct {
+	Allowed []string // Resolved allowed roots
+}
+
+// PathViolation describes a planned write outside the allowed paths
+type PathViolation struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+}
+
+// PlannedWrite is a filesystem write an operation intends to make
+type PlannedWrite struct {
+	Operation string // What the write is for, e.g. "install pack files"
+	Path      string
+}
+
+// PathPolicyError lists every planned write outside the allowed paths
+type PathPolicyError struct {
+	Allowed    []string
+	Violations []PathViolation
+}
+
+func (e *PathPolicyError) Error() string {
+	lines := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		lines = append(lines, fmt.Sprintf("%s: %s", v.Operation, v.Path))
+	}
+	return fmt.Sprintf("planned writes outside the allowed paths (%s):\n  %s",
+		strings.Join(e.Allowed, ", "), strings.Join(lines, "\n  "))
+}
+
+// NewPathPolicy resolves the allowed roots to absolute, symlink-free paths.
+// An empty list returns nil, which allows everything.
+func NewPathPolicy(allowed []string) (*PathPolicy, error) {
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+
+	policy := &PathPolicy{}
+	for _, root := range allowed {
+		if root == "" {
+			continue
+		}
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed path %s: %w", root, err)
+		}
+		policy.Allowed = append(policy.Allowed, resolved)
+	}
+	if len(policy.Allowed) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// Allows reports whether path lies inside one of the allowed roots. Symlinks
+// in the existing part of the path are resolved first, so a link pointing out
+// of an allowed tree does not pass.
+func (p *PathPolicy) Allows(path string) bool {
+	if p == nil {
+		return true
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range p.Allowed {
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check verifies every planned write and returns a *PathPolicyError listing
+// all violations, or nil when the plan stays inside the allowed paths
+func (p *PathPolicy) Check(writes []PlannedWrite) error {
+	if p == nil {
+		return nil
+	}
+	var violations []PathViolation
+	for _, write := range writes {
+		if !p.Allows(write.Path) {
+			violations = append(violations, PathViolation{Operation: write.Operation, Path: write.Path})
+		}
+	}
+	if len(violations) > 0 {
+		return &PathPolicyError{Allowed: p.Allowed, Violations: violations}
+	}
+	return nil
+}
+
+// resolvePath makes path absolute and resolves symlinks in its longest
+// existing ancestor; the missing remainder is appended unchanged
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing := abs
+	var missing []string
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{resolved}, missing...)...), nil
+}
@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathPolicy(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-policy-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	allowed := filepath.Join(tempDir, "allowed")
+	outside := filepath.Join(tempDir, "outside")
+	for _, dir := range []string{allowed, outside} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	// A link inside the allowed tree pointing out of it
+	if err := os.Symlink(outside, filepath.Join(allowed, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	policy, err := NewPathPolicy([]string{allowed})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{"root itself", allowed, true},
+		{"existing child", filepath.Join(allowed, "escape", ".."), true},
+		{"missing child", filepath.Join(allowed, "packs", "new_pack"), true},
+		{"sibling", outside, false},
+		{"prefix lookalike", allowed + "-other", false},
+		{"parent traversal", filepath.Join(allowed, "..", "outside"), false},
+		{"symlink escape", filepath.Join(allowed, "escape", "file"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Allows(tt.path); got != tt.expected {
+				t.Errorf("Allows(%s) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	err = policy.Check([]PlannedWrite{
+		{Operation: "install pack files", Path: filepath.Join(allowed, "pack")},
+		{Operation: "create backup", Path: outside},
+	})
+	var policyErr *PathPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected a PathPolicyError, got %v", err)
+	}
+	if len(policyErr.Violations) != 1 || policyErr.Violations[0].Path != outside {
+		t.Errorf("Expected only the backup to violate the policy, got %+v", policyErr.Violations)
+	}
+
+	var unrestricted *PathPolicy
+	if !unrestricted.Allows(outside) || unrestricted.Check([]PlannedWrite{{Path: outside}}) != nil {
+		t.Error("Expected a nil policy to allow everything")
+	}
+}
@@ -0,0 +1,85 @@
+package filesystem
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Progress receives the bytes processed by a long-running step such as
+// extraction, copying, or backup. Add may be called from several goroutines
+// during parallel extraction, so implementations must be safe for concurrent use.
+type Progress interface {
+	Start(step string, total int64) // total is 0 when unknown
+	Add(n int64)
+	Finish()
+}
+
+// progressOrNone returns p, or a Progress that discards reports when p is nil
+func progressOrNone(p Progress) Progress {
+	if p == nil {
+		return noProgress{}
+	}
+	return p
+}
+
+type noProgress struct{}
+
+func (noProgress) Start(string, int64) {}
+func (noProgress) Add(int64)           {}
+func (noProgress) Finish()             {}
+
+// ProgressWriter counts the bytes written through it as progress
+type ProgressWriter struct {
+	W        io.Writer
+	Progress Progress
+}
+
+func (pw ProgressWriter) Write(p []byte) (int, error) {
+	n, err := pw.W.Write(p)
+	pw.Progress.Add(int64(n))
+	return n, err
+}
+
+// TreeSize pre-scans a file or directory and returns the total size of its
+// regular files, so a copy of it can report progress against a known total.
+// A missing path has size 0.
+func TreeSize(path string) (int64, error) {
+	return TreeSizeFS(nil, path)
+}
+
+// TreeSizeFS is TreeSize on fsys; a nil fsys is the real filesystem
+func TreeSizeFS(fsys FS, path string) (int64, error) {
+	var total int64
+	err := WalkDir(fsys, path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// CopyWithProgress copies src to dst, reporting the bytes copied. Without a
+// Progress it copies directly so the kernel copy fast paths between real
+// files stay available.
+func CopyWithProgress(dst io.Writer, src io.Reader, progress Progress) (int64, error) {
+	if progress == nil {
+		return io.Copy(dst, src)
+	}
+	if _, ok := progress.(noProgress); ok {
+		return io.Copy(dst, src)
+	}
+	return io.Copy(ProgressWriter{W: dst, Progress: progress}, src)
+}
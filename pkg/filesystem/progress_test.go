@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingProgress records every report for assertions
+type recordingProgress struct {
+	mu       sync.Mutex
+	steps    []string
+	totals   []int64
+	added    int64
+	finished int
+}
+
+func (p *recordingProgress) Start(step string, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, step)
+	p.totals = append(p.totals, total)
+}
+
+func (p *recordingProgress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.added += n
+}
+
+func (p *recordingProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished++
+}
+
+func TestTreeSize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-progress-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "sub"), 0750); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("12345"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "sub", "b.txt"), []byte("123"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int64
+	}{
+		{"directory", tempDir, 8},
+		{"file", filepath.Join(tempDir, "a.txt"), 5},
+		{"missing", filepath.Join(tempDir, "missing"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := TreeSize(tt.path)
+			if err != nil {
+				t.Fatalf("TreeSize failed: %v", err)
+			}
+			if size != tt.expected {
+				t.Errorf("TreeSize() = %d, want %d", size, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractorProgress(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-progress-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"manifest.json":  `{"format_version": 2}`,
+		"textures/a.txt": strings.Repeat("a", 1000),
+		"textures/b.txt": strings.Repeat("b", 3000),
+	}
+	var expected int64
+	for _, content := range files {
+		expected += int64(len(content))
+	}
+
+	zipPath := filepath.Join(tempDir, "test.mcpack")
+	createTestZip(t, zipPath, files)
+
+	for _, workers := range []int{1, 4} {
+		progress := &recordingProgress{}
+		extractor := NewExtractor(ExtractLimits{Workers: workers})
+		extractor.Progress = progress
+
+		if err := extractor.Extract(zipPath, filepath.Join(tempDir, fmt.Sprintf("out%d", workers))); err != nil {
+			t.Fatalf("Extract with %d workers failed: %v", workers, err)
+		}
+		if len(progress.totals) != 1 || progress.totals[0] != expected {
+			t.Errorf("Expected one step with total %d, got %v", expected, progress.totals)
+		}
+		if progress.added != expected {
+			t.Errorf("Expected %d bytes reported with %d workers, got %d", expected, workers, progress.added)
+		}
+		if progress.finished != 1 {
+			t.Errorf("Expected the step to finish once, got %d", progress.finished)
+		}
+	}
+}
+
+func TestBackupProgress(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-progress-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("test content"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	progress := &recordingProgress{}
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	bm.Progress = progress
+
+	if _, err := bm.CreateBackup("install", "test", []string{testFile, filepath.Join(tempDir, "missing.json")}); err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	if len(progress.totals) != 1 || progress.totals[0] != 12 {
+		t.Errorf("Expected one step with total 12, got %v", progress.totals)
+	}
+	if progress.added != 12 {
+		t.Errorf("Expected 12 bytes reported, got %d", progress.added)
+	}
+}
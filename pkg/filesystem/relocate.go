@@ -0,0 +1,91 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Rebase moves the files a backup records from its ServerPath onto root, as
+// if the backup had been taken of a server there. Every file must lie in
+// ServerPath.
+func (m *BackupMetadata) Rebase(root string) error {
+	if m.ServerPath == "" {
+		return fmt.Errorf("backup %s does not record the server it was taken of", m.ID)
+	}
+	oldRoot := filepath.Clean(m.ServerPath)
+	newRoot := filepath.Clean(root)
+	rebase := func(path string) (string, error) {
+		rel, err := filepath.Rel(oldRoot, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("backup %s includes %s, which is outside its server %s", m.ID, path, oldRoot)
+		}
+		return filepath.Join(newRoot, rel), nil
+	}
+
+	files := make([]string, len(m.Files))
+	for i, file := range m.Files {
+		rebased, err := rebase(file)
+		if err != nil {
+			return err
+		}
+		files[i] = rebased
+	}
+	var checksums map[string]string
+	if m.Checksums != nil {
+		checksums = make(map[string]string, len(m.Checksums))
+		for path, digest := range m.Checksums {
+			rebased, err := rebase(path)
+			if err != nil {
+				return err
+			}
+			checksums[rebased] = digest
+		}
+	}
+
+	m.Files = files
+	m.Checksums = checksums
+	m.ServerPath = newRoot
+	return nil
+}
+
+// errRestoreOnly is returned by the operations of a manager with RestoreRoot
+// set that would write backups
+var errRestoreOnly = errors.New("backups rebased onto another server can only be restored")
+
+// writable fails for a manager that rebases backups, whose metadata must not
+// be saved with another server's paths
+func (bm *BackupManager) writable() error {
+	if bm.RestoreRoot != "" {
+		return errRestoreOnly
+	}
+	return nil
+}
+
+// relocate adjusts freshly loaded metadata to where its files are now: the
+// backup directory is found as findBackupDir does, and with RestoreRoot the
+// files are rebased
+func (bm *BackupManager) relocate(metadata *BackupMetadata) error {
+	bm.findBackupDir(metadata)
+	if bm.RestoreRoot == "" {
+		return nil
+	}
+	return metadata.Rebase(bm.RestoreRoot)
+}
+
+// findBackupDir points a backup at the directory of its ID in the backup root
+// when its recorded directory is gone, so a backup root copied elsewhere, as
+// onto a rebuilt machine, keeps working
+func (bm *BackupManager) findBackupDir(metadata *BackupMetadata) {
+	moved := filepath.Join(bm.BackupRoot, metadata.ID)
+	if metadata.BackupPath == moved {
+		return
+	}
+	if _, err := bm.fs().Stat(metadata.BackupPath); os.IsNotExist(err) {
+		if _, err := bm.fs().Stat(moved); err == nil {
+			metadata.BackupPath = moved
+		}
+	}
+}
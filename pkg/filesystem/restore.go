@@ -0,0 +1,321 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Restore change actions
+const (
+	RestoreCreate    = "create"
+	RestoreOverwrite = "overwrite"
+	RestoreDelete    = "delete"
+)
+
+// RestoreChange is one file a restore would create, overwrite, or delete
+type RestoreChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Diff   string `json:"diff,omitempty"` // Line diff (current -> backup) for overwritten JSON files
+}
+
+// RestorePlan lists every change RestoreBackup would make, without making it
+type RestorePlan struct {
+	BackupID string          `json:"backup_id"`
+	Changes  []RestoreChange `json:"changes"`
+}
+
+// PlanRestore simulates RestoreBackup and reports the files it would change.
+// Files whose contents already match the backup are omitted.
+func (bm *BackupManager) PlanRestore(backupID string) (*RestorePlan, error) {
+	return bm.PlanRestoreFiles(backupID, nil)
+}
+
+// PlanRestoreFiles simulates RestoreFiles as PlanRestore does RestoreBackup
+func (bm *BackupManager) PlanRestoreFiles(backupID string, files []string) (*RestorePlan, error) {
+	metadata, err := bm.loadMetadata(backupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup metadata: %w", err)
+	}
+	files, err = metadata.selectFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	backupDir, cleanup, err := bm.materialize(metadata)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	plan := &RestorePlan{BackupID: backupID, Changes: make([]RestoreChange, 0)}
+	for _, originalFile := range files {
+		changes, err := planRestoreFile(bm.fs(), originalFile, filepath.Join(backupDir, metadata.storedName(originalFile)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan restore of %s: %w", originalFile, err)
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+
+	return plan, nil
+}
+
+// planRestoreFile mirrors restoreFile without touching the filesystem
+func planRestoreFile(fsys FS, originalPath, backupPath string) ([]RestoreChange, error) {
+	// A missing marker means restore deletes whatever exists now
+	if _, err := fsys.Stat(backupPath + ".missing"); err == nil {
+		current, err := listTree(fsys, originalPath)
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]RestoreChange, 0, len(current))
+		for _, rel := range current {
+			changes = append(changes, RestoreChange{Path: joinTree(originalPath, rel), Action: RestoreDelete})
+		}
+		return changes, nil
+	}
+
+	if _, err := fsys.Stat(backupPath); err != nil {
+		return nil, fmt.Errorf("backup file not found: %w", err)
+	}
+
+	backupFiles, err := listTree(fsys, backupPath)
+	if err != nil {
+		return nil, err
+	}
+	currentFiles, err := listTree(fsys, originalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	inBackup := make(map[string]bool, len(backupFiles))
+	var changes []RestoreChange
+	for _, rel := range backupFiles {
+		inBackup[rel] = true
+		target := joinTree(originalPath, rel)
+		source := joinTree(backupPath, rel)
+
+		if _, err := fsys.Stat(target); os.IsNotExist(err) {
+			changes = append(changes, RestoreChange{Path: target, Action: RestoreCreate})
+			continue
+		}
+
+		equal, err := FilesEqualFS(fsys, source, target)
+		if err != nil {
+			return nil, err
+		}
+		if equal {
+			continue
+		}
+
+		change := RestoreChange{Path: target, Action: RestoreOverwrite}
+		if strings.EqualFold(filepath.Ext(target), ".json") {
+			change.Diff, err = diffFiles(fsys, target, source)
+			if err != nil {
+				return nil, err
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	// Directories are replaced wholesale, so files added since the backup are removed
+	for _, rel := range currentFiles {
+		if !inBackup[rel] {
+			changes = append(changes, RestoreChange{Path: joinTree(originalPath, rel), Action: RestoreDelete})
+		}
+	}
+
+	return changes, nil
+}
+
+// listTree returns the files below root relative to it, or "." when root is a
+// file. A missing root yields no files.
+func listTree(fsys FS, root string) ([]string, error) {
+	info, err := fsys.Stat(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{"."}, nil
+	}
+
+	var files []string
+	err = WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// joinTree resolves a listTree entry back to a full path
+func joinTree(root, rel string) string {
+	if rel == "." {
+		return root
+	}
+	return filepath.Join(root, rel)
+}
+
+// diffFiles returns the line diff from the current file to its backup copy
+func diffFiles(fsys FS, current, backup string) (string, error) {
+	currentData, err := ReadFile(fsys, current)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", current, err)
+	}
+	backupData, err := ReadFile(fsys, backup)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", backup, err)
+	}
+	return LineDiff(string(currentData), string(backupData), "current/"+filepath.Base(current), "backup/"+filepath.Base(backup)), nil
+}
+
+// RestoreVerification is the result of checking restored files against their backup
+type RestoreVerification struct {
+	BackupID      string   `json:"backup_id"`
+	FilesVerified int      `json:"files_verified"`
+	Problems      []string `json:"problems,omitempty"`
+}
+
+// VerifyRestore checks that the files of a backup are on disk as the backup
+// holds them, as they should be right after RestoreBackup: files that existed
+// when the backup was taken have the digests in its checksums, or those of
+// the backed-up copies for backups without checksums, and files that didn't
+// exist are absent. Every difference is a problem; an error means the check
+// itself could not run.
+func (bm *BackupManager) VerifyRestore(backupID string) (*RestoreVerification, error) {
+	return bm.VerifyRestoreFiles(backupID, nil)
+}
+
+// VerifyRestoreFiles checks the files RestoreFiles restored, as VerifyRestore
+// does for RestoreBackup
+func (bm *BackupManager) VerifyRestoreFiles(backupID string, files []string) (*RestoreVerification, error) {
+	metadata, err := bm.loadMetadata(backupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup metadata: %w", err)
+	}
+	files, err = metadata.selectFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &RestoreVerification{BackupID: backupID}
+	for _, originalFile := range files {
+		backupPath := filepath.Join(metadata.BackupPath, metadata.storedName(originalFile))
+		if _, err := bm.fs().Stat(backupPath + ".missing"); err == nil {
+			if _, err := bm.fs().Stat(originalFile); err == nil {
+				verification.Problems = append(verification.Problems,
+					fmt.Sprintf("%s exists, but did not when the backup was taken", originalFile))
+			}
+			continue
+		}
+
+		expected := metadata.checksumsOf(originalFile)
+		if expected == nil {
+			if expected, err = bm.treeChecksums(backupPath, originalFile); err != nil {
+				return nil, fmt.Errorf("failed to checksum the backup of %s: %w", originalFile, err)
+			}
+		}
+		actual, err := bm.treeChecksums(originalFile, originalFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %s: %w", originalFile, err)
+		}
+
+		diff := DiffHashes(expected, actual)
+		verification.FilesVerified += diff.Unchanged
+		for _, path := range diff.Removed {
+			verification.Problems = append(verification.Problems, fmt.Sprintf("%s is missing", path))
+		}
+		for _, path := range diff.Changed {
+			verification.Problems = append(verification.Problems, fmt.Sprintf("%s does not match the backup", path))
+		}
+		for _, path := range diff.Added {
+			verification.Problems = append(verification.Problems, fmt.Sprintf("%s is not in the backup", path))
+		}
+	}
+	return verification, nil
+}
+
+// recordChecksums adds the digests of the backed-up copy of originalPath to
+// the metadata's checksums
+func (bm *BackupManager) recordChecksums(metadata *BackupMetadata, originalPath string) error {
+	backupPath := filepath.Join(metadata.BackupPath, metadata.storedName(originalPath))
+	if _, err := bm.fs().Stat(backupPath + ".missing"); err == nil {
+		return nil
+	}
+	checksums, err := bm.treeChecksums(backupPath, originalPath)
+	if err != nil {
+		return err
+	}
+	if metadata.Checksums == nil {
+		metadata.Checksums = make(map[string]string, len(checksums))
+	}
+	for path, digest := range checksums {
+		metadata.Checksums[path] = digest
+	}
+	return nil
+}
+
+// treeChecksums hashes the files below root, keyed by their path as if root
+// were at originalPath
+func (bm *BackupManager) treeChecksums(root, originalPath string) (map[string]string, error) {
+	hashes, err := HashTreeFS(bm.fs(), root)
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string, len(hashes))
+	for rel, digest := range hashes {
+		checksums[joinTree(originalPath, filepath.FromSlash(rel))] = digest
+	}
+	return checksums, nil
+}
+
+// selectFiles returns the files of a backup to restore: files, each of which
+// must be an entry of Files, or all of Files when files is empty
+func (m *BackupMetadata) selectFiles(files []string) ([]string, error) {
+	if len(files) == 0 {
+		return m.Files, nil
+	}
+	listed := make(map[string]bool, len(m.Files))
+	for _, file := range m.Files {
+		listed[file] = true
+	}
+	for _, file := range files {
+		if !listed[file] {
+			return nil, fmt.Errorf("backup %s does not include %s", m.ID, file)
+		}
+	}
+	return files, nil
+}
+
+// checksumsOf returns the recorded digests of the files at or below
+// originalPath, or nil when the backup has no checksums
+func (m *BackupMetadata) checksumsOf(originalPath string) map[string]string {
+	if m.Checksums == nil {
+		return nil
+	}
+	checksums := make(map[string]string)
+	prefix := originalPath + string(filepath.Separator)
+	for path, digest := range m.Checksums {
+		if path == originalPath || strings.HasPrefix(path, prefix) {
+			checksums[path] = digest
+		}
+	}
+	return checksums
+}
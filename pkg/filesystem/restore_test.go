@@ -0,0 +1,275 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanRestore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "blockbench-restore-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configFile := filepath.Join(tempDir, "world_behavior_packs.json")
+	unchangedFile := filepath.Join(tempDir, "unchanged.json")
+	missingFile := filepath.Join(tempDir, "history.json")
+	packDir := filepath.Join(tempDir, "pack")
+
+	if err := os.WriteFile(configFile, []byte("[\n  \"a\"\n]\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(unchangedFile, []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.MkdirAll(packDir, 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "manifest.json"), []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	metadata, err := bm.CreateBackup("test", "Test plan", []string{configFile, unchangedFile, missingFile, packDir})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+
+	// Change the server state after the backup
+	if err := os.WriteFile(configFile, []byte("[\n  \"a\",\n  \"b\"\n]\n"), 0600); err != nil {
+		t.Fatalf("Failed to modify config: %v", err)
+	}
+	if err := os.WriteFile(missingFile, []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(packDir, "manifest.json")); err != nil {
+		t.Fatalf("Failed to remove manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "extra.txt"), []byte("new"), 0600); err != nil {
+		t.Fatalf("Failed to write extra file: %v", err)
+	}
+
+	plan, err := bm.PlanRestore(metadata.ID)
+	if err != nil {
+		t.Fatalf("PlanRestore failed: %v", err)
+	}
+
+	actions := make(map[string]RestoreChange)
+	for _, change := range plan.Changes {
+		actions[change.Path] = change
+	}
+
+	expected := map[string]string{
+		configFile:                              RestoreOverwrite,
+		missingFile:                             RestoreDelete,
+		filepath.Join(packDir, "manifest.json"): RestoreCreate,
+		filepath.Join(packDir, "extra.txt"):     RestoreDelete,
+	}
+	if len(actions) != len(expected) {
+		t.Errorf("Expected %d changes, got %+v", len(expected), plan.Changes)
+	}
+	for path, action := range expected {
+		if actions[path].Action != action {
+			t.Errorf("Expected %s for %s, got %q", action, path, actions[path].Action)
+		}
+	}
+
+	if diff := actions[configFile].Diff; !strings.Contains(diff, "+  \"a\"") || !strings.Contains(diff, "-  \"b\"") {
+		t.Errorf("Expected config diff to show the restored lines, got:\n%s", diff)
+	}
+
+	// Planning must not modify anything
+	if _, err := os.Stat(filepath.Join(packDir, "extra.txt")); err != nil {
+		t.Errorf("PlanRestore modified the filesystem: %v", err)
+	}
+}
+
+func TestVerifyRestore(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "world_behavior_packs.json")
+	missingFile := filepath.Join(tempDir, "history.json")
+	packDir := filepath.Join(tempDir, "pack")
+	if err := os.WriteFile(configFile, []byte("[]"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.MkdirAll(packDir, 0750); err != nil {
+		t.Fatalf("Failed to create pack dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "manifest.json"), []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	bm := NewBackupManager(filepath.Join(tempDir, "backups"))
+	metadata, err := bm.CreateBackup("test", "Test verify", []string{configFile, missingFile, packDir})
+	if err != nil {
+		t.Fatalf("Failed to create backup: %v", err)
+	}
+	if len(metadata.Checksums) != 2 || metadata.Checksums[filepath.Join(packDir, "manifest.json")] == "" {
+		t.Fatalf("Expected checksums of the config and the manifest, got %v", metadata.Checksums)
+	}
+
+	verify := func() []string {
+		t.Helper()
+		verification, err := bm.VerifyRestore(metadata.ID)
+		if err != nil {
+			t.Fatalf("VerifyRestore failed: %v", err)
+		}
+		return verification.Problems
+	}
+	if problems := verify(); len(problems) != 0 {
+		t.Errorf("Expected an unchanged server to verify, got %v", problems)
+	}
+
+	// Everything a restore should have undone is reported
+	if err := os.WriteFile(configFile, []byte("[\"a\"]"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.WriteFile(missingFile, []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(packDir, "extra.json"), []byte("{}"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	problems := verify()
+	if len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", problems)
+	}
+	for _, want := range []string{"world_behavior_packs.json does not match", "history.json exists", "extra.json is not in the backup"} {
+		if !strings.Contains(strings.Join(problems, "\n"), want) {
+			t.Errorf("Expected a problem containing %q, got %v", want, problems)
+		}
+	}
+
+	if err := bm.RestoreBackup(metadata.ID); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if problems := verify(); len(problems) != 0 {
+		t.Errorf("Expected the restored server to verify, got %v", problems)
+	}
+
+	// Backups without checksums are verified against their copies
+	metadata.Checksums = nil
+	if err := bm.UpdateMetadata(metadata); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(packDir, "manifest.json")); err != nil {
+		t.Fatalf("Failed to remove manifest: %v", err)
+	}
+	if problems := verify(); len(problems) != 1 || !strings.Contains(problems[0], "manifest.json is missing") {
+		t.Errorf("Expected the missing manifest to be reported, got %v", problems)
+	}
+}
+
+func TestRestoreFiles(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+	files := []string{configFile, packDir}
+
+	if _, err := bm.CreateBackup("snapshot", "First", files); err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	writeTestFile(t, configFile, `[{"pack_id": "a"}]`)
+	metadata, err := bm.CreateBackup("snapshot", "Second", files)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	// Files below a backed-up directory are found through the chain
+	manifest := filepath.Join(packDir, "manifest.json")
+	if path, existed, err := bm.BackedUpCopy(metadata, manifest); err != nil || !existed || filepath.Base(path) != "manifest.json" {
+		t.Errorf("BackedUpCopy(%s) = %s, %v, %v", manifest, path, existed, err)
+	}
+	if _, existed, err := bm.BackedUpCopy(metadata, filepath.Join(packDir, "absent.js")); err != nil || existed {
+		t.Errorf("Expected a file not in the backup reported missing, got %v, %v", existed, err)
+	}
+	if _, _, err := bm.BackedUpCopy(metadata, filepath.Join(t.TempDir(), "other")); err == nil {
+		t.Error("Expected BackedUpCopy of a file outside the backup to fail")
+	}
+
+	writeTestFile(t, configFile, "[]")
+	writeTestFile(t, manifest, `{"v": 2}`)
+	plan, err := bm.PlanRestoreFiles(metadata.ID, []string{packDir})
+	if err != nil || len(plan.Changes) != 1 || plan.Changes[0].Path != manifest {
+		t.Errorf("PlanRestoreFiles = %+v, %v, want only the manifest overwritten", plan, err)
+	}
+	if err := bm.RestoreFiles(metadata.ID, []string{packDir}); err != nil {
+		t.Fatalf("RestoreFiles failed: %v", err)
+	}
+	expectFile(t, manifest, `{"v": 1}`)
+	expectFile(t, configFile, "[]")
+	if verification, err := bm.VerifyRestoreFiles(metadata.ID, []string{packDir}); err != nil || len(verification.Problems) != 0 {
+		t.Errorf("VerifyRestoreFiles = %+v, %v", verification, err)
+	}
+
+	if err := bm.RestoreFiles(metadata.ID, []string{filepath.Join(packDir, "scripts")}); err == nil {
+		t.Error("Expected restoring a path the backup does not list to fail")
+	}
+}
+
+func TestRestoreRebased(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	serverRoot := filepath.Dir(configFile)
+	bm := NewBackupManager(filepath.Join(serverRoot, "backups"))
+	bm.Incremental = true
+
+	var metadata *BackupMetadata
+	for _, contents := range []string{"[]", `[{"pack_id": "a"}]`} {
+		writeTestFile(t, configFile, contents)
+		var err error
+		if metadata, err = bm.CreateBackup("snapshot", "Snapshot", []string{configFile, packDir}); err != nil {
+			t.Fatalf("CreateBackup failed: %v", err)
+		}
+		metadata.ServerPath = serverRoot
+		if err := bm.UpdateMetadata(metadata); err != nil {
+			t.Fatalf("UpdateMetadata failed: %v", err)
+		}
+	}
+
+	// The backups are copied to a rebuilt machine, away from the server they were taken of
+	copied := filepath.Join(t.TempDir(), "copied")
+	if err := copyDir(OSFS{}, bm.BackupRoot, copied, nil); err != nil {
+		t.Fatalf("Failed to copy the backups: %v", err)
+	}
+	target := t.TempDir()
+	rebased := NewBackupManager(copied)
+	rebased.RestoreRoot = target
+
+	if err := rebased.RestoreBackup(metadata.ID); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	expectFile(t, filepath.Join(target, "world_behavior_packs.json"), `[{"pack_id": "a"}]`)
+	expectFile(t, filepath.Join(target, "pack", "scripts", "main.js"), "main")
+	if verification, err := rebased.VerifyRestore(metadata.ID); err != nil || len(verification.Problems) != 0 {
+		t.Errorf("VerifyRestore = %+v, %v", verification, err)
+	}
+	expectFile(t, configFile, `[{"pack_id": "a"}]`)
+
+	if _, err := rebased.CreateBackup("snapshot", "Snapshot", []string{configFile}); err == nil {
+		t.Error("Expected a manager that rebases backups to refuse to take one")
+	}
+	if err := rebased.DeleteBackup(metadata.ID); err == nil {
+		t.Error("Expected a manager that rebases backups to refuse to delete one")
+	}
+
+	outside := &BackupMetadata{ID: "backup_1_x", ServerPath: serverRoot, Files: []string{filepath.Join(target, "other")}}
+	if err := outside.Rebase(target); err == nil {
+		t.Error("Expected rebasing a file outside the server to fail")
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	if diff := LineDiff("same\n", "same\n", "a", "b"); diff != "" {
+		t.Errorf("Expected no diff for identical input, got %q", diff)
+	}
+
+	diff := LineDiff("one\ntwo\nthree\n", "one\n2\nthree\n", "a", "b")
+	for _, line := range []string{"--- a", "+++ b", "-two", "+2", " one", " three"} {
+		if !strings.Contains(diff, line+"\n") {
+			t.Errorf("Expected diff to contain %q, got:\n%s", line, diff)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package filesystem
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retention limits how many backups of one operation are kept. A zero field
+// sets no limit.
+type Retention struct {
+	Keep   int           // Newest backups kept
+	MaxAge time.Duration // Backups older than this are deleted, except the newest
+}
+
+// IsZero reports whether the policy keeps every backup
+func (r Retention) IsZero() bool {
+	return r.Keep <= 0 && r.MaxAge <= 0
+}
+
+// Prune deletes the backups of operation the retention policy does not keep,
+// oldest first, and returns their IDs. Backups of other operations are never
+// touched, and the newest backup of operation is always kept. Deleting a
+// backup that others are based on detaches them first, so the kept backups
+// stay restorable. With dryRun nothing is deleted.
+func (bm *BackupManager) Prune(operation string, policy Retention, now time.Time, dryRun bool) ([]string, error) {
+	if policy.IsZero() {
+		return nil, nil
+	}
+	backups, err := bm.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	SortBackups(backups)
+
+	var expired []string
+	rank := 0
+	for _, backup := range backups {
+		if backup.Operation != operation {
+			continue
+		}
+		rank++
+		tooMany := policy.Keep > 0 && rank > policy.Keep
+		tooOld := policy.MaxAge > 0 && rank > 1 && now.Sub(backup.Timestamp) > policy.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, backup.ID)
+		}
+	}
+	// Oldest first, so each backup is detached from as few others as possible
+	for i, j := 0, len(expired)-1; i < j; i, j = i+1, j-1 {
+		expired[i], expired[j] = expired[j], expired[i]
+	}
+	if dryRun {
+		return expired, nil
+	}
+
+	for i, id := range expired {
+		if err := bm.DeleteBackup(id); err != nil {
+			return expired[:i], fmt.Errorf("failed to delete backup %s: %w", id, err)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	configFile, packDir := incrementalTestServer(t)
+	bm := NewBackupManager(filepath.Join(filepath.Dir(configFile), "backups"))
+	bm.Incremental = true
+
+	var snapshots []*BackupMetadata
+	for i := 0; i < 4; i++ {
+		writeTestFile(t, configFile, string(rune('a'+i)))
+		metadata, err := bm.CreateBackup("snapshot", "Snapshot", []string{configFile, packDir})
+		if err != nil {
+			t.Fatalf("CreateBackup failed: %v", err)
+		}
+		snapshots = append(snapshots, metadata)
+	}
+	install, err := bm.CreateBackup("install", "Install", []string{configFile})
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	if pruned, err := bm.Prune("snapshot", Retention{}, time.Now(), false); err != nil || len(pruned) != 0 {
+		t.Errorf("Prune with no limits = %v, %v, want nothing pruned", pruned, err)
+	}
+
+	pruned, err := bm.Prune("snapshot", Retention{Keep: 2}, time.Now(), true)
+	if err != nil || len(pruned) != 2 || pruned[0] != snapshots[0].ID || pruned[1] != snapshots[1].ID {
+		t.Errorf("Prune dry run = %v, %v, want the two oldest snapshots oldest first", pruned, err)
+	}
+	if backups, _ := bm.ListBackups(); len(backups) != 5 {
+		t.Errorf("Expected a dry run to delete nothing, got %d backups", len(backups))
+	}
+
+	if _, err := bm.Prune("snapshot", Retention{Keep: 2}, time.Now(), false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	backups, err := bm.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	kept := make(map[string]bool)
+	for _, backup := range backups {
+		kept[backup.ID] = true
+	}
+	if len(kept) != 3 || !kept[snapshots[2].ID] || !kept[snapshots[3].ID] || !kept[install.ID] {
+		t.Errorf("Expected the two newest snapshots and the install backup kept, got %v", kept)
+	}
+	// The kept snapshots were based on the deleted ones
+	for i, snapshot := range snapshots[2:] {
+		if err := bm.RestoreBackup(snapshot.ID); err != nil {
+			t.Fatalf("RestoreBackup(%s) failed: %v", snapshot.ID, err)
+		}
+		expectFile(t, configFile, string(rune('c'+i)))
+	}
+
+	// Age never deletes the newest backup
+	later := time.Now().Add(48 * time.Hour)
+	pruned, err = bm.Prune("snapshot", Retention{MaxAge: 24 * time.Hour}, later, false)
+	if err != nil || len(pruned) != 1 || pruned[0] != snapshots[2].ID {
+		t.Errorf("Prune by age = %v, %v, want only %s pruned", pruned, err, snapshots[2].ID)
+	}
+}
@@ -0,0 +1,168 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// ContentStore is a content-addressed store of file blobs named by their
+// SHA-256 digest. Dedupe replaces files with hard links to the blob holding
+// the same content, so servers carrying many versions of similar packs keep
+// each distinct file on disk once. The store must be on the same filesystem
+// as the directories it deduplicates.
+type ContentStore struct {
+	Root string
+}
+
+// DedupeResult summarizes a Dedupe call
+type DedupeResult struct {
+	Files      int   `json:"files"`       // Regular files examined
+	Linked     int   `json:"linked"`      // Files replaced by a link to an existing blob
+	SavedBytes int64 `json:"saved_bytes"` // Disk space released by those links
+}
+
+// GCResult summarizes a GC call
+type GCResult struct {
+	Blobs      int   `json:"blobs"`       // Blobs examined
+	Removed    int   `json:"removed"`     // Blobs no pack file links to any more
+	FreedBytes int64 `json:"freed_bytes"` // Size of the removed blobs
+}
+
+// NewContentStore creates a content store rooted at root
+func NewContentStore(root string) *ContentStore {
+	return &ContentStore{Root: root}
+}
+
+// blobPath shards blobs by the first two hex digits of their digest
+func (cs *ContentStore) blobPath(digest string) string {
+	return filepath.Join(cs.Root, digest[:2], digest)
+}
+
+// Dedupe adds every regular file below dir to the store. A file whose content
+// is already stored is atomically replaced by a hard link to the blob; a new
+// file becomes the blob itself through a second link, so nothing is copied.
+// Linked files share one set of permissions and must not be edited in place.
+func (cs *ContentStore) Dedupe(dir string) (*DedupeResult, error) {
+	result := &DedupeResult{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		result.Files++
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		digest, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		blob := cs.blobPath(digest)
+
+		blobInfo, err := os.Stat(blob)
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(blob), DefaultDirPerm); err != nil {
+				return fmt.Errorf("failed to create store directory: %w", err)
+			}
+			if err := os.Link(path, blob); err != nil {
+				return fmt.Errorf("failed to add %s to the content store (it must be on the same filesystem): %w", path, err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat blob %s: %w", blob, err)
+		}
+		if os.SameFile(info, blobInfo) {
+			return nil // Already linked
+		}
+
+		// Link next to the file, then rename over it so the file never disappears
+		tmp := path + ".blockbench-link"
+		if err := os.Link(blob, tmp); err != nil {
+			return fmt.Errorf("failed to link %s to the content store: %w", path, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			if rmErr := os.Remove(tmp); rmErr != nil {
+				slog.Warn("Failed to remove temporary file", "path", tmp, "error", rmErr)
+			}
+			return fmt.Errorf("failed to replace %s with a store link: %w", path, err)
+		}
+		result.Linked++
+		result.SavedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to deduplicate %s: %w", dir, err)
+	}
+	return result, nil
+}
+
+// GC removes blobs that no file outside the store links to any more, such as
+// the files of uninstalled packs. With dryRun set it only reports them.
+func (cs *ContentStore) GC(dryRun bool) (*GCResult, error) {
+	result := &GCResult{}
+	if _, err := os.Stat(cs.Root); os.IsNotExist(err) {
+		return result, nil
+	}
+
+	err := filepath.WalkDir(cs.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		result.Blobs++
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		links, ok := linkCount(info)
+		if !ok {
+			return fmt.Errorf("content store garbage collection is not supported on this platform")
+		}
+		if links > 1 {
+			return nil
+		}
+
+		result.Removed++
+		result.FreedBytes += info.Size()
+		if dryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to collect content store garbage: %w", err)
+	}
+
+	if !dryRun {
+		removeEmptyShards(cs.Root)
+	}
+	return result, nil
+}
+
+// removeEmptyShards removes shard directories left empty by GC
+func removeEmptyShards(root string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		shard := filepath.Join(root, entry.Name())
+		if children, err := os.ReadDir(shard); err == nil && len(children) == 0 {
+			_ = os.Remove(shard) // Best effort; a concurrent Dedupe may have refilled it
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filesystem
+
+import "os"
+
+// linkCount is not available here; GC reports the platform as unsupported
+func linkCount(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,118 @@
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestContentStoreDedupeAndGC(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("content store garbage collection needs hard link counts")
+	}
+
+	tempDir, err := os.MkdirTemp("", "blockbench-store-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Two versions of a pack sharing one texture
+	packA := filepath.Join(tempDir, "packs", "a")
+	packB := filepath.Join(tempDir, "packs", "b")
+	files := map[string]string{
+		filepath.Join(packA, "textures", "shared.png"): "shared texture",
+		filepath.Join(packA, "manifest.json"):          "version 1",
+		filepath.Join(packB, "textures", "shared.png"): "shared texture",
+		filepath.Join(packB, "manifest.json"):          "version 2",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	store := NewContentStore(filepath.Join(tempDir, "store"))
+
+	first, err := store.Dedupe(packA)
+	if err != nil {
+		t.Fatalf("Dedupe failed: %v", err)
+	}
+	if first.Files != 2 || first.Linked != 0 {
+		t.Errorf("Expected 2 new files and no links, got %+v", first)
+	}
+
+	second, err := store.Dedupe(packB)
+	if err != nil {
+		t.Fatalf("Dedupe failed: %v", err)
+	}
+	if second.Linked != 1 || second.SavedBytes != int64(len("shared texture")) {
+		t.Errorf("Expected the shared texture to be linked, got %+v", second)
+	}
+
+	infoA, err := os.Stat(filepath.Join(packA, "textures", "shared.png"))
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	infoB, err := os.Stat(filepath.Join(packB, "textures", "shared.png"))
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if !os.SameFile(infoA, infoB) {
+		t.Error("Expected identical files to share one inode")
+	}
+	if content, err := os.ReadFile(filepath.Join(packB, "manifest.json")); err != nil || string(content) != "version 2" {
+		t.Errorf("Expected distinct file to be unchanged, got %q (%v)", content, err)
+	}
+
+	// Deduplicating again is a no-op
+	again, err := store.Dedupe(packB)
+	if err != nil {
+		t.Fatalf("Dedupe failed: %v", err)
+	}
+	if again.Linked != 0 {
+		t.Errorf("Expected no new links on a second pass, got %+v", again)
+	}
+
+	// Every blob is still referenced
+	result, err := store.GC(false)
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if result.Blobs != 3 || result.Removed != 0 {
+		t.Errorf("Expected 3 referenced blobs, got %+v", result)
+	}
+
+	// Uninstalling pack A orphans only its manifest
+	if err := os.RemoveAll(packA); err != nil {
+		t.Fatalf("Failed to remove pack: %v", err)
+	}
+
+	result, err = store.GC(true)
+	if err != nil {
+		t.Fatalf("GC dry run failed: %v", err)
+	}
+	if result.Removed != 1 {
+		t.Errorf("Expected 1 orphaned blob in dry run, got %+v", result)
+	}
+
+	result, err = store.GC(false)
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if result.Removed != 1 || result.FreedBytes != int64(len("version 1")) {
+		t.Errorf("Expected the orphaned manifest blob to be removed, got %+v", result)
+	}
+
+	result, err = store.GC(false)
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if result.Blobs != 2 || result.Removed != 0 {
+		t.Errorf("Expected 2 blobs left, got %+v", result)
+	}
+}
@@ -0,0 +1,131 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SyncResult lists what SyncDir changed, by slash-separated path relative to
+// the directories
+type SyncResult struct {
+	Copied  []string `json:"copied"`
+	Removed []string `json:"removed"`
+}
+
+// Empty reports whether SyncDir found the directories already in sync
+func (r *SyncResult) Empty() bool {
+	return len(r.Copied) == 0 && len(r.Removed) == 0
+}
+
+// SyncDir makes dst a copy of src incrementally, like rsync --delete: only
+// files that are missing from dst or differ in size or contents are copied,
+// and whatever dst has that src doesn't is removed. skip, when set, leaves
+// entries of src out as it does for ArchiveDir, so dst's copies of them are
+// removed too. Copied files replace dst's rather than being written through,
+// since dst's files may be hard links into a content store.
+func SyncDir(src, dst string, skip func(rel string, entry fs.DirEntry) bool) (*SyncResult, error) {
+	result := &SyncResult{Copied: make([]string, 0), Removed: make([]string, 0)}
+	wanted := make(map[string]bool)
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return os.MkdirAll(dst, DefaultDirPerm)
+		}
+		slashed := filepath.ToSlash(rel)
+		if skip != nil && skip(slashed, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		wanted[slashed] = true
+
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(target, DefaultDirPerm)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		same, err := sameFile(path, target)
+		if err != nil || same {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		if err := copyFile(OSFS{}, path, target, nil); err != nil {
+			return err
+		}
+		result.Copied = append(result.Copied, slashed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove what src no longer has; a stale directory goes with its contents
+	var stale []string
+	err = filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !wanted[filepath.ToSlash(rel)] {
+			stale = append(stale, rel)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range stale {
+		if err := os.RemoveAll(filepath.Join(dst, rel)); err != nil {
+			return nil, err
+		}
+		result.Removed = append(result.Removed, filepath.ToSlash(rel))
+	}
+	sort.Strings(result.Removed)
+	return result, nil
+}
+
+// sameFile reports whether target is a regular file with the same size and
+// contents as path
+func sameFile(path, target string) (bool, error) {
+	targetInfo, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !targetInfo.Mode().IsRegular() || targetInfo.Size() != info.Size() {
+		return false, nil
+	}
+	return FilesEqual(path, target)
+}
@@ -0,0 +1,77 @@
+package filesystem
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyncDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	dst := filepath.Join(t.TempDir(), "dst")
+	writeTree(t, src, map[string]string{
+		"manifest.json":        "{}",
+		"scripts/main.js":      "// main",
+		"node_modules/x/a.js":  "x",
+		"textures/a.png":       "png",
+		".git/HEAD":            "ref",
+		"functions/setup.json": "setup",
+	})
+	skip := func(rel string, entry fs.DirEntry) bool {
+		return strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules"
+	}
+
+	result, err := SyncDir(src, dst, skip)
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	want := []string{"functions/setup.json", "manifest.json", "scripts/main.js", "textures/a.png"}
+	if !reflect.DeepEqual(result.Copied, want) || len(result.Removed) != 0 {
+		t.Errorf("Expected the first sync to copy %v, got %+v", want, result)
+	}
+
+	// Unchanged files are left alone
+	result, err = SyncDir(src, dst, skip)
+	if err != nil || !result.Empty() {
+		t.Errorf("Expected nothing to sync, got %+v, %v", result, err)
+	}
+
+	// Changed and removed files, and files the destination gained, are synced
+	writeTree(t, src, map[string]string{"scripts/main.js": "// changed"})
+	if err := os.RemoveAll(filepath.Join(src, "textures")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, dst, map[string]string{"stray.txt": "stray"})
+	result, err = SyncDir(src, dst, skip)
+	if err != nil {
+		t.Fatalf("SyncDir failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Copied, []string{"scripts/main.js"}) || !reflect.DeepEqual(result.Removed, []string{"stray.txt", "textures"}) {
+		t.Errorf("Unexpected sync %+v", result)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "scripts", "main.js")); err != nil || string(data) != "// changed" {
+		t.Errorf("Expected the changed file to be copied, got %q, %v", data, err)
+	}
+	for _, rel := range []string{"textures", "stray.txt", "node_modules", ".git"} {
+		if _, err := os.Stat(filepath.Join(dst, rel)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be absent from the destination", rel)
+		}
+	}
+}
+
+// writeTree writes files by slash-separated path below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
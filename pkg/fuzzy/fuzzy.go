@@ -0,0 +1,102 @@
+// Package fuzzy measures how closely strings match, to suggest what the user
+// meant when a name is mistyped.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Distance returns the Levenshtein edit distance between a and b: the number
+// of single-character insertions, deletions, and substitutions that turn one
+// into the other
+func Distance(a, b string) int {
+	return distance([]rune(a), []rune(b), false)
+}
+
+// SubstringDistance returns the fewest edits that make pattern appear
+// somewhere in text; it is 0 when text contains pattern
+func SubstringDistance(pattern, text string) int {
+	return distance([]rune(pattern), []rune(text), true)
+}
+
+// distance computes the edit distance from a to b, or from a to the closest
+// substring of b when anywhere is set
+func distance(a, b []rune, anywhere bool) int {
+	// previous[j] is the distance from the first i-1 runes of a to the first j of b
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		if !anywhere {
+			previous[j] = j
+		}
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	if !anywhere {
+		return previous[len(b)]
+	}
+	best := previous[0]
+	for _, d := range previous {
+		best = min(best, d)
+	}
+	return best
+}
+
+// Suggest returns up to limit candidates close enough to query to be what
+// was meant, closest first. Case is ignored. A candidate is close when a part
+// of it is within a third of the query's length in edits of the query, so
+// both mistyped names and mistyped fragments of names are found.
+func Suggest(query string, candidates []string, limit int) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	threshold := max(1, len([]rune(query))/3)
+
+	type suggestion struct {
+		candidate string
+		partial   int
+		whole     int
+	}
+	var suggestions []suggestion
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		lower := strings.ToLower(candidate)
+		if partial := SubstringDistance(query, lower); partial <= threshold {
+			suggestions = append(suggestions, suggestion{candidate, partial, Distance(query, lower)})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].partial != suggestions[j].partial {
+			return suggestions[i].partial < suggestions[j].partial
+		}
+		if suggestions[i].whole != suggestions[j].whole {
+			return suggestions[i].whole < suggestions[j].whole
+		}
+		return suggestions[i].candidate < suggestions[j].candidate
+	})
+
+	var result []string
+	for _, s := range suggestions {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, s.candidate)
+	}
+	return result
+}
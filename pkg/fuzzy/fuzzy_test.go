@@ -0,0 +1,73 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"Lucky Blocks", "Lucky Blocks", 0},
+		{"lucky blocks", "lukcy blocks", 2},
+		{"héllo", "hello", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestSubstringDistance(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		expected      int
+	}{
+		{"", "anything", 0},
+		{"block", "lucky blocks", 0},
+		{"blokc", "lucky blocks", 1},
+		{"lucy", "lucky blocks", 1},
+		{"xyz", "", 3},
+	}
+
+	for _, tt := range tests {
+		if got := SubstringDistance(tt.pattern, tt.text); got != tt.expected {
+			t.Errorf("SubstringDistance(%q, %q) = %d, want %d", tt.pattern, tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"Lucky Blocks", "Lucky Blocks RP", "More Ores", "Furniture", "More Ores"}
+
+	tests := []struct {
+		name     string
+		query    string
+		limit    int
+		expected []string
+	}{
+		{"mistyped name", "Lukcy Blocks", 0, []string{"Lucky Blocks", "Lucky Blocks RP"}},
+		{"mistyped fragment", "ores", 0, []string{"More Ores"}},
+		{"case is ignored", "FURNITUR", 0, []string{"Furniture"}},
+		{"limit", "lucky blok", 1, []string{"Lucky Blocks"}},
+		{"nothing close", "dragons", 0, nil},
+		{"empty query", "  ", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Suggest(tt.query, candidates, tt.limit); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Suggest(%q) = %q, want %q", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
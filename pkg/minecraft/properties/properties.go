@@ -0,0 +1,135 @@
+// Package properties reads and edits key=value files such as a Bedrock
+// server's server.properties. Saving writes back every line as it was read,
+// comments and blank lines included, with only the changed values rewritten
+// and new keys appended.
+package properties
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File is a parsed properties file
+type File struct {
+	path    string
+	lines   []line
+	newline string // Line ending of the file, kept when saving
+}
+
+// line is one line of the file; key is empty for comments and blank lines
+type line struct {
+	text  string
+	key   string
+	value string
+}
+
+// Load reads and parses a properties file
+func Load(path string) (*File, error) {
+	// #nosec G304 - path is the properties file the caller asked to edit
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	f := Parse(data)
+	f.path = path
+	return f, nil
+}
+
+// Parse parses the contents of a properties file. A File from Parse has no
+// path, so it is written with Bytes rather than Save.
+func Parse(data []byte) *File {
+	f := &File{newline: "\n"}
+	if bytes.Contains(data, []byte("\r\n")) {
+		f.newline = "\r\n"
+	}
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text == "" {
+		return f
+	}
+	for _, raw := range strings.Split(text, "\n") {
+		l := line{text: raw}
+		trimmed := strings.TrimSpace(raw)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "!") {
+			if key, value, ok := strings.Cut(trimmed, "="); ok {
+				l.key = strings.TrimSpace(key)
+				l.value = strings.TrimSpace(value)
+			}
+		}
+		f.lines = append(f.lines, l)
+	}
+	return f
+}
+
+// Get returns the value of the first line setting key, and whether there is one
+func (f *File) Get(key string) (string, bool) {
+	for _, l := range f.lines {
+		if l.key == key {
+			return l.value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets key to value on the first line setting it, or appends a line when
+// no line does. It reports whether the file changed.
+func (f *File) Set(key, value string) bool {
+	for i, l := range f.lines {
+		if l.key != key {
+			continue
+		}
+		if l.value == value {
+			return false
+		}
+		f.lines[i] = line{text: key + "=" + value, key: key, value: value}
+		return true
+	}
+	f.lines = append(f.lines, line{text: key + "=" + value, key: key, value: value})
+	return true
+}
+
+// Bytes returns the file's contents, ending with a line ending
+func (f *File) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, l := range f.lines {
+		buf.WriteString(l.text)
+		buf.WriteString(f.newline)
+	}
+	return buf.Bytes()
+}
+
+// Save atomically replaces the file it was loaded from: the contents are
+// written to a temporary file in the same directory, which is renamed over it
+func (f *File) Save() error {
+	if f.path == "" {
+		return fmt.Errorf("properties file has no path to save to")
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(f.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = tmp.Write(f.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name()) // #nosec G104 - cleanup on error path, already returning error
+		return fmt.Errorf("failed to save %s: %w", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package properties
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const serverProperties = `# Bedrock server settings
+server-name=Dedicated Server
+level-name = Bedrock level
+
+# Cheats
+allow-cheats=false
+allow-cheats=true
+`
+
+func TestGet(t *testing.T) {
+	f := Parse([]byte(serverProperties))
+
+	tests := []struct {
+		key   string
+		value string
+		found bool
+	}{
+		{"server-name", "Dedicated Server", true},
+		{"level-name", "Bedrock level", true},
+		{"allow-cheats", "false", true}, // The first line wins
+		{"# Cheats", "", false},
+		{"gamemode", "", false},
+	}
+	for _, tt := range tests {
+		value, found := f.Get(tt.key)
+		if value != tt.value || found != tt.found {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.key, value, found, tt.value, tt.found)
+		}
+	}
+}
+
+func TestSetKeepsOtherLines(t *testing.T) {
+	f := Parse([]byte(serverProperties))
+
+	if f.Set("server-name", "Dedicated Server") {
+		t.Error("Expected setting the current value to change nothing")
+	}
+	if !f.Set("allow-cheats", "true") || !f.Set("gamemode", "creative") {
+		t.Error("Expected changed and new keys to change the file")
+	}
+
+	want := `# Bedrock server settings
+server-name=Dedicated Server
+level-name = Bedrock level
+
+# Cheats
+allow-cheats=true
+allow-cheats=true
+gamemode=creative
+`
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("Bytes =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.properties")
+	if err := os.WriteFile(path, []byte("level-name=W\r\nallow-cheats=false"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	f.Set("allow-cheats", "true")
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != "level-name=W\r\nallow-cheats=true\r\n" {
+		t.Errorf("Expected the CRLF line endings to be kept, got %q", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v, %v", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file to be left, got %d entries", len(entries))
+	}
+
+	if err := Parse(nil).Save(); err == nil {
+		t.Error("Expected a parsed file without a path not to save")
+	}
+}
@@ -0,0 +1,49 @@
+package nbt
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// levelDatHeaderSize is the size of the header before level.dat's NBT: the
+// storage version and the length of the NBT that follows, both little-endian
+// 32-bit integers
+const levelDatHeaderSize = 8
+
+// LevelDat is a decoded Bedrock level.dat file
+type LevelDat struct {
+	StorageVersion int32 // Format version from the header, e.g. 10
+	Name           string
+	Root           Compound
+}
+
+// ParseLevelDat decodes a level.dat file: the 8-byte header and the NBT root
+// compound after it
+func ParseLevelDat(data []byte) (*LevelDat, error) {
+	if len(data) < levelDatHeaderSize {
+		return nil, fmt.Errorf("level.dat is %d bytes, too short for its header", len(data))
+	}
+	version := int32(binary.LittleEndian.Uint32(data[0:4]))
+	length := binary.LittleEndian.Uint32(data[4:8])
+	if int64(length) != int64(len(data)-levelDatHeaderSize) {
+		return nil, fmt.Errorf("level.dat header gives %d bytes of NBT, but %d follow", length, len(data)-levelDatHeaderSize)
+	}
+
+	name, root, err := Decode(data[levelDatHeaderSize:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid level.dat NBT: %w", err)
+	}
+	return &LevelDat{StorageVersion: version, Name: name, Root: root}, nil
+}
+
+// Bytes encodes the level.dat file, with a header giving the new NBT length
+func (l *LevelDat) Bytes() ([]byte, error) {
+	payload, err := Encode(l.Name, l.Root)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, levelDatHeaderSize, levelDatHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(l.StorageVersion))
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(payload)))
+	return append(data, payload...), nil
+}
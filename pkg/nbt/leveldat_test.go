@@ -0,0 +1,60 @@
+package nbt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLevelDatRoundTrip(t *testing.T) {
+	level := &LevelDat{StorageVersion: 10, Root: sample}
+	data, err := level.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.Equal(data[:4], []byte{10, 0, 0, 0}) {
+		t.Errorf("Expected storage version 10 in the header, got % x", data[:4])
+	}
+
+	parsed, err := ParseLevelDat(data)
+	if err != nil {
+		t.Fatalf("ParseLevelDat failed: %v", err)
+	}
+	if parsed.StorageVersion != 10 || !reflect.DeepEqual(parsed.Root, sample) {
+		t.Errorf("Parsed level.dat differs: %+v", parsed)
+	}
+
+	// Growing the NBT updates the length in the header
+	experiments, _ := parsed.Root.Compound("experiments")
+	parsed.Root = parsed.Root.Set("experiments", experiments.Set("data_driven_items", int8(1)))
+	grown, err := parsed.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if _, err := ParseLevelDat(grown); err != nil {
+		t.Errorf("Expected the edited level.dat to parse, got %v", err)
+	}
+}
+
+func TestParseLevelDatErrors(t *testing.T) {
+	valid, err := (&LevelDat{StorageVersion: 10, Root: Compound{}}).Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", []byte{10, 0, 0}},
+		{"length mismatch", append(append([]byte(nil), valid...), 0)},
+		{"bad NBT", []byte{10, 0, 0, 0, 3, 0, 0, 0, 8, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseLevelDat(tt.data); err == nil {
+				t.Error("Expected ParseLevelDat to fail")
+			}
+		})
+	}
+}
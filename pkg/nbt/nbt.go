@@ -0,0 +1,421 @@
+// Package nbt reads and writes the little-endian NBT (Named Binary Tag) format
+// Bedrock Edition uses for level.dat. Compounds keep their tags in file order,
+// so a file decoded and encoded again without changes comes out byte for byte
+// the same.
+package nbt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TagType identifies the kind of an NBT tag
+type TagType byte
+
+const (
+	TagEnd TagType = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// maxDepth bounds how deeply lists and compounds may nest, so a corrupt file
+// can't exhaust the stack
+const maxDepth = 512
+
+// Values decode to Go types by tag: int8, int16, int32, int64, float32,
+// float64, []byte, string, List, Compound, []int32, and []int64. Encoding
+// takes the same types.
+
+// NamedTag is one tag of a compound
+type NamedTag struct {
+	Name  string
+	Value any
+}
+
+// Compound is an NBT compound: named tags in file order
+type Compound []NamedTag
+
+// List is an NBT list, whose items all have the same type. An empty list may
+// have type TagEnd.
+type List struct {
+	Type  TagType
+	Items []any
+}
+
+// Get returns the value of the tag with the given name
+func (c Compound) Get(name string) (any, bool) {
+	for _, tag := range c {
+		if tag.Name == name {
+			return tag.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Compound returns the compound tag with the given name
+func (c Compound) Compound(name string) (Compound, bool) {
+	value, _ := c.Get(name)
+	compound, ok := value.(Compound)
+	return compound, ok
+}
+
+// String returns the string tag with the given name
+func (c Compound) String(name string) (string, bool) {
+	value, _ := c.Get(name)
+	s, ok := value.(string)
+	return s, ok
+}
+
+// Set sets the tag with the given name, appending it when the compound has
+// none. The compound may grow, so use the returned one.
+func (c Compound) Set(name string, value any) Compound {
+	for i, tag := range c {
+		if tag.Name == name {
+			c[i].Value = value
+			return c
+		}
+	}
+	return append(c, NamedTag{Name: name, Value: value})
+}
+
+// Decode parses little-endian NBT data holding one named compound, returning
+// its name and tags
+func Decode(data []byte) (string, Compound, error) {
+	d := &decoder{data: data}
+	tagType, err := d.byte()
+	if err != nil {
+		return "", nil, err
+	}
+	if TagType(tagType) != TagCompound {
+		return "", nil, fmt.Errorf("root tag is type %d, not a compound", tagType)
+	}
+	name, err := d.string()
+	if err != nil {
+		return "", nil, err
+	}
+	value, err := d.value(TagCompound, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if d.offset != len(data) {
+		return "", nil, fmt.Errorf("%d unexpected bytes after the root compound", len(data)-d.offset)
+	}
+	return name, value.(Compound), nil
+}
+
+// Encode returns the little-endian NBT encoding of a named root compound
+func Encode(name string, root Compound) ([]byte, error) {
+	e := &encoder{}
+	e.byte(byte(TagCompound))
+	e.string(name)
+	if err := e.value(root, 0); err != nil {
+		return nil, err
+	}
+	return e.data, nil
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+// next returns the next n bytes
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.offset {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", d.offset)
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b, nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) uint16() (uint16, error) {
+	b, err := d.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (d *decoder) uint64() (uint64, error) {
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := d.uint16()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.next(int(n))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// length reads an array or list length, rejecting lengths the rest of the
+// data can't hold at size bytes per item
+func (d *decoder) length(size int) (int, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return 0, err
+	}
+	length := int(int32(n))
+	if length < 0 || length > (len(d.data)-d.offset)/size {
+		return 0, fmt.Errorf("invalid length %d at offset %d", length, d.offset-4)
+	}
+	return length, nil
+}
+
+// value reads the payload of a tag of the given type
+func (d *decoder) value(tagType TagType, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("tags nested more than %d deep", maxDepth)
+	}
+
+	switch tagType {
+	case TagByte:
+		b, err := d.byte()
+		return int8(b), err
+	case TagShort:
+		n, err := d.uint16()
+		return int16(n), err
+	case TagInt:
+		n, err := d.uint32()
+		return int32(n), err
+	case TagLong:
+		n, err := d.uint64()
+		return int64(n), err
+	case TagFloat:
+		n, err := d.uint32()
+		return math.Float32frombits(n), err
+	case TagDouble:
+		n, err := d.uint64()
+		return math.Float64frombits(n), err
+	case TagByteArray:
+		length, err := d.length(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(length)
+		return append([]byte(nil), b...), err
+	case TagString:
+		return d.string()
+	case TagList:
+		itemType, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		length, err := d.length(1)
+		if err != nil {
+			return nil, err
+		}
+		list := List{Type: TagType(itemType), Items: make([]any, 0, length)}
+		for i := 0; i < length; i++ {
+			item, err := d.value(list.Type, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list.Items = append(list.Items, item)
+		}
+		return list, nil
+	case TagCompound:
+		compound := Compound{}
+		for {
+			b, err := d.byte()
+			if err != nil {
+				return nil, err
+			}
+			if TagType(b) == TagEnd {
+				return compound, nil
+			}
+			name, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.value(TagType(b), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			compound = append(compound, NamedTag{Name: name, Value: value})
+		}
+	case TagIntArray:
+		length, err := d.length(4)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int32, length)
+		for i := range values {
+			n, _ := d.uint32()
+			values[i] = int32(n)
+		}
+		return values, nil
+	case TagLongArray:
+		length, err := d.length(8)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int64, length)
+		for i := range values {
+			n, _ := d.uint64()
+			values[i] = int64(n)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unknown tag type %d at offset %d", tagType, d.offset)
+}
+
+type encoder struct {
+	data []byte
+}
+
+func (e *encoder) byte(b byte) {
+	e.data = append(e.data, b)
+}
+
+func (e *encoder) uint16(n uint16) {
+	e.data = binary.LittleEndian.AppendUint16(e.data, n)
+}
+
+func (e *encoder) uint32(n uint32) {
+	e.data = binary.LittleEndian.AppendUint32(e.data, n)
+}
+
+func (e *encoder) uint64(n uint64) {
+	e.data = binary.LittleEndian.AppendUint64(e.data, n)
+}
+
+func (e *encoder) string(s string) {
+	e.uint16(uint16(len(s)))
+	e.data = append(e.data, s...)
+}
+
+// typeOf returns the tag type a Go value encodes as
+func typeOf(value any) (TagType, error) {
+	switch value.(type) {
+	case int8:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case List:
+		return TagList, nil
+	case Compound:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	}
+	return TagEnd, fmt.Errorf("cannot encode %T as NBT", value)
+}
+
+// value writes the payload of a value
+func (e *encoder) value(value any, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("tags nested more than %d deep", maxDepth)
+	}
+
+	switch v := value.(type) {
+	case int8:
+		e.byte(byte(v))
+	case int16:
+		e.uint16(uint16(v))
+	case int32:
+		e.uint32(uint32(v))
+	case int64:
+		e.uint64(uint64(v))
+	case float32:
+		e.uint32(math.Float32bits(v))
+	case float64:
+		e.uint64(math.Float64bits(v))
+	case []byte:
+		e.uint32(uint32(len(v)))
+		e.data = append(e.data, v...)
+	case string:
+		if len(v) > math.MaxUint16 {
+			return fmt.Errorf("string of %d bytes is too long for NBT", len(v))
+		}
+		e.string(v)
+	case List:
+		e.byte(byte(v.Type))
+		e.uint32(uint32(len(v.Items)))
+		for i, item := range v.Items {
+			if itemType, err := typeOf(item); err != nil || itemType != v.Type {
+				return fmt.Errorf("list item %d is %T, not tag type %d", i, item, v.Type)
+			}
+			if err := e.value(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case Compound:
+		for _, tag := range v {
+			tagType, err := typeOf(tag.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag.Name, err)
+			}
+			e.byte(byte(tagType))
+			e.string(tag.Name)
+			if err := e.value(tag.Value, depth+1); err != nil {
+				return fmt.Errorf("%s: %w", tag.Name, err)
+			}
+		}
+		e.byte(byte(TagEnd))
+	case []int32:
+		e.uint32(uint32(len(v)))
+		for _, n := range v {
+			e.uint32(uint32(n))
+		}
+	case []int64:
+		e.uint32(uint32(len(v)))
+		for _, n := range v {
+			e.uint64(uint64(n))
+		}
+	default:
+		_, err := typeOf(value)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package nbt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sample has one tag of every type, nested in a named root compound
+var sample = Compound{
+	{Name: "LevelName", Value: "Bedrock level"},
+	{Name: "GameType", Value: int32(1)},
+	{Name: "commandsEnabled", Value: int8(1)},
+	{Name: "eduOffer", Value: int16(-2)},
+	{Name: "RandomSeed", Value: int64(-4172144997902289642)},
+	{Name: "rainLevel", Value: float32(0.5)},
+	{Name: "lightning", Value: float64(1.25)},
+	{Name: "blob", Value: []byte{1, 2, 3}},
+	{Name: "lastOpenedWithVersion", Value: List{Type: TagInt, Items: []any{int32(1), int32(21), int32(50)}}},
+	{Name: "empty", Value: List{Type: TagEnd, Items: []any{}}},
+	{Name: "experiments", Value: Compound{
+		{Name: "gametest", Value: int8(0)},
+		{Name: "experiments_ever_used", Value: int8(0)},
+	}},
+	{Name: "ints", Value: []int32{-1, 7}},
+	{Name: "longs", Value: []int64{1 << 40}},
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	data, err := Encode("root", sample)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	name, root, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if name != "root" {
+		t.Errorf("Expected root name %q, got %q", "root", name)
+	}
+	if !reflect.DeepEqual(root, sample) {
+		t.Errorf("Decoded compound differs:\n got %#v\nwant %#v", root, sample)
+	}
+
+	again, err := Encode(name, root)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("Expected re-encoding to give the same bytes")
+	}
+}
+
+func TestDecodeLittleEndian(t *testing.T) {
+	// Compound "" { Int "n" = 258 }
+	data := []byte{10, 0, 0, 3, 1, 0, 'n', 2, 1, 0, 0, 0}
+	_, root, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if value, _ := root.Get("n"); value != int32(258) {
+		t.Errorf("Expected n = 258, got %v", value)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "unexpected end"},
+		{"root not a compound", []byte{8, 0, 0, 0, 0}, "not a compound"},
+		{"truncated", []byte{10, 0, 0, 3, 1, 0, 'n', 2, 1}, "unexpected end"},
+		{"missing end tag", []byte{10, 0, 0}, "unexpected end"},
+		{"trailing bytes", []byte{10, 0, 0, 0, 0}, "unexpected bytes"},
+		{"length past the data", []byte{10, 0, 0, 7, 1, 0, 'b', 255, 255, 255, 127, 0}, "invalid length"},
+		{"negative length", []byte{10, 0, 0, 11, 1, 0, 'a', 255, 255, 255, 255, 0}, "invalid length"},
+		{"unknown tag", []byte{10, 0, 0, 42, 1, 0, 'x', 0}, "unknown tag type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Decode(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDecodeDepthLimit(t *testing.T) {
+	// Lists of lists nested past the limit
+	data := []byte{10, 0, 0, 9, 1, 0, 'l'}
+	for i := 0; i <= maxDepth; i++ {
+		data = append(data, byte(TagList), 1, 0, 0, 0)
+	}
+	data = append(data, byte(TagEnd), 0, 0, 0, 0, 0)
+
+	if _, _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Expected a nesting error, got %v", err)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		root Compound
+	}{
+		{"unsupported type", Compound{{Name: "n", Value: 5}}},
+		{"mixed list", Compound{{Name: "l", Value: List{Type: TagInt, Items: []any{int32(1), "two"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Encode("", tt.root); err == nil {
+				t.Error("Expected Encode to fail")
+			}
+		})
+	}
+}
+
+func TestCompoundSet(t *testing.T) {
+	c := Compound{{Name: "a", Value: int8(0)}}
+	c = c.Set("a", int8(1))
+	c = c.Set("b", "new")
+
+	want := Compound{{Name: "a", Value: int8(1)}, {Name: "b", Value: "new"}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Set gave %#v, want %#v", c, want)
+	}
+	if s, ok := c.String("b"); !ok || s != "new" {
+		t.Errorf("String(b) = %q, %v", s, ok)
+	}
+	if _, ok := c.Compound("a"); ok {
+		t.Error("Expected a byte tag not to be returned as a compound")
+	}
+}
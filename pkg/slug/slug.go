@@ -0,0 +1,67 @@
+// Package slug turns display names, such as pack names with color codes,
+// emoji, or slashes, into directory names that are safe on every filesystem
+// and still read like the name.
+package slug
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the longest slug in bytes. Pack names can run to a sentence;
+// a directory name only needs to be recognizable.
+const MaxLength = 64
+
+// punctuation is the punctuation kept in slugs; every filesystem accepts it
+const punctuation = "-_.()[]+&!,'"
+
+// Make returns the slug of name. Minecraft formatting codes (§ and the
+// character after it) are dropped. Letters of any script, digits, and a few
+// punctuation marks are kept; anything else, such as emoji, slashes, colons,
+// or control characters, separates words. Runs of separators and spaces
+// become one space, leading and trailing dots, spaces, and dashes are
+// trimmed, and the slug is cut to MaxLength bytes on a character boundary.
+// A name with nothing to keep has the empty slug.
+func Make(name string) string {
+	var b strings.Builder
+	space := false
+	skip := false
+	for _, r := range name {
+		switch {
+		case skip:
+			skip = false
+			continue
+		case r == '§':
+			skip = true
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || strings.ContainsRune(punctuation, r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return trim(cut(b.String(), MaxLength))
+}
+
+// cut returns s cut to at most length bytes without splitting a character
+func cut(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	s = s[:length]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// trim drops the characters a name shouldn't start or end with: dots, which
+// hide files or vanish on Windows, spaces, and dashes, which read as options
+func trim(s string) string {
+	return strings.Trim(s, ". -")
+}
@@ -0,0 +1,46 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Lucky Blocks", "Lucky Blocks"},
+		{"§6Lucky §lBlocks§r", "Lucky Blocks"},
+		{"🔥 Fire Pack 🔥", "Fire Pack"},
+		{"Blocks/Items", "Blocks Items"},
+		{"Boss: Fight?", "Boss Fight"},
+		{"Tab\tand\nnewline", "Tab and newline"},
+		{"Café Décor", "Café Décor"},
+		{"方块 包", "方块 包"},
+		{"More Tools (v2) [BP]", "More Tools (v2) [BP]"},
+		{"...hidden.", "hidden"},
+		{"--force", "force"},
+		{"  spaced   out  ", "spaced out"},
+		{"🔥🔥", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Make(tt.input); got != tt.expected {
+			t.Errorf("Make(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestMakeCutsLongNames(t *testing.T) {
+	got := Make(strings.Repeat("é", 40)) // 80 bytes
+	if got != strings.Repeat("é", MaxLength/2) {
+		t.Errorf("Make of a long name = %q (%d bytes), want %d whole characters", got, len(got), MaxLength/2)
+	}
+
+	got = Make(strings.Repeat("a", MaxLength-1) + " b")
+	if got != strings.Repeat("a", MaxLength-1) {
+		t.Errorf("Make = %q, want the trailing space trimmed after the cut", got)
+	}
+}
@@ -0,0 +1,179 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Field types understood by RecordSchema
+const (
+	FieldString      = "string"
+	FieldInteger     = "integer"
+	FieldBoolean     = "boolean"
+	FieldTimestamp   = "timestamp" // RFC 3339 string
+	FieldStringArray = "string_array"
+	FieldStringMap   = "string_map" // Object with string values
+)
+
+// RecordSchema describes a flat JSON object written by blockbench itself,
+// such as backup metadata or state files
+type RecordSchema struct {
+	Name    string                 `json:"name"`
+	Version int                    `json:"version"` // Current schema version; older files may need migration
+	Fields  map[string]FieldSchema `json:"fields"`
+}
+
+// FieldSchema describes one field of a RecordSchema
+type FieldSchema struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	NonEmpty bool   `json:"non_empty,omitempty"` // Strings and arrays must not be empty
+}
+
+// ParseRecordSchema parses a schema definition, typically embedded in the binary
+func ParseRecordSchema(data []byte) (*RecordSchema, error) {
+	var schema RecordSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	for name, field := range schema.Fields {
+		switch field.Type {
+		case FieldString, FieldInteger, FieldBoolean, FieldTimestamp, FieldStringArray, FieldStringMap:
+		default:
+			return nil, fmt.Errorf("schema %s: field %s has unknown type %q", schema.Name, name, field.Type)
+		}
+	}
+	return &schema, nil
+}
+
+// Validate checks a JSON document against the schema and returns every
+// problem found, sorted by field. Unknown fields are problems too, so files
+// written by a different tool or corrupted by hand are caught.
+func (s *RecordSchema) Validate(data []byte) []string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []string{fmt.Sprintf("not a valid JSON object: %v", err)}
+	}
+	if doc == nil {
+		return []string{"not a valid JSON object: null"}
+	}
+
+	var problems []string
+	for name, field := range s.Fields {
+		value, ok := doc[name]
+		if !ok || value == nil {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("%s: required field is missing", name))
+			}
+			continue
+		}
+		if problem := field.check(value); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, problem))
+		}
+	}
+	for name := range doc {
+		if _, known := s.Fields[name]; !known {
+			problems = append(problems, fmt.Sprintf("%s: unknown field", name))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+// check returns a problem description, or "" when the value matches the field
+func (f FieldSchema) check(value interface{}) string {
+	switch f.Type {
+	case FieldString, FieldTimestamp:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected %s, got %s", f.Type, jsonType(value))
+		}
+		if f.NonEmpty && str == "" {
+			return "must not be empty"
+		}
+		if f.Type == FieldTimestamp {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Sprintf("invalid timestamp %q", str)
+			}
+		}
+	case FieldInteger:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Sprintf("expected integer, got %s", jsonType(value))
+		}
+	case FieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("expected boolean, got %s", jsonType(value))
+		}
+	case FieldStringArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprintf("expected array of strings, got %s", jsonType(value))
+		}
+		for i, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Sprintf("item %d: expected string, got %s", i, jsonType(item))
+			}
+		}
+		if f.NonEmpty && len(items) == 0 {
+			return "must not be empty"
+		}
+	case FieldStringMap:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("expected object of strings, got %s", jsonType(value))
+		}
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := entries[key].(string); !ok {
+				return fmt.Sprintf("key %q: expected string, got %s", key, jsonType(entries[key]))
+			}
+		}
+		if f.NonEmpty && len(entries) == 0 {
+			return "must not be empty"
+		}
+	}
+	return ""
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// SchemaVersionOf reads the "schema_version" field of a JSON object. Files
+// written before versioning was introduced have no such field and report 0.
+func SchemaVersionOf(data []byte) (int, error) {
+	var header struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return header.SchemaVersion, nil
+}
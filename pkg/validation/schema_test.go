@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+  "name": "test",
+  "version": 2,
+  "fields": {
+    "id": {"type": "string", "required": true, "non_empty": true},
+    "created": {"type": "timestamp", "required": true},
+    "count": {"type": "integer"},
+    "enabled": {"type": "boolean"},
+    "tags": {"type": "string_array"},
+    "labels": {"type": "string_map"}
+  }
+}`
+
+func TestRecordSchemaValidate(t *testing.T) {
+	schema, err := ParseRecordSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{"valid", `{"id": "a", "created": "2024-01-02T03:04:05Z", "count": 3, "enabled": true, "tags": ["x"], "labels": {"k": "v"}}`, nil},
+		{"optional fields omitted", `{"id": "a", "created": "2024-01-02T03:04:05.123+02:00"}`, nil},
+		{"null optional field", `{"id": "a", "created": "2024-01-02T03:04:05Z", "tags": null}`, nil},
+		{"missing required", `{"id": "a"}`, []string{"created: required field is missing"}},
+		{"empty required string", `{"id": "", "created": "2024-01-02T03:04:05Z"}`, []string{"id: must not be empty"}},
+		{"bad timestamp", `{"id": "a", "created": "yesterday"}`, []string{`created: invalid timestamp "yesterday"`}},
+		{"wrong types", `{"id": 1, "created": "2024-01-02T03:04:05Z", "count": 1.5, "enabled": "yes"}`, []string{
+			"count: expected integer, got number",
+			"enabled: expected boolean, got string",
+			"id: expected string, got integer",
+		}},
+		{"bad array item", `{"id": "a", "created": "2024-01-02T03:04:05Z", "tags": ["x", 2]}`, []string{"tags: item 1: expected string, got integer"}},
+		{"bad map value", `{"id": "a", "created": "2024-01-02T03:04:05Z", "labels": {"k": "v", "n": 1}}`, []string{`labels: key "n": expected string, got integer`}},
+		{"map not an object", `{"id": "a", "created": "2024-01-02T03:04:05Z", "labels": ["v"]}`, []string{"labels: expected object of strings, got array"}},
+		{"unknown field", `{"id": "a", "created": "2024-01-02T03:04:05Z", "extra": 1}`, []string{"extra: unknown field"}},
+		{"not an object", `[1, 2]`, nil}, // Checked below: any problem is enough
+		{"truncated", `{"id": "a", "cre`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := schema.Validate([]byte(tt.data))
+			if tt.name == "not an object" || tt.name == "truncated" {
+				if len(problems) != 1 {
+					t.Errorf("Expected one parse problem, got %v", problems)
+				}
+				return
+			}
+			if !reflect.DeepEqual(problems, tt.expected) {
+				t.Errorf("Validate() = %v, want %v", problems, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseRecordSchemaUnknownType(t *testing.T) {
+	if _, err := ParseRecordSchema([]byte(`{"name": "bad", "fields": {"x": {"type": "float"}}}`)); err == nil {
+		t.Error("Expected error for unknown field type")
+	}
+}
+
+func TestSchemaVersionOf(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected int
+	}{
+		{`{"schema_version": 3}`, 3},
+		{`{"id": "a"}`, 0},
+	}
+
+	for _, tt := range tests {
+		version, err := SchemaVersionOf([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("SchemaVersionOf(%s) failed: %v", tt.data, err)
+		}
+		if version != tt.expected {
+			t.Errorf("SchemaVersionOf(%s) = %d, want %d", tt.data, version, tt.expected)
+		}
+	}
+}
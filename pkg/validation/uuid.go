@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"crypto/rand"
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -37,6 +39,17 @@ func ValidateUUID(uuid string) bool {
 	return matched
 }
 
+// NewUUID returns a random (version 4) UUID in lowercase with dashes
+func NewUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
 // NormalizeUUID converts a UUID to lowercase with dashes
 func NormalizeUUID(uuid string) string {
 	// Remove all dashes first
@@ -71,6 +84,17 @@ func IsValidVersion(version [3]int) bool {
 	return true
 }
 
+// ParseVersion parses a major.minor.patch version such as 1.2.0
+func ParseVersion(value string) ([3]int, error) {
+	var version [3]int
+	var rest string
+	n, _ := fmt.Sscanf(value, "%d.%d.%d%s", &version[0], &version[1], &version[2], &rest)
+	if n != 3 || !IsValidVersion(version) {
+		return [3]int{}, fmt.Errorf("invalid version %q: use major.minor.patch, e.g. 1.2.0", value)
+	}
+	return version, nil
+}
+
 // CompareVersions compares two version arrays
 // Returns: -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
 func CompareVersions(v1, v2 [3]int) int {
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -38,6 +39,26 @@ func TestValidateUUID(t *testing.T) {
 	}
 }
 
+func TestNewUUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		uuid, err := NewUUID()
+		if err != nil {
+			t.Fatalf("NewUUID failed: %v", err)
+		}
+		if !ValidateUUID(uuid) || NormalizeUUID(uuid) != uuid {
+			t.Errorf("Expected a normalized UUID, got %s", uuid)
+		}
+		if uuid[14] != '4' || !strings.ContainsRune("89ab", rune(uuid[19])) {
+			t.Errorf("Expected a version 4 UUID, got %s", uuid)
+		}
+		if seen[uuid] {
+			t.Fatalf("Generated %s twice", uuid)
+		}
+		seen[uuid] = true
+	}
+}
+
 func TestNormalizeUUID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -112,6 +133,27 @@ func TestIsValidVersion(t *testing.T) {
 	}
 }
 
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    [3]int
+		wantErr bool
+	}{
+		{"1.2.0", [3]int{1, 2, 0}, false},
+		{"0.0.10", [3]int{0, 0, 10}, false},
+		{"1.2", [3]int{}, true},
+		{"1.2.0-beta", [3]int{}, true},
+		{"1.-2.0", [3]int{}, true},
+		{"", [3]int{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) = %v, %v", tt.value, got, err)
+		}
+	}
+}
+
 func TestCompareVersions(t *testing.T) {
 	tests := []struct {
 		name     string
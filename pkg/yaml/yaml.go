@@ -0,0 +1,180 @@
+// Package yaml encodes values as YAML for command output. Values are encoded
+// as JSON first, so json struct tags and Marshalers apply, and the YAML keeps
+// the JSON's field order. Only block style is written; it is a small encoder
+// for output, not a YAML library.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Marshal returns the YAML encoding of v, ending in a newline
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	root, err := decodeNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if root.isBlock() {
+		writeBlock(&out, root, 0)
+	} else {
+		out.WriteString(root.scalar())
+		out.WriteString("\n")
+	}
+	return out.Bytes(), nil
+}
+
+// node is a decoded JSON value that keeps the order of object keys
+type node struct {
+	value  any // json.Number, string, bool, or nil for scalars
+	object bool
+	array  bool
+	keys   []string
+	items  []*node
+}
+
+// decodeNode reads the next JSON value from the decoder
+func decodeNode(decoder *json.Decoder) (*node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		n := &node{object: true}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object key %v", keyToken)
+			}
+			value, err := decodeNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			n.keys = append(n.keys, key)
+			n.items = append(n.items, value)
+		}
+		_, err := decoder.Token() // }
+		return n, err
+	case json.Delim('['):
+		n := &node{array: true}
+		for decoder.More() {
+			item, err := decodeNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		_, err := decoder.Token() // ]
+		return n, err
+	}
+	return &node{value: token}, nil
+}
+
+// isBlock reports whether the node is written as indented lines: a non-empty
+// object or array
+func (n *node) isBlock() bool {
+	return (n.object || n.array) && len(n.items) > 0
+}
+
+// writeBlock writes an object's keys or an array's items at indent
+func writeBlock(out *bytes.Buffer, n *node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for i, item := range n.items {
+		if n.object {
+			out.WriteString(pad + quote(n.keys[i]) + ":")
+			if item.isBlock() {
+				out.WriteString("\n")
+				writeBlock(out, item, indent+2)
+			} else {
+				out.WriteString(" " + item.scalar() + "\n")
+			}
+			continue
+		}
+
+		if !item.isBlock() {
+			out.WriteString(pad + "- " + item.scalar() + "\n")
+			continue
+		}
+		// A nested block starts on the dash's line: "- key: value"
+		var nested bytes.Buffer
+		writeBlock(&nested, item, indent+2)
+		out.WriteString(pad + "- ")
+		out.Write(nested.Bytes()[indent+2:])
+	}
+}
+
+// scalar formats a value that fits on one line
+func (n *node) scalar() string {
+	switch {
+	case n.object:
+		return "{}"
+	case n.array:
+		return "[]"
+	}
+	switch value := n.value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		return quote(value)
+	}
+	return fmt.Sprint(n.value)
+}
+
+// quote returns s as a plain YAML scalar when it reads back as the same
+// string, and as a double-quoted scalar otherwise
+func quote(s string) string {
+	if !needsQuotes(s) {
+		return s
+	}
+	// A JSON string is a valid YAML double-quoted scalar
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// needsQuotes reports whether a plain scalar would be read back as something
+// other than the string s: another type, a different string, or invalid YAML.
+// Strings starting with a digit are always quoted, which also keeps versions,
+// dates, and timestamps strings.
+func needsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "y", "n", "on", "off", "null", "~", ".inf", "-.inf", "+.inf", ".nan":
+		return true
+	}
+	if strings.ContainsRune("0123456789+-.?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package yaml
+
+import (
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type pack struct {
+		Name    string   `json:"name"`
+		UUID    string   `json:"uuid"`
+		Version [3]int   `json:"version"`
+		Size    int64    `json:"size,omitempty"`
+		Tags    []string `json:"tags"`
+		Active  bool     `json:"active"`
+		Link    *string  `json:"link"`
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{
+			"struct keeps field order and tags",
+			pack{Name: "Lucky Blocks", UUID: "1234-abcd", Version: [3]int{1, 2, 0}, Tags: []string{"fun", "blocks"}, Active: true},
+			`name: Lucky Blocks
+uuid: "1234-abcd"
+version:
+  - 1
+  - 2
+  - 0
+tags:
+  - fun
+  - blocks
+active: true
+link: null
+`,
+		},
+		{
+			"array of objects starts each on the dash line",
+			[]map[string]any{{"a": 1, "b": map[string]any{"c": []any{}}}, {"a": 2, "b": map[string]any{}}},
+			`- a: 1
+  b:
+    c: []
+- a: 2
+  b: {}
+`,
+		},
+		{"nested arrays", [][]int{{1, 2}, {}}, "- - 1\n  - 2\n- []\n"},
+		{"scalar document", "plain text", "plain text\n"},
+		{"empty array document", []string{}, "[]\n"},
+		{"nil document", nil, "null\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Marshal =\n%s\nwant\n%s", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Lucky Blocks", "Lucky Blocks"},
+		{"@minecraft/server", `"@minecraft/server"`},
+		{"", `""`},
+		{"yes", `"yes"`},
+		{"Null", `"Null"`},
+		{"1.0.0", `"1.0.0"`},
+		{"2026-10-16T14:45:00Z", `"2026-10-16T14:45:00Z"`},
+		{"-dash", `"-dash"`},
+		{"key: value", `"key: value"`},
+		{"trailing colon:", `"trailing colon:"`},
+		{"not # a comment", `"not # a comment"`},
+		{" padded", `" padded"`},
+		{"two\nlines", `"two\nlines"`},
+		{"say \"hi\"", `say "hi"`},
+		{"Ünïcode ✓", "Ünïcode ✓"},
+	}
+
+	for _, tt := range tests {
+		if got := quote(tt.input); got != tt.expected {
+			t.Errorf("quote(%q) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+}